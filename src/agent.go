@@ -0,0 +1,372 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Defaults for sink install-agent
+const (
+	DefaultAgentName     = "sink-agent"
+	DefaultAgentInterval = time.Hour
+	minAgentInterval     = time.Minute
+)
+
+// AgentOptions describes a scheduled convergence agent: sink bootstrap of
+// one config source, run every Interval by systemd or launchd
+type AgentOptions struct {
+	Name         string // Unit or launchd label
+	Binary       string // Absolute path of the sink binary to run
+	ConfigSource string // Anything sink bootstrap accepts
+	Interval     time.Duration
+	Splay        time.Duration
+	SHA256       string
+	ChecksumsURL string
+	Pubkey       string
+	ConfirmHost  string
+	Variables    map[string]string
+	System       bool // System-wide (root) rather than per-user
+}
+
+// AgentFile is a file install-agent writes
+type AgentFile struct {
+	Path    string
+	Content string
+}
+
+// agentBootstrapArgs builds the command line the agent runs. --json keeps
+// the run non-interactive and gives the system log structured records.
+func agentBootstrapArgs(opts AgentOptions) []string {
+	args := []string{opts.Binary, "bootstrap", opts.ConfigSource, "--json"}
+	if opts.SHA256 != "" {
+		args = append(args, "--sha256", opts.SHA256)
+	}
+	if opts.ChecksumsURL != "" {
+		args = append(args, "--checksums-url", opts.ChecksumsURL)
+	}
+	if opts.Pubkey != "" {
+		args = append(args, "--pubkey", opts.Pubkey)
+	}
+	if opts.ConfirmHost != "" {
+		args = append(args, "--confirm-host", opts.ConfirmHost)
+	}
+	if opts.Splay > 0 {
+		args = append(args, "--splay", opts.Splay.String())
+	}
+	for _, name := range sortedKeys(opts.Variables) {
+		args = append(args, "--var", name+"="+opts.Variables[name])
+	}
+	return args
+}
+
+// systemdQuote quotes an ExecStart argument. systemd expands % specifiers
+// and $ variables even inside quotes, so both are doubled.
+func systemdQuote(arg string) string {
+	arg = strings.NewReplacer("%", "%%", "$", "$$").Replace(arg)
+	if arg != "" && !strings.ContainsAny(arg, " \t\n\"'\\;") {
+		return arg
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(arg) + `"`
+}
+
+// systemdUnits returns the service and timer for the agent. The service is
+// a oneshot the timer starts after boot and then every interval.
+func systemdUnits(opts AgentOptions, dir string) []AgentFile {
+	args := agentBootstrapArgs(opts)
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = systemdQuote(arg)
+	}
+	interval := int64(opts.Interval / time.Second)
+
+	service := fmt.Sprintf(`[Unit]
+Description=sink convergence agent (%s)
+Wants=network-online.target
+After=network-online.target
+
+[Service]
+Type=oneshot
+ExecStart=%s
+`, opts.ConfigSource, strings.Join(quoted, " "))
+
+	timer := fmt.Sprintf(`[Unit]
+Description=Run %s every %s
+
+[Timer]
+OnBootSec=2min
+OnUnitActiveSec=%ds
+Unit=%s.service
+
+[Install]
+WantedBy=timers.target
+`, opts.Name, opts.Interval, interval, opts.Name)
+
+	return []AgentFile{
+		{Path: filepath.Join(dir, opts.Name+".service"), Content: service},
+		{Path: filepath.Join(dir, opts.Name+".timer"), Content: timer},
+	}
+}
+
+// xmlEscape escapes text for a plist string
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// launchdPlist returns the launchd job for the agent, logging to logPath
+func launchdPlist(opts AgentOptions, dir, logPath string) AgentFile {
+	var args strings.Builder
+	for _, arg := range agentBootstrapArgs(opts) {
+		fmt.Fprintf(&args, "\t\t<string>%s</string>\n", xmlEscape(arg))
+	}
+	content := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>%s</string>
+	<key>ProgramArguments</key>
+	<array>
+%s	</array>
+	<key>StartInterval</key>
+	<integer>%d</integer>
+	<key>RunAtLoad</key>
+	<true/>
+	<key>StandardOutPath</key>
+	<string>%s</string>
+	<key>StandardErrorPath</key>
+	<string>%s</string>
+</dict>
+</plist>
+`, xmlEscape(opts.Name), args.String(), int64(opts.Interval/time.Second), xmlEscape(logPath), xmlEscape(logPath))
+	return AgentFile{Path: filepath.Join(dir, opts.Name+".plist"), Content: content}
+}
+
+// agentPlan returns the files to write and the commands that enable the
+// agent on goos. logs is the per-user log directory.
+func agentPlan(opts AgentOptions, goos, home, logs string) ([]AgentFile, [][]string, error) {
+	switch goos {
+	case "linux":
+		dir := filepath.Join(home, ".config", "systemd", "user")
+		systemctl := []string{"systemctl", "--user"}
+		if opts.System {
+			dir = "/etc/systemd/system"
+			systemctl = []string{"systemctl"}
+		}
+		files := systemdUnits(opts, dir)
+		commands := [][]string{
+			append(append([]string{}, systemctl...), "daemon-reload"),
+			append(append([]string{}, systemctl...), "enable", "--now", opts.Name+".timer"),
+		}
+		return files, commands, nil
+	case "darwin":
+		dir := filepath.Join(home, "Library", "LaunchAgents")
+		logPath := filepath.Join(logs, opts.Name+".log")
+		if opts.System {
+			dir = "/Library/LaunchDaemons"
+			logPath = filepath.Join("/var/log", opts.Name+".log")
+		}
+		plist := launchdPlist(opts, dir, logPath)
+		commands := [][]string{
+			{"launchctl", "unload", plist.Path},
+			{"launchctl", "load", "-w", plist.Path},
+		}
+		return []AgentFile{plist}, commands, nil
+	}
+	return nil, nil, fmt.Errorf("install-agent supports systemd (linux) and launchd (darwin), not %s", goos)
+}
+
+// printAgentPlan shows what install-agent would write and run
+func printAgentPlan(w io.Writer, files []AgentFile, commands [][]string) {
+	for _, file := range files {
+		fmt.Fprintf(w, "# %s\n%s\n", file.Path, file.Content)
+	}
+	for _, command := range commands {
+		fmt.Fprintf(w, "$ %s\n", strings.Join(command, " "))
+	}
+}
+
+// installAgentCommand handles sink install-agent
+func installAgentCommand() {
+	opts := AgentOptions{
+		Name:      DefaultAgentName,
+		Interval:  DefaultAgentInterval,
+		Variables: map[string]string{},
+		System:    os.Geteuid() == 0,
+	}
+	dryRun := false
+
+	args := os.Args[2:]
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch arg {
+		case "-h", "--help":
+			printInstallAgentHelp()
+			os.Exit(0)
+		case "--dry-run":
+			dryRun = true
+		case "--user":
+			opts.System = false
+		case "--config-url", "--interval", "--splay", "--name", "--binary", "--sha256", "--checksums-url", "--pubkey", "--confirm-host", "--var":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: %s requires a value\n", arg)
+				os.Exit(1)
+			}
+			value := args[i+1]
+			i++
+			var err error
+			switch arg {
+			case "--config-url":
+				opts.ConfigSource = value
+			case "--interval":
+				opts.Interval, err = time.ParseDuration(value)
+				if err == nil && opts.Interval < minAgentInterval {
+					err = fmt.Errorf("--interval must be at least %s, got '%s'", minAgentInterval, value)
+				}
+			case "--splay":
+				opts.Splay, err = parseSplay(value)
+			case "--name":
+				opts.Name = value
+				if value == "" || strings.ContainsAny(value, `/\ `) {
+					err = fmt.Errorf("invalid --name '%s'", value)
+				}
+			case "--binary":
+				opts.Binary = value
+			case "--sha256":
+				opts.SHA256 = value
+			case "--checksums-url":
+				opts.ChecksumsURL = value
+			case "--pubkey":
+				opts.Pubkey = value
+			case "--confirm-host":
+				opts.ConfirmHost = value
+			case "--var":
+				var name string
+				name, value, err = parseVarFlag(value)
+				opts.Variables[name] = value
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		default:
+			fmt.Fprintf(os.Stderr, "Error: unexpected argument: %s\n", arg)
+			os.Exit(1)
+		}
+	}
+
+	if opts.ConfigSource == "" {
+		fmt.Fprintf(os.Stderr, "Error: --config-url is required\n\n")
+		printInstallAgentHelp()
+		os.Exit(1)
+	}
+
+	// The agent runs from another working directory, so local paths must
+	// be absolute
+	for _, path := range []*string{&opts.ConfigSource, &opts.Pubkey, &opts.Binary} {
+		if *path != "" && !strings.Contains(*path, ":") {
+			if abs, err := filepath.Abs(*path); err == nil {
+				*path = abs
+			}
+		}
+	}
+	if opts.Binary == "" {
+		binary, err := os.Executable()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: cannot locate sink binary (use --binary): %v\n", err)
+			os.Exit(1)
+		}
+		opts.Binary = binary
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil && !opts.System {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	logs, err := logDir()
+	if err != nil && !opts.System {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	files, commands, err := agentPlan(opts, runtime.GOOS, home, logs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if dryRun {
+		printAgentPlan(os.Stdout, files, commands)
+		return
+	}
+
+	for _, file := range files {
+		if err := os.MkdirAll(filepath.Dir(file.Path), 0755); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := os.WriteFile(file.Path, []byte(file.Content), 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✅ Wrote %s\n", file.Path)
+	}
+	for i, command := range commands {
+		output, err := exec.Command(command[0], command[1:]...).CombinedOutput()
+		// launchctl unload fails when the job was not loaded yet
+		if err != nil && !(runtime.GOOS == "darwin" && i == 0) {
+			fmt.Fprintf(os.Stderr, "Error: %s: %v\n%s", strings.Join(command, " "), err, output)
+			os.Exit(1)
+		}
+	}
+	fmt.Printf("✅ %s runs sink bootstrap %s every %s\n", opts.Name, opts.ConfigSource, opts.Interval)
+}
+
+func printInstallAgentHelp() {
+	fmt.Print(`sink install-agent - Run sink on a schedule with systemd or launchd
+
+Usage:
+  sink install-agent --config-url <source> [options]
+
+Description:
+  Writes and enables a systemd service and timer (Linux) or a launchd job
+  (macOS) that runs "sink bootstrap <source> --json" every interval, so a
+  machine keeps converging on its config. Run as root, the agent is
+  installed system-wide; otherwise it is installed for the current user.
+  Output goes to the journal (journalctl -u <name>) or, on macOS, to
+  /var/log/<name>.log or <name>.log in the log directory
+  (~/Library/Logs/sink, or SINK_LOG_DIR).
+
+Options:
+  --config-url <source>  Config to converge on; anything sink bootstrap
+                         accepts (required)
+  --interval <duration>  Time between runs (default: 1h, minimum: 1m)
+  --splay <duration>     Random delay before each run
+  --name <name>          Unit or launchd label (default: sink-agent)
+  --binary <path>        sink binary to run (default: this binary)
+  --sha256 <hash>        Expected config checksum
+  --checksums-url <url>  SHA256SUMS manifest to verify the config against
+                         (pinned HTTPS, or signed for --pubkey)
+  --pubkey <path>        Verify the config's signature with this key
+  --confirm-host <host>  Allow a config marked dangerous to run on <host>
+  --var <name=value>     Set a config variable (repeatable)
+  --user                 Install for the current user even when root
+  --dry-run              Print the files and commands instead of running them
+  -h, --help             Show this help message
+
+Examples:
+  sudo sink install-agent --interval 1h --splay 5m \
+    --config-url https://configs.example.com/dev.json \
+    --checksums-url https://configs.example.com/SHA256SUMS \
+    --pubkey /etc/sink/configs.pub
+  sink install-agent --config-url github:acme/configs/dev.json@v1.2.0 --dry-run
+`)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestAgentPlan tests the systemd and launchd files install-agent writes
+func TestAgentPlan(t *testing.T) {
+	opts := AgentOptions{
+		Name:         "sink-agent",
+		Binary:       "/usr/local/bin/sink",
+		ConfigSource: "https://configs.example.com/dev.json",
+		Interval:     30 * time.Minute,
+		Splay:        5 * time.Minute,
+		ChecksumsURL: "https://configs.example.com/SHA256SUMS",
+		Variables:    map[string]string{"greeting": "hello world", "discount": "50%"},
+		System:       true,
+	}
+
+	files, commands, err := agentPlan(opts, "linux", "/home/dev", "")
+	if err != nil {
+		t.Fatalf("linux plan failed: %v", err)
+	}
+	if len(files) != 2 || files[0].Path != "/etc/systemd/system/sink-agent.service" || files[1].Path != "/etc/systemd/system/sink-agent.timer" {
+		t.Fatalf("unexpected systemd files: %+v", files)
+	}
+	wantExec := `ExecStart=/usr/local/bin/sink bootstrap https://configs.example.com/dev.json --json --checksums-url https://configs.example.com/SHA256SUMS --splay 5m0s --var discount=50%% --var "greeting=hello world"`
+	if !strings.Contains(files[0].Content, wantExec+"\n") {
+		t.Errorf("service missing %q:\n%s", wantExec, files[0].Content)
+	}
+	if !strings.Contains(files[1].Content, "OnUnitActiveSec=1800s\n") {
+		t.Errorf("timer missing interval:\n%s", files[1].Content)
+	}
+	if got := strings.Join(commands[1], " "); got != "systemctl enable --now sink-agent.timer" {
+		t.Errorf("unexpected enable command: %s", got)
+	}
+
+	opts.System = false
+	files, commands, _ = agentPlan(opts, "linux", "/home/dev", "")
+	if files[0].Path != "/home/dev/.config/systemd/user/sink-agent.service" || commands[0][1] != "--user" {
+		t.Errorf("expected a user unit, got %s and %v", files[0].Path, commands[0])
+	}
+
+	opts.Variables = map[string]string{"name": "a<b"}
+	files, commands, err = agentPlan(opts, "darwin", "/Users/dev", "/Users/dev/Library/Logs/sink")
+	if err != nil {
+		t.Fatalf("darwin plan failed: %v", err)
+	}
+	plist := files[0].Content
+	for _, want := range []string{
+		"<string>sink-agent</string>",
+		"<string>/usr/local/bin/sink</string>\n\t\t<string>bootstrap</string>",
+		"<string>name=a&lt;b</string>",
+		"<integer>1800</integer>",
+		"<string>/Users/dev/Library/Logs/sink/sink-agent.log</string>",
+	} {
+		if !strings.Contains(plist, want) {
+			t.Errorf("plist missing %q:\n%s", want, plist)
+		}
+	}
+	if files[0].Path != "/Users/dev/Library/LaunchAgents/sink-agent.plist" || commands[1][0] != "launchctl" {
+		t.Errorf("unexpected launchd plan: %s %v", files[0].Path, commands)
+	}
+
+	if _, _, err := agentPlan(opts, "windows", "", ""); err == nil {
+		t.Error("expected an error on windows")
+	}
+}
+
+// TestSystemdQuote tests quoting ExecStart arguments
+func TestSystemdQuote(t *testing.T) {
+	tests := map[string]string{
+		"plain":      "plain",
+		"":           `""`,
+		"two words":  `"two words"`,
+		`say "hi"`:   `"say \"hi\""`,
+		"100%":       "100%%",
+		"$HOME/x":    "$$HOME/x",
+		`back\slash`: `"back\\slash"`,
+	}
+	for in, want := range tests {
+		if got := systemdQuote(in); got != want {
+			t.Errorf("systemdQuote(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
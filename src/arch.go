@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// archAliases maps the machine names uname -m and Go report to the names
+// release downloads use most: amd64, arm64, armv7, armv6 and 386. Names not
+// listed (ppc64le, s390x, riscv64, ...) are used as they are.
+var archAliases = map[string]string{
+	"x86_64":  "amd64",
+	"amd64":   "amd64",
+	"x64":     "amd64",
+	"aarch64": "arm64",
+	"arm64":   "arm64",
+	"armv8l":  "arm64",
+	"armv7l":  "armv7",
+	"armv7":   "armv7",
+	"armhf":   "armv7",
+	"arm":     "armv7",
+	"armv6l":  "armv6",
+	"armv6":   "armv6",
+	"i386":    "386",
+	"i486":    "386",
+	"i586":    "386",
+	"i686":    "386",
+	"x86":     "386",
+}
+
+// normalizeArch returns the normalized name of a machine architecture, so
+// configs need no transform table of their own
+func normalizeArch(machine string) string {
+	machine = strings.ToLower(strings.TrimSpace(machine))
+	if arch, ok := archAliases[machine]; ok {
+		return arch
+	}
+	return machine
+}
+
+// templateArchMap renames an architecture for projects whose downloads use
+// other names, as in {{archMap .sink.arch "amd64=x64,arm64=aarch64"}}. The
+// value is normalized first, and keys may be raw or normalized names. With
+// no mapping it only normalizes; with one, an architecture it does not list
+// is an error rather than a download URL that does not exist.
+func templateArchMap(value interface{}, mapping ...string) (string, error) {
+	raw := strings.TrimSpace(fmt.Sprint(value))
+	arch := normalizeArch(raw)
+	if len(mapping) == 0 {
+		return arch, nil
+	}
+	names := map[string]string{}
+	for _, list := range mapping {
+		for _, pair := range strings.Split(list, ",") {
+			from, to, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok || strings.TrimSpace(from) == "" {
+				return "", fmt.Errorf("archMap: invalid mapping '%s' (use from=to, e.g. amd64=x64)", pair)
+			}
+			names[strings.TrimSpace(from)] = strings.TrimSpace(to)
+		}
+	}
+	if name, ok := names[raw]; ok {
+		return name, nil
+	}
+	if name, ok := names[arch]; ok {
+		return name, nil
+	}
+	return "", fmt.Errorf("archMap: no mapping for architecture '%s' (%s)", raw, strings.Join(mapping, ","))
+}
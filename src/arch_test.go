@@ -0,0 +1,72 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestNormalizeArch tests the built-in architecture table
+func TestNormalizeArch(t *testing.T) {
+	tests := map[string]string{
+		"x86_64":   "amd64",
+		"amd64":    "amd64",
+		"aarch64":  "arm64",
+		"arm64":    "arm64",
+		"armv7l":   "armv7",
+		"armv6l":   "armv6",
+		"i686":     "386",
+		"i386":     "386",
+		"X86_64\n": "amd64",
+		"riscv64":  "riscv64",
+		"":         "",
+	}
+	for machine, want := range tests {
+		if got := normalizeArch(machine); got != want {
+			t.Errorf("normalizeArch(%q) = %q, want %q", machine, got, want)
+		}
+	}
+}
+
+// TestTemplateArchMap tests renaming architectures with archMap
+func TestTemplateArchMap(t *testing.T) {
+	tests := []struct {
+		value   string
+		mapping []string
+		want    string
+		err     string
+	}{
+		{"x86_64", nil, "amd64", ""},
+		{"x86_64", []string{"amd64=x64,arm64=aarch64"}, "x64", ""},
+		{"aarch64", []string{"amd64=x64, arm64=aarch64"}, "aarch64", ""},
+		{"armv7l", []string{"armv7l=armhf"}, "armhf", ""},
+		{"i686", []string{"amd64=x64", "386=x86"}, "x86", ""},
+		{"riscv64", []string{"amd64=x64,arm64=aarch64"}, "", "no mapping for architecture 'riscv64'"},
+		{"x86_64", []string{"amd64"}, "", "invalid mapping 'amd64'"},
+	}
+	for _, tt := range tests {
+		got, err := templateArchMap(tt.value, tt.mapping...)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("archMap %s %v: expected error %q, got %q, %v", tt.value, tt.mapping, tt.err, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("archMap %s %v = %q, %v; want %q", tt.value, tt.mapping, got, err, tt.want)
+		}
+	}
+}
+
+// TestSinkArch tests the normalized {{.sink.arch}} helper and archMap in commands
+func TestSinkArch(t *testing.T) {
+	executor := NewExecutor(&MockTransport{responses: map[string]MockResponse{}})
+	executor.context.Arch = "aarch64"
+	facts := executor.stepFacts(InstallStep{Name: "download"}, Facts{"version": "1.2.3"})
+	command, err := executor.interpolate(`curl -LO https://example.com/tool-{{.version}}-linux-{{.sink.arch}}.tar.gz && echo {{archMap .sink.arch "amd64=x64,arm64=aarch64"}}`, facts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if command != "curl -LO https://example.com/tool-1.2.3-linux-arm64.tar.gz && echo aarch64" {
+		t.Errorf("interpolated %q", command)
+	}
+}
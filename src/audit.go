@@ -0,0 +1,71 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// auditTag identifies sink's records in the system log
+const auditTag = "sink"
+
+// AuditRecord describes one command a run executed, for security teams that
+// require command-level audit trails
+type AuditRecord struct {
+	RunID    string
+	Host     string
+	User     string
+	Step     string
+	Command  string // Redacted like transcripts
+	ExitCode int
+	Duration time.Duration
+}
+
+// String formats the record as key=value pairs, quoting values that need it
+func (r AuditRecord) String() string {
+	fields := []struct{ key, value string }{
+		{"run_id", r.RunID},
+		{"host", r.Host},
+		{"user", r.User},
+		{"step", r.Step},
+		{"exit_code", strconv.Itoa(r.ExitCode)},
+		{"duration", r.Duration.Round(time.Millisecond).String()},
+		{"command", r.Command},
+	}
+	parts := make([]string, len(fields))
+	for i, field := range fields {
+		value := field.value
+		if value == "" || strings.ContainsAny(value, " \t\n\"=") {
+			value = strconv.Quote(value)
+		}
+		parts[i] = field.key + "=" + value
+	}
+	return strings.Join(parts, " ")
+}
+
+// AuditLogger writes audit records to the system log
+type AuditLogger interface {
+	Audit(record AuditRecord) error
+	Close() error
+}
+
+// audit logs an executed command when auditing is enabled. A failure to
+// write the audit trail is reported but does not stop the run.
+func (e *Executor) audit(command string, exitCode int, duration time.Duration) {
+	if e.Audit == nil {
+		return
+	}
+	err := e.Audit.Audit(AuditRecord{
+		RunID:    e.runID,
+		Host:     e.context.Host,
+		User:     e.context.User,
+		Step:     e.currentStep,
+		Command:  activeRedactor.Redact(command),
+		ExitCode: exitCode,
+		Duration: duration,
+	})
+	if err != nil && !e.auditFailed {
+		e.auditFailed = true
+		warn(WarningAuditFailed, "Audit log write failed: %v", err)
+	}
+}
@@ -0,0 +1,13 @@
+//go:build windows || plan9
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// newAuditLogger reports that this platform has no syslog
+func newAuditLogger() (AuditLogger, error) {
+	return nil, fmt.Errorf("--audit-log is not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,33 @@
+//go:build !windows && !plan9
+
+package main
+
+import "log/syslog"
+
+// syslogAuditor writes audit records to the local syslog socket, which
+// journald collects on Linux and unified logging on macOS
+type syslogAuditor struct {
+	writer *syslog.Writer
+}
+
+// newAuditLogger connects to the system log
+func newAuditLogger() (AuditLogger, error) {
+	writer, err := syslog.New(syslog.LOG_INFO|syslog.LOG_AUTHPRIV, auditTag)
+	if err != nil {
+		return nil, err
+	}
+	return &syslogAuditor{writer: writer}, nil
+}
+
+// Audit implements AuditLogger. Failed commands are logged as warnings.
+func (a *syslogAuditor) Audit(record AuditRecord) error {
+	if record.ExitCode != 0 {
+		return a.writer.Warning(record.String())
+	}
+	return a.writer.Info(record.String())
+}
+
+// Close implements AuditLogger
+func (a *syslogAuditor) Close() error {
+	return a.writer.Close()
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// recordingAuditor collects audit records
+type recordingAuditor struct {
+	records []AuditRecord
+}
+
+func (a *recordingAuditor) Audit(record AuditRecord) error {
+	a.records = append(a.records, record)
+	return nil
+}
+
+func (a *recordingAuditor) Close() error { return nil }
+
+// TestExecutorAudit tests that every executed command is audited with the
+// step that ran it
+func TestExecutorAudit(t *testing.T) {
+	mock := &MockTransport{responses: map[string]MockResponse{
+		"brew --version":   {exitCode: 127},
+		"brew install jq":  {stdout: "installed"},
+		"systemctl status": {exitCode: 3},
+	}}
+	executor := NewExecutor(mock)
+	auditor := &recordingAuditor{}
+	executor.Audit = auditor
+
+	executor.ExecuteStep(InstallStep{Name: "jq", Step: CheckRemediateStep{Check: "brew --version", OnMissing: []RemediationStep{{Name: "install", Command: "brew install jq"}}}}, nil)
+	executor.ExecuteStep(InstallStep{Name: "status", Step: CommandStep{Command: "systemctl status"}}, nil)
+
+	var got []AuditRecord
+	for _, record := range auditor.records {
+		if record.Step != "" {
+			got = append(got, record)
+		}
+	}
+	want := []struct {
+		step, command string
+		exitCode      int
+	}{
+		{"jq", "brew --version", 127},
+		{"jq", "brew install jq", 0},
+		{"jq", "brew --version", 127}, // Verification after remediating
+		{"status", "systemctl status", 3},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d audited commands, got %+v", len(want), got)
+	}
+	for i, w := range want {
+		if got[i].Step != w.step || got[i].Command != w.command || got[i].ExitCode != w.exitCode {
+			t.Errorf("record %d: got %+v, want %+v", i, got[i], w)
+		}
+		if got[i].RunID != executor.runID {
+			t.Errorf("record %d lacks run context: %+v", i, got[i])
+		}
+	}
+}
+
+// TestAuditRecordString tests the key=value format of audit records
+func TestAuditRecordString(t *testing.T) {
+	record := AuditRecord{RunID: "web01-1", Host: "web01", User: "deploy", Step: "Install jq", Command: `echo "hi"`, ExitCode: 1, Duration: 1500 * time.Millisecond}
+	want := `run_id=web01-1 host=web01 user=deploy step="Install jq" exit_code=1 duration=1.5s command="echo \"hi\""`
+	if got := record.String(); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
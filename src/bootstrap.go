@@ -1,6 +1,7 @@
 package main
 
 import (
+	"crypto/ed25519"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
@@ -9,6 +10,7 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"time"
 )
 
 // bootstrapCommand handles the bootstrap command for loading configs from URLs
@@ -20,14 +22,41 @@ func bootstrapCommand() {
 		os.Exit(1)
 	}
 
+	// A fleet manifest bootstraps many hosts over SSH instead of this one
+	if os.Args[2] == "--manifest" {
+		bootstrapManifestCommand(os.Args[3:])
+		return
+	}
+
 	// Parse flags
 	configSource := os.Args[2]
+	// SINK_* environment variables provide defaults; flags override them
+	env := loadEnvSettings()
 	dryRun := false
-	verbose := false
-	jsonOutput := false
-	platform := ""
+	verbose := env.Verbose
+	jsonOutput := env.JSON
+	platform := env.Platform
+	credentialHelper = env.CredentialHelper
 	sha256Hash := ""
 	skipChecksum := false
+	requirePinned := false
+	tofuStrict := false
+	warningsAsErrors := false
+	auditLog := env.AuditLog
+	checksumsURL := ""
+	var maxDuration time.Duration
+	var splay time.Duration
+	pubkeyPath := ""
+	gitSSHKey := ""
+	snapshot := false
+	force := false
+	strict := false
+	transcriptPath := ""
+	summaryMode := SummaryShort
+	runID := ""
+	var breakAt []string
+	confirmHost := ""
+	variables := map[string]string{}
 
 	for i := 3; i < len(os.Args); i++ {
 		arg := os.Args[i]
@@ -43,31 +72,209 @@ func bootstrapCommand() {
 			jsonOutput = true
 		case arg == "--skip-checksum":
 			skipChecksum = true
+		case arg == "--require-pinned":
+			requirePinned = true
+		case arg == "--tofu-strict":
+			tofuStrict = true
+		case arg == "--warnings-as-errors":
+			warningsAsErrors = true
+		case arg == "--audit-log":
+			auditLog = true
+		case arg == "--snapshot":
+			snapshot = true
+		case arg == "--force":
+			force = true
+		case arg == "--strict":
+			strict = true
 		case arg == "--platform" && i+1 < len(os.Args):
 			platform = os.Args[i+1]
 			i++
 		case arg == "--sha256" && i+1 < len(os.Args):
 			sha256Hash = os.Args[i+1]
 			i++
+		case arg == "--max-duration" && i+1 < len(os.Args):
+			d, err := parseMaxDuration(os.Args[i+1])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			maxDuration = d
+			i++
+		case arg == "--splay" && i+1 < len(os.Args):
+			d, err := parseSplay(os.Args[i+1])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			splay = d
+			i++
+		case arg == "--checksums-url" && i+1 < len(os.Args):
+			checksumsURL = os.Args[i+1]
+			i++
+		case arg == "--pubkey" && i+1 < len(os.Args):
+			pubkeyPath = os.Args[i+1]
+			i++
+		case arg == "--git-ssh-key" && i+1 < len(os.Args):
+			gitSSHKey = os.Args[i+1]
+			i++
+		case arg == "--credential-helper" && i+1 < len(os.Args):
+			credentialHelper = os.Args[i+1]
+			i++
+		case arg == "--transcript" && i+1 < len(os.Args):
+			transcriptPath = os.Args[i+1]
+			i++
+		case arg == "--state-dir" && i+1 < len(os.Args):
+			dirOverrides.State = os.Args[i+1]
+			i++
+		case arg == "--cache-dir" && i+1 < len(os.Args):
+			dirOverrides.Cache = os.Args[i+1]
+			i++
+		case arg == "--break-at" && i+1 < len(os.Args):
+			breakAt = append(breakAt, os.Args[i+1])
+			i++
+		case arg == "--confirm-host" && i+1 < len(os.Args):
+			confirmHost = os.Args[i+1]
+			i++
+		case arg == "--var" && i+1 < len(os.Args):
+			name, value, err := parseVarFlag(os.Args[i+1])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			variables[name] = value
+			i++
+		case arg == "--run-id" && i+1 < len(os.Args):
+			if err := validateRunID(os.Args[i+1]); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			runID = os.Args[i+1]
+			i++
+		case (arg == "--summary" && i+1 < len(os.Args)) || strings.HasPrefix(arg, "--summary="):
+			value, ok := strings.CutPrefix(arg, "--summary=")
+			if !ok {
+				value = os.Args[i+1]
+				i++
+			}
+			mode, err := parseSummaryMode(value)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			summaryMode = mode
 		default:
 			fmt.Fprintf(os.Stderr, "Unknown option: %s\n", arg)
 			os.Exit(1)
 		}
 	}
 
+	// Without a state directory there is nowhere to remember sources, which
+	// only matters when --tofu-strict asks for the check
+	trust, err := NewTrustStore(tofuStrict)
+	if err != nil {
+		if tofuStrict {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		warn(WarningTrustDisabled, "Trust-on-first-use checks disabled: %v", err)
+	}
+
+	var publicKey ed25519.PublicKey
+	if pubkeyPath != "" {
+		var err error
+		publicKey, err = loadPublicKey(pubkeyPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	exitIfHostDisabled(nil, jsonOutput, force)
+
+	// Spread fleet-wide runs out before touching the network
+	waitSplay(splay, jsonOutput)
+
+	// The time budget starts with the downloads, which --max-duration and
+	// an interrupt stop at once
+	beginRun(maxDuration)
+
+	// Load the checksum manifest first so it can verify the config itself
+	var checksums ChecksumManifest
+	if checksumsURL != "" {
+		var err error
+		checksums, err = loadChecksumManifest(checksumsURL, publicKey)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading checksums: %v\n", err)
+			os.Exit(runExitCode())
+		}
+		fmt.Printf("✅ Loaded %d checksums from %s\n", len(checksums), checksumsURL)
+	}
+
 	// Load config from URL or file
 	var config *Config
-	var err error
 
-	if strings.HasPrefix(configSource, "http://") || strings.HasPrefix(configSource, "https://") {
-		config, err = loadConfigFromURL(configSource, sha256Hash, skipChecksum)
+	if strings.HasPrefix(configSource, "github:") {
+		var resolved *ResolvedGitHubSource
+		resolved, err = resolveGitHubBootstrapSource(configSource)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error loading config from URL: %v\n", err)
+			fmt.Fprintf(os.Stderr, "Error resolving GitHub source: %v\n", err)
+			os.Exit(runExitCode())
+		}
+		configSource = resolved.URL
+		if sha256Hash == "" && resolved.Checksum != "" {
+			sha256Hash = resolved.Checksum
+			fmt.Printf("✅ Using SHA256 from release %s\n", resolved.Tag)
+		}
+		if checksums == nil {
+			checksums = resolved.Manifest
+		}
+	}
+
+	if strings.HasPrefix(configSource, "git+") {
+		var src *GitSource
+		src, err = ParseGitSource(configSource)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
+		config, err = loadConfigFromGit(src, GitLoadOptions{
+			SHA256:        sha256Hash,
+			RequirePinned: requirePinned,
+			Checksums:     checksums,
+			PublicKey:     publicKey,
+			Strict:        strict,
+			SSHKey:        gitSSHKey,
+			Trust:         trust,
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading config from git: %v\n", err)
+			os.Exit(runExitCode())
+		}
+	} else if strings.HasPrefix(configSource, "http://") || strings.HasPrefix(configSource, "https://") {
+		config, err = loadConfigFromURLWithOptions(configSource, URLLoadOptions{
+			SHA256:        sha256Hash,
+			SkipChecksum:  skipChecksum,
+			RequirePinned: requirePinned,
+			Checksums:     checksums,
+			PublicKey:     publicKey,
+			Strict:        strict,
+			JSONOutput:    jsonOutput,
+			Trust:         trust,
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading config from URL: %v\n", err)
+			os.Exit(runExitCode())
+		}
 	} else {
 		// Local file
-		config, err = LoadConfig(configSource)
+		if publicKey != nil {
+			if err := verifyFileSignature(configSource, publicKey); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("✅ Signature verified\n")
+		}
+		config, err = LoadConfigWithOptions(configSource, LoadOptions{Strict: strict})
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
 			os.Exit(1)
@@ -75,17 +282,81 @@ func bootstrapCommand() {
 	}
 
 	// Now execute using the same logic as executeCommand
-	executeConfigWithOptions(config, dryRun, verbose, jsonOutput, platform)
+	executeConfigWithOptions(config, ExecuteOptions{
+		DryRun:           dryRun,
+		Verbose:          verbose,
+		JSONOutput:       jsonOutput,
+		PlatformOverride: platform,
+		Checksums:        checksums,
+		MaxDuration:      maxDuration,
+		Snapshot:         snapshot,
+		Transcript:       transcriptPath,
+		Summary:          summaryMode,
+		RunID:            runID,
+		ConfigSource:     configSource,
+		Host:             env.Host,
+		BreakAt:          breakAt,
+		ConfirmHost:      confirmHost,
+		Variables:        variables,
+		WarningsAsErrors: warningsAsErrors,
+		AuditLog:         auditLog,
+	})
+
+	// Only a successful run makes a source's content trusted
+	if trust != nil && !dryRun {
+		if err := trust.Record(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+}
+
+// resolveGitHubBootstrapSource resolves a github:owner/repo//path@version source
+// to a raw URL pinned to a release tag, along with the release's checksums.
+func resolveGitHubBootstrapSource(source string) (*ResolvedGitHubSource, error) {
+	src, err := ParseGitHubSource(source)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("🔎 Resolving %s/%s@%s via GitHub releases\n", src.Owner, src.Repo, src.Constraint)
+	resolved, err := resolveGitHubSource(src)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("📌 Resolved to tag '%s'\n", resolved.Tag)
+
+	return resolved, nil
+}
+
+// URLLoadOptions controls verification when loading a config from a URL
+type URLLoadOptions struct {
+	SHA256        string            // Expected SHA256 of the config (--sha256)
+	SkipChecksum  bool              // Bypass checksum verification (--skip-checksum)
+	RequirePinned bool              // Refuse mutable refs and unchecksummed URLs (--require-pinned)
+	Checksums     ChecksumManifest  // Trusted manifest covering the config and files it references (--checksums-url)
+	PublicKey     ed25519.PublicKey // Require a valid detached signature at <url>.sig (--pubkey)
+	Strict        bool              // Reject keys the schema does not define (--strict)
+	JSONOutput    bool              // Report download progress as JSON events instead of a progress bar
+	Trust         *TrustStore       // Compare with the checksum first trusted for the URL (nil disables)
 }
 
-// loadConfigFromURL downloads and parses a Sink configuration from a URL.
+// loadConfigFromURL downloads a config without enforcing a pinning policy
+// beyond what the config itself declares. See loadConfigFromURLWithOptions.
+func loadConfigFromURL(url string, expectedSHA256 string, skipChecksum bool) (*Config, error) {
+	return loadConfigFromURLWithOptions(url, URLLoadOptions{SHA256: expectedSHA256, SkipChecksum: skipChecksum})
+}
+
+// loadConfigFromURLWithOptions downloads and parses a Sink configuration from a URL.
 // This function handles secure remote configuration loading with checksum verification,
 // GitHub URL pinning validation, and automatic security measures.
 //
 // Parameters:
 //   - url: The URL to download configuration from (HTTP/HTTPS supported)
-//   - expectedSHA256: Optional SHA256 checksum for verification (64-char hex)
-//   - skipChecksum: If true, bypasses checksum verification (not recommended)
+//   - opts.SHA256: Optional SHA256 checksum for verification (64-char hex)
+//   - opts.SkipChecksum: If true, bypasses checksum verification (not recommended)
+//   - opts.RequirePinned: If true, refuses sources that are neither pinned nor checksummed
+//   - opts.Checksums: Optional manifest; its entry for the config verifies the
+//     download (but does not pin it), and it covers unpinned dependencies
 //
 // Returns:
 //   - *Config: Parsed and validated Sink configuration
@@ -94,28 +365,68 @@ func bootstrapCommand() {
 // Security Model:
 //   - HTTP URLs: Require explicit checksum (mandatory for untrusted transport)
 //   - HTTPS URLs: Checksum optional but recommended
-//   - GitHub URLs: Automatic pin validation and security warnings
+//   - GitHub, GitLab and Bitbucket URLs: Automatic pin validation and security warnings
 //   - Auto-checksum: Attempts to fetch .sha256 file automatically
+//   - Pinning policy: --require-pinned or "policy.require_pinned" in the
+//     config rejects mutable refs and unchecksummed URLs
 //
 // Process Flow:
-//  1. Parse and validate GitHub URLs (if applicable)
-//  2. Attempt automatic checksum fetch if not provided
-//  3. Enforce security requirements (HTTP + checksum)
-//  4. Download configuration with 30-second timeout
-//  5. Verify checksum if provided
-//  6. Parse and validate JSON configuration
+//  1. Parse and validate forge URLs (if applicable)
+//  2. Enforce the pinning policy when requested on the command line
+//  3. Attempt automatic checksum fetch if not provided
+//  4. Enforce security requirements (HTTP + checksum)
+//  5. Download configuration with 30-second timeout
+//  6. Verify checksum if provided
+//  7. Parse and validate JSON configuration
+//  8. Enforce the pinning policy declared by the config itself
 //
 // Example Usage:
 //
-//	config, err := loadConfigFromURL(
+//	config, err := loadConfigFromURLWithOptions(
 //	    "https://raw.githubusercontent.com/org/configs/v1.0.0/prod.json",
-//	    "a1b2c3d4...", false)
-func loadConfigFromURL(url string, expectedSHA256 string, skipChecksum bool) (*Config, error) {
-	// Check if it's a GitHub URL
-	githubInfo, isGitHub := ParseGitHubURL(url)
+//	    URLLoadOptions{SHA256: "a1b2c3d4...", RequirePinned: true})
+func loadConfigFromURLWithOptions(url string, opts URLLoadOptions) (*Config, error) {
+	config, _, err := fetchConfigFromURL(url, opts)
+	return config, err
+}
+
+// fetchConfigFromURL is loadConfigFromURLWithOptions, also returning the
+// verified config document as downloaded
+func fetchConfigFromURL(url string, opts URLLoadOptions) (*Config, []byte, error) {
+	expectedSHA256 := opts.SHA256
+	skipChecksum := opts.SkipChecksum
+
+	// A checksum manifest covers the config itself
+	if expectedSHA256 == "" && opts.Checksums != nil {
+		name := configManifestName(url)
+		checksum, ok := opts.Checksums.Lookup(name)
+		if !ok {
+			return nil, nil, fmt.Errorf("checksum manifest has no entry for %s", name)
+		}
+		expectedSHA256 = checksum
+		fmt.Printf("✅ Using SHA256 for %s from checksum manifest\n", name)
+	}
+
+	// Check if it's a GitHub, GitLab or Bitbucket URL
+	githubInfo, isGitHub := ParseRepoURL(url)
 	if isGitHub {
 		validateGitHubPin(githubInfo)
+	}
+
+	// Decide pinning from --sha256 and the URL alone: neither a manifest
+	// entry nor a .sha256 served from the same mutable location pins
+	// anything
+	pinErr := checkPinnedSource(url, githubInfo, opts.SHA256)
+	if opts.RequirePinned && pinErr != nil {
+		return nil, nil, fmt.Errorf("--require-pinned: %w", pinErr)
+	}
+
+	// A .sha256 fetched from beside the config does not explain a change
+	explicitSHA256 := expectedSHA256 != ""
 
+	// API URLs name the file in the path or query, so only plain file URLs
+	// have a .sha256 alongside
+	if isGitHub && !strings.ContainsAny(url, "?#") {
 		// Try to auto-fetch checksum from .sha256 file if not provided
 		if expectedSHA256 == "" && !skipChecksum {
 			checksumURL := url + ".sha256"
@@ -126,76 +437,145 @@ func loadConfigFromURL(url string, expectedSHA256 string, skipChecksum bool) (*C
 		}
 	}
 
-	// Validate security requirements
-	if strings.HasPrefix(url, "http://") && expectedSHA256 == "" && !skipChecksum {
-		return nil, fmt.Errorf("HTTP URLs require --sha256 checksum or --skip-checksum flag for security")
+	// Validate security requirements (a signature protects integrity over HTTP too)
+	if strings.HasPrefix(url, "http://") && opts.SHA256 == "" && !skipChecksum && opts.PublicKey == nil {
+		return nil, nil, fmt.Errorf("HTTP URLs require --sha256 checksum or --skip-checksum flag for security")
 	}
 
 	// Download the config
 	fmt.Printf("📥 Downloading config from %s\n", url)
-	client := &http.Client{
-		Timeout: DefaultHTTPTimeout,
-	}
-
-	resp, err := client.Get(url)
+	resp, err := httpGet(url, httpTimeout(DefaultHTTPTimeout))
 	if err != nil {
-		return nil, fmt.Errorf("failed to download: %v", err)
+		return nil, nil, fmt.Errorf("failed to download: %v", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
+		return nil, nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
 	}
 
-	// Read the body
-	body, err := io.ReadAll(resp.Body)
+	// Read the body, reporting progress for large or slow downloads
+	progress := newProgressReader(resp.Body, url, resp.ContentLength, ProgressUpdateInterval, downloadReporter(opts.JSONOutput))
+	body, err := io.ReadAll(progress)
+	err = runStopped(runCtx, err)
+	progress.finish(err)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %v", err)
+		return nil, nil, fmt.Errorf("failed to read response: %v", err)
 	}
 
 	// Verify checksum if provided
 	if expectedSHA256 != "" {
 		if err := verifyChecksum(body, expectedSHA256); err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		fmt.Printf("✅ SHA256 verified\n")
-	} else if strings.HasPrefix(url, "https://") {
+	} else if strings.HasPrefix(url, "https://") && opts.PublicKey == nil {
 		fmt.Printf("✅ Downloaded via HTTPS (TLS verified)\n")
 	}
+	if expectedSHA256 == "" && opts.PublicKey == nil {
+		if skipChecksum {
+			warn(WarningChecksumSkipped, "Checksum verification skipped (--skip-checksum)")
+		} else if !isGitHub {
+			warn(WarningNoChecksum, "%s has no checksum (pass --sha256 or --checksums-url to verify its content)", url)
+		}
+	}
+
+	// Verify the detached signature before trusting anything in the body
+	if opts.PublicKey != nil {
+		sigURL := signatureURL(url)
+		signature, err := fetchText(sigURL)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to fetch signature %s: %w", sigURL, err)
+		}
+		if err := verifySignature(body, signature, opts.PublicKey); err != nil {
+			return nil, nil, err
+		}
+		fmt.Printf("✅ Signature verified\n")
+	}
+
+	// Expand library steps; a fetched config has no lock file, so its
+	// dependencies must pin their sha256 or be listed in the manifest
+	source := body
+	body = stripJSONC(body)
+	composed, err := composeConfig(body, LoadOptions{Checksums: opts.Checksums})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to compose config: %w", err)
+	}
 
 	// Parse JSON
 	var config Config
-	if err := json.Unmarshal(body, &config); err != nil {
-		return nil, fmt.Errorf("invalid JSON: %v", err)
+	if err := json.Unmarshal(composed, &config); err != nil {
+		return nil, nil, fmt.Errorf("invalid JSON: %w", locateSyntaxError(url, source, err))
+	}
+
+	if err := checkDuplicateKeys(body); err != nil {
+		return nil, nil, fmt.Errorf("config validation failed: %w", err)
+	}
+
+	if opts.Strict {
+		if err := checkUnknownFields(body); err != nil {
+			return nil, nil, fmt.Errorf("strict parsing failed: %w", err)
+		}
 	}
 
 	// Parse install steps into type-safe variants
 	for i := range config.Platforms {
 		if err := parsePlatformSteps(&config.Platforms[i]); err != nil {
-			return nil, fmt.Errorf("platform %s: %w", config.Platforms[i].Name, err)
+			return nil, nil, fmt.Errorf("platform %s: %w", config.Platforms[i].Name, err)
 		}
 	}
 
 	// Validate the configuration
 	if err := ValidateConfig(&config); err != nil {
-		return nil, fmt.Errorf("config validation failed: %w", err)
+		return nil, nil, fmt.Errorf("config validation failed: %w", err)
+	}
+
+	applyStepDefaults(&config)
+
+	if config.Policy != nil && config.Policy.RequirePinned && pinErr != nil {
+		return nil, nil, fmt.Errorf("config policy requires a pinned source: %w", pinErr)
+	}
+
+	if opts.Trust != nil {
+		if err := opts.Trust.Check(url, body, config.Version, explicitSHA256); err != nil {
+			return nil, nil, err
+		}
 	}
 
 	fmt.Printf("✅ Config loaded and validated\n")
-	return &config, nil
+	return &config, body, nil
+}
+
+// checkPinnedSource reports why a URL is not an immutable source, or nil if it is.
+// A source is pinned when it is a forge tag, commit or release URL, or when the
+// caller supplied an explicit checksum for it.
+func checkPinnedSource(url string, githubInfo *GitHubURLInfo, expectedSHA256 string) error {
+	if expectedSHA256 != "" {
+		return nil
+	}
+	if githubInfo != nil {
+		if githubInfo.IsPinned || githubInfo.PinType == GitHubPinRelease {
+			return nil
+		}
+		if githubInfo.IsMutable {
+			return fmt.Errorf("%s ref '%s' is a mutable branch (pin to a tag or commit, or pass --sha256)", githubInfo.forge(), githubInfo.Ref)
+		}
+		return fmt.Errorf("%s ref '%s' is not a recognizable tag or commit (pass --sha256)", githubInfo.forge(), githubInfo.Ref)
+	}
+	return fmt.Errorf("%s has no checksum (pass --sha256)", url)
 }
 
-// validateGitHubPin validates GitHub URL pinning and displays security warnings.
-// This function analyzes GitHub URLs to determine if they use pinned (immutable)
+// validateGitHubPin validates forge URL pinning and displays security warnings.
+// This function analyzes GitHub, GitLab and Bitbucket URLs to determine if they use pinned (immutable)
 // or mutable references, providing security guidance to users.
 //
 // Parameters:
-//   - info: Parsed GitHub URL information containing owner, repo, ref, and pin type
+//   - info: Parsed forge URL information containing forge, owner, repo, ref, and pin type
 //
 // Pin Types and Security Levels:
 //   - GitHubPinTag: Semantic version tags (v1.0.0) - Recommended ✅
 //   - GitHubPinCommit: Full commit SHAs - Highest security ✅✅
-//   - GitHubPinRelease: GitHub release downloads - Recommended ✅✅
+//   - GitHubPinRelease: GitHub or GitLab release downloads - Recommended ✅✅
 //   - GitHubPinBranch: Branch names (main, develop) - Mutable, warns user ⚠️
 //
 // Output Format:
@@ -206,23 +586,24 @@ func loadConfigFromURL(url string, expectedSHA256 string, skipChecksum bool) (*C
 // This function is essential for supply chain security, helping users
 // understand the immutability guarantees of their configuration sources.
 func validateGitHubPin(info *GitHubURLInfo) {
+	forge := info.forge()
 	switch info.PinType {
 	case GitHubPinTag:
-		fmt.Printf("✅ GitHub: Pinned to release tag '%s' ✓\n", info.Ref)
+		fmt.Printf("✅ %s: Pinned to release tag '%s' ✓\n", forge, info.Ref)
 	case GitHubPinCommit:
 		shortRef := info.Ref
 		if len(shortRef) > 8 {
 			shortRef = shortRef[:8] + "..."
 		}
-		fmt.Printf("✅ GitHub: Pinned to commit '%s' ✓\n", shortRef)
+		fmt.Printf("✅ %s: Pinned to commit '%s' ✓\n", forge, shortRef)
 	case GitHubPinRelease:
-		fmt.Printf("✅ GitHub Release: Pinned to '%s' ✓✓\n", info.Ref)
+		fmt.Printf("✅ %s Release: Pinned to '%s' ✓✓\n", forge, info.Ref)
 	case GitHubPinBranch:
-		fmt.Printf("⚠️  GitHub: Using MUTABLE branch '%s' (content can change)\n", info.Ref)
+		warn(WarningMutableRef, "%s: Using MUTABLE branch '%s' (content can change)", forge, info.Ref)
 	default:
-		fmt.Printf("ℹ️  GitHub: Using ref '%s' (assuming tag or branch)\n", info.Ref)
+		fmt.Printf("ℹ️  %s: Using ref '%s' (assuming tag or branch)\n", forge, info.Ref)
 	}
-	fmt.Printf("   Repository: %s/%s\n", info.Owner, info.Repo)
+	fmt.Printf("   Repository: %s\n", info.repository())
 }
 
 // fetchChecksum attempts to download a SHA256 checksum file from a URL.
@@ -252,11 +633,7 @@ func validateGitHubPin(info *GitHubURLInfo) {
 //	checksum, err := fetchChecksum("https://releases.example.com/v1.0.0/app.tar.gz")
 //	// Fetches from: https://releases.example.com/v1.0.0/app.tar.gz.sha256
 func fetchChecksum(url string) (string, error) {
-	client := &http.Client{
-		Timeout: ChecksumHTTPTimeout,
-	}
-
-	resp, err := client.Get(url)
+	resp, err := httpGet(url, httpTimeout(ChecksumHTTPTimeout))
 	if err != nil {
 		return "", err
 	}
@@ -283,8 +660,12 @@ func fetchChecksum(url string) (string, error) {
 // verifyChecksum verifies the SHA256 checksum of data
 func verifyChecksum(data []byte, expectedHex string) error {
 	hash := sha256.Sum256(data)
-	actualHex := hex.EncodeToString(hash[:])
+	return compareSHA256(hex.EncodeToString(hash[:]), expectedHex)
+}
 
+// compareSHA256 compares a computed SHA256 with the expected one, ignoring
+// case and surrounding whitespace
+func compareSHA256(actualHex, expectedHex string) error {
 	expectedHex = strings.TrimSpace(strings.ToLower(expectedHex))
 	actualHex = strings.ToLower(actualHex)
 
@@ -302,16 +683,56 @@ sink bootstrap - Bootstrap from URL or local file
 
 Usage:
   sink bootstrap <source> [options]
+  sink bootstrap --manifest <fleet.json> [fleet options]
 
 Arguments:
-  source              Config file URL or local path
-                      Supports: http://, https://, file paths
+  source              Config file URL, GitHub release spec, git repository
+                      path, or local path
+                      Supports: http://, https://, github:, git+, file paths
 
 Options:
   --dry-run          Show what would be executed without running
   --platform <os>    Override platform detection (darwin, linux, etc.)
   --sha256 <hash>    Expected SHA256 checksum (required for HTTP)
   --skip-checksum    Skip checksum verification (not recommended)
+  --require-pinned   Refuse mutable refs and URLs without a checksum
+  --tofu-strict      Fail if a source changed since it was first trusted
+                     without a version change (see Trust on First Use)
+  --warnings-as-errors
+                     Exit 1 if the run recorded warnings such as a mutable
+                     ref or a missing checksum (for CI)
+  --max-duration <d> Overall time budget for the run (exit 124 if exceeded)
+  --strict           Reject config keys the schema does not define (typos)
+  --force            Run even if /etc/sink/disabled or ~/.sink/skip exists
+  --snapshot         Report packages, tool versions and disk usage changed
+                     by the run
+  --splay <d>        Sleep a random duration up to <d> before starting, so a
+                     fleet bootstrapping from one URL does not stampede it
+  --pubkey <path>    Require a valid Ed25519 signature (<source>.sig) made
+                     by "sink sign" with the matching private key
+  --transcript <f>   Write a markdown transcript of the run to <f>
+  --audit-log        Log every executed command to syslog/journald (see
+                     sink execute --help)
+  --state-dir <dir>  Keep the trust store and other state in <dir>
+  --cache-dir <dir>  Keep cached downloads in <dir>
+  --summary <mode>   End-of-run step table: short (default), wide or none
+  --run-id <id>      Use <id> as the run ID instead of <host>-<uuidv7>
+  --break-at <step>  Pause before the named step with a debugging prompt
+                     (repeatable; see sink execute --help)
+  --var <name>=<value>
+                     Set a config variable (repeatable)
+  --confirm-host <host>
+                     Answer the confirmation prompt by naming this host
+                     (needed for configs marked "danger" with --json)
+  --git-ssh-key <key>
+                     Private key for git+ssh:// sources (default: ssh's
+                     own keys and agent)
+  --credential-helper <name>
+                     Ask sink-credential-<name> for credentials for HTTPS
+                     downloads (see Credential Helpers)
+  --checksums-url <url>
+                     SHA256SUMS manifest covering the config and the files
+                     it references (see Checksum Manifests)
   -v, --verbose      Enable verbose output for debugging
   --json             Output execution events as JSON to stdout
   -h, --help         Show this help message
@@ -319,21 +740,151 @@ Options:
 Description:
   The bootstrap command loads configuration from URLs or local files and
   executes the installation steps. It provides secure remote config loading
-  with GitHub URL pinning validation and checksum verification.
+  with forge URL pinning validation and checksum verification.
 
-GitHub URL Pinning:
-  Bootstrap automatically detects and validates GitHub URLs:
+Forge URL Pinning:
+  Bootstrap automatically detects and validates GitHub, GitLab (gitlab.com
+  or self-hosted) and Bitbucket (Cloud or Server) file URLs:
+
+     https://raw.githubusercontent.com/org/repo/<ref>/config.json
+     https://gitlab.example.com/group/repo/-/raw/<ref>/config.json
+     https://gitlab.example.com/api/v4/projects/<id>/repository/files/config.json/raw?ref=<ref>
+     https://bitbucket.org/org/repo/raw/<ref>/config.json
+     https://bitbucket.example.com/projects/OPS/repos/repo/raw/config.json?at=<ref>
 
   ✅ Pinned (Immutable - Recommended):
      - Semantic version tags: v1.0.0, v2.1.3-rc.1
      - Commit SHAs: abc123def456... (40 chars) or abc123d (7+ chars)
+     - Explicit tags: refs/tags/<name> (Bitbucket Server at=, GitLab ref=)
      - GitHub Releases: /releases/download/v1.0.0/config.json
+     - GitLab Releases: /-/releases/v1.0.0/downloads/config.json
 
   ⚠️  Mutable (Content Can Change):
-     - Branch names: main, master, develop, staging
+     - Branch names: main, master, develop, staging, refs/heads/<name>
+     - No ref at all (the default branch)
 
   Auto-checksum: If a .sha256 file exists alongside the config,
-  it will be automatically fetched and verified.
+  it will be automatically fetched and verified (not for URLs with a
+  query string, such as API URLs).
+
+Environment:
+  SINK_PLATFORM, SINK_VERBOSE and SINK_JSON set defaults for --platform,
+  --verbose and --json (flags win). SINK_HTTP_TIMEOUT overrides the timeout
+  for downloading the config and its checksums (e.g., 2m).
+
+Credential Helpers:
+  Private config hosts can be reached without putting tokens in flags or
+  the environment. With --credential-helper <name> (or
+  SINK_CREDENTIAL_HELPER=<name>), sink runs "sink-credential-<name> get"
+  once per host before an HTTPS download, using git's credential protocol.
+  A name containing "/" is run as a path. The helper reads:
+
+     protocol=https
+     host=configs.example.com
+     path=prod/setup.json
+
+  followed by a blank line, and prints either "token=<token>" (sent as a
+  Bearer token) or "username=<user>" and "password=<secret>" (Basic auth).
+  Printing nothing sends the request without credentials; a non-zero exit
+  aborts the bootstrap. Plain HTTP URLs never get credentials.
+
+Checksum Manifests:
+  --checksums-url points at a SHA256SUMS-style file ("<sha256>  <file>" per
+  line, as written by sha256sum). It must be fetched over HTTPS from a
+  release or a tag or commit URL, or be signed (<url>.sig, checked with
+  --pubkey). The config is verified against its entry, as are step
+  libraries without a sha256 and the relative copy sources and stdin_files
+  of the steps; a file the manifest does not list fails its step. A
+  manifest entry does not pin the config: HTTP configs still need --sha256
+  or --pubkey, and --require-pinned a pinned URL or --sha256.
+
+  Scripts and archives the steps download with their own commands (curl,
+  wget, ...) are checked by the step, using the checksum template function:
+
+     "command": "curl -fsSLo install.sh https://example.com/install.sh && echo '{{checksum \"install.sh\"}}  install.sh' | sha256sum -c"
+
+  Files the steps did not download themselves are checked the same way
+  with a verify_checksum step or the verifySha256 template function:
+
+     "verify_checksum": {"path": "/opt/agent/agent", "sha256": "{{checksum \"agent\"}}"}
+
+  github: sources use the release's SHA256SUMS asset automatically.
+
+Pinning Policy:
+  --require-pinned turns the mutable-ref warning into an error: only GitHub,
+  GitLab or Bitbucket tag/commit/release URLs or sources with an explicit
+  --sha256 (or a release checksum for github: sources) are accepted. A
+  config can demand the same for itself:
+
+     "policy": { "require_pinned": true }
+
+Trust on First Use:
+  After the first successful (non-dry) run from a URL or git+ source, its
+  config's SHA256 and "version" are recorded in
+  $SINK_CACHE_DIR/trusted_sources.json. Later downloads are compared with
+  it: a changed config with a changed "version" is accepted and recorded,
+  but a change without a version change is reported as possible tampering
+  and the original checksum stays trusted. --tofu-strict makes that an
+  error. To accept new content, pass its checksum with --sha256.
+
+Fleet Manifests:
+  --manifest bootstraps groups of hosts over SSH, like sink remote deploy,
+  each group from its own config:
+
+     {
+       "parallel": 8,
+       "groups": {
+         "web": {"hosts": ["deploy@web[01:06].lab"], "config": "https://example.com/web.json",
+                 "sha256": "<64 hex characters>"},
+         "db":  {"hosts": ["deploy@db-a.lab", "deploy@db-b.lab"], "config": "db.json"}
+       }
+     }
+
+  Each distinct config is downloaded and verified once on this machine,
+  then transferred to every host of its groups. Local configs are relative
+  to the manifest and bring their "files" section. Hosts run "parallel" at
+  a time (default 4) with their output prefixed by host name, and
+  {{.sink.host_index}} and {{.sink.host_count}} count within the group.
+  One confirmation here answers the prompt on every host.
+
+  Each host's output is also written to hosts/<host>.log in a run
+  directory (deploy-<run id> in the log directory, or --run-dir), with
+  results.json, and the run ends with a matrix of every host's status.
+  Every host must succeed unless --quorum <n> accepts n successes.
+
+  Fleet options: --dry-run, --yes, --parallel <n>, --binary <path>,
+  --var <name>=<value>, --no-cleanup, --require-pinned, --quorum <n>,
+  --all-must-succeed, --run-dir <dir>
+
+Warnings:
+  Mutable refs, missing or skipped checksums and changed sources are
+  printed where they happen and repeated after the summary (or as a
+  "warnings" event with --json), so they cannot scroll away.
+  --warnings-as-errors fails the run when there are any.
+
+GitHub Release Sources:
+  github:owner/repo//path/config.json@<version> queries the GitHub releases
+  API and pins the config to a release tag. <version> may be:
+     - latest-release (default): the latest published release
+     - ^1.2, ~1.2, >=1.0.0,<2.0.0: highest release matching the constraint
+     - v1.2.3: an exact release tag
+
+  The checksum is taken from a <file>.sha256 or SHA256SUMS asset attached
+  to the release. Set GITHUB_TOKEN to raise API rate limits.
+
+Git Sources:
+  git+<repo>//path/config.json@<ref> fetches the config over the git
+  protocol instead of HTTP, for self-hosted GitLab or Gitea instances whose
+  raw file URLs need a browser session. <repo> is an https://, ssh:// or
+  file:// repository URL and <ref> a tag, branch or full commit SHA (the
+  default branch when omitted). Only the one commit is fetched, without
+  history, and only the config's file contents are downloaded.
+
+  Authentication is git's own: ssh keys and agent for ssh://, credential
+  helpers for https://. Prompts are disabled, so missing credentials fail
+  the bootstrap instead of hanging. Tags and commits count as pinned for
+  --require-pinned; with --pubkey the signature is read from <path>.sig
+  in the same commit.
 
 Security Model:
   Source Type   | SHA256 Required? | Verification
@@ -341,15 +892,25 @@ Security Model:
   Local file    | No               | Trusted source
   HTTPS URL     | No (recommended) | TLS certificate
   HTTP URL      | YES              | SHA256 checksum
-  GitHub HTTPS  | No (auto-fetch)  | TLS + optional SHA256
+  Forge HTTPS   | No (auto-fetch)  | TLS + optional SHA256
+  git+ source   | No               | git transport + optional SHA256
 
 Examples:
+  # Bootstrap from the latest GitHub release
+  sink bootstrap github:org/configs//prod.json@latest-release
+
+  # Bootstrap from the newest 1.x release
+  sink bootstrap github:org/configs//prod.json@^1.2
+
   # Bootstrap from GitHub (pinned version)
   sink bootstrap https://raw.githubusercontent.com/org/configs/v1.0.0/prod.json
 
   # Bootstrap from GitHub commit
   sink bootstrap https://raw.githubusercontent.com/org/configs/abc123def/prod.json
 
+  # Bootstrap from a GitLab tag
+  sink bootstrap https://gitlab.example.com/ops/configs/-/raw/v1.0.0/prod.json
+
   # Bootstrap from HTTPS with auto-checksum
   sink bootstrap https://configs.example.com/setup.json
 
@@ -357,6 +918,22 @@ Examples:
   sink bootstrap http://configs.example.com/setup.json \
     --sha256 a3b2c1d4e5f6...
 
+  # Verify the config and the files it references against one manifest
+  sink bootstrap https://github.com/org/configs/releases/download/v2.0.0/setup.json \
+    --checksums-url https://github.com/org/configs/releases/download/v2.0.0/SHA256SUMS
+
+  # Production: refuse anything not pinned
+  sink bootstrap https://raw.githubusercontent.com/org/configs/v1.0.0/prod.json \
+    --require-pinned
+
+  # Fail if a branch URL silently changed since the last successful run
+  sink bootstrap https://raw.githubusercontent.com/org/configs/main/prod.json \
+    --tofu-strict
+
+  # Bootstrap from a self-hosted GitLab over ssh, pinned to a tag
+  sink bootstrap git+ssh://git@gitlab.example.com/ops/configs//lab/config.json@v1.2.0 \
+    --git-ssh-key ~/.ssh/deploy_key
+
   # Bootstrap from local file
   sink bootstrap config.json
 
@@ -372,11 +949,18 @@ Examples:
 Exit Codes:
   0    Success
   1    Error (download failed, validation failed, execution failed)
+  124  --max-duration exceeded (the budget includes the download)
+  130  Interrupted (Ctrl-C or SIGTERM); downloads stop at once
 
 Output:
   Bootstrap shows download progress, GitHub pin validation, checksum
   verification, and then the standard execution output with step progress.
 
+  Slow or large downloads draw a progress bar on stderr. With --json, the
+  download is reported as {"event": "download", ...} objects on stdout with
+  status started/progress/completed/failed, bytes, total, percent and
+  bytes_per_sec.
+
 Related Commands:
   sink execute    - Execute a local config file
   sink remote     - Deploy to remote hosts via SSH
@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -272,3 +273,86 @@ func TestLoadConfigFromURL_HTTPError(t *testing.T) {
 		t.Error("Expected error for HTTP 500, got nil")
 	}
 }
+
+// TestLoadConfigFromURL_RequirePinned tests the --require-pinned policy
+func TestLoadConfigFromURL_RequirePinned(t *testing.T) {
+	configJSON := `{
+		"version": "1.0",
+		"platforms": [{
+			"name": "Test",
+			"os": "darwin",
+			"match": "Darwin",
+			"install_steps": [{"name": "test", "command": "true"}]
+		}]
+	}`
+	hash := sha256.Sum256([]byte(configJSON))
+	expectedHash := hex.EncodeToString(hash[:])
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(configJSON))
+	}))
+	defer server.Close()
+
+	// Unchecksummed URL is refused
+	_, err := loadConfigFromURLWithOptions(server.URL, URLLoadOptions{SkipChecksum: true, RequirePinned: true})
+	if err == nil || !strings.Contains(err.Error(), "--require-pinned") {
+		t.Errorf("Expected --require-pinned error, got: %v", err)
+	}
+
+	// Explicit checksum satisfies the policy
+	if _, err := loadConfigFromURLWithOptions(server.URL, URLLoadOptions{SHA256: expectedHash, RequirePinned: true}); err != nil {
+		t.Errorf("Expected success with checksum, got: %v", err)
+	}
+}
+
+// TestLoadConfigFromURL_ConfigPolicyRequiresPinned tests the config-declared pinning policy
+func TestLoadConfigFromURL_ConfigPolicyRequiresPinned(t *testing.T) {
+	configJSON := `{
+		"version": "1.0",
+		"policy": {"require_pinned": true},
+		"platforms": [{
+			"name": "Test",
+			"os": "darwin",
+			"match": "Darwin",
+			"install_steps": [{"name": "test", "command": "true"}]
+		}]
+	}`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(configJSON))
+	}))
+	defer server.Close()
+
+	_, err := loadConfigFromURL(server.URL, "", true)
+	if err == nil || !strings.Contains(err.Error(), "policy requires a pinned source") {
+		t.Errorf("Expected config policy error, got: %v", err)
+	}
+}
+
+// TestCheckPinnedSource tests pin classification of bootstrap URLs
+func TestCheckPinnedSource(t *testing.T) {
+	tests := []struct {
+		url      string
+		checksum string
+		pinned   bool
+	}{
+		{"https://raw.githubusercontent.com/org/repo/v1.0.0/config.json", "", true},
+		{"https://raw.githubusercontent.com/org/repo/abc123def456/config.json", "", true},
+		{"https://github.com/org/repo/releases/download/v1.0.0/config.json", "", true},
+		{"https://raw.githubusercontent.com/org/repo/main/config.json", "", false},
+		{"https://raw.githubusercontent.com/org/repo/feature-x/config.json", "", false},
+		{"https://raw.githubusercontent.com/org/repo/main/config.json", "abc", true},
+		{"https://example.com/config.json", "", false},
+		{"https://example.com/config.json", "abc", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			info, _ := ParseGitHubURL(tt.url)
+			err := checkPinnedSource(tt.url, info, tt.checksum)
+			if (err == nil) != tt.pinned {
+				t.Errorf("checkPinnedSource(%s, %q) = %v, want pinned=%v", tt.url, tt.checksum, err, tt.pinned)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Debugger pauses a run before breakpoint steps (--break-at) and offers a
+// prompt to inspect facts and run commands on the target before deciding to
+// continue or abort
+type Debugger struct {
+	Breakpoints map[string]bool // Step names to pause before
+	In          *bufio.Reader
+	Out         io.Writer
+	stepping    bool // Pause before the next step too ("next")
+}
+
+// NewDebugger creates a debugger pausing before the named steps
+func NewDebugger(steps []string, in io.Reader, out io.Writer) *Debugger {
+	d := &Debugger{Breakpoints: map[string]bool{}, In: bufio.NewReader(in), Out: out}
+	for _, name := range steps {
+		d.Breakpoints[name] = true
+	}
+	return d
+}
+
+// abortedAtBreakpoint is the reason given for steps not run after an abort
+const abortedAtBreakpoint = "aborted at breakpoint"
+
+// validateBreakpoints checks that every --break-at names a step that will run
+func validateBreakpoints(names []string, steps []InstallStep) error {
+	known := map[string]bool{}
+	for _, step := range steps {
+		known[step.Name] = true
+	}
+	for _, name := range names {
+		if !known[name] {
+			return fmt.Errorf("--break-at: no step named '%s' in the selected platform", name)
+		}
+	}
+	return nil
+}
+
+// pause stops before step if it is a breakpoint (or the previous prompt
+// asked for "next") and runs the prompt. It reports whether to run the
+// step; false aborts the run. All methods are no-ops on a nil debugger.
+func (d *Debugger) pause(e *Executor, step InstallStep, index, total int, facts Facts) bool {
+	if d == nil || (!d.Breakpoints[step.Name] && !d.stepping) {
+		return true
+	}
+	d.stepping = false
+
+	fmt.Fprintf(d.Out, "\n⏸  Paused before step %d/%d: %s\n", index+1, total, step.Name)
+	for _, command := range stepCommands(step) {
+		fmt.Fprintf(d.Out, "   %s\n", firstLine(command))
+	}
+	fmt.Fprintln(d.Out, "   Type 'help' for commands.")
+
+	for {
+		fmt.Fprint(d.Out, "(sink) ")
+		line, err := d.In.ReadString('\n')
+		if err != nil && line == "" {
+			// End of input aborts rather than silently running the step
+			fmt.Fprintln(d.Out, "\nAborted (end of input)")
+			return false
+		}
+		cmd, arg, _ := strings.Cut(strings.TrimSpace(line), " ")
+		arg = strings.TrimSpace(arg)
+
+		switch {
+		case cmd == "":
+			continue
+		case cmd == "c" || cmd == "continue":
+			return true
+		case cmd == "n" || cmd == "next":
+			d.stepping = true
+			return true
+		case cmd == "a" || cmd == "abort" || cmd == "q" || cmd == "quit":
+			return false
+		case cmd == "f" || cmd == "facts":
+			printFacts(d.Out, facts, arg)
+		case cmd == "!" || cmd == "sh":
+			runShell(d.Out, e, arg, facts)
+		case strings.HasPrefix(cmd, "!"):
+			runShell(d.Out, e, strings.TrimSpace(strings.TrimPrefix(line, "!")), facts)
+		case cmd == "h" || cmd == "help" || cmd == "?":
+			fmt.Fprint(d.Out, debuggerHelp)
+		default:
+			fmt.Fprintf(d.Out, "Unknown command '%s'; type 'help' for commands\n", cmd)
+		}
+	}
+}
+
+// printFacts prints all facts, or only those whose name starts with prefix
+func printFacts(out io.Writer, facts Facts, prefix string) {
+	printed := 0
+	for _, name := range sortedKeys(facts) {
+		if strings.HasPrefix(name, prefix) {
+			fmt.Fprintf(out, "  %s = %v\n", name, facts[name])
+			printed++
+		}
+	}
+	if printed == 0 {
+		fmt.Fprintln(out, "  (no matching facts)")
+	}
+}
+
+// runShell runs a command on the target with facts interpolated. It is not
+// recorded in the transcript, which only holds what steps ran.
+func runShell(out io.Writer, e *Executor, command string, facts Facts) {
+	if command == "" {
+		fmt.Fprintln(out, "Usage: ! <command>")
+		return
+	}
+	interpolated, err := e.interpolate(command, facts)
+	if err != nil {
+		fmt.Fprintf(out, "Template error: %v\n", err)
+		return
+	}
+	stdout, stderr, exitCode, err := e.runWithDeadline(interpolated, "")
+	fmt.Fprint(out, stdout)
+	fmt.Fprint(out, stderr)
+	if err != nil {
+		fmt.Fprintf(out, "Error: %v\n", err)
+	} else if exitCode != 0 {
+		fmt.Fprintf(out, "(exit code %d)\n", exitCode)
+	}
+}
+
+// firstLine returns the first line of s, marking that more follow
+func firstLine(s string) string {
+	if line, _, more := strings.Cut(s, "\n"); more {
+		return line + " …"
+	}
+	return s
+}
+
+const debuggerHelp = `  continue, c       Run this step and carry on
+  next, n           Run this step and pause before the next one
+  abort, a          Stop the run; this and later steps are not run
+  facts [prefix]    Show gathered facts (optionally only names with prefix)
+  ! <command>       Run a command on the target ({{.fact}} templates work)
+  help              Show this help
+`
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestDebugger_BreakAt tests pausing, inspecting, stepping and aborting at breakpoints
+func TestDebugger_BreakAt(t *testing.T) {
+	transport := &MockTransportWithTracking{responses: map[string]MockResponse{
+		"prepare":    {},
+		"install":    {},
+		"echo linux": {stdout: "linux\n"},
+	}}
+	executor := NewExecutor(transport)
+	transport.calls = nil
+
+	var out bytes.Buffer
+	input := "facts o\n! echo {{.os}}\nbogus\nnext\nabort\n"
+	executor.Debugger = NewDebugger([]string{"Install"}, strings.NewReader(input), &out)
+
+	results := executor.ExecutePlatform(Platform{InstallSteps: []InstallStep{
+		{Name: "Prepare", Step: CommandStep{Command: "prepare"}},
+		{Name: "Install", Step: CommandStep{Command: "install"}},
+		{Name: "Configure", Step: CommandStep{Command: "configure"}},
+		{Name: "Finish", Step: CommandStep{Command: "finish"}},
+	}}, Facts{"os": "linux", "arch": "arm64"})
+
+	if got := strings.Join(transport.calls, ","); got != "prepare,echo linux,install" {
+		t.Errorf("unexpected commands %q", got)
+	}
+	if len(results) != 4 || results[2].Status != "not_run" || results[2].Output != abortedAtBreakpoint || results[3].Status != "not_run" || results[3].SkipReason != SkipReasonBreakpoint {
+		t.Errorf("expected Configure and Finish not run after abort, got %+v", results)
+	}
+	for _, want := range []string{
+		"Paused before step 2/4: Install",
+		"  os = linux\n",
+		"linux\n",
+		"Unknown command 'bogus'",
+		"Paused before step 3/4: Configure",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("prompt output missing %q:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "arch = arm64") {
+		t.Errorf("facts o should only list facts starting with o:\n%s", out.String())
+	}
+}
+
+// TestDebugger_EndOfInput tests that closed input aborts instead of running the step
+func TestDebugger_EndOfInput(t *testing.T) {
+	var out bytes.Buffer
+	d := NewDebugger([]string{"Install"}, strings.NewReader(""), &out)
+	if d.pause(NewExecutor(&MockTransport{}), InstallStep{Name: "Install"}, 0, 1, Facts{}) {
+		t.Error("expected abort at end of input")
+	}
+
+	var nilDebugger *Debugger
+	if !nilDebugger.pause(nil, InstallStep{Name: "Install"}, 0, 1, Facts{}) {
+		t.Error("a nil debugger never pauses")
+	}
+}
+
+// TestValidateBreakpoints tests rejecting --break-at names that match no step
+func TestValidateBreakpoints(t *testing.T) {
+	steps := []InstallStep{{Name: "Install Docker"}}
+	if err := validateBreakpoints([]string{"Install Docker"}, steps); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := validateBreakpoints([]string{"Install docker"}, steps); err == nil || !strings.Contains(err.Error(), "no step named 'Install docker'") {
+		t.Errorf("expected unknown step error, got %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// Broken pipe policies (--on-broken-pipe)
+const (
+	BrokenPipeContinue = "continue" // Stop writing to stdout and finish the run
+	BrokenPipeAbort    = "abort"    // Finish the running step, then mark the rest not run
+)
+
+// parseBrokenPipePolicy validates an --on-broken-pipe value
+func parseBrokenPipePolicy(value string) (string, error) {
+	switch value {
+	case BrokenPipeContinue, BrokenPipeAbort:
+		return value, nil
+	}
+	return "", fmt.Errorf("invalid --on-broken-pipe '%s' (use continue or abort)", value)
+}
+
+// catchBrokenPipe makes writes to a closed stdout return an error instead
+// of killing sink with SIGPIPE, as Go does by default. Commands sink runs
+// are not affected: caught signals are reset when they start.
+func catchBrokenPipe() {
+	signal.Notify(make(chan os.Signal, 1), syscall.SIGPIPE)
+}
+
+// StdoutGuard writes run output to stdout until a write fails, as when a
+// reader such as head exits early, then discards the rest so the run can
+// carry on without it. OnBroken is called once, with the write error.
+type StdoutGuard struct {
+	W        io.Writer
+	OnBroken func(error)
+	broken   bool
+}
+
+// Write implements io.Writer, never failing
+func (g *StdoutGuard) Write(p []byte) (int, error) {
+	if g.broken {
+		return len(p), nil
+	}
+	if _, err := g.W.Write(p); err != nil {
+		g.broken = true
+		if g.OnBroken != nil {
+			g.OnBroken(err)
+		}
+	}
+	return len(p), nil
+}
+
+// Broken reports whether stdout was closed during the run
+func (g *StdoutGuard) Broken() bool {
+	return g.broken
+}
+
+// RunRecord keeps every event of a run as JSON lines in the log directory,
+// whatever becomes of stdout, so the full result can be read back (or
+// loaded with sink db import) after the output was cut short
+type RunRecord struct {
+	Path string
+	file *os.File
+	enc  *json.Encoder
+}
+
+// openRunRecord creates the record of a run, run-<id>.jsonl in the log
+// directory
+func openRunRecord(runID string) (*RunRecord, error) {
+	logs, err := logDir()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(logs, 0700); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(logs, "run-"+runID+".jsonl")
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, TempFilePermission)
+	if err != nil {
+		return nil, err
+	}
+	return &RunRecord{Path: path, file: file, enc: json.NewEncoder(file)}, nil
+}
+
+// Deliver appends an event to the record
+func (r *RunRecord) Deliver(event ExecutionEvent) error {
+	return r.enc.Encode(event)
+}
+
+// Close appends the run's warnings and closes the record
+func (r *RunRecord) Close(warnings []Warning) error {
+	if len(warnings) > 0 {
+		if err := r.enc.Encode(WarningsEvent{Timestamp: eventTimestamp(time.Now()), Event: "warnings", Warnings: warnings}); err != nil {
+			r.file.Close()
+			return err
+		}
+	}
+	return r.file.Close()
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// closedWriter fails every write like a pipe whose reader exited
+type closedWriter struct{ writes int }
+
+func (w *closedWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return 0, syscall.EPIPE
+}
+
+// TestStdoutGuard tests that a closed stdout is reported once and then
+// ignored
+func TestStdoutGuard(t *testing.T) {
+	w := &closedWriter{}
+	var reported []error
+	guard := &StdoutGuard{W: w, OnBroken: func(err error) { reported = append(reported, err) }}
+	for i := 0; i < 3; i++ {
+		if n, err := guard.Write([]byte("event\n")); n != 6 || err != nil {
+			t.Fatalf("Write = %d, %v; want 6, nil", n, err)
+		}
+	}
+	if !guard.Broken() || len(reported) != 1 || !errors.Is(reported[0], syscall.EPIPE) {
+		t.Errorf("expected one broken pipe report, got %v", reported)
+	}
+	if w.writes != 1 {
+		t.Errorf("expected writes to stop after the failure, got %d", w.writes)
+	}
+}
+
+// TestBrokenPipeAbort tests that stopping the executor on a closed stdout
+// reports the remaining steps as not run
+func TestBrokenPipeAbort(t *testing.T) {
+	transport := &MockTransportWithTracking{responses: map[string]MockResponse{"one": {}, "two": {}, "three": {}}}
+	executor := NewExecutor(transport)
+	transport.calls = nil
+	executor.JSONOutput = true
+	executor.Stdout = &StdoutGuard{W: &closedWriter{}, OnBroken: func(error) { executor.Stop(SkipReasonBrokenPipe) }}
+
+	results := executor.ExecutePlatform(Platform{InstallSteps: []InstallStep{
+		{Name: "one", Step: CommandStep{Command: "one"}},
+		{Name: "two", Step: CommandStep{Command: "two"}},
+		{Name: "three", Step: CommandStep{Command: "three"}},
+	}}, nil)
+
+	if len(results) != 3 || results[0].Status != "success" {
+		t.Fatalf("expected the running step to finish, got %+v", results)
+	}
+	for _, result := range results[1:] {
+		if result.Status != "not_run" || result.SkipReason != SkipReasonBrokenPipe {
+			t.Errorf("step %s = %s (%s), want not_run (broken_pipe)", result.StepName, result.Status, result.SkipReason)
+		}
+	}
+	if strings.Join(transport.calls, ",") != "one" {
+		t.Errorf("expected only the first step to run, got %v", transport.calls)
+	}
+}
+
+// TestRunRecord tests that a run record can be imported like --json output
+func TestRunRecord(t *testing.T) {
+	t.Setenv(EnvLogDir, t.TempDir())
+	record, err := openRunRecord("host-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range []string{"running", "success"} {
+		if err := record.Deliver(ExecutionEvent{RunID: "host-1", StepName: "Install", Status: status, Timestamp: "2026-10-18T10:00:00Z"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := record.Close([]Warning{{Code: WarningStdoutClosed, Message: "stdout was closed"}}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(record.Path, "run-host-1.jsonl") {
+		t.Errorf("unexpected record path %s", record.Path)
+	}
+
+	file, err := os.Open(record.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	runs, err := parseResults(file, record.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 1 || len(runs[0].Steps) != 1 || runs[0].Steps[0].Status != "success" || runs[0].Warnings != 1 {
+		t.Errorf("unexpected import %+v", runs)
+	}
+}
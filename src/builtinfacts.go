@@ -0,0 +1,33 @@
+package main
+
+// builtinFactDescriptions names the facts every config gets without a
+// "facts" section, read as {{._os}} and so on. They come from the execution
+// context, so they describe the target host rather than the machine sink
+// was started on, and the names are reserved for them.
+var builtinFactDescriptions = map[string]string{
+	"_os":        "Operating system as platform \"os\" names it: linux, darwin, windows, freebsd, ...",
+	"_arch":      "Normalized architecture: amd64, arm64, armv7, 386, ...",
+	"_hostname":  "Host name of the target",
+	"_user":      "User commands run as",
+	"_home":      "That user's home directory",
+	"_cpu_count": "Number of CPUs available (integer)",
+}
+
+// isBuiltinFact reports whether a name is reserved for a built-in fact
+func isBuiltinFact(name string) bool {
+	_, ok := builtinFactDescriptions[name]
+	return ok
+}
+
+// builtinFacts returns the built-in facts of the host the context describes.
+// A value the context does not know is empty (0 for _cpu_count).
+func (ctx ExecutionContext) builtinFacts() map[string]interface{} {
+	return map[string]interface{}{
+		"_os":        goosFromUname(ctx.OS),
+		"_arch":      normalizeArch(ctx.Arch),
+		"_hostname":  ctx.Host,
+		"_user":      ctx.User,
+		"_home":      ctx.Home,
+		"_cpu_count": ctx.CPUCount,
+	}
+}
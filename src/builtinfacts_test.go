@@ -0,0 +1,35 @@
+package main
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// TestBuiltinFacts tests that {{._os}} and the other built-in facts come
+// from the target's context without a "facts" section
+func TestBuiltinFacts(t *testing.T) {
+	transport := &MockTransport{responses: map[string]MockResponse{
+		"hostname":      {stdout: "web-1\n"},
+		"whoami":        {stdout: "deploy\n"},
+		"uname -s":      {stdout: "Linux\n"},
+		"uname -m":      {stdout: "aarch64\n"},
+		preflightScript: {stdout: "home=/home/deploy\ncpu_count=8\n"},
+	}}
+	executor := NewExecutor(transport)
+
+	command := "{{._os}}/{{._arch}} {{._hostname}} {{._user}} {{._home}} {{if gt ._cpu_count 4}}parallel{{end}}"
+	got, err := executor.interpolate(command, executor.stepFacts(InstallStep{Name: "build"}, Facts{}))
+	if want := "linux/arm64 web-1 deploy /home/deploy parallel"; err != nil || got != want {
+		t.Errorf("expected %q, got %q, %v", want, got, err)
+	}
+
+	local := NewExecutor(NewLocalTransport()).GetContext().builtinFacts()
+	if local["_os"] != runtime.GOOS || local["_cpu_count"] != runtime.NumCPU() {
+		t.Errorf("expected the local host's OS and CPU count, got %v", local)
+	}
+
+	if err := ValidateFactDef("_os", FactDef{Command: "uname -s"}); err == nil || !strings.Contains(err.Error(), "reserved for the built-in fact") {
+		t.Errorf("expected _os to be reserved, got %v", err)
+	}
+}
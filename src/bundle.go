@@ -0,0 +1,426 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Bundle is a config bundle (see "sink package") unpacked for offline
+// execution with "sink execute --bundle"
+type Bundle struct {
+	Path     string           // The .tar.gz the bundle came from
+	Dir      string           // Directory it was unpacked into
+	Checksum string           // SHA256 of the .tar.gz
+	Manifest ChecksumManifest // Verified SHA256SUMS entries
+	Files    []string         // Bundled files (excluding SHA256SUMS), sorted
+	used     map[string]bool  // Files referenced by commands that ran
+}
+
+// BundleEvent reports the bundle assets a run used (JSON mode)
+type BundleEvent struct {
+	Timestamp string   `json:"timestamp"`
+	Event     string   `json:"event"` // Always "bundle"
+	Bundle    string   `json:"bundle"`
+	Assets    []string `json:"assets"` // Bundled files referenced by executed commands
+}
+
+// openBundle unpacks a bundle under the cache directory and verifies every
+// file against its embedded SHA256SUMS manifest. Files missing from the
+// manifest, manifest entries missing from the bundle, links and paths that
+// escape the bundle are all rejected. The unpacked copy is kept, and reused
+// by later runs of the same bundle while it still verifies, so that
+// commands still running in the background can use it after sink exits.
+//
+// Each run unpacks into a directory of its own and renames it into place,
+// so runs of the same bundle at the same time never see a partial copy or
+// delete files another run's commands are using.
+func openBundle(bundlePath string) (*Bundle, error) {
+	checksum, err := fileSHA256(bundlePath)
+	if err != nil {
+		return nil, fmt.Errorf("bundle %s: %w", bundlePath, err)
+	}
+	cache, err := cacheDir()
+	if err != nil {
+		return nil, err
+	}
+	bundles := filepath.Join(cache, "bundles")
+	if err := os.MkdirAll(bundles, 0700); err != nil {
+		return nil, err
+	}
+	unpacked, err := os.MkdirTemp(bundles, checksum[:16]+".unpack-")
+	if err != nil {
+		return nil, err
+	}
+
+	bundle := &Bundle{Path: bundlePath, Dir: unpacked, Checksum: checksum, used: map[string]bool{}}
+	if err := bundle.unpack(); err != nil {
+		bundle.Close()
+		return nil, fmt.Errorf("bundle %s: %w", bundlePath, err)
+	}
+	if err := bundle.verify(); err != nil {
+		bundle.Close()
+		return nil, fmt.Errorf("bundle %s: %w", bundlePath, err)
+	}
+	if err := bundle.install(filepath.Join(bundles, checksum[:16])); err != nil {
+		bundle.Close()
+		return nil, fmt.Errorf("bundle %s: %w", bundlePath, err)
+	}
+	return bundle, nil
+}
+
+// install moves the verified copy in b.Dir to dir. When another run put a
+// copy there first, that copy is used instead if it verifies, and ours is
+// removed; a damaged one is moved aside and replaced.
+func (b *Bundle) install(dir string) error {
+	unpacked := b.Dir
+	for attempt := 0; attempt < 3; attempt++ {
+		if err := os.Rename(unpacked, dir); err == nil {
+			b.Dir = dir
+			return nil
+		}
+		existing := *b
+		existing.Dir = dir
+		if existing.verify() == nil {
+			os.RemoveAll(unpacked)
+			b.Dir = dir
+			return nil
+		}
+		stale := fmt.Sprintf("%s.stale-%d", unpacked, attempt)
+		if os.Rename(dir, stale) == nil {
+			os.RemoveAll(stale)
+		}
+	}
+	return fmt.Errorf("cannot move the unpacked bundle to %s", dir)
+}
+
+// unpack extracts the tarball into b.Dir
+func (b *Bundle) unpack() error {
+	f, err := os.Open(b.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("not a .tar.gz: %w", err)
+	}
+	tr := tar.NewReader(gz)
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("corrupt tarball: %w", err)
+		}
+
+		name := path.Clean(header.Name)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return fmt.Errorf("entry %s escapes the bundle", header.Name)
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			continue
+		case tar.TypeReg:
+		default:
+			return fmt.Errorf("entry %s is not a regular file", header.Name)
+		}
+
+		target := filepath.Join(b.Dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, os.FileMode(header.Mode).Perm())
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(out, tr)
+		out.Close()
+		if err != nil {
+			return err
+		}
+		if name != BundleManifestName {
+			b.Files = append(b.Files, name)
+		}
+	}
+}
+
+// verify checks the unpacked files against SHA256SUMS
+func (b *Bundle) verify() error {
+	data, err := os.ReadFile(filepath.Join(b.Dir, BundleManifestName))
+	if err != nil {
+		return fmt.Errorf("no %s manifest (was it built with sink package?)", BundleManifestName)
+	}
+	manifest, err := parseChecksumManifest(string(data))
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", BundleManifestName, err)
+	}
+
+	sort.Strings(b.Files)
+	for _, file := range b.Files {
+		expected, ok := manifest[file]
+		if !ok {
+			return fmt.Errorf("%s is not listed in %s", file, BundleManifestName)
+		}
+		actual, err := fileSHA256(filepath.Join(b.Dir, filepath.FromSlash(file)))
+		if err != nil {
+			return err
+		}
+		if actual != expected {
+			return fmt.Errorf("checksum mismatch for %s\n  Expected: %s\n  Got:      %s", file, expected, actual)
+		}
+	}
+	if len(manifest) != len(b.Files) {
+		for _, file := range sortedKeys(manifest) {
+			if _, err := os.Stat(filepath.Join(b.Dir, filepath.FromSlash(file))); err != nil {
+				return fmt.Errorf("%s lists %s but the bundle does not contain it", BundleManifestName, file)
+			}
+		}
+	}
+
+	b.Manifest = manifest
+	return nil
+}
+
+// Close removes the unpacked copy
+func (b *Bundle) Close() {
+	os.RemoveAll(b.Dir)
+}
+
+// noteCommand records the bundled files a command refers to, by path
+// relative to the bundle root or by absolute path
+func (b *Bundle) noteCommand(command string) {
+	for _, file := range b.Files {
+		if strings.Contains(command, file) {
+			b.used[file] = true
+		}
+	}
+}
+
+// UsedAssets returns the bundled files referenced by executed commands
+func (b *Bundle) UsedAssets() []string {
+	var used []string
+	for _, file := range b.Files {
+		if b.used[file] {
+			used = append(used, file)
+		}
+	}
+	return used
+}
+
+// urlPattern matches URLs that commands would fetch
+var urlPattern = regexp.MustCompile(`\b(https?|ftp|s3|git)://[^\s'"]+`)
+
+// networkPrograms always reach the network
+var networkPrograms = map[string]bool{
+	"curl": true, "wget": true, "ssh": true, "scp": true, "sftp": true,
+	"ftp": true, "fetch": true, "nc": true, "ncat": true, "telnet": true,
+	"Invoke-WebRequest": true, "iwr": true, "Invoke-RestMethod": true, "irm": true,
+}
+
+// networkSubcommands lists subcommands that download, per program
+var networkSubcommands = map[string][]string{
+	"git":     {"clone", "fetch", "pull", "push", "ls-remote", "submodule"},
+	"apt":     {"update", "upgrade", "full-upgrade", "install", "download"},
+	"apt-get": {"update", "upgrade", "dist-upgrade", "install", "download", "source"},
+	"yum":     {"install", "update", "upgrade", "makecache"},
+	"dnf":     {"install", "update", "upgrade", "makecache"},
+	"apk":     {"add", "update", "upgrade", "fetch"},
+	"pkg":     {"install", "update", "upgrade", "fetch"},
+	"brew":    {"install", "update", "upgrade", "tap", "fetch"},
+	"pip":     {"install", "download"},
+	"pip3":    {"install", "download"},
+	"npm":     {"install", "i", "ci", "update"},
+	"yarn":    {"install", "add"},
+	"pnpm":    {"install", "add"},
+	"gem":     {"install", "update"},
+	"cargo":   {"install", "fetch"},
+	"go":      {"get", "install", "download"},
+	"snap":    {"install", "refresh"},
+	"docker":  {"pull", "push", "login"},
+	"podman":  {"pull", "push", "login"},
+	"choco":   {"install", "upgrade"},
+	"winget":  {"install", "upgrade"},
+}
+
+// offlineFlags mark a package manager invocation as using only local sources
+var offlineFlags = []string{"--no-index", "--offline", "--no-download", "--cacheonly"}
+
+// commandPrefixes are wrappers skipped to find the program a segment runs
+var commandPrefixes = map[string]bool{
+	"sudo": true, "env": true, "exec": true, "time": true, "nohup": true, "command": true,
+}
+
+// networkAccess returns why a command would access the network under the
+// offline bundle policy, or "" when it looks local. It is a heuristic over
+// the command text: each shell segment's program and subcommand are checked,
+// plus any URL anywhere in the command.
+func networkAccess(command string) string {
+	if url := urlPattern.FindString(command); url != "" {
+		return "fetches " + url
+	}
+
+	segments := strings.FieldsFunc(command, func(r rune) bool {
+		return strings.ContainsRune(";&|\n()`{}", r)
+	})
+	for _, segment := range segments {
+		words := strings.Fields(segment)
+		for len(words) > 0 && (commandPrefixes[words[0]] || strings.Contains(words[0], "=")) {
+			words = words[1:]
+		}
+		if len(words) == 0 {
+			continue
+		}
+
+		program := path.Base(strings.Trim(words[0], `'"`))
+		if networkPrograms[program] {
+			return program + " uses the network"
+		}
+		subcommands, ok := networkSubcommands[program]
+		if !ok {
+			continue
+		}
+		if reason := networkSubcommand(program, words[1:], subcommands); reason != "" {
+			return reason
+		}
+	}
+	return ""
+}
+
+// networkSubcommand checks a package manager or VCS invocation. Installs of
+// local package files (paths) and invocations with an offline flag are allowed.
+func networkSubcommand(program string, args []string, subcommands []string) string {
+	var positional []string
+	for _, arg := range args {
+		for _, flag := range offlineFlags {
+			if arg == flag {
+				return ""
+			}
+		}
+		if !strings.HasPrefix(arg, "-") {
+			positional = append(positional, arg)
+		}
+	}
+
+	// The subcommand is the first positional argument, or the second after
+	// an option value such as "git -C /src pull"
+	for i := 0; i < len(positional) && i < 2; i++ {
+		for _, sub := range subcommands {
+			if positional[i] != sub {
+				continue
+			}
+			operands := positional[i+1:]
+			if program != "git" && len(operands) > 0 && allLocalPaths(operands) {
+				return ""
+			}
+			return fmt.Sprintf("%s %s uses the network", program, sub)
+		}
+	}
+	return ""
+}
+
+// allLocalPaths reports whether every operand names a file (./pkg.deb, /opt/x.rpm)
+func allLocalPaths(operands []string) bool {
+	for _, operand := range operands {
+		if !strings.HasPrefix(operand, "./") && !strings.HasPrefix(operand, "/") && !strings.HasPrefix(operand, "../") {
+			return false
+		}
+	}
+	return true
+}
+
+// offlineViolations lists the fact and step commands the offline bundle
+// policy refuses, as "<where>: <reason>"
+func offlineViolations(facts map[string]FactDef, steps []InstallStep) []string {
+	var violations []string
+	for _, name := range sortedKeys(facts) {
+		def := facts[name]
+		if def.Source != "" {
+			violations = append(violations, fmt.Sprintf("fact %s: source %s needs the network", name, def.Source))
+		} else if reason := networkAccess(def.Command); reason != "" {
+			violations = append(violations, fmt.Sprintf("fact %s: %s", name, reason))
+		}
+	}
+
+	for _, step := range steps {
+		for _, command := range stepCommands(step) {
+			if reason := networkAccess(command); reason != "" {
+				violations = append(violations, fmt.Sprintf("step %q: %s", step.Name, reason))
+			}
+		}
+	}
+	return violations
+}
+
+// stepCommands returns every command a step may run
+func stepCommands(step InstallStep) []string {
+	switch s := step.Step.(type) {
+	case CommandStep:
+		if s.Verify != "" {
+			return []string{s.Command, s.Verify}
+		}
+		return []string{s.Command}
+	case CheckErrorStep:
+		if s.Check == "" {
+			return nil
+		}
+		return []string{s.Check}
+	case CheckRemediateStep:
+		var commands []string
+		if s.Check != "" {
+			commands = append(commands, s.Check)
+		}
+		for _, rem := range s.OnMissing {
+			commands = append(commands, rem.Command)
+		}
+		return commands
+	case GroupStep:
+		var commands []string
+		for _, member := range s.Group.Steps {
+			commands = append(commands, stepCommands(member)...)
+		}
+		return commands
+	default:
+		return nil
+	}
+}
+
+// printBundleAssets reports which bundled files the run used
+func printBundleAssets(bundle *Bundle) {
+	used := bundle.UsedAssets()
+	fmt.Printf("📦 Bundle %s: %d of %d assets used\n", filepath.Base(bundle.Path), len(used), len(bundle.Files))
+	for _, file := range used {
+		fmt.Printf("   %s  %s\n", bundle.Manifest[file][:12], file)
+	}
+}
+
+// emitBundleEventJSON reports which bundled files the run used (JSON mode)
+func emitBundleEventJSON(bundle *Bundle) {
+	event := BundleEvent{
+		Timestamp: eventTimestamp(time.Now()),
+		Event:     "bundle",
+		Bundle:    bundle.Path,
+		Assets:    bundle.UsedAssets(),
+	}
+	if event.Assets == nil {
+		event.Assets = []string{}
+	}
+	jsonBytes, err := json.MarshalIndent(event, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: Failed to marshal bundle event to JSON: %v\n", err)
+		return
+	}
+	writeJSONObject(os.Stdout, jsonBytes)
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// writeTestTarball writes a .tar.gz with the given files
+func writeTestTarball(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("failed to create tarball: %v", err)
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg})
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gz.Close()
+}
+
+// TestOpenBundle tests unpacking and verifying a packaged bundle
+func TestOpenBundle(t *testing.T) {
+	t.Setenv(EnvCacheDir, t.TempDir())
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "config.json"), []byte(bundleTestConfig), 0644)
+	os.MkdirAll(filepath.Join(dir, "scripts"), 0755)
+	os.WriteFile(filepath.Join(dir, "scripts", "setup.sh"), []byte("echo hi\n"), 0755)
+	output := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	if _, err := buildConfigBundle(PackageOptions{Dir: dir, Config: "config.json", Output: output}); err != nil {
+		t.Fatalf("buildConfigBundle failed: %v", err)
+	}
+
+	bundle, err := openBundle(output)
+	if err != nil {
+		t.Fatalf("openBundle failed: %v", err)
+	}
+	defer bundle.Close()
+
+	if strings.Join(bundle.Files, ",") != "config.json,scripts/setup.sh" {
+		t.Errorf("unexpected files: %v", bundle.Files)
+	}
+	if data, err := os.ReadFile(filepath.Join(bundle.Dir, "scripts", "setup.sh")); err != nil || string(data) != "echo hi\n" {
+		t.Errorf("setup.sh not unpacked: %q, %v", data, err)
+	}
+
+	bundle.noteCommand("sh scripts/setup.sh")
+	if used := bundle.UsedAssets(); len(used) != 1 || used[0] != "scripts/setup.sh" {
+		t.Errorf("UsedAssets = %v", used)
+	}
+
+	// Opening the same bundle again replaces the unpacked copy
+	if again, err := openBundle(output); err != nil || again.Dir != bundle.Dir {
+		t.Errorf("reopening bundle: %v (dir %s)", err, again.Dir)
+	}
+}
+
+// TestOpenBundle_Concurrent tests that runs of the same bundle at the same
+// time share one verified copy without removing each other's files, and
+// that a damaged copy is replaced
+func TestOpenBundle_Concurrent(t *testing.T) {
+	t.Setenv(EnvCacheDir, t.TempDir())
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "config.json"), []byte(bundleTestConfig), 0644)
+	os.WriteFile(filepath.Join(dir, "setup.sh"), []byte("echo hi\n"), 0755)
+	output := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	if _, err := buildConfigBundle(PackageOptions{Dir: dir, Config: "config.json", Output: output}); err != nil {
+		t.Fatalf("buildConfigBundle failed: %v", err)
+	}
+
+	bundles := make([]*Bundle, 8)
+	errs := make([]error, len(bundles))
+	var wg sync.WaitGroup
+	for i := range bundles {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			bundles[i], errs[i] = openBundle(output)
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("openBundle %d failed: %v", i, err)
+		}
+		if bundles[i].Dir != bundles[0].Dir {
+			t.Errorf("expected one shared copy, got %s and %s", bundles[0].Dir, bundles[i].Dir)
+		}
+	}
+	setup := filepath.Join(bundles[0].Dir, "setup.sh")
+	if data, err := os.ReadFile(setup); err != nil || string(data) != "echo hi\n" {
+		t.Errorf("setup.sh missing after concurrent opens: %q, %v", data, err)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(bundles[0].Dir)); len(entries) != 1 {
+		t.Errorf("expected only the shared copy to remain, got %d entries", len(entries))
+	}
+
+	os.WriteFile(setup, []byte("rm -rf /\n"), 0755)
+	if _, err := openBundle(output); err != nil {
+		t.Fatalf("reopening a damaged copy: %v", err)
+	}
+	if data, _ := os.ReadFile(setup); string(data) != "echo hi\n" {
+		t.Errorf("expected the damaged copy to be replaced, got %q", data)
+	}
+}
+
+// TestOpenBundle_Invalid tests that tampered or malformed bundles are rejected
+func TestOpenBundle_Invalid(t *testing.T) {
+	t.Setenv(EnvCacheDir, t.TempDir())
+	sum := strings.Repeat("0", 64)
+
+	tests := []struct {
+		name    string
+		files   map[string]string
+		wantErr string
+	}{
+		{"no manifest", map[string]string{"config.json": "{}"}, "no SHA256SUMS manifest"},
+		{"tampered", map[string]string{"config.json": "{}", "SHA256SUMS": sum + "  config.json\n"}, "checksum mismatch for config.json"},
+		{"unlisted", map[string]string{"config.json": "{}", "extra.sh": "x", "SHA256SUMS": "44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a  config.json\n"}, "extra.sh is not listed"},
+		{"missing", map[string]string{"SHA256SUMS": sum + "  config.json\n"}, "does not contain it"},
+		{"escape", map[string]string{"../evil.sh": "x"}, "escapes the bundle"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "bundle.tar.gz")
+			writeTestTarball(t, path, tt.files)
+			_, err := openBundle(path)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+// TestNetworkAccess tests the offline policy heuristics
+func TestNetworkAccess(t *testing.T) {
+	tests := []struct {
+		command string
+		network bool
+	}{
+		{"curl -fsSL -o /tmp/x https://example.com/x", true},
+		{"echo start && wget example.com/file", true},
+		{"sudo apt-get install -y nginx", true},
+		{"DEBIAN_FRONTEND=noninteractive apt-get update", true},
+		{"git -C /src pull", true},
+		{"git clone ./local-mirror /src", true},
+		{"pip install requests", true},
+		{"pkg install -y rsync", true},
+		{"fetch -o /tmp/x.txz example.com/x.txz", true},
+		{"pkg install -y ./packages/rsync.pkg", false},
+		{"cat file | ssh host", true},
+		{"test -f $(/usr/bin/curl x)", true},
+		{"sh scripts/setup.sh", false},
+		{"apt-get install -y ./packages/nginx.deb", false},
+		{"pip install --no-index --find-links wheels/ requests", false},
+		{"git status", false},
+		{"npm run build", false},
+		{"echo {{.sink.tmpfile}}", false},
+	}
+
+	for _, tt := range tests {
+		if got := networkAccess(tt.command) != ""; got != tt.network {
+			t.Errorf("networkAccess(%q) = %q, want network=%v", tt.command, networkAccess(tt.command), tt.network)
+		}
+	}
+}
+
+// TestOfflineViolations tests that facts and every step command are checked
+func TestOfflineViolations(t *testing.T) {
+	facts := map[string]FactDef{
+		"arch":  {Command: "uname -m"},
+		"token": {Source: "vault:secret/app#token"},
+	}
+	steps := []InstallStep{
+		{Name: "local", Step: CommandStep{Command: "sh install.sh"}},
+		{Name: "remediate", Step: CheckRemediateStep{
+			Check:     "which jq",
+			OnMissing: []RemediationStep{{Name: "get jq", Command: "brew install jq"}},
+		}},
+	}
+
+	violations := offlineViolations(facts, steps)
+	want := []string{
+		"fact token: source vault:secret/app#token needs the network",
+		`step "remediate": brew install uses the network`,
+	}
+	if strings.Join(violations, "\n") != strings.Join(want, "\n") {
+		t.Errorf("violations = %q, want %q", violations, want)
+	}
+}
+
+// TestExecutor_BundleRefusesNetwork tests the policy applied to interpolated commands
+func TestExecutor_BundleRefusesNetwork(t *testing.T) {
+	transport := &MockTransportWithTracking{responses: map[string]MockResponse{
+		"sh scripts/setup.sh": {stdout: "ok"},
+	}}
+	executor := NewExecutor(transport)
+	executor.Bundle = &Bundle{Files: []string{"config.json", "scripts/setup.sh"}, used: map[string]bool{}}
+
+	results := executor.ExecutePlatform(Platform{InstallSteps: []InstallStep{
+		{Name: "setup", Step: CommandStep{Command: "sh scripts/setup.sh"}},
+		{Name: "fetch", Step: CommandStep{Command: "{{.fetcher}} -O x"}},
+	}}, Facts{"fetcher": "wget"})
+
+	if results[0].Error != "" {
+		t.Errorf("local step failed: %s", results[0].Error)
+	}
+	if !strings.Contains(results[1].Error, "refused by offline bundle policy: wget uses the network") {
+		t.Errorf("expected policy refusal, got %q", results[1].Error)
+	}
+	for _, call := range transport.calls {
+		if strings.Contains(call, "wget") {
+			t.Errorf("refused command reached the transport: %s", call)
+		}
+	}
+	if used := executor.Bundle.UsedAssets(); len(used) != 1 || used[0] != "scripts/setup.sh" {
+		t.Errorf("UsedAssets = %v", used)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// checksumCommand prints SHA256 checksums for config files and optionally
+// writes <file>.sha256 sidecars in the format fetchChecksum expects
+func checksumCommand() {
+	write := false
+	var files []string
+
+	for _, arg := range os.Args[2:] {
+		switch arg {
+		case "-h", "--help":
+			printChecksumHelp()
+			os.Exit(0)
+		case "--write", "-w":
+			write = true
+		default:
+			if strings.HasPrefix(arg, "-") {
+				fmt.Fprintf(os.Stderr, "Error: unknown option: %s\n", arg)
+				os.Exit(1)
+			}
+			files = append(files, arg)
+		}
+	}
+
+	if len(files) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: at least one file required\n\n")
+		printChecksumHelp()
+		os.Exit(1)
+	}
+
+	failed := false
+	for _, file := range files {
+		line, err := writeChecksumFile(file, write)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			failed = true
+			continue
+		}
+		fmt.Print(line)
+		if write {
+			fmt.Fprintf(os.Stderr, "✅ Wrote %s.sha256\n", file)
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// writeChecksumFile computes the "<hex>  <basename>\n" checksum line for file,
+// writing it to <file>.sha256 when write is set
+func writeChecksumFile(file string, write bool) (string, error) {
+	checksum, err := fileSHA256(file)
+	if err != nil {
+		return "", err
+	}
+	line := fmt.Sprintf("%s  %s\n", checksum, filepath.Base(file))
+
+	if write {
+		if err := os.WriteFile(file+".sha256", []byte(line), 0644); err != nil {
+			return "", fmt.Errorf("failed to write %s.sha256: %w", file, err)
+		}
+	}
+	return line, nil
+}
+
+func printChecksumHelp() {
+	fmt.Print(`sink checksum - Compute SHA256 checksums for publishing configs
+
+Usage:
+  sink checksum <file>... [options]
+
+Description:
+  Prints "<sha256>  <name>" for each file, the format used by .sha256
+  sidecars and SHA256SUMS manifests. Bootstrap reads the same format.
+
+Options:
+  -w, --write        Also write <file>.sha256 next to each file
+  -h, --help         Show this help message
+
+Examples:
+  # Print the checksum to pass to --sha256
+  sink checksum config.json
+
+  # Publish a sidecar next to the config
+  sink checksum config.json --write
+
+  # Build a manifest for --checksums-url
+  sink checksum *.json > SHA256SUMS
+`)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestWriteChecksumFile tests that generated sidecars round-trip through verification
+func TestWriteChecksumFile(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.json")
+	data := []byte(`{"version":"1.0.0"}`)
+	os.WriteFile(file, data, 0644)
+
+	line, err := writeChecksumFile(file, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(file + ".sha256"); !os.IsNotExist(err) {
+		t.Error("sidecar should not be written without --write")
+	}
+
+	if _, err := writeChecksumFile(file, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	written, _ := os.ReadFile(file + ".sha256")
+	if string(written) != line {
+		t.Errorf("sidecar %q does not match printed line %q", written, line)
+	}
+
+	manifest, err := parseChecksumManifest(string(written))
+	if err != nil {
+		t.Fatalf("sidecar is not a valid manifest: %v", err)
+	}
+	if err := verifyChecksum(data, manifest["config.json"]); err != nil {
+		t.Errorf("sidecar checksum does not verify: %v", err)
+	}
+
+	if _, err := writeChecksumFile(filepath.Join(dir, "missing.json"), false); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 )
 
 var (
@@ -16,17 +18,40 @@ var (
 	// Valid fact name: starts with a-z or _, followed by a-z, 0-9, or _
 	factNameRegex = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
 
-	// Valid platforms
+	// Valid snapshot tool: a bare command name
+	snapshotToolRegex = regexp.MustCompile(`^[A-Za-z0-9_.+-]+$`)
+
+	// Valid annotation key: starts with a letter, followed by letters, digits, _, . or -
+	annotationKeyRegex = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_.-]*$`)
+
+	// Valid platforms: Go's GOOS names, which platform "os" is matched against
 	validPlatforms = map[string]bool{
 		"darwin":  true,
+		"freebsd": true,
 		"linux":   true,
+		"openbsd": true,
 		"windows": true,
 	}
 )
 
+// LoadOptions controls how LoadConfigWithOptions parses a configuration
+type LoadOptions struct {
+	Strict    bool             // Reject keys the schema does not define (see checkUnknownFields)
+	BaseDir   string           // Directory of the config file, holding sink.lock and local libraries; empty for fetched configs
+	CacheOnly bool             // Resolve dependencies from the library cache without fetching
+	File      string           // Config file named in syntax errors; empty for stdin and fetched configs
+	Checksums ChecksumManifest // Manifest whose entries stand in for a fetched config's unpinned dependencies (--checksums-url)
+}
+
 // LoadConfig loads and validates a configuration from a JSON file or stdin
 // Use "-" as filename to read from stdin
 func LoadConfig(filename string) (*Config, error) {
+	return LoadConfigWithOptions(filename, LoadOptions{})
+}
+
+// LoadConfigWithOptions loads and validates a configuration from a JSON file
+// or stdin ("-") with the given parsing options
+func LoadConfigWithOptions(filename string, opts LoadOptions) (*Config, error) {
 	var data []byte
 	var err error
 
@@ -41,11 +66,37 @@ func LoadConfig(filename string) (*Config, error) {
 		if err != nil {
 			return nil, fmt.Errorf("failed to read config file: %w", err)
 		}
+		opts.BaseDir = filepath.Dir(filename)
+		opts.File = filename
+	}
+
+	return parseConfigData(data, opts)
+}
+
+// parseConfigData parses and validates a configuration read from a file or
+// fetched from a repository
+func parseConfigData(source []byte, opts LoadOptions) (*Config, error) {
+	data := stripJSONC(source)
+	composed, err := composeConfig(data, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compose config: %w", err)
 	}
 
 	var config Config
-	if err := json.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("failed to parse config: %w", err)
+	if err := json.Unmarshal(composed, &config); err != nil {
+		// Invalid JSON is never composed, and stripping comments keeps
+		// offsets, so they point into source
+		return nil, fmt.Errorf("failed to parse config: %w", locateSyntaxError(opts.File, source, err))
+	}
+
+	if err := checkDuplicateKeys(data); err != nil {
+		return nil, fmt.Errorf("config validation failed: %w", err)
+	}
+
+	if opts.Strict {
+		if err := checkUnknownFields(data); err != nil {
+			return nil, fmt.Errorf("strict parsing failed: %w", err)
+		}
 	}
 
 	// Parse install steps into type-safe variants
@@ -59,6 +110,8 @@ func LoadConfig(filename string) (*Config, error) {
 		return nil, fmt.Errorf("config validation failed: %w", err)
 	}
 
+	applyStepDefaults(&config)
+
 	return &config, nil
 }
 
@@ -116,6 +169,52 @@ func ValidateConfig(config *Config) error {
 		}
 	}
 
+	// Validate variables
+	for name, def := range config.Variables {
+		if err := validateVariableDef(name, def, config.Facts); err != nil {
+			return fmt.Errorf("variable '%s': %w", name, err)
+		}
+	}
+
+	// Validate dependencies
+	for _, name := range sortedKeys(config.Dependencies) {
+		if err := validateDependency(name, config.Dependencies[name]); err != nil {
+			return fmt.Errorf("dependency '%s': %w", name, err)
+		}
+	}
+
+	if err := validateInstallScope(config.InstallScope); err != nil {
+		return err
+	}
+
+	// Validate maintenance window
+	if config.Window != "" {
+		if _, err := ParseWindow(config.Window); err != nil {
+			return fmt.Errorf("window: %w", err)
+		}
+	}
+
+	// Validate snapshot tools (they are interpolated into shell commands)
+	if config.Snapshot != nil {
+		for _, tool := range config.Snapshot.Tools {
+			if !snapshotToolRegex.MatchString(tool) {
+				return fmt.Errorf("snapshot: invalid tool name '%s'", tool)
+			}
+		}
+	}
+
+	// Validate supporting files for remote deploy
+	if err := validateRemoteFiles(config.Files); err != nil {
+		return err
+	}
+
+	// Validate step defaults
+	if config.Defaults != nil {
+		if err := validateStepDefaults(&config.Defaults.StepDefaults); err != nil {
+			return fmt.Errorf("defaults: %w", err)
+		}
+	}
+
 	// Validate each platform
 	for i, platform := range config.Platforms {
 		if err := validatePlatform(&platform); err != nil {
@@ -123,6 +222,24 @@ func ValidateConfig(config *Config) error {
 		}
 	}
 
+	// Validate refresh_facts and rate_limit references
+	for i, platform := range config.Platforms {
+		if err := validateRefreshFacts(platform.InstallSteps, config.Facts); err != nil {
+			return fmt.Errorf("platform[%d] %s: %w", i, platform.Name, err)
+		}
+		if err := validateRateLimits(config.RateLimits, platform.InstallSteps); err != nil {
+			return fmt.Errorf("platform[%d] %s: %w", i, platform.Name, err)
+		}
+		for j, dist := range platform.Distributions {
+			if err := validateRefreshFacts(dist.InstallSteps, config.Facts); err != nil {
+				return fmt.Errorf("platform[%d] %s: distribution[%d] %s: %w", i, platform.Name, j, dist.Name, err)
+			}
+			if err := validateRateLimits(config.RateLimits, dist.InstallSteps); err != nil {
+				return fmt.Errorf("platform[%d] %s: distribution[%d] %s: %w", i, platform.Name, j, dist.Name, err)
+			}
+		}
+	}
+
 	// TODO: Validate template references
 	// TODO: Detect circular dependencies in facts
 
@@ -135,9 +252,22 @@ func ValidateFactDef(name string, factDef FactDef) error {
 	if !factNameRegex.MatchString(name) {
 		return fmt.Errorf("fact name must match pattern ^[a-z_][a-z0-9_]*$")
 	}
+	if name == sinkFactsKey {
+		return fmt.Errorf("fact name '%s' is reserved for built-in helpers such as {{.sink.tmpfile}}", name)
+	}
+	if isBuiltinFact(name) {
+		return fmt.Errorf("fact name '%s' is reserved for the built-in fact", name)
+	}
 
-	// Validate command is not empty
-	if strings.TrimSpace(factDef.Command) == "" {
+	// Validate the fact has exactly one of command and source
+	if factDef.Source != "" {
+		if factDef.Command != "" {
+			return fmt.Errorf("command and source are mutually exclusive")
+		}
+		if _, err := ParseSecretSource(factDef.Source); err != nil {
+			return err
+		}
+	} else if strings.TrimSpace(factDef.Command) == "" {
 		return fmt.Errorf("command cannot be empty")
 	}
 
@@ -145,11 +275,15 @@ func ValidateFactDef(name string, factDef FactDef) error {
 	if factDef.Export != "" && !exportVarRegex.MatchString(factDef.Export) {
 		return fmt.Errorf("export variable name must match pattern ^[A-Z_][A-Z0-9_]*$")
 	}
+	// Over SSH the exports travel on the command line, where ps shows them
+	if factDef.Export != "" && factDef.IsSecret() {
+		return fmt.Errorf("export is not supported for secret facts; template the value into the step's stdin instead")
+	}
 
 	// Validate platforms
 	for _, platform := range factDef.Platforms {
 		if !validPlatforms[platform] {
-			return fmt.Errorf("invalid platform '%s', must be one of: darwin, linux, windows", platform)
+			return fmt.Errorf("invalid platform '%s', must be one of: %s", platform, strings.Join(sortedKeys(validPlatforms), ", "))
 		}
 	}
 
@@ -180,6 +314,9 @@ func validatePlatform(platform *Platform) error {
 	if platform.OS == "" {
 		return fmt.Errorf("os is required")
 	}
+	if !validPlatforms[platform.OS] {
+		return fmt.Errorf("invalid os '%s', must be one of: %s", platform.OS, strings.Join(sortedKeys(validPlatforms), ", "))
+	}
 	if platform.Match == "" {
 		return fmt.Errorf("match pattern is required")
 	}
@@ -198,11 +335,35 @@ func validatePlatform(platform *Platform) error {
 		return fmt.Errorf("platform cannot have both install_steps and distributions")
 	}
 
+	if platform.Defaults != nil {
+		if err := validateStepDefaults(platform.Defaults); err != nil {
+			return fmt.Errorf("defaults: %w", err)
+		}
+	}
+	if err := validateFailurePolicy(platform.FailurePolicy); err != nil {
+		return err
+	}
+
+	if err := validateStepAnnotations(platform.InstallSteps); err != nil {
+		return err
+	}
+	if err := validateUniqueStepNames(platform.InstallSteps); err != nil {
+		return err
+	}
+
 	// Validate distributions if present
+	seenIDs := map[string]int{}
 	for i, dist := range platform.Distributions {
 		if err := validateDistribution(&dist); err != nil {
 			return fmt.Errorf("distribution[%d] %s: %w", i, dist.Name, err)
 		}
+		for _, id := range dist.IDs {
+			if first, ok := seenIDs[id]; ok {
+				return fmt.Errorf("distribution[%d] %s: id '%s' is already used by distribution[%d] %s",
+					i, dist.Name, id, first, platform.Distributions[first].Name)
+			}
+			seenIDs[id] = i
+		}
 	}
 
 	return nil
@@ -219,5 +380,251 @@ func validateDistribution(dist *Distribution) error {
 	if len(dist.InstallSteps) == 0 {
 		return fmt.Errorf("at least one install step is required")
 	}
+	if err := validateStepAnnotations(dist.InstallSteps); err != nil {
+		return err
+	}
+	return validateUniqueStepNames(dist.InstallSteps)
+}
+
+// validateUniqueStepNames rejects repeated step names, which make results,
+// events and step selection ambiguous
+func validateUniqueStepNames(steps []InstallStep) error {
+	seen := map[string]int{}
+	for i, step := range steps {
+		if first, ok := seen[step.Name]; ok {
+			return fmt.Errorf("install_step[%d]: duplicate step name '%s' (first used by install_step[%d])", i, step.Name, first)
+		}
+		seen[step.Name] = i
+	}
+	return nil
+}
+
+// validateStepAnnotations checks that every step window parses, every
+// declared risk level is known, annotation keys are well-formed, exit code
+// policies are valid and copy and fetch steps describe their files
+func validateStepAnnotations(steps []InstallStep) error {
+	for i, step := range steps {
+		switch step.Risk {
+		case "", RiskLow, RiskMedium, RiskHigh:
+		default:
+			return fmt.Errorf("install_step[%d] %s: invalid risk '%s', must be one of: low, medium, high", i, step.Name, step.Risk)
+		}
+		for _, key := range sortedKeys(step.Annotations) {
+			if !annotationKeyRegex.MatchString(key) {
+				return fmt.Errorf("install_step[%d] %s: invalid annotation key '%s' (use letters, digits, _, . and -, starting with a letter)", i, step.Name, key)
+			}
+		}
+		if err := validateFailurePolicy(step.FailurePolicy); err != nil {
+			return fmt.Errorf("install_step[%d] %s: %w", i, step.Name, err)
+		}
+		if err := validateExitPolicies(step.Step); err != nil {
+			return fmt.Errorf("install_step[%d] %s: %w", i, step.Name, err)
+		}
+		switch v := step.Step.(type) {
+		case CopyStep:
+			if err := v.Copy.validate(); err != nil {
+				return fmt.Errorf("install_step[%d] %s: copy: %w", i, step.Name, err)
+			}
+		case FetchStep:
+			if err := v.Fetch.validate(); err != nil {
+				return fmt.Errorf("install_step[%d] %s: fetch: %w", i, step.Name, err)
+			}
+		case VerifyChecksumStep:
+			if err := v.VerifyChecksum.validate(); err != nil {
+				return fmt.Errorf("install_step[%d] %s: verify_checksum: %w", i, step.Name, err)
+			}
+		case PauseStep:
+			if err := v.Pause.validate(); err != nil {
+				return fmt.Errorf("install_step[%d] %s: pause: %w", i, step.Name, err)
+			}
+		case GroupStep:
+			if err := v.Group.validate(); err != nil {
+				return fmt.Errorf("install_step[%d] %s: group: %w", i, step.Name, err)
+			}
+		}
+		if step.Window == "" {
+			continue
+		}
+		if _, err := ParseWindow(step.Window); err != nil {
+			return fmt.Errorf("install_step[%d] %s: window: %w", i, step.Name, err)
+		}
+	}
+	return nil
+}
+
+// validateExitPolicies checks success_exit_codes and retry_on of a step and
+// its remediations
+func validateExitPolicies(step StepVariant) error {
+	switch v := step.(type) {
+	case CommandStep:
+		if err := validateSuccessExitCodes(v.SuccessExitCodes); err != nil {
+			return fmt.Errorf("success_exit_codes: %w", err)
+		}
+		if err := validateRetryOn(v.RetryOn); err != nil {
+			return fmt.Errorf("retry_on: %w", err)
+		}
+	case CheckRemediateStep:
+		for j, rem := range v.OnMissing {
+			if err := validateRetryOn(rem.RetryOn); err != nil {
+				return fmt.Errorf("on_missing[%d] %s: retry_on: %w", j, rem.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validateRetryOn checks that retry_on has a valid criterion
+func validateRetryOn(r *RetryOn) error {
+	if r == nil {
+		return nil
+	}
+	if len(r.ExitCodes) == 0 && r.OutputMatches == "" {
+		return fmt.Errorf("set exit_codes, output_matches or both")
+	}
+	for _, code := range r.ExitCodes {
+		if code < 0 || code > 255 {
+			return fmt.Errorf("invalid exit code %d (must be 0-255)", code)
+		}
+	}
+	if r.OutputMatches != "" {
+		if _, err := regexp.Compile(r.OutputMatches); err != nil {
+			return fmt.Errorf("invalid output_matches pattern: %w", err)
+		}
+	}
+	return nil
+}
+
+// validateSuccessExitCodes checks a success_exit_codes list. Omitting it
+// means [0]; an empty list would make every run fail.
+func validateSuccessExitCodes(codes []int) error {
+	if codes != nil && len(codes) == 0 {
+		return fmt.Errorf("must list at least one exit code")
+	}
+	for _, code := range codes {
+		if code < 0 || code > 255 {
+			return fmt.Errorf("invalid exit code %d (must be 0-255)", code)
+		}
+	}
+	return nil
+}
+
+// validateRefreshFacts checks that refresh_facts only names defined facts
+func validateRefreshFacts(steps []InstallStep, facts map[string]FactDef) error {
+	for i, step := range steps {
+		for _, name := range step.RefreshFacts {
+			if _, ok := facts[name]; !ok {
+				return fmt.Errorf("install_step[%d] %s: refresh_facts: unknown fact '%s'", i, step.Name, name)
+			}
+		}
+	}
 	return nil
 }
+
+// validateStepDefaults validates step policy defaults
+func validateStepDefaults(d *StepDefaults) error {
+	if d.Retry != nil && *d.Retry != "until" {
+		return fmt.Errorf("retry must be 'until', got '%s'", *d.Retry)
+	}
+	if d.Sleep != nil {
+		if _, err := time.ParseDuration(*d.Sleep); err != nil {
+			return fmt.Errorf("invalid sleep duration '%s': %w", *d.Sleep, err)
+		}
+	}
+	if _, _, err := parseTimeoutConfig(d.Timeout); err != nil {
+		return fmt.Errorf("timeout: %w", err)
+	}
+	return nil
+}
+
+// applyStepDefaults fills unset timeout, retry, sleep and verbose fields on
+// command and remediation steps. Step values win over platform defaults, which
+// win over config defaults. Verbose defaults can only turn verbosity on.
+func applyStepDefaults(config *Config) {
+	var configDefaults StepDefaults
+	if config.Defaults != nil {
+		configDefaults = config.Defaults.StepDefaults
+	}
+
+	for i := range config.Platforms {
+		platform := &config.Platforms[i]
+		defaults := configDefaults.overlay(platform.Defaults)
+
+		for j := range platform.InstallSteps {
+			platform.InstallSteps[j].Step = defaults.applyTo(platform.InstallSteps[j].Step)
+		}
+		for di := range platform.Distributions {
+			dist := &platform.Distributions[di]
+			for j := range dist.InstallSteps {
+				dist.InstallSteps[j].Step = defaults.applyTo(dist.InstallSteps[j].Step)
+			}
+		}
+	}
+}
+
+// overlay returns d with every field set in override replacing it
+func (d StepDefaults) overlay(override *StepDefaults) StepDefaults {
+	if override == nil {
+		return d
+	}
+	if len(override.Timeout) > 0 {
+		d.Timeout = override.Timeout
+	}
+	if override.Retry != nil {
+		d.Retry = override.Retry
+	}
+	if override.Sleep != nil {
+		d.Sleep = override.Sleep
+	}
+	if override.Verbose != nil {
+		d.Verbose = override.Verbose
+	}
+	return d
+}
+
+// applyTo fills unset policy fields of a step variant
+func (d StepDefaults) applyTo(step StepVariant) StepVariant {
+	switch v := step.(type) {
+	case CommandStep:
+		if len(v.Timeout) == 0 {
+			v.Timeout = d.Timeout
+		}
+		if v.Retry == nil {
+			v.Retry = d.Retry
+		}
+		if v.Sleep == nil {
+			v.Sleep = d.Sleep
+		}
+		if d.Verbose != nil && *d.Verbose {
+			v.Verbose = true
+		}
+		return v
+	case CheckRemediateStep:
+		remediation := make([]RemediationStep, len(v.OnMissing))
+		for i, rem := range v.OnMissing {
+			if len(rem.Timeout) == 0 {
+				rem.Timeout = d.Timeout
+			}
+			if rem.Retry == nil {
+				rem.Retry = d.Retry
+			}
+			if rem.Sleep == nil {
+				rem.Sleep = d.Sleep
+			}
+			if d.Verbose != nil && *d.Verbose {
+				rem.Verbose = true
+			}
+			remediation[i] = rem
+		}
+		v.OnMissing = remediation
+		return v
+	case GroupStep:
+		members := make([]InstallStep, len(v.Group.Steps))
+		for i, member := range v.Group.Steps {
+			member.Step = d.applyTo(member.Step)
+			members[i] = member
+		}
+		v.Group.Steps = members
+		return v
+	}
+	return step
+}
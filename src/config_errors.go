@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// configFrameContext is how many lines before the offending one a code
+// frame shows
+const configFrameContext = 2
+
+// ConfigSyntaxError locates a JSON syntax error in a config's source, so
+// "invalid character ']'" comes with the line it is on
+type ConfigSyntaxError struct {
+	File   string // Config file, when known
+	Offset int64  // Byte offset of the offending character
+	Line   int    // 1-based line
+	Column int    // 1-based column, in characters
+	Frame  string // The offending line and the lines before it, with a caret under the column
+	Err    *json.SyntaxError
+}
+
+// Error implements error
+func (e *ConfigSyntaxError) Error() string {
+	location := fmt.Sprintf("line %d, column %d", e.Line, e.Column)
+	if e.File != "" {
+		location = fmt.Sprintf("%s:%d:%d", e.File, e.Line, e.Column)
+	}
+	return fmt.Sprintf("%s: %v\n%s", location, e.Err, e.Frame)
+}
+
+// Unwrap returns the underlying *json.SyntaxError
+func (e *ConfigSyntaxError) Unwrap() error {
+	return e.Err
+}
+
+// locateSyntaxError converts a *json.SyntaxError from parsing data, read
+// from file, into a ConfigSyntaxError; other errors are returned unchanged
+func locateSyntaxError(file string, data []byte, err error) error {
+	var syntax *json.SyntaxError
+	if !errors.As(err, &syntax) {
+		return err
+	}
+	// Offset counts the bytes read, including the offending one; at the end
+	// of the input nothing is wrong but what is missing
+	offset := int(syntax.Offset) - 1
+	if strings.Contains(syntax.Error(), "unexpected end of JSON input") {
+		offset = len(strings.TrimRight(string(data), " \t\r\n"))
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > len(data) {
+		offset = len(data)
+	}
+	line, column := lineColumn(data, offset)
+	return &ConfigSyntaxError{
+		File:   file,
+		Offset: int64(offset),
+		Line:   line,
+		Column: column,
+		Frame:  codeFrame(string(data), line, column),
+		Err:    syntax,
+	}
+}
+
+// lineColumn returns the 1-based line and character column of a byte offset
+func lineColumn(data []byte, offset int) (line, column int) {
+	before := data[:offset]
+	lineStart := strings.LastIndexByte(string(before), '\n') + 1
+	return strings.Count(string(before), "\n") + 1, utf8.RuneCount(before[lineStart:]) + 1
+}
+
+// codeFrame renders the lines up to line with a caret under column, e.g.
+//
+//	  11 |     {"name": "b", "command": "echo"},
+//	> 12 |   ],
+//	     |   ^
+func codeFrame(text string, line, column int) string {
+	lines := strings.Split(text, "\n")
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+	first := line - configFrameContext
+	if first < 1 {
+		first = 1
+	}
+	width := len(fmt.Sprint(line))
+
+	var b strings.Builder
+	for n := first; n <= line; n++ {
+		marker := " "
+		if n == line {
+			marker = ">"
+		}
+		fmt.Fprintf(&b, "%s %*d | %s\n", marker, width, n, strings.TrimRight(lines[n-1], "\r"))
+	}
+	// Keep tabs so the caret lines up however the terminal expands them
+	var pad strings.Builder
+	for i, r := range []rune(lines[line-1]) {
+		if i >= column-1 {
+			break
+		}
+		if r == '\t' {
+			pad.WriteRune('\t')
+		} else {
+			pad.WriteByte(' ')
+		}
+	}
+	fmt.Fprintf(&b, "  %s | %s^", strings.Repeat(" ", width), pad.String())
+	return b.String()
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestLocateSyntaxError tests locating JSON syntax errors in config source
+func TestLocateSyntaxError(t *testing.T) {
+	tests := []struct {
+		name         string
+		source       string
+		line, column int
+		frame        string
+	}{
+		{
+			name:   "trailing comma",
+			source: "{\n  \"version\": \"1.0.0\",\n  \"platforms\": [\n    {\"name\": \"a\"},\n  ]\n}\n",
+			line:   5, column: 3,
+			frame: "  3 |   \"platforms\": [\n  4 |     {\"name\": \"a\"},\n> 5 |   ]\n    |   ^",
+		},
+		{
+			name:   "truncated",
+			source: "{\"version\": \"1.0.0\",\n",
+			line:   1, column: 21,
+			frame: "> 1 | {\"version\": \"1.0.0\",\n    |                     ^",
+		},
+		{
+			name:   "tab indentation and multi-byte text",
+			source: "{\n\t\"description\": \"héllo\" \"x\"\n}",
+			line:   2, column: 25,
+			frame: "  1 | {\n> 2 | \t\"description\": \"héllo\" \"x\"\n    | \t                       ^",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v interface{}
+			err := locateSyntaxError("config.json", []byte(tt.source), json.Unmarshal([]byte(tt.source), &v))
+			var located *ConfigSyntaxError
+			if !errors.As(err, &located) {
+				t.Fatalf("expected a ConfigSyntaxError, got %v", err)
+			}
+			if located.Line != tt.line || located.Column != tt.column {
+				t.Errorf("located at %d:%d, want %d:%d", located.Line, located.Column, tt.line, tt.column)
+			}
+			if located.Frame != tt.frame {
+				t.Errorf("frame:\n%s\nwant:\n%s", located.Frame, tt.frame)
+			}
+			var syntax *json.SyntaxError
+			if !errors.As(err, &syntax) {
+				t.Error("expected the json.SyntaxError to stay reachable")
+			}
+		})
+	}
+
+	other := errors.New("not a syntax error")
+	if locateSyntaxError("config.json", nil, other) != other {
+		t.Error("expected other errors unchanged")
+	}
+}
+
+// TestLoadConfig_SyntaxErrorLocation tests that loading a config names the
+// file, line and column of a syntax error
+func TestLoadConfig_SyntaxErrorLocation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "broken.json")
+	if err := os.WriteFile(path, []byte("{\n  \"version\": \"1.0.0\"\n  \"platforms\": []\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := LoadConfig(path)
+	if err == nil || !strings.Contains(err.Error(), path+":3:3: invalid character '\"' after object key:value pair") {
+		t.Fatalf("expected a located error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "> 3 |   \"platforms\": []") {
+		t.Errorf("expected a code frame, got %v", err)
+	}
+}
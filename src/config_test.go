@@ -3,6 +3,8 @@ package main
 import (
 	"encoding/json"
 	"os"
+	"runtime"
+	"strings"
 	"testing"
 )
 
@@ -354,11 +356,20 @@ func TestFactDefinitionValidation(t *testing.T) {
 			factName: "os_type",
 			factDef: FactDef{
 				Command:   "uname -s",
-				Platforms: []string{"darwin", "freebsd"},
+				Platforms: []string{"darwin", "solaris"},
 			},
 			wantErr: true,
 			errMsg:  "invalid platform",
 		},
+		{
+			name:     "BSD platforms",
+			factName: "os_type",
+			factDef: FactDef{
+				Command:   "uname -s",
+				Platforms: []string{"freebsd", "openbsd"},
+			},
+			wantErr: false,
+		},
 		{
 			name:     "empty command",
 			factName: "test_fact",
@@ -935,3 +946,235 @@ func stringContains(s, substr string) bool {
 	}
 	return false
 }
+
+// TestStepDefaults tests that config and platform defaults fill unset step policy
+func TestStepDefaults(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "test-config-*.json")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	configJSON := `{
+		"version": "1.0.0",
+		"defaults": {
+			"package": "jq",
+			"timeout": "5m",
+			"sleep": "1s"
+		},
+		"platforms": [{
+			"os": "darwin",
+			"match": "darwin*",
+			"name": "macOS",
+			"defaults": {"retry": "until", "timeout": {"interval": "2m", "error_code": 124}, "verbose": true},
+			"install_steps": [
+				{"name": "inherits", "command": "echo a"},
+				{"name": "overrides", "command": "echo b", "timeout": "10s", "sleep": "0s"},
+				{"name": "remediate", "check": "false", "on_missing": [{"name": "fix", "command": "echo c"}]}
+			]
+		}, {
+			"os": "linux",
+			"match": "linux*",
+			"name": "Linux",
+			"install_steps": [{"name": "config only", "command": "echo d"}]
+		}]
+	}`
+	if _, err := tmpFile.Write([]byte(configJSON)); err != nil {
+		t.Fatalf("Failed to write temp file: %v", err)
+	}
+	tmpFile.Close()
+
+	config, err := LoadConfig(tmpFile.Name())
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	if config.Defaults.Values["package"] != "jq" {
+		t.Errorf("expected free-form default package=jq, got %v", config.Defaults.Values)
+	}
+
+	darwin := config.Platforms[0]
+	inherits := darwin.InstallSteps[0].Step.(CommandStep)
+	if string(inherits.Timeout) != `{"interval": "2m", "error_code": 124}` {
+		t.Errorf("expected platform timeout, got %s", inherits.Timeout)
+	}
+	if inherits.Retry == nil || *inherits.Retry != "until" {
+		t.Error("expected platform retry default")
+	}
+	if inherits.Sleep == nil || *inherits.Sleep != "1s" {
+		t.Error("expected config sleep default")
+	}
+	if !inherits.Verbose {
+		t.Error("expected platform verbose default")
+	}
+
+	overrides := darwin.InstallSteps[1].Step.(CommandStep)
+	if string(overrides.Timeout) != `"10s"` || *overrides.Sleep != "0s" {
+		t.Errorf("step values should win, got timeout=%s sleep=%s", overrides.Timeout, *overrides.Sleep)
+	}
+
+	fix := darwin.InstallSteps[2].Step.(CheckRemediateStep).OnMissing[0]
+	if fix.Retry == nil || *fix.Retry != "until" || !fix.Verbose {
+		t.Error("expected defaults on remediation steps")
+	}
+
+	linux := config.Platforms[1].InstallSteps[0].Step.(CommandStep)
+	if string(linux.Timeout) != `"5m"` || linux.Retry != nil || linux.Verbose {
+		t.Errorf("expected only config defaults on linux, got timeout=%s retry=%v verbose=%v", linux.Timeout, linux.Retry, linux.Verbose)
+	}
+}
+
+// TestStepDefaultsValidation tests rejection of invalid step defaults
+func TestStepDefaultsValidation(t *testing.T) {
+	until := "until"
+	always := "always"
+	bad := "soon"
+
+	tests := []struct {
+		name     string
+		defaults StepDefaults
+		wantErr  bool
+	}{
+		{"valid", StepDefaults{Retry: &until, Timeout: json.RawMessage(`"30s"`)}, false},
+		{"invalid retry", StepDefaults{Retry: &always}, true},
+		{"invalid sleep", StepDefaults{Sleep: &bad}, true},
+		{"invalid timeout", StepDefaults{Timeout: json.RawMessage(`"forever"`)}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateStepDefaults(&tt.defaults)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateStepDefaults() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestStepAnnotations tests parsing and validation of step impact and risk
+func TestStepAnnotations(t *testing.T) {
+	configJSON := `{
+		"version": "1.0.0",
+		"platforms": [{
+			"os": "linux", "match": "linux*", "name": "Linux",
+			"install_steps": [
+				{"name": "Reload nginx", "command": "systemctl reload nginx", "impact": "restarts nginx", "risk": "high"},
+				{"name": "Check", "check": "true", "error": "never", "risk": "low"}
+			]
+		}]
+	}`
+
+	var config Config
+	if err := json.Unmarshal([]byte(configJSON), &config); err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	if err := ValidateConfig(&config); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+	steps := config.Platforms[0].InstallSteps
+	if steps[0].Impact != "restarts nginx" || steps[0].Risk != RiskHigh || steps[1].Risk != RiskLow {
+		t.Errorf("annotations not parsed: %+v", steps)
+	}
+	if high := highRiskSteps(steps); len(high) != 1 || high[0].Name != "Reload nginx" {
+		t.Errorf("unexpected high-risk steps: %+v", high)
+	}
+
+	config.Platforms[0].InstallSteps[1].Risk = "extreme"
+	if err := ValidateConfig(&config); err == nil {
+		t.Error("expected error for unknown risk level")
+	}
+}
+
+// TestStepCustomAnnotations tests parsing, validating and emitting step annotations
+func TestStepCustomAnnotations(t *testing.T) {
+	var step InstallStep
+	if err := json.Unmarshal([]byte(`{"name": "Docker", "command": "true", "annotations": {"ticket": "OPS-1234", "owner": "platform-team"}}`), &step); err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	if step.Annotations["ticket"] != "OPS-1234" || step.Annotations["owner"] != "platform-team" {
+		t.Errorf("annotations not parsed: %v", step.Annotations)
+	}
+	if err := json.Unmarshal([]byte(`{"name": "Docker", "command": "true", "annotations": {"count": 3}}`), &InstallStep{}); err == nil {
+		t.Error("expected error for a non-string annotation")
+	}
+	if err := validateStepAnnotations([]InstallStep{{Name: "Docker", Annotations: map[string]string{"cost center": "x"}, Step: CommandStep{Command: "true"}}}); err == nil {
+		t.Error("expected error for an annotation key with a space")
+	}
+
+	var events []ExecutionEvent
+	executor := NewExecutor(&MockTransport{responses: map[string]MockResponse{}})
+	executor.OnEvent = func(event ExecutionEvent) { events = append(events, event) }
+	executor.ExecuteStep(step, Facts{})
+	if len(events) != 2 {
+		t.Fatalf("expected running and completion events, got %d", len(events))
+	}
+	for _, event := range events {
+		if event.Annotations["ticket"] != "OPS-1234" {
+			t.Errorf("%s event missing annotations: %v", event.Status, event.Annotations)
+		}
+	}
+}
+
+// TestScriptSteps tests parsing multi-line scripts into command steps
+func TestScriptSteps(t *testing.T) {
+	tests := []struct {
+		name    string
+		step    string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "array of lines",
+			step: `{"name": "Build", "script": ["cd /src", "make", "make install"]}`,
+			want: scriptPrelude + "cd /src\nmake\nmake install",
+		},
+		{
+			name: "multi-line string",
+			step: `{"name": "Build", "script": "cd /src\nmake\n"}`,
+			want: scriptPrelude + "cd /src\nmake",
+		},
+		{
+			name: "not strict",
+			step: `{"name": "Build", "script": ["grep -q x /etc/hosts || echo missing"], "script_strict": false}`,
+			want: "grep -q x /etc/hosts || echo missing",
+		},
+		{name: "both", step: `{"name": "Build", "command": "make", "script": ["make"]}`, wantErr: "use either command or script"},
+		{name: "empty", step: `{"name": "Build", "script": []}`, wantErr: "script is empty"},
+		{name: "wrong type", step: `{"name": "Build", "script": 42}`, wantErr: "script must be a string or an array of lines"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var step InstallStep
+			err := json.Unmarshal([]byte(tt.step), &step)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to parse: %v", err)
+			}
+			if cmd := step.Step.(CommandStep); cmd.Command != tt.want {
+				t.Errorf("Command = %q, want %q", cmd.Command, tt.want)
+			}
+		})
+	}
+}
+
+// TestScriptStrictMode tests that scripts stop at the first failing line
+func TestScriptStrictMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("scripts need a POSIX shell")
+	}
+	var step InstallStep
+	if err := json.Unmarshal([]byte(`{"name": "Build", "script": ["echo one", "false", "echo two"]}`), &step); err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	result := NewExecutor(NewLocalTransport()).ExecuteStep(step, Facts{})
+	if result.Error == "" || strings.Contains(result.Output, "two") {
+		t.Errorf("script should stop at the failing line, got output %q, error %q", result.Output, result.Error)
+	}
+}
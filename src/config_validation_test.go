@@ -230,6 +230,18 @@ func TestValidateFactDef_InvalidExportName(t *testing.T) {
 	}
 }
 
+func TestValidateFactDef_SecretExport(t *testing.T) {
+	for _, factDef := range []FactDef{
+		{Command: "cat /run/token", Secret: true, Export: "API_TOKEN"},
+		{Source: "vault:secret/data/app#token", Export: "API_TOKEN"},
+	} {
+		err := ValidateFactDef("api_token", factDef)
+		if err == nil || !strings.Contains(err.Error(), "export is not supported for secret facts") {
+			t.Errorf("Expected secret export error for %+v, got: %v", factDef, err)
+		}
+	}
+}
+
 func TestValidateFactDef_InvalidPlatform(t *testing.T) {
 	factDef := FactDef{
 		Command:   "echo test",
@@ -320,6 +332,25 @@ func TestValidatePlatform_MissingOS(t *testing.T) {
 	}
 }
 
+func TestValidatePlatform_InvalidOS(t *testing.T) {
+	for _, osName := range []string{"freebsd", "openbsd", "solaris"} {
+		platform := &Platform{
+			Name:         "Test Platform",
+			OS:           osName,
+			Match:        osName + "*",
+			InstallSteps: []InstallStep{{Name: "Test", Step: CommandStep{Command: "test"}}},
+		}
+		err := validatePlatform(platform)
+		if osName == "solaris" {
+			if err == nil || !strings.Contains(err.Error(), "invalid os 'solaris', must be one of: darwin, freebsd, linux, openbsd, windows") {
+				t.Errorf("Expected an invalid os error, got: %v", err)
+			}
+		} else if err != nil {
+			t.Errorf("Expected %s to be valid, got: %v", osName, err)
+		}
+	}
+}
+
 func TestValidatePlatform_MissingMatch(t *testing.T) {
 	platform := &Platform{
 		Name:         "Test Platform",
@@ -511,3 +542,132 @@ func TestLoadConfig_CompleteValidation(t *testing.T) {
 		t.Errorf("Expected 1 platform, got %d", len(config.Platforms))
 	}
 }
+
+// TestDuplicateDetection tests rejection of duplicate step names, distribution IDs and facts
+func TestDuplicateDetection(t *testing.T) {
+	step := func(name string) InstallStep {
+		return InstallStep{Name: name, Step: CommandStep{Command: "true"}}
+	}
+
+	tests := []struct {
+		name     string
+		platform Platform
+		want     string
+	}{
+		{
+			name: "duplicate step names",
+			platform: Platform{OS: "linux", Match: "linux*", Name: "Linux",
+				InstallSteps: []InstallStep{step("a"), step("b"), step("a")}},
+			want: "install_step[2]: duplicate step name 'a' (first used by install_step[0])",
+		},
+		{
+			name: "duplicate step names in distribution",
+			platform: Platform{OS: "linux", Match: "linux*", Name: "Linux",
+				Distributions: []Distribution{{IDs: []string{"ubuntu"}, Name: "Ubuntu", InstallSteps: []InstallStep{step("a"), step("a")}}}},
+			want: "distribution[0] Ubuntu: install_step[1]: duplicate step name 'a'",
+		},
+		{
+			name: "duplicate distribution IDs",
+			platform: Platform{OS: "linux", Match: "linux*", Name: "Linux",
+				Distributions: []Distribution{
+					{IDs: []string{"ubuntu", "debian"}, Name: "Debian family", InstallSteps: []InstallStep{step("a")}},
+					{IDs: []string{"fedora", "debian"}, Name: "Other", InstallSteps: []InstallStep{step("a")}},
+				}},
+			want: "distribution[1] Other: id 'debian' is already used by distribution[0] Debian family",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateConfig(&Config{Version: "1.0.0", Platforms: []Platform{tt.platform}})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	err := checkDuplicateKeys([]byte(`{"facts":{"arch":{"command":"uname -m"},"arch":{"command":"arch"}},"platforms":[{"name":"a","name":"b"}]}`))
+	if err == nil || !strings.Contains(err.Error(), "duplicate fact 'arch'") || !strings.Contains(err.Error(), "duplicate key 'platforms[0].name'") {
+		t.Errorf("expected duplicate fact and key errors, got %v", err)
+	}
+	if err := checkDuplicateKeys([]byte(`{"a":{"x":1},"b":{"x":1},"c":[{"x":1},{"x":2}]}`)); err != nil {
+		t.Errorf("unexpected error for distinct keys: %v", err)
+	}
+}
+
+// TestValidateConfig_RefreshFactsUnknown tests that refresh_facts must name defined facts
+func TestValidateConfig_RefreshFactsUnknown(t *testing.T) {
+	config := &Config{
+		Version: "1.0.0",
+		Facts:   map[string]FactDef{"has_docker": {Command: "command -v docker"}},
+		Platforms: []Platform{{
+			OS: "linux", Match: "linux*", Name: "Linux",
+			InstallSteps: []InstallStep{
+				{Name: "Install", RefreshFacts: []string{"has_docker", "docker_version"}, Step: CommandStep{Command: "install"}},
+			},
+		}},
+	}
+
+	err := ValidateConfig(config)
+	if err == nil || !strings.Contains(err.Error(), "refresh_facts: unknown fact 'docker_version'") {
+		t.Errorf("expected unknown fact error, got %v", err)
+	}
+}
+
+// TestValidateConfig_SuccessExitCodes tests validation of success_exit_codes
+func TestValidateConfig_SuccessExitCodes(t *testing.T) {
+	tests := []struct {
+		name  string
+		codes []int
+		want  string
+	}{
+		{"valid", []int{0, 1}, ""},
+		{"empty", []int{}, "success_exit_codes: must list at least one exit code"},
+		{"out of range", []int{0, 256}, "success_exit_codes: invalid exit code 256"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateConfig(&Config{Version: "1.0.0", Platforms: []Platform{{
+				OS: "linux", Match: "linux*", Name: "Linux",
+				InstallSteps: []InstallStep{{Name: "Compare", Step: CommandStep{Command: "diff a b", SuccessExitCodes: tt.codes}}},
+			}}})
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+// TestValidateConfig_RetryOn tests validation of retry_on on commands and remediations
+func TestValidateConfig_RetryOn(t *testing.T) {
+	tests := []struct {
+		name string
+		step StepVariant
+		want string
+	}{
+		{"empty", CommandStep{Command: "fetch", RetryOn: &RetryOn{}}, "retry_on: set exit_codes, output_matches or both"},
+		{"bad pattern", CommandStep{Command: "fetch", RetryOn: &RetryOn{OutputMatches: "("}}, "retry_on: invalid output_matches pattern"},
+		{"remediation", CheckRemediateStep{Check: "test -f x", OnMissing: []RemediationStep{
+			{Name: "fetch", Command: "fetch", RetryOn: &RetryOn{ExitCodes: []int{-1}}},
+		}}, "on_missing[0] fetch: retry_on: invalid exit code -1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateConfig(&Config{Version: "1.0.0", Platforms: []Platform{{
+				OS: "linux", Match: "linux*", Name: "Linux",
+				InstallSteps: []InstallStep{{Name: "Fetch", Step: tt.step}},
+			}}})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Confirm customizes the prompt shown before a real run, for configs that
+// format disks, migrate services or otherwise cannot be undone
+type Confirm struct {
+	Message string `json:"message,omitempty"` // Shown above the prompt (templated with facts)
+	Danger  bool   `json:"danger,omitempty"`  // Require typing the host name instead of "yes"
+}
+
+// confirmRun shows the confirmation message and prompt and reports whether
+// the answer approves the run: "yes", or the host name for a dangerous config
+func confirmRun(in *bufio.Reader, out io.Writer, confirm *Confirm, message, host string) (bool, error) {
+	if message != "" {
+		for _, line := range strings.Split(strings.TrimRight(message, "\n"), "\n") {
+			fmt.Fprintf(out, "   %s\n", line)
+		}
+	}
+	want := "yes"
+	if confirm != nil && confirm.Danger {
+		want = host
+		fmt.Fprintf(out, "   🚨 This config is marked dangerous. Type the host name (%s) to continue: ", host)
+	} else {
+		fmt.Fprint(out, "   Continue? [yes/no]: ")
+	}
+
+	line, err := in.ReadString('\n')
+	if err != nil && line == "" {
+		fmt.Fprintln(out)
+		return false, fmt.Errorf("end of input")
+	}
+	return strings.TrimSpace(line) == want, nil
+}
+
+// checkConfirmHost checks a --confirm-host acknowledgement against the host
+// the run is about to change. Dangerous configs need one to run without a
+// prompt (--json).
+func checkConfirmHost(confirm *Confirm, confirmHost, host string, interactive bool) error {
+	if confirmHost != "" && confirmHost != host {
+		return fmt.Errorf("--confirm-host %s does not match this host (%s)", confirmHost, host)
+	}
+	if confirm != nil && confirm.Danger && confirmHost == "" && !interactive {
+		return fmt.Errorf("config is marked dangerous; pass --confirm-host %s to run it without a prompt", host)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestConfirmRun tests the default, custom and dangerous confirmation prompts
+func TestConfirmRun(t *testing.T) {
+	tests := []struct {
+		name    string
+		confirm *Confirm
+		message string
+		answer  string
+		want    bool
+		prompt  string
+	}{
+		{"default yes", nil, "", "yes\n", true, "   Continue? [yes/no]: "},
+		{"default no", nil, "", "no\n", false, "   Continue? [yes/no]: "},
+		{"message", &Confirm{Message: "x"}, "Erases /dev/sdb\nBack up first", "yes\n", true,
+			"   Erases /dev/sdb\n   Back up first\n   Continue? [yes/no]: "},
+		{"danger needs the host name", &Confirm{Danger: true}, "", "yes\n", false,
+			"   🚨 This config is marked dangerous. Type the host name (db1) to continue: "},
+		{"danger confirmed", &Confirm{Danger: true}, "", "db1\n", true,
+			"   🚨 This config is marked dangerous. Type the host name (db1) to continue: "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			got, err := confirmRun(bufio.NewReader(strings.NewReader(tt.answer)), &out, tt.confirm, tt.message, "db1")
+			if err != nil || got != tt.want || out.String() != tt.prompt {
+				t.Errorf("got %v, %v with prompt %q, want %v with %q", got, err, out.String(), tt.want, tt.prompt)
+			}
+		})
+	}
+
+	if ok, err := confirmRun(bufio.NewReader(strings.NewReader("")), &bytes.Buffer{}, nil, "", "db1"); ok || err == nil {
+		t.Errorf("expected end of input to cancel, got %v, %v", ok, err)
+	}
+}
+
+// TestCheckConfirmHost tests --confirm-host against dangerous configs and non-interactive runs
+func TestCheckConfirmHost(t *testing.T) {
+	danger := &Confirm{Danger: true}
+	tests := []struct {
+		name        string
+		confirm     *Confirm
+		confirmHost string
+		interactive bool
+		wantErr     string
+	}{
+		{"plain config in json mode", nil, "", false, ""},
+		{"danger interactive", danger, "", true, ""},
+		{"danger in json mode", danger, "", false, "pass --confirm-host db1"},
+		{"danger acknowledged", danger, "db1", false, ""},
+		{"wrong host", nil, "db2", true, "--confirm-host db2 does not match this host (db1)"},
+	}
+	for _, tt := range tests {
+		err := checkConfirmHost(tt.confirm, tt.confirmHost, "db1", tt.interactive)
+		if (tt.wantErr == "") != (err == nil) || (err != nil && !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s: got %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+
+	config, err := parseConfigData([]byte(`{"version": "1.0.0", "confirm": {"message": "Formats {{.disk}}", "danger": true},
+		"platforms": [{"os": "linux", "match": "Linux", "name": "Linux", "install_steps": [{"name": "a", "command": "true"}]}]}`), LoadOptions{Strict: true})
+	if err != nil || config.Confirm == nil || !config.Confirm.Danger || config.Confirm.Message != "Formats {{.disk}}" {
+		t.Errorf("expected the confirm section to parse, got %+v, %v", config, err)
+	}
+}
@@ -0,0 +1,528 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// consoleHistoryMax is how many lines of console history are kept on disk
+const consoleHistoryMax = 1000
+
+// Console is an interactive session against one config: facts are gathered
+// once, then single steps and ad-hoc commands run through the configured
+// transport on demand
+type Console struct {
+	ConfigFile  string
+	Platform    string            // Platform override (default: this OS)
+	Variables   map[string]string // Variable values from --var
+	Out         io.Writer
+	HistoryFile string // Where history persists between sessions ("" keeps it in memory)
+
+	editor    *lineEditor
+	raw       bool // Read lines with the line editor in raw terminal mode
+	transport Transport
+	platform  *Platform
+	facts     Facts
+	executor  *Executor
+}
+
+// NewConsole creates a console reading commands from in. When in is a
+// terminal, lines are edited in raw mode to offer history and completion.
+func NewConsole(configFile string, transport Transport, in io.Reader, out io.Writer) *Console {
+	c := &Console{ConfigFile: configFile, Out: out, transport: transport}
+	c.editor = &lineEditor{in: bufio.NewReader(in), out: out, complete: c.complete}
+	if f, ok := in.(*os.File); ok && isTerminal(f) {
+		c.raw = true
+	}
+	return c
+}
+
+// consoleCommand runs "sink console <config>"
+func consoleCommand() {
+	var configFile string
+	env := loadEnvSettings()
+	platformOverride := env.Platform
+	variables := map[string]string{}
+
+	args := os.Args[2:]
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch arg {
+		case "-h", "--help":
+			printConsoleHelp()
+			os.Exit(0)
+		case "--platform":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --platform requires a value\n")
+				os.Exit(1)
+			}
+			platformOverride = args[i+1]
+			i++
+		case "--var":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --var requires name=value\n")
+				os.Exit(1)
+			}
+			name, value, err := parseVarFlag(args[i+1])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			variables[name] = value
+			i++
+		default:
+			if strings.HasPrefix(arg, "-") || configFile != "" {
+				fmt.Fprintf(os.Stderr, "Error: unexpected argument: %s\n", arg)
+				os.Exit(1)
+			}
+			configFile = arg
+		}
+	}
+
+	if configFile == "" {
+		fmt.Fprintf(os.Stderr, "Error: config file required\n\n")
+		printConsoleHelp()
+		os.Exit(1)
+	}
+
+	console := NewConsole(configFile, NewLocalTransport(), os.Stdin, os.Stdout)
+	console.Platform = platformOverride
+	console.Variables = variables
+	if path, err := statePath("console_history"); err == nil {
+		console.HistoryFile = path
+	}
+	if err := console.Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// load (re)loads the config, gathers facts and selects the platform
+func (c *Console) load() error {
+	config, err := LoadConfig(c.ConfigFile)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	variables, err := ResolveVariables(config.Variables, c.Variables)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(c.Out, "📊 Gathering facts...")
+	gatherer := NewFactGatherer(config.Facts, c.transport)
+	facts, err := gatherer.Gather()
+	if err != nil {
+		return fmt.Errorf("gathering facts: %w", err)
+	}
+	activeRedactor = NewRedactor(config.Facts, facts)
+	for name, value := range variables {
+		facts[name] = value
+	}
+
+	targetOS := runtime.GOOS
+	if c.Platform != "" {
+		targetOS = c.Platform
+	}
+	var platform *Platform
+	for i := range config.Platforms {
+		if config.Platforms[i].OS == targetOS {
+			platform = &config.Platforms[i]
+			break
+		}
+	}
+	if platform == nil {
+		return fmt.Errorf("no platform configuration found for %s", targetOS)
+	}
+	if platform, _, err = selectDistribution(platform, detectDistributionIDs(c.transport)); err != nil {
+		return err
+	}
+
+	rateLimiter, err := NewRateLimiter(config.RateLimits)
+	if err != nil {
+		return err
+	}
+	executor := NewExecutor(c.transport)
+	executor.Gatherer = gatherer
+	executor.RateLimiter = rateLimiter
+	executor.Operator = &Operator{In: c.editor.in, Out: c.Out}
+	executor.SetVariables(variables)
+
+	c.platform, c.facts, c.executor = platform, facts, executor
+	fmt.Fprintf(c.Out, "   Gathered %d facts; platform %s (%s) has %d steps\n",
+		len(facts)-len(variables), platform.Name, platform.OS, len(platform.InstallSteps))
+	return nil
+}
+
+// Run loads the config and reads commands until exit or end of input
+func (c *Console) Run() error {
+	if err := c.load(); err != nil {
+		return err
+	}
+	c.editor.history = loadConsoleHistory(c.HistoryFile)
+	fmt.Fprintln(c.Out, "Type 'help' for commands.")
+
+	for {
+		line, err := c.readLine("sink> ")
+		if err != nil {
+			fmt.Fprintln(c.Out)
+			return nil
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		c.addHistory(line)
+		if !c.dispatch(line) {
+			return nil
+		}
+	}
+}
+
+// readLine reads one command, with the line editor on a terminal
+func (c *Console) readLine(prompt string) (string, error) {
+	if c.raw {
+		if restore, ok := rawTerminal(); ok {
+			defer restore()
+			return c.editor.readLine(prompt)
+		}
+	}
+	fmt.Fprint(c.Out, prompt)
+	line, err := c.editor.in.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return line, nil
+}
+
+// dispatch runs one console command, reporting false to end the session
+func (c *Console) dispatch(line string) bool {
+	cmd, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+
+	switch {
+	case cmd == "exit" || cmd == "quit" || cmd == "q":
+		return false
+	case cmd == "steps" || cmd == "ls":
+		c.listSteps()
+	case cmd == "run" || cmd == "r":
+		c.runStep(arg)
+	case cmd == "f" || cmd == "facts":
+		printFacts(c.Out, c.facts, arg)
+	case cmd == "!" || cmd == "sh":
+		runShell(c.Out, c.executor, arg, c.facts)
+	case strings.HasPrefix(cmd, "!"):
+		runShell(c.Out, c.executor, strings.TrimSpace(strings.TrimPrefix(line, "!")), c.facts)
+	case cmd == "reload":
+		if err := c.load(); err != nil {
+			fmt.Fprintf(c.Out, "Error: %v (keeping the previous config)\n", err)
+		}
+	case cmd == "history":
+		for i, entry := range c.editor.history {
+			fmt.Fprintf(c.Out, "  %4d  %s\n", i+1, entry)
+		}
+	case cmd == "h" || cmd == "help" || cmd == "?":
+		fmt.Fprint(c.Out, consoleHelp)
+	default:
+		fmt.Fprintf(c.Out, "Unknown command '%s'; type 'help' for commands\n", cmd)
+	}
+	return true
+}
+
+// listSteps prints the selected platform's steps with their numbers
+func (c *Console) listSteps() {
+	for i, step := range c.platform.InstallSteps {
+		fmt.Fprintf(c.Out, "  %2d  %s\n", i+1, step.Name)
+	}
+}
+
+// findStep looks a step up by exact name or by its number in "steps"
+func (c *Console) findStep(arg string) (InstallStep, bool) {
+	for _, step := range c.platform.InstallSteps {
+		if step.Name == arg {
+			return step, true
+		}
+	}
+	if n, err := strconv.Atoi(arg); err == nil && n >= 1 && n <= len(c.platform.InstallSteps) {
+		return c.platform.InstallSteps[n-1], true
+	}
+	return InstallStep{}, false
+}
+
+// runStep runs one step with the session's facts. Facts re-gathered by
+// refresh_facts are kept for later commands.
+func (c *Console) runStep(arg string) {
+	if arg == "" {
+		fmt.Fprintln(c.Out, "Usage: run <step name or number>")
+		return
+	}
+	step, ok := c.findStep(arg)
+	if !ok {
+		fmt.Fprintf(c.Out, "No step named '%s'; type 'steps' to list them\n", arg)
+		return
+	}
+
+	fmt.Fprintf(c.Out, "▶ %s\n", step.Name)
+	start := time.Now()
+	result, facts := c.executor.executeStep(step, c.facts)
+	c.facts = facts
+	duration := formatTranscriptDuration(time.Since(start))
+
+	switch result.Status {
+	case "success":
+		fmt.Fprintf(c.Out, "  ✓ Success (%s)\n", duration)
+	case "failed":
+		fmt.Fprintf(c.Out, "  ✗ Failed (%s): %s\n", duration, result.Error)
+	default:
+		fmt.Fprintf(c.Out, "  %s (%s)\n", result.Status, duration)
+	}
+	if output := strings.TrimRight(result.Output, "\n"); output != "" {
+		for _, line := range strings.Split(output, "\n") {
+			fmt.Fprintf(c.Out, "    %s\n", line)
+		}
+	}
+}
+
+// complete returns the full lines that could complete line: command names
+// for the first word, step names after "run" and fact names after "facts"
+func (c *Console) complete(line string) []string {
+	cmd, arg, hasArg := strings.Cut(line, " ")
+	var words []string
+	switch {
+	case !hasArg:
+		words, arg = consoleCommands, cmd
+		cmd = ""
+	case cmd == "run" || cmd == "r":
+		for _, step := range c.platform.InstallSteps {
+			words = append(words, step.Name)
+		}
+	case cmd == "f" || cmd == "facts":
+		words = sortedKeys(c.facts)
+	}
+
+	var matches []string
+	for _, word := range words {
+		if strings.HasPrefix(word, arg) {
+			if cmd == "" {
+				matches = append(matches, word)
+			} else {
+				matches = append(matches, cmd+" "+word)
+			}
+		}
+	}
+	return matches
+}
+
+// addHistory records a command in memory and in the history file.
+// History is a convenience, so failing to write it is not an error.
+func (c *Console) addHistory(line string) {
+	history := c.editor.history
+	if len(history) > 0 && history[len(history)-1] == line {
+		return
+	}
+	c.editor.history = append(history, line)
+	if c.HistoryFile == "" {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(c.HistoryFile), 0755); err != nil {
+		return
+	}
+	f, err := os.OpenFile(c.HistoryFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	fmt.Fprintln(f, line)
+}
+
+// loadConsoleHistory reads the most recent history lines from path
+func loadConsoleHistory(path string) []string {
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var history []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			history = append(history, line)
+		}
+	}
+	if len(history) > consoleHistoryMax {
+		history = history[len(history)-consoleHistoryMax:]
+	}
+	return history
+}
+
+// lineEditor reads a line byte by byte from a raw terminal, handling
+// backspace, Ctrl-C/Ctrl-D/Ctrl-U, up/down history and tab completion
+type lineEditor struct {
+	in       *bufio.Reader
+	out      io.Writer
+	history  []string
+	complete func(line string) []string // Full lines that could complete line
+}
+
+// readLine reads one line, echoing and redrawing it itself. Ctrl-D on an
+// empty line returns io.EOF.
+func (l *lineEditor) readLine(prompt string) (string, error) {
+	buf := ""
+	pos := len(l.history) // History entry shown; len(history) is the draft
+	draft := ""
+	redraw := func() { fmt.Fprintf(l.out, "\r\033[K%s%s", prompt, buf) }
+
+	fmt.Fprint(l.out, prompt)
+	for {
+		b, err := l.in.ReadByte()
+		if err != nil {
+			if buf != "" {
+				fmt.Fprint(l.out, "\r\n")
+				return buf, nil
+			}
+			return "", err
+		}
+
+		switch b {
+		case '\r', '\n':
+			fmt.Fprint(l.out, "\r\n")
+			return buf, nil
+		case keyCtrlC:
+			fmt.Fprint(l.out, "^C\r\n")
+			return "", nil
+		case keyCtrlD:
+			if buf == "" {
+				return "", io.EOF
+			}
+		case keyBackspace, keyDelete:
+			if buf != "" {
+				_, size := utf8.DecodeLastRuneInString(buf)
+				buf = buf[:len(buf)-size]
+				redraw()
+			}
+		case keyCtrlU:
+			buf = ""
+			redraw()
+		case keyTab:
+			buf = l.completeLine(buf, prompt)
+			redraw()
+		case keyEscape:
+			// Arrow keys arrive as ESC [ A-D; other sequences are ignored
+			if next, _ := l.in.ReadByte(); next != '[' {
+				continue
+			}
+			switch key, _ := l.in.ReadByte(); key {
+			case 'A':
+				if pos > 0 {
+					if pos == len(l.history) {
+						draft = buf
+					}
+					pos--
+					buf = l.history[pos]
+					redraw()
+				}
+			case 'B':
+				if pos < len(l.history) {
+					pos++
+					if pos == len(l.history) {
+						buf = draft
+					} else {
+						buf = l.history[pos]
+					}
+					redraw()
+				}
+			}
+		default:
+			if b >= ' ' {
+				buf += string(b)
+				fmt.Fprintf(l.out, "%c", b)
+			}
+		}
+	}
+}
+
+// completeLine extends line to the longest prefix shared by its
+// completions, listing them when there is more than one
+func (l *lineEditor) completeLine(line, prompt string) string {
+	if l.complete == nil {
+		return line
+	}
+	matches := l.complete(line)
+	switch len(matches) {
+	case 0:
+		return line
+	case 1:
+		return matches[0] + " "
+	}
+
+	prefix := matches[0]
+	for _, match := range matches[1:] {
+		for !strings.HasPrefix(match, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	if len(prefix) > len(line) {
+		return prefix
+	}
+	fmt.Fprint(l.out, "\r\n")
+	for _, match := range matches {
+		fmt.Fprintf(l.out, "  %s\r\n", match)
+	}
+	return line
+}
+
+// consoleCommands are the first words the console completes
+var consoleCommands = []string{"exit", "facts", "help", "history", "reload", "run", "sh", "steps"}
+
+const consoleHelp = `  steps, ls             List the platform's steps with their numbers
+  run <step|number>     Run one step (tab completes step names)
+  facts [prefix]        Show gathered facts (optionally only names with prefix)
+  ! <command>           Run a command on the target ({{.fact}} templates work)
+  reload                Re-read the config and gather facts again
+  history               Show command history
+  help                  Show this help
+  exit                  Leave the console (or Ctrl-D)
+`
+
+func printConsoleHelp() {
+	fmt.Print(`sink console - Interactively run steps and commands from a config
+
+Usage:
+  sink console <config> [options]
+
+Description:
+  Loads the config, gathers its facts and selects the platform, then
+  offers a prompt for running single steps or ad-hoc commands through the
+  configured transport. Commands are interpolated with the gathered facts,
+  so templates can be tried out before they go into a step. Use "reload"
+  after editing the config to pick up changes without leaving the console.
+
+  On a terminal, the prompt has history (up/down, kept in the sink cache
+  directory) and tab completion of commands, step names and fact names.
+  When input is not a terminal or stty is unavailable, plain lines are
+  read without editing.
+
+  Steps run one at a time as asked, so the config-level maintenance
+  window and the confirmation prompt of "sink execute" do not apply.
+  A step's own window still defers it.
+
+Options:
+  --platform <os>        Use the platform for <os> instead of this OS
+  --var <name>=<value>   Set a config variable (repeatable)
+  -h, --help             Show this help message
+
+Console commands:
+` + consoleHelp + `
+Examples:
+  sink console install-config.json
+  sink console --platform linux install-config.json
+`)
+}
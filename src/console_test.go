@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const consoleTestConfig = `{
+	"version": "1.0.0",
+	"facts": {"env": {"command": "echo lab"}},
+	"platforms": [
+		{
+			"os": "linux",
+			"match": "Linux",
+			"name": "Linux",
+			"install_steps": [
+				{"name": "Install tools", "command": "install"},
+				{"name": "Install agent", "command": "agent {{.env}}"},
+				{"name": "Configure", "command": "configure"}
+			]
+		}
+	]
+}`
+
+// TestConsole_Session tests running steps and ad-hoc commands from scripted input
+func TestConsole_Session(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configPath, []byte(consoleTestConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+	historyPath := filepath.Join(t.TempDir(), "history")
+	// A repeat of the last history entry is not recorded again
+	if err := os.WriteFile(historyPath, []byte("steps\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	transport := &MockTransportWithTracking{responses: map[string]MockResponse{
+		"echo lab":     {stdout: "lab\n"},
+		"agent lab":    {stdout: "agent installed\n"},
+		"configure":    {stderr: "boom\n", exitCode: 1},
+		"echo env=lab": {stdout: "env=lab\n"},
+	}}
+	input := "steps\nrun Install agent\nrun 3\nrun Missing\n! echo env={{.env}}\nfacts\nbogus\nhistory\nexit\nrun 1\n"
+	var out bytes.Buffer
+	console := NewConsole(configPath, transport, strings.NewReader(input), &out)
+	console.Platform = "linux"
+	console.HistoryFile = historyPath
+	if err := console.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	for _, want := range []string{
+		"platform Linux (linux) has 3 steps",
+		"   2  Install agent\n",
+		"▶ Install agent\n  ✓ Success",
+		"    agent installed\n",
+		"▶ Configure\n  ✗ Failed",
+		"No step named 'Missing'",
+		"env=lab\n",
+		"  env = lab\n",
+		"Unknown command 'bogus'",
+		"     1  steps\n     2  run Install agent\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+	for _, call := range transport.calls {
+		if call == "install" {
+			t.Error("commands after exit should not run")
+		}
+	}
+
+	data, _ := os.ReadFile(historyPath)
+	if !strings.HasPrefix(string(data), "steps\nrun Install agent\n") || !strings.HasSuffix(string(data), "exit\n") {
+		t.Errorf("unexpected history file:\n%s", data)
+	}
+}
+
+// TestConsole_Complete tests completion of commands, step names and fact names
+func TestConsole_Complete(t *testing.T) {
+	c := &Console{
+		platform: &Platform{InstallSteps: []InstallStep{{Name: "Install tools"}, {Name: "Install agent"}, {Name: "Configure"}}},
+		facts:    Facts{"os": "linux", "os_version": "12", "arch": "arm64"},
+	}
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"r", []string{"reload", "run"}},
+		{"st", []string{"steps"}},
+		{"run Inst", []string{"run Install tools", "run Install agent"}},
+		{"run C", []string{"run Configure"}},
+		{"facts os", []string{"facts os", "facts os_version"}},
+		{"history x", nil},
+	}
+	for _, tt := range tests {
+		if got := c.complete(tt.line); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("complete(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+// TestLineEditor tests editing, completion and history in raw terminal input
+func TestLineEditor(t *testing.T) {
+	read := func(input string, history []string) (string, string) {
+		var out bytes.Buffer
+		l := &lineEditor{
+			in:      bufio.NewReader(strings.NewReader(input)),
+			out:     &out,
+			history: history,
+			complete: func(line string) []string {
+				return (&Console{platform: &Platform{InstallSteps: []InstallStep{
+					{Name: "Install tools"}, {Name: "Install agent"}, {Name: "Configure"},
+				}}}).complete(line)
+			},
+		}
+		line, err := l.readLine("> ")
+		if err != nil {
+			t.Fatalf("readLine(%q): %v", input, err)
+		}
+		return line, out.String()
+	}
+
+	tests := []struct {
+		name    string
+		input   string
+		history []string
+		want    string
+	}{
+		{"typing", "steps\r", nil, "steps"},
+		{"backspace", "stepx\x7fs\r", nil, "steps"},
+		{"ctrl-u", "junk\x15steps\r", nil, "steps"},
+		{"unique completion", "run C\t\r", nil, "run Configure "},
+		{"common prefix", "run I\ta\t\r", nil, "run Install agent "},
+		{"history up", "\x1b[A\x1b[A\r", []string{"steps", "run 1"}, "steps"},
+		{"history down to draft", "dr\x1b[A\x1b[B\r", []string{"steps"}, "dr"},
+		{"ctrl-c", "junk\x03", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, _ := read(tt.input, tt.history); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Ambiguous completion without a longer prefix lists the candidates
+	if _, out := read("run Install \t\r", nil); !strings.Contains(out, "  run Install tools\r\n  run Install agent\r\n") {
+		t.Errorf("expected candidates listed, got %q", out)
+	}
+
+	// Ctrl-D on an empty line ends input
+	l := &lineEditor{in: bufio.NewReader(strings.NewReader("\x04")), out: &bytes.Buffer{}}
+	if _, err := l.readLine("> "); err == nil {
+		t.Error("expected EOF on Ctrl-D")
+	}
+}
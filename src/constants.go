@@ -53,6 +53,25 @@ const (
 	MaxCommandOutputSize = 1024 * 1024 // 1MB
 )
 
+// Exit Codes
+const (
+	// ExitDeadlineExceeded is the exit code when a run exceeds --max-duration
+	// (matches the timeout(1) convention)
+	ExitDeadlineExceeded = 124
+
+	// ExitDeferred is the exit code when steps were deferred because they fell
+	// outside their maintenance window (EX_TEMPFAIL: retry later)
+	ExitDeferred = 75
+
+	// ExitBrokenPipe is the exit code when --on-broken-pipe abort stopped a
+	// run because stdout was closed (128 + SIGPIPE, as a shell reports it)
+	ExitBrokenPipe = 141
+
+	// ExitInterrupted is the exit code when an interrupt (Ctrl-C or SIGTERM)
+	// stopped a run (128 + SIGINT, as a shell reports it)
+	ExitInterrupted = 130
+)
+
 // Network Configuration
 const (
 	// MaxIdleHTTPConnections is the maximum number of idle HTTP connections
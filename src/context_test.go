@@ -2,8 +2,10 @@ package main
 
 import (
 	"encoding/json"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
 )
 
 // TestExecutorContextDiscovery tests that execution context is discovered
@@ -56,7 +58,7 @@ func TestExecutorContextFields(t *testing.T) {
 	}
 
 	// Arch should be reasonable
-	validArchs := []string{"x86_64", "arm64", "amd64", "i386", "i686"}
+	validArchs := []string{"x86_64", "aarch64", "arm64", "amd64", "i386", "i686"}
 	validArch := false
 	for _, arch := range validArchs {
 		if ctx.Arch == arch {
@@ -231,3 +233,55 @@ func TestExecutorContextJSON(t *testing.T) {
 		t.Error("JSON missing transport field")
 	}
 }
+
+// TestDiscoverLocalContext tests that the local context read without
+// commands matches what hostname, whoami, pwd, uname and date report
+func TestDiscoverLocalContext(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uname is not available on Windows")
+	}
+	transport := NewLocalTransport()
+	executor := NewExecutor(transport)
+	var probed ExecutionContext
+	executor.probeContext(&probed)
+	if probed.Host == "" || probed.OS == "" || probed.Timezone == "" {
+		t.Skip("probe commands are not available")
+	}
+
+	ctx := executor.GetContext()
+	if ctx.Host != probed.Host || ctx.User != probed.User || ctx.WorkDir != probed.WorkDir ||
+		ctx.OS != probed.OS || ctx.Arch != probed.Arch || ctx.UTCOffset != probed.UTCOffset {
+		t.Errorf("local context %+v differs from probed %+v", ctx, probed)
+	}
+
+	transport.WorkDir = "/srv/app"
+	if ctx := NewExecutor(transport).GetContext(); ctx.WorkDir != "/srv/app" {
+		t.Errorf("expected the transport's working directory, got %q", ctx.WorkDir)
+	}
+}
+
+// TestUnameNames tests mapping Go platform names to uname's
+func TestUnameNames(t *testing.T) {
+	tests := []struct{ goos, goarch, os, arch string }{
+		{"linux", "amd64", "Linux", "x86_64"},
+		{"linux", "arm64", "Linux", "aarch64"},
+		{"darwin", "arm64", "Darwin", "arm64"},
+		{"freebsd", "amd64", "FreeBSD", "amd64"},
+		{"openbsd", "386", "OpenBSD", "i386"},
+		{"windows", "386", "Windows_NT", "i686"},
+		{"plan9", "riscv64", "plan9", "riscv64"},
+	}
+	for _, tt := range tests {
+		if os, arch := unameOS(tt.goos), unameArch(tt.goos, tt.goarch); os != tt.os || arch != tt.arch {
+			t.Errorf("%s/%s: got %s/%s, want %s/%s", tt.goos, tt.goarch, os, arch, tt.os, tt.arch)
+		}
+	}
+
+	zone, offset := localTimezone(time.Date(2025, 1, 1, 0, 0, 0, 0, time.FixedZone("NPT", 5*3600+45*60)))
+	if zone != "NPT" || offset != "+05:45" {
+		t.Errorf("got %s %s", zone, offset)
+	}
+	if _, offset := localTimezone(time.Date(2025, 1, 1, 0, 0, 0, 0, time.FixedZone("NST", -(3*3600+30*60)))); offset != "-03:30" {
+		t.Errorf("got %s", offset)
+	}
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// coverageSeparator joins the parts of a branch ID, e.g.
+// "linux/ubuntu › Install jq › on_missing[1] apt"
+const coverageSeparator = " › "
+
+// CoverageBranch is a path through a config that a run may take
+type CoverageBranch struct {
+	ID   string
+	Kind string // "step", "remediation" or "fallback"
+}
+
+// coveragePrefix names the branches of a platform, or of one of its
+// distributions, the way sink test names targets
+func coveragePrefix(platform *Platform, dist *Distribution) string {
+	if dist == nil {
+		return platform.OS
+	}
+	return MatrixTarget{OS: platform.OS, Distro: dist.IDs[0]}.String()
+}
+
+// configBranches lists every branch of a config in config order: the steps
+// and group members of each platform and distribution, the remediations of
+// check steps, and the fallbacks of platforms and of the config
+func configBranches(config *Config) []CoverageBranch {
+	var branches []CoverageBranch
+	var addSteps func(prefix string, steps []InstallStep)
+	addSteps = func(prefix string, steps []InstallStep) {
+		for _, step := range steps {
+			id := prefix + coverageSeparator + step.Name
+			branches = append(branches, CoverageBranch{ID: id, Kind: "step"})
+			switch s := step.Step.(type) {
+			case CheckRemediateStep:
+				for i, rem := range s.OnMissing {
+					branches = append(branches, CoverageBranch{ID: remediationBranch(id, i, rem), Kind: "remediation"})
+				}
+			case GroupStep:
+				addSteps(id, s.Group.Steps)
+			}
+		}
+	}
+
+	for i := range config.Platforms {
+		platform := &config.Platforms[i]
+		if len(platform.Distributions) == 0 {
+			addSteps(coveragePrefix(platform, nil), platform.InstallSteps)
+		}
+		for j := range platform.Distributions {
+			dist := &platform.Distributions[j]
+			addSteps(coveragePrefix(platform, dist), dist.InstallSteps)
+		}
+		if platform.Fallback != nil {
+			branches = append(branches, CoverageBranch{ID: fallbackBranch(platform), Kind: "fallback"})
+		}
+	}
+	if config.Fallback != nil {
+		branches = append(branches, CoverageBranch{ID: fallbackBranch(nil), Kind: "fallback"})
+	}
+	return branches
+}
+
+// remediationBranch names the i-th remediation of the step with branch ID step
+func remediationBranch(step string, i int, rem RemediationStep) string {
+	return fmt.Sprintf("%s%son_missing[%d] %s", step, coverageSeparator, i+1, rem.Name)
+}
+
+// fallbackBranch names a platform's fallback, or the config's when platform
+// is nil
+func fallbackBranch(platform *Platform) string {
+	if platform == nil {
+		return "fallback"
+	}
+	return platform.OS + coverageSeparator + "fallback"
+}
+
+// stepRan reports whether a step result comes from running the step, as
+// opposed to skipping it (dry run, conditions) or never reaching it
+func stepRan(result StepResult) bool {
+	return result.Status != "skipped" && result.Status != "not_run"
+}
+
+// resultBranches returns the branches a run exercised, from the results of
+// steps run under prefix. Results are in step order, as ExecutePlatform and
+// groups return them.
+func resultBranches(prefix string, steps []InstallStep, results []StepResult) []string {
+	var hits []string
+	for i, result := range results {
+		if i >= len(steps) || !stepRan(result) {
+			continue
+		}
+		step := steps[i]
+		id := prefix + coverageSeparator + step.Name
+		hits = append(hits, id)
+		switch s := step.Step.(type) {
+		case CheckRemediateStep:
+			for j, rem := range result.RemediationSteps {
+				if j < len(s.OnMissing) && stepRan(rem) {
+					hits = append(hits, remediationBranch(id, j, s.OnMissing[j]))
+				}
+			}
+		case GroupStep:
+			hits = append(hits, resultBranches(id, s.Group.Steps, result.GroupSteps)...)
+		}
+	}
+	return hits
+}
+
+// Coverage counts how often each branch of a config was exercised, across
+// the runs recorded in a coverage file
+type Coverage struct {
+	Config string         `json:"config,omitempty"` // Name of the config the runs belong to
+	Runs   int            `json:"runs"`
+	Hits   map[string]int `json:"hits"` // Branch ID to the number of runs that took it
+}
+
+// loadCoverage reads a coverage file; a file that does not exist yet is an
+// empty record
+func loadCoverage(path string) (*Coverage, error) {
+	coverage := &Coverage{Hits: map[string]int{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return coverage, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read coverage file: %w", err)
+	}
+	if err := json.Unmarshal(data, coverage); err != nil {
+		return nil, fmt.Errorf("invalid coverage file %s: %w", path, err)
+	}
+	if coverage.Hits == nil {
+		coverage.Hits = map[string]int{}
+	}
+	return coverage, nil
+}
+
+// add records one run that exercised hits
+func (c *Coverage) add(hits []string) {
+	c.Runs++
+	seen := map[string]bool{}
+	for _, id := range hits {
+		if !seen[id] {
+			seen[id] = true
+			c.Hits[id]++
+		}
+	}
+}
+
+// merge adds the runs recorded in other
+func (c *Coverage) merge(other *Coverage) {
+	c.Runs += other.Runs
+	for id, count := range other.Hits {
+		c.Hits[id] += count
+	}
+}
+
+// recordCoverage adds a run of config to the coverage file at path. A file
+// recorded for a differently named config is refused rather than mixed in.
+func recordCoverage(path string, config *Config, run *Coverage) error {
+	coverage, err := loadCoverage(path)
+	if err != nil {
+		return err
+	}
+	if coverage.Runs > 0 && coverage.Config != config.Name {
+		return fmt.Errorf("coverage file %s records config '%s', not '%s'", path, coverage.Config, config.Name)
+	}
+	coverage.Config = config.Name
+	coverage.merge(run)
+
+	data, err := json.MarshalIndent(coverage, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write coverage file: %w", err)
+	}
+	return nil
+}
+
+// recordRunCoverage adds the branches a run of sink execute took to the
+// --coverage file, if one was given. A failure to record is only a warning.
+func recordRunCoverage(path string, config *Config, hits []string) {
+	if path == "" {
+		return
+	}
+	run := &Coverage{Hits: map[string]int{}}
+	run.add(hits)
+	if err := recordCoverage(path, config, run); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+// printCoverageReport summarizes which branches of config the recorded runs
+// exercised and lists those they did not; verbose lists every branch with
+// its count. Hits of branches the config no longer has are ignored.
+func printCoverageReport(w io.Writer, config *Config, coverage *Coverage, verbose bool) {
+	branches := configBranches(config)
+	covered := 0
+	var missed []CoverageBranch
+	for _, branch := range branches {
+		if coverage.Hits[branch.ID] > 0 {
+			covered++
+		} else {
+			missed = append(missed, branch)
+		}
+	}
+
+	percent := 100
+	if len(branches) > 0 {
+		percent = covered * 100 / len(branches)
+	}
+	runs := fmt.Sprintf("%d runs", coverage.Runs)
+	if coverage.Runs == 1 {
+		runs = "1 run"
+	}
+	fmt.Fprintf(w, "Coverage: %d of %d branches exercised in %s (%d%%)\n", covered, len(branches), runs, percent)
+
+	if verbose {
+		for _, branch := range branches {
+			mark := "✓"
+			if coverage.Hits[branch.ID] == 0 {
+				mark = "✗"
+			}
+			fmt.Fprintf(w, "   %s %-12s %s (%d)\n", mark, branch.Kind, branch.ID, coverage.Hits[branch.ID])
+		}
+		return
+	}
+	if len(missed) > 0 {
+		fmt.Fprintf(w, "Not exercised:\n")
+		for _, branch := range missed {
+			fmt.Fprintf(w, "   %-12s %s\n", branch.Kind, branch.ID)
+		}
+	}
+}
+
+// planBranches returns the branches a plan covers: the steps and group
+// members of a target whose templates all render, or the fallback it
+// reached. Remediations only run when a check fails, so planning never
+// covers them.
+func planBranches(config *Config, result MatrixResult) []string {
+	platform := findPlatform(config, result.Target.OS)
+	if result.Fallback != "" {
+		if platform != nil {
+			return []string{fallbackBranch(platform)}
+		}
+		return []string{fallbackBranch(nil)}
+	}
+	if result.Error != "" {
+		return nil
+	}
+
+	var dist *Distribution
+	steps := platform.InstallSteps
+	for i := range platform.Distributions {
+		if containsString(platform.Distributions[i].IDs, result.Target.Distro) {
+			dist = &platform.Distributions[i]
+			steps = dist.InstallSteps
+		}
+	}
+
+	var hits []string
+	var addSteps func(prefix string, steps []InstallStep)
+	addSteps = func(prefix string, steps []InstallStep) {
+		for _, step := range steps {
+			id := prefix + coverageSeparator + step.Name
+			hits = append(hits, id)
+			if group, ok := step.Step.(GroupStep); ok {
+				addSteps(id, group.Group.Steps)
+			}
+		}
+	}
+	for i, planned := range result.Steps {
+		if i < len(steps) && len(planned.Errors) == 0 {
+			addSteps(coveragePrefix(platform, dist), steps[i:i+1])
+		}
+	}
+	return hits
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestConfigBranches tests listing a config's branches and matching the
+// results of a run against them
+func TestConfigBranches(t *testing.T) {
+	config, err := parseConfigData([]byte(matrixConfig), LoadOptions{})
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+
+	var ids []string
+	for _, branch := range configBranches(config) {
+		ids = append(ids, branch.Kind+": "+branch.ID)
+	}
+	expected := []string{
+		"step: darwin › Install jq",
+		"step: linux/ubuntu › Install jq",
+		"remediation: linux/ubuntu › Install jq › on_missing[1] apt",
+		"step: linux/alpine › Install jq",
+		"fallback: linux › fallback",
+	}
+	if strings.Join(ids, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected branches:\n%s", strings.Join(ids, "\n"))
+	}
+
+	steps := config.Platforms[1].Distributions[0].InstallSteps
+	passed := []StepResult{{StepName: "Install jq", Status: "success"}}
+	remediated := []StepResult{{StepName: "Install jq", Status: "success", RemediationSteps: []StepResult{{StepName: "apt", Status: "success"}}}}
+	if hits := resultBranches("linux/ubuntu", steps, passed); len(hits) != 1 {
+		t.Errorf("expected only the step, got %v", hits)
+	}
+	if hits := resultBranches("linux/ubuntu", steps, remediated); len(hits) != 2 || hits[1] != "linux/ubuntu › Install jq › on_missing[1] apt" {
+		t.Errorf("expected the step and its remediation, got %v", hits)
+	}
+	if hits := resultBranches("linux/ubuntu", steps, []StepResult{{StepName: "Install jq", Status: "skipped"}}); len(hits) != 0 {
+		t.Errorf("expected a skipped step to cover nothing, got %v", hits)
+	}
+
+	// Planning covers the steps whose templates render and fallbacks
+	facts, _ := mockFacts(config.Facts, "linux", nil)
+	if hits := planBranches(config, planTarget(config, MatrixTarget{OS: "linux", Distro: "debian"}, facts)); len(hits) != 1 || hits[0] != "linux/ubuntu › Install jq" {
+		t.Errorf("expected the ubuntu step, got %v", hits)
+	}
+	if hits := planBranches(config, planTarget(config, MatrixTarget{OS: "linux", Distro: "alpine"}, facts)); len(hits) != 0 {
+		t.Errorf("expected a failing template to cover nothing, got %v", hits)
+	}
+	if hits := planBranches(config, planTarget(config, MatrixTarget{OS: "linux", Distro: "arch"}, facts)); len(hits) != 1 || hits[0] != "linux › fallback" {
+		t.Errorf("expected the fallback, got %v", hits)
+	}
+}
+
+// TestRecordCoverage tests aggregating runs in a coverage file and the report
+func TestRecordCoverage(t *testing.T) {
+	config, err := parseConfigData([]byte(matrixConfig), LoadOptions{})
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	config.Name = "matrix"
+	path := filepath.Join(t.TempDir(), "coverage.json")
+
+	recordRunCoverage(path, config, []string{"darwin › Install jq", "linux/ubuntu › Install jq"})
+	recordRunCoverage(path, config, []string{"linux/ubuntu › Install jq", "linux/ubuntu › Install jq › on_missing[1] apt", "removed › step"})
+	coverage, err := loadCoverage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if coverage.Runs != 2 || coverage.Hits["linux/ubuntu › Install jq"] != 2 || coverage.Config != "matrix" {
+		t.Errorf("unexpected coverage %+v", coverage)
+	}
+
+	var out bytes.Buffer
+	printCoverageReport(&out, config, coverage, false)
+	expected := `Coverage: 3 of 5 branches exercised in 2 runs (60%)
+Not exercised:
+   step         linux/alpine › Install jq
+   fallback     linux › fallback
+`
+	if out.String() != expected {
+		t.Errorf("unexpected report:\n%s", out.String())
+	}
+
+	other := *config
+	other.Name = "other"
+	if err := recordCoverage(path, &other, &Coverage{Runs: 1, Hits: map[string]int{}}); err == nil || !strings.Contains(err.Error(), "records config 'matrix'") {
+		t.Errorf("expected a file of another config to be refused, got %v", err)
+	}
+}
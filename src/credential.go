@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CredentialHelperPrefix names credential helper executables: the helper
+// "vault" is the program sink-credential-vault on $PATH
+const CredentialHelperPrefix = "sink-credential-"
+
+// Credential is what a credential helper supplies for a URL
+type Credential struct {
+	Username string
+	Password string
+	Token    string // Sent as "Authorization: Bearer <token>"; wins over username/password
+}
+
+// IsEmpty reports whether the helper supplied nothing
+func (c Credential) IsEmpty() bool {
+	return c.Token == "" && c.Username == "" && c.Password == ""
+}
+
+// apply adds the credential to a request
+func (c Credential) apply(req *http.Request) {
+	switch {
+	case c.Token != "":
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	case c.Username != "" || c.Password != "":
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+}
+
+// credentialHelper is the helper selected with --credential-helper or
+// SINK_CREDENTIAL_HELPER ("" disables credential lookup)
+var credentialHelper string
+
+// runCredentialHelper runs a helper command with the request on stdin and
+// returns its stdout (replaced in tests)
+var runCredentialHelper = func(helper string, input string) (string, error) {
+	program := helper
+	if !strings.Contains(helper, "/") {
+		program = CredentialHelperPrefix + helper
+	}
+
+	cmd := exec.Command(program, "get")
+	cmd.Stdin = strings.NewReader(input)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("credential helper %s: %w: %s", program, err, msg)
+		}
+		return "", fmt.Errorf("credential helper %s: %w", program, err)
+	}
+	return string(out), nil
+}
+
+// credentialCache holds one lookup per host so a bootstrap that fetches the
+// config, its checksum and its signature asks the helper only once
+var credentialCache = struct {
+	sync.Mutex
+	byHost map[string]Credential
+}{byHost: map[string]Credential{}}
+
+// lookupCredential asks the configured helper for credentials for rawURL.
+// Following git's credential protocol, the helper receives "key=value"
+// lines (protocol, host, path) and a blank line on stdin, and answers with
+// "key=value" lines: token, or username and password. Credentials are only
+// requested for HTTPS URLs so they never cross the network in clear text.
+func lookupCredential(rawURL string) (Credential, error) {
+	if credentialHelper == "" {
+		return Credential{}, nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return Credential{}, nil
+	}
+
+	credentialCache.Lock()
+	defer credentialCache.Unlock()
+	if cred, ok := credentialCache.byHost[u.Host]; ok {
+		return cred, nil
+	}
+
+	input := fmt.Sprintf("protocol=%s\nhost=%s\npath=%s\n\n", u.Scheme, u.Host, strings.TrimPrefix(u.Path, "/"))
+	output, err := runCredentialHelper(credentialHelper, input)
+	if err != nil {
+		return Credential{}, err
+	}
+
+	cred := parseCredentialOutput(output)
+	credentialCache.byHost[u.Host] = cred
+	return cred, nil
+}
+
+// parseCredentialOutput reads "key=value" lines from a helper. Unknown keys
+// are ignored so helpers can share output with git credential helpers.
+func parseCredentialOutput(output string) Credential {
+	var cred Credential
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "token":
+			cred.Token = value
+		case "username":
+			cred.Username = value
+		case "password":
+			cred.Password = value
+		}
+	}
+	return cred
+}
+
+// httpGet performs a GET with the given timeout, adding credentials from the
+// configured credential helper. The request (and reading its body) stops
+// when the run's context is done.
+func httpGet(rawURL string, timeout time.Duration) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(runCtx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	cred, err := lookupCredential(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	cred.apply(req)
+
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, runStopped(runCtx, err)
+	}
+	return resp, nil
+}
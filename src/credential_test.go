@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// withCredentialHelper installs a fake helper for the duration of a test
+func withCredentialHelper(t *testing.T, run func(helper string, input string) (string, error)) {
+	t.Helper()
+	oldHelper, oldRun := credentialHelper, runCredentialHelper
+	credentialHelper = "test"
+	runCredentialHelper = run
+	credentialCache.byHost = map[string]Credential{}
+	t.Cleanup(func() {
+		credentialHelper, runCredentialHelper = oldHelper, oldRun
+		credentialCache.byHost = map[string]Credential{}
+	})
+}
+
+// TestLookupCredential tests the helper protocol and per-host caching
+func TestLookupCredential(t *testing.T) {
+	var inputs []string
+	withCredentialHelper(t, func(helper string, input string) (string, error) {
+		inputs = append(inputs, input)
+		return "username=deploy\npassword=s3cret\nquit=0\n", nil
+	})
+
+	cred, err := lookupCredential("https://configs.example.com/prod/setup.json?ref=v1")
+	if err != nil {
+		t.Fatalf("lookupCredential failed: %v", err)
+	}
+	if cred.Username != "deploy" || cred.Password != "s3cret" {
+		t.Errorf("Unexpected credential: %+v", cred)
+	}
+	want := "protocol=https\nhost=configs.example.com\npath=prod/setup.json\n\n"
+	if len(inputs) != 1 || inputs[0] != want {
+		t.Errorf("Helper input = %q, want %q", inputs, want)
+	}
+
+	// The checksum for the same host reuses the answer
+	if _, err := lookupCredential("https://configs.example.com/prod/setup.json.sha256"); err != nil {
+		t.Fatal(err)
+	}
+	if len(inputs) != 1 {
+		t.Errorf("Expected one helper call per host, got %d", len(inputs))
+	}
+
+	// Plain HTTP never gets credentials
+	cred, err = lookupCredential("http://configs.example.com/setup.json")
+	if err != nil || !cred.IsEmpty() {
+		t.Errorf("Expected no credential for HTTP, got %+v, %v", cred, err)
+	}
+}
+
+// TestLookupCredentialHelperFailure tests that a failing helper aborts the request
+func TestLookupCredentialHelperFailure(t *testing.T) {
+	withCredentialHelper(t, func(helper string, input string) (string, error) {
+		return "", errors.New("credential helper sink-credential-test: exit status 1: not logged in")
+	})
+
+	if _, err := httpGet("https://configs.example.com/setup.json", time.Second); err == nil || !strings.Contains(err.Error(), "not logged in") {
+		t.Errorf("Expected helper error, got %v", err)
+	}
+}
+
+// TestHTTPGetWithCredentialHelper tests that helper tokens reach the server
+func TestHTTPGetWithCredentialHelper(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer t0ken" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	oldTransport := http.DefaultTransport
+	http.DefaultTransport = server.Client().Transport
+	defer func() { http.DefaultTransport = oldTransport }()
+
+	withCredentialHelper(t, func(helper string, input string) (string, error) {
+		return "token=t0ken\n", nil
+	})
+
+	resp, err := httpGet(server.URL+"/setup.json", time.Second)
+	if err != nil {
+		t.Fatalf("httpGet failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 with helper token, got %d", resp.StatusCode)
+	}
+}
+
+// TestParseCredentialOutput tests reading helper output
+func TestParseCredentialOutput(t *testing.T) {
+	cred := parseCredentialOutput("token=abc\nignored line\nexpiry=123\n")
+	if cred.Token != "abc" || cred.Username != "" {
+		t.Errorf("Unexpected credential: %+v", cred)
+	}
+	if !parseCredentialOutput("").IsEmpty() {
+		t.Error("Expected empty credential for empty output")
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "https://example.com", nil)
+	Credential{Username: "u", Password: "p"}.apply(req)
+	if user, pass, ok := req.BasicAuth(); !ok || user != "u" || pass != "p" {
+		t.Errorf("Expected basic auth, got %q", req.Header.Get("Authorization"))
+	}
+}
@@ -0,0 +1,381 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// dbSchema is the results database layout. sink keeps no SQLite library
+// (zero dependencies); the sqlite3 command runs it, like git and ssh are run
+// for other features.
+const dbSchema = `CREATE TABLE IF NOT EXISTS hosts (
+  name        TEXT PRIMARY KEY,
+  os          TEXT,
+  arch        TEXT,
+  last_run_id TEXT,
+  last_seen   TEXT
+);
+CREATE TABLE IF NOT EXISTS runs (
+  run_id    TEXT PRIMARY KEY,
+  host      TEXT,
+  user      TEXT,
+  transport TEXT,
+  started   TEXT,
+  finished  TEXT,
+  success   INTEGER,
+  steps     INTEGER,
+  failed    INTEGER,
+  warnings  INTEGER,
+  source    TEXT
+);
+CREATE TABLE IF NOT EXISTS steps (
+  run_id    TEXT,
+  position  INTEGER,
+  name      TEXT,
+  status    TEXT,
+  started   TEXT,
+  finished  TEXT,
+  attempts  INTEGER,
+  exit_code INTEGER,
+  error     TEXT,
+  PRIMARY KEY (run_id, position)
+);
+CREATE INDEX IF NOT EXISTS runs_host ON runs (host, started);
+CREATE INDEX IF NOT EXISTS steps_status ON steps (status);
+`
+
+// DefaultDBName is the results database under the state directory
+const DefaultDBName = "results.db"
+
+// importedRun is one run read from a results file
+type importedRun struct {
+	RunID     string
+	Host      string
+	User      string
+	OS        string
+	Arch      string
+	Transport string
+	Started   string
+	Finished  string
+	Warnings  int
+	Source    string
+	Steps     []*importedStep
+}
+
+// importedStep is the outcome of one top-level step of a run
+type importedStep struct {
+	Name     string
+	Status   string
+	Started  string
+	Finished string
+	Attempts int
+	ExitCode *int
+	Error    string
+}
+
+// Failed counts the run's failed steps
+func (r *importedRun) Failed() int {
+	failed := 0
+	for _, step := range r.Steps {
+		if step.Status == "failed" {
+			failed++
+		}
+	}
+	return failed
+}
+
+// parseResults reads the JSON stream of sink execute/bootstrap --json.
+// Events are grouped by run ID, so a log several runs appended to yields
+// several runs. Remediation events belong to their parent step, a
+// "warnings" event to the run before it, and other side events are ignored.
+func parseResults(r io.Reader, source string) ([]*importedRun, error) {
+	var runs []*importedRun
+	var last *importedRun
+	byID := map[string]*importedRun{}
+
+	decoder := json.NewDecoder(r)
+	for {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("%s: %w", source, err)
+		}
+
+		var side struct {
+			Event    string    `json:"event"`
+			Warnings []Warning `json:"warnings"`
+		}
+		if json.Unmarshal(raw, &side) == nil && side.Event != "" {
+			if side.Event == "warnings" && last != nil {
+				last.Warnings += len(side.Warnings)
+			}
+			continue
+		}
+
+		var event ExecutionEvent
+		if err := json.Unmarshal(raw, &event); err != nil || event.RunID == "" || event.Status == "" {
+			continue // Not an event, such as a result object from another tool
+		}
+
+		run := byID[event.RunID]
+		if run == nil {
+			run = &importedRun{RunID: event.RunID, Source: source, Started: event.Timestamp}
+			byID[event.RunID] = run
+			runs = append(runs, run)
+		}
+		if run.Host == "" {
+			run.Host = event.Context.Host
+			run.User = event.Context.User
+			run.OS = event.Context.OS
+			run.Arch = event.Context.Arch
+			run.Transport = event.Context.Transport
+		}
+		if event.Timestamp < run.Started {
+			run.Started = event.Timestamp
+		}
+		if event.Timestamp > run.Finished {
+			run.Finished = event.Timestamp
+		}
+		last = run
+
+		// Rolling a step back after a later failure keeps its result
+		if event.Status == "rollback" {
+			continue
+		}
+		name := event.StepName
+		if event.ParentStep != "" {
+			name = event.ParentStep
+		}
+		var step *importedStep
+		for _, s := range run.Steps {
+			if s.Name == name {
+				step = s
+			}
+		}
+		if step == nil {
+			step = &importedStep{Name: name, Started: event.Timestamp, Attempts: 1}
+			run.Steps = append(run.Steps, step)
+		}
+		step.Finished = event.Timestamp
+		switch {
+		case event.Status == "retrying":
+			step.Attempts++
+		case event.ParentStep == "" && event.Status != "running":
+			step.Status = event.Status
+			step.Error = event.Error
+			step.ExitCode = event.ExitCode
+		}
+	}
+	for _, run := range runs {
+		for _, step := range run.Steps {
+			if step.Status == "" {
+				step.Status = "running" // Interrupted before the step finished
+			}
+		}
+	}
+	return runs, nil
+}
+
+// sqlQuote quotes a SQL string literal; empty strings are NULL
+func sqlQuote(s string) string {
+	if s == "" {
+		return "NULL"
+	}
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// importSQL returns the statements that store runs, replacing earlier
+// imports of the same runs
+func importSQL(runs []*importedRun) string {
+	var b strings.Builder
+	b.WriteString(dbSchema)
+	b.WriteString("BEGIN;\n")
+	for _, run := range runs {
+		success := 0
+		if run.Failed() == 0 {
+			success = 1
+		}
+		fmt.Fprintf(&b, "DELETE FROM steps WHERE run_id = %s;\n", sqlQuote(run.RunID))
+		fmt.Fprintf(&b, "INSERT OR REPLACE INTO runs VALUES (%s, %s, %s, %s, %s, %s, %d, %d, %d, %d, %s);\n",
+			sqlQuote(run.RunID), sqlQuote(run.Host), sqlQuote(run.User), sqlQuote(run.Transport),
+			sqlQuote(run.Started), sqlQuote(run.Finished), success, len(run.Steps), run.Failed(), run.Warnings, sqlQuote(run.Source))
+		for i, step := range run.Steps {
+			exitCode := "NULL"
+			if step.ExitCode != nil {
+				exitCode = strconv.Itoa(*step.ExitCode)
+			}
+			fmt.Fprintf(&b, "INSERT INTO steps VALUES (%s, %d, %s, %s, %s, %s, %d, %s, %s);\n",
+				sqlQuote(run.RunID), i+1, sqlQuote(step.Name), sqlQuote(step.Status),
+				sqlQuote(step.Started), sqlQuote(step.Finished), step.Attempts, exitCode, sqlQuote(step.Error))
+		}
+		if run.Host != "" {
+			// Only a newer run replaces a host's last run
+			fmt.Fprintf(&b, "INSERT INTO hosts VALUES (%s, %s, %s, %s, %s) ON CONFLICT (name) DO UPDATE SET os = excluded.os, arch = excluded.arch, last_run_id = excluded.last_run_id, last_seen = excluded.last_seen WHERE hosts.last_seen IS NULL OR excluded.last_seen >= hosts.last_seen;\n",
+				sqlQuote(run.Host), sqlQuote(run.OS), sqlQuote(run.Arch), sqlQuote(run.RunID), sqlQuote(run.Finished))
+		}
+	}
+	b.WriteString("COMMIT;\n")
+	return b.String()
+}
+
+// runSQLite runs sqlite3 on the database with args, feeding it stdin
+func runSQLite(db string, stdin string, args ...string) ([]byte, error) {
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		return nil, fmt.Errorf("sink db needs the sqlite3 command (install sqlite3 or sqlite)")
+	}
+	cmd := exec.Command("sqlite3", append(append([]string{"-bail"}, args...), db)...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return output, fmt.Errorf("sqlite3: %s", msg)
+		}
+		return output, fmt.Errorf("sqlite3: %w", err)
+	}
+	return output, nil
+}
+
+// dbCommand handles sink db import|query
+func dbCommand() {
+	args := os.Args[2:]
+	for _, arg := range args {
+		if arg == "-h" || arg == "--help" {
+			printDBHelp()
+			os.Exit(0)
+		}
+	}
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: Missing subcommand\n\n")
+		printDBHelp()
+		os.Exit(1)
+	}
+
+	db := ""
+	jsonOutput := false
+	var operands []string
+	for i := 1; i < len(args); i++ {
+		switch args[i] {
+		case "--db":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --db requires a file\n")
+				os.Exit(1)
+			}
+			db = args[i+1]
+			i++
+		case "--json":
+			jsonOutput = true
+		default:
+			operands = append(operands, args[i])
+		}
+	}
+	if db == "" {
+		path, err := statePath(DefaultDBName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v (use --db)\n", err)
+			os.Exit(1)
+		}
+		db = path
+		if err := os.MkdirAll(filepath.Dir(db), 0700); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	switch args[0] {
+	case "import":
+		if len(operands) == 0 {
+			fmt.Fprintf(os.Stderr, "Error: import needs at least one results file\n")
+			os.Exit(1)
+		}
+		var runs []*importedRun
+		steps := 0
+		for _, file := range operands {
+			f, err := os.Open(file)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			fileRuns, err := parseResults(f, file)
+			f.Close()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			for _, run := range fileRuns {
+				steps += len(run.Steps)
+			}
+			runs = append(runs, fileRuns...)
+		}
+		if _, err := runSQLite(db, importSQL(runs)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✅ Imported %d runs (%d steps) from %d files into %s\n", len(runs), steps, len(operands), db)
+	case "query":
+		if len(operands) != 1 {
+			fmt.Fprintf(os.Stderr, "Error: query needs exactly one SQL statement\n")
+			os.Exit(1)
+		}
+		mode := []string{"-header", "-column"}
+		if jsonOutput {
+			mode = []string{"-json"}
+		}
+		output, err := runSQLite(db, dbSchema+operands[0]+";\n", mode...)
+		os.Stdout.Write(output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown subcommand: %s\n\n", args[0])
+		printDBHelp()
+		os.Exit(1)
+	}
+}
+
+func printDBHelp() {
+	fmt.Print(`sink db - Keep fleet run results in a SQLite database
+
+Usage:
+  sink db import <results.json>... [--db <file>]
+  sink db query "<sql>" [--db <file>] [--json]
+
+Subcommands:
+  import <files>     Add the runs in results files to the database
+  query <sql>        Run a SQL statement and print the rows
+
+Description:
+  A results file is the output of sink execute --json or bootstrap --json;
+  one file may hold several runs. Importing a run again replaces it, so
+  files can be imported repeatedly as they grow. The database uses the
+  sqlite3 command, which must be installed.
+
+Tables:
+  hosts(name, os, arch, last_run_id, last_seen)
+  runs(run_id, host, user, transport, started, finished, success, steps,
+       failed, warnings, source)
+  steps(run_id, position, name, status, started, finished, attempts,
+        exit_code, error)
+
+Options:
+  --db <file>        Database file (default: results.db in the state
+                     directory)
+  --json             Print query rows as JSON
+  -h, --help         Show this help message
+
+Examples:
+  sink db import results/*.json
+  sink db query "SELECT host, started, failed FROM runs ORDER BY started DESC LIMIT 10"
+  sink db query "SELECT name, count(*) FROM steps WHERE status = 'failed' GROUP BY name" --json
+`)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// resultsStream builds a --json results stream from events and side events
+func resultsStream(t *testing.T, objects ...interface{}) string {
+	var b bytes.Buffer
+	for _, object := range objects {
+		data, err := json.MarshalIndent(object, "", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		b.Write(append(data, '\n'))
+	}
+	return b.String()
+}
+
+// TestParseResults tests grouping a results stream into runs and steps
+func TestParseResults(t *testing.T) {
+	ctx := ExecutionContext{Host: "web01", User: "deploy", OS: "Linux", Arch: "x86_64", Transport: "local"}
+	exitCode := 2
+	stream := resultsStream(t,
+		ExecutionEvent{Timestamp: "2026-03-01T10:00:00Z", RunID: "run-1", StepName: "Install", Status: "running", Context: ctx},
+		ExecutionEvent{Timestamp: "2026-03-01T10:00:01Z", RunID: "run-1", StepName: "Install", Status: "retrying", Attempt: 1, Context: ctx},
+		ExecutionEvent{Timestamp: "2026-03-01T10:00:05Z", RunID: "run-1", StepName: "Install", Status: "success", Context: ctx},
+		ExecutionEvent{Timestamp: "2026-03-01T10:00:06Z", RunID: "run-1", StepName: "Configure", Status: "running", Context: ctx},
+		ExecutionEvent{Timestamp: "2026-03-01T10:00:07Z", RunID: "run-1", StepName: "Write file", ParentStep: "Configure", Status: "failed", Context: ctx},
+		ExecutionEvent{Timestamp: "2026-03-01T10:00:08Z", RunID: "run-1", StepName: "Configure", Status: "failed", Error: "it's broken", ExitCode: &exitCode, Context: ctx},
+		WarningsEvent{Event: "warnings", Warnings: []Warning{{Code: WarningNoChecksum, Message: "unverified"}}},
+		SnapshotEvent{Event: "snapshot"},
+		ExecutionEvent{Timestamp: "2026-03-02T10:00:00Z", RunID: "run-2", StepName: "Install", Status: "success", Context: ctx},
+	)
+
+	runs, err := parseResults(strings.NewReader(stream), "web01.json")
+	if err != nil {
+		t.Fatalf("parseResults failed: %v", err)
+	}
+	if len(runs) != 2 {
+		t.Fatalf("expected 2 runs, got %d", len(runs))
+	}
+	run := runs[0]
+	if run.Host != "web01" || run.Started != "2026-03-01T10:00:00Z" || run.Finished != "2026-03-01T10:00:08Z" || run.Warnings != 1 || run.Failed() != 1 {
+		t.Errorf("unexpected run: %+v", run)
+	}
+	if len(run.Steps) != 2 {
+		t.Fatalf("expected remediations folded into their step, got %d steps", len(run.Steps))
+	}
+	if install := run.Steps[0]; install.Status != "success" || install.Attempts != 2 {
+		t.Errorf("unexpected install step: %+v", install)
+	}
+	if configure := run.Steps[1]; configure.Status != "failed" || configure.ExitCode == nil || *configure.ExitCode != 2 || configure.Error != "it's broken" {
+		t.Errorf("unexpected configure step: %+v", configure)
+	}
+	if runs[1].Warnings != 0 || runs[1].Failed() != 0 {
+		t.Errorf("unexpected second run: %+v", runs[1])
+	}
+
+	sql := importSQL(runs)
+	if !strings.Contains(sql, "'it''s broken'") {
+		t.Errorf("expected quotes to be escaped:\n%s", sql)
+	}
+
+	if _, err := parseResults(strings.NewReader(`{"run_id": `), "bad.json"); err == nil {
+		t.Error("expected an error for truncated JSON")
+	}
+}
+
+// TestDBImportQuery tests importing into and querying a real database
+func TestDBImportQuery(t *testing.T) {
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		t.Skip("sqlite3 is not installed")
+	}
+	ctx := ExecutionContext{Host: "web01"}
+	runs, _ := parseResults(strings.NewReader(resultsStream(t,
+		ExecutionEvent{Timestamp: "2026-03-01T10:00:00Z", RunID: "run-1", StepName: "Install", Status: "failed", Context: ctx},
+		ExecutionEvent{Timestamp: "2026-03-02T10:00:00Z", RunID: "run-2", StepName: "Install", Status: "success", Context: ctx},
+	)), "web01.json")
+
+	db := filepath.Join(t.TempDir(), "results.db")
+	// Importing twice replaces rather than duplicates
+	for i := 0; i < 2; i++ {
+		if _, err := runSQLite(db, importSQL(runs)); err != nil {
+			t.Fatalf("import failed: %v", err)
+		}
+	}
+
+	output, err := runSQLite(db, "SELECT count(*), sum(failed) FROM runs; SELECT count(*) FROM steps; SELECT last_run_id FROM hosts;")
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if got := strings.Fields(string(output)); strings.Join(got, " ") != "2|1 2 run-2" {
+		t.Errorf("unexpected rows: %q", output)
+	}
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// hostLogNameRegex matches the characters replaced in host log file names
+var hostLogNameRegex = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// DeployPolicy decides whether a multi-host deploy succeeded, which sets
+// its exit status
+type DeployPolicy struct {
+	Quorum int // Hosts that must succeed; 0 means every host (--all-must-succeed)
+}
+
+// parseQuorum validates a --quorum value
+func parseQuorum(value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("--quorum must be a positive number of hosts, got '%s'", value)
+	}
+	return n, nil
+}
+
+// required returns how many of hosts must succeed
+func (p DeployPolicy) required(hosts int) int {
+	if p.Quorum == 0 {
+		return hosts
+	}
+	return p.Quorum
+}
+
+// check refuses a quorum larger than the deploy, which could never be met
+func (p DeployPolicy) check(hosts int) error {
+	if p.Quorum > hosts {
+		return fmt.Errorf("--quorum %d is more than the %d hosts being deployed", p.Quorum, hosts)
+	}
+	return nil
+}
+
+// String describes the policy for the plan and the summary
+func (p DeployPolicy) String() string {
+	if p.Quorum == 0 {
+		return "all hosts must succeed"
+	}
+	return fmt.Sprintf("at least %d host(s) must succeed", p.Quorum)
+}
+
+// deployRun is the run directory of a multi-host deploy: one log per host
+// under hosts/ and results.json once every host has finished
+type deployRun struct {
+	ID      string
+	Dir     string
+	Started time.Time
+
+	mu   sync.Mutex
+	logs map[string]bool // Log names taken, so two targets on one host keep separate logs
+}
+
+// newDeployRun creates the run directory, deploy-<run id> in the log
+// directory unless dir is given
+func newDeployRun(dir string) (*deployRun, error) {
+	id := generateRunID()
+	if dir == "" {
+		logs, err := logDir()
+		if err != nil {
+			return nil, err
+		}
+		dir = filepath.Join(logs, "deploy-"+id)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "hosts"), 0700); err != nil {
+		return nil, err
+	}
+	return &deployRun{ID: id, Dir: dir, Started: time.Now(), logs: map[string]bool{}}, nil
+}
+
+// openHostLog creates the log of one host, hosts/<host>.log
+func (r *deployRun) openHostLog(host string) (*os.File, error) {
+	r.mu.Lock()
+	name := hostLogNameRegex.ReplaceAllString(host, "_")
+	for i := 2; r.logs[name]; i++ {
+		name = fmt.Sprintf("%s-%d", hostLogNameRegex.ReplaceAllString(host, "_"), i)
+	}
+	r.logs[name] = true
+	r.mu.Unlock()
+	return os.OpenFile(filepath.Join(r.Dir, "hosts", name+".log"), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, TempFilePermission)
+}
+
+// deploy deploys to one host, copying its output to the host's log, and
+// returns its result. The log ends with the outcome, so each one reads on
+// its own.
+func (r *deployRun) deploy(target fleetTarget, opts RemoteDeployOptions, newShell func(target string, out io.Writer) remoteShell, out io.Writer) fleetResult {
+	result := fleetResult{fleetTarget: target}
+	start := time.Now()
+	log, err := r.openHostLog(target.Host.Name)
+	if err != nil {
+		result.Err = fmt.Errorf("cannot create host log: %w", err)
+		return result
+	}
+	defer log.Close()
+	result.Log = log.Name()
+
+	w := io.MultiWriter(out, log)
+	result.Err = deployToHost(newShell(target.Target, w), target.Host, opts, target.Config.Files, w)
+	result.Duration = time.Since(start)
+	if result.Err != nil {
+		fmt.Fprintf(log, "--- %s failed after %s: %v\n", target.Target, formatDuration(result.Duration), result.Err)
+	} else {
+		fmt.Fprintf(log, "--- %s succeeded in %s\n", target.Target, formatDuration(result.Duration))
+	}
+	return result
+}
+
+// DeployReport is results.json in a deploy's run directory
+type DeployReport struct {
+	RunID     string             `json:"run_id"`
+	Started   string             `json:"started"`
+	Finished  string             `json:"finished"`
+	Policy    string             `json:"policy"`   // "all" or "quorum"
+	Required  int                `json:"required"` // Hosts that had to succeed
+	Succeeded int                `json:"succeeded"`
+	Failed    int                `json:"failed"`
+	Passed    bool               `json:"passed"` // Whether the policy was met (the exit status)
+	Hosts     []DeployHostReport `json:"hosts"`
+}
+
+// DeployHostReport is the outcome of one host in results.json
+type DeployHostReport struct {
+	Host     string `json:"host"`
+	Target   string `json:"target"`
+	Group    string `json:"group,omitempty"` // Manifest group
+	Status   string `json:"status"`          // "success" or "failed"
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+	Log      string `json:"log"` // Relative to the run directory
+}
+
+// report summarizes results against policy
+func (r *deployRun) report(results []fleetResult, policy DeployPolicy) DeployReport {
+	report := DeployReport{
+		RunID:    r.ID,
+		Started:  eventTimestamp(r.Started),
+		Finished: eventTimestamp(time.Now()),
+		Policy:   "all",
+		Required: policy.required(len(results)),
+		Hosts:    make([]DeployHostReport, len(results)),
+	}
+	if policy.Quorum > 0 {
+		report.Policy = "quorum"
+	}
+	for i, result := range results {
+		host := DeployHostReport{
+			Host:     result.Host.Name,
+			Target:   result.Target,
+			Group:    result.Group,
+			Status:   "success",
+			Duration: formatDuration(result.Duration),
+		}
+		if result.Log != "" {
+			host.Log, _ = filepath.Rel(r.Dir, result.Log)
+			host.Log = filepath.ToSlash(host.Log)
+		}
+		if result.Err != nil {
+			host.Status = "failed"
+			host.Error = result.Err.Error()
+			report.Failed++
+		} else {
+			report.Succeeded++
+		}
+		report.Hosts[i] = host
+	}
+	report.Passed = report.Succeeded >= report.Required
+	return report
+}
+
+// writeReport writes results.json, replacing it whole so a reader never
+// sees half of it
+func (r *deployRun) writeReport(report DeployReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(r.Dir, "results.json")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// printDeployMatrix prints one line per host, failures last, then the
+// totals against the policy
+func printDeployMatrix(w io.Writer, report DeployReport) {
+	hostWidth, groupWidth := len("HOST"), 0
+	for _, host := range report.Hosts {
+		hostWidth = max(hostWidth, len(host.Host))
+		groupWidth = max(groupWidth, len(host.Group))
+	}
+	if groupWidth > 0 {
+		groupWidth = max(groupWidth, len("GROUP"))
+	}
+
+	row := func(host, group, status, duration, detail string) {
+		line := fmt.Sprintf("   %-*s  ", hostWidth, host)
+		if groupWidth > 0 {
+			line += fmt.Sprintf("%-*s  ", groupWidth, group)
+		}
+		line += fmt.Sprintf("%-7s  %8s  %s", status, duration, detail)
+		fmt.Fprintln(w, strings.TrimRight(line, " "))
+	}
+	row("HOST", "GROUP", "STATUS", "TIME", "")
+	for _, failed := range []bool{false, true} {
+		for _, host := range report.Hosts {
+			if (host.Status == "failed") != failed {
+				continue
+			}
+			if failed {
+				row(host.Host, host.Group, "✗ fail", host.Duration, host.Error)
+			} else {
+				row(host.Host, host.Group, "✓ ok", host.Duration, "")
+			}
+		}
+	}
+
+	verdict := "met"
+	if !report.Passed {
+		verdict = "not met"
+	}
+	fmt.Fprintf(w, "\n   %d of %d hosts succeeded; %d required (%s)\n", report.Succeeded, len(report.Hosts), report.Required, verdict)
+}
+
+// finishDeployRun writes results.json, prints the matrix and where the logs
+// are, and returns an error when the policy was not met
+func finishDeployRun(w io.Writer, run *deployRun, results []fleetResult, policy DeployPolicy) error {
+	report := run.report(results, policy)
+	fmt.Fprintln(w, "\nDeploy results:")
+	printDeployMatrix(w, report)
+	if err := run.writeReport(report); err != nil {
+		fmt.Fprintf(w, "   ⚠️  cannot write results: %v\n", err)
+	}
+	fmt.Fprintf(w, "   Logs: %s\n", run.Dir)
+	if !report.Passed {
+		return fmt.Errorf("%d of %d hosts failed (%s)", report.Failed, len(results), policy)
+	}
+	return nil
+}
+
+// formatDuration rounds a host's deploy time for the matrix and report
+func formatDuration(d time.Duration) string {
+	return d.Round(100 * time.Millisecond).String()
+}
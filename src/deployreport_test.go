@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestDeployRun tests per-host logs, results.json and the exit policy of a
+// multi-host deploy
+func TestDeployRun(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "run")
+	run, err := newDeployRun(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binary := filepath.Join(t.TempDir(), "sink")
+	os.WriteFile(binary, []byte("binary"), 0755)
+
+	config := &fleetConfig{Path: "https://example.com/web.json"}
+	var targets []fleetTarget
+	for i, host := range []string{"deploy@web01", "deploy@web02", "admin@web02", "deploy@web03"} {
+		targets = append(targets, fleetTarget{Group: "web", Target: host, Host: HostInfo{Name: targetHostName(host), Index: i, Count: 4}, Config: config})
+	}
+	var out bytes.Buffer
+	results := runFleet(targets, 2, RemoteDeployOptions{Binary: binary, Yes: true}, run, func(target string, w io.Writer) remoteShell {
+		shell := &fakeShell{}
+		if target == "deploy@web03" {
+			shell.execErr = fmt.Errorf("exit status 1")
+		}
+		return shell
+	}, &out)
+
+	// Each host has its own unprefixed log ending with the outcome, even two
+	// targets on one host
+	data, err := os.ReadFile(filepath.Join(dir, "hosts", "web03.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "   ✓ Transferred sink binary\n") || !strings.Contains(string(data), "--- deploy@web03 failed after") {
+		t.Errorf("unexpected host log:\n%s", data)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "hosts", "web02-2.log")); err != nil {
+		t.Errorf("expected a second log for the second target on web02: %v", err)
+	}
+
+	tests := []struct {
+		policy DeployPolicy
+		passed bool
+	}{
+		{DeployPolicy{}, false},
+		{DeployPolicy{Quorum: 3}, true},
+		{DeployPolicy{Quorum: 4}, false},
+	}
+	for _, tt := range tests {
+		var matrix bytes.Buffer
+		err := finishDeployRun(&matrix, run, results, tt.policy)
+		if (err == nil) != tt.passed {
+			t.Errorf("%s: expected passed=%v, got %v", tt.policy, tt.passed, err)
+		}
+		if !strings.Contains(matrix.String(), "3 of 4 hosts succeeded") {
+			t.Errorf("%s: expected the totals in the matrix, got:\n%s", tt.policy, matrix.String())
+		}
+	}
+
+	var report DeployReport
+	data, _ = os.ReadFile(filepath.Join(dir, "results.json"))
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Policy != "quorum" || report.Required != 4 || report.Succeeded != 3 || report.Passed || len(report.Hosts) != 4 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+	if web03 := report.Hosts[3]; web03.Status != "failed" || web03.Log != "hosts/web03.log" || !strings.Contains(web03.Error, "execution failed") {
+		t.Errorf("unexpected host report: %+v", web03)
+	}
+}
+
+// TestDeployMatrix tests that failures are listed last with their error
+func TestDeployMatrix(t *testing.T) {
+	var out bytes.Buffer
+	printDeployMatrix(&out, DeployReport{Required: 2, Succeeded: 1, Hosts: []DeployHostReport{
+		{Host: "db", Status: "failed", Error: "execution failed: exit status 1", Duration: "2s"},
+		{Host: "web01", Status: "success", Duration: "1.5s"},
+	}})
+	want := `   HOST   STATUS       TIME
+   web01  ✓ ok         1.5s
+   db     ✗ fail         2s  execution failed: exit status 1
+
+   1 of 2 hosts succeeded; 2 required (not met)
+`
+	if out.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+// TestDeployPolicy tests --quorum parsing and checking
+func TestDeployPolicy(t *testing.T) {
+	for _, value := range []string{"0", "-1", "half"} {
+		if _, err := parseQuorum(value); err == nil {
+			t.Errorf("expected --quorum %s to be refused", value)
+		}
+	}
+	if n, err := parseQuorum("3"); err != nil || n != 3 {
+		t.Errorf("expected 3, got %d, %v", n, err)
+	}
+	if err := (DeployPolicy{Quorum: 5}).check(4); err == nil {
+		t.Error("expected a quorum larger than the deploy to be refused")
+	}
+	if err := (DeployPolicy{}).check(4); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
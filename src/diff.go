@@ -0,0 +1,100 @@
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+// ANSI colors for diff output
+const (
+	ansiRed   = "\033[31m"
+	ansiGreen = "\033[32m"
+	ansiReset = "\033[0m"
+)
+
+// OutputMismatch records a check whose output differed from expect_output
+type OutputMismatch struct {
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+// diffLine is one line of a line-level diff
+type diffLine struct {
+	Op   byte // ' ' (both), '-' (expected only) or '+' (actual only)
+	Text string
+}
+
+// diffLines computes a line diff from expected to actual using the longest
+// common subsequence, which is plenty for short check output
+func diffLines(expected, actual string) []diffLine {
+	a := strings.Split(expected, "\n")
+	b := strings.Split(actual, "\n")
+
+	// lcs[i][j] is the LCS length of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var lines []diffLine
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, diffLine{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, diffLine{'-', a[i]})
+			i++
+		default:
+			lines = append(lines, diffLine{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, diffLine{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, diffLine{'+', b[j]})
+	}
+	return lines
+}
+
+// formatOutputDiff renders expected-vs-actual output as a unified-style diff,
+// one line per entry prefixed with indent. Removed (expected) lines are red
+// and added (actual) lines green when color is true.
+func formatOutputDiff(m OutputMismatch, indent string, color bool) string {
+	var b strings.Builder
+	b.WriteString(indent + "--- expected\n")
+	b.WriteString(indent + "+++ actual\n")
+	for _, line := range diffLines(m.Expected, m.Actual) {
+		text := string(line.Op) + " " + line.Text
+		switch {
+		case color && line.Op == '-':
+			text = ansiRed + text + ansiReset
+		case color && line.Op == '+':
+			text = ansiGreen + text + ansiReset
+		}
+		b.WriteString(indent + strings.TrimRight(text, " ") + "\n")
+	}
+	return b.String()
+}
+
+// colorEnabled reports whether f is a terminal that should get colors.
+// NO_COLOR (https://no-color.org) and TERM=dumb turn colors off.
+func colorEnabled(f *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return isTerminal(f)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestDiffLines tests the line diff between expected and actual output
+func TestDiffLines(t *testing.T) {
+	lines := diffLines("go1.22.0\nlinux/amd64", "go1.21.3\nlinux/amd64")
+	var got []string
+	for _, line := range lines {
+		got = append(got, string(line.Op)+line.Text)
+	}
+	want := "-go1.22.0,+go1.21.3, linux/amd64"
+	if strings.Join(got, ",") != want {
+		t.Errorf("diffLines = %v, want %s", got, want)
+	}
+}
+
+// TestFormatOutputDiff tests plain and colored diff rendering
+func TestFormatOutputDiff(t *testing.T) {
+	m := OutputMismatch{Expected: "1.22.0", Actual: "1.21.3"}
+
+	plain := formatOutputDiff(m, "  ", false)
+	want := "  --- expected\n  +++ actual\n  - 1.22.0\n  + 1.21.3\n"
+	if plain != want {
+		t.Errorf("plain diff = %q, want %q", plain, want)
+	}
+
+	colored := formatOutputDiff(m, "", true)
+	if !strings.Contains(colored, ansiRed+"- 1.22.0"+ansiReset) || !strings.Contains(colored, ansiGreen+"+ 1.21.3"+ansiReset) {
+		t.Errorf("colored diff missing colors: %q", colored)
+	}
+}
+
+// TestExecuteCheckError_ExpectOutput tests expect_output checks and the mismatch event
+func TestExecuteCheckError_ExpectOutput(t *testing.T) {
+	transport := &MockTransportWithTracking{responses: map[string]MockResponse{
+		"go version | cut -d' ' -f3": {stdout: "go1.21.3\n"},
+	}}
+	executor := NewExecutor(transport)
+	var events []ExecutionEvent
+	executor.OnEvent = func(event ExecutionEvent) { events = append(events, event) }
+
+	expect := "go{{.go_version}}"
+	step := InstallStep{Name: "go version", Step: CheckErrorStep{
+		Check:        "go version | cut -d' ' -f3",
+		Error:        "wrong Go version",
+		ExpectOutput: &expect,
+	}}
+
+	result := executor.ExecuteStep(step, Facts{"go_version": "1.22.0"})
+	if result.Error != "wrong Go version" {
+		t.Fatalf("expected check failure, got %+v", result)
+	}
+	want := OutputMismatch{Expected: "go1.22.0", Actual: "go1.21.3"}
+	if result.OutputMismatch == nil || *result.OutputMismatch != want {
+		t.Errorf("OutputMismatch = %+v, want %+v", result.OutputMismatch, want)
+	}
+	last := events[len(events)-1]
+	if last.Status != "failed" || last.OutputMismatch == nil || *last.OutputMismatch != want {
+		t.Errorf("failed event missing mismatch: %+v", last)
+	}
+
+	if result := executor.ExecuteStep(step, Facts{"go_version": "1.21.3"}); result.Error != "" || result.OutputMismatch != nil {
+		t.Errorf("expected matching output to pass, got %+v", result)
+	}
+}
+
+// TestExecuteCheckRemediate_ExpectOutput tests remediation of an output mismatch
+func TestExecuteCheckRemediate_ExpectOutput(t *testing.T) {
+	version := "1.0"
+	transport := &StatefulMockTransport{
+		runFunc: func(cmd string) (string, string, int, error) {
+			switch strings.TrimSpace(cmd) {
+			case "tool --version":
+				return version + "\n", "", 0, nil
+			case "upgrade tool":
+				version = "2.0"
+				return "", "", 0, nil
+			}
+			return "", "", 0, nil
+		},
+	}
+	executor := NewExecutor(transport)
+
+	expect := "2.0"
+	result := executor.ExecuteStep(InstallStep{Name: "tool", Step: CheckRemediateStep{
+		Check:        "tool --version",
+		ExpectOutput: &expect,
+		OnMissing:    []RemediationStep{{Name: "upgrade", Command: "upgrade tool"}},
+	}}, Facts{})
+
+	if result.Error != "" || len(result.RemediationSteps) != 1 {
+		t.Errorf("expected mismatch to be remediated, got %+v", result)
+	}
+}
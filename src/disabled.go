@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// disableMarkerPaths returns the host-side files that opt a machine out of
+// convergence. Variable so tests can point it at a temporary directory.
+var disableMarkerPaths = func() []string {
+	paths := []string{"/etc/sink/disabled"}
+	if home, err := os.UserHomeDir(); err == nil {
+		paths = append(paths, filepath.Join(home, ".sink", "skip"))
+	}
+	return paths
+}
+
+// HostDisabledEvent reports a run skipped because of a disable marker
+type HostDisabledEvent struct {
+	Timestamp string `json:"timestamp"`
+	Event     string `json:"event"`  // Always "host_disabled"
+	Marker    string `json:"marker"` // Marker file that was found
+	Reason    string `json:"reason,omitempty"`
+}
+
+// findDisableMarker returns the first disable marker present on this host and
+// its first line (the operator's reason), or "" when management is enabled
+func findDisableMarker() (string, string) {
+	for _, path := range disableMarkerPaths() {
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		data, _ := os.ReadFile(path) // An unreadable marker still disables
+		reason, _, _ := strings.Cut(strings.TrimSpace(string(data)), "\n")
+		return path, strings.TrimSpace(reason)
+	}
+	return "", ""
+}
+
+// remoteDisableMarkerScript prints the first disable marker present on a
+// remote host and its first non-blank line, the same files as
+// disableMarkerPaths but with the remote user's home
+const remoteDisableMarkerScript = `for f in /etc/sink/disabled "$HOME/.sink/skip"; do ` +
+	`if [ -e "$f" ]; then echo "$f"; grep -m 1 '[^[:space:]]' "$f" 2>/dev/null; exit 0; fi; done`
+
+// findRemoteDisableMarker is findDisableMarker for the host a transport runs
+// commands on. A host that cannot be reached reports no marker, since the
+// run itself then fails to connect.
+func findRemoteDisableMarker(transport Transport) (string, string) {
+	stdout, _, exitCode, err := transport.Run(remoteDisableMarkerScript)
+	if err != nil || exitCode != 0 {
+		return "", ""
+	}
+	marker, reason, _ := strings.Cut(strings.TrimSpace(stdout), "\n")
+	return strings.TrimSpace(marker), strings.TrimSpace(reason)
+}
+
+// exitIfHostDisabled ends the run successfully when a disable marker exists
+// on the host the steps run on (this one, or the transport's when it is not
+// nil), so operators can temporarily opt a machine out without failing
+// schedulers
+func exitIfHostDisabled(transport Transport, jsonOutput bool, force bool) {
+	var marker, reason string
+	if transport == nil {
+		marker, reason = findDisableMarker()
+	} else {
+		marker, reason = findRemoteDisableMarker(transport)
+	}
+	if marker == "" {
+		return
+	}
+	if force {
+		if !jsonOutput {
+			fmt.Printf("⚠️  Management is disabled on this host (%s); continuing because of --force\n\n", marker)
+		}
+		return
+	}
+
+	if jsonOutput {
+		jsonBytes, err := json.MarshalIndent(HostDisabledEvent{
+			Timestamp: eventTimestamp(time.Now()),
+			Event:     "host_disabled",
+			Marker:    marker,
+			Reason:    reason,
+		}, "", "  ")
+		if err == nil {
+			writeJSONObject(os.Stdout, jsonBytes)
+		}
+	} else {
+		fmt.Printf("⏸  Management disabled on this host (%s)\n", marker)
+		if reason != "" {
+			fmt.Printf("   Reason: %s\n", reason)
+		}
+		fmt.Printf("   Remove the file or pass --force to run anyway\n")
+	}
+	os.Exit(0)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// TestFindDisableMarker tests detection of host-side disable markers
+func TestFindDisableMarker(t *testing.T) {
+	dir := t.TempDir()
+	etcMarker := filepath.Join(dir, "etc", "disabled")
+	homeMarker := filepath.Join(dir, "home", "skip")
+
+	orig := disableMarkerPaths
+	disableMarkerPaths = func() []string { return []string{etcMarker, homeMarker} }
+	defer func() { disableMarkerPaths = orig }()
+
+	if marker, _ := findDisableMarker(); marker != "" {
+		t.Errorf("expected no marker, got %s", marker)
+	}
+
+	os.MkdirAll(filepath.Dir(homeMarker), 0755)
+	os.WriteFile(homeMarker, nil, 0644)
+	if marker, reason := findDisableMarker(); marker != homeMarker || reason != "" {
+		t.Errorf("got %q %q, want empty marker at %s", marker, reason, homeMarker)
+	}
+
+	os.MkdirAll(filepath.Dir(etcMarker), 0755)
+	os.WriteFile(etcMarker, []byte("  disk replacement, ticket OPS-12\nsecond line\n"), 0644)
+	if marker, reason := findDisableMarker(); marker != etcMarker || reason != "disk replacement, ticket OPS-12" {
+		t.Errorf("got %q %q", marker, reason)
+	}
+}
+
+// TestFindRemoteDisableMarker tests that a remote host's marker is found
+// through its transport
+func TestFindRemoteDisableMarker(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the marker script needs a POSIX shell")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	transport := NewLocalTransport()
+	if _, err := os.Stat("/etc/sink/disabled"); err == nil {
+		t.Skip("this host is disabled")
+	}
+
+	if marker, _ := findRemoteDisableMarker(transport); marker != "" {
+		t.Errorf("expected no marker, got %s", marker)
+	}
+
+	os.MkdirAll(filepath.Join(home, ".sink"), 0755)
+	os.WriteFile(filepath.Join(home, ".sink", "skip"), []byte("\n  kernel upgrade\n"), 0644)
+	marker, reason := findRemoteDisableMarker(transport)
+	if marker != filepath.Join(home, ".sink", "skip") || reason != "kernel upgrade" {
+		t.Errorf("got %q %q", marker, reason)
+	}
+
+	unreachable := &MockTransport{responses: map[string]MockResponse{}}
+	if marker, _ := findRemoteDisableMarker(unreachable); marker != "" {
+		t.Errorf("expected no marker from a failed command, got %s", marker)
+	}
+}
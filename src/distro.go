@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// distributionIDsCommand prints the host's distribution ID followed by the
+// IDs of the distributions it derives from, e.g. "linuxmint ubuntu debian"
+const distributionIDsCommand = `. /etc/os-release 2>/dev/null && echo "$ID $ID_LIKE"`
+
+// detectDistributionIDs returns the host's distribution IDs from
+// /etc/os-release, most specific first, or nil when there is none
+func detectDistributionIDs(t Transport) []string {
+	stdout, _, exitCode, err := t.Run(distributionIDsCommand)
+	if err != nil || exitCode != 0 {
+		return nil
+	}
+	return strings.Fields(strings.ToLower(strings.Trim(stdout, "\"' \n")))
+}
+
+// selectDistribution returns platform reduced to the install steps of its
+// distribution for a host with the given IDs, and that distribution.
+// Platforms without distributions are returned as they are. A host whose
+// distribution is not listed gets the platform's fallback error.
+func selectDistribution(platform *Platform, ids []string) (*Platform, *Distribution, error) {
+	if len(platform.Distributions) == 0 {
+		return platform, nil, nil
+	}
+	for _, id := range ids {
+		for i, dist := range platform.Distributions {
+			if containsString(dist.IDs, id) {
+				selected := *platform
+				selected.Name = fmt.Sprintf("%s, %s", platform.Name, dist.Name)
+				selected.InstallSteps = dist.InstallSteps
+				selected.Distributions = nil
+				return &selected, &platform.Distributions[i], nil
+			}
+		}
+	}
+
+	if platform.Fallback != nil {
+		return nil, nil, fmt.Errorf("%s", platform.Fallback.Error)
+	}
+	if len(ids) == 0 {
+		return nil, nil, fmt.Errorf("cannot detect the distribution (no /etc/os-release) to choose among the %s distributions", platform.Name)
+	}
+	return nil, nil, fmt.Errorf("no %s distribution in the config matches '%s'", platform.Name, ids[0])
+}
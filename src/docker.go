@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// distributionImages maps distribution IDs to the official images sink test
+// --in-docker runs them in. Other IDs use <id>:latest.
+var distributionImages = map[string]string{
+	"ubuntu":        "ubuntu:latest",
+	"debian":        "debian:stable",
+	"alpine":        "alpine:latest",
+	"fedora":        "fedora:latest",
+	"rocky":         "rockylinux:9",
+	"almalinux":     "almalinux:9",
+	"amzn":          "amazonlinux:2023",
+	"arch":          "archlinux:latest",
+	"opensuse-leap": "opensuse/leap:latest",
+}
+
+// dockerHostname is the container host name, which --confirm-host is given
+// so configs that ask for confirmation run unattended
+const dockerHostname = "sink-test"
+
+// targetImage returns the image to run a target in; images overrides the
+// defaults by distribution ID. Only Linux targets run in containers.
+func targetImage(target MatrixTarget, images map[string]string) (string, error) {
+	if target.OS != "linux" {
+		return "", fmt.Errorf("containers only run linux targets")
+	}
+	id := target.Distro
+	if id == "" {
+		id = "ubuntu"
+	}
+	if image, ok := images[id]; ok {
+		return image, nil
+	}
+	if image, ok := distributionImages[id]; ok {
+		return image, nil
+	}
+	return id + ":latest", nil
+}
+
+// dockerRunArgs returns the docker arguments that run a config inside image.
+// The config's directory is mounted read-only and copied, so files the
+// config refers to are found and the host's copy is never changed. A
+// non-empty coverageDir is mounted for the run's coverage file.
+func dockerRunArgs(image, binary, configPath, coverageDir string, variables map[string]string) []string {
+	args := []string{
+		"run", "--rm", "--hostname", dockerHostname,
+		"-v", binary + ":/usr/local/bin/sink:ro",
+		"-v", filepath.Dir(configPath) + ":/src:ro",
+	}
+	if coverageDir != "" {
+		args = append(args, "-v", coverageDir+":/coverage")
+	}
+	args = append(args, image,
+		"sh", "-c", `cp -a /src /work && cd /work && exec sink execute "$0" --confirm-host `+dockerHostname+` "$@"`,
+		filepath.Base(configPath),
+	)
+	if coverageDir != "" {
+		args = append(args, "--coverage", "/coverage/"+dockerCoverageFile)
+	}
+	for _, name := range sortedKeys(variables) {
+		args = append(args, "--var", name+"="+variables[name])
+	}
+	return args
+}
+
+// dockerCoverageFile is the coverage file a container run writes
+const dockerCoverageFile = "coverage.json"
+
+// DockerResult is the outcome of running a config in a container
+type DockerResult struct {
+	Target   MatrixTarget
+	Image    string
+	Output   string // Combined output of docker and sink
+	Duration time.Duration
+	Error    string // Why the run failed; empty when it passed
+	Skipped  bool   // The target cannot run in a container
+	Coverage *Coverage
+}
+
+// runInDocker executes the config for real inside the target's image; with
+// coverage it records the branches the run takes
+func runInDocker(target MatrixTarget, images map[string]string, binary, configPath string, variables map[string]string, coverage bool) DockerResult {
+	result := DockerResult{Target: target}
+	image, err := targetImage(target, images)
+	if err != nil {
+		result.Error = err.Error()
+		result.Skipped = true
+		return result
+	}
+	result.Image = image
+
+	var coverageDir string
+	if coverage {
+		if coverageDir, err = os.MkdirTemp("", "sink-coverage-"); err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		defer os.RemoveAll(coverageDir)
+	}
+
+	start := time.Now()
+	output, err := exec.Command("docker", dockerRunArgs(image, binary, configPath, coverageDir, variables)...).CombinedOutput()
+	result.Duration = time.Since(start).Round(time.Second)
+	result.Output = string(output)
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		result.Error = fmt.Sprintf("exit code %d", exitErr.ExitCode())
+	} else if err != nil {
+		result.Error = err.Error()
+	}
+	if coverage {
+		// A run that stopped before choosing its steps records nothing
+		result.Coverage, _ = loadCoverage(filepath.Join(coverageDir, dockerCoverageFile))
+	}
+	return result
+}
+
+// dockerOutputTail is how many lines of a failed run's output are shown
+// without --verbose
+const dockerOutputTail = 15
+
+// printDockerResult reports a container run; verbose shows all its output
+func printDockerResult(w io.Writer, result DockerResult, verbose bool) {
+	if result.Skipped {
+		fmt.Fprintf(w, "⚠ %s: skipped (%s)\n", result.Target, result.Error)
+		return
+	}
+	mark := "✓"
+	if result.Error != "" {
+		mark = "✗"
+	}
+	fmt.Fprintf(w, "%s %s (%s) in %s", mark, result.Target, result.Image, result.Duration)
+	if result.Error != "" {
+		fmt.Fprintf(w, ": %s", result.Error)
+	}
+	fmt.Fprintln(w)
+	if result.Error == "" && !verbose {
+		return
+	}
+	lines := strings.Split(strings.TrimRight(result.Output, "\n"), "\n")
+	if !verbose && len(lines) > dockerOutputTail {
+		fmt.Fprintf(w, "   ... (%d lines, -v shows all)\n", len(lines)-dockerOutputTail)
+		lines = lines[len(lines)-dockerOutputTail:]
+	}
+	for _, line := range lines {
+		fmt.Fprintf(w, "   │ %s\n", line)
+	}
+}
+
+// dockerBinary returns the sink binary to mount into containers: binary if
+// given, else this executable when it is a Linux build
+func dockerBinary(binary string) (string, error) {
+	if binary == "" {
+		if runtime.GOOS != "linux" {
+			return "", fmt.Errorf("this sink is built for %s; pass --binary with a linux build (make build-static)", runtime.GOOS)
+		}
+		exe, err := os.Executable()
+		if err != nil {
+			return "", fmt.Errorf("cannot find the sink executable: %w; pass --binary", err)
+		}
+		binary = exe
+	}
+	abs, err := filepath.Abs(binary)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(abs); err != nil {
+		return "", fmt.Errorf("--binary: %w", err)
+	}
+	return abs, nil
+}
+
+// testInDocker runs the config in a container for each target and reports
+// which pass, returning the number of failures. Each run's branches are
+// merged into coverage when it is not nil.
+func testInDocker(w io.Writer, configPath string, targets []MatrixTarget, images map[string]string, binary string, variables map[string]string, verbose bool, coverage *Coverage) (int, error) {
+	if _, err := exec.LookPath("docker"); err != nil {
+		return 0, fmt.Errorf("--in-docker needs docker on the PATH: %w", err)
+	}
+	binary, err := dockerBinary(binary)
+	if err != nil {
+		return 0, err
+	}
+	configPath, err = filepath.Abs(configPath)
+	if err != nil {
+		return 0, err
+	}
+
+	failed := 0
+	for _, target := range targets {
+		result := runInDocker(target, images, binary, configPath, variables, coverage != nil)
+		printDockerResult(w, result, verbose)
+		if coverage != nil && result.Coverage != nil {
+			coverage.merge(result.Coverage)
+		}
+		if result.Error != "" && !result.Skipped {
+			failed++
+		}
+	}
+	return failed, nil
+}
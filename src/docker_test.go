@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestSelectDistribution tests choosing a platform's distribution by the
+// host's os-release IDs
+func TestSelectDistribution(t *testing.T) {
+	config, err := parseConfigData([]byte(matrixConfig), LoadOptions{})
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	linux := findPlatform(config, "linux")
+
+	// Linux Mint is unlisted but derives from Ubuntu
+	mock := &MockTransport{responses: map[string]MockResponse{
+		distributionIDsCommand: {stdout: "linuxmint ubuntu debian\n"},
+	}}
+	ids := detectDistributionIDs(mock)
+	selected, dist, err := selectDistribution(linux, ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if selected.Name != "Linux, Ubuntu" || len(selected.InstallSteps) != 1 || len(selected.Distributions) != 0 || dist.IDs[0] != "ubuntu" {
+		t.Errorf("unexpected selection %+v", selected)
+	}
+	if len(linux.Distributions) != 2 {
+		t.Error("selecting a distribution changed the config's platform")
+	}
+
+	if _, _, err := selectDistribution(linux, []string{"arch"}); err == nil || err.Error() != "unsupported distribution" {
+		t.Errorf("expected the platform fallback, got %v", err)
+	}
+	linux.Fallback = nil
+	if _, _, err := selectDistribution(linux, detectDistributionIDs(&MockTransport{})); err == nil || !strings.Contains(err.Error(), "/etc/os-release") {
+		t.Errorf("expected an os-release error, got %v", err)
+	}
+
+	darwin := findPlatform(config, "darwin")
+	if selected, _, err := selectDistribution(darwin, nil); err != nil || selected != darwin {
+		t.Errorf("a platform without distributions should be kept, got %v, %v", selected, err)
+	}
+}
+
+// TestDockerRunArgs tests the images and docker arguments of container runs
+func TestDockerRunArgs(t *testing.T) {
+	images := map[string]string{"ubuntu": "ubuntu:22.04"}
+	tests := []struct {
+		target MatrixTarget
+		image  string
+	}{
+		{MatrixTarget{OS: "linux", Distro: "fedora"}, "fedora:latest"},
+		{MatrixTarget{OS: "linux", Distro: "amzn"}, "amazonlinux:2023"},
+		{MatrixTarget{OS: "linux", Distro: "void"}, "void:latest"},
+		{MatrixTarget{OS: "linux"}, "ubuntu:22.04"},
+	}
+	for _, tt := range tests {
+		if image, err := targetImage(tt.target, images); err != nil || image != tt.image {
+			t.Errorf("%s: expected %s, got %s (%v)", tt.target, tt.image, image, err)
+		}
+	}
+	if _, err := targetImage(MatrixTarget{OS: "darwin"}, nil); err == nil {
+		t.Error("expected darwin to have no image")
+	}
+
+	args := dockerRunArgs("alpine:latest", "/opt/sink", "/home/me/configs/install.json", "", map[string]string{"version": "2", "channel": "beta"})
+	got := strings.Join(args, " ")
+	for _, want := range []string{
+		"run --rm --hostname sink-test",
+		"-v /opt/sink:/usr/local/bin/sink:ro -v /home/me/configs:/src:ro alpine:latest sh -c",
+		`--confirm-host sink-test "$@" install.json --var channel=beta --var version=2`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in docker arguments:\n%s", want, got)
+		}
+	}
+
+	got = strings.Join(dockerRunArgs("alpine:latest", "/opt/sink", "/cfg/install.json", "/tmp/cov", nil), " ")
+	if !strings.Contains(got, "-v /tmp/cov:/coverage alpine:latest") || !strings.HasSuffix(got, "install.json --coverage /coverage/coverage.json") {
+		t.Errorf("expected the coverage directory to be mounted and passed:\n%s", got)
+	}
+}
+
+// TestPrintDockerResult tests that failed runs show the tail of their output
+func TestPrintDockerResult(t *testing.T) {
+	var lines []string
+	for i := 1; i <= 20; i++ {
+		lines = append(lines, "line "+strings.Repeat("x", i))
+	}
+	result := DockerResult{Target: MatrixTarget{OS: "linux", Distro: "alpine"}, Image: "alpine:latest", Output: strings.Join(lines, "\n"), Error: "exit code 1"}
+
+	var out bytes.Buffer
+	printDockerResult(&out, result, false)
+	text := out.String()
+	if !strings.HasPrefix(text, "✗ linux/alpine (alpine:latest) in 0s: exit code 1\n") {
+		t.Errorf("unexpected summary line:\n%s", text)
+	}
+	if strings.Contains(text, "│ line x\n") || !strings.Contains(text, "(5 lines, -v shows all)") || !strings.Contains(text, lines[19]) {
+		t.Errorf("expected the last %d lines:\n%s", dockerOutputTail, text)
+	}
+
+	out.Reset()
+	result.Error, result.Output = "", "done"
+	printDockerResult(&out, result, false)
+	if out.String() != "✓ linux/alpine (alpine:latest) in 0s\n" {
+		t.Errorf("unexpected output for a passed run:\n%s", out.String())
+	}
+}
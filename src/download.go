@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Download event statuses
+const (
+	DownloadStarted   = "started"
+	DownloadProgress  = "progress"
+	DownloadCompleted = "completed"
+	DownloadFailed    = "failed"
+)
+
+// DownloadEvent reports the progress of an HTTP download
+type DownloadEvent struct {
+	Timestamp   string  `json:"timestamp"`
+	Event       string  `json:"event"`  // Always "download"
+	Status      string  `json:"status"` // "started", "progress", "completed", "failed"
+	URL         string  `json:"url"`
+	Bytes       int64   `json:"bytes"`             // Bytes received so far
+	Total       int64   `json:"total,omitempty"`   // Content-Length (omitted when unknown)
+	Percent     float64 `json:"percent,omitempty"` // 0-100 (omitted when total is unknown)
+	BytesPerSec float64 `json:"bytes_per_sec"`     // Average transfer rate
+	Error       string  `json:"error,omitempty"`
+}
+
+// progressReader wraps a response body and reports download progress
+type progressReader struct {
+	r        io.Reader
+	url      string
+	total    int64
+	read     int64
+	start    time.Time
+	last     time.Time
+	interval time.Duration
+	report   func(DownloadEvent)
+}
+
+// newProgressReader creates a reader that reports progress at most once per interval
+func newProgressReader(r io.Reader, url string, total int64, interval time.Duration, report func(DownloadEvent)) *progressReader {
+	now := time.Now()
+	pr := &progressReader{
+		r:        r,
+		url:      url,
+		total:    total,
+		start:    now,
+		last:     now,
+		interval: interval,
+		report:   report,
+	}
+	pr.emit(DownloadStarted, "")
+	return pr
+}
+
+// Read implements io.Reader
+func (pr *progressReader) Read(p []byte) (int, error) {
+	n, err := pr.r.Read(p)
+	pr.read += int64(n)
+
+	if n > 0 && time.Since(pr.last) >= pr.interval {
+		pr.last = time.Now()
+		pr.emit(DownloadProgress, "")
+	}
+
+	return n, err
+}
+
+// finish reports the final status of the download
+func (pr *progressReader) finish(err error) {
+	if err != nil {
+		pr.emit(DownloadFailed, err.Error())
+		return
+	}
+	pr.emit(DownloadCompleted, "")
+}
+
+// emit builds and reports a download event
+func (pr *progressReader) emit(status string, errMsg string) {
+	if pr.report == nil {
+		return
+	}
+
+	event := DownloadEvent{
+		Timestamp: eventTimestamp(time.Now()),
+		Event:     "download",
+		Status:    status,
+		URL:       pr.url,
+		Bytes:     pr.read,
+		Error:     errMsg,
+	}
+	if pr.total > 0 {
+		event.Total = pr.total
+		event.Percent = float64(pr.read) * 100 / float64(pr.total)
+	}
+	if elapsed := time.Since(pr.start).Seconds(); elapsed > 0 {
+		event.BytesPerSec = float64(pr.read) / elapsed
+	}
+
+	pr.report(event)
+}
+
+// downloadReporter returns the progress reporter for the current output mode:
+// JSON events on stdout, or a progress bar on stderr for humans
+func downloadReporter(jsonOutput bool) func(DownloadEvent) {
+	if jsonOutput {
+		return emitDownloadEventJSON
+	}
+	return newDownloadProgressBar(os.Stderr)
+}
+
+// emitDownloadEventJSON writes a download event to stdout as JSON
+func emitDownloadEventJSON(event DownloadEvent) {
+	jsonBytes, err := json.MarshalIndent(event, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: Failed to marshal download event to JSON: %v\n", err)
+		return
+	}
+	writeJSONObject(os.Stdout, jsonBytes)
+}
+
+// newDownloadProgressBar returns a reporter that redraws a single progress line.
+// Nothing is drawn for downloads that complete before the first progress update.
+func newDownloadProgressBar(w io.Writer) func(DownloadEvent) {
+	drawn := false
+	return func(event DownloadEvent) {
+		switch event.Status {
+		case DownloadProgress:
+			fmt.Fprintf(w, "\r   %s", formatDownloadProgress(event))
+			drawn = true
+		case DownloadCompleted, DownloadFailed:
+			if drawn {
+				fmt.Fprintf(w, "\r   %s\n", formatDownloadProgress(event))
+			}
+		}
+	}
+}
+
+// formatDownloadProgress renders "[=====>    ]  42% 1.2 MiB / 2.9 MiB (512.0 KiB/s)"
+func formatDownloadProgress(event DownloadEvent) string {
+	rate := formatBytes(int64(event.BytesPerSec)) + "/s"
+	if event.Total <= 0 {
+		return fmt.Sprintf("%s (%s)", formatBytes(event.Bytes), rate)
+	}
+
+	const width = 30
+	filled := int(event.Percent / 100 * width)
+	if filled > width {
+		filled = width
+	}
+	bar := strings.Repeat("=", filled)
+	if filled < width {
+		bar += ">" + strings.Repeat(" ", width-filled-1)
+	}
+
+	return fmt.Sprintf("[%s] %3.0f%% %s / %s (%s)", bar, event.Percent, formatBytes(event.Bytes), formatBytes(event.Total), rate)
+}
+
+// formatBytes renders a byte count with binary units
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
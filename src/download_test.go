@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// TestProgressReaderEvents tests that download progress is reported
+func TestProgressReaderEvents(t *testing.T) {
+	data := strings.Repeat("x", 4096)
+	var events []DownloadEvent
+
+	pr := newProgressReader(strings.NewReader(data), "https://example.com/big.tar.gz", int64(len(data)), 0, func(e DownloadEvent) {
+		events = append(events, e)
+	})
+	buf := make([]byte, 1024)
+	for {
+		_, err := pr.Read(buf)
+		if err == io.EOF {
+			break
+		}
+	}
+	pr.finish(nil)
+
+	if len(events) < 3 {
+		t.Fatalf("expected started, progress and completed events, got %d", len(events))
+	}
+	if events[0].Status != DownloadStarted {
+		t.Errorf("first event status = %s, want started", events[0].Status)
+	}
+	last := events[len(events)-1]
+	if last.Status != DownloadCompleted || last.Bytes != 4096 || last.Percent != 100 {
+		t.Errorf("unexpected final event: %+v", last)
+	}
+	for _, e := range events {
+		if e.Event != "download" || e.URL != "https://example.com/big.tar.gz" || e.Total != 4096 {
+			t.Errorf("unexpected event: %+v", e)
+		}
+	}
+}
+
+// TestProgressReaderFailure tests that failed downloads are reported
+func TestProgressReaderFailure(t *testing.T) {
+	var last DownloadEvent
+	pr := newProgressReader(strings.NewReader(""), "https://example.com/x", -1, 0, func(e DownloadEvent) { last = e })
+	pr.finish(errors.New("connection reset"))
+
+	if last.Status != DownloadFailed || last.Error != "connection reset" {
+		t.Errorf("unexpected event: %+v", last)
+	}
+	if last.Total != 0 || last.Percent != 0 {
+		t.Errorf("unknown total should leave total and percent empty: %+v", last)
+	}
+}
+
+// TestDownloadProgressBar tests human progress rendering
+func TestDownloadProgressBar(t *testing.T) {
+	var out bytes.Buffer
+	report := newDownloadProgressBar(&out)
+
+	// Fast download: nothing drawn
+	report(DownloadEvent{Status: DownloadStarted})
+	report(DownloadEvent{Status: DownloadCompleted, Bytes: 10, Total: 10, Percent: 100})
+	if out.Len() != 0 {
+		t.Errorf("expected no output for fast download, got %q", out.String())
+	}
+
+	report = newDownloadProgressBar(&out)
+	report(DownloadEvent{Status: DownloadProgress, Bytes: 512 * 1024, Total: 1024 * 1024, Percent: 50, BytesPerSec: 2048})
+	report(DownloadEvent{Status: DownloadCompleted, Bytes: 1024 * 1024, Total: 1024 * 1024, Percent: 100, BytesPerSec: 2048})
+
+	output := out.String()
+	for _, want := range []string{"50%", "512.0 KiB / 1.0 MiB", "2.0 KiB/s", "100%"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("progress output missing %q: %q", want, output)
+		}
+	}
+	if !strings.HasSuffix(output, "\n") {
+		t.Error("completed progress bar should end the line")
+	}
+}
+
+// TestFormatBytes tests byte count formatting
+func TestFormatBytes(t *testing.T) {
+	tests := map[int64]string{
+		0:               "0 B",
+		1023:            "1023 B",
+		1024:            "1.0 KiB",
+		1536:            "1.5 KiB",
+		5 * 1024 * 1024: "5.0 MiB",
+		3 << 30:         "3.0 GiB",
+	}
+	for n, want := range tests {
+		if got := formatBytes(n); got != want {
+			t.Errorf("formatBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
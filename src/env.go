@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Environment variables that configure sink without editing command lines.
+// Precedence: command-line flags > SINK_* environment variables > defaults.
+const (
+	EnvPlatform    = "SINK_PLATFORM"     // Same as --platform
+	EnvVerbose     = "SINK_VERBOSE"      // Same as --verbose (1/true/yes or 0/false/no)
+	EnvJSON        = "SINK_JSON"         // Same as --json (1/true/yes or 0/false/no)
+	EnvCacheDir    = "SINK_CACHE_DIR"    // Where sink keeps cached data
+	EnvStateDir    = "SINK_STATE_DIR"    // Where sink keeps state between runs
+	EnvLogDir      = "SINK_LOG_DIR"      // Where sink writes log files
+	EnvHTTPTimeout = "SINK_HTTP_TIMEOUT" // Timeout for HTTP requests ("45s" or seconds)
+
+	EnvCredentialHelper = "SINK_CREDENTIAL_HELPER" // Same as --credential-helper
+	EnvAuditLog         = "SINK_AUDIT_LOG"         // Same as --audit-log (1/true/yes or 0/false/no)
+	EnvWorkspaceDir     = "SINK_WORKSPACE_DIR"     // Where run workspaces are created
+
+	// Set by sink remote deploy on each target for {{.sink.host}} and friends
+	EnvHost      = "SINK_HOST"       // Host name as targeted
+	EnvHostIndex = "SINK_HOST_INDEX" // Position in the deploy's host list, from 0
+	EnvHostCount = "SINK_HOST_COUNT" // Number of hosts in the deploy
+)
+
+// EnvSettings holds global settings read from SINK_* environment variables
+type EnvSettings struct {
+	Platform    string
+	Verbose     bool
+	JSON        bool
+	CacheDir    string
+	StateDir    string
+	LogDir      string
+	HTTPTimeout time.Duration // Zero means the built-in defaults
+
+	CredentialHelper string
+	AuditLog         bool
+	Host             HostInfo // Zero Count means no SINK_HOST* variables were set
+}
+
+// parseEnvSettings reads SINK_* variables through getenv
+func parseEnvSettings(getenv func(string) string) (EnvSettings, error) {
+	settings := EnvSettings{
+		Platform:         getenv(EnvPlatform),
+		CacheDir:         getenv(EnvCacheDir),
+		StateDir:         getenv(EnvStateDir),
+		LogDir:           getenv(EnvLogDir),
+		CredentialHelper: getenv(EnvCredentialHelper),
+	}
+
+	var err error
+	if settings.Verbose, err = parseEnvBool(EnvVerbose, getenv(EnvVerbose)); err != nil {
+		return settings, err
+	}
+	if settings.JSON, err = parseEnvBool(EnvJSON, getenv(EnvJSON)); err != nil {
+		return settings, err
+	}
+	if settings.AuditLog, err = parseEnvBool(EnvAuditLog, getenv(EnvAuditLog)); err != nil {
+		return settings, err
+	}
+
+	if value := getenv(EnvHTTPTimeout); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			// Bare numbers are seconds
+			seconds, convErr := strconv.Atoi(value)
+			if convErr != nil {
+				return settings, fmt.Errorf("%s: invalid duration '%s' (e.g., 45s, 2m or 45)", EnvHTTPTimeout, value)
+			}
+			d = time.Duration(seconds) * time.Second
+		}
+		if d <= 0 {
+			return settings, fmt.Errorf("%s must be positive, got '%s'", EnvHTTPTimeout, value)
+		}
+		settings.HTTPTimeout = d
+	}
+
+	if settings.Host, err = parseEnvHost(getenv); err != nil {
+		return settings, err
+	}
+
+	return settings, nil
+}
+
+// parseEnvHost reads the SINK_HOST* variables set by sink remote deploy
+func parseEnvHost(getenv func(string) string) (HostInfo, error) {
+	name, index, count := getenv(EnvHost), getenv(EnvHostIndex), getenv(EnvHostCount)
+	if name == "" && index == "" && count == "" {
+		return HostInfo{}, nil
+	}
+	if name == "" {
+		return HostInfo{}, fmt.Errorf("%s and %s require %s", EnvHostIndex, EnvHostCount, EnvHost)
+	}
+
+	host := HostInfo{Name: name, Count: 1}
+	if count != "" {
+		n, err := strconv.Atoi(count)
+		if err != nil || n < 1 {
+			return HostInfo{}, fmt.Errorf("%s: invalid host count '%s'", EnvHostCount, count)
+		}
+		host.Count = n
+	}
+	if index != "" {
+		n, err := strconv.Atoi(index)
+		if err != nil || n < 0 || n >= host.Count {
+			return HostInfo{}, fmt.Errorf("%s: invalid host index '%s' for %d hosts", EnvHostIndex, index, host.Count)
+		}
+		host.Index = n
+	}
+	return host, nil
+}
+
+// parseEnvBool parses a boolean environment variable ("" is false)
+func parseEnvBool(name string, value string) (bool, error) {
+	switch value {
+	case "":
+		return false, nil
+	case "yes", "on":
+		return true, nil
+	case "no", "off":
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%s: invalid boolean '%s' (use 1/true/yes or 0/false/no)", name, value)
+	}
+	return b, nil
+}
+
+// loadEnvSettings reads SINK_* variables from the process environment,
+// exiting with an error when one is malformed
+func loadEnvSettings() EnvSettings {
+	settings, err := parseEnvSettings(os.Getenv)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	return settings
+}
+
+// httpTimeout returns SINK_HTTP_TIMEOUT when set, otherwise the given default
+func httpTimeout(defaultTimeout time.Duration) time.Duration {
+	if settings, err := parseEnvSettings(os.Getenv); err == nil && settings.HTTPTimeout > 0 {
+		return settings.HTTPTimeout
+	}
+	return defaultTimeout
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestParseEnvSettings tests reading SINK_* environment variables
+func TestParseEnvSettings(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    EnvSettings
+		wantErr string
+	}{
+		{"empty", map[string]string{}, EnvSettings{}, ""},
+		{
+			"all set",
+			map[string]string{
+				EnvPlatform:    "linux",
+				EnvVerbose:     "1",
+				EnvJSON:        "yes",
+				EnvCacheDir:    "/var/cache/sink",
+				EnvHTTPTimeout: "2m",
+			},
+			EnvSettings{Platform: "linux", Verbose: true, JSON: true, CacheDir: "/var/cache/sink", HTTPTimeout: 2 * time.Minute},
+			"",
+		},
+		{"false values", map[string]string{EnvVerbose: "false", EnvJSON: "off"}, EnvSettings{}, ""},
+		{"audit log", map[string]string{EnvAuditLog: "true"}, EnvSettings{AuditLog: true}, ""},
+		{"timeout in seconds", map[string]string{EnvHTTPTimeout: "45"}, EnvSettings{HTTPTimeout: 45 * time.Second}, ""},
+		{"bad boolean", map[string]string{EnvVerbose: "maybe"}, EnvSettings{}, "SINK_VERBOSE: invalid boolean"},
+		{"bad timeout", map[string]string{EnvHTTPTimeout: "soon"}, EnvSettings{}, "SINK_HTTP_TIMEOUT: invalid duration"},
+		{"zero timeout", map[string]string{EnvHTTPTimeout: "0s"}, EnvSettings{}, "must be positive"},
+		{
+			"host",
+			map[string]string{EnvHost: "web02.prod", EnvHostIndex: "1", EnvHostCount: "20"},
+			EnvSettings{Host: HostInfo{Name: "web02.prod", Index: 1, Count: 20}},
+			"",
+		},
+		{"host alone", map[string]string{EnvHost: "web"}, EnvSettings{Host: HostInfo{Name: "web", Count: 1}}, ""},
+		{"host index out of range", map[string]string{EnvHost: "web", EnvHostIndex: "3", EnvHostCount: "3"}, EnvSettings{}, "SINK_HOST_INDEX: invalid host index"},
+		{"host count without host", map[string]string{EnvHostCount: "3"}, EnvSettings{}, "require SINK_HOST"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseEnvSettings(func(name string) string { return tt.env[name] })
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("parseEnvSettings = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestHTTPTimeoutEnv tests that SINK_HTTP_TIMEOUT overrides the built-in timeouts
+func TestHTTPTimeoutEnv(t *testing.T) {
+	t.Setenv(EnvHTTPTimeout, "")
+	if got := httpTimeout(DefaultHTTPTimeout); got != DefaultHTTPTimeout {
+		t.Errorf("Expected default timeout, got %s", got)
+	}
+
+	t.Setenv(EnvHTTPTimeout, "90s")
+	if got := httpTimeout(DefaultHTTPTimeout); got != 90*time.Second {
+		t.Errorf("Expected 90s, got %s", got)
+	}
+}
+
+// TestCacheDirEnv tests SINK_CACHE_DIR and the default cache location
+func TestCacheDirEnv(t *testing.T) {
+	t.Setenv(EnvCacheDir, "/srv/sink-cache")
+	if dir, err := cacheDir(); err != nil || dir != "/srv/sink-cache" {
+		t.Errorf("cacheDir() = %q, %v", dir, err)
+	}
+
+	t.Setenv(EnvCacheDir, "")
+	t.Setenv("XDG_CACHE_HOME", "/home/test/.cache")
+	t.Setenv("HOME", "/home/test")
+	dir, err := cacheDir()
+	if err != nil {
+		t.Fatalf("cacheDir() failed: %v", err)
+	}
+	if filepath.Base(dir) != "sink" {
+		t.Errorf("Expected a sink directory under the user cache dir, got %q", dir)
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Event buffer defaults and overflow policies (--event-buffer, --event-overflow)
+const (
+	DefaultEventBuffer  = 256
+	EventOverflowBlock  = "block" // A full buffer makes the run wait for the consumer
+	EventOverflowDrop   = "drop"  // A full buffer drops new events and counts them
+	webhookMaxAttempts  = MaxHTTPRetries + 1
+	webhookInitialDelay = 500 * time.Millisecond
+)
+
+// EventPipeline hands events to a slow consumer, such as a webhook or an
+// OnEvent handler, from a goroutine of its own, so the run only waits for it
+// when the buffer is full and the policy is to block. Events are delivered
+// in order.
+type EventPipeline struct {
+	Name    string // Named in warnings, e.g. the webhook URL
+	events  chan ExecutionEvent
+	policy  string
+	deliver func(ExecutionEvent) error
+	done    chan struct{}
+
+	mu      sync.Mutex // Guards closed and dropped; Send holds it while it waits
+	closed  bool
+	dropped int
+
+	failed  int // Written by run only, read after done is closed
+	lastErr error
+}
+
+// NewEventPipeline starts delivering events to deliver through a buffer of
+// capacity events. A delivery error counts the event as failed; it does
+// not stop the pipeline.
+func NewEventPipeline(name string, capacity int, policy string, deliver func(ExecutionEvent) error) *EventPipeline {
+	p := &EventPipeline{
+		Name:    name,
+		events:  make(chan ExecutionEvent, capacity),
+		policy:  policy,
+		deliver: deliver,
+		done:    make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+// run delivers buffered events until the pipeline is closed and drained
+func (p *EventPipeline) run() {
+	defer close(p.done)
+	for event := range p.events {
+		if err := p.deliver(event); err != nil {
+			p.failed++
+			p.lastErr = err
+		}
+	}
+}
+
+// Send queues an event, waiting for room or dropping it when the buffer is
+// full, depending on the policy
+func (p *EventPipeline) Send(event ExecutionEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return
+	}
+	if p.policy != EventOverflowDrop {
+		p.events <- event
+		return
+	}
+	select {
+	case p.events <- event:
+	default:
+		p.dropped++
+	}
+}
+
+// Close waits until the buffered events are delivered and returns warnings
+// about events that were dropped or could not be delivered
+func (p *EventPipeline) Close() []Warning {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	close(p.events)
+	p.mu.Unlock()
+	<-p.done
+
+	var warnings []Warning
+	if p.dropped > 0 {
+		warnings = append(warnings, Warning{Code: WarningEventsDropped, Message: fmt.Sprintf("%d events to %s were dropped because its buffer was full (raise --event-buffer or use --event-overflow block)", p.dropped, p.Name)})
+	}
+	if p.failed > 0 {
+		warnings = append(warnings, Warning{Code: WarningEventsDropped, Message: fmt.Sprintf("%d events could not be delivered to %s: %v", p.failed, p.Name, p.lastErr)})
+	}
+	return warnings
+}
+
+// parseEventBuffer parses an --event-buffer value
+func parseEventBuffer(value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid --event-buffer '%s' (use a positive number of events)", value)
+	}
+	return n, nil
+}
+
+// parseEventOverflow validates an --event-overflow value
+func parseEventOverflow(value string) (string, error) {
+	switch value {
+	case EventOverflowBlock, EventOverflowDrop:
+		return value, nil
+	}
+	return "", fmt.Errorf("invalid --event-overflow '%s' (use block or drop)", value)
+}
+
+// webhookSink posts each event as JSON to a URL, retrying with backoff
+// when the request fails or the server answers 429 or 5xx. Credentials
+// for the URL come from the credential helpers, as for config downloads.
+type webhookSink struct {
+	url    string
+	client *http.Client
+	delay  time.Duration // Wait before the first retry, doubled for each further one
+}
+
+// newWebhookSink returns a sink posting events to url
+func newWebhookSink(url string) *webhookSink {
+	return &webhookSink{
+		url:    url,
+		client: &http.Client{Timeout: httpTimeout(DefaultHTTPTimeout)},
+		delay:  webhookInitialDelay,
+	}
+}
+
+// Deliver posts one event, retrying transient failures
+func (s *webhookSink) Deliver(event ExecutionEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	delay := s.delay
+	for attempt := 1; ; attempt++ {
+		retry, err := s.post(body)
+		if err == nil || !retry || attempt == webhookMaxAttempts {
+			return err
+		}
+		time.Sleep(delay)
+		delay *= RetryBackoffMultiplier
+	}
+}
+
+// post sends one request and reports whether a failure is worth retrying
+func (s *webhookSink) post(body []byte) (retry bool, err error) {
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	cred, err := lookupCredential(s.url)
+	if err != nil {
+		return false, err
+	}
+	cred.apply(req)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("HTTP %d from %s", resp.StatusCode, s.url)
+}
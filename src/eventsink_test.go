@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestEventPipeline_Order tests that buffered events arrive in order and
+// Close waits for them
+func TestEventPipeline_Order(t *testing.T) {
+	var got []string
+	pipeline := NewEventPipeline("test", 2, EventOverflowBlock, func(event ExecutionEvent) error {
+		time.Sleep(time.Millisecond)
+		got = append(got, event.StepName)
+		return nil
+	})
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		pipeline.Send(ExecutionEvent{StepName: name})
+	}
+	if warnings := pipeline.Close(); len(warnings) != 0 {
+		t.Errorf("unexpected warnings %v", warnings)
+	}
+	if strings.Join(got, "") != "abcde" {
+		t.Errorf("expected all events in order, got %v", got)
+	}
+	pipeline.Send(ExecutionEvent{StepName: "late"}) // Ignored after Close
+}
+
+// TestEventPipeline_Drop tests that a full buffer drops events without
+// holding up the run under the drop policy
+func TestEventPipeline_Drop(t *testing.T) {
+	release := make(chan struct{})
+	var delivered int32
+	pipeline := NewEventPipeline("slow", 1, EventOverflowDrop, func(event ExecutionEvent) error {
+		<-release
+		atomic.AddInt32(&delivered, 1)
+		return nil
+	})
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		pipeline.Send(ExecutionEvent{StepName: "step"})
+	}
+	if time.Since(start) > time.Second {
+		t.Error("Send blocked under the drop policy")
+	}
+	close(release)
+	warnings := pipeline.Close()
+	if len(warnings) != 1 || warnings[0].Code != WarningEventsDropped || !strings.Contains(warnings[0].Message, "were dropped") {
+		t.Errorf("expected a dropped events warning, got %v", warnings)
+	}
+	if n := atomic.LoadInt32(&delivered); n < 1 || n > 2 {
+		t.Errorf("expected the buffered events to be delivered, got %d", n)
+	}
+}
+
+// TestWebhookSink tests posting events and retrying transient failures
+func TestWebhookSink(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var event ExecutionEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil || event.StepName != "Install" {
+			t.Errorf("unexpected body: %+v, %v", event, err)
+		}
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected content type %q", r.Header.Get("Content-Type"))
+		}
+	}))
+	defer server.Close()
+
+	sink := newWebhookSink(server.URL)
+	sink.delay = time.Millisecond
+	if err := sink.Deliver(ExecutionEvent{StepName: "Install", Status: "success"}); err != nil {
+		t.Fatalf("expected delivery after retries, got %v", err)
+	}
+	if atomic.LoadInt32(&requests) != 3 {
+		t.Errorf("expected 3 requests, got %d", requests)
+	}
+
+	// Client errors are not retried
+	rejecting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer rejecting.Close()
+	atomic.StoreInt32(&requests, 0)
+	sink = newWebhookSink(rejecting.URL)
+	sink.delay = time.Millisecond
+	if err := sink.Deliver(ExecutionEvent{StepName: "Install"}); err == nil || !strings.Contains(err.Error(), "HTTP 400") {
+		t.Errorf("expected HTTP 400 error, got %v", err)
+	}
+	if atomic.LoadInt32(&requests) != 1 {
+		t.Errorf("expected no retries of a 400, got %d requests", requests)
+	}
+}
+
+// TestExecutorSinks tests that executor events reach its sinks
+func TestExecutorSinks(t *testing.T) {
+	executor := NewExecutor(&MockTransport{responses: map[string]MockResponse{"true": {}}})
+	var statuses []string
+	executor.Sinks = []*EventPipeline{NewEventPipeline("test", 4, EventOverflowBlock, func(event ExecutionEvent) error {
+		statuses = append(statuses, event.Status)
+		return nil
+	})}
+	executor.ExecutePlatform(Platform{InstallSteps: []InstallStep{{Name: "ok", Step: CommandStep{Command: "true"}}}}, nil)
+	if warnings := executor.CloseSinks(); len(warnings) != 0 {
+		t.Errorf("unexpected warnings %v", warnings)
+	}
+	if strings.Join(statuses, ",") != "running,success" {
+		t.Errorf("unexpected events %v", statuses)
+	}
+}
@@ -1,14 +1,16 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"regexp"
 	"time"
 )
 
-// verboseLog prints verbose execution details to stderr
+// verboseLog prints verbose execution details to stderr, hiding secret fact values
 func verboseLog(format string, args ...interface{}) {
-	fmt.Fprintf(os.Stderr, "[VERBOSE] "+format+"\n", args...)
+	fmt.Fprintf(os.Stderr, "[VERBOSE] %s\n", activeRedactor.Redact(fmt.Sprintf(format, args...)))
 }
 
 // applySleep applies a sleep duration if specified
@@ -51,5 +53,52 @@ func parseTimeoutConfig(timeoutRaw []byte) (time.Duration, *int, error) {
 		return 0, nil, fmt.Errorf("invalid timeout interval '%s': %w", interval, err)
 	}
 
+	if _, err := parsePollInterval(timeoutRaw); err != nil {
+		return 0, nil, err
+	}
+
 	return duration, errorCode, nil
 }
+
+// defaultPollInterval is how long retry loops wait between attempts unless
+// the timeout sets poll_interval
+const defaultPollInterval = 1 * time.Second
+
+// parsePollInterval returns the poll_interval of a timeout object, or the
+// default for a timeout string or an object without one
+func parsePollInterval(timeoutRaw []byte) (time.Duration, error) {
+	var cfg TimeoutConfig
+	if len(timeoutRaw) == 0 || json.Unmarshal(timeoutRaw, &cfg) != nil || cfg.PollInterval == "" {
+		return defaultPollInterval, nil
+	}
+	interval, err := time.ParseDuration(cfg.PollInterval)
+	if err != nil {
+		return 0, fmt.Errorf("invalid poll_interval '%s': %w", cfg.PollInterval, err)
+	}
+	if interval <= 0 {
+		return 0, fmt.Errorf("poll_interval must be positive")
+	}
+	return interval, nil
+}
+
+// retryable reports whether a failed attempt should be retried. Without
+// retry_on every failure is retried; with it only failures whose exit code is
+// listed or whose stdout or stderr matches output_matches are.
+func (r *RetryOn) retryable(stdout, stderr string, exitCode int) bool {
+	if r == nil {
+		return true
+	}
+	for _, code := range r.ExitCodes {
+		if code == exitCode {
+			return true
+		}
+	}
+	if r.OutputMatches == "" {
+		return false
+	}
+	re, err := regexp.Compile(r.OutputMatches)
+	if err != nil {
+		return false
+	}
+	return re.MatchString(stdout) || re.MatchString(stderr)
+}
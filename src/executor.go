@@ -2,34 +2,97 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"sort"
 	"strings"
+	"sync"
 	"text/template"
 	"time"
 )
 
 // Executor executes installation steps
 type Executor struct {
-	transport  Transport
-	DryRun     bool
-	Verbose    bool // Global verbose flag for debugging
-	JSONOutput bool // Output events as JSON to stdout
-	OnEvent    func(ExecutionEvent)
-	runID      string
-	context    ExecutionContext // Execution context (where commands run)
+	transport     Transport
+	DryRun        bool
+	Verbose       bool             // Global verbose flag for debugging
+	JSONOutput    bool             // Output events as JSON to stdout
+	Checksums     ChecksumManifest // Expected SHA256s available to templates via {{checksum "file"}}
+	Deadline      time.Time        // Overall run deadline from --max-duration (zero means none)
+	Context       context.Context  // The run's context (see beginRun); cancelling it stops running commands and waits (nil means none)
+	Window        string           // Config-level maintenance window (empty means always open)
+	Transcript    *Transcript      // Records steps and commands for --transcript (nil disables)
+	State         *RunState        // Records step results for --state-file and skips the steps --resume found completed (nil disables)
+	Audit         AuditLogger      // Logs every executed command to the system log for --audit-log (nil disables)
+	Workspace     string           // Run workspace behind {{.sink.step_dir}} and friends
+	Bundle        *Bundle          // Offline bundle being run (--bundle); refuses network access
+	Gatherer      *FactGatherer    // Re-gathers facts named in a step's refresh_facts and names exported ones (nil disables both)
+	ExportFacts   bool             // Pass facts with an "export" name to commands as environment variables (default true)
+	InstallScope  string           // Config install_scope behind {{.sink.bin_dir}} and friends
+	RateLimiter   *RateLimiter     // Throttles steps with a rate_limit (nil disables)
+	Debugger      *Debugger        // Pauses before --break-at steps (nil disables)
+	Operator      *Operator        // Confirms pause step prompts (nil makes them fail)
+	Host          HostInfo         // This host's place in a multi-host deploy ({{.sink.host}} and friends)
+	OnEvent       func(ExecutionEvent)
+	Sinks         []*EventPipeline // Buffered event consumers, such as --event-webhook
+	Stdout        io.Writer        // Where JSON events are written (nil means os.Stdout)
+	runID         string
+	context       ExecutionContext // Execution context (where commands run)
+	now           func() time.Time // Clock used for maintenance windows
+	stepSeq       int              // Steps started so far (numbers step directories)
+	stepDir       string           // Current step's directory inside Workspace
+	createdDirs   map[string]bool  // Step directories created on the target
+	started       time.Time        // Executor creation, with a monotonic clock reading
+	seq           int64            // Events emitted so far
+	emitMu        sync.Mutex       // Keeps seq, timestamps and output in one order when events come from several goroutines
+	stepNumber    int              // Running step's position in the order steps started, from 1 (0 between steps)
+	parentStep    string           // Group whose members are running, reported as their ParentStep
+	currentStep   string           // Step whose commands are running, named in audit records
+	auditFailed   bool             // An audit write failed and was reported
+	stateFailed   bool             // A state file write failed and was reported
+	stopReason    string           // Set by Stop: the skip reason of the steps not started
+	failurePolicy string           // The running platform's failure_policy
+	scratch       Scratch          // Values steps stored with "scratch" ({{.sink.scratch.*}})
+
+	remediationOf    string // Step whose remediation is running, reported as its retry events' ParentStep
+	remediationIndex int    // That remediation's position in on_missing, from 1
+}
+
+// SetRunID replaces the generated run ID (--run-id), moving the run
+// workspace with it
+func (e *Executor) SetRunID(runID string) {
+	e.runID = runID
+	e.Workspace = defaultWorkspace(runID)
+}
+
+// SetVariables records the run's resolved variables in the execution context
+func (e *Executor) SetVariables(values map[string]interface{}) {
+	if len(values) > 0 {
+		e.context.Variables = values
+	}
 }
 
 // NewExecutor creates a new executor
 func NewExecutor(transport Transport) *Executor {
 	executor := &Executor{
-		transport: transport,
-		runID:     generateRunID(),
+		transport:   transport,
+		runID:       generateRunID(),
+		now:         time.Now,
+		started:     time.Now(),
+		ExportFacts: true,
 	}
+	executor.Workspace = defaultWorkspace(executor.runID)
 
 	// Discover execution context immediately
 	executor.context = executor.discoverContext()
+	executor.Host = HostInfo{Name: executor.context.Host, Count: 1}
 
 	return executor
 }
@@ -41,10 +104,35 @@ func (e *Executor) discoverContext() ExecutionContext {
 	}
 
 	ctx := ExecutionContext{
-		Timestamp: time.Now().Format(time.RFC3339),
+		Timestamp: eventTimestamp(time.Now()),
 		Transport: "unknown",
 	}
 
+	// The local host is read directly rather than by running hostname,
+	// whoami, pwd, uname and date, which saves startup time and does not
+	// depend on sh; remote transports are probed with commands
+	if lt, ok := e.transport.(*LocalTransport); ok {
+		ctx.Transport = "local"
+		discoverLocalContext(&ctx, lt)
+	} else {
+		if _, ok := e.transport.(*SSHTransport); ok {
+			ctx.Transport = "ssh"
+		}
+		e.probeContext(&ctx)
+	}
+	ctx.Shell = transportCapabilities(e.transport).Shell
+	e.discoverPreflight(&ctx)
+
+	if e.Verbose {
+		verboseLog("Context discovered: Host=%s, User=%s, OS=%s, Arch=%s", ctx.Host, ctx.User, ctx.OS, ctx.Arch)
+	}
+
+	return ctx
+}
+
+// probeContext fills in the host, user, working directory, OS, architecture
+// and timezone by running commands on the transport
+func (e *Executor) probeContext(ctx *ExecutionContext) {
 	// Discover hostname
 	stdout, _, exitCode, _ := e.transport.Run("hostname")
 	if exitCode == 0 {
@@ -75,17 +163,110 @@ func (e *Executor) discoverContext() ExecutionContext {
 		ctx.Arch = strings.TrimSpace(stdout)
 	}
 
-	// Determine transport type
-	if _, ok := e.transport.(*LocalTransport); ok {
-		ctx.Transport = "local"
+	// Discover timezone ("CET +0100"), so local times in command output can
+	// be related to the UTC event timestamps
+	stdout, _, exitCode, _ = e.transport.Run("date '+%Z %z'")
+	if exitCode == 0 {
+		ctx.Timezone, ctx.UTCOffset = parseTimezone(stdout)
 	}
-	// SSH transport detection will be added when SSH is implemented
+}
 
-	if e.Verbose {
-		verboseLog("Context discovered: Host=%s, User=%s, OS=%s, Arch=%s", ctx.Host, ctx.User, ctx.OS, ctx.Arch)
+// discoverLocalContext fills in the same fields as probeContext for the
+// machine sink runs on, using the values uname and date would report
+func discoverLocalContext(ctx *ExecutionContext, lt *LocalTransport) {
+	if host, err := os.Hostname(); err == nil {
+		ctx.Host = host
+	}
+	if u, err := user.Current(); err == nil {
+		ctx.User = u.Username
+	} else {
+		ctx.User = os.Getenv("USER")
+	}
+	if lt.WorkDir != "" {
+		ctx.WorkDir = lt.WorkDir
+	} else if wd, err := os.Getwd(); err == nil {
+		ctx.WorkDir = wd
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		ctx.Home = home
+	}
+	ctx.OS = unameOS(runtime.GOOS)
+	ctx.Arch = unameArch(runtime.GOOS, runtime.GOARCH)
+	ctx.CPUCount = runtime.NumCPU()
+	ctx.Timezone, ctx.UTCOffset = localTimezone(time.Now())
+}
+
+// unameOS returns the `uname -s` name of a Go operating system
+func unameOS(goos string) string {
+	switch goos {
+	case "linux":
+		return "Linux"
+	case "darwin":
+		return "Darwin"
+	case "windows":
+		return "Windows_NT"
+	case "freebsd":
+		return "FreeBSD"
+	case "openbsd":
+		return "OpenBSD"
+	case "netbsd":
+		return "NetBSD"
+	case "dragonfly":
+		return "DragonFly"
+	case "solaris", "illumos":
+		return "SunOS"
+	case "aix":
+		return "AIX"
+	}
+	return goos
+}
+
+// goosFromUname returns the Go operating system, as used by platform "os",
+// for a `uname -s` name; the reverse of unameOS
+func goosFromUname(name string) string {
+	switch {
+	case name == "Windows_NT", strings.HasPrefix(name, "MINGW"), strings.HasPrefix(name, "MSYS"), strings.HasPrefix(name, "CYGWIN"):
+		return "windows"
+	case name == "SunOS":
+		return "solaris"
 	}
+	return strings.ToLower(name)
+}
 
-	return ctx
+// unameArch returns the `uname -m` name of a Go architecture. macOS and the
+// BSDs keep the Go-style names for 64-bit x86 and ARM.
+func unameArch(goos, goarch string) string {
+	switch goarch {
+	case "amd64":
+		if goos == "freebsd" || goos == "openbsd" || goos == "netbsd" || goos == "dragonfly" {
+			return "amd64"
+		}
+		return "x86_64"
+	case "arm64":
+		if goos == "linux" {
+			return "aarch64"
+		}
+		return "arm64"
+	case "386":
+		if goos == "freebsd" || goos == "openbsd" || goos == "netbsd" {
+			return "i386"
+		}
+		return "i686"
+	case "arm":
+		return "armv7l"
+	}
+	return goarch
+}
+
+// localTimezone returns the zone abbreviation and UTC offset of t, as
+// parseTimezone does for `date '+%Z %z'`
+func localTimezone(t time.Time) (zone string, offset string) {
+	zone, seconds := t.Zone()
+	sign := '+'
+	if seconds < 0 {
+		sign, seconds = '-', -seconds
+	}
+	return zone, fmt.Sprintf("%c%02d:%02d", sign, seconds/3600, seconds%3600/60)
 }
 
 // GetContext returns the execution context
@@ -95,36 +276,95 @@ func (e *Executor) GetContext() ExecutionContext {
 
 // ExecuteStep executes a single installation step
 func (e *Executor) ExecuteStep(step InstallStep, facts Facts) StepResult {
+	result, _ := e.executeStep(step, facts)
+	return result
+}
+
+// executeStep executes a step and returns the facts later steps should see,
+// re-gathered when the step succeeded and declares refresh_facts
+func (e *Executor) executeStep(step InstallStep, facts Facts) (StepResult, Facts) {
+	gathered := facts
+	defer func(previous string) { e.currentStep = previous }(e.currentStep)
+	defer func(previous int) { e.stepNumber = previous }(e.stepNumber)
+	e.currentStep = step.Name
 	if e.Verbose {
 		verboseLog("Executing step: %s", step.Name)
 		e.logStepMetadata(step)
 	}
+	facts = e.stepFacts(step, facts)
+	e.stepNumber = e.stepSeq
 
 	event := ExecutionEvent{
-		Timestamp: time.Now().Format(time.RFC3339),
-		RunID:     e.runID,
-		StepName:  step.Name,
-		Status:    "running",
+		RunID:    e.runID,
+		StepName: step.Name,
+		Status:   "running",
+		Impact:   step.Impact,
+		Risk:     step.Risk,
+
+		Annotations: step.Annotations,
 	}
 	e.populateVerboseMetadata(&event, step)
 	e.emitEvent(event)
 
+	// A resumed run does not repeat the steps an earlier attempt completed
+	if e.parentStep == "" && e.State.completed(step) {
+		reason := describeSkipReason(SkipReasonResumed)
+		skippedEvent := ExecutionEvent{
+			RunID:       e.runID,
+			StepName:    step.Name,
+			Status:      "skipped",
+			Output:      reason,
+			SkipReason:  SkipReasonResumed,
+			Annotations: step.Annotations,
+		}
+		e.populateVerboseMetadata(&skippedEvent, step)
+		e.emitEvent(skippedEvent)
+		return StepResult{
+			StepName:   step.Name,
+			Status:     "skipped",
+			Output:     reason,
+			SkipReason: SkipReasonResumed,
+		}, gathered
+	}
+
+	// Defer steps outside their maintenance window
+	if reason := e.outsideWindow(step.Window); reason != "" {
+		deferredEvent := ExecutionEvent{
+			RunID:       e.runID,
+			StepName:    step.Name,
+			Status:      "deferred",
+			Output:      reason,
+			SkipReason:  SkipReasonWindow,
+			Annotations: step.Annotations,
+		}
+		e.populateVerboseMetadata(&deferredEvent, step)
+		e.emitEvent(deferredEvent)
+		return StepResult{
+			StepName:   step.Name,
+			Status:     "deferred",
+			Output:     reason,
+			SkipReason: SkipReasonWindow,
+		}, gathered
+	}
+
 	// Handle dry-run mode
 	if e.DryRun {
 		skippedEvent := ExecutionEvent{
-			Timestamp: time.Now().Format(time.RFC3339),
-			RunID:     e.runID,
-			StepName:  step.Name,
-			Status:    "skipped",
-			Output:    "(dry-run mode)",
+			RunID:       e.runID,
+			StepName:    step.Name,
+			Status:      "skipped",
+			Output:      "(dry-run mode)",
+			SkipReason:  SkipReasonDryRun,
+			Annotations: step.Annotations,
 		}
 		e.populateVerboseMetadata(&skippedEvent, step)
 		e.emitEvent(skippedEvent)
 		return StepResult{
-			StepName: step.Name,
-			Status:   "skipped",
-			Output:   "(dry-run mode)",
-		}
+			StepName:   step.Name,
+			Status:     "skipped",
+			Output:     "(dry-run mode)",
+			SkipReason: SkipReasonDryRun,
+		}, gathered
 	}
 
 	// Execute based on step variant
@@ -132,10 +372,25 @@ func (e *Executor) ExecuteStep(step InstallStep, facts Facts) StepResult {
 	switch v := step.Step.(type) {
 	case CommandStep:
 		result = e.executeCommand(step.Name, v, facts)
+		if result.Error == "" && v.Verify != "" {
+			result = e.verifyCommand(v, result, facts)
+		}
 	case CheckErrorStep:
 		result = e.executeCheckError(step.Name, v, facts)
 	case CheckRemediateStep:
 		result = e.executeCheckRemediate(step.Name, v, facts)
+	case CopyStep:
+		result = e.executeCopy(step.Name, v, facts)
+	case FetchStep:
+		result = e.executeFetch(step.Name, v, facts)
+	case VerifyChecksumStep:
+		result = e.executeVerifyChecksum(step.Name, v, facts)
+	case PauseStep:
+		result = e.executePause(step.Name, v, facts)
+	case GroupStep:
+		var after Facts
+		result, after = e.executeGroup(step.Name, v, facts)
+		gathered = e.withGroupRefreshes(gathered, after)
 	case ErrorOnlyStep:
 		result = e.executeErrorOnly(step.Name, v)
 	default:
@@ -146,45 +401,366 @@ func (e *Executor) ExecuteStep(step InstallStep, facts Facts) StepResult {
 		}
 	}
 
+	// A failed "recheck" check waits for later remediations
+	if isPendingCheck(step, result) {
+		result.Status = "pending"
+	}
+
+	// Facts the step changed are re-gathered before it is reported
+	if result.Error == "" && len(step.RefreshFacts) > 0 {
+		refreshed, err := e.refreshFacts(step, gathered)
+		if err != nil {
+			result.Error = fmt.Sprintf("refresh_facts: %v", err)
+		} else {
+			gathered = refreshed
+		}
+	}
+
+	// Later steps see what the step stored in the scratch space
+	if result.Error == "" && result.Status != "pending" && len(step.Scratch) > 0 {
+		if err := e.writeScratch(step, facts, result); err != nil {
+			result.Status = "failed"
+			result.Error = err.Error()
+		}
+	}
+
+	// A top-level step's undo command is kept in case a later step fails
+	if result.Error == "" && result.Status != "pending" && step.Undo != "" && e.parentStep == "" {
+		undo, err := e.undoCommand(step, facts)
+		if err != nil {
+			result.Status = "failed"
+			result.Error = err.Error()
+		}
+		result.undo = undo
+	}
+
 	// Emit completion event
 	status := "success"
 	if result.Error != "" {
 		status = "failed"
 	}
+	if result.Status == "pending" {
+		status = "pending"
+	}
 	completionEvent := ExecutionEvent{
-		Timestamp: time.Now().Format(time.RFC3339),
-		RunID:     e.runID,
-		StepName:  step.Name,
-		Status:    status,
-		Output:    result.Output,
-		Error:     result.Error,
+		RunID:    e.runID,
+		StepName: step.Name,
+		Status:   status,
+		Output:   result.Output,
+		Error:    result.Error,
+		Stdout:   result.Stdout,
+		Stderr:   result.Stderr,
+
+		OutputMismatch: result.OutputMismatch,
+		Annotations:    step.Annotations,
 	}
 	if result.ExitCode != 0 {
 		completionEvent.ExitCode = &result.ExitCode
 	}
+	if status == "failed" && e.parentStep == "" {
+		if policy := e.stepFailurePolicy(step); policy != FailurePolicyAbort {
+			completionEvent.FailurePolicy = policy
+		}
+	}
 	e.populateVerboseMetadata(&completionEvent, step)
 	e.emitEvent(completionEvent)
 
-	return result
+	return result, gathered
+}
+
+// refreshFacts re-gathers the facts a step declared in refresh_facts
+func (e *Executor) refreshFacts(step InstallStep, facts Facts) (Facts, error) {
+	if e.Gatherer == nil {
+		return facts, nil
+	}
+	refreshed, err := e.Gatherer.Refresh(facts, step.RefreshFacts)
+	if err != nil {
+		return nil, err
+	}
+	e.exportFacts(refreshed)
+
+	if activeRedactor != nil {
+		activeRedactor = NewRedactor(e.Gatherer.definitions, refreshed)
+	}
+	if e.Verbose {
+		for _, name := range step.RefreshFacts {
+			value, ok := refreshed[name]
+			if !ok {
+				verboseLog("Refreshed fact '%s': not available", name)
+				continue
+			}
+			verboseLog("Refreshed fact '%s' = %s", name, displayFactValue(e.Gatherer.definitions[name], value))
+		}
+	}
+	return refreshed, nil
+}
+
+// exportFacts passes the facts with an "export" name to every command the
+// transport runs from now on, so steps can use $SINK_OS as well as
+// {{.os}}. Transports that cannot set the environment are left alone.
+func (e *Executor) exportFacts(facts Facts) {
+	et, ok := e.transport.(EnvTransport)
+	if !ok || !e.ExportFacts || e.Gatherer == nil {
+		return
+	}
+	exports := e.Gatherer.Export(facts)
+	sort.Strings(exports)
+	if e.Verbose && len(exports) > 0 {
+		names := make([]string, len(exports))
+		for i, exp := range exports {
+			names[i], _, _ = strings.Cut(exp, "=")
+		}
+		verboseLog("Exporting facts to the environment: %s", strings.Join(names, ", "))
+	}
+	et.SetEnv(exports)
 }
 
 // ExecutePlatform executes all steps for a platform
 func (e *Executor) ExecutePlatform(platform Platform, facts Facts) []StepResult {
 	results := []StepResult{}
+	defer e.cleanupWorkspace()
+	e.exportFacts(facts)
+	e.failurePolicy = platform.FailurePolicy
+	for name, value := range e.State.scratch() {
+		e.scratch.Set(name, value)
+	}
+
+	// Outside the config-level window nothing runs
+	if reason := e.outsideWindow(e.Window); reason != "" {
+		return e.markSteps(platform.InstallSteps, "deferred", reason, SkipReasonWindow)
+	}
+
+	var pending []pendingCheck
+	var undo []undoEntry
+	failed := false // The run failed in a way that rolls it back
+	for i, step := range platform.InstallSteps {
+		if e.stopReason != "" {
+			results = append(results, e.markSteps(platform.InstallSteps[i:], "not_run", describeSkipReason(e.stopReason), e.stopReason)...)
+			break
+		}
+		if e.DeadlineExceeded() {
+			results = append(results, e.markNotRun(platform.InstallSteps[i:])...)
+			failed = true
+			break
+		}
+		if e.Interrupted() {
+			results = append(results, e.markSteps(platform.InstallSteps[i:], "not_run", errRunInterrupted.Error(), SkipReasonInterrupt)...)
+			failed = true
+			break
+		}
 
-	for _, step := range platform.InstallSteps {
-		result := e.ExecuteStep(step, facts)
+		if !e.Debugger.pause(e, step, i, len(platform.InstallSteps), facts) {
+			results = append(results, e.markSteps(platform.InstallSteps[i:], "not_run", abortedAtBreakpoint, SkipReasonBreakpoint)...)
+			break
+		}
+
+		e.Transcript.beginStep(step)
+		started := time.Now()
+		result, refreshed := e.executeStep(step, facts)
+		facts = refreshed
+		result.Duration = time.Since(started)
+		e.Transcript.endStep(result, result.Duration)
 		results = append(results, result)
+		if !e.DryRun {
+			if err := e.State.record(step, result, e.scratch.Values()); err != nil && !e.stateFailed {
+				e.stateFailed = true
+				if e.JSONOutput {
+					runWarnings.add(Warning{Code: WarningStateFailed, Message: fmt.Sprintf("cannot write the state file: %v", err)})
+				} else {
+					warn(WarningStateFailed, "Cannot write the state file: %v", err)
+				}
+			}
+		}
 
-		// Stop on first error
+		if result.Status == "pending" {
+			pending = append(pending, pendingCheck{index: len(results) - 1, step: step, check: step.Step.(CheckErrorStep)})
+			continue
+		}
+		if result.undo != nil && stepChanged(step, result, e.DryRun) == "yes" {
+			result.undo.result = len(results) - 1
+			undo = append(undo, *result.undo)
+		}
+		if result.Error == "" && len(result.RemediationSteps) > 0 && len(pending) > 0 {
+			pending = e.recheckPending(pending, results, step.Name, facts)
+		}
+
+		// Stop on the first error unless the failure policy carries on
 		if result.Error != "" {
+			if e.DeadlineExceeded() {
+				results = append(results, e.markNotRun(platform.InstallSteps[i+1:])...)
+			} else if e.Interrupted() {
+				results = append(results, e.markSteps(platform.InstallSteps[i+1:], "not_run", errRunInterrupted.Error(), SkipReasonInterrupt)...)
+			} else if policy := e.stepFailurePolicy(step); policy != FailurePolicyAbort {
+				results[len(results)-1].Tolerated = policy == FailurePolicyContinue
+				continue
+			}
+			failed = true
 			break
 		}
 	}
+	e.failPending(pending, results)
+	for _, p := range pending {
+		if e.stepFailurePolicy(p.step) == FailurePolicyAbort {
+			failed = true
+		}
+	}
+	if failed {
+		e.rollback(undo, results)
+	}
+
+	return results
+}
+
+// Stop makes the run end after the running step; the steps after it are
+// reported as not run with the given skip reason
+func (e *Executor) Stop(skipReason string) {
+	e.stopReason = skipReason
+}
+
+// DeadlineExceeded reports whether the run deadline (--max-duration) has passed
+func (e *Executor) DeadlineExceeded() bool {
+	return !e.Deadline.IsZero() && !time.Now().Before(e.Deadline)
+}
+
+// Interrupted reports whether the run's context was cancelled by an
+// interrupt rather than by its deadline
+func (e *Executor) Interrupted() bool {
+	return e.Context != nil && errors.Is(e.Context.Err(), context.Canceled)
+}
+
+// sleep waits for d, returning early (and false) when the run's context is
+// done
+func (e *Executor) sleep(d time.Duration) bool {
+	if e.Context == nil {
+		time.Sleep(d)
+		return true
+	}
+	return sleepContext(e.Context, d)
+}
+
+// markNotRun records steps that never started because the run deadline passed
+func (e *Executor) markNotRun(steps []InstallStep) []StepResult {
+	return e.markSteps(steps, "not_run", errRunDeadlineExceeded.Error(), SkipReasonDeadline)
+}
+
+// markSteps records steps that were never started with the given status,
+// reason and skip reason
+func (e *Executor) markSteps(steps []InstallStep, status string, reason string, skipReason string) []StepResult {
+	results := make([]StepResult, 0, len(steps))
+	for _, step := range steps {
+		event := ExecutionEvent{
+			RunID:       e.runID,
+			StepName:    step.Name,
+			Status:      status,
+			Output:      reason,
+			SkipReason:  skipReason,
+			Annotations: step.Annotations,
+		}
+		e.populateVerboseMetadata(&event, step)
+		e.emitEvent(event)
+		e.Transcript.addStep(step, status, reason)
 
+		results = append(results, StepResult{
+			StepName:   step.Name,
+			Status:     status,
+			Output:     reason,
+			SkipReason: skipReason,
+		})
+	}
 	return results
 }
 
+// outsideWindow returns why execution must be deferred, or "" when the
+// window is open (or unset). Windows are validated at config load.
+func (e *Executor) outsideWindow(spec string) string {
+	if spec == "" {
+		return ""
+	}
+	window, err := ParseWindow(spec)
+	if err != nil || window.Contains(e.now()) {
+		return ""
+	}
+	return fmt.Sprintf("outside maintenance window '%s'", spec)
+}
+
+// errRunDeadlineExceeded is reported for commands cut short by --max-duration
+var errRunDeadlineExceeded = errors.New("run exceeded max duration")
+
+// run executes a command through the transport and records it in the transcript
+func (e *Executor) run(command string) (stdout, stderr string, exitCode int, err error) {
+	return e.runInput(command, "")
+}
+
+// runInput runs a command like run, feeding stdin to its standard input
+func (e *Executor) runInput(command, stdin string) (stdout, stderr string, exitCode int, err error) {
+	started := time.Now()
+	if e.Bundle != nil {
+		if reason := networkAccess(command); reason != "" {
+			err = fmt.Errorf("refused by offline bundle policy: %s", reason)
+			e.Transcript.recordCommand(command, "", err.Error(), 1, 0)
+			e.audit(command, 1, 0)
+			return "", "", 1, err
+		}
+		e.Bundle.noteCommand(command)
+	}
+	stdout, stderr, exitCode, err = e.runWithDeadline(command, stdin)
+	e.Transcript.recordCommand(command, stdout, stderr, exitCode, time.Since(started))
+	e.audit(command, exitCode, time.Since(started))
+	return stdout, stderr, exitCode, err
+}
+
+// runWithDeadline runs a command, cancelling it when the run deadline passes
+// or the run is interrupted (if the transport supports cancellation) and
+// feeding it stdin if not empty
+func (e *Executor) runWithDeadline(command, stdin string) (stdout, stderr string, exitCode int, err error) {
+	if e.Deadline.IsZero() && e.Context == nil && stdin == "" {
+		return e.transport.Run(command)
+	}
+	if e.DeadlineExceeded() {
+		return "", "", ExitDeadlineExceeded, errRunDeadlineExceeded
+	}
+	if e.Interrupted() {
+		return "", "", ExitInterrupted, errRunInterrupted
+	}
+
+	ctx := context.Background()
+	if e.Context != nil {
+		ctx = e.Context
+	}
+	if !e.Deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, e.Deadline)
+		defer cancel()
+	}
+
+	if stdin != "" {
+		it, ok := e.transport.(InputTransport)
+		if !ok {
+			return "", "", 1, fmt.Errorf("transport cannot send stdin to commands")
+		}
+		stdout, stderr, exitCode, err = it.RunInput(ctx, command, stdin)
+	} else if ct, ok := e.transport.(ContextTransport); ok {
+		stdout, stderr, exitCode, err = ct.RunContext(ctx, command)
+	} else {
+		return e.transport.Run(command)
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return stdout, stderr, ExitDeadlineExceeded, errRunDeadlineExceeded
+	}
+	if errors.Is(err, context.Canceled) {
+		return stdout, stderr, ExitInterrupted, errRunInterrupted
+	}
+	return stdout, stderr, exitCode, err
+}
+
+// retryDeadline caps a retry loop deadline at the run deadline
+func (e *Executor) retryDeadline(deadline time.Time) time.Time {
+	if !e.Deadline.IsZero() && e.Deadline.Before(deadline) {
+		return e.Deadline
+	}
+	return deadline
+}
+
 // executeCommand executes a CommandStep
 func (e *Executor) executeCommand(stepName string, cmd CommandStep, facts Facts) StepResult {
 	// Check if retry is enabled
@@ -201,6 +777,14 @@ func (e *Executor) executeCommand(stepName string, cmd CommandStep, facts Facts)
 			Error:    fmt.Sprintf("template error: %v", err),
 		}
 	}
+	stdin, err := e.commandInput(cmd, facts)
+	if err != nil {
+		return StepResult{
+			StepName: stepName,
+			Status:   "failed",
+			Error:    err.Error(),
+		}
+	}
 
 	// Log command execution in verbose mode (use global verbose or step-specific)
 	verbose := e.Verbose || cmd.Verbose
@@ -208,8 +792,17 @@ func (e *Executor) executeCommand(stepName string, cmd CommandStep, facts Facts)
 		verboseLog("Executing command: %s", command)
 	}
 
+	// Wait for the step's rate limit, if any
+	if err := e.throttle(cmd.RateLimit, stepName, verbose); err != nil {
+		return StepResult{
+			StepName: stepName,
+			Status:   "failed",
+			Error:    fmt.Sprintf("rate limit: %v", err),
+		}
+	}
+
 	// Execute command
-	stdout, stderr, exitCode, err := e.transport.Run(command)
+	stdout, stderr, exitCode, err := e.runInput(command, stdin)
 
 	if verbose {
 		verboseLog("Command exit code: %d", exitCode)
@@ -230,7 +823,7 @@ func (e *Executor) executeCommand(stepName string, cmd CommandStep, facts Facts)
 		}
 	}
 
-	if err != nil || exitCode != 0 {
+	if err != nil || !cmd.succeeded(exitCode) {
 		errorMsg := fmt.Sprintf("command failed (exit %d)", exitCode)
 		if err != nil {
 			errorMsg = fmt.Sprintf("%s: %v", errorMsg, err)
@@ -245,6 +838,8 @@ func (e *Executor) executeCommand(stepName string, cmd CommandStep, facts Facts)
 			Output:   stdout,
 			Error:    errorMsg,
 			ExitCode: exitCode,
+			Stdout:   stdout,
+			Stderr:   stderr,
 		}
 	}
 
@@ -253,9 +848,68 @@ func (e *Executor) executeCommand(stepName string, cmd CommandStep, facts Facts)
 		Status:   "success",
 		Output:   stdout,
 		ExitCode: exitCode,
+		Stdout:   stdout,
+		Stderr:   stderr,
 	}
 }
 
+// commandInput returns what a command step sends to the command's standard
+// input: stdin with facts interpolated, or the contents of stdin_file
+// (relative paths are inside the bundle when running one)
+func (e *Executor) commandInput(cmd CommandStep, facts Facts) (string, error) {
+	switch {
+	case cmd.Stdin != nil:
+		stdin, err := e.interpolate(*cmd.Stdin, facts)
+		if err != nil {
+			return "", fmt.Errorf("template error in stdin: %v", err)
+		}
+		return stdin, nil
+	case cmd.StdinFile != "":
+		path, err := e.interpolate(cmd.StdinFile, facts)
+		if err != nil {
+			return "", fmt.Errorf("template error in stdin_file: %v", err)
+		}
+		if e.Bundle != nil && !filepath.IsAbs(path) {
+			path = filepath.Join(e.Bundle.Dir, path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("stdin_file: %v", err)
+		}
+		expected, err := e.referencedSHA256(path)
+		if err != nil {
+			return "", fmt.Errorf("stdin_file: %v", err)
+		}
+		if expected != "" {
+			if err := verifyChecksum(data, expected); err != nil {
+				return "", fmt.Errorf("stdin_file: %s: %v", path, err)
+			}
+		}
+		return string(data), nil
+	}
+	return "", nil
+}
+
+// verifyCommand runs a command step's verify post-condition after the
+// command succeeded, failing the step if it does not hold
+func (e *Executor) verifyCommand(cmd CommandStep, result StepResult, facts Facts) StepResult {
+	verifyCmd, err := e.interpolate(cmd.Verify, facts)
+	if err != nil {
+		result.Status = "failed"
+		result.Error = fmt.Sprintf("template error in verify: %v", err)
+		return result
+	}
+
+	if e.Verbose || cmd.Verbose {
+		verboseLog("Running verify command: %s", verifyCmd)
+	}
+	if _, passed, _, _ := e.runCheck(verifyCmd, CheckProbe{}, nil, facts); !passed {
+		result.Status = "failed"
+		result.Error = fmt.Sprintf("command completed but verify fails: %s", verifyCmd)
+	}
+	return result
+}
+
 // executeCommandWithRetry executes a command with retry-until-success
 func (e *Executor) executeCommandWithRetry(stepName string, cmd CommandStep, facts Facts) StepResult {
 	// Interpolate command with facts
@@ -267,124 +921,43 @@ func (e *Executor) executeCommandWithRetry(stepName string, cmd CommandStep, fac
 			Error:    fmt.Sprintf("template error: %v", err),
 		}
 	}
-
-	// Log command execution in verbose mode (use global verbose or step-specific)
-	verbose := e.Verbose || cmd.Verbose
-	if verbose {
-		verboseLog("Executing command with retry: %s", command)
-	}
-
-	// Parse timeout configuration (default 60s if not specified)
-	timeout := 60 * time.Second
-	var customErrorCode *int
-
-	if len(cmd.Timeout) > 0 {
-		parsedTimeout, errCode, err := parseTimeoutConfig(cmd.Timeout)
-		if err != nil {
-			return StepResult{
-				StepName: stepName,
-				Status:   "failed",
-				Error:    err.Error(),
-			}
-		}
-		if parsedTimeout > 0 {
-			timeout = parsedTimeout
-		}
-		customErrorCode = errCode
-	}
-
-	if verbose {
-		verboseLog("Retry timeout: %s", timeout)
-		if customErrorCode != nil {
-			verboseLog("Custom timeout error code: %d", *customErrorCode)
+	stdin, err := e.commandInput(cmd, facts)
+	if err != nil {
+		return StepResult{
+			StepName: stepName,
+			Status:   "failed",
+			Error:    err.Error(),
 		}
 	}
 
-	// Polling loop
-	startTime := time.Now()
-	deadline := startTime.Add(timeout)
-	pollInterval := 1 * time.Second
-
-	var lastStdout, lastErrorMsg string
-	var lastExitCode int
-	attemptNum := 0
-
-	if verbose {
-		verboseLog("Starting retry loop: polling every %s, timeout at %s", pollInterval, deadline.Format("15:04:05"))
-	}
-
-	for time.Now().Before(deadline) {
-		attemptNum++
-		stdout, stderr, exitCode, err := e.transport.Run(command)
-
-		if verbose {
-			remaining := time.Until(deadline).Round(time.Second)
-			verboseLog("Retry attempt #%d - exit code: %d (timeout in %s)", attemptNum, exitCode, remaining)
-		}
-
-		// Success!
-		if err == nil && exitCode == 0 {
-			elapsed := time.Since(startTime).Round(time.Second)
-			if verbose {
-				verboseLog("✓ Retry succeeded after %d attempt(s) in %s", attemptNum, elapsed)
-			}
-
-			// Apply sleep after successful retry
-			if sleepErr := applySleep(cmd.Sleep, verbose); sleepErr != nil {
-				return StepResult{
-					StepName: stepName,
-					Status:   "failed",
-					Error:    fmt.Sprintf("sleep error: %v", sleepErr),
-				}
+	return e.retry(retryLoop{
+		name:      stepName,
+		kind:      "command",
+		command:   command,
+		timeout:   cmd.Timeout,
+		rateLimit: cmd.RateLimit,
+		sleep:     cmd.Sleep,
+		retryOn:   cmd.RetryOn,
+		verbose:   e.Verbose || cmd.Verbose,
+		attempt: func(n int) (string, string, int, error) {
+			if n == 1 {
+				return e.runInput(command, stdin)
 			}
-
-			return StepResult{
-				StepName: stepName,
-				Status:   "success",
-				Output:   fmt.Sprintf("Ready after %s\n%s", elapsed, stdout),
-				ExitCode: exitCode,
+			command, err := e.interpolate(cmd.Command, withAttempt(facts, n))
+			if err != nil {
+				return "", "", -1, err
 			}
-		}
-
-		// Save last error for reporting
-		lastStdout = stdout
-		lastExitCode = exitCode
-
-		if stderr != "" {
-			lastErrorMsg = fmt.Sprintf("exit code %d: %s", exitCode, strings.TrimSpace(stderr))
-		} else if err != nil {
-			lastErrorMsg = fmt.Sprintf("exit code %d: %v", exitCode, err)
-		} else {
-			lastErrorMsg = fmt.Sprintf("exit code %d", exitCode)
-		}
-
-		// Wait before retrying
-		time.Sleep(pollInterval)
-	}
-
-	// Timeout reached
-	elapsed := time.Since(startTime).Round(time.Second)
-	errorMsg := fmt.Sprintf("Timeout after %s\nLast error: %s", elapsed, lastErrorMsg)
-
-	// Use custom error code if specified, otherwise use last exit code
-	finalExitCode := lastExitCode
-	if customErrorCode != nil {
-		finalExitCode = *customErrorCode
-	}
-
-	return StepResult{
-		StepName: stepName,
-		Status:   "failed",
-		Output:   lastStdout,
-		Error:    errorMsg,
-		ExitCode: finalExitCode,
-	}
+			return e.runInput(command, stdin)
+		},
+		succeeded: cmd.succeeded,
+		onRetry:   e.emitRetryEvent,
+	})
 }
 
 // executeCheckError executes a CheckErrorStep
 func (e *Executor) executeCheckError(stepName string, check CheckErrorStep, facts Facts) StepResult {
 	// Interpolate check command
-	checkCmd, err := e.interpolate(check.Check, facts)
+	checkCmd, probe, err := e.interpolateCheck(check.Check, check.CheckProbe, facts)
 	if err != nil {
 		return StepResult{
 			StepName: stepName,
@@ -394,22 +967,26 @@ func (e *Executor) executeCheckError(stepName string, check CheckErrorStep, fact
 	}
 
 	if e.Verbose {
-		verboseLog("Running check command: %s", checkCmd)
+		verboseLog("Running check command: %s", describeCheck(checkCmd, probe))
 	}
 
 	// Run the check
-	stdout, _, exitCode, _ := e.transport.Run(checkCmd)
-
-	if e.Verbose {
-		verboseLog("Check command exit code: %d", exitCode)
+	stdout, passed, mismatch, err := e.runCheck(checkCmd, probe, check.ExpectOutput, facts)
+	if err != nil {
+		return StepResult{
+			StepName: stepName,
+			Status:   "failed",
+			Error:    fmt.Sprintf("template error in expect_output: %v", err),
+		}
 	}
 
-	if exitCode != 0 {
+	if !passed {
 		// Check failed, return the error message
 		return StepResult{
-			StepName: stepName,
-			Status:   "failed",
-			Error:    check.Error,
+			StepName:       stepName,
+			Status:         "failed",
+			Error:          check.Error,
+			OutputMismatch: mismatch,
 		}
 	}
 
@@ -420,10 +997,49 @@ func (e *Executor) executeCheckError(stepName string, check CheckErrorStep, fact
 	}
 }
 
+// runCheck runs a check command, or evaluates probe if set. A command passes
+// when it exits 0 and, with expect_output, its trimmed output equals the
+// interpolated expectation; otherwise mismatch holds both outputs for
+// diagnosis.
+func (e *Executor) runCheck(checkCmd string, probe CheckProbe, expectOutput *string, facts Facts) (stdout string, passed bool, mismatch *OutputMismatch, err error) {
+	if probe.isSet() {
+		passed = e.runProbe(probe)
+		if e.Verbose {
+			verboseLog("Check %s: %t", probe, passed)
+		}
+		return "", passed, nil, nil
+	}
+
+	var expected string
+	if expectOutput != nil {
+		if expected, err = e.interpolate(*expectOutput, facts); err != nil {
+			return "", false, nil, err
+		}
+	}
+
+	stdout, _, exitCode, _ := e.run(checkCmd)
+	if e.Verbose {
+		verboseLog("Check command exit code: %d", exitCode)
+	}
+	if exitCode != 0 || expectOutput == nil {
+		return stdout, exitCode == 0, nil, nil
+	}
+
+	actual := strings.TrimSpace(stdout)
+	expected = strings.TrimSpace(expected)
+	if actual != expected {
+		if e.Verbose {
+			verboseLog("Check output %q does not match expected %q", actual, expected)
+		}
+		return stdout, false, &OutputMismatch{Expected: expected, Actual: actual}, nil
+	}
+	return stdout, true, nil, nil
+}
+
 // executeCheckRemediate executes a CheckRemediateStep
 func (e *Executor) executeCheckRemediate(stepName string, checkRem CheckRemediateStep, facts Facts) StepResult {
 	// Interpolate check command
-	checkCmd, err := e.interpolate(checkRem.Check, facts)
+	checkCmd, probe, err := e.interpolateCheck(checkRem.Check, checkRem.CheckProbe, facts)
 	if err != nil {
 		return StepResult{
 			StepName: stepName,
@@ -433,17 +1049,20 @@ func (e *Executor) executeCheckRemediate(stepName string, checkRem CheckRemediat
 	}
 
 	if e.Verbose {
-		verboseLog("Running check command: %s", checkCmd)
+		verboseLog("Running check command: %s", describeCheck(checkCmd, probe))
 	}
 
 	// Run the check
-	_, _, exitCode, _ := e.transport.Run(checkCmd)
-
-	if e.Verbose {
-		verboseLog("Check command exit code: %d", exitCode)
+	_, passed, _, err := e.runCheck(checkCmd, probe, checkRem.ExpectOutput, facts)
+	if err != nil {
+		return StepResult{
+			StepName: stepName,
+			Status:   "failed",
+			Error:    fmt.Sprintf("template error in expect_output: %v", err),
+		}
 	}
 
-	if exitCode == 0 {
+	if passed {
 		// Check passed, no remediation needed
 		return StepResult{
 			StepName: stepName,
@@ -458,8 +1077,12 @@ func (e *Executor) executeCheckRemediate(stepName string, checkRem CheckRemediat
 	}
 
 	remediationResults := []StepResult{}
-	for _, remStep := range checkRem.OnMissing {
+	for i, remStep := range checkRem.OnMissing {
+		e.emitRemediationEvent(stepName, i+1, remStep, StepResult{Status: "running"})
+		e.remediationOf, e.remediationIndex = stepName, i+1
 		remResult := e.executeRemediation(remStep, facts)
+		e.remediationOf, e.remediationIndex = "", 0
+		e.emitRemediationEvent(stepName, i+1, remStep, remResult)
 		remediationResults = append(remediationResults, remResult)
 
 		// Stop on first remediation failure
@@ -475,21 +1098,18 @@ func (e *Executor) executeCheckRemediate(stepName string, checkRem CheckRemediat
 
 	// Re-run the check to verify remediation actually fixed the issue
 	if e.Verbose {
-		verboseLog("Re-running check to verify remediation: %s", checkCmd)
+		verboseLog("Re-running check to verify remediation: %s", describeCheck(checkCmd, probe))
 	}
 
-	_, _, recheckExitCode, _ := e.transport.Run(checkCmd)
-
-	if e.Verbose {
-		verboseLog("Recheck exit code: %d", recheckExitCode)
-	}
+	_, passed, mismatch, _ := e.runCheck(checkCmd, probe, checkRem.ExpectOutput, facts)
 
-	if recheckExitCode != 0 {
+	if !passed {
 		return StepResult{
 			StepName:         stepName,
 			Status:           "failed",
 			Error:            "remediation completed but check still fails",
 			RemediationSteps: remediationResults,
+			OutputMismatch:   mismatch,
 		}
 	}
 
@@ -501,6 +1121,48 @@ func (e *Executor) executeCheckRemediate(stepName string, checkRem CheckRemediat
 	}
 }
 
+// emitRemediationEvent reports a remediation's progress as a child event of
+// the check-with-remediation step that runs it
+func (e *Executor) emitRemediationEvent(parentStep string, index int, remStep RemediationStep, result StepResult) {
+	status := result.Status
+	if status != "running" {
+		status = "success"
+		if result.Error != "" {
+			status = "failed"
+		}
+	}
+	event := ExecutionEvent{
+		StepName:         remStep.Name,
+		Status:           status,
+		Output:           result.Output,
+		Error:            result.Error,
+		Stdout:           result.Stdout,
+		Stderr:           result.Stderr,
+		ParentStep:       parentStep,
+		RemediationIndex: index,
+	}
+	if result.ExitCode != 0 {
+		exitCode := result.ExitCode
+		event.ExitCode = &exitCode
+	}
+	if e.Verbose || e.JSONOutput {
+		event.StepType = "RemediationStep"
+		if remStep.Error != nil {
+			event.CustomError = *remStep.Error
+		}
+		if remStep.Retry != nil {
+			event.Retry = *remStep.Retry
+		}
+		if len(remStep.Timeout) > 0 {
+			event.Timeout = string(remStep.Timeout)
+		}
+		if remStep.Sleep != nil {
+			event.Sleep = *remStep.Sleep
+		}
+	}
+	e.emitEvent(event)
+}
+
 // executeRemediation executes a RemediationStep
 func (e *Executor) executeRemediation(remStep RemediationStep, facts Facts) StepResult {
 	// Check if retry is enabled
@@ -524,8 +1186,17 @@ func (e *Executor) executeRemediation(remStep RemediationStep, facts Facts) Step
 		verboseLog("Executing remediation command: %s", command)
 	}
 
+	// Wait for the step's rate limit, if any
+	if err := e.throttle(remStep.RateLimit, remStep.Name, verbose); err != nil {
+		return StepResult{
+			StepName: remStep.Name,
+			Status:   "failed",
+			Error:    fmt.Sprintf("rate limit: %v", err),
+		}
+	}
+
 	// Run the command
-	stdout, stderr, exitCode, err := e.transport.Run(command)
+	stdout, stderr, exitCode, err := e.run(command)
 
 	if verbose {
 		verboseLog("Remediation exit code: %d", exitCode)
@@ -561,6 +1232,8 @@ func (e *Executor) executeRemediation(remStep RemediationStep, facts Facts) Step
 			Output:   stdout,
 			Error:    errorMsg,
 			ExitCode: exitCode,
+			Stdout:   stdout,
+			Stderr:   stderr,
 		}
 	}
 
@@ -569,6 +1242,8 @@ func (e *Executor) executeRemediation(remStep RemediationStep, facts Facts) Step
 		Status:   "success",
 		Output:   stdout,
 		ExitCode: exitCode,
+		Stdout:   stdout,
+		Stderr:   stderr,
 	}
 }
 
@@ -584,116 +1259,28 @@ func (e *Executor) executeRemediationWithRetry(remStep RemediationStep, facts Fa
 		}
 	}
 
-	if remStep.Verbose {
-		verboseLog("Executing remediation with retry: %s", command)
-	}
-
-	// Parse timeout configuration (default 60s if not specified)
-	timeout := 60 * time.Second
-	var customErrorCode *int
-
-	if len(remStep.Timeout) > 0 {
-		parsedTimeout, errCode, err := parseTimeoutConfig(remStep.Timeout)
-		if err != nil {
-			return StepResult{
-				StepName: remStep.Name,
-				Status:   "failed",
-				Error:    err.Error(),
-			}
-		}
-		if parsedTimeout > 0 {
-			timeout = parsedTimeout
-		}
-		customErrorCode = errCode
-	}
-
-	if remStep.Verbose {
-		verboseLog("Retry timeout: %s", timeout)
-		if customErrorCode != nil {
-			verboseLog("Custom timeout error code: %d", *customErrorCode)
-		}
-	}
-
-	// Polling loop
-	startTime := time.Now()
-	deadline := startTime.Add(timeout)
-	pollInterval := 1 * time.Second
-
-	var lastStdout, lastErrorMsg string
-	var lastExitCode int
-	attemptNum := 0
-
-	verbose := e.Verbose || remStep.Verbose
-	if verbose {
-		verboseLog("Starting remediation retry loop: polling every %s, timeout at %s", pollInterval, deadline.Format("15:04:05"))
-	}
-
-	for time.Now().Before(deadline) {
-		attemptNum++
-		stdout, stderr, exitCode, err := e.transport.Run(command)
-
-		if verbose {
-			remaining := time.Until(deadline).Round(time.Second)
-			verboseLog("Remediation retry attempt #%d - exit code: %d (timeout in %s)", attemptNum, exitCode, remaining)
-		}
-
-		// Success!
-		if err == nil && exitCode == 0 {
-			elapsed := time.Since(startTime).Round(time.Second)
-			if verbose {
-				verboseLog("✓ Remediation retry succeeded after %d attempt(s) in %s", attemptNum, elapsed)
-			}
-
-			// Apply sleep after successful retry
-			if sleepErr := applySleep(remStep.Sleep, verbose); sleepErr != nil {
-				return StepResult{
-					StepName: remStep.Name,
-					Status:   "failed",
-					Error:    fmt.Sprintf("sleep error: %v", sleepErr),
-				}
+	return e.retry(retryLoop{
+		name:      remStep.Name,
+		kind:      "remediation",
+		command:   command,
+		timeout:   remStep.Timeout,
+		rateLimit: remStep.RateLimit,
+		sleep:     remStep.Sleep,
+		retryOn:   remStep.RetryOn,
+		verbose:   e.Verbose || remStep.Verbose,
+		attempt: func(n int) (string, string, int, error) {
+			if n == 1 {
+				return e.run(command)
 			}
-
-			return StepResult{
-				StepName: remStep.Name,
-				Status:   "success",
-				Output:   fmt.Sprintf("Ready after %s\n%s", elapsed, stdout),
-				ExitCode: exitCode,
+			command, err := e.interpolate(remStep.Command, withAttempt(facts, n))
+			if err != nil {
+				return "", "", -1, err
 			}
-		}
-
-		// Save last error for reporting
-		lastStdout = stdout
-		lastExitCode = exitCode
-
-		if stderr != "" {
-			lastErrorMsg = fmt.Sprintf("exit code %d: %s", exitCode, strings.TrimSpace(stderr))
-		} else if err != nil {
-			lastErrorMsg = fmt.Sprintf("exit code %d: %v", exitCode, err)
-		} else {
-			lastErrorMsg = fmt.Sprintf("exit code %d", exitCode)
-		}
-
-		// Wait before retrying
-		time.Sleep(pollInterval)
-	}
-
-	// Timeout reached
-	elapsed := time.Since(startTime).Round(time.Second)
-	errorMsg := fmt.Sprintf("Timeout after %s\nLast error: %s", elapsed, lastErrorMsg)
-
-	// Use custom error code if specified, otherwise use last exit code
-	finalExitCode := lastExitCode
-	if customErrorCode != nil {
-		finalExitCode = *customErrorCode
-	}
-
-	return StepResult{
-		StepName: remStep.Name,
-		Status:   "failed",
-		Output:   lastStdout,
-		Error:    errorMsg,
-		ExitCode: finalExitCode,
-	}
+			return e.run(command)
+		},
+		succeeded: func(exitCode int) bool { return exitCode == 0 },
+		onRetry:   e.emitRetryEvent,
+	})
 }
 
 // executeErrorOnly executes an ErrorOnlyStep
@@ -717,12 +1304,19 @@ func (e *Executor) interpolate(command string, facts Facts) (string, error) {
 		verboseLog("Available facts: %v", facts)
 	}
 
-	tmpl, err := template.New("command").Parse(command)
+	// Declared facts that were not gathered read as their type's zero value,
+	// so {{if .optional_fact}} works; unknown names are errors rather than a
+	// silent "<no value>" in a shell command
+	if e.Gatherer != nil {
+		facts = e.Gatherer.withDeclared(facts)
+	}
+	funcs := e.templateFuncs()
+	tmpl, err := template.New("command").Funcs(funcs).Option("missingkey=error").Parse(command)
 	if err != nil {
 		if e.Verbose {
 			verboseLog("Template parse error: %v", err)
 		}
-		return "", fmt.Errorf("template parse error: %w", err)
+		return "", fmt.Errorf("template parse error: %w", explainTemplateError(err, command, facts, templateFuncNames(funcs)))
 	}
 
 	var buf bytes.Buffer
@@ -731,21 +1325,97 @@ func (e *Executor) interpolate(command string, facts Facts) (string, error) {
 			verboseLog("Template execution error: %v", err)
 			verboseLog("Available facts were: %v", facts)
 		}
-		return "", fmt.Errorf("template execution error (check fact names): %w", err)
+		return "", fmt.Errorf("template execution error: %w", explainTemplateError(err, command, facts, templateFuncNames(funcs)))
 	}
 
 	result := buf.String()
 	if e.Verbose && command != result {
 		verboseLog("Template after interpolation: %s", result)
 	}
+	e.ensureStepDir(result)
 
 	return result, nil
 }
 
-// emitEvent emits an execution event if a handler is configured
+// templateFuncNames lists the custom and built-in template function names
+// (used for "did you mean" suggestions)
+func templateFuncNames(funcs template.FuncMap) []string {
+	names := []string{"and", "call", "html", "index", "js", "len", "not", "or", "print", "printf", "println", "slice", "urlquery", "eq", "ne", "lt", "le", "gt", "ge"}
+	for name := range funcs {
+		names = append(names, name)
+	}
+	return names
+}
+
+// templateFuncs returns the functions available to command templates
+func (e *Executor) templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		// checksum returns the expected SHA256 of a file from the checksum manifest
+		"checksum": func(name string) (string, error) {
+			if e.Checksums == nil {
+				return "", fmt.Errorf("no checksum manifest loaded (use --checksums-url)")
+			}
+			sum, ok := e.Checksums.Lookup(name)
+			if !ok {
+				return "", fmt.Errorf("checksum manifest has no entry for %s", name)
+			}
+			return sum, nil
+		},
+		// sha256 returns the SHA256 of a file on the target
+		"sha256": func(file string) (string, error) {
+			if sum := e.targetSHA256(file); sum != "" {
+				return sum, nil
+			}
+			return "", fmt.Errorf("cannot read %s on the target", file)
+		},
+		// verifySha256 renders nothing when a file on the target has the
+		// expected SHA256 and fails the step otherwise
+		"verifySha256": func(file, expected string) (string, error) {
+			_, err := e.verifySHA256(file, expected)
+			return "", err
+		},
+		// add sums integers, treating "" as 0, for counters kept in scratch
+		"add": templateAdd,
+		// archMap normalizes an architecture and renames it for downloads
+		"archMap": templateArchMap,
+	}
+}
+
+// throttle waits until a step's rate_limit allows running one of its commands
+func (e *Executor) throttle(rateLimit, stepName string, verbose bool) error {
+	if rateLimit == "" || e.RateLimiter == nil {
+		return nil
+	}
+	waited, err := e.RateLimiter.Wait(rateLimit, stepName)
+	if verbose && waited > 0 {
+		verboseLog("Rate limit %s: waited %s", rateLimit, waited.Round(time.Millisecond))
+	}
+	return err
+}
+
+// emitEvent emits an execution event if a handler is configured. It is
+// safe to call from several goroutines: events are numbered, written and
+// handed to OnEvent one at a time.
 func (e *Executor) emitEvent(event ExecutionEvent) {
-	// Always include execution context in events
+	e.emitMu.Lock()
+	defer e.emitMu.Unlock()
+
+	// Always include execution context in events, and the host and step
+	// they belong to, so merged streams can be split again
 	event.Context = e.context
+	if event.ParentStep == "" {
+		event.ParentStep = e.parentStep
+	}
+	event.RunID = e.runID
+	event.Host = e.Host.Name
+	if event.Host == "" {
+		event.Host = e.context.Host
+	}
+	event.StepNumber = e.stepNumber
+	e.seq++
+	event.Seq = e.seq
+	event.Timestamp = eventTimestamp(e.clock())
+	event = activeRedactor.redactEvent(event)
 
 	// Output as JSON if JSON mode is enabled
 	if e.JSONOutput {
@@ -755,18 +1425,63 @@ func (e *Executor) emitEvent(event ExecutionEvent) {
 			fmt.Fprintf(os.Stderr, "WARNING: Failed to marshal event to JSON: %v\n", err)
 			fmt.Fprintf(os.Stderr, "Event details: step=%s, status=%s\n", event.StepName, event.Status)
 		} else {
-			fmt.Println(string(jsonBytes))
+			stdout := e.Stdout
+			if stdout == nil {
+				stdout = os.Stdout
+			}
+			writeJSONObject(stdout, jsonBytes)
 		}
 	}
 
 	if e.OnEvent != nil {
 		e.OnEvent(event)
 	}
+	for _, sink := range e.Sinks {
+		sink.Send(event)
+	}
 }
 
-// generateRunID generates a unique run ID
-func generateRunID() string {
-	return fmt.Sprintf("run-%d", time.Now().UnixNano())
+// CloseSinks delivers the events still buffered for the sinks and returns
+// warnings about events they lost
+func (e *Executor) CloseSinks() []Warning {
+	var warnings []Warning
+	for _, sink := range e.Sinks {
+		warnings = append(warnings, sink.Close()...)
+	}
+	return warnings
+}
+
+// clock returns the current time measured on the monotonic clock since the
+// executor started, so event timestamps never go backwards when the wall
+// clock is adjusted mid-run
+func (e *Executor) clock() time.Time {
+	if e.started.IsZero() {
+		return time.Now()
+	}
+	return e.started.Add(time.Since(e.started))
+}
+
+// eventTimestamp formats an event time as UTC RFC3339 with nanoseconds, so
+// events from fast steps and from hosts in different zones order correctly
+func eventTimestamp(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// parseTimezone splits `date '+%Z %z'` output into the zone abbreviation and
+// an RFC3339-style offset ("+0100" becomes "+01:00")
+func parseTimezone(output string) (zone string, offset string) {
+	fields := strings.Fields(output)
+	if len(fields) == 0 {
+		return "", ""
+	}
+	zone = fields[0]
+	if len(fields) > 1 {
+		offset = fields[1]
+		if len(offset) == 5 && (offset[0] == '+' || offset[0] == '-') {
+			offset = offset[:3] + ":" + offset[3:]
+		}
+	}
+	return zone, offset
 }
 
 // logStepMetadata logs all metadata fields from the step configuration
@@ -789,6 +1504,9 @@ func (e *Executor) logStepMetadata(step InstallStep) {
 		if v.Sleep != nil {
 			verboseLog("  Sleep: %s", *v.Sleep)
 		}
+		if v.Verify != "" {
+			verboseLog("  Verify: %s", v.Verify)
+		}
 		verboseLog("  Verbose: %v", v.Verbose)
 
 	case CheckErrorStep:
@@ -817,6 +1535,31 @@ func (e *Executor) logStepMetadata(step InstallStep) {
 			}
 		}
 
+	case CopyStep:
+		verboseLog("  Step type: CopyStep")
+		verboseLog("  Copy: %s → %s", v.Copy.Source, v.Copy.Destination)
+
+	case FetchStep:
+		verboseLog("  Step type: FetchStep")
+		verboseLog("  Fetch: %s → %s", v.Fetch.Source, v.Fetch.Destination)
+
+	case VerifyChecksumStep:
+		verboseLog("  Step type: VerifyChecksumStep")
+		verboseLog("  Verify: %s (sha256 %s)", v.VerifyChecksum.Path, v.VerifyChecksum.SHA256)
+
+	case GroupStep:
+		verboseLog("  Step type: GroupStep")
+		verboseLog("  Policy: %s, %d steps", v.Group.describePolicy(), len(v.Group.Steps))
+
+	case PauseStep:
+		verboseLog("  Step type: PauseStep")
+		if v.Pause.Duration != "" {
+			verboseLog("  Duration: %s", v.Pause.Duration)
+		}
+		if v.Pause.Prompt != "" {
+			verboseLog("  Prompt: %s", v.Pause.Prompt)
+		}
+
 	case ErrorOnlyStep:
 		verboseLog("  Step type: ErrorOnlyStep")
 		verboseLog("  Error: %s", v.Error)
@@ -877,6 +1620,22 @@ func (e *Executor) populateVerboseMetadata(event *ExecutionEvent, step InstallSt
 			}
 		}
 
+	case CopyStep:
+		event.StepType = "CopyStep"
+
+	case FetchStep:
+		event.StepType = "FetchStep"
+
+	case VerifyChecksumStep:
+		event.StepType = "VerifyChecksumStep"
+
+	case GroupStep:
+		event.StepType = "GroupStep"
+
+	case PauseStep:
+		event.StepType = "PauseStep"
+		event.Message = v.Pause.Prompt
+
 	case ErrorOnlyStep:
 		event.StepType = "ErrorOnlyStep"
 		event.CustomError = v.Error
@@ -886,9 +1645,24 @@ func (e *Executor) populateVerboseMetadata(event *ExecutionEvent, step InstallSt
 // StepResult represents the result of executing a step
 type StepResult struct {
 	StepName         string
-	Status           string // "success", "failed", "skipped"
+	Status           string // "success", "failed", "skipped", "deferred", "not_run", "pending"
 	Output           string
 	Error            string
 	ExitCode         int
+	Stdout           string // Standard output of the command, also on success
+	Stderr           string // Standard error of the command, also on success
 	RemediationSteps []StepResult
+	Duration         time.Duration   // Wall time of the step (set by ExecutePlatform)
+	OutputMismatch   *OutputMismatch // Set when a check's output differed from expect_output
+	Unchanged        bool            // A copy or fetch found its destination already up to date
+	GroupSteps       []StepResult    // Results of the group members that ran, in order
+	SkipReason       string          // Why a skipped, deferred or not run step did not run (SkipReason*)
+	Tolerated        bool            // Failed under failure_policy "continue", so it does not fail the run
+
+	// A later failure ran the step's undo command: RolledBack when it
+	// succeeded, RollbackError when it failed
+	RolledBack    bool
+	RollbackError string
+
+	undo *undoEntry // The step's undo command, for a later rollback
 }
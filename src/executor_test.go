@@ -1,8 +1,14 @@
 package main
 
 import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
 )
 
 // TestExecutorCommandStep tests execution of simple command steps
@@ -264,7 +270,7 @@ func TestCheckRemediateWithRecheck(t *testing.T) {
 		executor := NewExecutor(transport)
 
 		// Create a test file that acts as our "state"
-		testFile := "/tmp/sink-test-recheck"
+		testFile := filepath.Join(t.TempDir(), "sink-test-recheck")
 		transport.Run("rm -f " + testFile)
 
 		step := InstallStep{
@@ -514,6 +520,9 @@ func TestExecutorDryRun(t *testing.T) {
 	if result.Status != "skipped" {
 		t.Errorf("expected status 'skipped' in dry-run, got %s", result.Status)
 	}
+	if result.SkipReason != SkipReasonDryRun {
+		t.Errorf("expected skip reason %q, got %q", SkipReasonDryRun, result.SkipReason)
+	}
 	if result.Error != "" {
 		t.Errorf("unexpected error in dry-run: %s", result.Error)
 	}
@@ -556,6 +565,125 @@ func TestExecutorEventEmission(t *testing.T) {
 	}
 }
 
+// TestExecutorMaxDuration tests that the run deadline cancels the running step
+// and marks the remaining steps as not_run
+func TestExecutorMaxDuration(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sleep")
+	}
+
+	executor := NewExecutor(NewLocalTransport())
+	executor.Deadline = time.Now().Add(300 * time.Millisecond)
+
+	var statuses []string
+	executor.OnEvent = func(event ExecutionEvent) {
+		statuses = append(statuses, event.StepName+":"+event.Status)
+	}
+
+	platform := Platform{
+		InstallSteps: []InstallStep{
+			{Name: "quick", Step: CommandStep{Command: "true"}},
+			{Name: "slow", Step: CommandStep{Command: "sleep 10"}},
+			{Name: "after1", Step: CommandStep{Command: "true"}},
+			{Name: "after2", Step: CommandStep{Command: "true"}},
+		},
+	}
+
+	start := time.Now()
+	results := executor.ExecutePlatform(platform, nil)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("slow step was not cancelled (took %s)", elapsed)
+	}
+
+	if len(results) != 4 {
+		t.Fatalf("expected 4 results, got %d", len(results))
+	}
+	if results[0].Status != "success" {
+		t.Errorf("quick step status = %s, want success", results[0].Status)
+	}
+	if results[1].Status != "failed" || !strings.Contains(results[1].Error, "max duration") {
+		t.Errorf("slow step = %s %q, want failed with max duration error", results[1].Status, results[1].Error)
+	}
+	if results[1].ExitCode != ExitDeadlineExceeded {
+		t.Errorf("slow step exit code = %d, want %d", results[1].ExitCode, ExitDeadlineExceeded)
+	}
+	for _, r := range results[2:] {
+		if r.Status != "not_run" || r.SkipReason != SkipReasonDeadline {
+			t.Errorf("step %s status = %s (%s), want not_run (%s)", r.StepName, r.Status, r.SkipReason, SkipReasonDeadline)
+		}
+	}
+	if !executor.DeadlineExceeded() {
+		t.Error("expected DeadlineExceeded() to be true")
+	}
+	if statuses[len(statuses)-1] != "after2:not_run" {
+		t.Errorf("expected not_run events, got %v", statuses)
+	}
+}
+
+// TestExecutorNoDeadline tests that a zero deadline never marks steps not_run
+func TestExecutorNoDeadline(t *testing.T) {
+	mockTransport := &MockTransport{
+		responses: map[string]MockResponse{
+			"echo a": {stdout: "a\n", exitCode: 0},
+		},
+	}
+	executor := NewExecutor(mockTransport)
+	results := executor.ExecutePlatform(Platform{
+		InstallSteps: []InstallStep{{Name: "a", Step: CommandStep{Command: "echo a"}}},
+	}, nil)
+
+	if executor.DeadlineExceeded() || results[0].Status != "success" {
+		t.Errorf("unexpected result without deadline: %+v", results[0])
+	}
+}
+
+// TestExecutorWindow tests that steps outside their maintenance window are deferred
+func TestExecutorWindow(t *testing.T) {
+	mockTransport := &MockTransportWithTracking{
+		responses: map[string]MockResponse{
+			"echo a": {stdout: "a\n", exitCode: 0},
+			"echo b": {stdout: "b\n", exitCode: 0},
+		},
+	}
+	executor := NewExecutor(mockTransport)
+	executor.now = func() time.Time { return time.Date(2026, 3, 9, 12, 0, 0, 0, time.UTC) }
+
+	platform := Platform{
+		InstallSteps: []InstallStep{
+			{Name: "a", Window: "22:00-06:00 UTC", Step: CommandStep{Command: "echo a"}},
+			{Name: "b", Window: "09:00-17:00 UTC", Step: CommandStep{Command: "echo b"}},
+		},
+	}
+
+	results := executor.ExecutePlatform(platform, nil)
+	if results[0].Status != "deferred" || results[1].Status != "success" {
+		t.Errorf("unexpected statuses: %s, %s", results[0].Status, results[1].Status)
+	}
+	if results[0].SkipReason != SkipReasonWindow || results[1].SkipReason != "" {
+		t.Errorf("unexpected skip reasons: %q, %q", results[0].SkipReason, results[1].SkipReason)
+	}
+	for _, call := range mockTransport.calls {
+		if call == "echo a" {
+			t.Errorf("deferred step should not run, calls: %v", mockTransport.calls)
+		}
+	}
+
+	// A config-level window defers every step
+	mockTransport.calls = nil
+	executor.Window = "Sat,Sun 00:00-24:00 UTC"
+	results = executor.ExecutePlatform(platform, nil)
+	for _, r := range results {
+		if r.Status != "deferred" || r.SkipReason != SkipReasonWindow {
+			t.Errorf("step %s status = %s (%s), want deferred (%s)", r.StepName, r.Status, r.SkipReason, SkipReasonWindow)
+		}
+	}
+	for _, call := range mockTransport.calls {
+		if strings.HasPrefix(call, "echo") {
+			t.Errorf("no steps should run outside the config window, calls: %v", mockTransport.calls)
+		}
+	}
+}
+
 // Enhanced MockTransport with call tracking
 type MockTransportWithTracking struct {
 	responses map[string]MockResponse
@@ -585,3 +713,450 @@ type StatefulMockTransport struct {
 func (s *StatefulMockTransport) Run(cmd string) (stdout, stderr string, exitCode int, err error) {
 	return s.runFunc(cmd)
 }
+
+// TestExecutePlatform_RefreshFacts tests that refresh_facts re-gathers facts for later steps
+func TestExecutePlatform_RefreshFacts(t *testing.T) {
+	installed := false
+	transport := &StatefulMockTransport{
+		runFunc: func(cmd string) (string, string, int, error) {
+			switch strings.TrimSpace(cmd) {
+			case "command -v docker >/dev/null && echo true || echo false":
+				return fmt.Sprintf("%v\n", installed), "", 0, nil
+			case "install docker":
+				installed = true
+				return "", "", 0, nil
+			case "echo docker=true":
+				return "docker=true\n", "", 0, nil
+			}
+			return "", "command not mocked", 127, nil
+		},
+	}
+
+	definitions := map[string]FactDef{
+		"has_docker": {Command: "command -v docker >/dev/null && echo true || echo false", Type: "boolean"},
+	}
+	gatherer := NewFactGatherer(definitions, transport)
+	facts, err := gatherer.Gather()
+	if err != nil || facts["has_docker"] != false {
+		t.Fatalf("initial gather: %v, %v", facts, err)
+	}
+
+	executor := NewExecutor(transport)
+	executor.Gatherer = gatherer
+	results := executor.ExecutePlatform(Platform{InstallSteps: []InstallStep{
+		{Name: "Install docker", RefreshFacts: []string{"has_docker"}, Step: CommandStep{Command: "install docker"}},
+		{Name: "Report", Step: CommandStep{Command: "echo docker={{.has_docker}}"}},
+	}}, facts)
+
+	if len(results) != 2 || results[1].Error != "" {
+		t.Fatalf("expected both steps to succeed, got %+v", results)
+	}
+	if results[1].Output != "docker=true\n" {
+		t.Errorf("later step saw stale fact: %q", results[1].Output)
+	}
+	if facts["has_docker"] != false {
+		t.Error("caller's facts should not be modified")
+	}
+}
+
+// TestExecutePlatform_ExportFacts tests passing exported facts to commands
+// as environment variables, refreshed after refresh_facts
+func TestExecutePlatform_ExportFacts(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses POSIX shell commands")
+	}
+	marker := filepath.Join(t.TempDir(), "installed")
+	transport := NewLocalTransport()
+	gatherer := NewFactGatherer(map[string]FactDef{
+		"tool_state": {Command: fmt.Sprintf("test -f %s && echo installed || echo missing", marker), Export: "SINK_TOOL_STATE"},
+		"unexported": {Command: "echo hidden"},
+	}, transport)
+	facts, err := gatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []InstallStep{
+		{Name: "Before", Step: CommandStep{Command: "echo \"state=$SINK_TOOL_STATE\""}},
+		{Name: "Install", RefreshFacts: []string{"tool_state"}, Step: CommandStep{Command: "touch " + marker}},
+		{Name: "After", Step: CommandStep{Command: "echo \"state=$SINK_TOOL_STATE\""}},
+	}
+	executor := NewExecutor(transport)
+	executor.Gatherer = gatherer
+	results := executor.ExecutePlatform(Platform{InstallSteps: steps}, facts)
+	if len(results) != 3 || results[0].Output != "state=missing\n" || results[2].Output != "state=installed\n" {
+		t.Fatalf("expected the exported fact before and after its refresh, got %+v", results)
+	}
+	if strings.Contains(strings.Join(transport.ExtraEnv, " "), "hidden") {
+		t.Errorf("expected only facts with export in the environment, got %v", transport.ExtraEnv)
+	}
+
+	os.Remove(marker)
+	transport = NewLocalTransport()
+	executor = NewExecutor(transport)
+	executor.Gatherer = NewFactGatherer(gatherer.definitions, transport)
+	executor.ExportFacts = false
+	results = executor.ExecutePlatform(Platform{InstallSteps: steps[:1]}, facts)
+	if len(results) != 1 || results[0].Output != "state=\n" {
+		t.Errorf("expected no exported facts with export_facts false, got %+v", results)
+	}
+}
+
+// TestExecutePlatform_RefreshFactsFailure tests that a required fact failing to refresh fails the step
+func TestExecutePlatform_RefreshFactsFailure(t *testing.T) {
+	transport := &MockTransportWithTracking{responses: map[string]MockResponse{
+		"install tool":   {},
+		"tool --version": {exitCode: 1, err: fmt.Errorf("not found")},
+	}}
+	gatherer := NewFactGatherer(map[string]FactDef{
+		"tool_version": {Command: "tool --version", Required: true},
+	}, transport)
+
+	executor := NewExecutor(transport)
+	executor.Gatherer = gatherer
+	results := executor.ExecutePlatform(Platform{InstallSteps: []InstallStep{
+		{Name: "Install tool", RefreshFacts: []string{"tool_version"}, Step: CommandStep{Command: "install tool"}},
+		{Name: "Never", Step: CommandStep{Command: "echo never"}},
+	}}, Facts{"tool_version": "0"})
+
+	if len(results) != 1 || !strings.Contains(results[0].Error, "refresh_facts: required fact 'tool_version' failed") {
+		t.Errorf("expected refresh failure to stop the run, got %+v", results)
+	}
+}
+
+// TestExecutePlatform_RecheckPending tests that one remediation can satisfy earlier pending checks
+func TestExecutePlatform_RecheckPending(t *testing.T) {
+	installed := false
+	transport := &StatefulMockTransport{
+		runFunc: func(cmd string) (string, string, int, error) {
+			switch strings.TrimSpace(cmd) {
+			case "command -v docker", "command -v dockerd":
+				if installed {
+					return "/usr/bin/docker\n", "", 0, nil
+				}
+				return "", "", 1, nil
+			case "install docker":
+				installed = true
+				return "", "", 0, nil
+			}
+			return "", "command not mocked", 127, nil
+		},
+	}
+
+	executor := NewExecutor(transport)
+	var events []ExecutionEvent
+	executor.OnEvent = func(event ExecutionEvent) { events = append(events, event) }
+
+	results := executor.ExecutePlatform(Platform{InstallSteps: []InstallStep{
+		{Name: "daemon present", Step: CheckErrorStep{Check: "command -v dockerd", Error: "dockerd missing", Recheck: true}},
+		{Name: "docker", Step: CheckRemediateStep{
+			Check:     "command -v docker",
+			OnMissing: []RemediationStep{{Name: "install", Command: "install docker"}},
+		}},
+	}}, Facts{})
+
+	if len(results) != 2 || results[0].Status != "success" || results[0].Error != "" || results[1].Error != "" {
+		t.Fatalf("expected pending check to pass after remediation, got %+v", results)
+	}
+
+	var statuses []string
+	for _, event := range events {
+		if event.StepName == "daemon present" {
+			statuses = append(statuses, event.Status+":"+event.RecheckedAfter)
+		}
+	}
+	if strings.Join(statuses, ",") != "running:,pending:,success:docker" {
+		t.Errorf("unexpected events for pending check: %v", statuses)
+	}
+}
+
+// TestExecutePlatform_RecheckStillFailing tests that unsatisfied pending checks fail the run
+func TestExecutePlatform_RecheckStillFailing(t *testing.T) {
+	transport := &MockTransportWithTracking{responses: map[string]MockResponse{
+		"test -f /etc/app.conf": {exitCode: 1},
+		"echo next":             {stdout: "next\n"},
+	}}
+	executor := NewExecutor(transport)
+
+	results := executor.ExecutePlatform(Platform{InstallSteps: []InstallStep{
+		{Name: "config present", Step: CheckErrorStep{Check: "test -f /etc/app.conf", Error: "app.conf missing", Recheck: true}},
+		{Name: "next", Step: CommandStep{Command: "echo next"}},
+	}}, Facts{})
+
+	if len(results) != 2 {
+		t.Fatalf("expected the run to continue past the pending check, got %+v", results)
+	}
+	if results[0].Status != "failed" || results[0].Error != "app.conf missing" {
+		t.Errorf("expected pending check to fail at the end, got %+v", results[0])
+	}
+	if results[1].Error != "" {
+		t.Errorf("later step failed: %s", results[1].Error)
+	}
+}
+
+// TestEmitEvent_TimestampsAndSequence tests UTC nanosecond timestamps and per-run sequence numbers
+func TestEmitEvent_TimestampsAndSequence(t *testing.T) {
+	transport := &MockTransportWithTracking{responses: map[string]MockResponse{
+		"date '+%Z %z'": {stdout: "CET +0100\n"},
+		"echo a":        {stdout: "a\n"},
+	}}
+	executor := NewExecutor(transport)
+	var events []ExecutionEvent
+	executor.OnEvent = func(event ExecutionEvent) { events = append(events, event) }
+
+	executor.ExecutePlatform(Platform{InstallSteps: []InstallStep{
+		{Name: "a", Step: CommandStep{Command: "echo a"}},
+		{Name: "b", Step: CommandStep{Command: "echo a"}},
+	}}, Facts{})
+
+	if len(events) != 4 {
+		t.Fatalf("expected 4 events, got %d", len(events))
+	}
+	var last time.Time
+	for i, event := range events {
+		if event.Seq != int64(i+1) {
+			t.Errorf("event %d has seq %d", i, event.Seq)
+		}
+		ts, err := time.Parse(time.RFC3339Nano, event.Timestamp)
+		if err != nil || !strings.HasSuffix(event.Timestamp, "Z") {
+			t.Errorf("timestamp %q is not UTC RFC3339Nano: %v", event.Timestamp, err)
+		}
+		if ts.Before(last) {
+			t.Errorf("timestamp %s went backwards from %s", ts, last)
+		}
+		last = ts
+	}
+
+	if ctx := events[0].Context; ctx.Timezone != "CET" || ctx.UTCOffset != "+01:00" {
+		t.Errorf("context timezone = %q %q, want CET +01:00", ctx.Timezone, ctx.UTCOffset)
+	}
+}
+
+// TestExecuteCheckRemediate_ChildEvents tests that remediations emit events linked to their parent step
+func TestExecuteCheckRemediate_ChildEvents(t *testing.T) {
+	transport := &MockTransportWithTracking{responses: map[string]MockResponse{
+		"command -v jq":         {exitCode: 1},
+		"apt-get update":        {stdout: "updated\n"},
+		"apt-get install -y jq": {exitCode: 100, stderr: "E: locked"},
+	}}
+	executor := NewExecutor(transport)
+	var events []ExecutionEvent
+	executor.OnEvent = func(event ExecutionEvent) { events = append(events, event) }
+
+	executor.ExecuteStep(InstallStep{Name: "jq", Step: CheckRemediateStep{
+		Check: "command -v jq",
+		OnMissing: []RemediationStep{
+			{Name: "update", Command: "apt-get update"},
+			{Name: "install", Command: "apt-get install -y jq"},
+		},
+	}}, Facts{})
+
+	var got []string
+	for _, event := range events {
+		got = append(got, fmt.Sprintf("%s/%s:%d:%s", event.ParentStep, event.StepName, event.RemediationIndex, event.Status))
+	}
+	want := []string{
+		"/jq:0:running",
+		"jq/update:1:running",
+		"jq/update:1:success",
+		"jq/install:2:running",
+		"jq/install:2:failed",
+		"/jq:0:failed",
+	}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+	if failed := events[4]; failed.ExitCode == nil || *failed.ExitCode != 100 || !strings.Contains(failed.Error, "E: locked") {
+		t.Errorf("failed remediation event missing details: %+v", failed)
+	}
+}
+
+// TestExecuteStep_SeparateStdoutStderr tests that successful steps keep stderr apart from stdout
+func TestExecuteStep_SeparateStdoutStderr(t *testing.T) {
+	transport := &MockTransportWithTracking{responses: map[string]MockResponse{
+		"make build": {stdout: "built app\n", stderr: "compiling 12 files\n"},
+	}}
+	executor := NewExecutor(transport)
+	var events []ExecutionEvent
+	executor.OnEvent = func(event ExecutionEvent) { events = append(events, event) }
+
+	result := executor.ExecuteStep(InstallStep{Name: "build", Step: CommandStep{Command: "make build"}}, Facts{})
+
+	if result.Error != "" {
+		t.Fatalf("unexpected error: %s", result.Error)
+	}
+	if result.Stdout != "built app\n" || result.Stderr != "compiling 12 files\n" {
+		t.Errorf("result stdout/stderr = %q/%q", result.Stdout, result.Stderr)
+	}
+	if result.Output != "built app\n" {
+		t.Errorf("Output should stay stdout, got %q", result.Output)
+	}
+	completion := events[len(events)-1]
+	if completion.Status != "success" || completion.Stdout != "built app\n" || completion.Stderr != "compiling 12 files\n" {
+		t.Errorf("success event missing stdout/stderr: %+v", completion)
+	}
+}
+
+// TestExecuteCommand_SuccessExitCodes tests that listed non-zero exit codes succeed and keep the real code
+func TestExecuteCommand_SuccessExitCodes(t *testing.T) {
+	transport := &MockTransportWithTracking{responses: map[string]MockResponse{
+		"diff a b": {stdout: "< x\n> y\n", exitCode: 1},
+		"diff a c": {stderr: "diff: c: No such file or directory", exitCode: 2},
+	}}
+	executor := NewExecutor(transport)
+	var events []ExecutionEvent
+	executor.OnEvent = func(event ExecutionEvent) { events = append(events, event) }
+
+	var step InstallStep
+	if err := json.Unmarshal([]byte(`{"name": "compare", "command": "diff a b", "success_exit_codes": [0, 1]}`), &step); err != nil {
+		t.Fatalf("failed to parse step: %v", err)
+	}
+	result := executor.ExecuteStep(step, Facts{})
+	if result.Error != "" || result.ExitCode != 1 {
+		t.Errorf("expected success with exit code 1, got exit %d, error %q", result.ExitCode, result.Error)
+	}
+	if completion := events[len(events)-1]; completion.Status != "success" || completion.ExitCode == nil || *completion.ExitCode != 1 {
+		t.Errorf("success event should report exit code 1: %+v", completion)
+	}
+
+	result = executor.ExecuteStep(InstallStep{Name: "missing", Step: CommandStep{Command: "diff a c", SuccessExitCodes: []int{0, 1}}}, Facts{})
+	if !strings.Contains(result.Error, "command failed (exit 2)") {
+		t.Errorf("exit code 2 should fail, got %q", result.Error)
+	}
+}
+
+// TestExecuteCommand_RetryOn tests that retry_on retries transient failures and stops on permanent ones
+func TestExecuteCommand_RetryOn(t *testing.T) {
+	until := "until"
+	attempts := 0
+	transport := &StatefulMockTransport{
+		runFunc: func(cmd string) (string, string, int, error) {
+			if !strings.HasPrefix(cmd, "fetch-") {
+				return "", "", 0, nil
+			}
+			attempts++
+			switch {
+			case cmd == "fetch-index" && attempts == 1:
+				return "", "503 Service temporarily unavailable", 1, nil
+			case cmd == "fetch-index":
+				return "ok", "", 0, nil
+			default:
+				return "", "404 Not Found", 1, nil
+			}
+		},
+	}
+	executor := NewExecutor(transport)
+	retryOn := &RetryOn{ExitCodes: []int{75}, OutputMatches: "temporarily unavailable"}
+
+	result := executor.executeCommand("transient", CommandStep{Command: "fetch-index", Retry: &until, Timeout: []byte(`"10s"`), RetryOn: retryOn}, Facts{})
+	if result.Error != "" || attempts != 2 {
+		t.Errorf("expected success on attempt 2, got %d attempts, error %q", attempts, result.Error)
+	}
+
+	attempts = 0
+	started := time.Now()
+	result = executor.executeCommand("permanent", CommandStep{Command: "fetch-missing", Retry: &until, Timeout: []byte(`"10s"`), RetryOn: retryOn}, Facts{})
+	if attempts != 1 || time.Since(started) > time.Second {
+		t.Errorf("permanent failure should not be retried, got %d attempts in %s", attempts, time.Since(started))
+	}
+	if !strings.Contains(result.Error, "Permanent failure on attempt 1 (does not match retry_on)") || !strings.Contains(result.Error, "404 Not Found") {
+		t.Errorf("unexpected error: %q", result.Error)
+	}
+}
+
+// TestExecuteCommand_PollInterval tests that poll_interval sets the wait
+// between attempts and that the last wait stops at the deadline
+func TestExecuteCommand_PollInterval(t *testing.T) {
+	until := "until"
+	var times []time.Time
+	transport := &StatefulMockTransport{
+		runFunc: func(cmd string) (string, string, int, error) {
+			if cmd != "probe" {
+				return "", "", 0, nil
+			}
+			times = append(times, time.Now())
+			return "", "not ready", 1, nil
+		},
+	}
+	executor := NewExecutor(transport)
+
+	started := time.Now()
+	result := executor.executeCommand("ready", CommandStep{Command: "probe", Retry: &until, Timeout: []byte(`{"interval": "1s", "poll_interval": "400ms"}`)}, Facts{})
+	if result.Error == "" || len(times) != 3 {
+		t.Fatalf("expected 3 failed attempts, got %d, error %q", len(times), result.Error)
+	}
+	if gap := times[1].Sub(times[0]); gap < 400*time.Millisecond {
+		t.Errorf("attempts only %s apart", gap)
+	}
+	if elapsed := time.Since(started); elapsed > 1500*time.Millisecond {
+		t.Errorf("retry loop overran its 1s timeout: %s", elapsed)
+	}
+
+	if _, err := parsePollInterval([]byte(`{"interval": "1m", "poll_interval": "soon"}`)); err == nil {
+		t.Error("expected an invalid poll_interval to be rejected")
+	}
+	if interval, _ := parsePollInterval([]byte(`"1m"`)); interval != defaultPollInterval {
+		t.Errorf("expected the default poll interval, got %s", interval)
+	}
+}
+
+// TestExecuteStep_Verify tests that a command's verify post-condition decides the step result
+func TestExecuteStep_Verify(t *testing.T) {
+	transport := &MockTransportWithTracking{responses: map[string]MockResponse{
+		"install-jq":              {stdout: "installed\n"},
+		"jq --version":            {stdout: "jq-1.7\n"},
+		"install-yq":              {stdout: "installed\n"},
+		"yq --version":            {exitCode: 127, stderr: "yq: not found"},
+		"install-broken --silent": {exitCode: 1},
+	}}
+	executor := NewExecutor(transport)
+
+	result := executor.ExecuteStep(InstallStep{Name: "jq", Step: CommandStep{Command: "install-jq", Verify: "{{.tool}} --version"}}, Facts{"tool": "jq"})
+	if result.Error != "" || result.Output != "installed\n" {
+		t.Errorf("verified step should succeed with the command's output, got %+v", result)
+	}
+
+	result = executor.ExecuteStep(InstallStep{Name: "yq", Step: CommandStep{Command: "install-yq", Verify: "yq --version"}}, Facts{})
+	if result.Error != "command completed but verify fails: yq --version" {
+		t.Errorf("unexpected error: %q", result.Error)
+	}
+
+	transport.calls = nil
+	executor.ExecuteStep(InstallStep{Name: "broken", Step: CommandStep{Command: "install-broken --silent", Verify: "broken --version"}}, Facts{})
+	for _, call := range transport.calls {
+		if call == "broken --version" {
+			t.Error("verify should not run after the command failed")
+		}
+	}
+}
+
+// TestExecuteCommand_Stdin tests piping literal, templated and file input to commands
+func TestExecuteCommand_Stdin(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses cat")
+	}
+	executor := NewExecutor(NewLocalTransport())
+	manifest := "kind: Namespace\nname: {{.namespace}}\n"
+	result := executor.executeCommand("apply", CommandStep{Command: "cat", Stdin: &manifest}, Facts{"namespace": "apps"})
+	if result.Error != "" || result.Output != "kind: Namespace\nname: apps\n" {
+		t.Errorf("templated stdin: output %q, error %q", result.Output, result.Error)
+	}
+
+	keyFile := filepath.Join(t.TempDir(), "key.asc")
+	os.WriteFile(keyFile, []byte("-----BEGIN PGP PUBLIC KEY BLOCK-----\n"), 0644)
+	result = executor.executeCommand("import", CommandStep{Command: "wc -l", StdinFile: keyFile}, Facts{})
+	if result.Error != "" || strings.TrimSpace(result.Output) != "1" {
+		t.Errorf("stdin_file: output %q, error %q", result.Output, result.Error)
+	}
+
+	result = executor.executeCommand("missing", CommandStep{Command: "cat", StdinFile: filepath.Join(t.TempDir(), "missing.asc")}, Facts{})
+	if !strings.HasPrefix(result.Error, "stdin_file: ") {
+		t.Errorf("expected stdin_file error, got %q", result.Error)
+	}
+
+	// Transports that cannot send input fail the command rather than run it without
+	mock := NewExecutor(&MockTransport{responses: map[string]MockResponse{"cat": {stdout: ""}}})
+	if result := mock.executeCommand("apply", CommandStep{Command: "cat", Stdin: &manifest}, Facts{"namespace": "apps"}); !strings.Contains(result.Error, "cannot send stdin") {
+		t.Errorf("expected stdin support error, got %q", result.Error)
+	}
+}
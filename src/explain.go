@@ -0,0 +1,378 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// explainedStep is a step found by name, with where it lives in the config
+type explainedStep struct {
+	Place string // Platform (and distribution or group) holding the step
+	Step  InstallStep
+}
+
+// findSteps returns the steps named name in every platform, distribution
+// and group of config
+func findSteps(config *Config, name string) []explainedStep {
+	var found []explainedStep
+	var search func(place string, steps []InstallStep)
+	search = func(place string, steps []InstallStep) {
+		for _, step := range steps {
+			if step.Name == name {
+				found = append(found, explainedStep{Place: place, Step: step})
+			}
+			if group, ok := step.Step.(GroupStep); ok {
+				search(fmt.Sprintf("%s, group %s", place, step.Name), group.Group.Steps)
+			}
+		}
+	}
+	for _, platform := range config.Platforms {
+		place := fmt.Sprintf("%s (%s)", platform.Name, platform.OS)
+		search(place, platform.InstallSteps)
+		for _, dist := range platform.Distributions {
+			search(fmt.Sprintf("%s, %s", place, dist.Name), dist.InstallSteps)
+		}
+	}
+	return found
+}
+
+// stepNames lists the step names of config for "no step named" errors
+func stepNames(config *Config) []string {
+	seen := map[string]bool{}
+	var collect func(steps []InstallStep)
+	collect = func(steps []InstallStep) {
+		for _, step := range steps {
+			seen[step.Name] = true
+			if group, ok := step.Step.(GroupStep); ok {
+				collect(group.Group.Steps)
+			}
+		}
+	}
+	for _, platform := range config.Platforms {
+		collect(platform.InstallSteps)
+		for _, dist := range platform.Distributions {
+			collect(dist.InstallSteps)
+		}
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var (
+	// {{if .version}} reads the fact "version"
+	templateAction = regexp.MustCompile(`\{\{[^}]*\}\}`)
+	templateField  = regexp.MustCompile(`(?:^|[^\w.])\.([A-Za-z_]\w*)`)
+)
+
+// stepTemplate is a field of a step that is interpolated with facts
+type stepTemplate struct {
+	Field string // e.g. "check" or "on_missing[1] Add repository: command"
+	Text  string
+}
+
+// stepTemplates returns the templated fields of a step, its remediations
+// and its group members
+func stepTemplates(step InstallStep) []stepTemplate {
+	var fields []stepTemplate
+	add := func(field string, text *string) {
+		if text != nil && *text != "" {
+			fields = append(fields, stepTemplate{Field: field, Text: *text})
+		}
+	}
+	switch s := step.Step.(type) {
+	case CommandStep:
+		add("command", &s.Command)
+		add("stdin", s.Stdin)
+		add("stdin_file", &s.StdinFile)
+		add("verify", &s.Verify)
+	case CheckErrorStep:
+		add("check", &s.Check)
+		add("check_file", &s.File)
+		add("check_command_exists", &s.CommandExists)
+		add("expect_output", s.ExpectOutput)
+	case CheckRemediateStep:
+		add("check", &s.Check)
+		add("check_file", &s.File)
+		add("check_command_exists", &s.CommandExists)
+		add("expect_output", s.ExpectOutput)
+		for i, rem := range s.OnMissing {
+			add(fmt.Sprintf("on_missing[%d] %s: command", i+1, rem.Name), &rem.Command)
+		}
+	case CopyStep:
+		add("copy source", &s.Copy.Source)
+		add("copy destination", &s.Copy.Destination)
+	case FetchStep:
+		add("fetch source", &s.Fetch.Source)
+		add("fetch destination", &s.Fetch.Destination)
+	case VerifyChecksumStep:
+		add("verify_checksum path", &s.VerifyChecksum.Path)
+		add("verify_checksum sha256", &s.VerifyChecksum.SHA256)
+	case PauseStep:
+		add("prompt", &s.Pause.Prompt)
+	case GroupStep:
+		for i, member := range s.Group.Steps {
+			for _, field := range stepTemplates(member) {
+				field.Field = fmt.Sprintf("steps[%d] %s: %s", i+1, member.Name, field.Field)
+				fields = append(fields, field)
+			}
+		}
+	}
+	return fields
+}
+
+// stepFactRefs returns the facts a step reads in its templates or refreshes,
+// in order of first use. The reserved sink helpers and built-in facts are
+// not config facts.
+func stepFactRefs(step InstallStep) []string {
+	seen := map[string]bool{sinkFactsKey: true}
+	for name := range builtinFactDescriptions {
+		seen[name] = true
+	}
+	var refs []string
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			refs = append(refs, name)
+		}
+	}
+	for _, field := range stepTemplates(step) {
+		for _, action := range templateAction.FindAllString(field.Text, -1) {
+			for _, match := range templateField.FindAllStringSubmatch(action, -1) {
+				add(match[1])
+			}
+		}
+	}
+	for _, name := range step.RefreshFacts {
+		add(name)
+	}
+	return refs
+}
+
+// describeStepType names a step's variant for explain output
+func describeStepType(step InstallStep) string {
+	switch s := step.Step.(type) {
+	case CommandStep:
+		if s.Verify != "" {
+			return "command with verification"
+		}
+		return "command"
+	case CheckErrorStep:
+		return "check"
+	case CheckRemediateStep:
+		return "check with remediation"
+	case CopyStep:
+		return "copy to target"
+	case FetchStep:
+		return "fetch from target"
+	case VerifyChecksumStep:
+		return "verify checksum"
+	case PauseStep:
+		return "pause"
+	case GroupStep:
+		return fmt.Sprintf("group (policy %s)", s.Group.describePolicy())
+	case ErrorOnlyStep:
+		return "error"
+	}
+	return "unknown"
+}
+
+// writeIndented writes text (possibly multi-line) under a label
+func writeIndented(w io.Writer, indent, label, text string) {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	fmt.Fprintf(w, "%s%-12s %s\n", indent, label+":", lines[0])
+	for _, line := range lines[1:] {
+		fmt.Fprintf(w, "%s%-12s %s\n", indent, "", line)
+	}
+}
+
+// explainStep prints a step's description, what it runs and the facts it
+// uses in readable form
+func explainStep(w io.Writer, found explainedStep, facts map[string]FactDef) {
+	step := found.Step
+	fmt.Fprintf(w, "%s\n", step.Name)
+	fmt.Fprintf(w, "  %s\n\n", found.Place)
+	if step.Description != "" {
+		for _, line := range strings.Split(strings.TrimRight(step.Description, "\n"), "\n") {
+			fmt.Fprintf(w, "  %s\n", line)
+		}
+		fmt.Fprintln(w)
+	}
+
+	writeIndented(w, "  ", "Type", describeStepType(step))
+	switch s := step.Step.(type) {
+	case CommandStep:
+		writeIndented(w, "  ", "Command", s.Command)
+		if s.Verify != "" {
+			writeIndented(w, "  ", "Verify", s.Verify)
+		}
+		if s.Error != nil {
+			writeIndented(w, "  ", "On failure", *s.Error)
+		}
+	case CheckErrorStep:
+		writeIndented(w, "  ", "Check", describeCheck(s.Check, s.CheckProbe))
+		if s.ExpectOutput != nil {
+			writeIndented(w, "  ", "Expects", *s.ExpectOutput)
+		}
+		writeIndented(w, "  ", "On failure", s.Error)
+	case CheckRemediateStep:
+		writeIndented(w, "  ", "Check", describeCheck(s.Check, s.CheckProbe))
+		if s.ExpectOutput != nil {
+			writeIndented(w, "  ", "Expects", *s.ExpectOutput)
+		}
+	case CopyStep:
+		writeIndented(w, "  ", "Copy", fmt.Sprintf("%s → %s", s.Copy.Source, s.Copy.Destination))
+	case FetchStep:
+		writeIndented(w, "  ", "Fetch", fmt.Sprintf("%s → %s", s.Fetch.Source, s.Fetch.Destination))
+	case VerifyChecksumStep:
+		writeIndented(w, "  ", "Verify", fmt.Sprintf("%s has sha256 %s", s.VerifyChecksum.Path, s.VerifyChecksum.SHA256))
+	case PauseStep:
+		if s.Pause.Duration != "" {
+			writeIndented(w, "  ", "Wait", s.Pause.Duration)
+		}
+		if s.Pause.Prompt != "" {
+			writeIndented(w, "  ", "Prompt", s.Pause.Prompt)
+		}
+	case ErrorOnlyStep:
+		writeIndented(w, "  ", "Error", s.Error)
+	}
+	if step.Impact != "" {
+		writeIndented(w, "  ", "Impact", step.Impact)
+	}
+	if step.Risk != "" {
+		writeIndented(w, "  ", "Risk", step.Risk)
+	}
+	if step.Window != "" {
+		writeIndented(w, "  ", "Window", step.Window)
+	}
+	if step.Undo != "" {
+		writeIndented(w, "  ", "Undo", step.Undo)
+	}
+	if len(step.Annotations) > 0 {
+		keys := make([]string, 0, len(step.Annotations))
+		for key := range step.Annotations {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		labels := make([]string, len(keys))
+		for i, key := range keys {
+			labels[i] = key + "=" + step.Annotations[key]
+		}
+		writeIndented(w, "  ", "Annotations", strings.Join(labels, ", "))
+	}
+
+	if s, ok := step.Step.(CheckRemediateStep); ok {
+		fmt.Fprintf(w, "\n  If the check fails, remediate with:\n")
+		for i, rem := range s.OnMissing {
+			fmt.Fprintf(w, "    %d. %s\n", i+1, rem.Name)
+			if rem.Description != "" {
+				for _, line := range strings.Split(strings.TrimRight(rem.Description, "\n"), "\n") {
+					fmt.Fprintf(w, "       %s\n", line)
+				}
+			}
+			writeIndented(w, "       ", "Command", rem.Command)
+		}
+		fmt.Fprintf(w, "    then re-run the check\n")
+	}
+	if s, ok := step.Step.(GroupStep); ok {
+		fmt.Fprintf(w, "\n  Steps:\n")
+		for i, member := range s.Group.Steps {
+			fmt.Fprintf(w, "    %d. %s (%s)\n", i+1, member.Name, describeStepType(member))
+			if member.Description != "" {
+				fmt.Fprintf(w, "       %s\n", firstLine(member.Description))
+			}
+		}
+	}
+
+	if refs := stepFactRefs(step); len(refs) > 0 {
+		fmt.Fprintf(w, "\n  Facts:\n")
+		for _, name := range refs {
+			def, ok := facts[name]
+			switch {
+			case !ok:
+				fmt.Fprintf(w, "    %s (not defined in the config)\n", name)
+			case def.Description != "":
+				fmt.Fprintf(w, "    %s: %s\n", name, def.Description)
+			case def.Command != "":
+				fmt.Fprintf(w, "    %s: from `%s`\n", name, firstLine(def.Command))
+			default:
+				fmt.Fprintf(w, "    %s\n", name)
+			}
+		}
+	}
+}
+
+// explainCommand prints a readable explanation of one step of a config
+func explainCommand() {
+	var args []string
+	for _, arg := range os.Args[2:] {
+		switch {
+		case arg == "-h" || arg == "--help":
+			printExplainHelp()
+			os.Exit(0)
+		case strings.HasPrefix(arg, "-"):
+			fmt.Fprintf(os.Stderr, "Error: unexpected argument: %s\n", arg)
+			os.Exit(1)
+		default:
+			args = append(args, arg)
+		}
+	}
+	if len(args) != 2 {
+		fmt.Fprintf(os.Stderr, "Error: config file and step name required\n\n")
+		printExplainHelp()
+		os.Exit(1)
+	}
+
+	config, err := LoadConfig(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	found := findSteps(config, args[1])
+	if len(found) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: no step named '%s'\n", args[1])
+		fmt.Fprintf(os.Stderr, "Steps: %s\n", strings.Join(stepNames(config), ", "))
+		os.Exit(1)
+	}
+	for i, step := range found {
+		if i > 0 {
+			fmt.Println()
+		}
+		explainStep(os.Stdout, step, config.Facts)
+	}
+}
+
+func printExplainHelp() {
+	fmt.Printf(`sink explain - Explain a step of a configuration
+
+Usage:
+  sink explain <config> <step-name>
+
+Description:
+  Prints a step in readable form: its description, the command or check it
+  runs, its remediation chain and the facts it uses, with their own
+  descriptions. Nothing is executed.
+
+  Steps document themselves with an optional "description" field, which
+  remediation steps accept too. A step defined for several platforms or
+  distributions is explained once for each.
+
+Options:
+  -h, --help             Show this help message
+
+Arguments:
+  <config>               Path to configuration file
+  <step-name>            Name of the step to explain
+
+Examples:
+  sink explain install-config.json "Install Docker"
+`)
+}
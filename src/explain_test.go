@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestExplainStep tests explaining a step with remediations and facts
+func TestExplainStep(t *testing.T) {
+	config, err := parseConfigData([]byte(`{"version": "1.0.0",
+		"facts": {"arch": {"command": "uname -m", "description": "CPU architecture"}, "docker_version": {"command": "echo 27.1"}},
+		"platforms": [{"os": "linux", "match": "Linux", "name": "Linux", "distributions": [{"ids": ["ubuntu"], "name": "Ubuntu", "install_steps": [
+			{"name": "Install Docker", "description": "Docker runs the build agents", "risk": "high", "annotations": {"team": "ci"},
+			 "check": "docker --version | grep -q {{.docker_version}}",
+			 "on_missing": [{"name": "Install packages", "description": "From Docker's repository", "command": "apt-get install -y docker-ce={{.docker_version}}* # {{if eq .arch \"x86_64\"}}amd64{{end}}"}],
+			 "refresh_facts": ["docker_version"]}]}]}]}`), LoadOptions{Strict: true})
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+
+	found := findSteps(config, "Install Docker")
+	if len(found) != 1 || found[0].Place != "Linux (linux), Ubuntu" {
+		t.Fatalf("expected one step in Ubuntu, got %+v", found)
+	}
+	var out bytes.Buffer
+	explainStep(&out, found[0], config.Facts)
+	for _, want := range []string{
+		"Install Docker\n  Linux (linux), Ubuntu\n\n  Docker runs the build agents\n",
+		"  Type:        check with remediation\n  Check:       docker --version | grep -q {{.docker_version}}\n",
+		"  Risk:        high\n  Annotations: team=ci\n",
+		"    1. Install packages\n       From Docker's repository\n       Command:",
+		"  Facts:\n    docker_version: from `echo 27.1`\n    arch: CPU architecture\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected %q in:\n%s", want, out.String())
+		}
+	}
+	if len(findSteps(config, "Install docker")) != 0 || strings.Join(stepNames(config), ",") != "Install Docker" {
+		t.Errorf("expected exact name matching")
+	}
+}
+
+// TestStepFactRefs tests finding the facts a step's templates read
+func TestStepFactRefs(t *testing.T) {
+	stdin := "{{.token}}"
+	step := InstallStep{
+		Name:         "a",
+		RefreshFacts: []string{"version"},
+		Step: CommandStep{
+			Command: "install {{.version}} {{if .debug}}-v{{end}} {{.sink.os}} {{printf \"%s\" .arch}} {{.arch}}",
+			Stdin:   &stdin,
+		},
+	}
+	if refs := strings.Join(stepFactRefs(step), ","); refs != "version,debug,arch,token" {
+		t.Errorf("got %s", refs)
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Formats sink export can write
+const (
+	ExportFormatCloudInit = "cloud-init"
+)
+
+// releaseBinaryURLFormat is where a release's Linux binaries are published,
+// by version and architecture
+const releaseBinaryURLFormat = "https://github.com/radiolabme/sink/releases/download/v%s/sink-linux-%s"
+
+// Where the cloud-init document puts sink and an embedded config
+const (
+	cloudInitSinkPath   = "/usr/local/bin/sink"
+	cloudInitConfigPath = "/etc/sink/config.json"
+)
+
+// exportArchitectures are the Linux architectures releases are built for
+var exportArchitectures = []string{"amd64", "arm64"}
+
+// ExportOptions describes a document that delivers sink and a config to a
+// new machine and runs the config on first boot
+type ExportOptions struct {
+	Format     string
+	Config     string // Local config file, validated and checksummed
+	ConfigURL  string // Where the machine downloads the config; empty embeds it
+	SinkURL    string // sink binary to download (default: this release)
+	SinkSHA256 string // Expected checksum of the sink binary
+	Arch       string
+	Variables  map[string]string
+}
+
+// yamlQuote single-quotes a YAML scalar
+func yamlQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// configSHA256 is the checksum sink bootstrap --sha256 checks a config
+// against
+func configSHA256(config []byte) string {
+	sum := sha256.Sum256(config)
+	return hex.EncodeToString(sum[:])
+}
+
+// cloudInitUserData returns a #cloud-config document that downloads the
+// sink binary, checks it against opts.SinkSHA256, and runs sink bootstrap
+// of the config. A config downloaded from opts.ConfigURL is checked against
+// the checksum of config; without a URL, config is written by write_files
+// instead.
+func cloudInitUserData(opts ExportOptions, config []byte) string {
+	agent := AgentOptions{
+		Binary:       cloudInitSinkPath,
+		ConfigSource: opts.ConfigURL,
+		SHA256:       configSHA256(config),
+		Variables:    opts.Variables,
+	}
+	var b strings.Builder
+	b.WriteString("#cloud-config\n")
+	fmt.Fprintf(&b, "# Generated by sink %s from %s\n", Version, opts.Config)
+	if agent.ConfigSource == "" {
+		agent.ConfigSource, agent.SHA256 = cloudInitConfigPath, ""
+		fmt.Fprintf(&b, "write_files:\n")
+		fmt.Fprintf(&b, "  - path: %s\n", cloudInitConfigPath)
+		fmt.Fprintf(&b, "    permissions: '0600'\n")
+		fmt.Fprintf(&b, "    encoding: b64\n")
+		fmt.Fprintf(&b, "    content: %s\n", base64.StdEncoding.EncodeToString(config))
+	}
+
+	download := cloudInitSinkPath + ".download"
+	b.WriteString("runcmd:\n  - |\n    set -eu\n")
+	fmt.Fprintf(&b, "    curl -fsSL --retry 5 -o %s %s\n", download, shellQuote(opts.SinkURL))
+	fmt.Fprintf(&b, "    echo %s | sha256sum -c -\n", shellQuote(opts.SinkSHA256+"  "+download))
+	fmt.Fprintf(&b, "    chmod 755 %s\n", download)
+	fmt.Fprintf(&b, "    mv %s %s\n", download, cloudInitSinkPath)
+
+	args := agentBootstrapArgs(agent)
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = yamlQuote(arg)
+	}
+	fmt.Fprintf(&b, "  - [%s]\n", strings.Join(quoted, ", "))
+	return b.String()
+}
+
+// exportCommand handles sink export
+func exportCommand() {
+	opts := ExportOptions{Arch: "amd64", Variables: map[string]string{}}
+	output := ""
+
+	args := os.Args[2:]
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch arg {
+		case "-h", "--help":
+			printExportHelp()
+			os.Exit(0)
+		case "-o", "--output", "--format", "--config-url", "--sink-url", "--sink-sha256", "--arch", "--var":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: %s requires a value\n", arg)
+				os.Exit(1)
+			}
+			value := args[i+1]
+			i++
+			var err error
+			switch arg {
+			case "--format":
+				opts.Format = value
+			case "--config-url":
+				opts.ConfigURL = value
+			case "--sink-url":
+				opts.SinkURL = value
+			case "--sink-sha256":
+				opts.SinkSHA256 = strings.ToLower(value)
+				if !sha256Regex.MatchString(opts.SinkSHA256) {
+					err = fmt.Errorf("invalid --sink-sha256 '%s', must be 64 hex characters", value)
+				}
+			case "--arch":
+				opts.Arch = value
+			case "--var":
+				var name string
+				name, value, err = parseVarFlag(value)
+				opts.Variables[name] = value
+			default:
+				output = value
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		default:
+			if strings.HasPrefix(arg, "-") || opts.Config != "" {
+				fmt.Fprintf(os.Stderr, "Error: unexpected argument: %s\n", arg)
+				os.Exit(1)
+			}
+			opts.Config = arg
+		}
+	}
+
+	if opts.Config == "" || opts.Format == "" {
+		fmt.Fprintf(os.Stderr, "Error: --format and a config file are required\n\n")
+		printExportHelp()
+		os.Exit(1)
+	}
+	if opts.Format != ExportFormatCloudInit {
+		fmt.Fprintf(os.Stderr, "Error: unsupported --format '%s', must be: %s\n", opts.Format, ExportFormatCloudInit)
+		os.Exit(1)
+	}
+	if !containsString(exportArchitectures, opts.Arch) {
+		fmt.Fprintf(os.Stderr, "Error: unsupported --arch '%s', must be one of: %s\n", opts.Arch, strings.Join(exportArchitectures, ", "))
+		os.Exit(1)
+	}
+	if opts.SinkURL == "" {
+		opts.SinkURL = fmt.Sprintf(releaseBinaryURLFormat, Version, opts.Arch)
+	}
+	// The machine runs the binary as root, so it must be checked against a
+	// checksum taken by whoever exports, not fetched beside it
+	if opts.SinkSHA256 == "" {
+		fmt.Fprintf(os.Stderr, "Error: --sink-sha256 is required to verify %s on the machine\n", opts.SinkURL)
+		os.Exit(1)
+	}
+
+	if _, err := LoadConfig(opts.Config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s: %v\n", opts.Config, err)
+		os.Exit(1)
+	}
+	config, err := os.ReadFile(opts.Config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	userData := cloudInitUserData(opts, config)
+	if output == "" {
+		fmt.Print(userData)
+		return
+	}
+	if err := os.WriteFile(output, []byte(userData), 0600); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✅ Wrote %s (config sha256 %s)\n", output, configSHA256(config))
+}
+
+func printExportHelp() {
+	fmt.Print(`sink export - Generate a document that delivers sink to a new machine
+
+Usage:
+  sink export --format cloud-init <config> [options]
+
+Description:
+  Writes cloud-init user-data that, on the machine's first boot, downloads
+  the sink binary of this release (checked against --sink-sha256), then
+  runs "sink bootstrap <config> --sha256 <checksum> --json" as root. The
+  config is validated and its checksum pinned when the document is made.
+
+  With --config-url the machine downloads the config from there, so the
+  file must be published unchanged at that URL. Without it the config is
+  embedded in the document and written to /etc/sink/config.json. Files the
+  config copies from beside itself are not embedded; publish those and use
+  --config-url, or deliver a bundle (sink package).
+
+Options:
+  --format <format>      Document to write: cloud-init (required)
+  --config-url <url>     Where the machine downloads the config (default:
+                         embed it)
+  --sink-url <url>       sink binary to download (default: the v` + Version + `
+                         release for --arch)
+  --sink-sha256 <hash>   Expected SHA256 of the sink binary (required)
+  --arch <arch>          Machine architecture: amd64 or arm64 (default: amd64)
+  --var <name=value>     Set a config variable (repeatable)
+  -o, --output <file>    Write the document to a file (default: stdout)
+  -h, --help             Show this help message
+
+Examples:
+  sink export --format cloud-init config.json -o user-data.yaml \
+    --sink-sha256 $(sha256sum sink-linux-amd64 | cut -d' ' -f1)
+  sink export --format cloud-init config.json --arch arm64 \
+    --config-url https://configs.example.com/dev.json \
+    --sink-sha256 $(sha256sum sink-linux-arm64 | cut -d' ' -f1)
+`)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+// TestCloudInitUserData tests the user-data sink export writes, with the
+// config downloaded and with it embedded
+func TestCloudInitUserData(t *testing.T) {
+	config := []byte(`{"version": "1.0.0"}`)
+	sum := configSHA256(config)
+	opts := ExportOptions{
+		Format:     ExportFormatCloudInit,
+		Config:     "dev.json",
+		ConfigURL:  "https://configs.example.com/dev.json",
+		SinkURL:    "https://example.com/sink-linux-arm64",
+		SinkSHA256: strings.Repeat("ab", 32),
+		Variables:  map[string]string{"owner": "o'brien"},
+	}
+
+	userData := cloudInitUserData(opts, config)
+	for _, want := range []string{
+		"#cloud-config\n",
+		"    curl -fsSL --retry 5 -o /usr/local/bin/sink.download 'https://example.com/sink-linux-arm64'\n",
+		"    echo '" + opts.SinkSHA256 + "  /usr/local/bin/sink.download' | sha256sum -c -\n",
+		"  - ['/usr/local/bin/sink', 'bootstrap', 'https://configs.example.com/dev.json', '--json', '--sha256', '" + sum + "', '--var', 'owner=o''brien']\n",
+	} {
+		if !strings.Contains(userData, want) {
+			t.Errorf("user-data missing %q:\n%s", want, userData)
+		}
+	}
+	if strings.Contains(userData, "write_files") {
+		t.Errorf("expected a downloaded config not to be embedded:\n%s", userData)
+	}
+
+	opts.ConfigURL = ""
+	userData = cloudInitUserData(opts, config)
+	for _, want := range []string{
+		"    content: " + base64.StdEncoding.EncodeToString(config) + "\n",
+		"  - ['/usr/local/bin/sink', 'bootstrap', '/etc/sink/config.json', '--json', '--var', 'owner=o''brien']\n",
+		"    echo '" + opts.SinkSHA256 + "  /usr/local/bin/sink.download' | sha256sum -c -\n",
+	} {
+		if !strings.Contains(userData, want) {
+			t.Errorf("user-data missing %q:\n%s", want, userData)
+		}
+	}
+}
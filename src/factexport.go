@@ -0,0 +1,119 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Fact output formats (sink facts --output)
+const (
+	FactOutputJSON   = "json"   // One JSON object of fact names to typed values
+	FactOutputDotenv = "dotenv" // NAME=value lines for .env files
+	FactOutputShell  = "shell"  // export NAME='value' lines for eval
+	FactOutputGHA    = "gha"    // name=value lines for $GITHUB_OUTPUT
+)
+
+// dotenvBareRegex matches values a .env file can hold without quotes
+var dotenvBareRegex = regexp.MustCompile(`^[A-Za-z0-9_./:@%+,-]*$`)
+
+// parseFactOutput validates a --output value
+func parseFactOutput(value string) (string, error) {
+	switch value {
+	case FactOutputJSON, FactOutputDotenv, FactOutputShell, FactOutputGHA:
+		return value, nil
+	}
+	return "", fmt.Errorf("invalid --output '%s' (use json, dotenv, shell or gha)", value)
+}
+
+// factString formats a fact value the way it is exported to the environment
+func factString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// factVariable is the environment variable a fact is written as: its
+// "export" name, or its name in upper case
+func factVariable(name string, def FactDef) string {
+	if def.Export != "" {
+		return def.Export
+	}
+	return strings.ToUpper(name)
+}
+
+// writeFacts writes gathered facts in format, sorted by name. Secret facts
+// are left out; --output is for files and pipelines that outlive the run.
+func writeFacts(w io.Writer, format string, defs map[string]FactDef, facts Facts) error {
+	var names []string
+	for _, name := range sortedKeys(facts) {
+		if !defs[name].IsSecret() {
+			names = append(names, name)
+		}
+	}
+
+	if format == FactOutputJSON {
+		values := make(map[string]interface{}, len(names))
+		for _, name := range names {
+			values[name] = facts[name]
+		}
+		data, err := json.MarshalIndent(values, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	}
+
+	for _, name := range names {
+		value := factString(facts[name])
+		var line string
+		switch format {
+		case FactOutputDotenv:
+			line = fmt.Sprintf("%s=%s\n", factVariable(name, defs[name]), dotenvQuote(value))
+		case FactOutputShell:
+			line = fmt.Sprintf("export %s=%s\n", factVariable(name, defs[name]), shellQuote(value))
+		case FactOutputGHA:
+			line = githubOutput(name, value)
+		}
+		if _, err := io.WriteString(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dotenvQuote double-quotes a value for a .env file unless it needs none.
+// Newlines are escaped and $ is escaped so loaders do not expand it.
+func dotenvQuote(value string) string {
+	if dotenvBareRegex.MatchString(value) {
+		return value
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "\n", `\n`, "\r", `\r`)
+	return `"` + r.Replace(value) + `"`
+}
+
+// githubOutput formats one GitHub Actions output. Multi-line values use the
+// name<<delimiter form with a random delimiter, so a value cannot end the
+// block early and set other outputs.
+func githubOutput(name, value string) string {
+	if !strings.ContainsAny(value, "\r\n") {
+		return fmt.Sprintf("%s=%s\n", name, value)
+	}
+	b := make([]byte, 8)
+	rand.Read(b)
+	delimiter := "ghadelimiter_" + hex.EncodeToString(b)
+	return fmt.Sprintf("%s<<%s\n%s\n%s\n", name, delimiter, value, delimiter)
+}
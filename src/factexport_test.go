@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// TestWriteFacts tests writing facts in each --output format
+func TestWriteFacts(t *testing.T) {
+	defs := map[string]FactDef{
+		"os_name": {Command: "x", Export: "SINK_OS"},
+		"cpus":    {Command: "x", Type: "integer"},
+		"docker":  {Command: "x", Type: "boolean"},
+		"motd":    {Command: "x"},
+		"token":   {Source: "vault:secret/token"},
+	}
+	facts := Facts{"os_name": "Ubuntu 24.04", "cpus": int64(8), "docker": true, "motd": "it's $HOME\nline 2", "token": "s3cret"}
+
+	tests := []struct {
+		format, expected string
+	}{
+		{FactOutputDotenv, "CPUS=8\nDOCKER=true\nMOTD=\"it's \\$HOME\\nline 2\"\nSINK_OS=\"Ubuntu 24.04\"\n"},
+		{FactOutputShell, "export CPUS='8'\nexport DOCKER='true'\nexport MOTD='it'\\''s $HOME\nline 2'\nexport SINK_OS='Ubuntu 24.04'\n"},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		if err := writeFacts(&out, tt.format, defs, facts); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != tt.expected {
+			t.Errorf("%s:\n%s\nwant:\n%s", tt.format, out.String(), tt.expected)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := writeFacts(&out, FactOutputJSON, defs, facts); err != nil {
+		t.Fatal(err)
+	}
+	var values map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &values); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out.String(), err)
+	}
+	if values["cpus"] != float64(8) || values["docker"] != true || values["os_name"] != "Ubuntu 24.04" {
+		t.Errorf("expected typed values by fact name, got %v", values)
+	}
+
+	out.Reset()
+	if err := writeFacts(&out, FactOutputGHA, defs, facts); err != nil {
+		t.Fatal(err)
+	}
+	gha := regexp.MustCompile(`^cpus=8\ndocker=true\nmotd<<(ghadelimiter_[0-9a-f]{16})\nit's \$HOME\nline 2\n(ghadelimiter_[0-9a-f]{16})\nos_name=Ubuntu 24.04\n$`)
+	if m := gha.FindStringSubmatch(out.String()); m == nil || m[1] != m[2] {
+		t.Errorf("unexpected GitHub Actions output:\n%s", out.String())
+	}
+
+	for _, format := range []string{FactOutputJSON, FactOutputDotenv, FactOutputShell, FactOutputGHA} {
+		out.Reset()
+		writeFacts(&out, format, defs, facts)
+		if strings.Contains(out.String(), "s3cret") || strings.Contains(strings.ToLower(out.String()), "token") {
+			t.Errorf("%s: expected secret facts left out, got %s", format, out.String())
+		}
+	}
+
+	if _, err := parseFactOutput("yaml"); err == nil {
+		t.Error("expected an unknown format to be rejected")
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FactRecord is the facts a run gathered, kept per config under the state
+// directory so sink facts --diff-last can show what drifted since. Secret
+// facts are never recorded.
+type FactRecord struct {
+	Source   string            `json:"source"`
+	RunID    string            `json:"run_id,omitempty"`
+	Recorded string            `json:"recorded"` // UTC, RFC3339
+	Facts    map[string]string `json:"facts"`
+}
+
+// FactChange is a fact whose value differs from the recorded run
+type FactChange struct {
+	Name   string
+	Before string
+	After  string
+	Kind   string // "added", "removed" or "changed"
+}
+
+// factHistorySource identifies a config across runs: URLs as given, files
+// by absolute path
+func factHistorySource(source string) string {
+	if isURL(source) || source == "-" {
+		return source
+	}
+	if abs, err := filepath.Abs(source); err == nil {
+		return abs
+	}
+	return source
+}
+
+// factHistoryPath returns where the last facts of a config are recorded
+func factHistoryPath(source string) (string, error) {
+	sum := sha256.Sum256([]byte(factHistorySource(source)))
+	return statePath(filepath.Join("facts", hex.EncodeToString(sum[:8])+".json"))
+}
+
+// recordFacts replaces the recorded facts of a config with facts
+func recordFacts(source, runID string, defs map[string]FactDef, facts Facts, now time.Time) error {
+	path, err := factHistoryPath(source)
+	if err != nil {
+		return err
+	}
+	record := FactRecord{
+		Source:   factHistorySource(source),
+		RunID:    runID,
+		Recorded: now.UTC().Format(time.RFC3339),
+		Facts:    map[string]string{},
+	}
+	for name, value := range facts {
+		if def, ok := defs[name]; ok && !def.IsSecret() {
+			record.Facts[name] = fmt.Sprint(value)
+		}
+	}
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// loadFactRecord returns the recorded facts of a config, or nil when no run
+// has recorded any
+func loadFactRecord(source string) (*FactRecord, error) {
+	path, err := factHistoryPath(source)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var record FactRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &record, nil
+}
+
+// diffFacts compares the recorded facts with the current ones, by name, and
+// counts the facts that did not change. Secret facts are left out, as they
+// are never recorded.
+func diffFacts(previous map[string]string, defs map[string]FactDef, current Facts) (changes []FactChange, unchanged int) {
+	now := map[string]string{}
+	for name, value := range current {
+		if def, ok := defs[name]; ok && !def.IsSecret() {
+			now[name] = fmt.Sprint(value)
+		}
+	}
+	for _, name := range sortedKeys(now) {
+		before, ok := previous[name]
+		switch {
+		case !ok:
+			changes = append(changes, FactChange{Name: name, After: now[name], Kind: "added"})
+		case before != now[name]:
+			changes = append(changes, FactChange{Name: name, Before: before, After: now[name], Kind: "changed"})
+		default:
+			unchanged++
+		}
+	}
+	for _, name := range sortedKeys(previous) {
+		if _, ok := now[name]; !ok {
+			changes = append(changes, FactChange{Name: name, Before: previous[name], Kind: "removed"})
+		}
+	}
+	return changes, unchanged
+}
+
+// printFactDiff reports the changes since a recorded run
+func printFactDiff(w io.Writer, record *FactRecord, changes []FactChange, unchanged int) {
+	since := record.Recorded
+	if record.RunID != "" {
+		since += " (run " + record.RunID + ")"
+	}
+	if len(changes) == 0 {
+		fmt.Fprintf(w, "✅ No fact changed since %s\n", since)
+		return
+	}
+	fmt.Fprintf(w, "Facts changed since %s:\n", since)
+	for _, change := range changes {
+		switch change.Kind {
+		case "added":
+			fmt.Fprintf(w, "  + %s: %s (new)\n", change.Name, change.After)
+		case "removed":
+			fmt.Fprintf(w, "  - %s: %s (no longer gathered)\n", change.Name, change.Before)
+		default:
+			fmt.Fprintf(w, "  ~ %s: %s → %s\n", change.Name, change.Before, change.After)
+		}
+	}
+	fmt.Fprintf(w, "%d changed, %d unchanged\n", len(changes), unchanged)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestFactHistory tests recording a run's facts and reading them back
+func TestFactHistory(t *testing.T) {
+	t.Setenv(EnvStateDir, t.TempDir())
+	defs := map[string]FactDef{
+		"os_version": {Command: "uname -r"},
+		"token":      {Command: "cat token", Secret: true},
+	}
+
+	if record, err := loadFactRecord("config.json"); err != nil || record != nil {
+		t.Fatalf("expected no record before the first run, got %+v, %v", record, err)
+	}
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	facts := Facts{"os_version": "6.1", "token": "hunter2", "region": "eu"} // region is a variable
+	if err := recordFacts("config.json", "run-1", defs, facts, now); err != nil {
+		t.Fatal(err)
+	}
+	record, err := loadFactRecord("./config.json")
+	if err != nil || record == nil {
+		t.Fatalf("expected the record, got %v", err)
+	}
+	if record.RunID != "run-1" || record.Recorded != "2026-10-01T12:00:00Z" || len(record.Facts) != 1 || record.Facts["os_version"] != "6.1" {
+		t.Errorf("unexpected record %+v", record)
+	}
+	if other, _ := loadFactRecord("other.json"); other != nil {
+		t.Error("expected records to be kept per config")
+	}
+}
+
+// TestDiffFacts tests classifying fact changes between runs
+func TestDiffFacts(t *testing.T) {
+	defs := map[string]FactDef{
+		"os_version": {Command: "uname -r"},
+		"arch":       {Command: "uname -m"},
+		"has_podman": {Command: "command -v podman", Type: "boolean"},
+		"token":      {Source: "vault:secret/app#token"},
+	}
+	previous := map[string]string{"os_version": "22.04", "arch": "x86_64", "docker_version": "24.0.5"}
+	current := Facts{"os_version": "24.04", "arch": "x86_64", "has_podman": true, "token": "s3cret"}
+
+	changes, unchanged := diffFacts(previous, defs, current)
+	if unchanged != 1 {
+		t.Errorf("expected arch to be unchanged, got %d unchanged", unchanged)
+	}
+	var got []string
+	for _, change := range changes {
+		got = append(got, change.Kind+" "+change.Name)
+	}
+	if strings.Join(got, ", ") != "added has_podman, changed os_version, removed docker_version" {
+		t.Errorf("unexpected changes: %v", got)
+	}
+
+	var out bytes.Buffer
+	printFactDiff(&out, &FactRecord{Recorded: "2026-10-01T12:00:00Z", RunID: "run-1"}, changes, unchanged)
+	for _, want := range []string{
+		"since 2026-10-01T12:00:00Z (run run-1)",
+		"+ has_podman: true (new)",
+		"~ os_version: 22.04 → 24.04",
+		"- docker_version: 24.0.5 (no longer gathered)",
+		"3 changed, 1 unchanged",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected %q in:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "s3cret") {
+		t.Error("secret fact value printed")
+	}
+
+	out.Reset()
+	printFactDiff(&out, &FactRecord{Recorded: "2026-10-01T12:00:00Z"}, nil, 4)
+	if !strings.Contains(out.String(), "No fact changed since 2026-10-01T12:00:00Z") {
+		t.Errorf("unexpected output %q", out.String())
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Transport abstracts command execution (local or SSH)
@@ -34,119 +35,256 @@ func (fg *FactGatherer) Gather() (Facts, error) {
 	facts := make(Facts)
 
 	for name, def := range fg.definitions {
-		// Skip if platform-specific and doesn't match current OS
-		if len(def.Platforms) > 0 {
-			matched := false
-			for _, platform := range def.Platforms {
-				if platform == fg.currentOS {
-					matched = true
-					break
-				}
-			}
-			if !matched {
-				continue
-			}
+		if !fg.appliesToOS(def) {
+			continue
+		}
+		value, ok, err := fg.gatherFact(name, def)
+		if err != nil {
+			return nil, err
 		}
+		if ok {
+			facts[name] = value
+		}
+	}
+
+	return facts, nil
+}
 
-		// Log fact gathering in verbose mode (use global verbose or step-specific)
-		verbose := fg.Verbose || def.Verbose
-		if verbose {
-			verboseLog("Gathering fact '%s': %s", name, def.Command)
+// Refresh re-gathers the named facts and returns a copy of facts with their
+// new values. An optional fact that can no longer be gathered is removed.
+func (fg *FactGatherer) Refresh(facts Facts, names []string) (Facts, error) {
+	refreshed := make(Facts, len(facts))
+	for name, value := range facts {
+		refreshed[name] = value
+	}
+
+	for _, name := range names {
+		def, ok := fg.definitions[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown fact '%s'", name)
+		}
+		if !fg.appliesToOS(def) {
+			continue
+		}
+		value, ok, err := fg.gatherFact(name, def)
+		if err != nil {
+			return nil, err
 		}
+		if ok {
+			refreshed[name] = value
+		} else {
+			delete(refreshed, name)
+		}
+	}
 
-		// Run the command with timeout support
-		stdout, stderr, exitCode, err := fg.runFactCommand(name, def)
+	return refreshed, nil
+}
 
-		if verbose {
-			verboseLog("Fact '%s' exit code: %d", name, exitCode)
-			if stdout != "" {
-				verboseLog("Fact '%s' stdout: %s", name, stdout)
-			}
-			if stderr != "" {
-				verboseLog("Fact '%s' stderr: %s", name, stderr)
+// withDeclared returns facts with the zero value of its type for each
+// declared fact that has no value: an optional fact that could not be
+// gathered, or one for another platform. facts is returned unchanged when
+// every declared fact has a value.
+func (fg *FactGatherer) withDeclared(facts Facts) Facts {
+	var filled Facts
+	for name, def := range fg.definitions {
+		if _, ok := facts[name]; ok {
+			continue
+		}
+		if filled == nil {
+			filled = make(Facts, len(facts)+len(fg.definitions))
+			for k, v := range facts {
+				filled[k] = v
 			}
 		}
+		filled[name] = zeroFactValue(def.Type)
+	}
+	if filled == nil {
+		return facts
+	}
+	return filled
+}
 
-		// Apply sleep if specified
-		if sleepErr := applySleep(def.Sleep, verbose); sleepErr != nil {
-			if def.Required {
-				return nil, fmt.Errorf("fact '%s' sleep error: %w", name, sleepErr)
-			}
-			// Skip optional fact with sleep error
-			continue
+// zeroFactValue is the value of a declared fact that was not gathered
+func zeroFactValue(typ string) interface{} {
+	switch typ {
+	case "boolean":
+		return false
+	case "integer":
+		return int64(0)
+	}
+	return ""
+}
+
+// appliesToOS reports whether a fact is gathered on the current platform
+func (fg *FactGatherer) appliesToOS(def FactDef) bool {
+	if len(def.Platforms) == 0 {
+		return true
+	}
+	for _, platform := range def.Platforms {
+		if platform == fg.currentOS {
+			return true
+		}
+	}
+	return false
+}
+
+// gatherFact runs one fact's command. It returns ok=false for an optional
+// fact that could not be gathered, and an error for a required one.
+func (fg *FactGatherer) gatherFact(name string, def FactDef) (interface{}, bool, error) {
+	value, err := fg.probeFact(name, def)
+	if err != nil {
+		if def.Required {
+			return nil, false, fmt.Errorf("required %w", err)
 		}
+		// Skip optional facts that could not be gathered
+		return nil, false, nil
+	}
+	return value, true, nil
+}
 
-		// Handle failures based on Required flag
-		if err != nil || exitCode != 0 {
-			if def.Required {
-				return nil, fmt.Errorf("required fact '%s' failed: %w", name, err)
-			}
-			// Skip optional failed facts
-			continue
+// factCommand returns the command a fact runs: its own, or the secret
+// provider's CLI command for a source
+func factCommand(def FactDef) string {
+	if def.Source != "" {
+		if source, err := ParseSecretSource(def.Source); err == nil {
+			return source.Command()
+		}
+	}
+	return def.Command
+}
+
+// probeFact runs one fact's command and returns its typed value, or why it
+// could not be gathered whether or not the fact is required
+func (fg *FactGatherer) probeFact(name string, def FactDef) (interface{}, error) {
+	// Secret sources are fetched with the provider's CLI
+	command := def.Command
+	var source SecretSource
+	if def.Source != "" {
+		var err error
+		if source, err = ParseSecretSource(def.Source); err != nil {
+			return nil, fmt.Errorf("fact '%s': %w", name, err)
 		}
+		command = source.Command()
+	}
 
-		// Trim output
-		value := strings.TrimSpace(stdout)
+	// Log fact gathering in verbose mode (use global verbose or step-specific)
+	verbose := fg.Verbose || def.Verbose
+	if verbose {
+		verboseLog("Gathering fact '%s': %s", name, command)
+	}
 
-		// Apply transform if specified
-		if def.Transform != nil {
-			transformed, err := applyTransform(value, def.Transform, def.Strict)
-			if err != nil {
-				if def.Required {
-					return nil, fmt.Errorf("fact '%s' transform failed: %w", name, err)
-				}
-				continue
-			}
-			value = transformed
+	// Run the command with timeout support
+	stdout, stderr, exitCode, err := fg.runFactCommand(name, command)
+
+	if verbose {
+		verboseLog("Fact '%s' exit code: %d", name, exitCode)
+		if stdout != "" && !def.IsSecret() {
+			verboseLog("Fact '%s' stdout: %s", name, stdout)
+		}
+		if stderr != "" {
+			verboseLog("Fact '%s' stderr: %s", name, stderr)
 		}
+	}
+
+	// Apply sleep if specified
+	if sleepErr := applySleep(def.Sleep, verbose); sleepErr != nil {
+		return nil, fmt.Errorf("fact '%s' sleep error: %w", name, sleepErr)
+	}
 
-		// Coerce to the specified type
-		typedValue, err := coerceType(value, def.Type)
+	if err != nil {
+		return nil, fmt.Errorf("fact '%s' failed: %w", name, err)
+	}
+	if exitCode != 0 {
+		what := "command"
+		if def.Source != "" {
+			what = def.Source
+		}
+		return nil, fmt.Errorf("fact '%s' failed: %s exited %d: %s", name, what, exitCode, strings.TrimSpace(stderr))
+	}
+
+	// Trim output (secrets keep their whitespace, only the newline is dropped)
+	value := strings.TrimSpace(stdout)
+	if def.Source != "" {
+		if value, err = source.Extract(stdout); err != nil {
+			return nil, fmt.Errorf("fact '%s': %w", name, err)
+		}
+	}
+
+	// Apply transform if specified
+	if def.Transform != nil {
+		transformed, err := applyTransform(value, def.Transform, def.Strict)
 		if err != nil {
-			if def.Required {
-				return nil, fmt.Errorf("fact '%s' type coercion failed: %w", name, err)
-			}
-			continue
+			return nil, fmt.Errorf("fact '%s' transform failed: %w", name, err)
 		}
+		value = transformed
+	}
 
-		facts[name] = typedValue
+	// Coerce to the specified type
+	typedValue, err := coerceType(value, def.Type)
+	if err != nil {
+		return nil, fmt.Errorf("fact '%s' type coercion failed: %w", name, err)
 	}
 
-	return facts, nil
+	return typedValue, nil
+}
+
+// FactReport is the outcome of gathering one fact, for inspecting facts
+// individually rather than failing on the first required one
+type FactReport struct {
+	Name     string
+	Def      FactDef
+	Command  string        // Command run (the provider's CLI for a secret source)
+	Value    interface{}   // Typed value; nil when not gathered
+	Err      error         // Why the fact could not be gathered
+	Skipped  bool          // The fact does not apply to this platform
+	Duration time.Duration // Time taken to gather the fact
+}
+
+// Inspect gathers one fact and reports its value or error
+func (fg *FactGatherer) Inspect(name string) FactReport {
+	def := fg.definitions[name]
+	report := FactReport{Name: name, Def: def, Command: factCommand(def)}
+	if !fg.appliesToOS(def) {
+		report.Skipped = true
+		return report
+	}
+	start := time.Now()
+	report.Value, report.Err = fg.probeFact(name, def)
+	report.Duration = time.Since(start)
+	return report
+}
+
+// InspectAll gathers every fact, in name order, and reports each outcome
+func (fg *FactGatherer) InspectAll() []FactReport {
+	reports := make([]FactReport, 0, len(fg.definitions))
+	for _, name := range sortedKeys(fg.definitions) {
+		reports = append(reports, fg.Inspect(name))
+	}
+	return reports
 }
 
 // runFactCommand runs a fact-gathering command with timeout support
-func (fg *FactGatherer) runFactCommand(name string, def FactDef) (stdout, stderr string, exitCode int, err error) {
+func (fg *FactGatherer) runFactCommand(name string, command string) (stdout, stderr string, exitCode int, err error) {
 	// For now, we don't implement custom timeout handling for facts
 	// This would require wrapping the transport Run() call with timeout logic
 	// TODO: Implement timeout support for fact gathering
-	return fg.transport.Run(def.Command)
+	return fg.transport.Run(command)
 }
 
-// Export converts facts to environment variable format
+// Export converts facts to environment variable format. Secret facts are
+// left out, as validation rejects export on them: over SSH the exports
+// become part of the command line, where any user on either host can read
+// them with ps.
 func (fg *FactGatherer) Export(facts Facts) []string {
 	var exports []string
 
 	for name, value := range facts {
 		def, ok := fg.definitions[name]
-		if !ok || def.Export == "" {
+		if !ok || def.Export == "" || def.IsSecret() {
 			continue
 		}
 
-		// Convert value to string
-		var strValue string
-		switch v := value.(type) {
-		case string:
-			strValue = v
-		case bool:
-			strValue = strconv.FormatBool(v)
-		case int64:
-			strValue = strconv.FormatInt(v, 10)
-		default:
-			strValue = fmt.Sprintf("%v", v)
-		}
-
-		exports = append(exports, fmt.Sprintf("%s=%s", def.Export, strValue))
+		exports = append(exports, fmt.Sprintf("%s=%s", def.Export, factString(value)))
 	}
 
 	return exports
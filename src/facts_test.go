@@ -219,13 +219,15 @@ func TestFactExport(t *testing.T) {
 		"os":       "darwin",
 		"arch":     "amd64",
 		"has_brew": true,
+		"token":    "s3cret",
 	}
 
 	factDefs := map[string]FactDef{
 		"os":        {Export: "SINK_OS"},
 		"arch":      {Export: "SINK_ARCH"},
 		"has_brew":  {Export: "SINK_HAS_BREW"},
-		"no_export": {Export: ""}, // Should not be exported
+		"no_export": {Export: ""},                                                 // Should not be exported
+		"token":     {Source: "vault:secret/data/app#token", Export: "API_TOKEN"}, // Secret, should not be exported
 	}
 
 	gatherer := &FactGatherer{definitions: factDefs}
@@ -341,6 +343,54 @@ func TestFactOutputTrimming(t *testing.T) {
 	}
 }
 
+// TestFactInspect tests that inspecting facts reports why optional facts were not gathered
+func TestFactInspect(t *testing.T) {
+	mockTransport := &MockTransport{
+		responses: map[string]MockResponse{
+			"uname -s":      {stdout: "Linux\n"},
+			"docker --help": {stderr: "docker: not found\n", exitCode: 127},
+		},
+	}
+	gatherer := NewFactGatherer(map[string]FactDef{
+		"os":         {Command: "uname -s"},
+		"docker":     {Command: "docker --help"},
+		"mac_only":   {Command: "sw_vers", Platforms: []string{"darwin"}},
+		"vault_pass": {Source: "vault:secret/ci#password"},
+	}, mockTransport)
+	gatherer.currentOS = "linux"
+
+	reports := gatherer.InspectAll()
+	if len(reports) != 4 {
+		t.Fatalf("expected 4 reports, got %d", len(reports))
+	}
+	byName := map[string]FactReport{}
+	for _, report := range reports {
+		byName[report.Name] = report
+	}
+
+	if r := byName["os"]; r.Err != nil || r.Value != "Linux" || r.Command != "uname -s" {
+		t.Errorf("unexpected os report %+v", r)
+	}
+	if r := byName["docker"]; r.Err == nil || !strings.Contains(r.Err.Error(), "command exited 127: docker: not found") {
+		t.Errorf("expected optional fact failure to be reported, got %+v", r)
+	}
+	if r := byName["mac_only"]; !r.Skipped || r.Err != nil {
+		t.Errorf("expected mac_only to be skipped, got %+v", r)
+	}
+	if r := byName["vault_pass"]; !strings.HasPrefix(r.Command, "vault kv get") {
+		t.Errorf("expected the provider command for a secret source, got %q", r.Command)
+	}
+
+	// Gather still skips the optional fact rather than failing
+	facts, err := gatherer.Gather()
+	if err != nil {
+		t.Fatalf("Gather() failed: %v", err)
+	}
+	if _, ok := facts["docker"]; ok {
+		t.Error("expected failed optional fact to be skipped")
+	}
+}
+
 // MockTransport is a mock implementation of Transport for testing
 type MockTransport struct {
 	responses map[string]MockResponse
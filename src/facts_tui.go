@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// Fact explorer layout
+const (
+	explorerMaxName     = 30 // Widest name column
+	explorerDetailLines = 9  // Separator plus the selected fact's details
+)
+
+// factExplorer is the state of "sink facts --tui": every fact's latest
+// outcome and the selected row
+type factExplorer struct {
+	title    string
+	gatherer *FactGatherer
+	reports  []FactReport
+	selected int
+	message  string // Shown in the footer after a re-run
+}
+
+// runFactExplorer shows the fact explorer until the user quits
+func runFactExplorer(title string, gatherer *FactGatherer) error {
+	if !isTerminal(os.Stdin) || !isTerminal(os.Stdout) {
+		return fmt.Errorf("--tui needs a terminal")
+	}
+	restore, ok := rawTerminal()
+	if !ok {
+		return fmt.Errorf("--tui cannot put the terminal in raw mode (stty unavailable)")
+	}
+	defer restore()
+	fmt.Print(ansiAltScreen + ansiHideCursor)
+	defer fmt.Print(ansiShowCursor + ansiMainScreen)
+
+	x := &factExplorer{title: title, gatherer: gatherer, message: "Gathering facts..."}
+	rows, cols := terminalSize()
+	fmt.Print(x.render(rows, cols))
+	x.reports = gatherer.InspectAll()
+	x.message = ""
+
+	in := bufio.NewReader(os.Stdin)
+	for {
+		rows, cols = terminalSize()
+		fmt.Print(x.render(rows, cols))
+		key, err := readKey(in)
+		if err != nil {
+			return nil
+		}
+		// Re-runs can take a while; say so before blocking on them
+		if key == "r" || key == "enter" || key == "a" {
+			x.message = "Running..."
+			fmt.Print(x.render(rows, cols))
+		}
+		if !x.handleKey(key) {
+			return nil
+		}
+	}
+}
+
+// handleKey applies one key press, reporting false to quit
+func (x *factExplorer) handleKey(key string) bool {
+	switch key {
+	case "up", "k":
+		if x.selected > 0 {
+			x.selected--
+		}
+	case "down", "j":
+		if x.selected < len(x.reports)-1 {
+			x.selected++
+		}
+	case "r", "enter":
+		if len(x.reports) == 0 {
+			break
+		}
+		report := x.gatherer.Inspect(x.reports[x.selected].Name)
+		x.reports[x.selected] = report
+		x.message = fmt.Sprintf("Re-ran %s: %s", report.Name, factReportStatus(report))
+	case "a":
+		x.reports = x.gatherer.InspectAll()
+		x.message = fmt.Sprintf("Re-ran %d facts", len(x.reports))
+	case "q", "esc", "ctrl-c":
+		return false
+	}
+	return true
+}
+
+// render draws the whole screen: a header with counts, the fact list
+// (scrolled to keep the selection visible), the selected fact's details
+// and a key help footer
+func (x *factExplorer) render(rows, cols int) string {
+	var b strings.Builder
+	line := func(text string) {
+		b.WriteString(truncateRunes(text, cols) + ansiClearLine + "\r\n")
+	}
+
+	failed, skipped := 0, 0
+	nameWidth := len("NAME")
+	for _, report := range x.reports {
+		switch {
+		case report.Skipped:
+			skipped++
+		case report.Err != nil:
+			failed++
+		}
+		nameWidth = max(nameWidth, min(utf8.RuneCountInString(report.Name), explorerMaxName))
+	}
+
+	b.WriteString(ansiHome)
+	line(fmt.Sprintf(" sink facts — %s   %d facts · %d failed · %d skipped", x.title, len(x.reports), failed, skipped))
+	line("")
+	line(fmt.Sprintf("   %-2s %-*s  %-8s  %s", "", nameWidth, "NAME", "TYPE", "VALUE"))
+
+	// Rows left for the list once the header, details and footer are drawn
+	visible := max(rows-3-explorerDetailLines-1, 1)
+	offset := max(x.selected-visible+1, 0)
+	for i := offset; i < len(x.reports) && i < offset+visible; i++ {
+		report := x.reports[i]
+		marker := " "
+		if i == x.selected {
+			marker = ">"
+		}
+		row := fmt.Sprintf(" %s %-2s %-*s  %-8s  %s", marker, factReportIcon(report),
+			nameWidth, truncateRunes(report.Name, explorerMaxName), factType(report.Def), factReportValue(report))
+		if i == x.selected {
+			b.WriteString(ansiReverse + truncateRunes(row, cols) + ansiReset + ansiClearLine + "\r\n")
+			continue
+		}
+		line(row)
+	}
+	for i := len(x.reports) - offset; i < visible; i++ {
+		line("")
+	}
+
+	b.WriteString(ansiDim + strings.Repeat("─", cols) + ansiReset + ansiClearLine + "\r\n")
+	details := make([]string, explorerDetailLines-1)
+	if len(x.reports) > 0 {
+		details = factReportDetails(x.reports[x.selected], explorerDetailLines-1)
+	}
+	for _, detail := range details {
+		line(detail)
+	}
+
+	footer := " ↑/↓ select · r re-run · a re-run all · q quit"
+	if x.message != "" {
+		footer += "   " + x.message
+	}
+	b.WriteString(truncateRunes(footer, cols) + ansiClearLine + ansiClearDown)
+	return b.String()
+}
+
+// factReportIcon marks a fact gathered (✓), failed (✗) or not applicable (–)
+func factReportIcon(report FactReport) string {
+	switch {
+	case report.Skipped:
+		return "–"
+	case report.Err != nil:
+		return "✗"
+	}
+	return "✓"
+}
+
+// factReportStatus describes a fact's outcome in a few words
+func factReportStatus(report FactReport) string {
+	switch {
+	case report.Skipped:
+		return "not gathered on this platform"
+	case report.Err != nil:
+		return "failed"
+	}
+	return fmt.Sprintf("%s (%s)", displayFactValue(report.Def, report.Value), formatTranscriptDuration(report.Duration))
+}
+
+// factReportValue is the value column: the value, or why there is none
+func factReportValue(report FactReport) string {
+	switch {
+	case report.Skipped:
+		return "(not on this platform)"
+	case report.Err != nil:
+		return "(error)"
+	}
+	return firstLine(displayFactValue(report.Def, report.Value))
+}
+
+// factType is a fact's declared type, defaulting to string
+func factType(def FactDef) string {
+	if def.Type == "" {
+		return "string"
+	}
+	return def.Type
+}
+
+// factReportDetails lists a fact's definition and outcome, padded or cut
+// to n lines
+func factReportDetails(report FactReport, n int) []string {
+	details := []string{
+		" Name:        " + report.Name,
+		" Command:     " + firstLine(report.Command),
+		" Type:        " + factType(report.Def),
+	}
+	switch {
+	case report.Skipped:
+		details = append(details, " Platforms:   "+strings.Join(report.Def.Platforms, ", "))
+	case report.Err != nil:
+		details = append(details, " Error:       "+firstLine(report.Err.Error()))
+	default:
+		details = append(details, " Value:       "+firstLine(displayFactValue(report.Def, report.Value)))
+		details = append(details, " Took:        "+formatTranscriptDuration(report.Duration))
+	}
+	if report.Def.Required {
+		details = append(details, " Required:    yes")
+	}
+	if report.Def.Export != "" {
+		details = append(details, " Export:      "+report.Def.Export)
+	}
+	if report.Def.Description != "" {
+		details = append(details, " Description: "+report.Def.Description)
+	}
+
+	for len(details) < n {
+		details = append(details, "")
+	}
+	return details[:n]
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestFactExplorer tests selecting, re-running and rendering facts
+func TestFactExplorer(t *testing.T) {
+	transport := &MockTransport{responses: map[string]MockResponse{
+		"uname -s":         {stdout: "Linux\n"},
+		"docker --version": {stderr: "docker: not found\n", exitCode: 127},
+	}}
+	gatherer := NewFactGatherer(map[string]FactDef{
+		"os":     {Command: "uname -s", Description: "Kernel name"},
+		"docker": {Command: "docker --version", Export: "DOCKER_VERSION"},
+		"token":  {Command: "cat token", Secret: true},
+	}, transport)
+	x := &factExplorer{title: "config.json", gatherer: gatherer, reports: gatherer.InspectAll()}
+
+	screen := x.render(24, 100)
+	for _, want := range []string{
+		"sink facts — config.json   3 facts · 2 failed · 0 skipped",
+		" > ✗  docker  string    (error)",
+		"   ✓  os      string    Linux",
+		" Error:       fact 'docker' failed: command exited 127: docker: not found",
+		" Export:      DOCKER_VERSION",
+	} {
+		if !strings.Contains(screen, want) {
+			t.Errorf("screen missing %q:\n%s", want, screen)
+		}
+	}
+
+	// The fact starts working; re-running it picks up the new value
+	transport.responses["docker --version"] = MockResponse{stdout: "Docker version 27.0\n"}
+	x.handleKey("r")
+	if x.reports[0].Err != nil || x.reports[0].Value != "Docker version 27.0" {
+		t.Errorf("expected re-run to gather docker, got %+v", x.reports[0])
+	}
+	if !strings.Contains(x.message, "Re-ran docker: Docker version 27.0") {
+		t.Errorf("unexpected message %q", x.message)
+	}
+
+	x.handleKey("down")
+	x.handleKey("down")
+	x.handleKey("down")
+	if x.selected != 2 {
+		t.Errorf("expected selection to stop at the last fact, got %d", x.selected)
+	}
+	transport.responses["cat token"] = MockResponse{stdout: "hunter2\n"}
+	x.handleKey("a")
+	if screen := x.render(24, 100); strings.Contains(screen, "hunter2") || !strings.Contains(screen, RedactedValue) {
+		t.Errorf("expected secret fact to be redacted:\n%s", screen)
+	}
+
+	if x.handleKey("q") {
+		t.Error("expected q to quit")
+	}
+}
+
+// TestFactExplorer_Scroll tests that the list scrolls to keep the selection visible
+func TestFactExplorer_Scroll(t *testing.T) {
+	x := &factExplorer{}
+	for _, name := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		x.reports = append(x.reports, FactReport{Name: "fact_" + name, Value: name})
+	}
+	x.selected = 7
+
+	// 16 rows leave 3 for the list
+	screen := x.render(16, 80)
+	if strings.Contains(screen, "fact_e") || !strings.Contains(screen, "fact_f") || !strings.Contains(screen, "> ✓  fact_h") {
+		t.Errorf("expected fact_f to fact_h visible:\n%s", screen)
+	}
+	if lines := strings.Count(screen, "\r\n"); lines != 15 {
+		t.Errorf("expected the screen to fill 16 rows, got %d line breaks", lines)
+	}
+}
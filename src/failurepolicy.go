@@ -0,0 +1,33 @@
+package main
+
+import "fmt"
+
+// Failure policies say what a failed step does to the rest of the run. They
+// are set with "failure_policy" on a platform, as the default of its steps,
+// or on a step.
+const (
+	FailurePolicyAbort           = "abort"            // Stop at the failed step (default)
+	FailurePolicyContinue        = "continue"         // Run the remaining steps; the failure does not fail the run
+	FailurePolicyContinueCollect = "continue-collect" // Run the remaining steps, then fail the run with every failure reported
+)
+
+// validateFailurePolicy checks a failure_policy value
+func validateFailurePolicy(policy string) error {
+	switch policy {
+	case "", FailurePolicyAbort, FailurePolicyContinue, FailurePolicyContinueCollect:
+		return nil
+	}
+	return fmt.Errorf("invalid failure_policy '%s', must be one of: abort, continue, continue-collect", policy)
+}
+
+// stepFailurePolicy returns the policy that applies when a top-level step
+// fails: its own, else the platform's, else abort
+func (e *Executor) stepFailurePolicy(step InstallStep) string {
+	switch {
+	case step.FailurePolicy != "":
+		return step.FailurePolicy
+	case e.failurePolicy != "":
+		return e.failurePolicy
+	}
+	return FailurePolicyAbort
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestExecutePlatform_FailurePolicy tests that platform and step failure
+// policies decide whether the steps after a failure run
+func TestExecutePlatform_FailurePolicy(t *testing.T) {
+	responses := map[string]MockResponse{
+		"fail one": {exitCode: 1, err: fmt.Errorf("exit status 1")},
+		"fail two": {exitCode: 2, err: fmt.Errorf("exit status 2")},
+		"echo ok":  {stdout: "ok"},
+	}
+	steps := func(policy string) []InstallStep {
+		return []InstallStep{
+			{Name: "First", Step: CommandStep{Command: "fail one"}},
+			{Name: "Second", FailurePolicy: policy, Step: CommandStep{Command: "fail two"}},
+			{Name: "Third", Step: CommandStep{Command: "echo ok"}},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		platform  string
+		step      string
+		results   int
+		tolerated []bool
+	}{
+		{name: "abort by default", results: 1, tolerated: []bool{false}},
+		{name: "continue", platform: FailurePolicyContinue, results: 3, tolerated: []bool{true, true, false}},
+		{name: "continue-collect", platform: FailurePolicyContinueCollect, results: 3, tolerated: []bool{false, false, false}},
+		{name: "step overrides platform", platform: FailurePolicyContinue, step: FailurePolicyAbort, results: 2, tolerated: []bool{true, false}},
+		{name: "step policy alone", step: FailurePolicyContinueCollect, results: 1, tolerated: []bool{false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := NewExecutor(&MockTransport{responses: responses})
+			var events []ExecutionEvent
+			executor.OnEvent = func(event ExecutionEvent) { events = append(events, event) }
+
+			results := executor.ExecutePlatform(Platform{FailurePolicy: tt.platform, InstallSteps: steps(tt.step)}, Facts{})
+			if len(results) != tt.results {
+				t.Fatalf("expected %d results, got %+v", tt.results, results)
+			}
+			for i, tolerated := range tt.tolerated {
+				if results[i].Tolerated != tolerated {
+					t.Errorf("step %d: expected tolerated=%v, got %+v", i, tolerated, results[i])
+				}
+			}
+			for _, event := range events {
+				if event.Status == "failed" && event.StepName == "First" && event.FailurePolicy != tt.platform {
+					t.Errorf("expected failure_policy %q on the failed event, got %q", tt.platform, event.FailurePolicy)
+				}
+			}
+		})
+	}
+}
+
+// TestFailurePolicyValidation tests failure_policy in configs
+func TestFailurePolicyValidation(t *testing.T) {
+	var platform Platform
+	err := json.Unmarshal([]byte(`{
+		"os": "linux", "match": "linux*", "name": "Linux", "failure_policy": "continue-collect",
+		"install_steps": [{"name": "a", "command": "true", "failure_policy": "continue"}]
+	}`), &platform)
+	if err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if platform.FailurePolicy != FailurePolicyContinueCollect || platform.InstallSteps[0].FailurePolicy != FailurePolicyContinue {
+		t.Errorf("expected failure policies to be parsed, got %+v", platform)
+	}
+	if err := validatePlatform(&platform); err != nil {
+		t.Errorf("expected a valid platform, got %v", err)
+	}
+
+	platform.FailurePolicy = "ignore"
+	if err := validatePlatform(&platform); err == nil || !strings.Contains(err.Error(), "invalid failure_policy 'ignore'") {
+		t.Errorf("expected an invalid platform policy error, got %v", err)
+	}
+
+	err = validateStepAnnotations([]InstallStep{{Name: "a", FailurePolicy: "skip", Step: CommandStep{Command: "true"}}})
+	if err == nil || !strings.Contains(err.Error(), "install_step[0] a: invalid failure_policy 'skip'") {
+		t.Errorf("expected an invalid step policy error, got %v", err)
+	}
+
+	group := Group{Steps: []InstallStep{{Name: "m", FailurePolicy: FailurePolicyContinue, Step: CommandStep{Command: "true"}}}}
+	if err := group.validate(); err == nil || !strings.Contains(err.Error(), "failure_policy is not supported inside a group") {
+		t.Errorf("expected group members to reject failure_policy, got %v", err)
+	}
+}
+
+// TestSummaryNote_Tolerated tests the note of a failure the run continued past
+func TestSummaryNote_Tolerated(t *testing.T) {
+	result := StepResult{Status: "failed", Error: "exit status 1", Tolerated: true}
+	if note := summaryNote(result, SummaryShort); note != "continued: exit status 1" {
+		t.Errorf("unexpected note %q", note)
+	}
+}
@@ -0,0 +1,411 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultFleetParallel is how many hosts a manifest bootstraps at once
+// unless the manifest or --parallel says otherwise
+const defaultFleetParallel = 4
+
+// FleetManifest maps host groups to the configs they bootstrap from, for
+// sink bootstrap --manifest
+type FleetManifest struct {
+	Parallel int                   `json:"parallel,omitempty"` // Hosts bootstrapped at once (default 4)
+	Groups   map[string]FleetGroup `json:"groups"`
+}
+
+// FleetGroup is a set of hosts bootstrapped from one config
+type FleetGroup struct {
+	Hosts  []string `json:"hosts"`            // SSH targets; ranges such as web[01:03] expand
+	Config string   `json:"config"`           // Config URL, or path relative to the manifest
+	SHA256 string   `json:"sha256,omitempty"` // Expected checksum of the config
+}
+
+// fleetConfig is a config downloaded and verified once for every host that
+// uses it
+type fleetConfig struct {
+	Source string       // As written in the manifest
+	Path   string       // Verified local copy transferred to the hosts
+	Files  []stagedFile // The config's "files" section (local configs only)
+}
+
+// fleetTarget is one host of a manifest with the config it bootstraps from
+type fleetTarget struct {
+	Group  string
+	Target string   // SSH target
+	Host   HostInfo // Position within the group
+	Config *fleetConfig
+}
+
+// fleetResult is the outcome of bootstrapping one host
+type fleetResult struct {
+	fleetTarget
+	Err      error
+	Duration time.Duration
+	Log      string // The host's log in the run directory
+}
+
+// loadFleetManifest reads and validates a fleet manifest
+func loadFleetManifest(path string) (*FleetManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var manifest FleetManifest
+	if err := decoder.Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %w", path, err)
+	}
+	if len(manifest.Groups) == 0 {
+		return nil, fmt.Errorf("manifest %s has no groups", path)
+	}
+	if manifest.Parallel < 0 {
+		return nil, fmt.Errorf("manifest parallel must be positive, got %d", manifest.Parallel)
+	}
+	for _, name := range sortedKeys(manifest.Groups) {
+		group := manifest.Groups[name]
+		if len(group.Hosts) == 0 {
+			return nil, fmt.Errorf("group %s: hosts is required", name)
+		}
+		if strings.TrimSpace(group.Config) == "" {
+			return nil, fmt.Errorf("group %s: config is required", name)
+		}
+		if strings.HasPrefix(group.Config, "github:") || strings.HasPrefix(group.Config, "git+") {
+			return nil, fmt.Errorf("group %s: config must be an http(s) URL or a file", name)
+		}
+		if group.SHA256 != "" && !sha256Regex.MatchString(group.SHA256) {
+			return nil, fmt.Errorf("group %s: sha256 must be 64 lowercase hex characters", name)
+		}
+	}
+	return &manifest, nil
+}
+
+// fleetTargets expands the manifest's host patterns. Groups are taken in
+// name order and a host may only belong to one group.
+func fleetTargets(manifest *FleetManifest, configs map[string]*fleetConfig) ([]fleetTarget, error) {
+	var targets []fleetTarget
+	groupOf := map[string]string{}
+	for _, name := range sortedKeys(manifest.Groups) {
+		group := manifest.Groups[name]
+		hosts, err := expandHosts(strings.Join(group.Hosts, ","))
+		if err != nil {
+			return nil, fmt.Errorf("group %s: %w", name, err)
+		}
+		for i, host := range hosts {
+			if other, ok := groupOf[host]; ok {
+				return nil, fmt.Errorf("host %s is in both groups %s and %s", host, other, name)
+			}
+			groupOf[host] = name
+			targets = append(targets, fleetTarget{
+				Group:  name,
+				Target: host,
+				Host:   HostInfo{Name: targetHostName(host), Index: i, Count: len(hosts)},
+				Config: configs[name],
+			})
+		}
+	}
+	return targets, nil
+}
+
+// prepareFleetConfigs downloads and verifies each distinct config once,
+// keyed by group. URL configs are saved under workDir; local paths are
+// relative to baseDir.
+func prepareFleetConfigs(manifest *FleetManifest, baseDir, workDir string, verify URLLoadOptions) (map[string]*fleetConfig, error) {
+	configs := map[string]*fleetConfig{}
+	bySource := map[string]*fleetConfig{}
+	checksums := map[string]string{}
+	for _, name := range sortedKeys(manifest.Groups) {
+		group := manifest.Groups[name]
+		source := group.Config
+		if !isURL(source) && !filepath.IsAbs(source) {
+			source = filepath.Join(baseDir, source)
+		}
+		if config, ok := bySource[source]; ok {
+			if group.SHA256 != checksums[source] {
+				return nil, fmt.Errorf("group %s: config %s is listed with different sha256 values", name, group.Config)
+			}
+			configs[name] = config
+			continue
+		}
+
+		config := &fleetConfig{Source: group.Config, Path: source}
+		if isURL(source) {
+			opts := verify
+			opts.SHA256 = group.SHA256
+			_, body, err := fetchConfigFromURL(source, opts)
+			if err != nil {
+				return nil, fmt.Errorf("group %s: %w", name, err)
+			}
+			config.Path = filepath.Join(workDir, strconv.Itoa(len(bySource))+".json")
+			if err := os.WriteFile(config.Path, body, 0600); err != nil {
+				return nil, err
+			}
+		} else {
+			if group.SHA256 != "" {
+				sum, err := fileSHA256(source)
+				if err != nil {
+					return nil, fmt.Errorf("group %s: %w", name, err)
+				}
+				if sum != group.SHA256 {
+					return nil, fmt.Errorf("group %s: SHA256 mismatch for %s (expected %s, got %s)", name, group.Config, group.SHA256, sum)
+				}
+			}
+			loaded, err := LoadConfig(source)
+			if err != nil {
+				return nil, fmt.Errorf("group %s: %w", name, err)
+			}
+			if err := validateRemoteFiles(loaded.Files); err != nil {
+				return nil, fmt.Errorf("group %s: %w", name, err)
+			}
+			if config.Files, err = stageRemoteFiles(loaded.Files, filepath.Dir(source)); err != nil {
+				return nil, fmt.Errorf("group %s: %w", name, err)
+			}
+		}
+		bySource[source] = config
+		checksums[source] = group.SHA256
+		configs[name] = config
+	}
+	return configs, nil
+}
+
+// runFleet bootstraps targets, up to parallel at a time. Each host's output
+// is prefixed with its name so interleaved lines stay readable, and kept
+// unprefixed in the host's log in run.
+func runFleet(targets []fleetTarget, parallel int, opts RemoteDeployOptions, run *deployRun, newShell func(target string, out io.Writer) remoteShell, out io.Writer) []fleetResult {
+	results := make([]fleetResult, len(targets))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, max(parallel, 1))
+	for i, target := range targets {
+		wg.Add(1)
+		go func(i int, target fleetTarget) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			hostOut := &prefixWriter{mu: &mu, w: out, prefix: "[" + target.Host.Name + "] "}
+			hostOpts := opts
+			hostOpts.Config = target.Config.Path
+			results[i] = run.deploy(target, hostOpts, newShell, hostOut)
+			hostOut.Flush()
+		}(i, target)
+	}
+	wg.Wait()
+	return results
+}
+
+// prefixWriter prefixes each line written to w, sharing mu with the other
+// writers of w so lines from different hosts never mix
+type prefixWriter struct {
+	mu     *sync.Mutex
+	w      io.Writer
+	prefix string
+	buf    []byte
+}
+
+// Write implements io.Writer, holding back a partial last line
+func (p *prefixWriter) Write(data []byte) (int, error) {
+	p.buf = append(p.buf, data...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			return len(data), nil
+		}
+		p.writeLine(p.buf[:i+1])
+		p.buf = p.buf[i+1:]
+	}
+}
+
+// Flush writes a partial last line
+func (p *prefixWriter) Flush() {
+	if len(p.buf) > 0 {
+		p.writeLine(append(p.buf, '\n'))
+		p.buf = nil
+	}
+}
+
+// writeLine writes one prefixed line
+func (p *prefixWriter) writeLine(line []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Fprintf(p.w, "%s%s", p.prefix, line)
+}
+
+// printFleetPlan lists each group's config and hosts
+func printFleetPlan(w io.Writer, manifest *FleetManifest, targets []fleetTarget) {
+	for _, name := range sortedKeys(manifest.Groups) {
+		group := manifest.Groups[name]
+		fmt.Fprintf(w, "   %s: %s\n", name, group.Config)
+		for _, target := range targets {
+			if target.Group == name {
+				fmt.Fprintf(w, "     [%d] %s\n", target.Host.Index, target.Target)
+			}
+		}
+	}
+}
+
+// bootstrapManifestCommand handles sink bootstrap --manifest <file>
+func bootstrapManifestCommand(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: --manifest needs a manifest file")
+		os.Exit(1)
+	}
+	manifestPath := args[0]
+	opts := RemoteDeployOptions{}
+	var verify URLLoadOptions
+	parallel := 0
+
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "-h" || arg == "--help":
+			printBootstrapHelp()
+			os.Exit(0)
+		case arg == "--dry-run":
+			opts.DryRun = true
+		case arg == "--no-cleanup":
+			opts.NoCleanup = true
+		case arg == "--yes" || arg == "-y":
+			opts.Yes = true
+		case arg == "--require-pinned":
+			verify.RequirePinned = true
+		case arg == "--all-must-succeed":
+			opts.Policy.Quorum = 0
+		case arg == "--quorum" && i+1 < len(args):
+			n, err := parseQuorum(args[i+1])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			opts.Policy.Quorum = n
+			i++
+		case arg == "--run-dir" && i+1 < len(args):
+			opts.RunDir = args[i+1]
+			i++
+		case arg == "--binary" && i+1 < len(args):
+			opts.Binary = args[i+1]
+			i++
+		case arg == "--parallel" && i+1 < len(args):
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil || n < 1 {
+				fmt.Fprintf(os.Stderr, "Error: --parallel must be a positive number, got '%s'\n", args[i+1])
+				os.Exit(1)
+			}
+			parallel = n
+			i++
+		case arg == "--var" && i+1 < len(args):
+			if _, _, err := parseVarFlag(args[i+1]); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			opts.Variables = append(opts.Variables, args[i+1])
+			i++
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown option: %s\n", arg)
+			os.Exit(1)
+		}
+	}
+
+	manifest, err := loadFleetManifest(manifestPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if parallel == 0 {
+		parallel = manifest.Parallel
+	}
+	if parallel == 0 {
+		parallel = defaultFleetParallel
+	}
+	if opts.Binary == "" {
+		self, err := os.Executable()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: cannot locate sink binary (use --binary): %v\n", err)
+			os.Exit(1)
+		}
+		opts.Binary = self
+	}
+
+	workDir, err := os.MkdirTemp("", "sink-fleet-")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer os.RemoveAll(workDir)
+
+	fmt.Println("🚀 Sink Fleet Bootstrap")
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	configs, err := prepareFleetConfigs(manifest, filepath.Dir(manifestPath), workDir, verify)
+	if err == nil {
+		var targets []fleetTarget
+		if targets, err = fleetTargets(manifest, configs); err == nil {
+			err = bootstrapFleet(manifest, targets, parallel, opts)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.RemoveAll(workDir)
+		os.Exit(1)
+	}
+}
+
+// bootstrapFleet shows the plan, confirms it once for every host and runs it
+func bootstrapFleet(manifest *FleetManifest, targets []fleetTarget, parallel int, opts RemoteDeployOptions) error {
+	if err := opts.Policy.check(len(targets)); err != nil {
+		return err
+	}
+	fmt.Printf("   Hosts:    %d in %d group(s), %d at a time\n", len(targets), len(manifest.Groups), parallel)
+	fmt.Printf("   Policy:   %s\n", opts.Policy)
+	if opts.DryRun {
+		fmt.Println("   Mode:     DRY RUN")
+	}
+	fmt.Println()
+	printFleetPlan(os.Stdout, manifest, targets)
+	fmt.Println()
+	if opts.DryRun {
+		return nil
+	}
+
+	// Hosts run unattended, so one answer here stands for all of them
+	if !opts.Yes {
+		fmt.Printf("⚠️  You are about to bootstrap %d hosts\n", len(targets))
+		confirmed, err := confirmRun(bufio.NewReader(os.Stdin), os.Stdout, nil, "", "")
+		if !confirmed {
+			if err != nil {
+				return fmt.Errorf("bootstrap cancelled: %w", err)
+			}
+			fmt.Println("\n❌ Bootstrap cancelled by user")
+			return nil
+		}
+		fmt.Println()
+	}
+	opts.Yes = true
+
+	run, err := newDeployRun(opts.RunDir)
+	if err != nil {
+		return fmt.Errorf("cannot create run directory: %w", err)
+	}
+	results := runFleet(targets, parallel, opts, run, func(target string, out io.Writer) remoteShell {
+		shell := newSSHShell(target, opts.SSH)
+		shell.out = out
+		return shell
+	}, os.Stdout)
+
+	if err := finishDeployRun(os.Stdout, run, results, opts.Policy); err != nil {
+		return err
+	}
+	fmt.Printf("\n✅ Bootstrapped %d hosts\n", len(results))
+	return nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestFleetManifest tests that each distinct config is fetched once and
+// every host deploys its group's verified copy
+func TestFleetManifest(t *testing.T) {
+	config := `{"version": "1.0", "platforms": [{"name": "Linux", "os": "linux", "match": "Linux", "install_steps": [{"name": "x", "command": "true"}]}]}`
+	var mu sync.Mutex
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		w.Write([]byte(config))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "db.json"), []byte(config), 0644)
+	os.WriteFile(filepath.Join(dir, "sink"), []byte("binary"), 0755)
+	manifestPath := filepath.Join(dir, "fleet.json")
+	os.WriteFile(manifestPath, []byte(fmt.Sprintf(`{
+  "groups": {
+    "web": {"hosts": ["deploy@web[01:02].lab"], "config": %q},
+    "cache": {"hosts": ["deploy@cache.lab"], "config": %q},
+    "db": {"hosts": ["deploy@db.lab"], "config": "db.json"}
+  }
+}`, server.URL+"/web.json", server.URL+"/web.json")), 0644)
+
+	manifest, err := loadFleetManifest(manifestPath)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	configs, err := prepareFleetConfigs(manifest, dir, t.TempDir(), URLLoadOptions{SkipChecksum: true})
+	if err != nil {
+		t.Fatalf("prepare failed: %v", err)
+	}
+	if requests != 1 || configs["web"] != configs["cache"] {
+		t.Errorf("expected the shared config to be fetched once, got %d requests", requests)
+	}
+	targets, err := fleetTargets(manifest, configs)
+	if err != nil {
+		t.Fatalf("targets failed: %v", err)
+	}
+	if len(targets) != 4 || targets[3].Target != "deploy@web02.lab" || targets[3].Host.Index != 1 || targets[3].Host.Count != 2 {
+		t.Fatalf("unexpected targets: %+v", targets)
+	}
+
+	shells := map[string]*fakeShell{}
+	var out bytes.Buffer
+	run, err := newDeployRun(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := runFleet(targets, 2, RemoteDeployOptions{Binary: filepath.Join(dir, "sink"), Yes: true}, run, func(target string, w io.Writer) remoteShell {
+		mu.Lock()
+		defer mu.Unlock()
+		shell := &fakeShell{}
+		if target == "deploy@db.lab" {
+			shell.execErr = fmt.Errorf("exit status 1")
+		}
+		shells[target] = shell
+		return shell
+	}, &out)
+
+	if report := run.report(results, DeployPolicy{}); report.Failed != 1 || report.Passed {
+		t.Errorf("expected 1 failed host to fail the deploy, got %+v", report)
+	}
+	if local := shells["deploy@web01.lab"].uploads["/tmp/sink-deploy.abc123/config.json"]; local != configs["web"].Path {
+		t.Errorf("expected the verified copy to be uploaded, got %q", local)
+	}
+	if !strings.Contains(out.String(), "[web02.lab]    ✓ Transferred config\n") {
+		t.Errorf("expected prefixed host output, got:\n%s", out.String())
+	}
+}
+
+// TestFleetManifestErrors tests manifest validation
+func TestFleetManifestErrors(t *testing.T) {
+	tests := []struct {
+		manifest string
+		err      string
+	}{
+		{`{"groups": {}}`, "has no groups"},
+		{`{"groups": {"web": {"config": "web.json"}}}`, "hosts is required"},
+		{`{"groups": {"web": {"hosts": ["a"], "config": "git+ssh://x//y.json"}}}`, "http(s) URL or a file"},
+		{`{"groups": {"web": {"hosts": ["a"], "config": "x.json", "sha": "y"}}}`, "unknown field"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "fleet.json")
+		os.WriteFile(path, []byte(tt.manifest), 0644)
+		if _, err := loadFleetManifest(path); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.manifest, tt.err, err)
+		}
+	}
+
+	manifest := &FleetManifest{Groups: map[string]FleetGroup{
+		"a": {Hosts: []string{"web01"}, Config: "a.json"},
+		"b": {Hosts: []string{"web[01:02]"}, Config: "b.json"},
+	}}
+	if _, err := fleetTargets(manifest, nil); err == nil || !strings.Contains(err.Error(), "in both groups a and b") {
+		t.Errorf("expected a duplicate host to be refused, got %v", err)
+	}
+}
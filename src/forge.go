@@ -0,0 +1,112 @@
+package main
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Forges whose file URLs sink recognizes for pin detection
+const (
+	ForgeGitHub    = "GitHub"
+	ForgeGitLab    = "GitLab"
+	ForgeBitbucket = "Bitbucket"
+)
+
+var (
+	// https://gitlab.example.com/group/sub/project/-/raw/<ref>/path (also /-/blob/)
+	gitlabRawPattern = regexp.MustCompile(`^https?://[^/]+/(.+)/([^/]+)/-/(?:raw|blob)/([^/?#]+)/`)
+	// https://gitlab.example.com/group/project/-/releases/<tag>/downloads/file
+	gitlabReleasePattern = regexp.MustCompile(`^https?://[^/]+/(.+)/([^/]+)/-/releases/([^/?#]+)/downloads/`)
+	// https://gitlab.example.com/api/v4/projects/<id>/repository/files/<path>/raw?ref=<ref>
+	gitlabAPIPattern = regexp.MustCompile(`^https?://[^/]+/api/v4/projects/([^/]+)/repository/files/[^/]+/raw(?:[?#]|$)`)
+	// https://bitbucket.org/<workspace>/<repo>/raw/<ref>/path
+	bitbucketRawPattern = regexp.MustCompile(`^https?://bitbucket\.org/([^/]+)/([^/]+)/raw/([^/?#]+)/`)
+	// https://api.bitbucket.org/2.0/repositories/<workspace>/<repo>/src/<ref>/path
+	bitbucketAPIPattern = regexp.MustCompile(`^https?://api\.bitbucket\.org/2\.0/repositories/([^/]+)/([^/]+)/src/([^/?#]+)/`)
+	// Bitbucket Server: https://host/[rest/api/1.0/]projects/<key>/repos/<repo>/raw/path?at=<ref>
+	bitbucketServerPattern = regexp.MustCompile(`^https?://[^/]+/(?:.*/)?projects/([^/]+)/repos/([^/]+)/raw/`)
+)
+
+// ParseRepoURL extracts pinning information from a config URL on GitHub,
+// GitLab (gitlab.com or self-hosted) or Bitbucket (Cloud or Server)
+func ParseRepoURL(rawURL string) (*GitHubURLInfo, bool) {
+	if info, ok := ParseGitHubURL(rawURL); ok {
+		return info, true
+	}
+
+	if m := gitlabReleasePattern.FindStringSubmatch(rawURL); m != nil {
+		return &GitHubURLInfo{Forge: ForgeGitLab, Owner: m[1], Repo: m[2], Ref: m[3],
+			PinType: GitHubPinRelease, IsPinned: true}, true
+	}
+	if m := gitlabRawPattern.FindStringSubmatch(rawURL); m != nil {
+		return newRefInfo(ForgeGitLab, m[1], m[2], m[3]), true
+	}
+	if m := gitlabAPIPattern.FindStringSubmatch(rawURL); m != nil {
+		project, err := url.PathUnescape(m[1])
+		if err != nil {
+			return nil, false
+		}
+		return newRefInfo(ForgeGitLab, "", project, queryParam(rawURL, "ref")), true
+	}
+
+	if m := bitbucketRawPattern.FindStringSubmatch(rawURL); m != nil {
+		return newRefInfo(ForgeBitbucket, m[1], m[2], m[3]), true
+	}
+	if m := bitbucketAPIPattern.FindStringSubmatch(rawURL); m != nil {
+		return newRefInfo(ForgeBitbucket, m[1], m[2], m[3]), true
+	}
+	if m := bitbucketServerPattern.FindStringSubmatch(rawURL); m != nil {
+		return newRefInfo(ForgeBitbucket, m[1], m[2], queryParam(rawURL, "at")), true
+	}
+
+	return nil, false
+}
+
+// newRefInfo classifies a ref named in a forge URL. An explicit
+// refs/tags/ or refs/heads/ prefix decides the type; a missing ref means
+// the default branch, which is mutable.
+func newRefInfo(forge, owner, repo, ref string) *GitHubURLInfo {
+	info := &GitHubURLInfo{Forge: forge, Owner: owner, Repo: repo, Ref: ref}
+	switch {
+	case ref == "":
+		info.Ref = "(default branch)"
+		info.PinType = GitHubPinBranch
+	case strings.HasPrefix(ref, "refs/tags/"):
+		info.PinType = GitHubPinTag
+	case strings.HasPrefix(ref, "refs/heads/"):
+		info.PinType = GitHubPinBranch
+	default:
+		info.PinType = determineRefType(ref)
+	}
+	info.IsPinned = info.PinType == GitHubPinTag || info.PinType == GitHubPinCommit
+	info.IsMutable = info.PinType == GitHubPinBranch
+	return info
+}
+
+// queryParam returns a URL query parameter, or "" when absent
+func queryParam(rawURL, name string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Query().Get(name)
+}
+
+// forge names the forge a URL is on; infos built before forges other than
+// GitHub were recognized have no Forge set
+func (info *GitHubURLInfo) forge() string {
+	if info.Forge == "" {
+		return ForgeGitHub
+	}
+	return info.Forge
+}
+
+// repository names the repository for display: owner/repo, or just the
+// project when the URL only gives its ID
+func (info *GitHubURLInfo) repository() string {
+	if info.Owner == "" {
+		return info.Repo
+	}
+	return info.Owner + "/" + info.Repo
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestParseRepoURL tests pin detection for GitLab and Bitbucket URLs
+func TestParseRepoURL(t *testing.T) {
+	sha := strings.Repeat("a1", 20)
+	tests := []struct {
+		url     string
+		forge   string
+		repo    string
+		ref     string
+		pinType GitHubPinType
+	}{
+		// GitHub URLs keep their existing parsing
+		{"https://raw.githubusercontent.com/org/configs/v1.0.0/prod.json", ForgeGitHub, "org/configs", "v1.0.0", GitHubPinTag},
+
+		{"https://gitlab.com/ops/configs/-/raw/v1.2.0/lab/config.json", ForgeGitLab, "ops/configs", "v1.2.0", GitHubPinTag},
+		{"https://gitlab.example.com/ops/infra/configs/-/raw/main/config.json", ForgeGitLab, "ops/infra/configs", "main", GitHubPinBranch},
+		{"https://gitlab.example.com/ops/configs/-/blob/" + sha + "/config.json", ForgeGitLab, "ops/configs", sha, GitHubPinCommit},
+		{"https://gitlab.example.com/ops/configs/-/raw/v1.2.0/config.json?inline=false", ForgeGitLab, "ops/configs", "v1.2.0", GitHubPinTag},
+		{"https://gitlab.example.com/ops/configs/-/releases/v2.0.0/downloads/config.json", ForgeGitLab, "ops/configs", "v2.0.0", GitHubPinRelease},
+		{"https://gitlab.example.com/api/v4/projects/42/repository/files/config.json/raw?ref=v1.0.0", ForgeGitLab, "42", "v1.0.0", GitHubPinTag},
+		{"https://gitlab.example.com/api/v4/projects/ops%2Fconfigs/repository/files/lab%2Fconfig.json/raw?ref=refs/heads/release", ForgeGitLab, "ops/configs", "refs/heads/release", GitHubPinBranch},
+		{"https://gitlab.example.com/api/v4/projects/42/repository/files/config.json/raw", ForgeGitLab, "42", "(default branch)", GitHubPinBranch},
+
+		{"https://bitbucket.org/ops/configs/raw/v1.0.0/config.json", ForgeBitbucket, "ops/configs", "v1.0.0", GitHubPinTag},
+		{"https://bitbucket.org/ops/configs/raw/develop/config.json", ForgeBitbucket, "ops/configs", "develop", GitHubPinBranch},
+		{"https://api.bitbucket.org/2.0/repositories/ops/configs/src/" + sha + "/config.json", ForgeBitbucket, "ops/configs", sha, GitHubPinCommit},
+		{"https://bitbucket.example.com/projects/OPS/repos/configs/raw/config.json?at=refs/tags/lab-2024", ForgeBitbucket, "OPS/configs", "refs/tags/lab-2024", GitHubPinTag},
+		{"https://bitbucket.example.com/rest/api/1.0/projects/OPS/repos/configs/raw/config.json?at=refs/heads/main", ForgeBitbucket, "OPS/configs", "refs/heads/main", GitHubPinBranch},
+		{"https://bitbucket.example.com/projects/OPS/repos/configs/raw/config.json", ForgeBitbucket, "OPS/configs", "(default branch)", GitHubPinBranch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			info, ok := ParseRepoURL(tt.url)
+			if !ok {
+				t.Fatalf("expected %s to be recognized", tt.url)
+			}
+			if info.Forge != tt.forge || info.repository() != tt.repo || info.Ref != tt.ref || info.PinType != tt.pinType {
+				t.Errorf("got %s %s@%s (%s), want %s %s@%s (%s)",
+					info.Forge, info.repository(), info.Ref, info.PinType, tt.forge, tt.repo, tt.ref, tt.pinType)
+			}
+			wantPinned := tt.pinType == GitHubPinTag || tt.pinType == GitHubPinCommit || tt.pinType == GitHubPinRelease
+			if info.IsPinned != wantPinned || info.IsMutable != (tt.pinType == GitHubPinBranch) {
+				t.Errorf("IsPinned=%v IsMutable=%v for %s", info.IsPinned, info.IsMutable, tt.pinType)
+			}
+		})
+	}
+
+	for _, url := range []string{
+		"https://example.com/config.json",
+		"https://gitlab.example.com/ops/configs/config.json",
+		"https://bitbucket.org/ops/configs/downloads/config.json",
+	} {
+		if info, ok := ParseRepoURL(url); ok {
+			t.Errorf("expected %s not to be recognized, got %+v", url, info)
+		}
+	}
+}
+
+// TestCheckPinnedSource_Forges tests that the pinning policy names the forge
+func TestCheckPinnedSource_Forges(t *testing.T) {
+	url := "https://bitbucket.example.com/projects/OPS/repos/configs/raw/config.json?at=refs/heads/main"
+	info, _ := ParseRepoURL(url)
+	err := checkPinnedSource(url, info, "")
+	if err == nil || !strings.Contains(err.Error(), "Bitbucket ref 'refs/heads/main' is a mutable branch") {
+		t.Errorf("expected a Bitbucket mutable branch error, got %v", err)
+	}
+
+	url = "https://gitlab.example.com/ops/configs/-/raw/v1.2.0/config.json"
+	info, _ = ParseRepoURL(url)
+	if err := checkPinnedSource(url, info, ""); err != nil {
+		t.Errorf("expected a GitLab tag to be pinned, got %v", err)
+	}
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// workspaceNameRegex matches the workspace of a generated run ID,
+	// sink-[<host>-]<UUIDv7> (see generateRunID)
+	workspaceNameRegex = regexp.MustCompile(`^sink-([a-z0-9-]+-)?[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+	// bundleDirRegex matches an unpacked bundle, named by its checksum
+	// prefix; directories still being unpacked or set aside are not
+	bundleDirRegex = regexp.MustCompile(`^[0-9a-f]{16}$`)
+)
+
+// GCOptions are the retention rules for sink gc. An entry is removed when it
+// is not among the KeepLast newest of its kind and is older than OlderThan;
+// a zero rule does not protect anything.
+type GCOptions struct {
+	KeepLast  int
+	OlderThan time.Duration
+	DryRun    bool
+}
+
+// gcTarget is one kind of run data sink gc prunes: the entries of a
+// directory that match
+type gcTarget struct {
+	Kind  string // "workspace", "bundle", "library" or "log"
+	Dir   string
+	Match func(entry fs.DirEntry) bool
+}
+
+// gcEntry is a file or directory sink gc may remove
+type gcEntry struct {
+	Kind    string
+	Path    string
+	ModTime time.Time
+	Size    int64
+}
+
+// parseRetention parses an --older-than value: a Go duration, or a whole
+// number of days ("30d") or weeks ("2w")
+func parseRetention(value string) (time.Duration, error) {
+	unit := time.Duration(0)
+	switch {
+	case strings.HasSuffix(value, "d"):
+		unit = 24 * time.Hour
+	case strings.HasSuffix(value, "w"):
+		unit = 7 * 24 * time.Hour
+	}
+	var d time.Duration
+	if unit != 0 {
+		n, err := strconv.Atoi(value[:len(value)-1])
+		if err != nil {
+			return 0, fmt.Errorf("invalid --older-than '%s'", value)
+		}
+		d = time.Duration(n) * unit
+	} else {
+		var err error
+		if d, err = time.ParseDuration(value); err != nil {
+			return 0, fmt.Errorf("invalid --older-than '%s' (use e.g. 30d, 2w or 12h)", value)
+		}
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("--older-than must be positive, got '%s'", value)
+	}
+	return d, nil
+}
+
+// gcTargets lists the run data sink gc manages. State such as the trust
+// store and rate limit budgets is never pruned.
+func gcTargets() []gcTarget {
+	var targets []gcTarget
+	workspaces := workspaceBaseDir()
+	targets = append(targets, gcTarget{
+		Kind: "workspace",
+		Dir:  workspaces,
+		Match: func(entry fs.DirEntry) bool {
+			return isIdleWorkspace(workspaces, entry)
+		},
+	})
+	if cache, err := cacheDir(); err == nil {
+		targets = append(targets,
+			gcTarget{Kind: "bundle", Dir: filepath.Join(cache, "bundles"), Match: func(entry fs.DirEntry) bool {
+				return entry.IsDir() && bundleDirRegex.MatchString(entry.Name())
+			}},
+			gcTarget{Kind: "library", Dir: filepath.Join(cache, "libraries"), Match: func(entry fs.DirEntry) bool {
+				return strings.HasSuffix(entry.Name(), ".json")
+			}},
+		)
+	}
+	if logs, err := logDir(); err == nil {
+		targets = append(targets, gcTarget{Kind: "log", Dir: logs, Match: isRunLog})
+	}
+	return targets
+}
+
+// isIdleWorkspace reports whether an entry of the workspace directory is
+// the workspace of a run that is no longer running. Only generated run IDs
+// are recognized, so other programs' files in a shared temporary
+// directory, remote deploy workspaces and workspaces named by --run-id are
+// left alone, as are workspaces whose run's process still exists.
+func isIdleWorkspace(dir string, entry fs.DirEntry) bool {
+	if !entry.IsDir() || !workspaceNameRegex.MatchString(entry.Name()) {
+		return false
+	}
+	data, err := os.ReadFile(filepath.Join(dir, entry.Name(), workspacePIDFile))
+	if err != nil {
+		return true
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	return err != nil || !processAlive(pid)
+}
+
+// isRunLog reports whether a log directory entry is a finished run's record:
+// a run-<id>.jsonl run record or a deploy-<id> directory of a multi-host
+// deploy. Anything else, such as an agent's log that is still being written
+// or other programs' files when SINK_LOG_DIR is shared, is left alone.
+func isRunLog(entry fs.DirEntry) bool {
+	name := entry.Name()
+	if entry.IsDir() {
+		return strings.HasPrefix(name, "deploy-")
+	}
+	return strings.HasPrefix(name, "run-") && strings.HasSuffix(name, ".jsonl")
+}
+
+// collectGC returns a target's entries, newest first. A missing directory
+// has no entries.
+func collectGC(target gcTarget) ([]gcEntry, error) {
+	dirEntries, err := os.ReadDir(target.Dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []gcEntry
+	for _, dirEntry := range dirEntries {
+		if !target.Match(dirEntry) {
+			continue
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			continue // Removed while listing
+		}
+		path := filepath.Join(target.Dir, dirEntry.Name())
+		entries = append(entries, gcEntry{Kind: target.Kind, Path: path, ModTime: info.ModTime(), Size: diskUsage(path)})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ModTime.After(entries[j].ModTime) })
+	return entries, nil
+}
+
+// diskUsage returns the total size of the regular files under path
+func diskUsage(path string) int64 {
+	var total int64
+	filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err == nil && d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				total += info.Size()
+			}
+		}
+		return nil
+	})
+	return total
+}
+
+// selectGC applies the retention rules to entries sorted newest first
+func selectGC(entries []gcEntry, opts GCOptions, now time.Time) []gcEntry {
+	var expired []gcEntry
+	for i, entry := range entries {
+		if i < opts.KeepLast {
+			continue
+		}
+		if opts.OlderThan > 0 && now.Sub(entry.ModTime) < opts.OlderThan {
+			continue
+		}
+		expired = append(expired, entry)
+	}
+	return expired
+}
+
+// runGC prunes every target and reports what it removed (or would remove)
+func runGC(w io.Writer, targets []gcTarget, opts GCOptions, now time.Time) error {
+	verb := "Removed"
+	if opts.DryRun {
+		verb = "Would remove"
+	}
+	count, freed := 0, int64(0)
+	var failed []string
+	for _, target := range targets {
+		entries, err := collectGC(target)
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", target.Dir, err))
+			continue
+		}
+		for _, entry := range selectGC(entries, opts, now) {
+			if !opts.DryRun {
+				if err := os.RemoveAll(entry.Path); err != nil {
+					failed = append(failed, err.Error())
+					continue
+				}
+			}
+			age := now.Sub(entry.ModTime).Round(time.Hour)
+			fmt.Fprintf(w, "  %-9s %s (%s old, %s)\n", entry.Kind, entry.Path, age, formatBytes(entry.Size))
+			count++
+			freed += entry.Size
+		}
+	}
+	fmt.Fprintf(w, "%s %d entries, %s\n", verb, count, formatBytes(freed))
+	if len(failed) > 0 {
+		return fmt.Errorf("could not remove everything:\n  %s", strings.Join(failed, "\n  "))
+	}
+	return nil
+}
+
+// gcCommand handles sink gc
+func gcCommand() {
+	opts := GCOptions{}
+	rules := false
+
+	args := os.Args[2:]
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch arg {
+		case "-h", "--help":
+			printGCHelp()
+			os.Exit(0)
+		case "--dry-run":
+			opts.DryRun = true
+		case "--keep-last", "--older-than":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: %s requires a value\n", arg)
+				os.Exit(1)
+			}
+			var err error
+			if arg == "--keep-last" {
+				opts.KeepLast, err = strconv.Atoi(args[i+1])
+				if err == nil && opts.KeepLast < 0 {
+					err = fmt.Errorf("--keep-last must not be negative, got '%s'", args[i+1])
+				}
+			} else {
+				opts.OlderThan, err = parseRetention(args[i+1])
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			rules = true
+			i++
+		default:
+			fmt.Fprintf(os.Stderr, "Error: unexpected argument: %s\n", arg)
+			os.Exit(1)
+		}
+	}
+
+	// Removing everything is never a default
+	if !rules {
+		fmt.Fprintf(os.Stderr, "Error: give --keep-last, --older-than or both\n\n")
+		printGCHelp()
+		os.Exit(1)
+	}
+
+	if err := runGC(os.Stdout, gcTargets(), opts, time.Now()); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func printGCHelp() {
+	fmt.Print(`sink gc - Remove old run data
+
+Usage:
+  sink gc [--keep-last <n>] [--older-than <age>] [--dry-run]
+
+Description:
+  Prunes data sink leaves behind: run workspaces left by interrupted runs,
+  unpacked bundles, cached step libraries and run logs (run-*.jsonl records
+  and deploy-* directories). Each kind is pruned on its own: an entry is
+  removed when it is not among the <n> newest of its kind and is older than
+  <age>. At least one rule is required.
+
+  Only workspaces named after a generated run ID (sink-<run id>) whose run
+  is no longer running are removed, and only fully unpacked bundles. State
+  such as the trust store and rate limit budgets is never removed, nor are
+  other files in the workspace or log directory, such as remote deploy
+  workspaces, workspaces named by --run-id or an agent's log.
+  Directories follow SINK_WORKSPACE_DIR, SINK_CACHE_DIR and SINK_LOG_DIR.
+
+Options:
+  --keep-last <n>      Keep the <n> newest entries of each kind
+  --older-than <age>   Only remove entries older than <age> (e.g. 30d, 2w, 12h)
+  --dry-run            List what would be removed without removing it
+  -h, --help           Show this help message
+
+Examples:
+  sink gc --keep-last 20 --older-than 30d --dry-run
+  sink gc --older-than 2w
+`)
+}
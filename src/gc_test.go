@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestParseRetention tests --older-than values
+func TestParseRetention(t *testing.T) {
+	valid := map[string]time.Duration{
+		"30d": 30 * 24 * time.Hour,
+		"2w":  14 * 24 * time.Hour,
+		"12h": 12 * time.Hour,
+	}
+	for value, want := range valid {
+		if got, err := parseRetention(value); err != nil || got != want {
+			t.Errorf("parseRetention(%q) = %s, %v; want %s", value, got, err, want)
+		}
+	}
+	for _, value := range []string{"", "d", "1.5d", "-3d", "0h", "soon"} {
+		if _, err := parseRetention(value); err == nil {
+			t.Errorf("parseRetention(%q): expected an error", value)
+		}
+	}
+}
+
+// TestRunGC tests pruning with --keep-last and --older-than
+func TestRunGC(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	dir := t.TempDir()
+	ages := map[string]int{"sink-a": 1, "sink-b": 10, "sink-c": 40, "sink-d": 50, "other": 90}
+	for name, days := range ages {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(path, 0755)
+		os.WriteFile(filepath.Join(path, "out.log"), []byte("12345"), 0644)
+		modTime := now.Add(-time.Duration(days) * 24 * time.Hour)
+		os.Chtimes(path, modTime, modTime)
+	}
+	match := func(entry fs.DirEntry) bool { return strings.HasPrefix(entry.Name(), "sink-") }
+	targets := []gcTarget{{Kind: "workspace", Dir: dir, Match: match}}
+	missing := gcTarget{Kind: "log", Dir: filepath.Join(dir, "missing"), Match: targets[0].Match}
+	targets = append(targets, missing)
+
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(dir, name))
+		return err == nil
+	}
+
+	// Dry run lists what would go without removing it
+	var out bytes.Buffer
+	if err := runGC(&out, targets, GCOptions{KeepLast: 1, OlderThan: 30 * 24 * time.Hour, DryRun: true}, now); err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "Would remove 2 entries, 10 B") || !exists("sink-c") {
+		t.Errorf("unexpected dry run:\n%s", out.String())
+	}
+
+	// The newest is kept regardless of age, and only old entries go
+	out.Reset()
+	if err := runGC(&out, targets, GCOptions{KeepLast: 1, OlderThan: 30 * 24 * time.Hour}, now); err != nil {
+		t.Fatalf("gc failed: %v", err)
+	}
+	if !exists("sink-a") || !exists("sink-b") || exists("sink-c") || exists("sink-d") || !exists("other") {
+		t.Errorf("unexpected result:\n%s", out.String())
+	}
+
+	// --keep-last alone ignores age
+	out.Reset()
+	runGC(&out, targets, GCOptions{KeepLast: 1}, now)
+	if !exists("sink-a") || exists("sink-b") {
+		t.Errorf("expected only the newest to remain:\n%s", out.String())
+	}
+}
+
+// TestGCTargets_Log tests that only run records and deploy directories in
+// the log directory are pruned
+func TestGCTargets_Log(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-90 * 24 * time.Hour)
+	for _, name := range []string{"run-r1.jsonl", "deploy-d1/", "sink-agent.log", "syslog", "runner/"} {
+		path := filepath.Join(dir, strings.TrimSuffix(name, "/"))
+		if strings.HasSuffix(name, "/") {
+			os.MkdirAll(path, 0755)
+		} else {
+			os.WriteFile(path, []byte("x"), 0644)
+		}
+		os.Chtimes(path, old, old)
+	}
+
+	var target gcTarget
+	for _, candidate := range gcTargets() {
+		if candidate.Kind == "log" {
+			target = candidate
+		}
+	}
+	target.Dir = dir
+	var out bytes.Buffer
+	if err := runGC(&out, []gcTarget{target}, GCOptions{OlderThan: time.Hour}, time.Now()); err != nil {
+		t.Fatalf("gc failed: %v", err)
+	}
+	for name, kept := range map[string]bool{"run-r1.jsonl": false, "deploy-d1": false, "sink-agent.log": true, "syslog": true, "runner": true} {
+		if _, err := os.Stat(filepath.Join(dir, name)); (err == nil) != kept {
+			t.Errorf("%s: expected kept=%t:\n%s", name, kept, out.String())
+		}
+	}
+}
+
+// TestGCTargets_WorkspaceAndBundle tests that only idle run workspaces and
+// unpacked bundles are pruned
+func TestGCTargets_WorkspaceAndBundle(t *testing.T) {
+	workspaces := t.TempDir()
+	t.Setenv(EnvWorkspaceDir, workspaces)
+	t.Setenv(EnvCacheDir, t.TempDir())
+	targets := gcTargets()
+	bundles := targets[1].Dir
+
+	old := time.Now().Add(-90 * 24 * time.Hour)
+	create := func(dir, name, pid string) {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(path, 0755)
+		if pid != "" {
+			os.WriteFile(filepath.Join(path, workspacePIDFile), []byte(pid), 0600)
+		}
+		os.Chtimes(path, old, old)
+	}
+	uuid := newUUIDv7(old)
+	kept := map[string]bool{
+		"sink-" + uuid:         false,
+		"sink-web01-" + uuid:   false,
+		"sink-crashed-" + uuid: false,
+		"sink-live-" + uuid:    true,
+		"sink-deploy.abc123":   true,
+		"sink-my-run":          true,
+		"sink-helper.sock":     true,
+	}
+	for name := range kept {
+		switch {
+		case strings.HasPrefix(name, "sink-live-"):
+			create(workspaces, name, strconv.Itoa(os.Getpid()))
+		case strings.HasPrefix(name, "sink-crashed-"):
+			create(workspaces, name, "999999999")
+		default:
+			create(workspaces, name, "")
+		}
+	}
+	bundleKept := map[string]bool{
+		"0123456789abcdef":             false,
+		"0123456789abcdef.unpack-4021": true,
+		"fedcba9876543210.stale-1":     true,
+	}
+	for name := range bundleKept {
+		create(bundles, name, "")
+	}
+
+	var out bytes.Buffer
+	if err := runGC(&out, targets[:2], GCOptions{OlderThan: time.Hour}, time.Now()); err != nil {
+		t.Fatalf("gc failed: %v", err)
+	}
+	for dir, names := range map[string]map[string]bool{workspaces: kept, bundles: bundleKept} {
+		for name, want := range names {
+			if _, err := os.Stat(filepath.Join(dir, name)); (err == nil) != want {
+				t.Errorf("%s: expected kept=%t:\n%s", name, want, out.String())
+			}
+		}
+	}
+}
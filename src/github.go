@@ -15,8 +15,10 @@ const (
 	GitHubPinRelease
 )
 
-// GitHubURLInfo contains parsed information from a GitHub URL
+// GitHubURLInfo contains parsed information from a GitHub URL, or from a
+// GitLab or Bitbucket URL (see ParseRepoURL)
 type GitHubURLInfo struct {
+	Forge     string // ForgeGitHub, ForgeGitLab or ForgeBitbucket
 	Owner     string
 	Repo      string
 	Ref       string
@@ -31,6 +33,7 @@ func ParseGitHubURL(url string) (*GitHubURLInfo, bool) {
 	rawPattern := regexp.MustCompile(`raw\.githubusercontent\.com/([^/]+)/([^/]+)/([^/]+)/`)
 	if matches := rawPattern.FindStringSubmatch(url); matches != nil {
 		info := &GitHubURLInfo{
+			Forge: ForgeGitHub,
 			Owner: matches[1],
 			Repo:  matches[2],
 			Ref:   matches[3],
@@ -45,6 +48,7 @@ func ParseGitHubURL(url string) (*GitHubURLInfo, bool) {
 	releasePattern := regexp.MustCompile(`github\.com/([^/]+)/([^/]+)/releases/download/([^/]+)/`)
 	if matches := releasePattern.FindStringSubmatch(url); matches != nil {
 		info := &GitHubURLInfo{
+			Forge:     ForgeGitHub,
 			Owner:     matches[1],
 			Repo:      matches[2],
 			Ref:       matches[3],
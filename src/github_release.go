@@ -0,0 +1,378 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GitHub endpoints (variables so tests can point them at a local server)
+var (
+	githubAPIBaseURL = "https://api.github.com"
+	githubRawBaseURL = "https://raw.githubusercontent.com"
+)
+
+// checksumAssetNames are release asset names searched for a checksum manifest
+var checksumAssetNames = []string{"SHA256SUMS", "SHA256SUMS.txt", "checksums.txt", "sha256sums.txt"}
+
+// GitHubSource is a parsed github: bootstrap source
+// Format: github:owner/repo//path/to/config.json@constraint
+type GitHubSource struct {
+	Owner      string
+	Repo       string
+	Path       string
+	Constraint string // "latest-release", "^1.2", "~1.2", ">=1.0.0" or an exact tag
+}
+
+// GitHubRelease is the subset of the GitHub releases API response used by sink
+type GitHubRelease struct {
+	TagName    string               `json:"tag_name"`
+	Draft      bool                 `json:"draft"`
+	Prerelease bool                 `json:"prerelease"`
+	UploadURL  string               `json:"upload_url"` // URI template for uploading assets
+	Assets     []GitHubReleaseAsset `json:"assets"`
+}
+
+// GitHubReleaseAsset is a single file attached to a GitHub release
+type GitHubReleaseAsset struct {
+	Name               string `json:"name"`
+	BrowserDownloadURL string `json:"browser_download_url"`
+}
+
+// ResolvedGitHubSource is a github: source resolved to a pinned tag
+type ResolvedGitHubSource struct {
+	Tag      string
+	URL      string           // raw.githubusercontent.com URL pinned to Tag
+	Checksum string           // SHA256 from the release checksum asset (empty if none)
+	Manifest ChecksumManifest // SHA256SUMS manifest attached to the release (nil if none)
+}
+
+var githubSourcePattern = regexp.MustCompile(`^github:([^/\s]+)/([^/\s]+)//([^@\s]+)(?:@(\S+))?$`)
+
+// ParseGitHubSource parses a github:owner/repo//path@constraint source string.
+// The constraint defaults to "latest-release" when omitted.
+func ParseGitHubSource(source string) (*GitHubSource, error) {
+	matches := githubSourcePattern.FindStringSubmatch(source)
+	if matches == nil {
+		return nil, fmt.Errorf("invalid github source '%s' (expected github:owner/repo//path/config.json@version)", source)
+	}
+
+	constraint := matches[4]
+	if constraint == "" {
+		constraint = "latest-release"
+	}
+
+	return &GitHubSource{
+		Owner:      matches[1],
+		Repo:       matches[2],
+		Path:       strings.TrimPrefix(matches[3], "/"),
+		Constraint: constraint,
+	}, nil
+}
+
+// resolveGitHubSource queries the GitHub releases API to pin a github: source
+// to a concrete tag and looks up the config checksum in the release assets.
+func resolveGitHubSource(src *GitHubSource) (*ResolvedGitHubSource, error) {
+	release, err := selectGitHubRelease(src)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := &ResolvedGitHubSource{
+		Tag: release.TagName,
+		URL: fmt.Sprintf("%s/%s/%s/%s/%s", githubRawBaseURL, src.Owner, src.Repo, release.TagName, src.Path),
+	}
+
+	checksum, manifest, err := findReleaseChecksum(release, src.Path)
+	if err != nil {
+		return nil, err
+	}
+	resolved.Checksum = checksum
+	resolved.Manifest = manifest
+
+	return resolved, nil
+}
+
+// selectGitHubRelease picks the release matching the source constraint
+func selectGitHubRelease(src *GitHubSource) (*GitHubRelease, error) {
+	repoURL := fmt.Sprintf("%s/repos/%s/%s", githubAPIBaseURL, src.Owner, src.Repo)
+
+	switch {
+	case src.Constraint == "latest-release" || src.Constraint == "latest":
+		var release GitHubRelease
+		if err := githubAPIGet(repoURL+"/releases/latest", &release); err != nil {
+			return nil, fmt.Errorf("failed to fetch latest release: %w", err)
+		}
+		return &release, nil
+
+	case isVersionConstraint(src.Constraint):
+		var releases []GitHubRelease
+		if err := githubAPIGet(repoURL+"/releases?per_page=100", &releases); err != nil {
+			return nil, fmt.Errorf("failed to list releases: %w", err)
+		}
+		release, err := matchReleaseConstraint(releases, src.Constraint)
+		if err != nil {
+			return nil, err
+		}
+		return release, nil
+
+	default:
+		// Exact tag
+		var release GitHubRelease
+		if err := githubAPIGet(repoURL+"/releases/tags/"+src.Constraint, &release); err != nil {
+			return nil, fmt.Errorf("failed to fetch release '%s': %w", src.Constraint, err)
+		}
+		return &release, nil
+	}
+}
+
+// githubAPIGet performs a GET against the GitHub API and decodes the JSON response
+func githubAPIGet(url string, out interface{}) error {
+	req, err := http.NewRequestWithContext(runCtx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	} else {
+		cred, err := lookupCredential(url)
+		if err != nil {
+			return err
+		}
+		cred.apply(req)
+	}
+
+	client := &http.Client{Timeout: httpTimeout(DefaultHTTPTimeout)}
+	resp, err := client.Do(req)
+	if err != nil {
+		return runStopped(runCtx, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d from %s", resp.StatusCode, url)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// findReleaseChecksum looks for a checksum for configPath among the release assets.
+// A "<basename>.sha256" asset takes precedence over a SHA256SUMS-style manifest.
+// The manifest, when present, is returned so steps can verify the files they fetch.
+func findReleaseChecksum(release *GitHubRelease, configPath string) (string, ChecksumManifest, error) {
+	var manifest ChecksumManifest
+	for _, name := range checksumAssetNames {
+		for _, asset := range release.Assets {
+			if asset.Name != name || manifest != nil {
+				continue
+			}
+			m, err := fetchChecksumManifest(asset.BrowserDownloadURL)
+			if err != nil {
+				return "", nil, err
+			}
+			manifest = m
+		}
+	}
+
+	base := path.Base(configPath)
+	for _, asset := range release.Assets {
+		if asset.Name == base+".sha256" {
+			checksum, err := fetchChecksum(asset.BrowserDownloadURL)
+			return checksum, manifest, err
+		}
+	}
+
+	if manifest != nil {
+		checksum, ok := manifest.Lookup(configPath)
+		if !ok {
+			return "", nil, fmt.Errorf("checksum manifest in release %s has no entry for %s", release.TagName, configPath)
+		}
+		return checksum, manifest, nil
+	}
+
+	return "", nil, nil
+}
+
+// fetchText downloads a small text file
+func fetchText(url string) (string, error) {
+	resp, err := httpGet(url, httpTimeout(ChecksumHTTPTimeout))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+// semver is a parsed semantic version (vMAJOR.MINOR.PATCH[-PRERELEASE])
+type semver struct {
+	Major, Minor, Patch int
+	Prerelease          string
+}
+
+var semverTagPattern = regexp.MustCompile(`^v?([0-9]+)(?:\.([0-9]+))?(?:\.([0-9]+))?(?:-([0-9A-Za-z.-]+))?$`)
+
+// parseSemver parses a full or partial version ("1", "1.2", "v1.2.3-rc.1").
+// It also returns how many numeric components were present.
+func parseSemver(s string) (semver, int, bool) {
+	matches := semverTagPattern.FindStringSubmatch(s)
+	if matches == nil {
+		return semver{}, 0, false
+	}
+
+	var v semver
+	parts := 0
+	for i, dst := range []*int{&v.Major, &v.Minor, &v.Patch} {
+		if matches[i+1] == "" {
+			break
+		}
+		*dst, _ = strconv.Atoi(matches[i+1])
+		parts++
+	}
+	v.Prerelease = matches[4]
+	return v, parts, true
+}
+
+// compare returns -1, 0 or 1. Prereleases sort before their release.
+func (v semver) compare(o semver) int {
+	for _, pair := range [][2]int{{v.Major, o.Major}, {v.Minor, o.Minor}, {v.Patch, o.Patch}} {
+		if pair[0] != pair[1] {
+			if pair[0] < pair[1] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case v.Prerelease == o.Prerelease:
+		return 0
+	case v.Prerelease == "":
+		return 1
+	case o.Prerelease == "":
+		return -1
+	case v.Prerelease < o.Prerelease:
+		return -1
+	default:
+		return 1
+	}
+}
+
+// isVersionConstraint reports whether s is a range constraint rather than a tag
+func isVersionConstraint(s string) bool {
+	for _, prefix := range []string{"^", "~", ">=", ">", "<=", "<", "="} {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// versionMatches reports whether v satisfies a single constraint
+// (^1.2, ~1.2, >=1.0.0, >1.0, <=2, <2.0.0, =1.2.3).
+func versionMatches(v semver, constraint string) (bool, error) {
+	op := ""
+	for _, prefix := range []string{">=", "<=", "^", "~", ">", "<", "="} {
+		if strings.HasPrefix(constraint, prefix) {
+			op = prefix
+			break
+		}
+	}
+
+	base, parts, ok := parseSemver(strings.TrimSpace(strings.TrimPrefix(constraint, op)))
+	if !ok {
+		return false, fmt.Errorf("invalid version constraint '%s'", constraint)
+	}
+
+	cmp := v.compare(base)
+	switch op {
+	case ">=":
+		return cmp >= 0, nil
+	case ">":
+		return cmp > 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case "<":
+		return cmp < 0, nil
+	case "=":
+		return cmp == 0, nil
+	case "~":
+		// ~1.2 -> >=1.2.0 <1.3.0, ~1 -> >=1.0.0 <2.0.0
+		if cmp < 0 || v.Major != base.Major {
+			return false, nil
+		}
+		return parts < 2 || v.Minor == base.Minor, nil
+	case "^":
+		// ^1.2 -> >=1.2.0 <2.0.0, ^0.2 -> >=0.2.0 <0.3.0
+		if cmp < 0 || v.Major != base.Major {
+			return false, nil
+		}
+		if base.Major == 0 && parts >= 2 {
+			return v.Minor == base.Minor, nil
+		}
+		return true, nil
+	}
+
+	return false, fmt.Errorf("invalid version constraint '%s'", constraint)
+}
+
+// matchReleaseConstraint returns the highest non-draft, non-prerelease release
+// whose tag satisfies every comma-separated constraint
+func matchReleaseConstraint(releases []GitHubRelease, constraint string) (*GitHubRelease, error) {
+	type candidate struct {
+		release *GitHubRelease
+		version semver
+	}
+
+	var candidates []candidate
+	for i := range releases {
+		r := &releases[i]
+		if r.Draft || r.Prerelease {
+			continue
+		}
+		v, parts, ok := parseSemver(r.TagName)
+		if !ok || parts != 3 {
+			continue
+		}
+
+		matched := true
+		for _, c := range strings.Split(constraint, ",") {
+			ok, err := versionMatches(v, strings.TrimSpace(c))
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			candidates = append(candidates, candidate{release: r, version: v})
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no release matches constraint '%s'", constraint)
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].version.compare(candidates[j].version) > 0
+	})
+
+	return candidates[0].release, nil
+}
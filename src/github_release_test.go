@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestParseGitHubSource tests parsing of github: bootstrap sources
+func TestParseGitHubSource(t *testing.T) {
+	tests := []struct {
+		name       string
+		source     string
+		wantErr    bool
+		owner      string
+		repo       string
+		path       string
+		constraint string
+	}{
+		{
+			name:       "latest release",
+			source:     "github:org/configs//prod/config.json@latest-release",
+			owner:      "org",
+			repo:       "configs",
+			path:       "prod/config.json",
+			constraint: "latest-release",
+		},
+		{
+			name:       "caret constraint",
+			source:     "github:org/configs//config.json@^1.2",
+			owner:      "org",
+			repo:       "configs",
+			path:       "config.json",
+			constraint: "^1.2",
+		},
+		{
+			name:       "default constraint",
+			source:     "github:org/configs//config.json",
+			owner:      "org",
+			repo:       "configs",
+			path:       "config.json",
+			constraint: "latest-release",
+		},
+		{
+			name:    "missing path separator",
+			source:  "github:org/configs/config.json",
+			wantErr: true,
+		},
+		{
+			name:    "missing repo",
+			source:  "github:org//config.json",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src, err := ParseGitHubSource(tt.source)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error for %q", tt.source)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if src.Owner != tt.owner || src.Repo != tt.repo || src.Path != tt.path || src.Constraint != tt.constraint {
+				t.Errorf("got %+v", src)
+			}
+		})
+	}
+}
+
+// TestVersionConstraints tests semver constraint matching
+func TestVersionConstraints(t *testing.T) {
+	tests := []struct {
+		version    string
+		constraint string
+		want       bool
+	}{
+		{"v1.2.0", "^1.2", true},
+		{"v1.9.3", "^1.2", true},
+		{"v2.0.0", "^1.2", false},
+		{"v1.1.9", "^1.2", false},
+		{"v0.2.5", "^0.2", true},
+		{"v0.3.0", "^0.2", false},
+		{"v1.2.7", "~1.2", true},
+		{"v1.3.0", "~1.2", false},
+		{"v1.3.0", ">=1.2.0", true},
+		{"v1.3.0", "<1.3.0", false},
+		{"v1.2.3", "=1.2.3", true},
+		{"v1.2.3-rc.1", ">=1.2.3", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version+" "+tt.constraint, func(t *testing.T) {
+			v, _, ok := parseSemver(tt.version)
+			if !ok {
+				t.Fatalf("failed to parse %s", tt.version)
+			}
+			got, err := versionMatches(v, tt.constraint)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("versionMatches(%s, %s) = %v, want %v", tt.version, tt.constraint, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestMatchReleaseConstraint tests picking the highest matching release
+func TestMatchReleaseConstraint(t *testing.T) {
+	releases := []GitHubRelease{
+		{TagName: "v1.2.0"},
+		{TagName: "v1.4.1"},
+		{TagName: "v1.5.0", Prerelease: true},
+		{TagName: "v1.6.0", Draft: true},
+		{TagName: "v2.0.0"},
+		{TagName: "nightly"},
+	}
+
+	release, err := matchReleaseConstraint(releases, "^1.2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if release.TagName != "v1.4.1" {
+		t.Errorf("expected v1.4.1, got %s", release.TagName)
+	}
+
+	release, err = matchReleaseConstraint(releases, ">=1.0.0, <1.3.0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if release.TagName != "v1.2.0" {
+		t.Errorf("expected v1.2.0, got %s", release.TagName)
+	}
+
+	if _, err := matchReleaseConstraint(releases, "^3"); err == nil {
+		t.Error("expected error when no release matches")
+	}
+}
+
+// TestResolveGitHubSource tests resolution against a fake GitHub API
+func TestResolveGitHubSource(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/org/configs/releases/latest":
+			json.NewEncoder(w).Encode(GitHubRelease{
+				TagName: "v1.4.0",
+				Assets: []GitHubReleaseAsset{
+					{Name: "SHA256SUMS", BrowserDownloadURL: server.URL + "/download/v1.4.0/SHA256SUMS"},
+				},
+			})
+		case "/repos/org/configs/releases":
+			json.NewEncoder(w).Encode([]GitHubRelease{
+				{TagName: "v1.4.0"},
+				{TagName: "v1.3.2", Assets: []GitHubReleaseAsset{
+					{Name: "config.json.sha256", BrowserDownloadURL: server.URL + "/download/v1.3.2/config.json.sha256"},
+				}},
+			})
+		case "/download/v1.4.0/SHA256SUMS":
+			w.Write([]byte(strings.Repeat("a", 64) + "  config.json\n"))
+		case "/download/v1.3.2/config.json.sha256":
+			w.Write([]byte("def456  config.json\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	origAPI, origRaw := githubAPIBaseURL, githubRawBaseURL
+	githubAPIBaseURL, githubRawBaseURL = server.URL, "https://raw.example.com"
+	defer func() { githubAPIBaseURL, githubRawBaseURL = origAPI, origRaw }()
+
+	resolved, err := resolveGitHubSource(&GitHubSource{Owner: "org", Repo: "configs", Path: "config.json", Constraint: "latest-release"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resolved.Tag != "v1.4.0" || resolved.Checksum != strings.Repeat("a", 64) || resolved.Manifest == nil {
+		t.Errorf("unexpected resolution: %+v", resolved)
+	}
+	if resolved.URL != "https://raw.example.com/org/configs/v1.4.0/config.json" {
+		t.Errorf("unexpected URL: %s", resolved.URL)
+	}
+
+	resolved, err = resolveGitHubSource(&GitHubSource{Owner: "org", Repo: "configs", Path: "config.json", Constraint: "~1.3"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resolved.Tag != "v1.3.2" || resolved.Checksum != "def456" {
+		t.Errorf("unexpected resolution: %+v", resolved)
+	}
+
+	_, err = resolveGitHubSource(&GitHubSource{Owner: "org", Repo: "configs", Path: "config.json", Constraint: "v9.9.9"})
+	if err == nil || !strings.Contains(err.Error(), "v9.9.9") {
+		t.Errorf("expected error for unknown tag, got %v", err)
+	}
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// GitSource is a git+<repo>//<path>@<ref> bootstrap source: a config inside
+// a repository fetched over the git protocol, for self-hosted forges whose
+// raw file URLs need a browser session
+type GitSource struct {
+	Repo string // Repository URL given to git (https://, ssh:// or file://)
+	Path string // Config path inside the repository
+	Ref  string // Tag, branch or full commit SHA ("" means the default branch)
+}
+
+// gitRepoSchemes are the repository URL schemes a git+ source accepts
+var gitRepoSchemes = []string{"https://", "ssh://", "file://"}
+
+// fullCommitRegex matches a full commit SHA, the only form git can fetch
+var fullCommitRegex = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// ParseGitSource parses git+https://host/org/repo//path/config.json@v1.2.0.
+// The double slash separates the repository from the path inside it.
+func ParseGitSource(source string) (*GitSource, error) {
+	invalid := fmt.Errorf("invalid git source '%s' (expected git+https://host/org/repo//path/config.json@ref)", source)
+
+	repo, ok := strings.CutPrefix(source, "git+")
+	if !ok {
+		return nil, invalid
+	}
+	scheme := ""
+	for _, s := range gitRepoSchemes {
+		if strings.HasPrefix(repo, s) {
+			scheme = s
+		}
+	}
+	if scheme == "" {
+		return nil, fmt.Errorf("git source '%s': repository must be an https://, ssh:// or file:// URL", source)
+	}
+
+	sep := strings.Index(repo[len(scheme):], "//")
+	if sep < 0 {
+		return nil, invalid
+	}
+	sep += len(scheme)
+	src := &GitSource{Repo: repo[:sep], Path: repo[sep+2:]}
+	if i := strings.LastIndex(src.Path, "@"); i >= 0 {
+		src.Path, src.Ref = src.Path[:i], src.Path[i+1:]
+		if src.Ref == "" {
+			return nil, invalid
+		}
+	}
+	if src.Path == "" || strings.HasSuffix(src.Path, "/") || strings.HasPrefix(src.Ref, "-") {
+		return nil, invalid
+	}
+	if determineRefType(src.Ref) == GitHubPinCommit && !fullCommitRegex.MatchString(src.Ref) {
+		return nil, fmt.Errorf("git source '%s': commits must be given as a full 40-character SHA", source)
+	}
+	return src, nil
+}
+
+// GitLoadOptions controls verification when loading a config from a git source
+type GitLoadOptions struct {
+	SHA256        string            // Expected SHA256 of the config (--sha256)
+	RequirePinned bool              // Refuse branches unless a checksum is given (--require-pinned)
+	Checksums     ChecksumManifest  // Manifest covering the config (--checksums-url)
+	PublicKey     ed25519.PublicKey // Require a valid <path>.sig in the same commit (--pubkey)
+	Strict        bool              // Reject keys the schema does not define (--strict)
+	SSHKey        string            // Private key for ssh:// repositories (--git-ssh-key)
+	Trust         *TrustStore       // Compare with the checksum first trusted for the source (nil disables)
+}
+
+// checkGitPin reports why a git source is not an immutable source, or nil
+// if it is: a full commit SHA, a version tag or an explicit checksum
+func checkGitPin(src *GitSource, expectedSHA256 string) error {
+	if expectedSHA256 != "" {
+		return nil
+	}
+	switch determineRefType(src.Ref) {
+	case GitHubPinCommit, GitHubPinTag:
+		return nil
+	case GitHubPinBranch:
+		return fmt.Errorf("git ref '%s' is a mutable branch (pin to a tag or commit, or pass --sha256)", src.Ref)
+	}
+	if src.Ref == "" {
+		return fmt.Errorf("git source has no ref and follows the default branch (pin to a tag or commit, or pass --sha256)")
+	}
+	return fmt.Errorf("git ref '%s' is not a recognizable tag or commit (pass --sha256)", src.Ref)
+}
+
+// loadConfigFromGit fetches a config from a repository and verifies it like
+// a URL source: checksum, signature and pinning policy
+func loadConfigFromGit(src *GitSource, opts GitLoadOptions) (*Config, error) {
+	expectedSHA256 := opts.SHA256
+	if expectedSHA256 == "" && opts.Checksums != nil {
+		name := configManifestName(src.Path)
+		checksum, ok := opts.Checksums.Lookup(name)
+		if !ok {
+			return nil, fmt.Errorf("checksum manifest has no entry for %s", name)
+		}
+		expectedSHA256 = checksum
+		fmt.Printf("✅ Using SHA256 for %s from checksum manifest\n", name)
+	}
+
+	pinErr := checkGitPin(src, opts.SHA256)
+	if opts.RequirePinned && pinErr != nil {
+		return nil, fmt.Errorf("--require-pinned: %w", pinErr)
+	}
+	if pinErr != nil {
+		warn(WarningMutableRef, "Git: %v", pinErr)
+	}
+
+	ref := src.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+	fmt.Printf("📥 Fetching %s from %s@%s\n", src.Path, src.Repo, ref)
+	repo, err := fetchGitRef(src.Repo, ref, opts.SSHKey)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(repo.dir)
+	fmt.Printf("📌 Fetched commit %s\n", repo.commit[:12])
+
+	if fullCommitRegex.MatchString(src.Ref) && repo.commit != src.Ref {
+		return nil, fmt.Errorf("fetched commit %s does not match requested %s", repo.commit, src.Ref)
+	}
+
+	data, err := repo.show(src.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	if expectedSHA256 != "" {
+		if err := verifyChecksum(data, expectedSHA256); err != nil {
+			return nil, err
+		}
+		fmt.Printf("✅ SHA256 verified\n")
+	}
+
+	// The signature must come from the same commit as the config
+	if opts.PublicKey != nil {
+		signature, err := repo.show(src.Path + SignatureExtension)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch signature: %w", err)
+		}
+		if err := verifySignature(data, string(signature), opts.PublicKey); err != nil {
+			return nil, err
+		}
+		fmt.Printf("✅ Signature verified\n")
+	}
+
+	config, err := parseConfigData(data, LoadOptions{Strict: opts.Strict, File: src.Path, Checksums: opts.Checksums})
+	if err != nil {
+		return nil, err
+	}
+	if config.Policy != nil && config.Policy.RequirePinned && pinErr != nil {
+		return nil, fmt.Errorf("config policy requires a pinned source: %w", pinErr)
+	}
+
+	if opts.Trust != nil {
+		source := "git+" + src.Repo + "//" + src.Path
+		if src.Ref != "" {
+			source += "@" + src.Ref
+		}
+		if err := opts.Trust.Check(source, data, config.Version, expectedSHA256 != ""); err != nil {
+			return nil, err
+		}
+	}
+
+	fmt.Printf("✅ Config loaded and validated\n")
+	return config, nil
+}
+
+// gitCheckout is a temporary repository holding one fetched commit
+type gitCheckout struct {
+	dir    string
+	commit string
+	env    []string
+}
+
+// fetchGitRef fetches a single commit into a temporary repository without
+// history or file contents; blobs are fetched lazily by show. Prompts are
+// disabled so a missing credential fails instead of hanging.
+func fetchGitRef(repoURL, ref, sshKey string) (*gitCheckout, error) {
+	dir, err := os.MkdirTemp("", "sink-git-*")
+	if err != nil {
+		return nil, err
+	}
+	repo := &gitCheckout{dir: dir, env: []string{"GIT_TERMINAL_PROMPT=0"}}
+	if sshKey != "" {
+		repo.env = append(repo.env, "GIT_SSH_COMMAND=ssh -i "+shellQuote(sshKey)+" -o IdentitiesOnly=yes -o BatchMode=yes")
+	}
+
+	steps := [][]string{
+		{"init", "-q"},
+		{"remote", "add", "origin", repoURL},
+		{"config", "remote.origin.promisor", "true"},
+		{"config", "remote.origin.partialclonefilter", "blob:none"},
+		{"fetch", "-q", "--depth", "1", "--filter=blob:none", "origin", ref},
+	}
+	for _, args := range steps {
+		if _, err := repo.git(args...); err != nil {
+			os.RemoveAll(dir)
+			return nil, err
+		}
+	}
+	commit, err := repo.git("rev-parse", "FETCH_HEAD")
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	repo.commit = strings.TrimSpace(string(commit))
+	return repo, nil
+}
+
+// show returns a file's contents at the fetched commit
+func (r *gitCheckout) show(path string) ([]byte, error) {
+	data, err := r.git("show", "FETCH_HEAD:"+path)
+	if err != nil {
+		return nil, fmt.Errorf("%s not found at commit %s: %w", path, r.commit[:12], err)
+	}
+	return data, nil
+}
+
+// git runs a git command in the repository, bounded by the HTTP timeout
+func (r *gitCheckout) git(args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), httpTimeout(DefaultHTTPTimeout))
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = r.dir
+	cmd.Env = append(os.Environ(), r.env...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
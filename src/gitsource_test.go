@@ -0,0 +1,161 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestParseGitSource tests splitting git+ sources into repository, path and ref
+func TestParseGitSource(t *testing.T) {
+	sha := strings.Repeat("a1", 20)
+	tests := []struct {
+		source  string
+		want    GitSource
+		wantErr string
+	}{
+		{"git+https://git.example.com/ops/configs//lab/config.json@v1.2.0",
+			GitSource{Repo: "https://git.example.com/ops/configs", Path: "lab/config.json", Ref: "v1.2.0"}, ""},
+		{"git+ssh://git@gitlab.example.com:2222/ops/configs.git//config.json@main",
+			GitSource{Repo: "ssh://git@gitlab.example.com:2222/ops/configs.git", Path: "config.json", Ref: "main"}, ""},
+		{"git+file:///srv/git/configs//config.json",
+			GitSource{Repo: "file:///srv/git/configs", Path: "config.json"}, ""},
+		{"git+https://git.example.com/ops/configs//config.json@" + sha,
+			GitSource{Repo: "https://git.example.com/ops/configs", Path: "config.json", Ref: sha}, ""},
+		{"git+https://git.example.com/ops/configs//config.json@abc1234", GitSource{}, "full 40-character SHA"},
+		{"git+https://git.example.com/ops/configs/config.json", GitSource{}, "invalid git source"},
+		{"git+https://git.example.com/ops/configs//config.json@", GitSource{}, "invalid git source"},
+		{"git+https://git.example.com/ops/configs//lab/", GitSource{}, "invalid git source"},
+		{"git+https://git.example.com/ops/configs//config.json@--upload-pack=x", GitSource{}, "invalid git source"},
+		{"git+http://git.example.com/ops/configs//config.json", GitSource{}, "https://, ssh:// or file://"},
+	}
+	for _, tt := range tests {
+		got, err := ParseGitSource(tt.source)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseGitSource(%q): expected error containing %q, got %v", tt.source, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil || *got != tt.want {
+			t.Errorf("ParseGitSource(%q) = %+v, %v; want %+v", tt.source, got, err, tt.want)
+		}
+	}
+}
+
+// gitTestRepo creates a repository with a config committed on main and
+// tagged v1.0.0, then a second commit on main. It returns the repository URL
+// and the tagged commit.
+func gitTestRepo(t *testing.T, config string, extra map[string]string) (string, string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) string {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	write := func(name, content string) {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	git("init", "-q", "-b", "main")
+	write("lab/config.json", config)
+	for name, content := range extra {
+		write(name, content)
+	}
+	git("add", ".")
+	git("commit", "-q", "-m", "config")
+	git("tag", "v1.0.0")
+	tagged := git("rev-parse", "HEAD")
+
+	write("lab/config.json", strings.Replace(config, `"name": "Linux"`, `"name": "Changed"`, 1))
+	git("commit", "-q", "-am", "change")
+	return "file://" + dir, tagged
+}
+
+const gitTestConfig = `{"version": "1.0.0", "platforms": [{"os": "linux", "match": "Linux", "name": "Linux", "install_steps": [{"name": "hello", "command": "echo hi"}]}]}`
+
+// TestLoadConfigFromGit tests fetching configs by tag, commit and branch
+func TestLoadConfigFromGit(t *testing.T) {
+	repo, tagged := gitTestRepo(t, gitTestConfig, nil)
+	load := func(ref string, opts GitLoadOptions) (*Config, error) {
+		return loadConfigFromGit(&GitSource{Repo: repo, Path: "lab/config.json", Ref: ref}, opts)
+	}
+
+	config, err := load("v1.0.0", GitLoadOptions{RequirePinned: true})
+	if err != nil {
+		t.Fatalf("load by tag: %v", err)
+	}
+	if config.Platforms[0].Name != "Linux" {
+		t.Errorf("expected the tagged config, got platform %q", config.Platforms[0].Name)
+	}
+
+	if config, err = load(tagged, GitLoadOptions{RequirePinned: true}); err != nil || config.Platforms[0].Name != "Linux" {
+		t.Errorf("load by commit: %+v, %v", config, err)
+	}
+
+	// The branch has moved on past the tag
+	if config, err = load("main", GitLoadOptions{}); err != nil || config.Platforms[0].Name != "Changed" {
+		t.Errorf("load by branch: %+v, %v", config, err)
+	}
+	if _, err := load("main", GitLoadOptions{RequirePinned: true}); err == nil || !strings.Contains(err.Error(), "mutable branch") {
+		t.Errorf("expected --require-pinned to refuse a branch, got %v", err)
+	}
+	if _, err := load("", GitLoadOptions{RequirePinned: true}); err == nil || !strings.Contains(err.Error(), "default branch") {
+		t.Errorf("expected --require-pinned to refuse the default branch, got %v", err)
+	}
+
+	// A checksum pins a branch
+	sum := sha256.Sum256([]byte(strings.Replace(gitTestConfig, `"name": "Linux"`, `"name": "Changed"`, 1)))
+	if _, err := load("main", GitLoadOptions{RequirePinned: true, SHA256: hex.EncodeToString(sum[:])}); err != nil {
+		t.Errorf("expected a checksummed branch to load, got %v", err)
+	}
+	if _, err := load("v1.0.0", GitLoadOptions{SHA256: hex.EncodeToString(sum[:])}); err == nil || !strings.Contains(err.Error(), "SHA256 mismatch") {
+		t.Errorf("expected checksum mismatch, got %v", err)
+	}
+
+	if _, err := loadConfigFromGit(&GitSource{Repo: repo, Path: "missing.json", Ref: "v1.0.0"}, GitLoadOptions{}); err == nil || !strings.Contains(err.Error(), "missing.json not found") {
+		t.Errorf("expected missing file error, got %v", err)
+	}
+	if _, err := load("v9.9.9", GitLoadOptions{}); err == nil || !strings.Contains(err.Error(), "git fetch") {
+		t.Errorf("expected fetch error for an unknown tag, got %v", err)
+	}
+}
+
+// TestLoadConfigFromGit_Signature tests that the signature is read from the same commit
+func TestLoadConfigFromGit_Signature(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, _ := gitTestRepo(t, gitTestConfig, map[string]string{
+		"lab/config.json.sig": signData([]byte(gitTestConfig), priv),
+	})
+
+	if _, err := loadConfigFromGit(&GitSource{Repo: repo, Path: "lab/config.json", Ref: "v1.0.0"}, GitLoadOptions{PublicKey: pub}); err != nil {
+		t.Errorf("expected signed tag to load, got %v", err)
+	}
+	// main changed the config but not its signature
+	if _, err := loadConfigFromGit(&GitSource{Repo: repo, Path: "lab/config.json", Ref: "main"}, GitLoadOptions{PublicKey: pub}); err == nil || !strings.Contains(err.Error(), "signature verification failed") {
+		t.Errorf("expected signature failure on main, got %v", err)
+	}
+}
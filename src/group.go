@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Group failure policies
+const (
+	GroupPolicyAll     = "all"      // Every step must succeed (default)
+	GroupPolicyAny     = "any"      // One success is enough, e.g. redundant mirrors
+	GroupPolicyAtLeast = "at_least" // At least at_least steps must succeed
+)
+
+// GroupStep runs its steps in order as one unit whose outcome follows a
+// failure policy, to model redundant installation paths such as downloading
+// from whichever mirror works
+type GroupStep struct {
+	Group Group `json:"group"`
+}
+
+func (GroupStep) isStep() {}
+
+// Group is the body of a group step
+type Group struct {
+	Steps   []InstallStep `json:"steps"`
+	Policy  string        `json:"policy,omitempty"`   // GroupPolicyAll, GroupPolicyAny or GroupPolicyAtLeast
+	AtLeast int           `json:"at_least,omitempty"` // Successes needed with policy "at_least"
+
+	// FailFast stops the group as soon as its policy can no longer be met
+	// (the default); false runs every step and then reports
+	FailFast *bool `json:"fail_fast,omitempty"`
+}
+
+// required returns how many steps must succeed
+func (g Group) required() int {
+	switch g.Policy {
+	case GroupPolicyAny:
+		return 1
+	case GroupPolicyAtLeast:
+		return g.AtLeast
+	}
+	return len(g.Steps)
+}
+
+// describePolicy names the policy for messages, e.g. "at_least 2"
+func (g Group) describePolicy() string {
+	switch g.Policy {
+	case "":
+		return GroupPolicyAll
+	case GroupPolicyAtLeast:
+		return fmt.Sprintf("%s %d", GroupPolicyAtLeast, g.AtLeast)
+	}
+	return g.Policy
+}
+
+// validate checks the policy and the member steps. Members run as part of
+// the group, so they cannot be groups, have their own maintenance window,
+// failure policy or undo command, or stay pending for a later re-check.
+func (g Group) validate() error {
+	if len(g.Steps) == 0 {
+		return fmt.Errorf("needs at least one step")
+	}
+	switch g.Policy {
+	case "", GroupPolicyAll, GroupPolicyAny:
+		if g.AtLeast != 0 {
+			return fmt.Errorf("at_least is only used with policy \"at_least\"")
+		}
+	case GroupPolicyAtLeast:
+		if g.AtLeast < 1 || g.AtLeast > len(g.Steps) {
+			return fmt.Errorf("at_least must be between 1 and the number of steps (%d), got %d", len(g.Steps), g.AtLeast)
+		}
+	default:
+		return fmt.Errorf("invalid policy '%s', must be one of: all, any, at_least", g.Policy)
+	}
+
+	for i, step := range g.Steps {
+		switch v := step.Step.(type) {
+		case GroupStep:
+			return fmt.Errorf("step[%d] %s: groups cannot be nested", i, step.Name)
+		case CheckErrorStep:
+			if v.Recheck {
+				return fmt.Errorf("step[%d] %s: recheck is not supported inside a group", i, step.Name)
+			}
+		}
+		if step.Window != "" {
+			return fmt.Errorf("step[%d] %s: window is not supported inside a group (set it on the group)", i, step.Name)
+		}
+		if step.FailurePolicy != "" {
+			return fmt.Errorf("step[%d] %s: failure_policy is not supported inside a group (use the group's policy)", i, step.Name)
+		}
+		if step.Undo != "" {
+			return fmt.Errorf("step[%d] %s: undo is not supported inside a group (set it on the group)", i, step.Name)
+		}
+	}
+	if err := validateStepAnnotations(g.Steps); err != nil {
+		return err
+	}
+	return validateUniqueStepNames(g.Steps)
+}
+
+// executeGroup runs a group's steps, reporting each as a child event of the
+// group, and stops once the policy is met or, with fail_fast, once it can no
+// longer be. Facts refreshed by a member are seen by the members after it,
+// and returned for the steps after the group.
+func (e *Executor) executeGroup(stepName string, step GroupStep, facts Facts) (StepResult, Facts) {
+	g := step.Group
+	required := g.required()
+	failFast := g.FailFast == nil || *g.FailFast
+
+	parent := e.parentStep
+	e.parentStep = stepName
+	defer func() { e.parentStep = parent }()
+
+	var results []StepResult
+	var failures []string
+	succeeded := 0
+	for i, member := range g.Steps {
+		if succeeded >= required {
+			break
+		}
+		if failFast && len(g.Steps)-len(failures) < required {
+			break
+		}
+		if i > 0 && e.DeadlineExceeded() {
+			failures = append(failures, fmt.Sprintf("%s: %v", member.Name, errRunDeadlineExceeded))
+			break
+		}
+
+		result, refreshed := e.executeStep(member, facts)
+		facts = refreshed
+		results = append(results, result)
+		if result.Error == "" && result.Status != "deferred" {
+			succeeded++
+		} else {
+			failures = append(failures, fmt.Sprintf("%s: %s", member.Name, result.Error))
+		}
+	}
+
+	summary := fmt.Sprintf("%d of %d steps succeeded (policy %s)", succeeded, len(g.Steps), g.describePolicy())
+	if skipped := len(g.Steps) - len(results); skipped > 0 {
+		summary += fmt.Sprintf(", %d not run", skipped)
+	}
+	if succeeded < required {
+		return StepResult{
+			StepName:   stepName,
+			Status:     "failed",
+			Error:      fmt.Sprintf("%s; %s", summary, strings.Join(failures, "; ")),
+			GroupSteps: results,
+		}, facts
+	}
+	return StepResult{StepName: stepName, Status: "success", Output: summary, GroupSteps: results}, facts
+}
+
+// withGroupRefreshes returns facts with the declared facts as a group's
+// members left them in after, leaving out the builtins and helpers the
+// group's own step added to after
+func (e *Executor) withGroupRefreshes(facts, after Facts) Facts {
+	if e.Gatherer == nil {
+		return facts
+	}
+	merged := make(Facts, len(facts))
+	for name, value := range facts {
+		merged[name] = value
+	}
+	for name := range e.Gatherer.definitions {
+		if value, ok := after[name]; ok {
+			merged[name] = value
+		} else {
+			delete(merged, name)
+		}
+	}
+	return merged
+}
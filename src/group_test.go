@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// mirrorGroup builds a group downloading from three mirrors
+func mirrorGroup(policy string, atLeast int, failFast *bool) InstallStep {
+	return InstallStep{Name: "Download", Step: GroupStep{Group: Group{
+		Policy:   policy,
+		AtLeast:  atLeast,
+		FailFast: failFast,
+		Steps: []InstallStep{
+			{Name: "mirror-a", Step: CommandStep{Command: "fetch a"}},
+			{Name: "mirror-b", Step: CommandStep{Command: "fetch b"}},
+			{Name: "mirror-c", Step: CommandStep{Command: "fetch c"}},
+		},
+	}}}
+}
+
+// TestGroupStep_Policies tests any, all and at_least with fail-fast and run-all
+func TestGroupStep_Policies(t *testing.T) {
+	runAll := false
+	tests := []struct {
+		name    string
+		step    InstallStep
+		calls   []string
+		wantErr string
+		output  string
+	}{
+		{"any stops at the first success", mirrorGroup(GroupPolicyAny, 0, nil),
+			[]string{"fetch a", "fetch b"}, "", "1 of 3 steps succeeded (policy any), 1 not run"},
+		{"all fails fast", mirrorGroup("", 0, nil),
+			[]string{"fetch a"}, "0 of 3 steps succeeded (policy all), 2 not run; mirror-a: command failed", ""},
+		{"all runs everything without fail_fast", mirrorGroup(GroupPolicyAll, 0, &runAll),
+			[]string{"fetch a", "fetch b", "fetch c"}, "2 of 3 steps succeeded (policy all); mirror-a: command failed", ""},
+		{"at_least 2", mirrorGroup(GroupPolicyAtLeast, 2, nil),
+			[]string{"fetch a", "fetch b", "fetch c"}, "", "2 of 3 steps succeeded (policy at_least 2)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &MockTransportWithTracking{responses: map[string]MockResponse{
+				"fetch a": {stderr: "connection refused", exitCode: 7},
+				"fetch b": {stdout: "ok", exitCode: 0},
+				"fetch c": {stdout: "ok", exitCode: 0},
+			}}
+			executor := NewExecutor(mock)
+			mock.calls = nil
+
+			var children []string
+			executor.OnEvent = func(event ExecutionEvent) {
+				if event.ParentStep == "Download" && event.Status != "running" {
+					children = append(children, event.StepName+":"+event.Status)
+				}
+			}
+			result := executor.ExecuteStep(tt.step, Facts{})
+
+			if strings.Join(mock.calls, ",") != strings.Join(tt.calls, ",") {
+				t.Errorf("ran %v, want %v", mock.calls, tt.calls)
+			}
+			if tt.wantErr != "" && !strings.HasPrefix(result.Error, tt.wantErr) {
+				t.Errorf("error %q, want prefix %q", result.Error, tt.wantErr)
+			}
+			if tt.wantErr == "" && (result.Error != "" || result.Output != tt.output) {
+				t.Errorf("got %+v, want output %q", result, tt.output)
+			}
+			if len(result.GroupSteps) != len(tt.calls) || len(children) != len(tt.calls) {
+				t.Errorf("expected %d member results and child events, got %d and %v", len(tt.calls), len(result.GroupSteps), children)
+			}
+		})
+	}
+}
+
+// TestGroupStep_RefreshFacts tests that facts refreshed by a member reach
+// the steps after the group
+func TestGroupStep_RefreshFacts(t *testing.T) {
+	installed := false
+	transport := &StatefulMockTransport{
+		runFunc: func(cmd string) (string, string, int, error) {
+			switch strings.TrimSpace(cmd) {
+			case "command -v docker >/dev/null && echo true || echo false":
+				return fmt.Sprintf("%v\n", installed), "", 0, nil
+			case "install docker":
+				installed = true
+				return "", "", 0, nil
+			case "echo docker=true":
+				return "docker=true\n", "", 0, nil
+			}
+			return "", "command not mocked", 127, nil
+		},
+	}
+	gatherer := NewFactGatherer(map[string]FactDef{
+		"has_docker": {Command: "command -v docker >/dev/null && echo true || echo false", Type: "boolean"},
+	}, transport)
+	facts, err := gatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	executor := NewExecutor(transport)
+	executor.Gatherer = gatherer
+	results := executor.ExecutePlatform(Platform{InstallSteps: []InstallStep{
+		{Name: "Setup", Step: GroupStep{Group: Group{Steps: []InstallStep{
+			{Name: "Install docker", RefreshFacts: []string{"has_docker"}, Step: CommandStep{Command: "install docker"}},
+		}}}},
+		{Name: "Report", Step: CommandStep{Command: "echo docker={{.has_docker}}"}},
+	}}, facts)
+
+	if len(results) != 2 || results[1].Output != "docker=true\n" {
+		t.Errorf("expected the step after the group to see the refreshed fact, got %+v", results)
+	}
+}
+
+// TestUnmarshalGroupStep tests parsing, defaults and validation of group steps
+func TestUnmarshalGroupStep(t *testing.T) {
+	data := `{"version": "1.0.0", "defaults": {"timeout": "30s"}, "platforms": [{"os": "linux", "match": "Linux", "name": "Linux",
+		"install_steps": [{"name": "Download", "group": {"policy": "any", "steps": [
+			{"name": "mirror-a", "command": "curl -fO https://a.example.com/tool.tgz", "retry": "until"},
+			{"name": "mirror-b", "command": "curl -fO https://b.example.com/tool.tgz"}
+		]}}]}]}`
+	config, err := parseConfigData([]byte(data), LoadOptions{Strict: true})
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	group, ok := config.Platforms[0].InstallSteps[0].Step.(GroupStep)
+	if !ok || group.Group.Policy != GroupPolicyAny || len(group.Group.Steps) != 2 {
+		t.Fatalf("expected a GroupStep, got %#v", config.Platforms[0].InstallSteps[0].Step)
+	}
+	if member, ok := group.Group.Steps[0].Step.(CommandStep); !ok || string(member.Timeout) != `"30s"` {
+		t.Errorf("expected defaults to apply to members, got %#v", group.Group.Steps[0].Step)
+	}
+
+	for body, want := range map[string]string{
+		`{"steps": []}`: "needs at least one step",
+		`{"policy": "most", "steps": [{"name": "a", "command": "true"}]}`:                    "invalid policy 'most'",
+		`{"policy": "at_least", "at_least": 3, "steps": [{"name": "a", "command": "true"}]}`: "at_least must be between 1 and the number of steps (1)",
+		`{"at_least": 1, "steps": [{"name": "a", "command": "true"}]}`:                       "at_least is only used",
+		`{"steps": [{"name": "a", "group": {"steps": [{"name": "b", "command": "true"}]}}]}`: "groups cannot be nested",
+		`{"steps": [{"name": "a", "command": "true", "window": "22:00-06:00"}]}`:             "window is not supported inside a group",
+		`{"steps": [{"name": "a", "command": "true"}, {"name": "a", "command": "false"}]}`:   "duplicate",
+		`{"steps": [{"name": "a", "check": "false", "error": "no", "recheck": true}]}`:       "recheck is not supported",
+		`{"steps": [{"name": "a", "command": "true", "rate_limit": "github"}]}`:              "rate_limit",
+	} {
+		var step InstallStep
+		if err := json.Unmarshal([]byte(`{"name": "g", "group": `+body+`}`), &step); err != nil {
+			t.Fatalf("unmarshal %s: %v", body, err)
+		}
+		err := ValidateConfig(&Config{Version: "1.0.0", Platforms: []Platform{{OS: "linux", Match: "Linux", Name: "Linux", InstallSteps: []InstallStep{step}}}})
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("group %s: expected %q, got %v", body, want, err)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxExpandedHosts guards against a typo such as web[1:100000] turning a
+// deploy into an accidental fleet-wide run
+const maxExpandedHosts = 10000
+
+// hostRangeRegex matches a range in a host pattern: [01:20] or [a:f]
+var hostRangeRegex = regexp.MustCompile(`\[([0-9]+|[a-z]):([0-9]+|[a-z])\]`)
+
+// HostInfo identifies the host a run is on within a multi-host deploy,
+// exposed to templates as {{.sink.host}}, {{.sink.host_index}} and
+// {{.sink.host_count}}. Runs outside sink remote deploy are host 0 of 1.
+type HostInfo struct {
+	Name  string // Host name as targeted, without user@ and :port
+	Index int    // Position in the deploy's host list, from 0
+	Count int    // Number of hosts in the deploy
+}
+
+// facts returns the host's entries for the reserved "sink" fact
+func (h HostInfo) facts() map[string]string {
+	return map[string]string{
+		"host":       h.Name,
+		"host_index": strconv.Itoa(h.Index),
+		"host_count": strconv.Itoa(h.Count),
+	}
+}
+
+// expandHosts splits a comma-separated target list and expands ranges in
+// each pattern, so "deploy@web[01:03].prod" becomes deploy@web01.prod,
+// deploy@web02.prod and deploy@web03.prod.
+// Numeric ranges keep the zero padding of their start; letter ranges such
+// as db-[a:c] run through the alphabet. Several ranges in one pattern
+// expand to every combination.
+func expandHosts(target string) ([]string, error) {
+	var hosts []string
+	seen := map[string]bool{}
+	for _, pattern := range strings.Split(target, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			return nil, fmt.Errorf("empty host in target list '%s'", target)
+		}
+		expanded, err := expandHostPattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, host := range expanded {
+			if seen[host] {
+				return nil, fmt.Errorf("host %s is targeted more than once", host)
+			}
+			seen[host] = true
+			hosts = append(hosts, host)
+			if len(hosts) > maxExpandedHosts {
+				return nil, fmt.Errorf("target expands to more than %d hosts", maxExpandedHosts)
+			}
+		}
+	}
+	return hosts, nil
+}
+
+// expandHostPattern expands the first range in pattern and recurses for the rest
+func expandHostPattern(pattern string) ([]string, error) {
+	loc := hostRangeRegex.FindStringSubmatchIndex(pattern)
+	if loc == nil {
+		if strings.ContainsAny(pattern, "[]") {
+			return nil, fmt.Errorf("invalid host pattern '%s' (ranges look like [01:20] or [a:f])", pattern)
+		}
+		return []string{pattern}, nil
+	}
+	prefix, suffix := pattern[:loc[0]], pattern[loc[1]:]
+	start, end := pattern[loc[2]:loc[3]], pattern[loc[4]:loc[5]]
+
+	values, err := hostRangeValues(start, end)
+	if err != nil {
+		return nil, fmt.Errorf("host pattern '%s': %w", pattern, err)
+	}
+
+	rest, err := expandHostPattern(suffix)
+	if err != nil {
+		return nil, err
+	}
+	if len(values)*len(rest) > maxExpandedHosts {
+		return nil, fmt.Errorf("host pattern '%s' expands to more than %d hosts", pattern, maxExpandedHosts)
+	}
+	var hosts []string
+	for _, value := range values {
+		for _, tail := range rest {
+			hosts = append(hosts, prefix+value+tail)
+		}
+	}
+	return hosts, nil
+}
+
+// hostRangeValues lists the values of a [start:end] range
+func hostRangeValues(start, end string) ([]string, error) {
+	startLetter := start[0] >= 'a' && start[0] <= 'z'
+	endLetter := end[0] >= 'a' && end[0] <= 'z'
+	if startLetter != endLetter {
+		return nil, fmt.Errorf("range [%s:%s] mixes letters and numbers", start, end)
+	}
+
+	var values []string
+	if startLetter {
+		if start[0] > end[0] {
+			return nil, fmt.Errorf("range [%s:%s] runs backwards", start, end)
+		}
+		for c := start[0]; c <= end[0]; c++ {
+			values = append(values, string(c))
+		}
+		return values, nil
+	}
+
+	from, err := strconv.Atoi(start)
+	if err != nil {
+		return nil, err
+	}
+	to, err := strconv.Atoi(end)
+	if err != nil {
+		return nil, err
+	}
+	if from > to {
+		return nil, fmt.Errorf("range [%s:%s] runs backwards", start, end)
+	}
+	if to-from >= maxExpandedHosts {
+		return nil, fmt.Errorf("range [%s:%s] expands to more than %d hosts", start, end, maxExpandedHosts)
+	}
+	width := 0
+	if len(start) > 1 && start[0] == '0' {
+		width = len(start)
+	}
+	for n := from; n <= to; n++ {
+		values = append(values, fmt.Sprintf("%0*d", width, n))
+	}
+	return values, nil
+}
+
+// targetHostName strips user@ and :port from an SSH target
+func targetHostName(target string) string {
+	if i := strings.LastIndex(target, "@"); i >= 0 {
+		target = target[i+1:]
+	}
+	if i := strings.LastIndex(target, ":"); i > 0 {
+		target = target[:i]
+	}
+	return target
+}
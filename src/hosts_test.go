@@ -0,0 +1,56 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestExpandHosts tests host range patterns in deploy targets
+func TestExpandHosts(t *testing.T) {
+	tests := []struct {
+		target  string
+		want    []string
+		wantErr string
+	}{
+		{"user@host", []string{"user@host"}, ""},
+		{"a, b", []string{"a", "b"}, ""},
+		{"deploy@web[08:10].prod", []string{"deploy@web08.prod", "deploy@web09.prod", "deploy@web10.prod"}, ""},
+		{"node[1:3]:2222", []string{"node1:2222", "node2:2222", "node3:2222"}, ""},
+		{"db-[a:b][1:2]", []string{"db-a1", "db-a2", "db-b1", "db-b2"}, ""},
+		{"web[01:02],db", []string{"web01", "web02", "db"}, ""},
+		{"web[3:1]", nil, "runs backwards"},
+		{"web[a:3]", nil, "mixes letters and numbers"},
+		{"web[1-3]", nil, "invalid host pattern"},
+		{"web[1:2],web2", nil, "targeted more than once"},
+		{"a,,b", nil, "empty host"},
+		{"web[1:20000]", nil, "more than 10000 hosts"},
+		{"web[1:200][1:200]", nil, "more than 10000 hosts"},
+	}
+
+	for _, tt := range tests {
+		got, err := expandHosts(tt.target)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expandHosts(%q): expected error containing %q, got %v", tt.target, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("expandHosts(%q) = %v, %v; want %v", tt.target, got, err, tt.want)
+		}
+	}
+}
+
+// TestTargetHostName tests stripping user@ and :port from SSH targets
+func TestTargetHostName(t *testing.T) {
+	for target, want := range map[string]string{
+		"web01.prod":             "web01.prod",
+		"deploy@web01.prod":      "web01.prod",
+		"deploy@web01.prod:2222": "web01.prod",
+	} {
+		if got := targetHostName(target); got != want {
+			t.Errorf("targetHostName(%q) = %q, want %q", target, got, want)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"path"
+)
+
+// Install scopes (install_scope) choose between installing for the user
+// running sink and for the whole system
+const (
+	InstallScopeUser   = "user"   // Into the user's home; needs no privileges
+	InstallScopeSystem = "system" // System-wide; needs root or passwordless sudo
+	InstallScopeAuto   = "auto"   // System-wide when possible, otherwise for the user (default)
+)
+
+// Privilege escalation found by the preflight probe (ExecutionContext.Sudo)
+const (
+	SudoRoot         = "root"         // Already running as root
+	SudoPasswordless = "passwordless" // sudo -n works
+	SudoNone         = "none"         // Neither
+)
+
+// systemBinDir is where system-wide installs put executables
+const systemBinDir = "/usr/local/bin"
+
+// validateInstallScope checks an install_scope value
+func validateInstallScope(scope string) error {
+	switch scope {
+	case "", InstallScopeUser, InstallScopeSystem, InstallScopeAuto:
+		return nil
+	}
+	return fmt.Errorf("invalid install_scope '%s', must be one of: user, system, auto", scope)
+}
+
+// resolveInstallScope returns the scope a run installs into. "auto" (or no
+// scope) is system when the host allows it; "system" on a host that does
+// not is an error, found before any step runs. When the probe could not
+// tell, "auto" falls back to user and "system" is taken on trust.
+func resolveInstallScope(scope string, ctx ExecutionContext) (string, error) {
+	privileged := ctx.Sudo == SudoRoot || ctx.Sudo == SudoPasswordless
+	switch scope {
+	case InstallScopeUser:
+		return InstallScopeUser, nil
+	case InstallScopeSystem:
+		if ctx.Sudo == SudoNone {
+			return "", fmt.Errorf("install_scope \"system\" needs root or passwordless sudo, and %s has neither (use \"auto\" to fall back to a user install)", ctx.User)
+		}
+		return InstallScopeSystem, nil
+	}
+	if privileged {
+		return InstallScopeSystem, nil
+	}
+	return InstallScopeUser, nil
+}
+
+// installScopeFacts exposes the install scope as {{.sink.*}} facts:
+// bin_dir is the directory to install executables into for the resolved
+// scope, and sudo is the prefix ("sudo " or "") commands that write there
+// need. The directories are only known for POSIX hosts.
+func installScopeFacts(scope string, ctx ExecutionContext) map[string]interface{} {
+	resolved, err := resolveInstallScope(scope, ctx)
+	if err != nil {
+		resolved = InstallScopeSystem
+	}
+	userBinDir, systemDir := "", ""
+	if ctx.Shell == ShellPOSIX {
+		systemDir = systemBinDir
+		if ctx.Home != "" {
+			userBinDir = path.Join(ctx.Home, ".local", "bin")
+		}
+	}
+
+	facts := map[string]interface{}{
+		"install_scope":  resolved,
+		"user_bin_dir":   userBinDir,
+		"system_bin_dir": systemDir,
+		"has_sudo":       ctx.Sudo == SudoRoot || ctx.Sudo == SudoPasswordless,
+		"bin_dir":        userBinDir,
+		"sudo":           "",
+	}
+	if resolved == InstallScopeSystem {
+		facts["bin_dir"] = systemDir
+		if ctx.Sudo == SudoPasswordless {
+			facts["sudo"] = "sudo "
+		}
+	}
+	return facts
+}
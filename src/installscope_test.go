@@ -0,0 +1,78 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestResolveInstallScope tests choosing between user and system installs
+func TestResolveInstallScope(t *testing.T) {
+	tests := []struct {
+		scope   string
+		sudo    string
+		want    string
+		wantErr bool
+	}{
+		{scope: "", sudo: SudoRoot, want: InstallScopeSystem},
+		{scope: InstallScopeAuto, sudo: SudoPasswordless, want: InstallScopeSystem},
+		{scope: InstallScopeAuto, sudo: SudoNone, want: InstallScopeUser},
+		{scope: InstallScopeAuto, sudo: "", want: InstallScopeUser},
+		{scope: InstallScopeUser, sudo: SudoRoot, want: InstallScopeUser},
+		{scope: InstallScopeSystem, sudo: SudoPasswordless, want: InstallScopeSystem},
+		{scope: InstallScopeSystem, sudo: "", want: InstallScopeSystem},
+		{scope: InstallScopeSystem, sudo: SudoNone, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := resolveInstallScope(tt.scope, ExecutionContext{User: "dev", Sudo: tt.sudo})
+		if tt.wantErr {
+			if err == nil || !strings.Contains(err.Error(), "dev has neither") {
+				t.Errorf("%q with sudo %q: expected an error, got %q, %v", tt.scope, tt.sudo, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%q with sudo %q: expected %s, got %q, %v", tt.scope, tt.sudo, tt.want, got, err)
+		}
+	}
+
+	if err := validateInstallScope("global"); err == nil || !strings.Contains(err.Error(), "invalid install_scope 'global'") {
+		t.Errorf("expected an invalid scope error, got %v", err)
+	}
+}
+
+// TestInstallScopeFacts tests the {{.sink.bin_dir}} and {{.sink.sudo}}
+// helpers steps install with
+func TestInstallScopeFacts(t *testing.T) {
+	transport := &MockTransport{responses: map[string]MockResponse{
+		preflightScript: {stdout: "home=/home/dev\nsudo=passwordless\n"},
+	}}
+	executor := NewExecutor(transport)
+	if ctx := executor.GetContext(); ctx.Home != "/home/dev" || ctx.Sudo != SudoPasswordless {
+		t.Fatalf("unexpected context %+v", ctx)
+	}
+
+	command := "{{.sink.sudo}}install -m 755 jq {{.sink.bin_dir}}/jq{{if .sink.has_sudo}} # {{.sink.install_scope}}{{end}}"
+	tests := []struct {
+		scope string
+		want  string
+	}{
+		{scope: "", want: "sudo install -m 755 jq /usr/local/bin/jq # system"},
+		{scope: InstallScopeUser, want: "install -m 755 jq /home/dev/.local/bin/jq # user"},
+	}
+	for _, tt := range tests {
+		executor.InstallScope = tt.scope
+		got, err := executor.interpolate(command, executor.stepFacts(InstallStep{Name: "jq"}, Facts{}))
+		if err != nil || got != tt.want {
+			t.Errorf("scope %q: interpolated %q, %v", tt.scope, got, err)
+		}
+	}
+
+	facts := installScopeFacts(InstallScopeSystem, ExecutionContext{Shell: ShellPOSIX, Sudo: SudoRoot})
+	if facts["sudo"] != "" || facts["bin_dir"] != systemBinDir {
+		t.Errorf("expected root to install without sudo, got %v", facts)
+	}
+	facts = installScopeFacts("", ExecutionContext{Shell: ShellPowerShell})
+	if facts["bin_dir"] != "" || facts["system_bin_dir"] != "" {
+		t.Errorf("expected no directories without a POSIX shell, got %v", facts)
+	}
+}
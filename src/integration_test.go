@@ -1,6 +1,7 @@
 package main
 
 import (
+	"path/filepath"
 	"runtime"
 	"strings"
 	"testing"
@@ -122,6 +123,7 @@ func TestIntegrationFullStack(t *testing.T) {
 // TestIntegrationWithRemediation tests check-remediate flow
 func TestIntegrationWithRemediation(t *testing.T) {
 	transport := NewLocalTransport()
+	marker := filepath.Join(t.TempDir(), "sink-test-marker")
 
 	// Create a platform with check-remediate step
 	platform := Platform{
@@ -132,11 +134,11 @@ func TestIntegrationWithRemediation(t *testing.T) {
 			{
 				Name: "Check and install marker",
 				Step: CheckRemediateStep{
-					Check: "test -f /tmp/sink-test-marker",
+					Check: "test -f " + marker,
 					OnMissing: []RemediationStep{
 						{
 							Name:    "Create marker",
-							Command: "touch /tmp/sink-test-marker",
+							Command: "touch " + marker,
 						},
 					},
 				},
@@ -173,9 +175,6 @@ func TestIntegrationWithRemediation(t *testing.T) {
 	if len(results2[0].RemediationSteps) != 0 {
 		t.Errorf("expected no remediation steps on second run, got %d", len(results2[0].RemediationSteps))
 	}
-
-	// Clean up
-	transport.Run("rm -f /tmp/sink-test-marker")
 }
 
 // TestIntegrationFactExport tests fact export as environment variables
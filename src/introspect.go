@@ -0,0 +1,278 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// Introspection describes what this sink binary supports, so orchestration
+// tools managing a fleet with mixed sink versions can check a host's binary
+// before sending it a config that needs a newer feature
+type Introspection struct {
+	Version       string                `json:"version"`
+	SchemaVersion string                `json:"schema_version"`
+	OS            string                `json:"os"`
+	Arch          string                `json:"arch"`
+	Commands      []string              `json:"commands"`
+	StepTypes     []IntrospectStepType  `json:"step_types"`
+	Transports    []IntrospectTransport `json:"transports"`
+	FactSources   []string              `json:"fact_sources"`
+	FactTypes     []string              `json:"fact_types"`
+	VariableTypes []string              `json:"variable_types"`
+	ConfigFields  []string              `json:"config_fields"` // Top-level config keys the schema defines
+	Features      []string              `json:"features"`
+	ExitCodes     map[string]int        `json:"exit_codes"`
+}
+
+// IntrospectStepType is a step variant and the keys that select it
+type IntrospectStepType struct {
+	Name        string   `json:"name"`
+	Keys        []string `json:"keys"`
+	Description string   `json:"description,omitempty"`
+}
+
+// IntrospectTransport is a way of running commands and what it supports
+type IntrospectTransport struct {
+	Name         string `json:"name"`
+	Description  string `json:"description"`
+	Shell        string `json:"shell"`
+	Cancel       bool   `json:"cancel"`
+	Stdin        bool   `json:"stdin"`
+	FileTransfer bool   `json:"file_transfer"`
+	Streaming    bool   `json:"streaming"`
+}
+
+// introspectStepTypes lists the step variants InstallStep.UnmarshalJSON
+// recognizes, and library steps expanded before it, by the keys that select
+// them
+var introspectStepTypes = []IntrospectStepType{
+	{Name: "command", Keys: []string{"command"}},
+	{Name: "check_error", Keys: []string{"check", "error"}},
+	{Name: "check_remediate", Keys: []string{"check", "on_missing"}},
+	{Name: "copy", Keys: []string{"copy"}},
+	{Name: "fetch", Keys: []string{"fetch"}},
+	{Name: "verify_checksum", Keys: []string{"verify_checksum"}},
+	{Name: "group", Keys: []string{"group"}},
+	{Name: "pause", Keys: []string{"pause"}},
+	{Name: "library", Keys: []string{"use"}},
+	{Name: "error", Keys: []string{"error"}},
+}
+
+// introspectCommands lists the commands main dispatches
+var introspectCommands = []string{
+	"execute", "bootstrap", "remote", "facts", "console", "validate", "explain",
+	"test", "lock", "lsp", "introspect", "schema", "templates", "serve-config", "install-agent", "gc", "db",
+	"checksum", "sign", "verify-signature", "package", "export", "tf-wrapper", "version",
+}
+
+// introspectFeatures names optional capabilities that arrived over time.
+// Names are stable: features are only ever added, so a tool can test for
+// one with a plain membership check.
+var introspectFeatures = []string{
+	"arch_helpers",        // {{.sink.arch}} and the archMap template function
+	"audit_log",           // execute/bootstrap --audit-log to syslog
+	"break_at",            // execute --break-at
+	"broken_pipe",         // execute --on-broken-pipe and run records in the log directory
+	"bsd_platforms",       // freebsd and openbsd platforms, package_install
+	"builtin_facts",       // {{._os}}, {{._arch}}, {{._hostname}}, {{._user}}, {{._home}}, {{._cpu_count}}
+	"bundles",             // Offline bundles (sink package, execute --bundle)
+	"check_probes",        // check_file, check_command_exists and check_port
+	"cloud_init_export",   // sink export --format cloud-init
+	"config_error_frames", // file:line:column and a code frame for JSON syntax errors
+	"confirm",             // Config "confirm" messages and --confirm-host
+	"coverage",            // execute/test --coverage
+	"credential_helpers",  // --credential-helper for config downloads
+	"db",                  // sink db import/query of run results in SQLite
+	"dependencies",        // Step libraries in "dependencies", locked by sink lock
+	"deploy_reports",      // Per-host logs, results.json and --quorum for multi-host deploys
+	"distributions",       // Per-distribution steps chosen from /etc/os-release
+	"event_tags",          // "host" and "step_number" on every event
+	"event_webhooks",      // execute --event-webhook with --event-buffer and --event-overflow
+	"export_facts",        // Facts with "export" in every command's environment
+	"fact_diff",           // sink facts --diff-last
+	"fact_output",         // sink facts --output json|dotenv|shell|gha
+	"failure_policy",      // Platform and step "failure_policy": abort, continue, continue-collect
+	"fleet_manifests",     // bootstrap --manifest
+	"gc",                  // sink gc retention rules
+	"groups",              // Group steps with failure policies
+	"install_agent",       // install-agent with systemd timers or launchd
+	"install_scope",       // Config "install_scope" and {{.sink.bin_dir}}, {{.sink.sudo}}
+	"interrupts",          // Ctrl-C and --max-duration stop commands, downloads and waits at once
+	"json_events",         // execute --json
+	"jsonc_configs",       // Comments and trailing commas in configs and libraries
+	"max_duration",        // execute --max-duration
+	"posix_lint",          // validate --posix, shell_dialect and not_posix warnings
+	"preflight_context",   // Package managers, SELinux, firewall and container in the context
+	"rate_limits",         // Config "rate_limits"
+	"run_id",              // execute --run-id
+	"run_state",           // execute --state-file and --resume
+	"schema_pinning",      // Templates pin "$schema" to the release; validate warns on a major mismatch
+	"scratch",             // Step "scratch" values and run_id, step_index and attempt helpers
+	"script",              // Multi-line "script" in command steps
+	"secret_facts",        // Fact "source" from vault or aws-sm
+	"serve_probes",        // serve-config /healthz, /readyz and /status
+	"signatures",          // Detached config signatures
+	"skip_reasons",        // "skip_reason" on skipped, deferred and not_run events
+	"snapshots",           // execute --snapshot and config "snapshot"
+	"splay",               // execute --splay
+	"ssh_transport",       // execute --ssh and --ssh-* options for remote deploy
+	"stdin",               // Command "stdin" and "stdin_file"
+	"strict",              // Rejection of unknown config keys
+	"templates",           // Embedded starter configs (sink templates)
+	"test_in_docker",      // test --in-docker
+	"test_matrix",         // test --matrix
+	"tf_wrapper",          // sink tf-wrapper for Terraform external data and provisioners
+	"transcripts",         // execute --transcript
+	"ui",                  // execute --ui
+	"undo",                // Step "undo" commands and rollback events
+	"variables",           // Config "variables" and --var
+	"verify_checksum",     // "verify_checksum" step and the sha256/verifySha256 template functions
+	"warnings",            // Warnings summary, "warnings" JSON event and bootstrap --warnings-as-errors
+	"windows",             // Maintenance windows
+}
+
+// introspect describes this binary
+func introspect() Introspection {
+	var schema map[string]interface{}
+	json.Unmarshal([]byte(embeddedSchema), &schema)
+	w := &schemaWalker{root: schema}
+
+	// Step descriptions come from the schema's variants
+	descriptions := map[string]string{}
+	for _, branch := range w.nodes([]interface{}{"platforms", 0, "install_steps", 0}) {
+		if description, ok := branch["description"].(string); ok {
+			descriptions[strings.Join(variantKeys(branch), ",")] = description
+		}
+	}
+	steps := make([]IntrospectStepType, len(introspectStepTypes))
+	for i, step := range introspectStepTypes {
+		step.Description = descriptions[strings.Join(step.Keys, ",")]
+		steps[i] = step
+	}
+
+	local := NewLocalTransport()
+	caps := local.Capabilities()
+	sshCaps := (&SSHTransport{}).Capabilities()
+	properties, _ := schema["properties"].(map[string]interface{})
+	var configFields []string
+	for _, key := range sortedKeys(properties) {
+		if key != "$schema" {
+			configFields = append(configFields, key)
+		}
+	}
+	features := append([]string(nil), introspectFeatures...)
+	sort.Strings(features)
+
+	return Introspection{
+		Version:       Version,
+		SchemaVersion: SchemaVersion,
+		OS:            runtime.GOOS,
+		Arch:          runtime.GOARCH,
+		Commands:      introspectCommands,
+		StepTypes:     steps,
+		Transports: []IntrospectTransport{{
+			Name:         "local",
+			Description:  "Runs commands on this host; sink remote deploy runs a copy of sink on each SSH host",
+			Shell:        caps.Shell,
+			Cancel:       caps.Cancel,
+			Stdin:        caps.Stdin,
+			FileTransfer: caps.FileTransfer,
+			Streaming:    caps.Streaming,
+		}, {
+			Name:         "ssh",
+			Description:  "Runs commands on an SSH host with the system ssh and scp (execute --ssh)",
+			Shell:        sshCaps.Shell,
+			Cancel:       sshCaps.Cancel,
+			Stdin:        sshCaps.Stdin,
+			FileTransfer: sshCaps.FileTransfer,
+			Streaming:    sshCaps.Streaming,
+		}},
+		FactSources:   []string{"command", SecretSourceVault, SecretSourceAWSSM},
+		FactTypes:     []string{"string", "boolean", "integer"},
+		VariableTypes: []string{"string", "integer", "boolean", "enum"},
+		ConfigFields:  configFields,
+		Features:      features,
+		ExitCodes: map[string]int{
+			"success":           0,
+			"failure":           1,
+			"deferred":          ExitDeferred,
+			"deadline_exceeded": ExitDeadlineExceeded,
+			"broken_pipe":       ExitBrokenPipe,
+		},
+	}
+}
+
+// introspectCommand runs "sink introspect": report what this binary supports
+func introspectCommand() {
+	jsonOutput := loadEnvSettings().JSON
+	for _, arg := range os.Args[2:] {
+		switch arg {
+		case "-h", "--help":
+			printIntrospectHelp()
+			os.Exit(0)
+		case "--json":
+			jsonOutput = true
+		default:
+			fmt.Fprintf(os.Stderr, "Error: unexpected argument: %s\n", arg)
+			os.Exit(1)
+		}
+	}
+
+	info := introspect()
+	if jsonOutput {
+		data, _ := json.MarshalIndent(info, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+
+	fmt.Printf("sink %s (schema %s, %s/%s)\n\n", info.Version, info.SchemaVersion, info.OS, info.Arch)
+	fmt.Printf("Step types:\n")
+	for _, step := range info.StepTypes {
+		fmt.Printf("  %-16s %s\n", step.Name, strings.Join(step.Keys, " + "))
+	}
+	fmt.Printf("\nTransports:\n")
+	for _, t := range info.Transports {
+		fmt.Printf("  %-16s shell %s, cancel %t, stdin %t, file transfer %t, streaming %t\n",
+			t.Name, t.Shell, t.Cancel, t.Stdin, t.FileTransfer, t.Streaming)
+	}
+	fmt.Printf("\nFact sources:   %s\n", strings.Join(info.FactSources, ", "))
+	fmt.Printf("Fact types:     %s\n", strings.Join(info.FactTypes, ", "))
+	fmt.Printf("Variable types: %s\n", strings.Join(info.VariableTypes, ", "))
+	fmt.Printf("Config fields:  %s\n", strings.Join(info.ConfigFields, ", "))
+	fmt.Printf("Commands:       %s\n", strings.Join(info.Commands, ", "))
+	fmt.Printf("\nFeatures:\n")
+	for _, feature := range info.Features {
+		fmt.Printf("  %s\n", feature)
+	}
+}
+
+func printIntrospectHelp() {
+	fmt.Printf(`sink introspect - Report what this sink binary supports
+
+Usage:
+  sink introspect [--json]
+
+Description:
+  Lists the binary's version, schema version, step types, transports and
+  their capabilities, fact sources and types, variable types, top-level
+  config fields, commands, feature flags and exit codes. With --json the
+  report is one JSON object for orchestration tools, which can check a
+  host's sink before sending it a config that needs a newer feature.
+
+  Feature names are stable and only ever added, so checking for one is a
+  membership test, e.g. with jq:
+    sink introspect --json | jq -e '.features | index("variables")'
+
+Options:
+  --json                 Output JSON (also SINK_JSON=1)
+  -h, --help             Show this help message
+
+Examples:
+  sink introspect
+  sink introspect --json
+`)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// TestIntrospectStepTypes tests that every step variant in the schema is
+// reported, so the list cannot fall behind new step types
+func TestIntrospectStepTypes(t *testing.T) {
+	var schema map[string]interface{}
+	if err := json.Unmarshal([]byte(embeddedSchema), &schema); err != nil {
+		t.Fatal(err)
+	}
+	w := &schemaWalker{root: schema}
+
+	info := introspect()
+	reported := map[string]bool{}
+	for _, step := range info.StepTypes {
+		reported[strings.Join(step.Keys, ",")] = true
+		if step.Description == "" {
+			t.Errorf("step type %s has no description in the schema", step.Name)
+		}
+	}
+	for _, branch := range w.nodes([]interface{}{"platforms", 0, "install_steps", 0}) {
+		if keys := variantKeys(branch); variantName(branch) != "" && !reported[strings.Join(keys, ",")] {
+			t.Errorf("schema step variant %s (%v) is not reported", variantName(branch), keys)
+		}
+	}
+}
+
+// TestIntrospectJSON tests the fields orchestration tools rely on
+func TestIntrospectJSON(t *testing.T) {
+	data, err := json.Marshal(introspect())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var info map[string]interface{}
+	if err := json.Unmarshal(data, &info); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"version", "schema_version", "step_types", "transports", "fact_sources", "features", "exit_codes"} {
+		if _, ok := info[key]; !ok {
+			t.Errorf("expected %q in the report", key)
+		}
+	}
+	if info["version"] != Version || !strings.Contains(string(data), `"variables"`) || !strings.Contains(string(data), `"deferred":75`) {
+		t.Errorf("unexpected report %s", data)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+// stripJSONC turns JSONC (JSON with // and /* */ comments and trailing
+// commas) into plain JSON. Comments and trailing commas are replaced with
+// spaces, keeping newlines, so byte offsets, lines and columns in the
+// result match the source and syntax errors still point at what was
+// written. Text inside strings is left alone, and data that is already
+// plain JSON is returned unchanged.
+func stripJSONC(data []byte) []byte {
+	var out []byte
+	// blank replaces data[start:end] with spaces in out, copying data first
+	blank := func(start, end int) {
+		if out == nil {
+			out = append([]byte(nil), data...)
+		}
+		for i := start; i < end; i++ {
+			if out[i] != '\n' && out[i] != '\r' {
+				out[i] = ' '
+			}
+		}
+	}
+
+	comma := -1 // Offset of a comma that may turn out to be trailing
+	for i := 0; i < len(data); i++ {
+		switch c := data[i]; {
+		case c == '"':
+			comma = -1
+			for i++; i < len(data) && data[i] != '"'; i++ {
+				if data[i] == '\\' {
+					i++
+				}
+			}
+		case c == '/' && i+1 < len(data) && data[i+1] == '/':
+			start := i
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+			blank(start, i)
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			start := i
+			i += 2
+			for i+1 < len(data) && !(data[i] == '*' && data[i+1] == '/') {
+				i++
+			}
+			// An unterminated comment runs to the end of the input
+			i = min(i+2, len(data))
+			blank(start, i)
+			i--
+		case c == ',':
+			comma = i
+		case c == '}' || c == ']':
+			if comma >= 0 {
+				blank(comma, comma+1)
+			}
+			comma = -1
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+		default:
+			comma = -1
+		}
+	}
+
+	if out == nil {
+		return data
+	}
+	return out
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestStripJSONC tests removing comments and trailing commas from JSONC
+func TestStripJSONC(t *testing.T) {
+	tests := []struct {
+		name, input, expected string
+	}{
+		{"plain JSON", `{"a": [1, 2]}`, `{"a": [1, 2]}`},
+		{"line comment", "{\"a\": 1 // one\n}", "{\"a\": 1       \n}"},
+		{"block comment", "{/* a\nb */\"a\": 1}", "{    \n    \"a\": 1}"},
+		{"trailing commas", "{\"a\": [1, 2,],\n}", "{\"a\": [1, 2 ] \n}"},
+		{"comma before comment", "[1, // last\n]", "[1         \n]"},
+		{"comment markers in strings", `{"url": "http://x/*y*/", "s": "a\",]"}`, `{"url": "http://x/*y*/", "s": "a\",]"}`},
+		{"unterminated comment", "{} /* open", "{}        "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := string(stripJSONC([]byte(tt.input)))
+			if got != tt.expected {
+				t.Errorf("stripJSONC(%q) = %q, want %q", tt.input, got, tt.expected)
+			}
+			if len(got) != len(tt.input) {
+				t.Errorf("expected offsets kept, length %d became %d", len(tt.input), len(got))
+			}
+		})
+	}
+}
+
+// TestLoadConfig_JSONC tests loading a commented config with trailing
+// commas, and that syntax errors still point at the source
+func TestLoadConfig_JSONC(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	source := `{
+  // Hand-maintained: keep in step with the wiki
+  "version": "1.0.0",
+  "platforms": [
+    {
+      "os": "linux", "match": "linux*", "name": "Linux",
+      "install_steps": [
+        /* Pinned, see ticket */
+        {"name": "Install jq", "command": "echo jq"},
+      ],
+    },
+  ],
+}
+`
+	if err := os.WriteFile(path, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config, err := LoadConfigWithOptions(path, LoadOptions{Strict: true})
+	if err != nil {
+		t.Fatalf("expected JSONC to load, got %v", err)
+	}
+	if len(config.Platforms) != 1 || len(config.Platforms[0].InstallSteps) != 1 {
+		t.Errorf("unexpected config: %+v", config)
+	}
+
+	broken := strings.Replace(source, `"echo jq"}`, `"echo jq"} {"name": "x"}`, 1)
+	if err := os.WriteFile(path, []byte(broken), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = LoadConfig(path)
+	if err == nil || !strings.Contains(err.Error(), path+":9:54:") || !strings.Contains(err.Error(), "/* Pinned, see ticket */") {
+		t.Errorf("expected a located error framing the source, got %v", err)
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"io"
+	"sync"
+)
+
+// jsonStreamMu serializes the JSON objects sink writes to stdout. Events,
+// warnings, snapshots and downloads may come from different goroutines
+// (parallel hosts, event sinks), and an indented object written with
+// several writes could otherwise be split by another one.
+var jsonStreamMu sync.Mutex
+
+// writeJSONObject writes one marshalled JSON object and its newline with a
+// single Write, so readers of the stream always see whole objects
+func writeJSONObject(w io.Writer, data []byte) error {
+	line := make([]byte, 0, len(data)+1)
+	line = append(append(line, data...), '\n')
+
+	jsonStreamMu.Lock()
+	defer jsonStreamMu.Unlock()
+	_, err := w.Write(line)
+	return err
+}
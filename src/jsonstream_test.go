@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+)
+
+// TestEmitEvent_Concurrent tests that events emitted from several
+// goroutines come out as whole JSON objects, numbered without gaps
+func TestEmitEvent_Concurrent(t *testing.T) {
+	var out bytes.Buffer
+	executor := NewExecutor(&MockTransport{responses: map[string]MockResponse{}})
+	executor.JSONOutput = true
+	executor.Stdout = &out
+	executor.Host = HostInfo{Name: "web01", Index: 0, Count: 2}
+
+	const goroutines, events = 8, 50
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < events; i++ {
+				executor.emitEvent(ExecutionEvent{StepName: fmt.Sprintf("step-%d", g), Status: "running", Output: "line 1\nline 2"})
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	decoder := json.NewDecoder(&out)
+	seen := map[int64]bool{}
+	for {
+		var event ExecutionEvent
+		if err := decoder.Decode(&event); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("corrupt JSON stream after %d events: %v", len(seen), err)
+		}
+		if seen[event.Seq] {
+			t.Errorf("duplicate seq %d", event.Seq)
+		}
+		seen[event.Seq] = true
+		if event.Host != "web01" {
+			t.Errorf("expected events tagged with host web01, got %q", event.Host)
+		}
+	}
+	if len(seen) != goroutines*events {
+		t.Errorf("expected %d events, got %d", goroutines*events, len(seen))
+	}
+}
+
+// TestEmitEvent_StepNumber tests tagging events with the step they belong to
+func TestEmitEvent_StepNumber(t *testing.T) {
+	executor := NewExecutor(&MockTransport{responses: map[string]MockResponse{"prepare": {}, "fetch a": {}}})
+	numbers := map[string][]int{}
+	executor.OnEvent = func(event ExecutionEvent) {
+		numbers[event.StepName] = append(numbers[event.StepName], event.StepNumber)
+	}
+	executor.ExecutePlatform(Platform{InstallSteps: []InstallStep{
+		{Name: "Prepare", Step: CommandStep{Command: "prepare"}},
+		mirrorGroup(GroupPolicyAny, 0, nil),
+	}}, nil)
+
+	for name, want := range map[string]string{"Prepare": "[1 1]", "Download": "[2 2]", "mirror-a": "[3 3]"} {
+		if got := fmt.Sprint(numbers[name]); got != want {
+			t.Errorf("%s step numbers = %s, want %s", name, got, want)
+		}
+	}
+}
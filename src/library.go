@@ -0,0 +1,767 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// LockFileName is the lock file sink lock writes beside a config
+const LockFileName = "sink.lock"
+
+// ociLibraryMediaType marks the layer of an OCI artifact holding a library
+const ociLibraryMediaType = "application/vnd.sink.library.v1+json"
+
+// ociScheme is how registries are reached; tests serve them over plain HTTP
+var ociScheme = "https"
+
+var (
+	// Valid dependency name: the prefix of "use" references
+	dependencyNameRegex = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+	// A {{param "name"}} action in a library step
+	paramActionRegex = regexp.MustCompile(`\{\{\s*param\s+"([A-Za-z_][A-Za-z0-9_]*)"\s*\}\}`)
+
+	// Key="value" pairs of a WWW-Authenticate challenge
+	challengeParamRegex = regexp.MustCompile(`(\w+)="([^"]*)"`)
+)
+
+// Dependency is a published library of step templates a config composes
+// steps from, referenced by steps as "use": "<dependency>.<step>"
+type Dependency struct {
+	Source  string `json:"source"`            // https:// URL ({version} is substituted), oci://<registry>/<repository>, or a path relative to the config
+	Version string `json:"version,omitempty"` // Library version: the OCI tag, and checked against the library's own version
+	SHA256  string `json:"sha256,omitempty"`  // Expected SHA256 of the library file
+}
+
+// Library is a published set of step templates. Steps are install steps
+// without a name; string values may use {{param "x"}}, filled from the
+// "with" of the step that uses them.
+type Library struct {
+	Name        string                     `json:"name,omitempty"`
+	Version     string                     `json:"version,omitempty"`
+	Description string                     `json:"description,omitempty"`
+	Steps       map[string]json.RawMessage `json:"steps"`
+}
+
+// LockFile records the exact content each dependency of a config resolved
+// to, so every team composing the config gets the same steps
+type LockFile struct {
+	Version      int                         `json:"version"`
+	Dependencies map[string]LockedDependency `json:"dependencies"`
+}
+
+// LockedDependency is a dependency as sink lock resolved it
+type LockedDependency struct {
+	Source   string `json:"source"`
+	Version  string `json:"version,omitempty"`
+	Resolved string `json:"resolved"` // URL, path or oci://<registry>/<repository>@<manifest digest> fetched
+	SHA256   string `json:"sha256"`
+}
+
+// validateDependency checks a dependency's source and pins
+func validateDependency(name string, dep Dependency) error {
+	if !dependencyNameRegex.MatchString(name) {
+		return fmt.Errorf("invalid name (use lowercase letters, digits and _)")
+	}
+	switch {
+	case dep.Source == "":
+		return fmt.Errorf("source is required")
+	case strings.HasPrefix(dep.Source, "oci://"):
+		if _, _, err := parseOCISource(dep.Source); err != nil {
+			return err
+		}
+		if dep.Version == "" {
+			return fmt.Errorf("OCI sources need a version (the tag to pull)")
+		}
+	case strings.HasPrefix(dep.Source, "http://"):
+		if dep.SHA256 == "" {
+			return fmt.Errorf("HTTP sources require a sha256")
+		}
+	case strings.Contains(dep.Source, "://") && !strings.HasPrefix(dep.Source, "https://"):
+		return fmt.Errorf("unsupported source '%s' (use https://, oci:// or a path)", dep.Source)
+	}
+	if strings.Contains(dep.Source, "{version}") && dep.Version == "" {
+		return fmt.Errorf("source uses {version} but no version is set")
+	}
+	if dep.SHA256 != "" && !sha256Regex.MatchString(dep.SHA256) {
+		return fmt.Errorf("sha256 must be 64 lowercase hex characters")
+	}
+	return nil
+}
+
+// dependencyLocation is where a dependency is fetched from, with its
+// version substituted
+func dependencyLocation(dep Dependency) string {
+	return strings.ReplaceAll(dep.Source, "{version}", dep.Version)
+}
+
+// isLocalSource reports whether a dependency is a file beside the config
+func isLocalSource(source string) bool {
+	return !strings.Contains(source, "://")
+}
+
+// fetchDependency resolves a dependency afresh, returning the library file
+// and where it was resolved to. Local paths are relative to baseDir.
+func fetchDependency(dep Dependency, baseDir string) ([]byte, string, error) {
+	location := dependencyLocation(dep)
+	switch {
+	case strings.HasPrefix(location, "oci://"):
+		return fetchOCILibrary(location, dep.Version)
+	case isLocalSource(location):
+		data, err := os.ReadFile(filepath.Join(baseDir, location))
+		if err != nil {
+			return nil, "", err
+		}
+		return data, location, nil
+	default:
+		data, err := fetchLibraryURL(location)
+		return data, location, err
+	}
+}
+
+// fetchLocked fetches the exact content a lock file entry recorded
+func fetchLocked(locked LockedDependency, baseDir string) ([]byte, error) {
+	var data []byte
+	var err error
+	switch {
+	case strings.HasPrefix(locked.Resolved, "oci://"):
+		ref, _, _ := strings.Cut(locked.Resolved, "@")
+		registry, repository, perr := parseOCISource(ref)
+		if perr != nil {
+			return nil, perr
+		}
+		data, err = fetchOCIBlob(registry, repository, "sha256:"+locked.SHA256)
+	case isLocalSource(locked.Resolved):
+		data, err = os.ReadFile(filepath.Join(baseDir, locked.Resolved))
+	default:
+		data, err = fetchLibraryURL(locked.Resolved)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyChecksum(data, locked.SHA256); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// fetchLibraryURL downloads a library file over HTTP(S)
+func fetchLibraryURL(rawURL string) ([]byte, error) {
+	resp, err := httpGet(rawURL, httpTimeout(DefaultHTTPTimeout))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: HTTP %d", rawURL, resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// parseOCISource splits oci://<registry>/<repository>
+func parseOCISource(source string) (registry, repository string, err error) {
+	registry, repository, ok := strings.Cut(strings.TrimPrefix(source, "oci://"), "/")
+	if !ok || registry == "" || repository == "" || strings.ContainsAny(repository, ":@") {
+		return "", "", fmt.Errorf("invalid OCI source '%s' (use oci://<registry>/<repository>; the version is the tag)", source)
+	}
+	return registry, repository, nil
+}
+
+// ociGet requests a path under a repository of a registry. A Bearer
+// challenge is answered with a token for anonymous pulls, or for the
+// credential helper's credentials for the token service.
+func ociGet(registry, repository, path, accept string) ([]byte, http.Header, error) {
+	target := fmt.Sprintf("%s://%s/v2/%s/%s", ociScheme, registry, repository, path)
+	client := &http.Client{Timeout: httpTimeout(DefaultHTTPTimeout)}
+
+	request := func(token string) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(runCtx, http.MethodGet, target, nil)
+		if err != nil {
+			return nil, err
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, runStopped(runCtx, err)
+		}
+		return resp, nil
+	}
+
+	resp, err := request("")
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		token, err := ociToken(client, challenge)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", target, err)
+		}
+		if resp, err = request(token); err != nil {
+			return nil, nil, err
+		}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("%s: HTTP %d", target, resp.StatusCode)
+	}
+	data, err := io.ReadAll(resp.Body)
+	return data, resp.Header, err
+}
+
+// ociToken answers a registry's Bearer challenge
+func ociToken(client *http.Client, challenge string) (string, error) {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return "", fmt.Errorf("registry requires unsupported authentication '%s'", scheme)
+	}
+	values := map[string]string{}
+	for _, match := range challengeParamRegex.FindAllStringSubmatch(params, -1) {
+		values[match[1]] = match[2]
+	}
+	if values["realm"] == "" {
+		return "", fmt.Errorf("registry challenge has no realm")
+	}
+	query := url.Values{}
+	for _, key := range []string{"service", "scope"} {
+		if values[key] != "" {
+			query.Set(key, values[key])
+		}
+	}
+	realm := values["realm"]
+	if len(query) > 0 {
+		realm += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(runCtx, http.MethodGet, realm, nil)
+	if err != nil {
+		return "", err
+	}
+	cred, err := lookupCredential(realm)
+	if err != nil {
+		return "", err
+	}
+	cred.apply(req)
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", runStopped(runCtx, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token request: HTTP %d", resp.StatusCode)
+	}
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("token request: %w", err)
+	}
+	if body.Token == "" {
+		body.Token = body.AccessToken
+	}
+	return body.Token, nil
+}
+
+// fetchOCILibrary pulls a library artifact: the manifest tagged version,
+// then its library layer. The result is resolved to the manifest digest.
+func fetchOCILibrary(source, version string) ([]byte, string, error) {
+	registry, repository, err := parseOCISource(source)
+	if err != nil {
+		return nil, "", err
+	}
+	manifest, header, err := ociGet(registry, repository, "manifests/"+version,
+		"application/vnd.oci.image.manifest.v1+json")
+	if err != nil {
+		return nil, "", err
+	}
+	digest := header.Get("Docker-Content-Digest")
+	if digest == "" {
+		sum := sha256.Sum256(manifest)
+		digest = "sha256:" + hex.EncodeToString(sum[:])
+	}
+
+	var parsed struct {
+		Layers []struct {
+			MediaType string `json:"mediaType"`
+			Digest    string `json:"digest"`
+		} `json:"layers"`
+	}
+	if err := json.Unmarshal(manifest, &parsed); err != nil {
+		return nil, "", fmt.Errorf("invalid manifest for %s:%s: %w", source, version, err)
+	}
+	layer := ""
+	for _, l := range parsed.Layers {
+		if l.MediaType == ociLibraryMediaType {
+			layer = l.Digest
+		}
+	}
+	if layer == "" && len(parsed.Layers) == 1 {
+		layer = parsed.Layers[0].Digest
+	}
+	if layer == "" {
+		return nil, "", fmt.Errorf("%s:%s has no %s layer", source, version, ociLibraryMediaType)
+	}
+
+	data, err := fetchOCIBlob(registry, repository, layer)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, fmt.Sprintf("oci://%s/%s@%s", registry, repository, digest), nil
+}
+
+// fetchOCIBlob downloads a blob and checks it against its digest
+func fetchOCIBlob(registry, repository, digest string) ([]byte, error) {
+	hexDigest, ok := strings.CutPrefix(digest, "sha256:")
+	if !ok {
+		return nil, fmt.Errorf("unsupported digest '%s'", digest)
+	}
+	data, _, err := ociGet(registry, repository, "blobs/"+digest, "")
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyChecksum(data, hexDigest); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// libraryCachePath is where a library with the given digest is cached
+func libraryCachePath(digest string) (string, error) {
+	cache, err := cacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cache, "libraries", strings.ToLower(digest)+".json"), nil
+}
+
+// cachedLibrary returns a cached library file, or nil when it is not cached
+// or no longer matches its digest
+func cachedLibrary(digest string) []byte {
+	path, err := libraryCachePath(digest)
+	if err != nil {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || verifyChecksum(data, digest) != nil {
+		return nil
+	}
+	return data
+}
+
+// cacheLibrary keeps a library file for later loads; failing to is harmless
+func cacheLibrary(digest string, data []byte) {
+	path, err := libraryCachePath(digest)
+	if err != nil {
+		return
+	}
+	if os.MkdirAll(filepath.Dir(path), 0700) == nil {
+		os.WriteFile(path, data, 0600)
+	}
+}
+
+// parseLibrary parses a library file fetched for dependency name
+func parseLibrary(name string, dep Dependency, data []byte) (*Library, error) {
+	var lib Library
+	if err := json.Unmarshal(stripJSONC(data), &lib); err != nil {
+		return nil, fmt.Errorf("dependency '%s': invalid library: %w", name, err)
+	}
+	if dep.Version != "" && lib.Version != "" && lib.Version != dep.Version {
+		return nil, fmt.Errorf("dependency '%s': library is version %s, not %s", name, lib.Version, dep.Version)
+	}
+	if len(lib.Steps) == 0 {
+		return nil, fmt.Errorf("dependency '%s': library has no steps", name)
+	}
+	return &lib, nil
+}
+
+// loadLockFile reads the lock file in dir; a missing file is an empty lock
+func loadLockFile(dir string) (*LockFile, error) {
+	lock := &LockFile{Version: 1, Dependencies: map[string]LockedDependency{}}
+	data, err := os.ReadFile(filepath.Join(dir, LockFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return lock, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, lock); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", LockFileName, err)
+	}
+	if lock.Dependencies == nil {
+		lock.Dependencies = map[string]LockedDependency{}
+	}
+	return lock, nil
+}
+
+// resolveLibraries loads the libraries of a config's dependencies. A config
+// read from a file uses the lock file beside it; one fetched from a URL or a
+// repository has no lock file, so its dependencies must pin a sha256, or
+// have an https:// source listed in the checksum manifest.
+func resolveLibraries(deps map[string]Dependency, opts LoadOptions) (map[string]*Library, error) {
+	var lock *LockFile
+	if opts.BaseDir != "" {
+		var err error
+		if lock, err = loadLockFile(opts.BaseDir); err != nil {
+			return nil, err
+		}
+	}
+
+	libs := map[string]*Library{}
+	for _, name := range sortedKeys(deps) {
+		dep := deps[name]
+		if err := validateDependency(name, dep); err != nil {
+			return nil, fmt.Errorf("dependency '%s': %w", name, err)
+		}
+
+		locked := LockedDependency{Source: dep.Source, Version: dep.Version, Resolved: dependencyLocation(dep), SHA256: dep.SHA256}
+		switch {
+		case lock != nil:
+			entry, ok := lock.Dependencies[name]
+			if !ok || entry.Source != dep.Source || entry.Version != dep.Version {
+				return nil, fmt.Errorf("dependency '%s' is not in %s or has changed; run sink lock", name, LockFileName)
+			}
+			if dep.SHA256 != "" && !strings.EqualFold(entry.SHA256, dep.SHA256) {
+				return nil, fmt.Errorf("dependency '%s': %s records sha256 %s, the config pins %s", name, LockFileName, entry.SHA256, dep.SHA256)
+			}
+			locked = entry
+		case isLocalSource(dep.Source):
+			return nil, fmt.Errorf("dependency '%s': a path source needs a config loaded from a file", name)
+		case dep.SHA256 == "":
+			sum, ok := opts.Checksums.Lookup(configManifestName(locked.Resolved))
+			if !ok || !strings.HasPrefix(locked.Resolved, "https://") {
+				return nil, fmt.Errorf("dependency '%s': a config without a %s must pin each dependency's sha256", name, LockFileName)
+			}
+			locked.SHA256 = sum
+		}
+
+		data := cachedLibrary(locked.SHA256)
+		if data == nil {
+			if opts.CacheOnly {
+				return nil, fmt.Errorf("dependency '%s' is not cached; run sink lock", name)
+			}
+			var err error
+			if data, err = fetchLocked(locked, opts.BaseDir); err != nil {
+				return nil, fmt.Errorf("dependency '%s': %w", name, err)
+			}
+			cacheLibrary(locked.SHA256, data)
+		}
+		lib, err := parseLibrary(name, dep, data)
+		if err != nil {
+			return nil, err
+		}
+		libs[name] = lib
+	}
+	return libs, nil
+}
+
+// composeConfig replaces the "use" steps of a config with the library steps
+// they reference. It works on the raw JSON because the step variant is
+// chosen while unmarshaling. Data that is not a JSON object is returned
+// unchanged for the parser to report.
+func composeConfig(data []byte, opts LoadOptions) ([]byte, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil || !bytes.Contains(data, []byte(`"use"`)) {
+		return data, nil
+	}
+
+	var libs map[string]*Library
+	if raw, ok := doc["dependencies"]; ok {
+		encoded, _ := json.Marshal(raw)
+		var deps map[string]Dependency
+		if err := json.Unmarshal(encoded, &deps); err != nil {
+			return nil, fmt.Errorf("dependencies: %w", err)
+		}
+		var err error
+		if libs, err = resolveLibraries(deps, opts); err != nil {
+			return nil, err
+		}
+	}
+
+	platforms, _ := doc["platforms"].([]interface{})
+	for _, p := range platforms {
+		platform, _ := p.(map[string]interface{})
+		if err := expandStepList(platform, libs); err != nil {
+			return nil, fmt.Errorf("platform %v: %w", platform["name"], err)
+		}
+		dists, _ := platform["distributions"].([]interface{})
+		for _, d := range dists {
+			dist, _ := d.(map[string]interface{})
+			if err := expandStepList(dist, libs); err != nil {
+				return nil, fmt.Errorf("platform %v: distribution %v: %w", platform["name"], dist["name"], err)
+			}
+		}
+	}
+	return json.Marshal(doc)
+}
+
+// expandStepList expands the "install_steps" of a platform or distribution
+func expandStepList(parent map[string]interface{}, libs map[string]*Library) error {
+	steps, ok := parent["install_steps"].([]interface{})
+	if !ok {
+		return nil
+	}
+	return expandSteps(steps, libs, false)
+}
+
+// expandSteps replaces "use" steps in place, descending into groups
+func expandSteps(steps []interface{}, libs map[string]*Library, inLibrary bool) error {
+	for i, s := range steps {
+		step, ok := s.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if use, ok := step["use"]; ok {
+			if inLibrary {
+				return fmt.Errorf("install_step[%d]: library steps cannot use other libraries", i)
+			}
+			expanded, err := expandUse(step, use, libs)
+			if err != nil {
+				return fmt.Errorf("step '%v': %w", step["name"], err)
+			}
+			steps[i] = expanded
+			step = expanded
+		}
+		if group, ok := step["group"].(map[string]interface{}); ok {
+			members, _ := group["steps"].([]interface{})
+			if err := expandSteps(members, libs, inLibrary); err != nil {
+				return fmt.Errorf("group '%v': %w", step["name"], err)
+			}
+		}
+	}
+	return nil
+}
+
+// expandUse builds the step a "use" step stands for: the library step with
+// its parameters filled from "with", overridden by the using step's other
+// keys, and annotated with the library step it came from
+func expandUse(step map[string]interface{}, use interface{}, libs map[string]*Library) (map[string]interface{}, error) {
+	ref, _ := use.(string)
+	libName, stepName, ok := strings.Cut(ref, ".")
+	if !ok || libName == "" || stepName == "" {
+		return nil, fmt.Errorf("use must be <dependency>.<step>, got '%v'", use)
+	}
+	lib, ok := libs[libName]
+	if !ok {
+		return nil, fmt.Errorf("unknown dependency '%s'", libName)
+	}
+	raw, ok := lib.Steps[stepName]
+	if !ok {
+		return nil, fmt.Errorf("dependency '%s' has no step '%s' (has: %s)", libName, stepName, strings.Join(sortedKeys(lib.Steps), ", "))
+	}
+
+	var template interface{}
+	if err := json.Unmarshal(raw, &template); err != nil {
+		return nil, fmt.Errorf("%s: %w", ref, err)
+	}
+	params := map[string]string{}
+	if with, ok := step["with"].(map[string]interface{}); ok {
+		for key, value := range with {
+			params[key] = fmt.Sprint(value)
+		}
+	}
+	used := map[string]bool{}
+	var missing []string
+	template = fillParams(template, params, used, &missing)
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%s needs with.%s", ref, missing[0])
+	}
+	for _, key := range sortedKeys(params) {
+		if !used[key] {
+			return nil, fmt.Errorf("%s has no parameter '%s'", ref, key)
+		}
+	}
+
+	expanded, ok := template.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s is not a step object", ref)
+	}
+	if group, ok := expanded["group"].(map[string]interface{}); ok {
+		members, _ := group["steps"].([]interface{})
+		if err := expandSteps(members, nil, true); err != nil {
+			return nil, fmt.Errorf("%s: %w", ref, err)
+		}
+	}
+	for key, value := range step {
+		if key != "use" && key != "with" {
+			expanded[key] = value
+		}
+	}
+
+	annotations, _ := expanded["annotations"].(map[string]interface{})
+	if annotations == nil {
+		annotations = map[string]interface{}{}
+	}
+	source := ref
+	if lib.Version != "" {
+		source += "@" + lib.Version
+	}
+	annotations["library"] = source
+	expanded["annotations"] = annotations
+	return expanded, nil
+}
+
+// fillParams substitutes {{param "x"}} in every string of a library step,
+// recording the parameters used and those missing from params
+func fillParams(value interface{}, params map[string]string, used map[string]bool, missing *[]string) interface{} {
+	switch v := value.(type) {
+	case string:
+		return paramActionRegex.ReplaceAllStringFunc(v, func(action string) string {
+			name := paramActionRegex.FindStringSubmatch(action)[1]
+			used[name] = true
+			param, ok := params[name]
+			if !ok {
+				*missing = append(*missing, name)
+			}
+			return param
+		})
+	case []interface{}:
+		for i := range v {
+			v[i] = fillParams(v[i], params, used, missing)
+		}
+	case map[string]interface{}:
+		for key := range v {
+			v[key] = fillParams(v[key], params, used, missing)
+		}
+	}
+	return value
+}
+
+// lockDependencies resolves the dependencies of the config at configPath
+// and writes the lock file beside it. An entry whose content changed while
+// its source and version did not is refused unless update is set.
+func lockDependencies(w io.Writer, configPath string, update bool) error {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	var doc struct {
+		Dependencies map[string]Dependency `json:"dependencies"`
+	}
+	if err := json.Unmarshal(stripJSONC(data), &doc); err != nil {
+		return fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	dir := filepath.Dir(configPath)
+	previous, err := loadLockFile(dir)
+	if err != nil {
+		return err
+	}
+	lock := &LockFile{Version: 1, Dependencies: map[string]LockedDependency{}}
+	for _, name := range sortedKeys(doc.Dependencies) {
+		dep := doc.Dependencies[name]
+		if err := validateDependency(name, dep); err != nil {
+			return fmt.Errorf("dependency '%s': %w", name, err)
+		}
+		library, resolved, err := fetchDependency(dep, dir)
+		if err != nil {
+			return fmt.Errorf("dependency '%s': %w", name, err)
+		}
+		if dep.SHA256 != "" {
+			if err := verifyChecksum(library, dep.SHA256); err != nil {
+				return fmt.Errorf("dependency '%s': %w", name, err)
+			}
+		}
+		if _, err := parseLibrary(name, dep, library); err != nil {
+			return err
+		}
+
+		sum := sha256.Sum256(library)
+		digest := hex.EncodeToString(sum[:])
+		old, ok := previous.Dependencies[name]
+		if ok && !update && old.Source == dep.Source && old.Version == dep.Version && old.SHA256 != digest {
+			return fmt.Errorf("dependency '%s' %s changed content without a new version (was %s, now %s); use --update to accept it",
+				name, dep.Version, old.SHA256[:12], digest[:12])
+		}
+		lock.Dependencies[name] = LockedDependency{Source: dep.Source, Version: dep.Version, Resolved: resolved, SHA256: digest}
+		cacheLibrary(digest, library)
+		fmt.Fprintf(w, "🔒 %s %s  sha256:%s  %s\n", name, dep.Version, digest[:12], resolved)
+	}
+
+	encoded, err := json.MarshalIndent(lock, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, LockFileName), append(encoded, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", LockFileName, err)
+	}
+	fmt.Fprintf(w, "Wrote %s (%d dependencies)\n", filepath.Join(dir, LockFileName), len(lock.Dependencies))
+	return nil
+}
+
+// lockCommand runs "sink lock": resolve a config's dependencies into sink.lock
+func lockCommand() {
+	var configPath string
+	update := false
+	for _, arg := range os.Args[2:] {
+		switch arg {
+		case "-h", "--help":
+			printLockHelp()
+			os.Exit(0)
+		case "--update":
+			update = true
+		default:
+			if strings.HasPrefix(arg, "-") || configPath != "" {
+				fmt.Fprintf(os.Stderr, "Error: unexpected argument: %s\n", arg)
+				os.Exit(1)
+			}
+			configPath = arg
+		}
+	}
+	if configPath == "" {
+		fmt.Fprintf(os.Stderr, "Error: config file required\n\n")
+		printLockHelp()
+		os.Exit(1)
+	}
+
+	if err := lockDependencies(os.Stdout, configPath, update); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func printLockHelp() {
+	fmt.Printf(`sink lock - Resolve a config's step libraries into sink.lock
+
+Usage:
+  sink lock <config-file> [--update]
+
+Description:
+  Fetches every library in the config's "dependencies", checks it against
+  the dependency's sha256 if set, and records the version, where it was
+  resolved to and its SHA256 in sink.lock beside the config. Commit the lock
+  file with the config: loading the config then uses exactly the locked
+  libraries, from the cache or re-fetched and verified, and refuses to run
+  while a dependency is missing from the lock or has changed.
+
+  Sources are https:// URLs ({version} is replaced by the version), OCI
+  registries as oci://<registry>/<repository> with the version as the tag,
+  or paths relative to the config. A library that changes content without
+  a new version is refused unless --update is given.
+
+Options:
+  --update               Accept changed content for unchanged versions
+  -h, --help             Show this help message
+
+Examples:
+  sink lock install.json
+  sink lock install.json --update
+`)
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testLibrary = `{
+  "name": "base",
+  "version": "1.0.0",
+  "steps": {
+    "install_package": {
+      "check": "command -v {{param \"package\"}}",
+      "on_missing": [{"name": "apt", "command": "apt-get install -y {{param \"package\"}}"}]
+    },
+    "banner": {"command": "echo ready", "annotations": {"team": "platform"}}
+  }
+}`
+
+// libraryConfig is a config using the library dependency "base" from source
+func libraryConfig(source, steps string) string {
+	return fmt.Sprintf(`{
+  "version": "1.0.0",
+  "dependencies": {"base": {"source": %q, "version": "1.0.0"}},
+  "platforms": [{"os": "linux", "match": "Linux", "name": "Linux", "install_steps": [%s]}]
+}`, source, steps)
+}
+
+// writeLibraryConfig writes a config and a local library into a new directory
+func writeLibraryConfig(t *testing.T, steps string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "base.json"), []byte(testLibrary), 0644); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "install.json")
+	if err := os.WriteFile(path, []byte(libraryConfig("base.json", steps)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// TestComposeConfig tests expanding library steps from a locked local library
+func TestComposeConfig(t *testing.T) {
+	t.Setenv(EnvCacheDir, t.TempDir())
+	path := writeLibraryConfig(t, `{"name": "Install jq", "use": "base.install_package", "with": {"package": "jq"}},
+	  {"name": "Banner", "use": "base.banner", "risk": "low"}`)
+
+	if _, err := LoadConfig(path); err == nil || !strings.Contains(err.Error(), "run sink lock") {
+		t.Fatalf("expected an unlocked dependency to be refused, got %v", err)
+	}
+	var out bytes.Buffer
+	if err := lockDependencies(&out, path, false); err != nil {
+		t.Fatalf("lock failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "🔒 base 1.0.0") {
+		t.Errorf("unexpected lock output:\n%s", out.String())
+	}
+
+	config, err := LoadConfigWithOptions(path, LoadOptions{Strict: true})
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	steps := config.Platforms[0].InstallSteps
+	check, ok := steps[0].Step.(CheckRemediateStep)
+	if !ok {
+		t.Fatalf("expected a check-remediate step, got %T", steps[0].Step)
+	}
+	if check.Check != "command -v jq" || check.OnMissing[0].Command != "apt-get install -y jq" {
+		t.Errorf("parameters not filled: %q, %q", check.Check, check.OnMissing[0].Command)
+	}
+	if steps[0].Annotations["library"] != "base.install_package@1.0.0" {
+		t.Errorf("expected a library annotation, got %v", steps[0].Annotations)
+	}
+	if steps[1].Risk != "low" || steps[1].Annotations["team"] != "platform" {
+		t.Errorf("expected overrides and library annotations to combine, got %+v", steps[1])
+	}
+
+	tests := []struct {
+		name  string
+		steps string
+		err   string
+	}{
+		{"missing parameter", `{"name": "x", "use": "base.install_package"}`, "needs with.package"},
+		{"unknown parameter", `{"name": "x", "use": "base.banner", "with": {"color": "red"}}`, "has no parameter 'color'"},
+		{"unknown step", `{"name": "x", "use": "base.nope"}`, "has no step 'nope' (has: banner, install_package)"},
+		{"unknown dependency", `{"name": "x", "use": "other.banner"}`, "unknown dependency 'other'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := []byte(libraryConfig("base.json", tt.steps))
+			_, err := parseConfigData(data, LoadOptions{BaseDir: filepath.Dir(path)})
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("expected error containing %q, got %v", tt.err, err)
+			}
+		})
+	}
+
+	// Changing the version without relocking is refused
+	changed := strings.Replace(libraryConfig("base.json", `{"name": "x", "use": "base.banner"}`), `"1.0.0"}`, `"1.1.0"}`, 1)
+	if _, err := parseConfigData([]byte(changed), LoadOptions{BaseDir: filepath.Dir(path)}); err == nil || !strings.Contains(err.Error(), "has changed") {
+		t.Errorf("expected a stale lock to be refused, got %v", err)
+	}
+}
+
+// TestLockChangedContent tests that content changing under the same version
+// needs --update
+func TestLockChangedContent(t *testing.T) {
+	t.Setenv(EnvCacheDir, t.TempDir())
+	path := writeLibraryConfig(t, `{"name": "Banner", "use": "base.banner"}`)
+	if err := lockDependencies(&bytes.Buffer{}, path, false); err != nil {
+		t.Fatalf("lock failed: %v", err)
+	}
+
+	library := strings.Replace(testLibrary, "echo ready", "echo changed", 1)
+	os.WriteFile(filepath.Join(filepath.Dir(path), "base.json"), []byte(library), 0644)
+	if err := lockDependencies(&bytes.Buffer{}, path, false); err == nil || !strings.Contains(err.Error(), "--update") {
+		t.Fatalf("expected changed content to be refused, got %v", err)
+	}
+	if err := lockDependencies(&bytes.Buffer{}, path, true); err != nil {
+		t.Fatalf("lock --update failed: %v", err)
+	}
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if command := config.Platforms[0].InstallSteps[0].Step.(CommandStep).Command; command != "echo changed" {
+		t.Errorf("expected the updated library, got %q", command)
+	}
+}
+
+// TestComposeFetchedConfig tests that a config without a lock file must pin
+// its dependencies, which are then fetched and verified
+func TestComposeFetchedConfig(t *testing.T) {
+	t.Setenv(EnvCacheDir, t.TempDir())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/libs/base-1.0.0.json" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(testLibrary))
+	}))
+	defer server.Close()
+
+	unpinned := libraryConfig("https://example.com/libs/base-{version}.json", `{"name": "Banner", "use": "base.banner"}`)
+	if _, err := parseConfigData([]byte(unpinned), LoadOptions{}); err == nil || !strings.Contains(err.Error(), "must pin") {
+		t.Fatalf("expected an unpinned dependency to be refused, got %v", err)
+	}
+	config := libraryConfig(server.URL+"/libs/base-{version}.json", `{"name": "Banner", "use": "base.banner"}`)
+	if _, err := parseConfigData([]byte(config), LoadOptions{}); err == nil || !strings.Contains(err.Error(), "HTTP sources require a sha256") {
+		t.Fatalf("expected an unpinned HTTP dependency to be refused, got %v", err)
+	}
+
+	sum := sha256.Sum256([]byte(testLibrary))
+	pinned := strings.Replace(config, `"version": "1.0.0"}`, fmt.Sprintf(`"version": "1.0.0", "sha256": %q}`, hex.EncodeToString(sum[:])), 1)
+	if _, err := parseConfigData([]byte(pinned), LoadOptions{}); err != nil {
+		t.Fatalf("expected a pinned dependency to load, got %v", err)
+	}
+
+	wrong := strings.Replace(config, `"version": "1.0.0"}`, fmt.Sprintf(`"version": "1.0.0", "sha256": %q}`, strings.Repeat("0", 64)), 1)
+	if _, err := parseConfigData([]byte(wrong), LoadOptions{}); err == nil || !strings.Contains(err.Error(), "SHA256 mismatch") {
+		t.Errorf("expected a checksum mismatch, got %v", err)
+	}
+}
+
+// TestLockOCI tests resolving a library from an OCI registry that requires
+// an anonymous Bearer token
+func TestLockOCI(t *testing.T) {
+	t.Setenv(EnvCacheDir, t.TempDir())
+	sum := sha256.Sum256([]byte(testLibrary))
+	layer := "sha256:" + hex.EncodeToString(sum[:])
+	manifest := fmt.Sprintf(`{"schemaVersion": 2, "layers": [{"mediaType": %q, "digest": %q}]}`, ociLibraryMediaType, layer)
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token":
+			w.Write([]byte(`{"token": "anon"}`))
+		case r.Header.Get("Authorization") != "Bearer anon":
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test",scope="repository:acme/base:pull"`, server.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path == "/v2/acme/base/manifests/1.0.0":
+			w.Header().Set("Docker-Content-Digest", "sha256:"+strings.Repeat("a", 64))
+			w.Write([]byte(manifest))
+		case r.URL.Path == "/v2/acme/base/blobs/"+layer:
+			w.Write([]byte(testLibrary))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	ociScheme = "http"
+	defer func() { ociScheme = "https" }()
+
+	dir := t.TempDir()
+	source := "oci://" + strings.TrimPrefix(server.URL, "http://") + "/acme/base"
+	path := filepath.Join(dir, "install.json")
+	os.WriteFile(path, []byte(libraryConfig(source, `{"name": "Banner", "use": "base.banner"}`)), 0644)
+	if err := lockDependencies(&bytes.Buffer{}, path, false); err != nil {
+		t.Fatalf("lock failed: %v", err)
+	}
+
+	lock, err := loadLockFile(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := lock.Dependencies["base"]
+	if entry.Resolved != source+"@sha256:"+strings.Repeat("a", 64) || "sha256:"+entry.SHA256 != layer {
+		t.Errorf("unexpected lock entry: %+v", entry)
+	}
+
+	// With an empty cache, loading fetches the locked blob
+	t.Setenv(EnvCacheDir, t.TempDir())
+	if _, err := LoadConfig(path); err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if _, err := parseConfigData([]byte(libraryConfig(source, `{"name": "x", "use": "base.banner"}`)), LoadOptions{BaseDir: dir, CacheOnly: true}); err != nil {
+		t.Errorf("expected the cached library to be used, got %v", err)
+	}
+}
@@ -0,0 +1,1076 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// LSP types, limited to the fields sink lsp uses
+
+// lspPosition is a zero-based line and UTF-16 character offset
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+// Diagnostic severities
+const (
+	lspSeverityError   = 1
+	lspSeverityWarning = 2
+)
+
+type lspDiagnostic struct {
+	Range    lspRange `json:"range"`
+	Severity int      `json:"severity"`
+	Source   string   `json:"source"`
+	Message  string   `json:"message"`
+}
+
+type lspMarkup struct {
+	Kind  string `json:"kind"` // "markdown"
+	Value string `json:"value"`
+}
+
+// Completion item kinds and insert text formats
+const (
+	lspKindVariable  = 6
+	lspKindProperty  = 10
+	lspKindValue     = 12
+	lspKindSnippet   = 15
+	lspFormatSnippet = 2
+)
+
+// JSON-RPC error codes
+const (
+	lspErrInvalidJSON = -32700
+	lspErrMethod      = -32601
+)
+
+type lspCompletionItem struct {
+	Label            string     `json:"label"`
+	Kind             int        `json:"kind"`
+	Detail           string     `json:"detail,omitempty"`
+	Documentation    *lspMarkup `json:"documentation,omitempty"`
+	InsertText       string     `json:"insertText,omitempty"`
+	InsertTextFormat int        `json:"insertTextFormat,omitempty"`
+}
+
+// lspMessage is a JSON-RPC 2.0 request, notification or response
+type lspMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *lspError       `json:"error,omitempty"`
+}
+
+type lspError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// lspDocument is an open config file
+type lspDocument struct {
+	Text string
+
+	// Facts and variables of the last version that parsed, for completing
+	// templates while the file is mid-edit
+	Facts     map[string]FactDef
+	Variables map[string]VariableDef
+}
+
+// lspServer is a language server for sink config files speaking LSP over
+// a pair of streams
+type lspServer struct {
+	in       *bufio.Reader
+	out      io.Writer
+	docs     map[string]*lspDocument // By URI
+	schema   *schemaWalker
+	shutdown bool
+}
+
+// newLSPServer creates a server reading requests from in
+func newLSPServer(in io.Reader, out io.Writer) (*lspServer, error) {
+	var schema map[string]interface{}
+	if err := json.Unmarshal([]byte(embeddedSchema), &schema); err != nil {
+		return nil, fmt.Errorf("invalid embedded schema: %w", err)
+	}
+	return &lspServer{
+		in:     bufio.NewReader(in),
+		out:    out,
+		docs:   map[string]*lspDocument{},
+		schema: &schemaWalker{root: schema},
+	}, nil
+}
+
+// readMessage reads one Content-Length framed message
+func (s *lspServer) readMessage() ([]byte, error) {
+	length := -1
+	for {
+		line, err := s.in.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		if name, value, ok := strings.Cut(line, ":"); ok && strings.EqualFold(name, "Content-Length") {
+			if length, err = strconv.Atoi(strings.TrimSpace(value)); err != nil {
+				return nil, fmt.Errorf("invalid Content-Length: %s", value)
+			}
+		}
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("message without Content-Length")
+	}
+	body := make([]byte, length)
+	_, err := io.ReadFull(s.in, body)
+	return body, err
+}
+
+// send writes one message with its Content-Length header
+func (s *lspServer) send(msg lspMessage) error {
+	msg.JSONRPC = "2.0"
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	return err
+}
+
+// reply answers a request; a nil result is sent as JSON null
+func (s *lspServer) reply(id json.RawMessage, result interface{}) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return s.send(lspMessage{ID: id, Result: data})
+}
+
+// notify sends a notification to the client
+func (s *lspServer) notify(method string, params interface{}) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return s.send(lspMessage{Method: method, Params: data})
+}
+
+// Serve handles messages until the client sends "exit" or closes the input.
+// It returns an error if the client exits without asking to shut down.
+func (s *lspServer) Serve() error {
+	for {
+		body, err := s.readMessage()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var msg lspMessage
+		if err := json.Unmarshal(body, &msg); err != nil {
+			if err := s.send(lspMessage{ID: json.RawMessage("null"), Error: &lspError{Code: lspErrInvalidJSON, Message: err.Error()}}); err != nil {
+				return err
+			}
+			continue
+		}
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return fmt.Errorf("client exited without shutdown")
+			}
+			return nil
+		}
+		if err := s.handle(msg); err != nil {
+			return err
+		}
+	}
+}
+
+// handle dispatches one request or notification
+func (s *lspServer) handle(msg lspMessage) error {
+	switch msg.Method {
+	case "initialize":
+		return s.reply(msg.ID, map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync": 1, // Full text on every change
+				"completionProvider": map[string]interface{}{
+					"triggerCharacters": []string{"\"", ".", "{"},
+				},
+				"hoverProvider": true,
+			},
+			"serverInfo": map[string]string{"name": "sink", "version": Version},
+		})
+	case "shutdown":
+		s.shutdown = true
+		return s.reply(msg.ID, nil)
+
+	case "textDocument/didOpen":
+		var params struct {
+			TextDocument struct {
+				URI  string `json:"uri"`
+				Text string `json:"text"`
+			} `json:"textDocument"`
+		}
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil
+		}
+		return s.update(params.TextDocument.URI, params.TextDocument.Text)
+	case "textDocument/didChange":
+		var params struct {
+			TextDocument struct {
+				URI string `json:"uri"`
+			} `json:"textDocument"`
+			ContentChanges []struct {
+				Text string `json:"text"`
+			} `json:"contentChanges"`
+		}
+		if err := json.Unmarshal(msg.Params, &params); err != nil || len(params.ContentChanges) == 0 {
+			return nil
+		}
+		return s.update(params.TextDocument.URI, params.ContentChanges[len(params.ContentChanges)-1].Text)
+	case "textDocument/didClose":
+		var params struct {
+			TextDocument struct {
+				URI string `json:"uri"`
+			} `json:"textDocument"`
+		}
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil
+		}
+		delete(s.docs, params.TextDocument.URI)
+		return s.notify("textDocument/publishDiagnostics", map[string]interface{}{
+			"uri": params.TextDocument.URI, "diagnostics": []lspDiagnostic{},
+		})
+
+	case "textDocument/completion", "textDocument/hover":
+		var params struct {
+			TextDocument struct {
+				URI string `json:"uri"`
+			} `json:"textDocument"`
+			Position lspPosition `json:"position"`
+		}
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return s.reply(msg.ID, nil)
+		}
+		doc := s.docs[params.TextDocument.URI]
+		if doc == nil {
+			return s.reply(msg.ID, nil)
+		}
+		offset := lspOffset(doc.Text, params.Position)
+		if msg.Method == "textDocument/hover" {
+			text := s.hover(doc, offset)
+			if text == "" {
+				return s.reply(msg.ID, nil)
+			}
+			return s.reply(msg.ID, map[string]interface{}{"contents": lspMarkup{Kind: "markdown", Value: text}})
+		}
+		items := s.complete(doc, offset)
+		if items == nil {
+			items = []lspCompletionItem{}
+		}
+		return s.reply(msg.ID, items)
+	}
+
+	// Notifications need no answer; unknown requests get an error
+	if len(msg.ID) > 0 {
+		return s.send(lspMessage{ID: msg.ID, Error: &lspError{Code: lspErrMethod, Message: "method not supported: " + msg.Method}})
+	}
+	return nil
+}
+
+// update stores a document's new text and publishes its diagnostics
+func (s *lspServer) update(uri, text string) error {
+	doc := s.docs[uri]
+	if doc == nil {
+		doc = &lspDocument{}
+		s.docs[uri] = doc
+	}
+	doc.Text = text
+
+	var parsed struct {
+		Facts     map[string]FactDef     `json:"facts"`
+		Variables map[string]VariableDef `json:"variables"`
+	}
+	if json.Unmarshal(stripJSONC([]byte(text)), &parsed) == nil {
+		doc.Facts, doc.Variables = parsed.Facts, parsed.Variables
+	}
+
+	diagnostics := s.diagnose(text, uriDir(uri))
+	if diagnostics == nil {
+		diagnostics = []lspDiagnostic{}
+	}
+	return s.notify("textDocument/publishDiagnostics", map[string]interface{}{
+		"uri": uri, "diagnostics": diagnostics,
+	})
+}
+
+// diagnose returns the problems "sink validate" would report, placed at the
+// key or character they concern where the error says which one that is.
+// Dependencies are resolved from baseDir's sink.lock and the library cache
+// only, so editing never waits on downloads.
+func (s *lspServer) diagnose(text, baseDir string) []lspDiagnostic {
+	// Stripping comments keeps offsets, so positions still point into text
+	data := stripJSONC([]byte(text))
+	at := func(start, end int, severity int, message string) lspDiagnostic {
+		return lspDiagnostic{
+			Range:    lspRange{Start: lspPositionAt(text, start), End: lspPositionAt(text, end)},
+			Severity: severity,
+			Source:   "sink",
+			Message:  message,
+		}
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		var syntax *json.SyntaxError
+		offset := len(text)
+		if errors.As(err, &syntax) {
+			offset = int(syntax.Offset)
+		}
+		start := offset - 1
+		if start < 0 {
+			start = 0
+		}
+		return []lspDiagnostic{at(start, offset, lspSeverityError, err.Error())}
+	}
+
+	var diagnostics []lspDiagnostic
+	keys, duplicates := indexKeys(data)
+	for _, dup := range duplicates {
+		diagnostics = append(diagnostics, at(dup.start, dup.end, lspSeverityError, fmt.Sprintf("duplicate key '%s'", dup.path)))
+	}
+
+	w := &schemaWalker{root: s.schema.root, unknown: map[string]string{}}
+	w.walk(value, w.root, "")
+	for _, path := range sortedKeys(w.unknown) {
+		message := fmt.Sprintf("unknown field '%s'", path)
+		if suggestion := w.unknown[path]; suggestion != "" {
+			message += fmt.Sprintf(" (did you mean '%s'?)", suggestion)
+		}
+		span := keys[path]
+		diagnostics = append(diagnostics, at(span.start, span.end, lspSeverityError, message))
+	}
+
+	// Duplicates fail parsing too; they are reported above at each key
+	if len(duplicates) == 0 {
+		if _, err := parseConfigData(data, LoadOptions{BaseDir: baseDir, CacheOnly: true}); err != nil {
+			start, end := 0, 0
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) {
+				start, end = int(typeErr.Offset)-1, int(typeErr.Offset)
+				if span, ok := keys[typeErr.Field]; ok {
+					start, end = span.start, span.end
+				}
+			}
+			diagnostics = append(diagnostics, at(start, end, lspSeverityError, err.Error()))
+		}
+	}
+	return diagnostics
+}
+
+// keySpan is where a key appears in a document, with its path written the
+// way validation errors write it, e.g. "platforms[0].install_steps[1].name"
+type keySpan struct {
+	path       string
+	start, end int // Byte offsets of the quoted key
+}
+
+// indexKeys returns the span of every object key in a valid JSON document
+// by path, and the spans of keys that repeat one earlier in their object
+func indexKeys(data []byte) (map[string]keySpan, []keySpan) {
+	spans := map[string]keySpan{}
+	var duplicates []keySpan
+	dec := json.NewDecoder(bytes.NewReader(data))
+
+	var walk func(path string) error
+	walk = func(path string) error {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'):
+			for dec.More() {
+				keyTok, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				key, _ := keyTok.(string)
+				end := int(dec.InputOffset())
+				start := bytes.LastIndexByte(data[:end-1], '"')
+				childPath := key
+				if path != "" {
+					childPath = path + "." + key
+				}
+				span := keySpan{path: childPath, start: start, end: end}
+				if _, seen := spans[childPath]; seen {
+					duplicates = append(duplicates, span)
+				} else {
+					spans[childPath] = span
+				}
+				if err := walk(childPath); err != nil {
+					return err
+				}
+			}
+			_, err = dec.Token()
+			return err
+		case json.Delim('['):
+			for i := 0; dec.More(); i++ {
+				if err := walk(fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+			_, err = dec.Token()
+			return err
+		}
+		return nil
+	}
+	walk("")
+	return spans, duplicates
+}
+
+// jsonCursor describes where a cursor is in a possibly incomplete JSON
+// document
+type jsonCursor struct {
+	Path     []interface{} // Keys (string) and indexes (int) leading to the innermost container
+	InObject bool          // The innermost container is an object (else an array or the top level)
+	InArray  bool
+	Key      bool     // At an object key, or where one is expected
+	Property string   // For values in an object, the key they belong to
+	InString bool     // Inside a string
+	Prefix   string   // The string's text before the cursor
+	Rest     string   // The string's text after the cursor
+	Keys     []string // Keys of the innermost object before the cursor, not counting one being typed
+}
+
+// scanJSON finds where offset is in text. It tolerates the incomplete
+// documents of a file being edited by only tracking containers and keys.
+func scanJSON(text string, offset int) jsonCursor {
+	type frame struct {
+		object    bool
+		key       string
+		index     int
+		expectKey bool
+		keys      []string
+	}
+	var stack []frame
+	var cursor jsonCursor
+
+scan:
+	for i := 0; i < offset && i < len(text); i++ {
+		switch text[i] {
+		case '"':
+			start := i + 1
+			end := start
+			for end < len(text) && text[end] != '"' && text[end] != '\n' {
+				if text[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end > len(text) {
+				end = len(text)
+			}
+			raw := text[start:end]
+			if offset <= end {
+				cursor.InString = true
+				cursor.Prefix = unquoteJSON(text[start:offset])
+				cursor.Rest = unquoteJSON(text[offset:end])
+				break scan
+			}
+			if n := len(stack); n > 0 && stack[n-1].object && stack[n-1].expectKey {
+				stack[n-1].key = unquoteJSON(raw)
+				stack[n-1].keys = append(stack[n-1].keys, stack[n-1].key)
+			}
+			i = end
+		case '{':
+			stack = append(stack, frame{object: true, expectKey: true})
+		case '[':
+			stack = append(stack, frame{})
+		case '}', ']':
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case ':':
+			if n := len(stack); n > 0 && stack[n-1].object {
+				stack[n-1].expectKey = false
+			}
+		case ',':
+			if n := len(stack); n > 0 {
+				if stack[n-1].object {
+					stack[n-1].expectKey = true
+					stack[n-1].key = ""
+				} else {
+					stack[n-1].index++
+				}
+			}
+		}
+	}
+
+	for i, f := range stack {
+		if i == len(stack)-1 {
+			break
+		}
+		if f.object {
+			cursor.Path = append(cursor.Path, f.key)
+		} else {
+			cursor.Path = append(cursor.Path, f.index)
+		}
+	}
+	if n := len(stack); n > 0 {
+		top := stack[n-1]
+		cursor.InObject = top.object
+		cursor.InArray = !top.object
+		cursor.Keys = top.keys
+		if top.object {
+			cursor.Key = top.expectKey
+			if !top.expectKey {
+				cursor.Property = top.key
+			}
+		}
+	}
+	return cursor
+}
+
+// unquoteJSON decodes the escapes of JSON string content, keeping the raw
+// text if it is incomplete
+func unquoteJSON(raw string) string {
+	var s string
+	if json.Unmarshal([]byte(`"`+raw+`"`), &s) != nil {
+		return raw
+	}
+	return s
+}
+
+// nodes returns the schemas, with their combined branches, that apply at
+// path, following properties, patternProperties, additionalProperties and
+// items as walk does
+func (w *schemaWalker) nodes(path []interface{}) []map[string]interface{} {
+	nodes := w.branches(w.root)
+	for _, elem := range path {
+		var next []map[string]interface{}
+		for _, n := range nodes {
+			switch e := elem.(type) {
+			case string:
+				if props, ok := n["properties"].(map[string]interface{}); ok {
+					if sub, ok := props[e].(map[string]interface{}); ok {
+						next = append(next, sub)
+					}
+				}
+				if patterns, ok := n["patternProperties"].(map[string]interface{}); ok {
+					for pattern, sub := range patterns {
+						if re, err := regexp.Compile(pattern); err == nil && re.MatchString(e) {
+							if sub, ok := sub.(map[string]interface{}); ok {
+								next = append(next, sub)
+							}
+						}
+					}
+				}
+				if ap, ok := n["additionalProperties"].(map[string]interface{}); ok {
+					next = append(next, ap)
+				}
+			case int:
+				if items, ok := n["items"].(map[string]interface{}); ok {
+					next = append(next, items)
+				}
+			}
+		}
+		nodes = nil
+		for _, n := range next {
+			nodes = append(nodes, w.branches(n)...)
+		}
+	}
+	return nodes
+}
+
+// schemaDescription returns the first description among nodes
+func schemaDescription(nodes []map[string]interface{}) string {
+	for _, n := range nodes {
+		if d, ok := n["description"].(string); ok {
+			return d
+		}
+	}
+	return ""
+}
+
+// schemaType names the JSON type of nodes, e.g. "string" or "string | array"
+func schemaType(nodes []map[string]interface{}) string {
+	var types []string
+	for _, n := range nodes {
+		switch t := n["type"].(type) {
+		case string:
+			if !containsString(types, t) {
+				types = append(types, t)
+			}
+		case []interface{}:
+			for _, item := range t {
+				if s, ok := item.(string); ok && !containsString(types, s) {
+					types = append(types, s)
+				}
+			}
+		}
+	}
+	return strings.Join(types, " | ")
+}
+
+// variantName is the short name of a step variant from its schema
+// description, e.g. "Command execution step"
+func variantName(branch map[string]interface{}) string {
+	description, _ := branch["description"].(string)
+	name, _, _ := strings.Cut(description, " - ")
+	return name
+}
+
+// variantKeys returns the keys a step variant requires besides "name"
+func variantKeys(branch map[string]interface{}) []string {
+	var keys []string
+	// Command steps require one of command or script, check steps one of
+	// check and its probes; the first alternative stands for them
+	if alternatives, ok := branch["oneOf"].([]interface{}); ok && len(alternatives) > 0 {
+		if first, ok := alternatives[0].(map[string]interface{}); ok {
+			if required, ok := first["required"].([]interface{}); ok {
+				for _, key := range required {
+					if k, ok := key.(string); ok {
+						keys = append(keys, k)
+					}
+				}
+			}
+		}
+	}
+	required, _ := branch["required"].([]interface{})
+	for _, key := range required {
+		if k, ok := key.(string); ok && k != "name" {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// templateExpression matches the field expression being typed at the end of
+// an unclosed {{ action, e.g. ".sink.tm"
+var templateExpression = regexp.MustCompile(`(\.?[A-Za-z_][\w.]*\.?|\.)?$`)
+
+// openTemplate returns the text of the template action the cursor is in,
+// when the string before it has a {{ that is not yet closed
+func openTemplate(prefix string) (string, bool) {
+	i := strings.LastIndex(prefix, "{{")
+	if i < 0 || strings.Contains(prefix[i:], "}}") {
+		return "", false
+	}
+	return prefix[i+2:], true
+}
+
+// sinkHelperNames lists the fields of the reserved {{.sink}} fact
+func sinkHelperNames() []string {
+	names := []string{"run_dir", "step_dir", "tmpfile", "arch", "run_id", "step_index", "attempt", "scratch"}
+	names = append(names, sortedKeys(HostInfo{}.facts())...)
+	names = append(names, sortedKeys(ExecutionContext{}.preflightFacts())...)
+	names = append(names, sortedKeys(installScopeFacts("", ExecutionContext{}))...)
+	return names
+}
+
+// complete returns the completions at offset: keys the schema allows, step
+// types, values of enums and booleans, and facts, variables and sink
+// helpers inside {{ }}
+func (s *lspServer) complete(doc *lspDocument, offset int) []lspCompletionItem {
+	cursor := scanJSON(doc.Text, offset)
+
+	if cursor.InString && !cursor.Key {
+		if action, ok := openTemplate(cursor.Prefix); ok {
+			return templateCompletions(doc, templateExpression.FindString(action))
+		}
+	}
+
+	if cursor.InObject && cursor.Key {
+		return s.keyCompletions(cursor)
+	}
+
+	var path []interface{}
+	switch {
+	case cursor.InObject:
+		path = append(append(path, cursor.Path...), cursor.Property)
+	case cursor.InArray && !cursor.InString:
+		return s.stepCompletions(append(cursor.Path, 0))
+	default:
+		return nil
+	}
+	return valueCompletions(s.schema.nodes(path), cursor.InString)
+}
+
+// keyCompletions offers the keys the schema allows in the object at cursor
+// that it does not have yet
+func (s *lspServer) keyCompletions(cursor jsonCursor) []lspCompletionItem {
+	nodes := s.schema.nodes(cursor.Path)
+	seen := map[string]bool{}
+	for _, key := range cursor.Keys {
+		seen[key] = true
+	}
+
+	// Keys that select a step variant are labeled with the variant
+	variants := map[string]string{}
+	for _, n := range nodes {
+		if name := variantName(n); name != "" {
+			for _, key := range variantKeys(n) {
+				variants[key] = name
+			}
+		}
+	}
+
+	var items []lspCompletionItem
+	for _, n := range nodes {
+		props, _ := n["properties"].(map[string]interface{})
+		for _, key := range sortedKeys(props) {
+			if seen[key] || key == "$schema" {
+				continue
+			}
+			seen[key] = true
+			prop, _ := props[key].(map[string]interface{})
+			propNodes := s.schema.branches(prop)
+			item := lspCompletionItem{Label: key, Kind: lspKindProperty, Detail: schemaType(propNodes)}
+			if name := variants[key]; name != "" {
+				item.Detail = name
+			}
+			if description := schemaDescription(propNodes); description != "" {
+				item.Documentation = &lspMarkup{Kind: "markdown", Value: description}
+			}
+			if !cursor.InString {
+				item.InsertText = fmt.Sprintf("%q: ", key)
+			}
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// stepCompletions offers a snippet per step type when the array being
+// edited holds install steps
+func (s *lspServer) stepCompletions(itemPath []interface{}) []lspCompletionItem {
+	var items []lspCompletionItem
+	for _, n := range s.schema.nodes(itemPath) {
+		name := variantName(n)
+		keys := variantKeys(n)
+		if name == "" || len(keys) == 0 {
+			continue
+		}
+		props, _ := n["properties"].(map[string]interface{})
+		fields := []string{`"name": "${1:name}"`}
+		for i, key := range keys {
+			placeholder := fmt.Sprintf(`"$%d"`, i+2)
+			if prop, ok := props[key].(map[string]interface{}); ok {
+				switch schemaType(s.schema.branches(prop)) {
+				case "object":
+					placeholder = fmt.Sprintf("{$%d}", i+2)
+				case "array":
+					placeholder = fmt.Sprintf("[$%d]", i+2)
+				}
+			}
+			fields = append(fields, fmt.Sprintf("%q: %s", key, placeholder))
+		}
+		description, _ := n["description"].(string)
+		items = append(items, lspCompletionItem{
+			Label:            name,
+			Kind:             lspKindSnippet,
+			Detail:           strings.Join(keys, ", "),
+			Documentation:    &lspMarkup{Kind: "markdown", Value: description},
+			InsertText:       "{" + strings.Join(fields, ", ") + "}",
+			InsertTextFormat: lspFormatSnippet,
+		})
+	}
+	return items
+}
+
+// valueCompletions offers the values of enums and booleans; inString means
+// the quotes are already typed
+func valueCompletions(nodes []map[string]interface{}, inString bool) []lspCompletionItem {
+	var items []lspCompletionItem
+	seen := map[string]bool{}
+	add := func(value interface{}) {
+		label := fmt.Sprint(value)
+		if seen[label] {
+			return
+		}
+		seen[label] = true
+		_, isString := value.(string)
+		if inString && !isString {
+			return
+		}
+		item := lspCompletionItem{Label: label, Kind: lspKindValue}
+		if isString && !inString {
+			item.InsertText = strconv.Quote(label)
+		}
+		items = append(items, item)
+	}
+	for _, n := range nodes {
+		if values, ok := n["enum"].([]interface{}); ok {
+			for _, value := range values {
+				add(value)
+			}
+		}
+		if n["type"] == "boolean" {
+			add(true)
+			add(false)
+		}
+	}
+	return items
+}
+
+// templateCompletions offers the names a template field expression can
+// refer to: facts, variables and the sink helpers, or the helpers' fields
+// after ".sink."
+func templateCompletions(doc *lspDocument, expression string) []lspCompletionItem {
+	var items []lspCompletionItem
+	// Without a leading dot the completion has to add it
+	dot := "."
+	if strings.HasPrefix(expression, ".") {
+		dot = ""
+	}
+
+	if strings.HasPrefix(expression, "."+sinkFactsKey+".") {
+		for _, name := range sinkHelperNames() {
+			items = append(items, lspCompletionItem{Label: name, Kind: lspKindVariable, Detail: "sink helper"})
+		}
+		return items
+	}
+
+	for _, name := range sortedKeys(doc.Facts) {
+		def := doc.Facts[name]
+		item := lspCompletionItem{Label: name, Kind: lspKindVariable, Detail: "fact", InsertText: dot + name}
+		if def.Type != "" {
+			item.Detail += " (" + def.Type + ")"
+		}
+		if text := factHover(name, def); text != "" {
+			item.Documentation = &lspMarkup{Kind: "markdown", Value: text}
+		}
+		items = append(items, item)
+	}
+	for _, name := range sortedKeys(builtinFactDescriptions) {
+		items = append(items, lspCompletionItem{
+			Label: name, Kind: lspKindVariable, Detail: "built-in fact", InsertText: dot + name,
+			Documentation: &lspMarkup{Kind: "markdown", Value: builtinFactDescriptions[name]},
+		})
+	}
+	for _, name := range sortedKeys(doc.Variables) {
+		item := lspCompletionItem{Label: name, Kind: lspKindVariable, Detail: "variable", InsertText: dot + name}
+		item.Documentation = &lspMarkup{Kind: "markdown", Value: variableHover(name, doc.Variables[name])}
+		items = append(items, item)
+	}
+	items = append(items, lspCompletionItem{
+		Label: sinkFactsKey, Kind: lspKindVariable, Detail: "sink helpers", InsertText: dot + sinkFactsKey,
+		Documentation: &lspMarkup{Kind: "markdown", Value: "Built-in helpers: " + strings.Join(sinkHelperNames(), ", ")},
+	})
+	return items
+}
+
+// factHover describes a fact for hover and completion documentation
+func factHover(name string, def FactDef) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "**%s** (fact", name)
+	if def.Type != "" {
+		fmt.Fprintf(&b, ", %s", def.Type)
+	}
+	b.WriteString(")")
+	if def.Description != "" {
+		fmt.Fprintf(&b, "\n\n%s", def.Description)
+	}
+	switch {
+	case def.Command != "":
+		fmt.Fprintf(&b, "\n\n`%s`", firstLine(def.Command))
+	case def.Source != "":
+		fmt.Fprintf(&b, "\n\nFrom `%s`", def.Source)
+	}
+	if len(def.Platforms) > 0 {
+		fmt.Fprintf(&b, "\n\nOnly gathered on %s", strings.Join(def.Platforms, ", "))
+	}
+	return b.String()
+}
+
+// variableHover describes a variable for hover and completion documentation
+func variableHover(name string, def VariableDef) string {
+	typ := def.Type
+	if typ == "" {
+		typ = "string"
+	}
+	text := fmt.Sprintf("**%s** (variable, %s)", name, typ)
+	if def.Description != "" {
+		text += "\n\n" + def.Description
+	}
+	if def.Default != nil {
+		text += fmt.Sprintf("\n\nDefault: `%v`", def.Default)
+	}
+	return text
+}
+
+// hover describes the key, or the fact or variable in a template, at offset
+func (s *lspServer) hover(doc *lspDocument, offset int) string {
+	cursor := scanJSON(doc.Text, offset)
+	if !cursor.InString {
+		return ""
+	}
+
+	if cursor.Key {
+		key := cursor.Prefix + cursor.Rest
+		nodes := s.schema.nodes(append(cursor.Path, key))
+		if len(nodes) == 0 {
+			return ""
+		}
+		text := fmt.Sprintf("**%s**", key)
+		if typ := schemaType(nodes); typ != "" {
+			text += fmt.Sprintf(" (`%s`)", typ)
+		}
+		if description := schemaDescription(nodes); description != "" {
+			text += "\n\n" + description
+		}
+		for _, n := range nodes {
+			if values, ok := n["enum"].([]interface{}); ok {
+				text += fmt.Sprintf("\n\nOne of: %s", strings.Trim(fmt.Sprint(values), "[]"))
+				break
+			}
+		}
+		return text
+	}
+
+	// A field name in a template: the identifier around the cursor, after a dot
+	if _, ok := openTemplate(cursor.Prefix); !ok {
+		return ""
+	}
+	before := regexp.MustCompile(`\.(\w*)$`).FindStringSubmatch(cursor.Prefix)
+	if before == nil || strings.HasSuffix(strings.TrimSuffix(cursor.Prefix, "."+before[1]), "."+sinkFactsKey) {
+		return ""
+	}
+	name := before[1] + regexp.MustCompile(`^\w*`).FindString(cursor.Rest)
+	if def, ok := doc.Facts[name]; ok {
+		return factHover(name, def)
+	}
+	if def, ok := doc.Variables[name]; ok {
+		return variableHover(name, def)
+	}
+	if name == sinkFactsKey {
+		return "**sink** (built-in helpers)\n\n" + strings.Join(sinkHelperNames(), ", ")
+	}
+	if description, ok := builtinFactDescriptions[name]; ok {
+		return "**" + name + "** (built-in fact)\n\n" + description
+	}
+	return ""
+}
+
+// uriDir is the directory of a file:// document, or "" for other URIs
+func uriDir(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" || u.Path == "" {
+		return ""
+	}
+	return filepath.Dir(filepath.FromSlash(u.Path))
+}
+
+// lspOffset converts an LSP position to a byte offset in text
+func lspOffset(text string, pos lspPosition) int {
+	offset := 0
+	for line := 0; line < pos.Line; line++ {
+		i := strings.IndexByte(text[offset:], '\n')
+		if i < 0 {
+			return len(text)
+		}
+		offset += i + 1
+	}
+	for units := 0; units < pos.Character && offset < len(text) && text[offset] != '\n'; {
+		r, size := utf8.DecodeRuneInString(text[offset:])
+		units += len(utf16.Encode([]rune{r}))
+		offset += size
+	}
+	return offset
+}
+
+// lspPositionAt converts a byte offset in text to an LSP position
+func lspPositionAt(text string, offset int) lspPosition {
+	if offset > len(text) {
+		offset = len(text)
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	lineStart := strings.LastIndexByte(text[:offset], '\n') + 1
+	character := 0
+	for _, r := range text[lineStart:offset] {
+		character += len(utf16.Encode([]rune{r}))
+	}
+	return lspPosition{Line: strings.Count(text[:offset], "\n"), Character: character}
+}
+
+// lspCommand runs "sink lsp": a language server on stdin and stdout
+func lspCommand() {
+	for _, arg := range os.Args[2:] {
+		switch arg {
+		case "-h", "--help":
+			printLSPHelp()
+			os.Exit(0)
+		case "--stdio":
+			// Editors pass this by convention; stdio is the only transport
+		default:
+			fmt.Fprintf(os.Stderr, "Error: unexpected argument: %s\n", arg)
+			os.Exit(1)
+		}
+	}
+
+	server, err := newLSPServer(os.Stdin, os.Stdout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := server.Serve(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func printLSPHelp() {
+	fmt.Printf(`sink lsp - Language server for sink config files
+
+Usage:
+  sink lsp [--stdio]
+
+Description:
+  Speaks the Language Server Protocol on stdin and stdout, so any editor
+  with an LSP client gets support for sink configs:
+
+  • Completion of the keys the schema allows where the cursor is, of enum
+    and boolean values, and of step types as snippets in install_steps
+  • Completion of fact names, variables and {{.sink.*}} helpers inside
+    {{ }} templates
+  • Hover documentation for keys from the schema, and for facts and
+    variables in templates from their descriptions
+  • Diagnostics as you type: the JSON syntax errors, duplicate keys,
+    unknown fields and validation errors "sink validate" reports
+
+  Point the editor's LSP client at "sink lsp" for *.json files that are
+  sink configs (e.g. install-config.json).
+
+Options:
+  --stdio                Accepted for editors that pass it; stdio is the
+                         only transport
+  -h, --help             Show this help message
+
+Examples:
+  sink lsp
+`)
+}
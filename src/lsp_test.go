@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// lspFacts starts a config with facts and variables, open at its install steps
+const lspFacts = `{"version": "1.0.0",
+	"facts": {"has_docker": {"command": "command -v docker", "type": "boolean", "description": "Docker is installed"}},
+	"variables": {"channel": {"default": "stable"}},
+	"platforms": [{"os": "linux", "match": "Linux", "name": "Linux", "install_steps": [`
+
+// lspCursor returns a test document without its | cursor marker, and the
+// cursor's offset
+func lspCursor(text string) (*lspDocument, int) {
+	offset := strings.Index(text, "|")
+	text = text[:offset] + text[offset+1:]
+	doc := &lspDocument{Text: text}
+	var parsed struct {
+		Facts     map[string]FactDef     `json:"facts"`
+		Variables map[string]VariableDef `json:"variables"`
+	}
+	json.Unmarshal([]byte(lspFacts+"]}]}"), &parsed)
+	doc.Facts, doc.Variables = parsed.Facts, parsed.Variables
+	return doc, offset
+}
+
+// completionLabels indexes completion items by label
+func completionLabels(items []lspCompletionItem) map[string]lspCompletionItem {
+	labels := map[string]lspCompletionItem{}
+	for _, item := range items {
+		labels[item.Label] = item
+	}
+	return labels
+}
+
+// TestLSPComplete tests completions of keys, step types, values and
+// template fields in an incomplete config
+func TestLSPComplete(t *testing.T) {
+	server, err := newLSPServer(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Keys of a step, labeled with the step type they select
+	items := completionLabels(server.complete(lspCursor(lspFacts + `{"name": "Install", |`)))
+	if item, ok := items["command"]; !ok || item.Detail != "Command execution step" || item.InsertText != `"command": ` {
+		t.Errorf("expected the command key, got %+v", item)
+	}
+	if _, ok := items["on_missing"]; !ok {
+		t.Error("expected the keys of every step type")
+	}
+	if _, ok := items["name"]; ok {
+		t.Error("expected keys already present to be left out")
+	}
+
+	// A key being typed inside quotes is completed without quotes
+	items = completionLabels(server.complete(lspCursor(lspFacts + `{"name": "Install", "ch|`)))
+	if item := items["check"]; item.InsertText != "" || item.Documentation == nil {
+		t.Errorf("expected a bare check key with documentation, got %+v", item)
+	}
+
+	// Step types in install_steps
+	items = completionLabels(server.complete(lspCursor(lspFacts + `|`)))
+	if item := items["Check-with-remediation step"]; item.InsertText != `{"name": "${1:name}", "check": "$2", "on_missing": [$3]}` || item.InsertTextFormat != lspFormatSnippet {
+		t.Errorf("unexpected snippet %+v", item)
+	}
+
+	// Enum values
+	items = completionLabels(server.complete(lspCursor(lspFacts + `{"name": "Install", "risk": |`)))
+	if item := items["high"]; item.InsertText != `"high"` || len(items) != 3 {
+		t.Errorf("expected the risk levels, got %v", items)
+	}
+
+	// Template fields: facts, variables and helpers
+	items = completionLabels(server.complete(lspCursor(lspFacts + `{"name": "Install", "command": "apt-get install {{if .ha|`)))
+	if item := items["has_docker"]; item.InsertText != "has_docker" || item.Detail != "fact (boolean)" {
+		t.Errorf("expected the has_docker fact, got %+v", item)
+	}
+	if _, ok := items["channel"]; !ok {
+		t.Error("expected the channel variable")
+	}
+	items = completionLabels(server.complete(lspCursor(lspFacts + `{"name": "Install", "command": "echo {{|`)))
+	if item := items["sink"]; item.InsertText != ".sink" {
+		t.Errorf("expected a leading dot to be added, got %+v", item)
+	}
+	items = completionLabels(server.complete(lspCursor(lspFacts + `{"name": "Install", "command": "cat {{.sink.|}}"}`)))
+	if _, ok := items["tmpfile"]; !ok || items["has_docker"].Label != "" {
+		t.Errorf("expected the sink helpers, got %v", items)
+	}
+}
+
+// TestLSPHover tests hover documentation of keys and template fields
+func TestLSPHover(t *testing.T) {
+	server, err := newLSPServer(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	text := server.hover(lspCursor(lspFacts + `{"name": "Install", "ri|sk": "high"}`))
+	if !strings.HasPrefix(text, "**risk** (`string`)\n\nRisk level") || !strings.Contains(text, "One of: low medium high") {
+		t.Errorf("unexpected key hover:\n%s", text)
+	}
+	text = server.hover(lspCursor(lspFacts + `{"name": "Install", "command": "{{if .has_do|cker}}x{{end}}"}`))
+	if !strings.Contains(text, "**has_docker** (fact, boolean)") || !strings.Contains(text, "Docker is installed") {
+		t.Errorf("unexpected fact hover:\n%s", text)
+	}
+	if text := server.hover(lspCursor(lspFacts + `{"name": "Ins|tall"}`)); text != "" {
+		t.Errorf("expected no hover for a plain value, got %q", text)
+	}
+}
+
+// TestLSPDiagnose tests that validation problems are placed where they are
+func TestLSPDiagnose(t *testing.T) {
+	server, err := newLSPServer(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		text     string
+		message  string
+		position lspPosition
+	}{
+		{"syntax", lspFacts + "\n{\"name\": }]}]}", "invalid character '}'", lspPosition{Line: 4, Character: 9}},
+		{"unknown field", lspFacts + "\n{\"name\": \"jq\", \"command\": \"true\",\n \"on_missng\": []}]}]}",
+			"unknown field 'platforms[0].install_steps[0].on_missng' (did you mean 'on_missing'?)", lspPosition{Line: 5, Character: 1}},
+		{"duplicate", lspFacts + "\n{\"name\": \"jq\", \"command\": \"true\", \"name\": \"x\"}]}]}", "duplicate key 'platforms[0].install_steps[0].name'", lspPosition{Line: 4, Character: 34}},
+		{"validation", lspFacts + "]}]}", "platform must have either install_steps or distributions", lspPosition{}},
+	}
+	for _, tt := range tests {
+		diagnostics := server.diagnose(tt.text, "")
+		if len(diagnostics) != 1 {
+			t.Errorf("%s: expected one diagnostic, got %+v", tt.name, diagnostics)
+			continue
+		}
+		if d := diagnostics[0]; !strings.Contains(d.Message, tt.message) || d.Range.Start != tt.position {
+			t.Errorf("%s: unexpected diagnostic %+v", tt.name, d)
+		}
+	}
+	if diagnostics := server.diagnose(lspFacts+`{"name": "jq", "command": "true"}]}]}`, ""); len(diagnostics) != 0 {
+		t.Errorf("expected a valid config to have no diagnostics, got %+v", diagnostics)
+	}
+}
+
+// TestLSPPositions tests converting between offsets and UTF-16 positions
+func TestLSPPositions(t *testing.T) {
+	text := "{\n  \"name\": \"héllo 🚀\", \"x\": 1\n}"
+	offset := strings.Index(text, `"x"`)
+	pos := lspPositionAt(text, offset)
+	if pos != (lspPosition{Line: 1, Character: 22}) {
+		t.Errorf("unexpected position %+v", pos)
+	}
+	if got := lspOffset(text, pos); got != offset {
+		t.Errorf("expected offset %d, got %d", offset, got)
+	}
+}
+
+// TestLSPServe tests a session: initialize, open a document, complete,
+// shut down and exit
+func TestLSPServe(t *testing.T) {
+	var in bytes.Buffer
+	send := func(id int, method string, params interface{}) {
+		msg := map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params}
+		if id > 0 {
+			msg["id"] = id
+		}
+		body, _ := json.Marshal(msg)
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	}
+	uri := "file:///tmp/install-config.json"
+	send(1, "initialize", map[string]interface{}{})
+	send(0, "initialized", map[string]interface{}{})
+	send(0, "textDocument/didOpen", map[string]interface{}{"textDocument": map[string]interface{}{"uri": uri, "text": `{"versio": "1.0.0"}`}})
+	send(2, "textDocument/completion", map[string]interface{}{"textDocument": map[string]string{"uri": uri}, "position": lspPosition{Line: 0, Character: 1}})
+	send(3, "workspace/symbol", map[string]interface{}{})
+	send(4, "shutdown", nil)
+	send(0, "exit", nil)
+
+	var out bytes.Buffer
+	server, err := newLSPServer(&in, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := server.Serve(); err != nil {
+		t.Fatalf("Serve failed: %v", err)
+	}
+
+	reader := &lspServer{in: bufio.NewReader(&out)}
+	var replies []lspMessage
+	for {
+		body, err := reader.readMessage()
+		if err != nil {
+			break
+		}
+		var msg lspMessage
+		if err := json.Unmarshal(body, &msg); err != nil {
+			t.Fatal(err)
+		}
+		replies = append(replies, msg)
+	}
+	if len(replies) != 5 {
+		t.Fatalf("expected 5 messages, got %d", len(replies))
+	}
+	if !strings.Contains(string(replies[0].Result), `"hoverProvider":true`) {
+		t.Errorf("unexpected initialize result %s", replies[0].Result)
+	}
+	if replies[1].Method != "textDocument/publishDiagnostics" || !strings.Contains(string(replies[1].Params), "unknown field 'versio' (did you mean 'version'?)") {
+		t.Errorf("expected diagnostics, got %+v", replies[1])
+	}
+	if !strings.Contains(string(replies[2].Result), `"label":"platforms"`) {
+		t.Errorf("expected key completions, got %s", replies[2].Result)
+	}
+	if replies[3].Error == nil || replies[3].Error.Code != lspErrMethod {
+		t.Errorf("expected an unsupported method error, got %+v", replies[3])
+	}
+	if string(replies[4].Result) != "null" {
+		t.Errorf("expected a null shutdown result, got %s", replies[4].Result)
+	}
+}
@@ -5,8 +5,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 )
 
 //go:embed sink.schema.json
@@ -37,10 +39,44 @@ func main() {
 		remoteCommand()
 	case "facts":
 		factsCommand()
+	case "console":
+		consoleCommand()
 	case "validate":
 		validateCommand()
+	case "explain":
+		explainCommand()
+	case "test":
+		testCommand()
+	case "lock":
+		lockCommand()
+	case "lsp":
+		lspCommand()
+	case "introspect":
+		introspectCommand()
 	case "schema":
 		schemaCommand()
+	case "templates":
+		templatesCommand()
+	case "serve-config":
+		serveConfigCommand()
+	case "install-agent":
+		installAgentCommand()
+	case "gc":
+		gcCommand()
+	case "db":
+		dbCommand()
+	case "checksum":
+		checksumCommand()
+	case "sign":
+		signCommand()
+	case "verify-signature":
+		verifySignatureCommand()
+	case "package":
+		packageCommand()
+	case "export":
+		exportCommand()
+	case "tf-wrapper":
+		tfWrapperCommand()
 	case "help", "-h", "--help":
 		// Handle "sink help <command>"
 		if len(os.Args) > 2 {
@@ -66,8 +102,26 @@ Commands:
   bootstrap <source>  Bootstrap from URL or file (HTTP/HTTPS/GitHub)
   remote deploy       Deploy to remote hosts via SSH
   facts <config>      Gather and display facts from config file
+  console <config>    Interactively run steps and commands from a config
   validate <config>   Validate config file structure
+  explain <config> <step>
+                      Describe a step, its remediations and the facts it uses
+  test <config>       Plan a config for each platform with mocked facts
+  lock <config>       Resolve step library dependencies into sink.lock
+  lsp                 Language server for editing configs
+  introspect          Report supported step types, transports and features
   schema              Output JSON schema to stdout
+  templates           List or render built-in starter configs
+  serve-config <dir>  Serve a directory of configs over HTTP
+  install-agent       Run sink bootstrap on a schedule (systemd/launchd)
+  gc                  Remove old workspaces, cached downloads and logs
+  db                  Import run results into SQLite and query them
+  checksum <file>     Print (or --write) a file's SHA256 checksum
+  sign <file>         Write a detached signature for a config
+  verify-signature    Check a config against its detached signature
+  package <dir>       Bundle a config and its assets into a .tar.gz
+  export <config>     Generate cloud-init user-data that bootstraps a config
+  tf-wrapper          Run a config from Terraform (JSON spec on stdin)
   version             Show version information
   help [command]      Show help for a specific command
 
@@ -75,6 +129,28 @@ Global Options:
   -h, --help         Show help for command
   -v, --version      Show version information
 
+Environment:
+  SINK_PLATFORM      Default for --platform
+  SINK_VERBOSE       Default for --verbose (1/true/yes or 0/false/no)
+  SINK_JSON          Default for --json (1/true/yes or 0/false/no)
+  SINK_CACHE_DIR     Where sink keeps cached data such as bundles and step
+                     libraries (default: $XDG_CACHE_HOME/sink or
+                     ~/Library/Caches/sink)
+  SINK_STATE_DIR     Where sink keeps the trust store, rate limit budgets
+                     and console history (default: $XDG_STATE_HOME/sink or
+                     ~/Library/Application Support/sink)
+  SINK_LOG_DIR       Where sink writes log files (default: the state
+                     directory's logs/ or ~/Library/Logs/sink)
+  SINK_WORKSPACE_DIR Where run workspaces are created (default: the temp
+                     directory)
+  SINK_HTTP_TIMEOUT  Timeout for HTTP requests (e.g., 45s, 2m; bare numbers
+                     are seconds)
+  SINK_CREDENTIAL_HELPER
+                     Default for bootstrap --credential-helper
+  SINK_AUDIT_LOG     Default for --audit-log (1/true/yes or 0/false/no)
+  Command-line flags take precedence over environment variables, which
+  take precedence over built-in defaults.
+
 Get detailed help for a command:
   sink help execute
   sink execute --help
@@ -84,6 +160,8 @@ Examples:
   sink execute --dry-run install-config.json
   sink facts install-config.json
   sink validate install-config.json
+  sink explain install-config.json "Install Docker"
+  sink test install-config.json --matrix darwin,linux/ubuntu
   sink schema > schema.json
 
 Documentation:
@@ -108,7 +186,20 @@ Version: %s
 //   - remote: SSH deployment to remote hosts
 //   - facts: System fact gathering
 //   - validate: Configuration validation
+//   - explain: Readable description of a config step
+//   - test: Matrix planning with mocked facts
+//   - lock: Step library resolution into sink.lock
+//   - lsp: Language server for config files
+//   - introspect: Supported features, for orchestration tools
 //   - schema: JSON schema output
+//   - templates: Embedded starter configs
+//   - serve-config: HTTP server for a directory of configs
+//   - install-agent: Scheduled bootstrap with systemd or launchd
+//   - gc: Retention-based cleanup of run data
+//   - db: SQLite database of imported run results
+//   - checksum: SHA256 checksum generation
+//   - sign/verify-signature: Detached config signatures
+//   - package: Config bundle creation
 //   - version: Version information
 //
 // For unknown commands, displays an error message and shows general usage.
@@ -126,10 +217,44 @@ func printCommandHelp(command string) {
 		printRemoteHelp()
 	case "facts":
 		printFactsHelp()
+	case "console":
+		printConsoleHelp()
 	case "validate":
 		printValidateHelp()
+	case "explain":
+		printExplainHelp()
+	case "test":
+		printTestHelp()
+	case "lock":
+		printLockHelp()
+	case "lsp":
+		printLSPHelp()
+	case "introspect":
+		printIntrospectHelp()
 	case "schema":
 		printSchemaHelp()
+	case "templates":
+		printTemplatesHelp()
+	case "serve-config":
+		printServeConfigHelp()
+	case "install-agent":
+		printInstallAgentHelp()
+	case "gc":
+		printGCHelp()
+	case "db":
+		printDBHelp()
+	case "checksum":
+		printChecksumHelp()
+	case "sign":
+		printSignHelp()
+	case "verify-signature":
+		printVerifySignatureHelp()
+	case "package":
+		printPackageHelp()
+	case "export":
+		printExportHelp()
+	case "tf-wrapper":
+		printTFWrapperHelp()
 	case "version":
 		printVersionHelp()
 	default:
@@ -143,6 +268,7 @@ func printExecuteHelp() {
 
 Usage:
   sink execute <config> [options]
+  sink execute --bundle <bundle.tar.gz> [<config>] [options]
   sink exec <config> [options]
 
 Description:
@@ -172,6 +298,115 @@ Options:
   --json                 Output execution events as JSON to stdout
                          Enables machine-readable structured output
                          Compatible with --verbose for detailed metadata
+
+  --max-duration <dur>   Overall time budget for the run (e.g., 30m, 1h)
+                         When exceeded, the running step is cancelled,
+                         remaining steps are reported as not_run, and
+                         sink exits with code 124. Ctrl-C stops a run
+                         the same way and exits with code 130
+
+  --snapshot             Record installed packages, tool versions and disk
+                         usage before and after the run and report the diff
+                         (always on when the config has a "snapshot" section)
+
+  --strict               Reject config keys the schema does not define, such
+                         as a misspelled "on_missng" (validate does this by
+                         default)
+
+  --force                Run even if management is disabled on this host
+                         (see Disabling Hosts)
+
+  --splay <dur>          Sleep a random duration up to <dur> before starting
+                         (e.g., 10m). Spreads out fleet-wide runs scheduled
+                         at the same time
+
+  --transcript <file>    Write a markdown transcript of the run (commands,
+                         truncated output, durations and statuses) for
+                         pasting into an incident ticket or PR
+
+  --audit-log            Log every executed command with its user, host,
+                         run ID, step and exit code to syslog (journald on
+                         Linux, unified logging on macOS). The run stops if
+                         the log cannot be opened
+
+  --state-dir <dir>      Keep state such as rate limit budgets in <dir>
+                         (overrides SINK_STATE_DIR)
+  --cache-dir <dir>      Keep cached bundles and libraries in <dir>
+                         (overrides SINK_CACHE_DIR)
+
+  --coverage <file>      Add the steps, remediations and fallbacks the run
+                         took to a coverage file (created if missing), so
+                         "sink test --coverage" can report the branches no
+                         run has exercised
+
+  --event-webhook <url>  POST every event as JSON to <url> (repeatable).
+                         Events are buffered so a slow endpoint does not
+                         hold up the run; failed posts are retried with
+                         backoff on network errors, 429 and 5xx
+  --event-buffer <n>     Events buffered per webhook (default 256)
+  --event-overflow <p>   When a buffer is full: block (wait for the
+                         endpoint, the default) or drop (count and skip
+                         new events, warning at the end of the run)
+
+  --on-broken-pipe <p>   When stdout is closed mid-run (sink execute --json
+                         | head): continue (the default) stops writing
+                         output and finishes the run; abort finishes the
+                         running step, reports the rest not run and exits
+                         with code 141. Either way the full result is kept
+                         in run-<run id>.jsonl in the log directory
+
+  --summary <mode>       End-of-run table of steps (status, changed,
+                         duration, note): short (default; long notes
+                         cut), wide (full notes) or none
+
+  --run-id <id>          Use <id> as the run ID in events, transcripts and
+                         the run workspace instead of a generated
+                         <host>-<uuidv7>, so an orchestrator can correlate
+                         runs it started
+
+  --state-file <file>    Record each step's result in <file>, keyed by run
+                         ID, as soon as the step finishes
+  --resume               With --state-file, continue the latest run of this
+                         config recorded there (or the --run-id run): steps
+                         it completed are skipped unless the config changed
+                         them, so a long install restarts where a network
+                         drop or reboot cut it short
+
+  --bundle <file>        Run a bundle built with "sink package" entirely
+                         offline (see Air-Gapped Bundles)
+
+  --ssh <user@host[:port]>
+                         Run the steps on a remote host over SSH instead of
+                         this machine; facts, the platform and the context
+                         come from that host. Uses the system ssh and scp
+                         without prompting: keys or an agent must
+                         authenticate, and the host key must be known
+  --ssh-identity <file>  Authenticate with this private key only
+  --ssh-forward-agent    Forward the local ssh-agent to the host
+  --ssh-known-hosts <file>
+                         Verify the host key against this file
+  --ssh-accept-new       Record the key of a host not seen before (a
+                         changed key is still refused)
+
+  --ui                   Show progress in a full-screen terminal UI: the
+                         step list with statuses and elapsed times, counts
+                         and the output of steps as they finish. Plain
+                         output stays the default
+
+  --break-at <step>      Pause before the named step (repeatable) with a
+                         prompt: "facts" shows gathered facts, "! <cmd>"
+                         runs a command on the target with {{.fact}}
+                         templates, "next" runs the step and pauses again,
+                         "continue" carries on and "abort" stops the run
+
+  --var <name>=<value>   Set a variable declared in the config's "variables"
+                         section (repeatable); values are checked against
+                         the declared type, values, pattern or range
+
+  --confirm-host <host>  Answer the confirmation prompt by naming the host
+                         the run is about to change; the run stops if it
+                         is a different host. Needed to run a config
+                         marked "danger" with --json
   
   -h, --help             Show this help message
 
@@ -179,9 +414,35 @@ Arguments:
   <config>               Path to configuration file (JSON format)
                          Must be a valid Sink configuration
 
+Environment:
+  SINK_PLATFORM, SINK_VERBOSE and SINK_JSON set defaults for --platform,
+  --verbose and --json; the flags win when both are given. See "sink help".
+
+Disabling Hosts:
+  If /etc/sink/disabled or ~/.sink/skip exists, sink exits 0 without
+  running anything and reports "management disabled on this host". The
+  first line of the file is shown as the reason. With --ssh the files are
+  looked for on the target host.
+
+Air-Gapped Bundles:
+  With --bundle, sink verifies every file in the bundle against its
+  SHA256SUMS manifest, unpacks it under the cache directory and runs
+  <config> (default: config.json) from the bundle root. Before anything
+  runs, fact and step commands are checked against an offline policy:
+  commands that download (curl, wget, git clone, apt-get install of
+  repository packages, pip install, ...) or mention a URL are refused,
+  and the run stops. Installing local files (apt-get install ./pkg.deb)
+  and offline flags (pip --no-index) are allowed. The run ends by listing
+  the bundled assets that its commands used.
+
 Exit Codes:
   0                      All steps executed successfully
   1                      One or more steps failed or config invalid
+  75                     Steps deferred (outside their maintenance window)
+  124                    --max-duration exceeded
+  130                    Interrupted (Ctrl-C or SIGTERM)
+  141                    Stdout was closed and --on-broken-pipe abort
+                         stopped the run
 
 Examples:
   # Execute configuration
@@ -205,6 +466,18 @@ Examples:
   # Override platform for testing
   sink execute --platform linux install-config.json
 
+  # Stay inside a CI job's time budget
+  sink execute --max-duration 20m install-config.json
+
+  # Start at a random point within the next 10 minutes
+  sink execute --splay 10m install-config.json
+
+  # Keep a record of what changed for the change ticket
+  sink execute --transcript run.md install-config.json
+
+  # Run a packaged bundle on an air-gapped host
+  sink execute --bundle lab-v1.2.0.tar.gz
+
   # Execute with short command alias
   sink exec config.json
 
@@ -231,7 +504,7 @@ func printFactsHelp() {
 	fmt.Printf(`sink facts - Gather and display system facts
 
 Usage:
-  sink facts <config>
+  sink facts <config> [--tui | --diff-last | --output <format>]
 
 Description:
   Gathers facts (system information) defined in the configuration file
@@ -249,13 +522,41 @@ Description:
   • Understanding what information will be available
   • Testing fact commands before execution
   • Viewing environment variable exports
+  • Investigating drift since the last run (--diff-last)
 
 Options:
+  --tui                  Explore facts in an interactive terminal UI
+  --diff-last            Show facts that changed since the last run of this
+                         config on this host
+  --output <format>      Print only the facts, as json, dotenv, shell or gha
   -h, --help             Show this help message
 
 Arguments:
   <config>               Path to configuration file with facts section
 
+Drift (--diff-last):
+  Each sink execute or bootstrap run (not dry runs) records the facts it
+  gathered under the state directory. --diff-last gathers them again and
+  lists new facts (+), changed values (~) and facts no longer gathered (-),
+  such as a new OS version or a tool that disappeared. Secret facts are
+  never recorded or compared.
+
+Output formats (--output):
+  json     {"fact_name": value, ...} with strings, booleans and integers
+  dotenv   NAME=value lines for a .env file, quoted where needed
+  shell    export NAME='value' lines to eval
+  gha      fact_name=value lines to append to $GITHUB_OUTPUT; multi-line
+           values use the name<<delimiter form
+
+  dotenv and shell name variables after a fact's "export", or its name in
+  upper case. Secret facts are left out of every format.
+
+Fact Explorer (--tui):
+  Lists every fact with its status, type and value; the selected fact's
+  command, error (for facts that failed, required or not), export and
+  description are shown below the list. Keys: up/down (or j/k) select,
+  r or Enter re-runs the selected fact, a re-runs all, q quits.
+
 Output:
   For each fact, displays:
   • Name - The fact identifier
@@ -270,8 +571,16 @@ Examples:
   # Gather and display all facts
   sink facts install-config.json
 
-  # Use with eval to export to shell
-  eval $(sink facts config.json | grep "export")
+  # Debug fact definitions interactively
+  sink facts install-config.json --tui
+
+  # What changed on this host since the last run?
+  sink facts install-config.json --diff-last
+
+  # Export facts to the shell, a .env file or a GitHub Actions step
+  eval "$(sink facts config.json --output shell)"
+  sink facts config.json --output dotenv > .env
+  sink facts config.json --output gha >> "$GITHUB_OUTPUT"
 
   # View facts for a specific platform
   sink facts --platform linux config.json
@@ -303,13 +612,20 @@ Usage:
 
 Description:
   Validates the configuration file against the Sink schema. Checks for:
-  • Valid JSON syntax
+  • Valid JSON syntax (// and /* */ comments and trailing commas are allowed)
   • Required fields present (version, platforms)
   • Correct data types for all fields
   • Valid platform patterns and OS names
   • Valid fact definitions
   • Valid install step structures
+  • Unknown keys such as a misspelled "on_missng" (reported with their path)
   • Bootstrap configuration (if present)
+  • A "$schema" release tag of a different major schema version (warning)
+
+  Commands are also linted for portability: bash-isms such as [[ ]] or
+  <<< and GNU-only flags such as grep -P fail where /bin/sh is dash or
+  BusyBox ash (Debian, Alpine, minimal images). Findings are reported
+  without failing validation unless --posix is given.
 
   This command is useful for:
   • Testing configurations before deployment
@@ -318,6 +634,8 @@ Description:
   • Understanding configuration structure
 
 Options:
+  --no-strict            Ignore keys the schema does not define
+  --posix                Fail when a command is not POSIX
   -h, --help             Show this help message
 
 Arguments:
@@ -332,6 +650,7 @@ Output:
     - Number of platforms
     - Platform details (install steps, distributions)
     - Default values (if present)
+  • Portability findings (if any)
 
   On failure:
   • ❌ Validation failed with detailed error message
@@ -432,6 +751,10 @@ Schema Location:
   Online: https://raw.githubusercontent.com/radiolabme/sink/main/src/sink.schema.json
   Versioned: .../v0.1.0/src/sink.schema.json (replace with git tag)
 
+  sink templates render adds a "$schema" pinned to this binary's tag, and
+  sink validate warns when "$schema" names a release tag of a different
+  major schema version than the one embedded.
+
 Related Commands:
   sink validate <config>     Validate against schema
 `)
@@ -463,6 +786,36 @@ func executeCommand() {
 	var verbose bool
 	var jsonOutput bool
 	var platformOverride string
+	var maxDuration time.Duration
+	var splay time.Duration
+	var snapshot bool
+	var force bool
+	var strict bool
+	var transcriptPath string
+	var stateFile string
+	var resume bool
+	var bundlePath string
+	var runID string
+	var breakAt []string
+	var confirmHost string
+	var coveragePath string
+	var eventWebhooks []string
+	var eventBuffer int
+	eventOverflow := EventOverflowBlock
+	onBrokenPipe := BrokenPipeContinue
+	variables := map[string]string{}
+	var ui bool
+	summaryMode := SummaryShort
+	var sshTarget string
+	var sshOptions SSHOptions
+
+	// SINK_* environment variables provide defaults; flags override them
+	env := loadEnvSettings()
+	verbose = env.Verbose
+	jsonOutput = env.JSON
+	platformOverride = env.Platform
+	auditLog := env.AuditLog
+	credentialHelper = env.CredentialHelper // Authenticates --event-webhook posts
 
 	// Parse flags
 	args := os.Args[2:]
@@ -478,6 +831,18 @@ func executeCommand() {
 			verbose = true
 		case "--json":
 			jsonOutput = true
+		case "--snapshot":
+			snapshot = true
+		case "--force":
+			force = true
+		case "--strict":
+			strict = true
+		case "--audit-log":
+			auditLog = true
+		case "--ui":
+			ui = true
+		case "--resume":
+			resume = true
 		case "--platform":
 			if i+1 < len(args) {
 				platformOverride = args[i+1]
@@ -486,8 +851,192 @@ func executeCommand() {
 				fmt.Fprintf(os.Stderr, "Error: --platform requires a value\n")
 				os.Exit(1)
 			}
+		case "--max-duration":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --max-duration requires a value\n")
+				os.Exit(1)
+			}
+			d, err := parseMaxDuration(args[i+1])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			maxDuration = d
+			i++
+		case "--transcript":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --transcript requires a file\n")
+				os.Exit(1)
+			}
+			transcriptPath = args[i+1]
+			i++
+		case "--state-file":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --state-file requires a file\n")
+				os.Exit(1)
+			}
+			stateFile = args[i+1]
+			i++
+		case "--state-dir", "--cache-dir":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: %s requires a directory\n", arg)
+				os.Exit(1)
+			}
+			if arg == "--state-dir" {
+				dirOverrides.State = args[i+1]
+			} else {
+				dirOverrides.Cache = args[i+1]
+			}
+			i++
+		case "--summary":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --summary requires a value (wide, short or none)\n")
+				os.Exit(1)
+			}
+			mode, err := parseSummaryMode(args[i+1])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			summaryMode = mode
+			i++
+		case "--run-id":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --run-id requires a value\n")
+				os.Exit(1)
+			}
+			if err := validateRunID(args[i+1]); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			runID = args[i+1]
+			i++
+		case "--break-at":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --break-at requires a step name\n")
+				os.Exit(1)
+			}
+			breakAt = append(breakAt, args[i+1])
+			i++
+		case "--confirm-host":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --confirm-host requires a host name\n")
+				os.Exit(1)
+			}
+			confirmHost = args[i+1]
+			i++
+		case "--var":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --var requires name=value\n")
+				os.Exit(1)
+			}
+			name, value, err := parseVarFlag(args[i+1])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			variables[name] = value
+			i++
+		case "--bundle":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --bundle requires a file\n")
+				os.Exit(1)
+			}
+			bundlePath = args[i+1]
+			i++
+		case "--coverage":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --coverage requires a file\n")
+				os.Exit(1)
+			}
+			coveragePath = args[i+1]
+			i++
+		case "--event-webhook":
+			if i+1 >= len(args) || !isURL(args[i+1]) {
+				fmt.Fprintf(os.Stderr, "Error: --event-webhook requires an http(s) URL\n")
+				os.Exit(1)
+			}
+			eventWebhooks = append(eventWebhooks, args[i+1])
+			i++
+		case "--event-buffer":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --event-buffer requires a number of events\n")
+				os.Exit(1)
+			}
+			n, err := parseEventBuffer(args[i+1])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			eventBuffer = n
+			i++
+		case "--event-overflow":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --event-overflow requires a value (block or drop)\n")
+				os.Exit(1)
+			}
+			policy, err := parseEventOverflow(args[i+1])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			eventOverflow = policy
+			i++
+		case "--on-broken-pipe":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --on-broken-pipe requires a value (continue or abort)\n")
+				os.Exit(1)
+			}
+			policy, err := parseBrokenPipePolicy(args[i+1])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			onBrokenPipe = policy
+			i++
+		case "--ssh":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --ssh requires a target (user@host or user@host:port)\n")
+				os.Exit(1)
+			}
+			sshTarget = args[i+1]
+			i++
+		case "--ssh-identity", "--ssh-known-hosts":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: %s requires a file\n", arg)
+				os.Exit(1)
+			}
+			if arg == "--ssh-identity" {
+				sshOptions.Identity = args[i+1]
+			} else {
+				sshOptions.KnownHosts = args[i+1]
+			}
+			i++
+		case "--ssh-forward-agent":
+			sshOptions.ForwardAgent = true
+		case "--ssh-accept-new":
+			sshOptions.AcceptNew = true
+		case "--splay":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --splay requires a value\n")
+				os.Exit(1)
+			}
+			d, err := parseSplay(args[i+1])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			splay = d
+			i++
 		default:
-			if configFile == "" {
+			if value, ok := strings.CutPrefix(arg, "--summary="); ok {
+				mode, err := parseSummaryMode(value)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					os.Exit(1)
+				}
+				summaryMode = mode
+			} else if configFile == "" {
 				configFile = arg
 			} else {
 				fmt.Fprintf(os.Stderr, "Error: unexpected argument: %s\n", arg)
@@ -496,20 +1045,197 @@ func executeCommand() {
 		}
 	}
 
-	if configFile == "" {
+	if configFile == "" && bundlePath == "" {
 		fmt.Fprintf(os.Stderr, "Error: config file required\n")
 		os.Exit(1)
 	}
+	if resume && stateFile == "" {
+		fmt.Fprintf(os.Stderr, "Error: --resume needs --state-file\n")
+		os.Exit(1)
+	}
+	if sshTarget == "" && sshOptions != (SSHOptions{}) {
+		fmt.Fprintf(os.Stderr, "Error: --ssh-* options need --ssh\n")
+		os.Exit(1)
+	}
+	if sshTarget != "" && bundlePath != "" {
+		fmt.Fprintf(os.Stderr, "Error: --ssh cannot be combined with --bundle (bundles run where they are unpacked)\n")
+		os.Exit(1)
+	}
+
+	if ui {
+		switch {
+		case jsonOutput:
+			fmt.Fprintf(os.Stderr, "Error: --ui cannot be combined with --json\n")
+			os.Exit(1)
+		case len(breakAt) > 0:
+			fmt.Fprintf(os.Stderr, "Error: --ui cannot be combined with --break-at\n")
+			os.Exit(1)
+		case !isTerminal(os.Stdout):
+			fmt.Fprintln(os.Stderr, "Warning: --ui needs a terminal; showing plain progress")
+			ui = false
+		}
+	}
+
+	// The disable marker belongs to the host the steps run on; a remote
+	// host's is checked over SSH below
+	if sshTarget == "" {
+		exitIfHostDisabled(nil, jsonOutput, force)
+	}
+
+	// A bundle is verified and unpacked first; the config argument then
+	// names the config inside it
+	var bundle *Bundle
+	configPath := configFile
+	if bundlePath != "" {
+		var err error
+		bundle, err = openBundle(bundlePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if configFile == "" {
+			configFile = "config.json"
+		}
+		configPath = filepath.Join(bundle.Dir, filepath.FromSlash(configFile))
+		if !jsonOutput {
+			fmt.Printf("📦 Verified bundle %s (%d files, SHA256 %s)\n", bundlePath, len(bundle.Files), bundle.Checksum[:12])
+		}
+	}
 
 	// Load config
-	config, err := LoadConfig(configFile)
+	config, err := LoadConfigWithOptions(configPath, LoadOptions{Strict: strict})
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
 		os.Exit(1)
 	}
 
+	waitSplay(splay, jsonOutput)
+
+	var transport Transport
+	if sshTarget != "" {
+		transport = NewSSHTransport(sshTarget, sshOptions)
+		exitIfHostDisabled(transport, jsonOutput, force)
+	}
+
 	// Execute using shared function
-	executeConfigWithOptions(config, dryRun, verbose, jsonOutput, platformOverride)
+	executeConfigWithOptions(config, ExecuteOptions{
+		Transport:        transport,
+		DryRun:           dryRun,
+		Verbose:          verbose,
+		JSONOutput:       jsonOutput,
+		PlatformOverride: platformOverride,
+		MaxDuration:      maxDuration,
+		Snapshot:         snapshot,
+		Transcript:       transcriptPath,
+		StateFile:        stateFile,
+		Resume:           resume,
+		ConfigSource:     configFile,
+		Bundle:           bundle,
+		Summary:          summaryMode,
+		RunID:            runID,
+		Host:             env.Host,
+		BreakAt:          breakAt,
+		UI:               ui,
+		ConfirmHost:      confirmHost,
+		Variables:        variables,
+		Coverage:         coveragePath,
+		AuditLog:         auditLog,
+		EventWebhooks:    eventWebhooks,
+		EventBuffer:      eventBuffer,
+		EventOverflow:    eventOverflow,
+		OnBrokenPipe:     onBrokenPipe,
+	})
+}
+
+// highRiskSteps returns the steps declared with risk "high"
+func highRiskSteps(steps []InstallStep) []InstallStep {
+	var high []InstallStep
+	for _, step := range steps {
+		if step.Risk == RiskHigh {
+			high = append(high, step)
+		}
+	}
+	return high
+}
+
+// formatStepAnnotations renders a step's declared impact and risk for display
+func formatStepAnnotations(impact string, risk string) string {
+	switch {
+	case impact != "" && risk != "":
+		return fmt.Sprintf("Impact: %s (risk: %s)", impact, risk)
+	case impact != "":
+		return "Impact: " + impact
+	default:
+		return "Risk: " + risk
+	}
+}
+
+// exitOnOfflineViolations refuses to run a bundle whose facts or steps
+// would access the network
+func exitOnOfflineViolations(violations []string) {
+	if len(violations) == 0 {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Error: bundle cannot run offline; %d commands would access the network:\n", len(violations))
+	for _, violation := range violations {
+		fmt.Fprintf(os.Stderr, "  - %s\n", violation)
+	}
+	os.Exit(1)
+}
+
+// exitOnUnsupportedSteps refuses to run steps the transport cannot run
+func exitOnUnsupportedSteps(problems []string) {
+	if len(problems) == 0 {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Error: %d steps are not supported by the transport:\n", len(problems))
+	for _, problem := range problems {
+		fmt.Fprintf(os.Stderr, "  - %s\n", problem)
+	}
+	os.Exit(1)
+}
+
+// parseMaxDuration parses a --max-duration value such as "30m" or "1h30m"
+func parseMaxDuration(value string) (time.Duration, error) {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid --max-duration '%s': %w", value, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("--max-duration must be positive, got '%s'", value)
+	}
+	return d, nil
+}
+
+// ExecuteOptions controls how executeConfigWithOptions runs a configuration
+type ExecuteOptions struct {
+	DryRun           bool              // Preview steps without executing them
+	Verbose          bool              // Enable detailed logging for debugging
+	JSONOutput       bool              // Emit events as JSON to stdout
+	PlatformOverride string            // Platform to use instead of runtime.GOOS
+	MaxDuration      time.Duration     // Overall time budget for the run (0 means unlimited)
+	Checksums        ChecksumManifest  // Expected SHA256s for files steps fetch (--checksums-url)
+	Snapshot         bool              // Report host changes made by the run (--snapshot or a config "snapshot" section)
+	Transcript       string            // Write a markdown transcript of the run to this file (--transcript)
+	StateFile        string            // Record step results in this file (--state-file)
+	Resume           bool              // Skip the steps the latest recorded run completed (--resume)
+	ConfigSource     string            // Config file or URL, named in the transcript when the config has no name
+	Bundle           *Bundle           // Run offline from this verified bundle (--bundle)
+	Summary          string            // End-of-run table: SummaryShort (default), SummaryWide or SummaryNone
+	RunID            string            // Run ID chosen by an external orchestrator (--run-id); generated when empty
+	Host             HostInfo          // Place in a multi-host deploy from SINK_HOST*; zero Count means this host alone
+	BreakAt          []string          // Steps to pause before with a debugging prompt (--break-at)
+	UI               bool              // Show progress in a full-screen terminal UI (--ui)
+	ConfirmHost      string            // Host name that answers the confirmation prompt (--confirm-host)
+	Variables        map[string]string // Variable values from --var, overriding config defaults
+	Coverage         string            // Add the branches the run takes to this coverage file (--coverage)
+	WarningsAsErrors bool              // Exit nonzero if the run recorded any warnings (--warnings-as-errors)
+	AuditLog         bool              // Log every executed command to syslog/journald (--audit-log)
+	EventWebhooks    []string          // URLs every event is posted to (--event-webhook)
+	EventBuffer      int               // Events buffered per webhook (--event-buffer); 0 means DefaultEventBuffer
+	EventOverflow    string            // What a full buffer does: EventOverflowBlock (default) or EventOverflowDrop
+	OnBrokenPipe     string            // When stdout closes: BrokenPipeContinue (default) or BrokenPipeAbort
+	Transport        Transport         // Where steps run (--ssh); nil means this machine
 }
 
 // executeConfigWithOptions executes a loaded configuration with the specified options.
@@ -517,12 +1243,11 @@ func executeCommand() {
 //
 // Parameters:
 //   - config: A validated Sink configuration loaded from JSON
-//   - dryRun: If true, preview steps without executing them
-//   - verbose: If true, enable detailed logging for debugging
-//   - platformOverride: Optional platform override (e.g., "linux", "darwin")
+//   - opts: Execution options (dry-run, verbose, JSON output, platform override,
+//     checksum manifest); see ExecuteOptions
 //
 // The function performs the following operations:
-//  1. Creates a local transport for command execution
+//  1. Creates a local transport for command execution, unless opts.Transport is set
 //  2. Gathers facts defined in the configuration
 //  3. Determines the target platform (detected or overridden)
 //  4. Selects the appropriate platform configuration
@@ -537,15 +1262,63 @@ func executeCommand() {
 //
 // The function handles user interaction for confirmation in non-dry-run mode
 // and provides real-time progress feedback during execution.
-func executeConfigWithOptions(config *Config, dryRun bool, verbose bool, jsonOutput bool, platformOverride string) {
-	// Create transport
-	transport := NewLocalTransport()
+func executeConfigWithOptions(config *Config, opts ExecuteOptions) {
+	dryRun := opts.DryRun
+	verbose := opts.Verbose
+	jsonOutput := opts.JSONOutput
+	platformOverride := opts.PlatformOverride
+
+	// A reader that exits early (sink execute --json | head) must not kill
+	// the run halfway through a step
+	catchBrokenPipe()
+
+	// The time budget covers the whole run, including fact gathering (and
+	// a bootstrap's download, which began the run)
+	runContext := beginRun(opts.MaxDuration)
+	var deadline time.Time
+	if d, ok := runContext.Deadline(); ok {
+		deadline = d
+	}
+
+	// Variables are checked before anything runs on the host
+	variables, err := ResolveVariables(config.Variables, opts.Variables)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Create transport; a remote host's platform is the OS it reports
+	transport := opts.Transport
+	hostOS := runtime.GOOS
+	if transport == nil {
+		local := NewLocalTransport()
+		// Bundles run from their unpacked root without touching the network
+		if opts.Bundle != nil {
+			local.WorkDir = opts.Bundle.Dir
+			if opts.Checksums == nil {
+				opts.Checksums = opts.Bundle.Manifest
+			}
+			exitOnOfflineViolations(offlineViolations(config.Facts, nil))
+		}
+		transport = local
+	} else {
+		stdout, stderr, exitCode, err := transport.Run("uname -s")
+		if err == nil && exitCode != 0 {
+			err = fmt.Errorf("uname -s exited with %d: %s", exitCode, strings.TrimSpace(stderr))
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: cannot reach the target: %v\n", err)
+			os.Exit(1)
+		}
+		hostOS = goosFromUname(strings.TrimSpace(stdout))
+	}
 
 	// Gather facts
 	if !jsonOutput {
 		fmt.Println("📊 Gathering facts...")
 	}
 	gatherer := NewFactGatherer(config.Facts, transport)
+	gatherer.currentOS = hostOS
 	gatherer.Verbose = verbose
 	facts, err := gatherer.Gather()
 	if err != nil {
@@ -553,16 +1326,29 @@ func executeConfigWithOptions(config *Config, dryRun bool, verbose bool, jsonOut
 		os.Exit(1)
 	}
 
+	activeRedactor = NewRedactor(config.Facts, facts)
+
 	// Display gathered facts (only in non-JSON mode)
 	if !jsonOutput && len(facts) > 0 {
 		fmt.Printf("   Gathered %d facts:\n", len(facts))
 		for name, value := range facts {
-			fmt.Printf("   • %s = %v\n", name, value)
+			fmt.Printf("   • %s = %s\n", name, displayFactValue(config.Facts[name], value))
+		}
+	}
+	if !jsonOutput && len(variables) > 0 {
+		fmt.Printf("   Variables:\n")
+		for _, name := range sortedKeys(variables) {
+			fmt.Printf("   • %s = %v\n", name, variables[name])
 		}
 	}
 
+	// Variables share the template namespace with facts (names cannot clash)
+	for name, value := range variables {
+		facts[name] = value
+	}
+
 	// Determine platform
-	targetOS := runtime.GOOS
+	targetOS := hostOS
 	if platformOverride != "" && !jsonOutput {
 		targetOS = platformOverride
 		fmt.Printf("🎯 Platform override: %s\n", targetOS)
@@ -591,6 +1377,13 @@ func executeConfigWithOptions(config *Config, dryRun bool, verbose bool, jsonOut
 	}
 
 	if selectedPlatform == nil {
+		if config.Fallback != nil {
+			if !dryRun {
+				recordRunCoverage(opts.Coverage, config, []string{fallbackBranch(nil)})
+			}
+			fmt.Fprintf(os.Stderr, "Error: %s\n", config.Fallback.Error)
+			os.Exit(1)
+		}
 		fmt.Fprintf(os.Stderr, "Error: no platform configuration found for %s\n", targetOS)
 		if verbose {
 			fmt.Fprintf(os.Stderr, "[VERBOSE] No platform matched target OS '%s'\n", targetOS)
@@ -606,6 +1399,38 @@ func executeConfigWithOptions(config *Config, dryRun bool, verbose bool, jsonOut
 		os.Exit(1)
 	}
 
+	// Linux platforms may list steps per distribution
+	coverage := coveragePrefix(selectedPlatform, nil)
+	if len(selectedPlatform.Distributions) > 0 {
+		ids := detectDistributionIDs(transport)
+		if verbose {
+			fmt.Fprintf(os.Stderr, "[VERBOSE] Distribution IDs: %v\n", ids)
+		}
+		platform, dist, err := selectDistribution(selectedPlatform, ids)
+		if err != nil {
+			if selectedPlatform.Fallback != nil && !dryRun {
+				recordRunCoverage(opts.Coverage, config, []string{fallbackBranch(selectedPlatform)})
+			}
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		coverage = coveragePrefix(selectedPlatform, dist)
+		selectedPlatform = platform
+	}
+
+	if opts.Bundle != nil {
+		exitOnOfflineViolations(offlineViolations(nil, selectedPlatform.InstallSteps))
+	}
+	if err := validateBreakpoints(opts.BreakAt, selectedPlatform.InstallSteps); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	caps := transportCapabilities(transport)
+	exitOnUnsupportedSteps(unsupportedSteps(selectedPlatform.InstallSteps, caps))
+	if opts.MaxDuration > 0 && !caps.Cancel && !jsonOutput {
+		fmt.Fprintln(os.Stderr, "Warning: the transport cannot interrupt commands; --max-duration is only checked between commands")
+	}
+
 	if !jsonOutput {
 		fmt.Printf("🖥️  Platform: %s (%s)\n", selectedPlatform.Name, selectedPlatform.OS)
 		fmt.Printf("📝 Steps: %d\n\n", len(selectedPlatform.InstallSteps))
@@ -613,9 +1438,119 @@ func executeConfigWithOptions(config *Config, dryRun bool, verbose bool, jsonOut
 
 	// Create executor
 	executor := NewExecutor(transport)
+	if opts.RunID != "" {
+		executor.SetRunID(opts.RunID)
+	}
+	// --state-file records every step's result; --resume continues the
+	// latest run of this config recorded there
+	if opts.StateFile != "" {
+		stateFile, err := loadStateFile(opts.StateFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		source := opts.ConfigSource
+		if abs, err := filepath.Abs(source); err == nil && !isURL(source) {
+			source = abs
+		}
+		state := newRunState(stateFile, opts.RunID, executor.runID, source, selectedPlatform.Name, opts.Resume)
+		executor.SetRunID(state.runID)
+		executor.State = state
+		if opts.Resume && !jsonOutput {
+			if n := state.Resumed(); n > 0 {
+				fmt.Printf("↻ Resuming run %s: %d steps completed earlier\n\n", state.runID, n)
+			} else {
+				fmt.Printf("↻ No earlier run to resume in %s, starting run %s\n\n", opts.StateFile, state.runID)
+			}
+		}
+	}
 	executor.DryRun = dryRun
 	executor.Verbose = verbose
 	executor.JSONOutput = jsonOutput
+	executor.SetVariables(variables)
+	executor.Checksums = opts.Checksums
+	executor.Deadline = deadline
+	executor.Context = runContext
+	executor.Window = config.Window
+	executor.Bundle = opts.Bundle
+	executor.Gatherer = gatherer
+	if config.ExportFacts != nil {
+		executor.ExportFacts = *config.ExportFacts
+	}
+	executor.InstallScope = config.InstallScope
+	// sink facts --diff-last compares later facts with these
+	if !dryRun && opts.ConfigSource != "" {
+		if err := recordFacts(opts.ConfigSource, executor.runID, config.Facts, facts, time.Now()); err != nil && verbose {
+			verboseLog("Cannot record facts: %v", err)
+		}
+	}
+	if opts.Host.Count > 0 {
+		executor.Host = opts.Host
+	}
+	rateLimiter, err := NewRateLimiter(config.RateLimits)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	executor.RateLimiter = rateLimiter
+	// Pause prompts and breakpoints share one reader so neither loses the
+	// other's buffered input
+	operator := NewOperator(os.Stdin, os.Stderr)
+	if !jsonOutput && !opts.UI {
+		executor.Operator = operator
+	}
+	if len(opts.BreakAt) > 0 {
+		executor.Debugger = NewDebugger(opts.BreakAt, operator.In, os.Stderr)
+	}
+	if opts.Transcript != "" {
+		executor.Transcript = NewTranscript()
+	}
+	for _, url := range opts.EventWebhooks {
+		capacity := opts.EventBuffer
+		if capacity <= 0 {
+			capacity = DefaultEventBuffer
+		}
+		overflow := opts.EventOverflow
+		if overflow == "" {
+			overflow = EventOverflowBlock
+		}
+		executor.Sinks = append(executor.Sinks, NewEventPipeline(url, capacity, overflow, newWebhookSink(url).Deliver))
+	}
+	// The run record keeps the full result whatever becomes of stdout
+	var record *RunRecord
+	if !dryRun {
+		if r, err := openRunRecord(executor.runID); err != nil {
+			if verbose {
+				verboseLog("Cannot record the run: %v", err)
+			}
+		} else {
+			record = r
+			executor.Sinks = append(executor.Sinks, NewEventPipeline(record.Path, DefaultEventBuffer, EventOverflowBlock, record.Deliver))
+		}
+	}
+	stdout := &StdoutGuard{W: os.Stdout}
+	stdout.OnBroken = func(err error) {
+		message := "stdout was closed; output stopped, the run continues"
+		if opts.OnBrokenPipe == BrokenPipeAbort {
+			message = "stdout was closed; the run stops after the current step"
+			executor.Stop(SkipReasonBrokenPipe)
+		}
+		if record != nil {
+			message += " (full results in " + record.Path + ")"
+		}
+		runWarnings.add(Warning{Code: WarningStdoutClosed, Message: message})
+		fmt.Fprintf(os.Stderr, "Warning: %s: %v\n", message, err)
+	}
+	executor.Stdout = stdout
+	if opts.AuditLog && !dryRun {
+		auditor, err := newAuditLogger()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: cannot open the audit log: %v\n", err)
+			os.Exit(1)
+		}
+		defer auditor.Close()
+		executor.Audit = auditor
+	}
 
 	// Display execution context (only in non-JSON mode)
 	ctx := executor.GetContext()
@@ -625,29 +1560,98 @@ func executeConfigWithOptions(config *Config, dryRun bool, verbose bool, jsonOut
 		fmt.Printf("   User:      %s\n", ctx.User)
 		fmt.Printf("   Work Dir:  %s\n", ctx.WorkDir)
 		fmt.Printf("   OS/Arch:   %s/%s\n", ctx.OS, ctx.Arch)
-		fmt.Printf("   Transport: %s\n", ctx.Transport)
+		if ctx.ShellDialect != "" && ctx.ShellDialect != ctx.Shell {
+			fmt.Printf("   Transport: %s (%s, %s)\n", ctx.Transport, ctx.Shell, ctx.ShellDialect)
+		} else {
+			fmt.Printf("   Transport: %s (%s)\n", ctx.Transport, ctx.Shell)
+		}
+		if len(ctx.PackageManagers) > 0 {
+			fmt.Printf("   Packages:  %s\n", strings.Join(ctx.PackageManagers, ", "))
+		}
+		if ctx.Container != "" {
+			fmt.Printf("   Container: %s\n", ctx.Container)
+		}
+		if ctx.SELinux != "" {
+			fmt.Printf("   SELinux:   %s\n", ctx.SELinux)
+		}
+		if ctx.Firewall != "" {
+			fmt.Printf("   Firewall:  %s\n", ctx.Firewall)
+		}
+		if config.InstallScope != "" {
+			scope := installScopeFacts(config.InstallScope, ctx)
+			fmt.Printf("   Install:   %s (%s)\n", scope["install_scope"], scope["bin_dir"])
+		}
 		fmt.Println()
 	}
 
+	// A system install on a host without root or sudo fails before any step
+	if _, err := resolveInstallScope(config.InstallScope, ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Bash-isms that validate only reports fail for certain on dash and
+	// BusyBox ash
+	if minimalShell(ctx.ShellDialect) {
+		for _, finding := range posixFindings(nil, selectedPlatform.InstallSteps) {
+			message := fmt.Sprintf("%s, which %s does not support", finding, ctx.ShellDialect)
+			if jsonOutput {
+				runWarnings.add(Warning{Code: WarningNotPOSIX, Message: message})
+			} else {
+				warn(WarningNotPOSIX, "%s", message)
+			}
+		}
+	}
+
+	highRisk := highRiskSteps(selectedPlatform.InstallSteps)
+
 	if dryRun {
 		if !jsonOutput {
 			fmt.Println("🔍 DRY RUN MODE - No commands will be executed")
+			if len(highRisk) > 0 {
+				fmt.Printf("   %d high-risk steps would run\n", len(highRisk))
+			}
 			fmt.Println()
 		}
 	} else {
+		// A --confirm-host that names this host answers the prompt; a
+		// dangerous config cannot run without a prompt unless given one
+		if err := checkConfirmHost(config.Confirm, opts.ConfirmHost, ctx.Host, !jsonOutput); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
 		// Confirmation prompt for real execution (skip in JSON mode)
-		if !jsonOutput {
+		if !jsonOutput && opts.ConfirmHost == "" {
 			fmt.Printf("⚠️  You are about to execute %d steps on %s as %s\n",
 				len(selectedPlatform.InstallSteps),
 				ctx.Host,
 				ctx.User)
-			fmt.Print("   Continue? [yes/no]: ")
-
-			var response string
-			fmt.Scanln(&response)
+			var message string
+			if config.Confirm != nil && config.Confirm.Message != "" {
+				message, err = executor.interpolate(config.Confirm.Message, facts)
+				if err != nil {
+					message = config.Confirm.Message
+				}
+			}
+			if len(highRisk) > 0 {
+				fmt.Printf("   %d high-risk steps will run:\n", len(highRisk))
+				for _, step := range highRisk {
+					if step.Impact != "" {
+						fmt.Printf("     - %s (%s)\n", step.Name, step.Impact)
+					} else {
+						fmt.Printf("     - %s\n", step.Name)
+					}
+				}
+			}
 
-			if response != "yes" {
-				fmt.Println("\n❌ Execution cancelled by user")
+			confirmed, err := confirmRun(operator.In, os.Stdout, config.Confirm, message, ctx.Host)
+			if !confirmed {
+				if err != nil {
+					fmt.Printf("\n❌ Execution cancelled: %v\n", err)
+				} else {
+					fmt.Println("\n❌ Execution cancelled by user")
+				}
 				os.Exit(0)
 			}
 			fmt.Println()
@@ -656,56 +1660,275 @@ func executeConfigWithOptions(config *Config, dryRun bool, verbose bool, jsonOut
 
 	// Set up event handler for progress (only in non-JSON mode)
 	stepNum := 0
+	pendingSteps := map[string]bool{}
+	color := colorEnabled(os.Stdout)
 	if !jsonOutput {
 		executor.OnEvent = func(event ExecutionEvent) {
+			// Remediations run inside their parent step
+			if event.ParentStep != "" {
+				switch event.Status {
+				case "running":
+					fmt.Fprintf(stdout, "      → %s...\n", event.StepName)
+				case "success":
+					fmt.Fprintf(stdout, "        ✓ Done\n")
+				case "failed":
+					fmt.Fprintf(stdout, "        ✗ Failed: %s\n", event.Error)
+				}
+				return
+			}
+
+			// Final status of a check held pending by "recheck"
+			if pendingSteps[event.StepName] && event.Status != "pending" {
+				delete(pendingSteps, event.StepName)
+				if event.Status == "success" {
+					fmt.Fprintf(stdout, "      ↻ %s passed on re-check after %s\n", event.StepName, event.RecheckedAfter)
+				} else {
+					fmt.Fprintf(stdout, "      ✗ %s still failing: %s\n", event.StepName, event.Error)
+					if event.OutputMismatch != nil {
+						fmt.Fprint(stdout, formatOutputDiff(*event.OutputMismatch, "        ", color))
+					}
+				}
+				return
+			}
+
 			switch event.Status {
 			case "running":
 				stepNum++
-				fmt.Printf("[%d/%d] %s...\n", stepNum, len(selectedPlatform.InstallSteps), event.StepName)
+				fmt.Fprintf(stdout, "[%d/%d] %s...\n", stepNum, len(selectedPlatform.InstallSteps), event.StepName)
+				if event.Impact != "" || event.Risk != "" {
+					fmt.Fprintf(stdout, "      %s\n", formatStepAnnotations(event.Impact, event.Risk))
+				}
 			case "success":
-				fmt.Printf("      ✓ Success\n")
+				fmt.Fprintf(stdout, "      ✓ Success\n")
 				if event.Output != "" && !dryRun {
 					// Show first line of output
 					lines := strings.Split(event.Output, "\n")
 					if len(lines) > 0 && lines[0] != "" {
-						fmt.Printf("      Output: %s\n", lines[0])
+						fmt.Fprintf(stdout, "      Output: %s\n", lines[0])
 					}
 				}
 			case "failed":
-				fmt.Printf("      ✗ Failed: %s\n", event.Error)
+				fmt.Fprintf(stdout, "      ✗ Failed: %s\n", event.Error)
+				if event.OutputMismatch != nil {
+					fmt.Fprint(stdout, formatOutputDiff(*event.OutputMismatch, "        ", color))
+				}
+				if event.FailurePolicy != "" {
+					fmt.Fprintf(stdout, "      ↪ Continuing (failure_policy %s)\n", event.FailurePolicy)
+				}
 			case "skipped":
-				fmt.Printf("      ⊘ Skipped\n")
+				fmt.Fprintf(stdout, "      ⊘ Skipped (%s)\n", describeSkipReason(event.SkipReason))
+			case "not_run":
+				fmt.Fprintf(stdout, "[-/%d] %s... not run (%s)\n", len(selectedPlatform.InstallSteps), event.StepName, describeSkipReason(event.SkipReason))
+			case "deferred":
+				fmt.Fprintf(stdout, "      ⏸ Deferred: %s\n", event.Output)
+			case "pending":
+				pendingSteps[event.StepName] = true
+				fmt.Fprintf(stdout, "      ⏳ Pending: %s (re-checked after later remediations)\n", event.Error)
+			case "rollback":
+				if event.Error != "" {
+					fmt.Fprintf(stdout, "      ✗ Undo of %s failed: %s\n", event.StepName, event.Error)
+				} else {
+					fmt.Fprintf(stdout, "      ↩ Rolled back %s\n", event.StepName)
+				}
 			}
 		}
 	}
 
+	// The full-screen UI replaces the plain progress lines
+	var progressUI *ProgressUI
+	if opts.UI && !jsonOutput {
+		progressUI = NewProgressUI("sink execute — "+selectedPlatform.Name, selectedPlatform.InstallSteps, os.Stdout)
+		executor.OnEvent = progressUI.HandleEvent
+	}
+
+	// Record host state so the report can show what the run changed
+	snapshotEnabled := (opts.Snapshot || config.Snapshot != nil) && !dryRun
+	var snapshotConfig SnapshotConfig
+	if config.Snapshot != nil {
+		snapshotConfig = *config.Snapshot
+	}
+	var before Snapshot
+	if snapshotEnabled {
+		before = captureSnapshot(transport, snapshotConfig)
+	}
+
 	// Execute
+	if executor.Transcript != nil {
+		executor.Transcript.Started = time.Now()
+	}
+	if progressUI != nil {
+		progressUI.Start()
+	}
 	results := executor.ExecutePlatform(*selectedPlatform, facts)
+	if progressUI != nil {
+		progressUI.Stop()
+	}
+	for _, w := range executor.CloseSinks() {
+		if jsonOutput {
+			runWarnings.add(w)
+		} else {
+			warn(w.Code, "%s", w.Message)
+		}
+	}
+	if !dryRun {
+		recordRunCoverage(opts.Coverage, config, resultBranches(coverage, selectedPlatform.InstallSteps, results))
+	}
+
+	if snapshotEnabled {
+		diff := diffSnapshots(before, captureSnapshot(transport, snapshotConfig))
+		if jsonOutput {
+			emitSnapshotEventJSON(diff)
+		} else {
+			fmt.Println()
+			printSnapshotDiff(diff)
+		}
+	}
+
+	if opts.Bundle != nil && !dryRun {
+		if jsonOutput {
+			emitBundleEventJSON(opts.Bundle)
+		} else {
+			fmt.Println()
+			printBundleAssets(opts.Bundle)
+		}
+	}
+
+	if executor.Transcript != nil {
+		transcript := executor.Transcript
+		transcript.Config = config.Name
+		if transcript.Config == "" {
+			transcript.Config = opts.ConfigSource
+		}
+		transcript.Platform = selectedPlatform.Name
+		transcript.Context = ctx
+		transcript.RunID = executor.runID
+		transcript.DryRun = dryRun
+		transcript.Finished = time.Now()
+		if err := transcript.WriteFile(opts.Transcript); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		} else if !jsonOutput {
+			fmt.Printf("📝 Transcript written to %s\n", opts.Transcript)
+		}
+	}
+
+	successCount := 0
+	failCount := 0
+	toleratedCount := 0
+	rolledBackCount := 0
+	notRunCount := 0
+	stopReason := "" // Why the run stopped early, from the first step it cut short
+	deferredCount := 0
+	for _, result := range results {
+		if result.RolledBack {
+			rolledBackCount++
+		}
+		if stopReason == "" {
+			stopReason = resultStopReason(result)
+		}
+		switch {
+		case result.Status == "not_run":
+			notRunCount++
+		case result.Status == "deferred":
+			deferredCount++
+		case result.Error == "":
+			successCount++
+		case result.Tolerated:
+			toleratedCount++
+		default:
+			failCount++
+		}
+	}
+
+	warnings := runWarnings.List()
+	if jsonOutput {
+		emitWarningsEventJSON(warnings)
+	}
+	if record != nil {
+		if err := record.Close(warnings); err != nil && verbose {
+			verboseLog("Cannot record the run: %v", err)
+		}
+	}
+	if stdout.Broken() && opts.OnBrokenPipe == BrokenPipeAbort {
+		os.Exit(ExitBrokenPipe)
+	}
+
+	// The results decide: a run that finished its last step just after the
+	// deadline or an interrupt still succeeded
+	deadlineExceeded := stopReason == SkipReasonDeadline
+	interrupted := stopReason == SkipReasonInterrupt
+	if jsonOutput && deadlineExceeded {
+		os.Exit(ExitDeadlineExceeded)
+	}
+	if jsonOutput && interrupted {
+		os.Exit(ExitInterrupted)
+	}
+	if jsonOutput && notRunCount > 0 {
+		os.Exit(1) // Aborted at a breakpoint, or stopped for another reason
+	}
+	if jsonOutput && failCount == 0 && deferredCount > 0 {
+		os.Exit(ExitDeferred)
+	}
 
 	// Summary (only in non-JSON mode)
 	if !jsonOutput {
 		fmt.Println()
-		successCount := 0
-		failCount := 0
-		for _, result := range results {
-			if result.Error == "" {
-				successCount++
+		if opts.Summary != SummaryNone {
+			writeSummaryTable(os.Stdout, summaryRows(selectedPlatform.InstallSteps, results, dryRun, opts.Summary), opts.Summary)
+			fmt.Println()
+		}
+		if len(warnings) > 0 {
+			printWarnings(os.Stdout, warnings)
+			fmt.Println()
+		}
+
+		if deadlineExceeded {
+			fmt.Printf("⏱️  Max duration %s exceeded: %d succeeded, %d failed, %d not run\n", opts.MaxDuration, successCount, failCount, notRunCount)
+			os.Exit(ExitDeadlineExceeded)
+		}
+		if interrupted {
+			fmt.Printf("🛑 Execution interrupted: %d succeeded, %d failed, %d not run\n", successCount, failCount, notRunCount)
+			os.Exit(ExitInterrupted)
+		}
+
+		if notRunCount > 0 {
+			if stopReason == SkipReasonBreakpoint {
+				fmt.Printf("🛑 Execution aborted at breakpoint: %d succeeded, %d not run\n", successCount, notRunCount)
 			} else {
-				failCount++
+				fmt.Printf("🛑 Execution stopped (%s): %d succeeded, %d failed, %d not run\n", describeSkipReason(stopReason), successCount, failCount, notRunCount)
 			}
+			os.Exit(1)
 		}
 
 		if failCount > 0 {
-			fmt.Printf("❌ Execution failed: %d succeeded, %d failed\n", successCount, failCount)
+			continued := ""
+			if toleratedCount > 0 {
+				continued = fmt.Sprintf(" (and %d with failure_policy continue)", toleratedCount)
+			}
+			if rolledBackCount > 0 {
+				continued += fmt.Sprintf(", %d rolled back", rolledBackCount)
+			}
+			fmt.Printf("❌ Execution failed: %d succeeded, %d failed%s\n", successCount, failCount, continued)
 			os.Exit(1)
+		} else if deferredCount > 0 {
+			fmt.Printf("⏸  Execution deferred: %d succeeded, %d deferred (outside maintenance window)\n", successCount, deferredCount)
+			os.Exit(ExitDeferred)
 		} else {
 			if dryRun {
 				fmt.Printf("✅ Dry run complete: %d steps validated\n", successCount)
+			} else if toleratedCount > 0 {
+				fmt.Printf("✅ Execution complete: %d steps succeeded, %d failed and continued (failure_policy continue)\n", successCount, toleratedCount)
 			} else {
 				fmt.Printf("✅ Execution complete: %d steps succeeded\n", successCount)
 			}
 		}
 	}
+
+	if opts.WarningsAsErrors && len(warnings) > 0 {
+		if !jsonOutput {
+			fmt.Printf("❌ Failing on %d warning(s) (--warnings-as-errors)\n", len(warnings))
+		}
+		os.Exit(1)
+	}
 }
 
 func factsCommand() {
@@ -717,13 +1940,49 @@ func factsCommand() {
 		}
 	}
 
-	if len(os.Args) < 3 {
+	var configFile, output string
+	var tui, diffLast bool
+	args := os.Args[2:]
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--tui":
+			tui = true
+		case arg == "--diff-last":
+			diffLast = true
+		case arg == "--output" || strings.HasPrefix(arg, "--output="):
+			value, ok := strings.CutPrefix(arg, "--output=")
+			if !ok {
+				if i+1 >= len(args) {
+					fmt.Fprintf(os.Stderr, "Error: --output requires a format (json, dotenv, shell or gha)\n")
+					os.Exit(1)
+				}
+				value = args[i+1]
+				i++
+			}
+			format, err := parseFactOutput(value)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			output = format
+		case strings.HasPrefix(arg, "-") || configFile != "":
+			fmt.Fprintf(os.Stderr, "Error: unexpected argument: %s\n", arg)
+			os.Exit(1)
+		default:
+			configFile = arg
+		}
+	}
+
+	if configFile == "" {
 		fmt.Fprintf(os.Stderr, "Error: config file required\n\n")
 		printFactsHelp()
 		os.Exit(1)
 	}
-
-	configFile := os.Args[2]
+	if output != "" && (tui || diffLast) {
+		fmt.Fprintf(os.Stderr, "Error: --output cannot be combined with --tui or --diff-last\n")
+		os.Exit(1)
+	}
 
 	// Load config
 	config, err := LoadConfig(configFile)
@@ -732,7 +1991,7 @@ func factsCommand() {
 		os.Exit(1)
 	}
 
-	if len(config.Facts) == 0 {
+	if len(config.Facts) == 0 && output == "" {
 		fmt.Println("No facts defined in config")
 		return
 	}
@@ -740,9 +1999,19 @@ func factsCommand() {
 	// Create transport
 	transport := NewLocalTransport()
 
-	// Gather facts
-	fmt.Println("📊 Gathering facts...")
-	fmt.Println()
+	if tui {
+		if err := runFactExplorer(configFile, NewFactGatherer(config.Facts, transport)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Gather facts; with --output, stdout holds only the formatted facts
+	if output == "" {
+		fmt.Println("📊 Gathering facts...")
+		fmt.Println()
+	}
 	gatherer := NewFactGatherer(config.Facts, transport)
 	facts, err := gatherer.Gather()
 	if err != nil {
@@ -750,15 +2019,41 @@ func factsCommand() {
 		os.Exit(1)
 	}
 
+	if output != "" {
+		if err := writeFacts(os.Stdout, output, config.Facts, facts); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if diffLast {
+		record, err := loadFactRecord(configFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if record == nil {
+			fmt.Printf("No run of %s has recorded facts on this host yet (sink execute records them)\n", configFile)
+			return
+		}
+		changes, unchanged := diffFacts(record.Facts, config.Facts, facts)
+		printFactDiff(os.Stdout, record, changes, unchanged)
+		return
+	}
+
 	// Display facts
 	fmt.Printf("Gathered %d facts:\n\n", len(facts))
 	for name, value := range facts {
 		def := config.Facts[name]
 		fmt.Printf("  %s\n", name)
-		fmt.Printf("    Value: %v\n", value)
+		fmt.Printf("    Value: %s\n", displayFactValue(def, value))
 		fmt.Printf("    Type: %T\n", value)
+		if def.Source != "" {
+			fmt.Printf("    Source: %s\n", def.Source)
+		}
 		if def.Export != "" {
-			fmt.Printf("    Export: %s=%v\n", def.Export, value)
+			fmt.Printf("    Export: %s=%s\n", def.Export, displayFactValue(def, value))
 		}
 		if def.Description != "" {
 			fmt.Printf("    Description: %s\n", def.Description)
@@ -766,7 +2061,7 @@ func factsCommand() {
 		fmt.Println()
 	}
 
-	// Show export statements
+	// Show export statements (secret facts are never exported)
 	exports := gatherer.Export(facts)
 	if len(exports) > 0 {
 		fmt.Println("Environment variables:")
@@ -798,16 +2093,33 @@ func validateCommand() {
 		}
 	}
 
-	if len(os.Args) < 3 {
+	configFile := ""
+	strict := true
+	posix := false
+	for _, arg := range os.Args[2:] {
+		switch {
+		case arg == "--no-strict":
+			strict = false
+		case arg == "--posix":
+			posix = true
+		case arg == "--strict":
+			strict = true
+		case configFile == "" && (arg == "-" || !strings.HasPrefix(arg, "-")):
+			configFile = arg
+		default:
+			fmt.Fprintf(os.Stderr, "Error: unexpected argument: %s\n", arg)
+			os.Exit(1)
+		}
+	}
+
+	if configFile == "" {
 		fmt.Fprintf(os.Stderr, "Error: config file required\n\n")
 		printValidateHelp()
 		os.Exit(1)
 	}
 
-	configFile := os.Args[2]
-
 	// Load and validate config
-	config, err := LoadConfig(configFile)
+	config, err := LoadConfigWithOptions(configFile, LoadOptions{Strict: strict})
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "❌ Validation failed: %v\n", err)
 		os.Exit(1)
@@ -837,4 +2149,19 @@ func validateCommand() {
 		defaultsJSON, _ := json.MarshalIndent(config.Defaults, "    ", "  ")
 		fmt.Printf("    %s\n", string(defaultsJSON))
 	}
+
+	if warning := schemaVersionWarning(config.Schema); warning != "" {
+		fmt.Printf("\n  ⚠️  %s\n", warning)
+	}
+
+	if findings := configPOSIXFindings(config); len(findings) > 0 {
+		fmt.Printf("\n  Not POSIX (fails under dash and BusyBox ash):\n")
+		for _, finding := range findings {
+			fmt.Printf("    ⚠️  %s\n", finding)
+		}
+		if posix {
+			fmt.Fprintf(os.Stderr, "❌ %d commands are not POSIX (--posix)\n", len(findings))
+			os.Exit(1)
+		}
+	}
 }
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ChecksumManifest maps file paths to expected SHA256 hex digests.
+// It is parsed from SHA256SUMS-style text as produced by `sha256sum`.
+type ChecksumManifest map[string]string
+
+// parseChecksumManifest parses "<hex>  <file>" lines (or "<hex> *<file>" for
+// binary mode). Blank lines and lines starting with # are ignored.
+func parseChecksumManifest(text string) (ChecksumManifest, error) {
+	manifest := ChecksumManifest{}
+
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("line %d: expected '<sha256>  <file>'", i+1)
+		}
+		if len(fields[0]) != 64 {
+			return nil, fmt.Errorf("line %d: '%s' is not a SHA256 digest", i+1, fields[0])
+		}
+
+		file := strings.Join(fields[1:], " ")
+		file = strings.TrimPrefix(strings.TrimPrefix(file, "*"), "./")
+		manifest[file] = strings.ToLower(fields[0])
+	}
+
+	return manifest, nil
+}
+
+// Lookup returns the checksum for name, matching the full relative path first
+// and falling back to a unique basename match.
+func (m ChecksumManifest) Lookup(name string) (string, bool) {
+	name = strings.TrimPrefix(name, "./")
+	if sum, ok := m[name]; ok {
+		return sum, true
+	}
+
+	base := path.Base(name)
+	found := ""
+	for file, sum := range m {
+		if path.Base(file) != base {
+			continue
+		}
+		if found != "" {
+			// Ambiguous basename
+			return "", false
+		}
+		found = sum
+	}
+
+	return found, found != ""
+}
+
+// fetchChecksumManifest downloads and parses a SHA256SUMS-style manifest
+func fetchChecksumManifest(url string) (ChecksumManifest, error) {
+	text, err := fetchText(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch checksum manifest: %w", err)
+	}
+
+	manifest, err := parseChecksumManifest(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid checksum manifest %s: %w", url, err)
+	}
+
+	return manifest, nil
+}
+
+// loadChecksumManifest fetches the --checksums-url manifest. Its entries
+// verify the config and the files it references, so the manifest itself
+// must be trustworthy: signed (<url>.sig, checked with publicKey), or
+// fetched over HTTPS from a release or a forge URL pinned to a tag or
+// commit. A manifest never counts as pinning the config.
+func loadChecksumManifest(url string, publicKey ed25519.PublicKey) (ChecksumManifest, error) {
+	if publicKey == nil {
+		info, _ := ParseRepoURL(url)
+		if !strings.HasPrefix(url, "https://") || info == nil || checkPinnedSource(url, info, "") != nil {
+			return nil, fmt.Errorf("checksum manifest %s is neither pinned nor signed (use an HTTPS release, tag or commit URL, or sign it and pass --pubkey)", url)
+		}
+	}
+
+	text, err := fetchText(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch checksum manifest: %w", err)
+	}
+	if publicKey != nil {
+		sigURL := signatureURL(url)
+		signature, err := fetchText(sigURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch signature %s: %w", sigURL, err)
+		}
+		if err := verifySignature([]byte(text), signature, publicKey); err != nil {
+			return nil, fmt.Errorf("checksum manifest %s: %w", url, err)
+		}
+	}
+
+	manifest, err := parseChecksumManifest(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid checksum manifest %s: %w", url, err)
+	}
+	return manifest, nil
+}
+
+// referencedSHA256 returns the manifest checksum of a local file a step
+// reads, such as a copy source or a stdin_file, or "" when no manifest is
+// loaded. Relative paths (inside the bundle when running one) are files
+// published with the config, which the manifest must list; absolute paths
+// are files of this machine and are not looked up.
+func (e *Executor) referencedSHA256(file string) (string, error) {
+	if e.Checksums == nil {
+		return "", nil
+	}
+	name := file
+	if e.Bundle != nil {
+		if rel, err := filepath.Rel(e.Bundle.Dir, file); err == nil && filepath.IsLocal(rel) {
+			name = rel
+		}
+	}
+	if filepath.IsAbs(name) {
+		return "", nil
+	}
+	sum, ok := e.Checksums.Lookup(filepath.ToSlash(name))
+	if !ok {
+		return "", fmt.Errorf("checksum manifest has no entry for %s", name)
+	}
+	return sum, nil
+}
+
+// configManifestName returns the manifest entry name for a config or
+// library URL
+func configManifestName(url string) string {
+	if i := strings.IndexAny(url, "?#"); i >= 0 {
+		url = url[:i]
+	}
+	return path.Base(url)
+}
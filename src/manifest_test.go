@@ -0,0 +1,247 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// TestParseChecksumManifest tests SHA256SUMS parsing and lookup
+func TestParseChecksumManifest(t *testing.T) {
+	a := strings.Repeat("a", 64)
+	b := strings.Repeat("b", 64)
+	c := strings.Repeat("C", 64)
+	text := "# release checksums\n" + a + "  setup.json\n" + b + " *scripts/install.sh\n\n" + c + "  ./archives/tool.tar.gz\n"
+
+	manifest, err := parseChecksumManifest(text)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(manifest) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(manifest))
+	}
+
+	tests := []struct {
+		name string
+		want string
+		ok   bool
+	}{
+		{"setup.json", a, true},
+		{"scripts/install.sh", b, true},
+		{"install.sh", b, true},
+		{"./archives/tool.tar.gz", strings.ToLower(c), true},
+		{"missing.sh", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := manifest.Lookup(tt.name)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("Lookup(%q) = %q, %v; want %q, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+// TestParseChecksumManifest_Invalid tests rejection of malformed manifests
+func TestParseChecksumManifest_Invalid(t *testing.T) {
+	for _, text := range []string{"justonefield\n", "abc123  setup.json\n"} {
+		if _, err := parseChecksumManifest(text); err == nil {
+			t.Errorf("expected error for %q", text)
+		}
+	}
+}
+
+// TestChecksumManifest_AmbiguousBasename tests that duplicate basenames need a full path
+func TestChecksumManifest_AmbiguousBasename(t *testing.T) {
+	manifest := ChecksumManifest{
+		"linux/install.sh":  strings.Repeat("a", 64),
+		"darwin/install.sh": strings.Repeat("b", 64),
+	}
+	if _, ok := manifest.Lookup("install.sh"); ok {
+		t.Error("expected ambiguous basename lookup to fail")
+	}
+	if _, ok := manifest.Lookup("darwin/install.sh"); !ok {
+		t.Error("expected full path lookup to succeed")
+	}
+}
+
+// TestLoadConfigFromURL_ChecksumManifest tests verifying a config against a manifest
+func TestLoadConfigFromURL_ChecksumManifest(t *testing.T) {
+	configJSON := `{"version": "1.0", "platforms": [{"name": "Test", "os": "darwin", "match": "Darwin", "install_steps": [{"name": "t", "command": "true"}]}]}`
+	hash := sha256.Sum256([]byte(configJSON))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(configJSON))
+	}))
+	defer server.Close()
+
+	// A manifest entry verifies the config but does not stand in for
+	// --sha256 over plain HTTP, nor pin it
+	good := ChecksumManifest{"setup.json": hex.EncodeToString(hash[:])}
+	if _, err := loadConfigFromURLWithOptions(server.URL+"/v1/setup.json", URLLoadOptions{Checksums: good}); err == nil || !strings.Contains(err.Error(), "HTTP URLs require --sha256") {
+		t.Errorf("expected the HTTP checksum requirement, got: %v", err)
+	}
+	if _, err := loadConfigFromURLWithOptions(server.URL+"/v1/setup.json", URLLoadOptions{Checksums: good, SkipChecksum: true, RequirePinned: true}); err == nil || !strings.Contains(err.Error(), "--require-pinned") {
+		t.Errorf("expected --require-pinned to ignore the manifest, got: %v", err)
+	}
+	if _, err := loadConfigFromURLWithOptions(server.URL+"/v1/setup.json", URLLoadOptions{Checksums: good, SkipChecksum: true}); err != nil {
+		t.Errorf("expected success, got: %v", err)
+	}
+
+	bad := ChecksumManifest{"setup.json": strings.Repeat("0", 64)}
+	if _, err := loadConfigFromURLWithOptions(server.URL+"/v1/setup.json", URLLoadOptions{Checksums: bad, SkipChecksum: true}); err == nil {
+		t.Error("expected checksum mismatch error")
+	}
+
+	missing := ChecksumManifest{"other.json": hex.EncodeToString(hash[:])}
+	_, err := loadConfigFromURLWithOptions(server.URL+"/v1/setup.json", URLLoadOptions{Checksums: missing, SkipChecksum: true})
+	if err == nil || !strings.Contains(err.Error(), "no entry for setup.json") {
+		t.Errorf("expected missing entry error, got: %v", err)
+	}
+}
+
+// TestLoadChecksumManifest tests that only a pinned HTTPS or signed
+// manifest is accepted
+func TestLoadChecksumManifest(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest := strings.Repeat("a", 64) + "  setup.json\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/SHA256SUMS":
+			w.Write([]byte(manifest))
+		case "/SHA256SUMS.sig":
+			w.Write([]byte(signData([]byte(manifest), priv)))
+		case "/tampered/SHA256SUMS":
+			w.Write([]byte(manifest + strings.Repeat("b", 64) + "  evil.sh\n"))
+		case "/tampered/SHA256SUMS.sig":
+			w.Write([]byte(signData([]byte(manifest), priv)))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	for _, url := range []string{
+		server.URL + "/SHA256SUMS",
+		"https://configs.example.com/SHA256SUMS",
+		"https://raw.githubusercontent.com/org/configs/main/SHA256SUMS",
+	} {
+		if _, err := loadChecksumManifest(url, nil); err == nil || !strings.Contains(err.Error(), "neither pinned nor signed") {
+			t.Errorf("%s: expected an unpinned manifest error, got %v", url, err)
+		}
+	}
+
+	checksums, err := loadChecksumManifest(server.URL+"/SHA256SUMS", pub)
+	if err != nil {
+		t.Fatalf("expected a signed manifest to load, got %v", err)
+	}
+	if _, ok := checksums.Lookup("setup.json"); !ok {
+		t.Errorf("expected an entry for setup.json, got %v", checksums)
+	}
+	if _, err := loadChecksumManifest(server.URL+"/tampered/SHA256SUMS", pub); err == nil || !strings.Contains(err.Error(), "signature verification failed") {
+		t.Errorf("expected a signature failure, got %v", err)
+	}
+}
+
+// TestReferencedFiles tests verifying copy sources, stdin_file and
+// dependencies against the checksum manifest
+func TestReferencedFiles(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+	t.Setenv(EnvCacheDir, t.TempDir())
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "app.conf"), []byte("listen 80\n"), 0644)
+	sum, err := fileSHA256(filepath.Join(dir, "app.conf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Relative paths are resolved inside the bundle
+	executor := NewExecutor(NewLocalTransport())
+	executor.Bundle = &Bundle{Dir: dir}
+	copyStep := func(source string) StepResult {
+		return executor.ExecuteStep(InstallStep{Name: "conf", Step: CopyStep{Copy: FileTransfer{
+			Source: source, Destination: filepath.Join(dir, "out", "app.conf"),
+		}}}, Facts{})
+	}
+
+	executor.Checksums = ChecksumManifest{"app.conf": sum}
+	if result := copyStep("app.conf"); result.Status != "success" {
+		t.Errorf("expected a listed file to copy, got %+v", result)
+	}
+	executor.Checksums = ChecksumManifest{"app.conf": strings.Repeat("0", 64)}
+	if result := copyStep("app.conf"); !strings.Contains(result.Error, "SHA256 mismatch") {
+		t.Errorf("expected a checksum mismatch, got %+v", result)
+	}
+	executor.Checksums = ChecksumManifest{"other.conf": sum}
+	if result := copyStep("app.conf"); !strings.Contains(result.Error, "no entry for app.conf") {
+		t.Errorf("expected a missing entry error, got %+v", result)
+	}
+	local := filepath.Join(t.TempDir(), "local.conf")
+	os.WriteFile(local, []byte("listen 8080\n"), 0644)
+	if result := copyStep(local); result.Status != "success" {
+		t.Errorf("absolute paths are not looked up, got %+v", result)
+	}
+
+	input := InstallStep{Name: "input", Step: CommandStep{Command: "cat", StdinFile: "app.conf"}}
+	if result := executor.ExecuteStep(input, Facts{}); !strings.Contains(result.Error, "stdin_file: checksum manifest has no entry") {
+		t.Errorf("expected stdin_file to be looked up, got %+v", result)
+	}
+	executor.Checksums = ChecksumManifest{"app.conf": sum}
+	if result := executor.ExecuteStep(input, Facts{}); result.Status != "success" || strings.TrimSpace(result.Output) != "listen 80" {
+		t.Errorf("expected stdin_file to be read, got %+v", result)
+	}
+
+	library := []byte(`{"steps": {"hello": {"command": "echo hello"}}}`)
+	libHash := sha256.Sum256(library)
+	deps := map[string]Dependency{"lib": {Source: "https://libs.example.com/lib.json"}}
+	if _, err := resolveLibraries(deps, LoadOptions{}); err == nil || !strings.Contains(err.Error(), "must pin") {
+		t.Errorf("expected an unpinned dependency error, got %v", err)
+	}
+	cacheLibrary(hex.EncodeToString(libHash[:]), library)
+	libs, err := resolveLibraries(deps, LoadOptions{Checksums: ChecksumManifest{"lib.json": hex.EncodeToString(libHash[:])}})
+	if err != nil || libs["lib"] == nil {
+		t.Errorf("expected the manifest to pin the dependency, got %v", err)
+	}
+}
+
+// TestExecutorChecksumTemplate tests the checksum template function
+func TestExecutorChecksumTemplate(t *testing.T) {
+	sum := strings.Repeat("d", 64)
+	mockTransport := &MockTransport{
+		responses: map[string]MockResponse{
+			"echo " + sum: {stdout: sum + "\n", exitCode: 0},
+		},
+	}
+
+	executor := NewExecutor(mockTransport)
+	executor.Checksums = ChecksumManifest{"scripts/install.sh": sum}
+
+	step := InstallStep{Name: "verify", Step: CommandStep{Command: `echo {{checksum "install.sh"}}`}}
+	result := executor.ExecuteStep(step, Facts{})
+	if result.Error != "" {
+		t.Fatalf("unexpected error: %s", result.Error)
+	}
+
+	step = InstallStep{Name: "missing", Step: CommandStep{Command: `echo {{checksum "nope.sh"}}`}}
+	result = executor.ExecuteStep(step, Facts{})
+	if !strings.Contains(result.Error, "no entry for nope.sh") {
+		t.Errorf("expected missing entry error, got: %s", result.Error)
+	}
+
+	executor.Checksums = nil
+	result = executor.ExecuteStep(step, Facts{})
+	if !strings.Contains(result.Error, "--checksums-url") {
+		t.Errorf("expected no manifest error, got: %s", result.Error)
+	}
+}
@@ -0,0 +1,482 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// MatrixTarget is a platform, and optionally a distribution, to plan a
+// config for: "darwin" or "linux/ubuntu"
+type MatrixTarget struct {
+	OS     string
+	Distro string // Distribution ID; empty for platforms without distributions
+}
+
+// String formats the target as it is written in --matrix
+func (t MatrixTarget) String() string {
+	if t.Distro == "" {
+		return t.OS
+	}
+	return t.OS + "/" + t.Distro
+}
+
+// parseMatrix parses a --matrix list such as "darwin,linux/ubuntu"
+func parseMatrix(value string) ([]MatrixTarget, error) {
+	var targets []MatrixTarget
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		osName, distro, _ := strings.Cut(entry, "/")
+		if osName == "" || strings.Contains(distro, "/") {
+			return nil, fmt.Errorf("--matrix: invalid target '%s' (use <os> or <os>/<distribution>)", entry)
+		}
+		targets = append(targets, MatrixTarget{OS: osName, Distro: distro})
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("--matrix: no targets given")
+	}
+	return targets, nil
+}
+
+// configTargets returns every platform and distribution a config defines
+func configTargets(config *Config) []MatrixTarget {
+	var targets []MatrixTarget
+	for _, platform := range config.Platforms {
+		if len(platform.Distributions) == 0 {
+			targets = append(targets, MatrixTarget{OS: platform.OS})
+		}
+		for _, dist := range platform.Distributions {
+			targets = append(targets, MatrixTarget{OS: platform.OS, Distro: dist.IDs[0]})
+		}
+	}
+	return targets
+}
+
+// expandTargets replaces an OS-only target for a platform with distributions
+// by one target per distribution
+func expandTargets(config *Config, targets []MatrixTarget) []MatrixTarget {
+	var expanded []MatrixTarget
+	for _, target := range targets {
+		platform := findPlatform(config, target.OS)
+		if target.Distro != "" || platform == nil || len(platform.Distributions) == 0 {
+			expanded = append(expanded, target)
+			continue
+		}
+		for _, dist := range platform.Distributions {
+			expanded = append(expanded, MatrixTarget{OS: target.OS, Distro: dist.IDs[0]})
+		}
+	}
+	return expanded
+}
+
+// findPlatform returns the config's platform for an OS
+func findPlatform(config *Config, osName string) *Platform {
+	for i := range config.Platforms {
+		if config.Platforms[i].OS == osName {
+			return &config.Platforms[i]
+		}
+	}
+	return nil
+}
+
+// mockFacts returns stand-in values for the facts gathered on osName:
+// false, 0 or "<name>" by type, unless overridden with --fact. Facts limited
+// to other platforms are left out, as they would be on a real host.
+func mockFacts(defs map[string]FactDef, osName string, overrides map[string]string) (Facts, error) {
+	facts := Facts{}
+	for name, def := range defs {
+		if len(def.Platforms) > 0 && !containsString(def.Platforms, osName) {
+			continue
+		}
+		value, ok := overrides[name]
+		if !ok {
+			switch def.Type {
+			case "boolean":
+				facts[name] = false
+			case "integer":
+				facts[name] = int64(0)
+			default:
+				facts[name] = "<" + name + ">"
+			}
+			continue
+		}
+		typed, err := coerceType(value, def.Type)
+		if err != nil {
+			return nil, fmt.Errorf("--fact %s: %w", name, err)
+		}
+		facts[name] = typed
+	}
+	for name := range overrides {
+		if _, ok := defs[name]; !ok {
+			return nil, fmt.Errorf("--fact %s: the config defines no such fact", name)
+		}
+	}
+	return facts, nil
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// planTransport stands in for a host that sink test has no access to
+type planTransport struct{}
+
+// Run refuses every command, so context discovery finds nothing
+func (planTransport) Run(cmd string) (string, string, int, error) {
+	return "", "", 127, fmt.Errorf("sink test does not run commands")
+}
+
+// MatrixStep is the plan for one step on a target
+type MatrixStep struct {
+	Name     string
+	Rendered []stepTemplate // Fields with facts applied
+	Errors   []string       // Fields whose templates fail
+}
+
+// MatrixResult is the plan for one target
+type MatrixResult struct {
+	Target   MatrixTarget
+	Platform string // Platform (and distribution) name
+	Steps    []MatrixStep
+	Error    string // Why the target has no plan
+	Fallback string // Error the config declares for the unsupported target instead
+}
+
+// Failed reports whether the target has no plan or a step that cannot run.
+// A target the config declares unsupported with a fallback has not failed.
+func (r MatrixResult) Failed() bool {
+	if r.Error != "" && r.Fallback == "" {
+		return true
+	}
+	for _, step := range r.Steps {
+		if len(step.Errors) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// planTarget renders every templated field of the steps the target would
+// run, without running anything
+func planTarget(config *Config, target MatrixTarget, facts Facts) MatrixResult {
+	result := MatrixResult{Target: target}
+	platform := findPlatform(config, target.OS)
+	if platform == nil {
+		result.Error = fmt.Sprintf("no platform for %s", target.OS)
+		if config.Fallback != nil {
+			result.Fallback = config.Fallback.Error
+		}
+		return result
+	}
+
+	result.Platform = platform.Name
+	steps := platform.InstallSteps
+	if len(platform.Distributions) > 0 {
+		var dist *Distribution
+		for i := range platform.Distributions {
+			if containsString(platform.Distributions[i].IDs, target.Distro) {
+				dist = &platform.Distributions[i]
+			}
+		}
+		if dist == nil {
+			result.Error = fmt.Sprintf("no distribution of %s for '%s'", platform.Name, target.Distro)
+			if platform.Fallback != nil {
+				result.Fallback = platform.Fallback.Error
+			}
+			return result
+		}
+		result.Platform += ", " + dist.Name
+		steps = dist.InstallSteps
+	}
+
+	executor := NewExecutor(planTransport{})
+	executor.DryRun = true
+	executor.context.OS = unameOS(target.OS) // {{._os}} is the target's
+	for _, step := range steps {
+		planned := MatrixStep{Name: step.Name}
+		stepFacts := executor.stepFacts(step, facts)
+		for _, field := range stepTemplates(step) {
+			text, err := executor.interpolate(field.Text, stepFacts)
+			if err != nil {
+				planned.Errors = append(planned.Errors, fmt.Sprintf("%s: %v", field.Field, err))
+				continue
+			}
+			planned.Rendered = append(planned.Rendered, stepTemplate{Field: field.Field, Text: text})
+		}
+		result.Steps = append(result.Steps, planned)
+	}
+	return result
+}
+
+// printMatrixResult reports a target's plan; verbose shows rendered commands
+func printMatrixResult(w io.Writer, result MatrixResult, verbose bool) {
+	if result.Fallback != "" {
+		fmt.Fprintf(w, "⚠ %s: %s; the config reports: %s\n", result.Target, result.Error, result.Fallback)
+		return
+	}
+	if result.Error != "" {
+		fmt.Fprintf(w, "✗ %s: %s\n", result.Target, result.Error)
+		return
+	}
+	mark := "✓"
+	if result.Failed() {
+		mark = "✗"
+	}
+	steps := fmt.Sprintf("%d steps", len(result.Steps))
+	if len(result.Steps) == 1 {
+		steps = "1 step"
+	}
+	fmt.Fprintf(w, "%s %s (%s): %s\n", mark, result.Target, result.Platform, steps)
+	for _, step := range result.Steps {
+		if len(step.Errors) == 0 && !verbose {
+			continue
+		}
+		mark := "✓"
+		if len(step.Errors) > 0 {
+			mark = "✗"
+		}
+		fmt.Fprintf(w, "   %s %s\n", mark, step.Name)
+		for _, err := range step.Errors {
+			fmt.Fprintf(w, "      %s\n", err)
+		}
+		if verbose {
+			for _, field := range step.Rendered {
+				fmt.Fprintf(w, "      %s: %s\n", field.Field, firstLine(field.Text))
+			}
+		}
+	}
+}
+
+// testCommand runs "sink test": plan a config for several platforms with
+// mocked facts and report steps whose templates would fail, or with
+// --in-docker run it for real in a container per distribution
+func testCommand() {
+	var configFile, matrix, binary, coveragePath string
+	var verbose, inDocker bool
+	variables := map[string]string{}
+	factValues := map[string]string{}
+	images := map[string]string{}
+
+	args := os.Args[2:]
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch arg {
+		case "-h", "--help":
+			printTestHelp()
+			os.Exit(0)
+		case "-v", "--verbose":
+			verbose = true
+		case "--in-docker":
+			inDocker = true
+		case "--matrix", "--binary", "--coverage", "--var", "--fact", "--image":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: %s requires a value\n", arg)
+				os.Exit(1)
+			}
+			i++
+			switch arg {
+			case "--matrix":
+				matrix = args[i]
+				continue
+			case "--binary":
+				binary = args[i]
+				continue
+			case "--coverage":
+				coveragePath = args[i]
+				continue
+			}
+			name, value, err := parseVarFlag(args[i])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %s\n", strings.Replace(err.Error(), "--var", arg, 1))
+				os.Exit(1)
+			}
+			switch arg {
+			case "--var":
+				variables[name] = value
+			case "--fact":
+				factValues[name] = value
+			default:
+				images[name] = value
+			}
+		default:
+			if strings.HasPrefix(arg, "-") || configFile != "" {
+				fmt.Fprintf(os.Stderr, "Error: unexpected argument: %s\n", arg)
+				os.Exit(1)
+			}
+			configFile = arg
+		}
+	}
+	if configFile == "" {
+		fmt.Fprintf(os.Stderr, "Error: config file required\n\n")
+		printTestHelp()
+		os.Exit(1)
+	}
+
+	config, err := LoadConfig(configFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	targets := configTargets(config)
+	if matrix != "" {
+		if targets, err = parseMatrix(matrix); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		targets = expandTargets(config, targets)
+	}
+	values, err := ResolveVariables(config.Variables, variables)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Runs of this invocation, added to the --coverage file at the end
+	var run *Coverage
+	if coveragePath != "" {
+		run = &Coverage{Hits: map[string]int{}}
+	}
+
+	if inDocker {
+		if len(factValues) > 0 {
+			fmt.Fprintf(os.Stderr, "Error: --fact is for planning; containers gather real facts\n")
+			os.Exit(1)
+		}
+		failed, err := testInDocker(os.Stdout, configFile, targets, images, binary, variables, verbose, run)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("\n%d of %d targets passed\n", len(targets)-failed, len(targets))
+		reportTestCoverage(coveragePath, config, run, verbose)
+		if failed > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	failed := 0
+	for _, target := range targets {
+		facts, err := mockFacts(config.Facts, target.OS, factValues)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		for name, value := range values {
+			facts[name] = value
+		}
+		result := planTarget(config, target, facts)
+		printMatrixResult(os.Stdout, result, verbose)
+		if result.Failed() {
+			failed++
+		}
+		if run != nil {
+			run.add(planBranches(config, result))
+		}
+	}
+
+	fmt.Printf("\n%d of %d targets passed\n", len(targets)-failed, len(targets))
+	reportTestCoverage(coveragePath, config, run, verbose)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// reportTestCoverage adds the runs of sink test to the --coverage file and
+// reports the branches that no run recorded in it has exercised
+func reportTestCoverage(path string, config *Config, run *Coverage, verbose bool) {
+	if path == "" {
+		return
+	}
+	if err := recordCoverage(path, config, run); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	coverage, err := loadCoverage(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println()
+	printCoverageReport(os.Stdout, config, coverage, verbose)
+}
+
+func printTestHelp() {
+	fmt.Printf(`sink test - Plan a config for several platforms with mocked facts
+
+Usage:
+  sink test <config> [--matrix <targets>] [options]
+
+Description:
+  Plans the config for each target platform and distribution without a
+  machine of that kind: facts get stand-in values, every templated field
+  of the steps that would run (commands, checks, remediations, stdin,
+  file paths, prompts) is rendered, and nothing is executed. A step that
+  uses a fact gathered only on another platform, or a misspelled fact,
+  fails the target, which catches "works on my Mac" errors in CI.
+
+  Stand-in facts are false for booleans, 0 for integers and "<name>" for
+  strings; --fact sets a real value, e.g. to take the other side of an
+  {{if}}. Facts limited to other platforms are absent, as on a real host.
+
+  With --in-docker the config is executed for real, each Linux target in
+  a fresh container of its distribution's official image, and a target
+  passes when sink execute succeeds there. The config's directory is
+  mounted read-only and copied into the container; the container's host
+  name is sink-test and is passed as --confirm-host. Non-Linux targets are
+  skipped. Images: ubuntu, debian, alpine, fedora, rocky, almalinux, amzn
+  (amazonlinux), arch (archlinux) and opensuse-leap have defaults, other
+  IDs use <id>:latest, and a plain linux target uses ubuntu.
+
+  --coverage adds the branches this invocation exercised to a coverage
+  file and reports, across every run recorded there, the steps,
+  remediations and fallbacks no run has taken. Planning covers the steps
+  of each target and the fallbacks it reaches; remediations only run when
+  a check fails, so they are covered by --in-docker runs and by
+  "sink execute --coverage" runs against fixtures.
+
+Options:
+  --matrix <targets>     Comma-separated targets: <os> or <os>/<distribution
+                         id>, e.g. darwin,linux/ubuntu,linux/alpine. An <os>
+                         whose platform has distributions means all of them.
+                         Default: every platform and distribution in the config
+  --fact <name>=<value>  Value for a fact instead of its stand-in (repeatable)
+  --var <name>=<value>   Set a config variable (repeatable)
+  --in-docker            Execute the config in a container per target
+  --image <id>=<image>   Image for a distribution ID with --in-docker
+                         (repeatable), e.g. ubuntu=ubuntu:22.04
+  --coverage <file>      Record exercised branches in <file> (created if
+                         missing) and report the untested ones
+  --binary <path>        Linux sink binary to run in the containers.
+                         Default: this executable on Linux. Use a static
+                         build (make build-static) for alpine
+  -v, --verbose          Show every step with its rendered commands, or
+                         with --in-docker the full output of every run;
+                         with --coverage list every branch and its count
+  -h, --help             Show this help message
+
+Exit Codes:
+  0                      Every target has a plan (or a fallback error the
+                         config declares for it), or with --in-docker every
+                         container run succeeds
+  1                      A target has no platform or distribution, a step
+                         template fails, or a container run fails
+
+Examples:
+  sink test install-config.json
+  sink test install-config.json --matrix darwin,linux/ubuntu,linux/alpine
+  sink test install-config.json --fact has_docker=true -v
+  sink test install-config.json --in-docker --matrix linux/ubuntu,linux/fedora
+  sink test install-config.json --in-docker --coverage coverage.json
+`)
+}
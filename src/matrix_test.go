@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// matrixConfig has a macOS platform using a darwin-only fact and Linux
+// distributions, one of which uses it by mistake
+const matrixConfig = `{"version": "1.0.0",
+	"facts": {
+		"brew_prefix": {"command": "brew --prefix", "platforms": ["darwin"]},
+		"has_docker": {"command": "command -v docker >/dev/null && echo true || echo false", "type": "boolean"}
+	},
+	"platforms": [
+		{"os": "darwin", "match": "Darwin", "name": "macOS", "install_steps": [
+			{"name": "Install jq", "command": "{{.brew_prefix}}/bin/brew install jq"}]},
+		{"os": "linux", "match": "Linux", "name": "Linux", "fallback": {"error": "unsupported distribution"}, "distributions": [
+			{"ids": ["ubuntu", "debian"], "name": "Ubuntu", "install_steps": [
+				{"name": "Install jq", "check": "command -v jq", "on_missing": [{"name": "apt", "command": "apt-get install -y jq{{if .has_docker}} docker-ce{{end}}"}]}]},
+			{"ids": ["alpine"], "name": "Alpine", "install_steps": [
+				{"name": "Install jq", "command": "{{.brew_prefix}}/bin/brew install jq"}]}
+		]}
+	]}`
+
+// TestPlanTarget tests planning each target with mocked facts
+func TestPlanTarget(t *testing.T) {
+	config, err := parseConfigData([]byte(matrixConfig), LoadOptions{})
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+
+	targets := configTargets(config)
+	if got := len(targets); got != 3 || targets[1].String() != "linux/ubuntu" {
+		t.Fatalf("unexpected targets %v", targets)
+	}
+
+	results := map[string]MatrixResult{}
+	for _, target := range append(targets, MatrixTarget{OS: "linux", Distro: "arch"}, MatrixTarget{OS: "windows"}) {
+		facts, err := mockFacts(config.Facts, target.OS, map[string]string{"has_docker": "true"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		results[target.String()] = planTarget(config, target, facts)
+	}
+
+	if r := results["darwin"]; r.Failed() || r.Steps[0].Rendered[0].Text != "<brew_prefix>/bin/brew install jq" {
+		t.Errorf("darwin: unexpected plan %+v", r)
+	}
+	if r := results["linux/ubuntu"]; r.Failed() || r.Platform != "Linux, Ubuntu" ||
+		r.Steps[0].Rendered[1].Text != "apt-get install -y jq docker-ce" || r.Steps[0].Rendered[1].Field != "on_missing[1] apt: command" {
+		t.Errorf("ubuntu: unexpected plan %+v", r)
+	}
+	if r := results["linux/alpine"]; !r.Failed() || !strings.Contains(r.Steps[0].Errors[0], "unknown fact 'brew_prefix'") {
+		t.Errorf("alpine: expected the darwin-only fact to fail, got %+v", r)
+	}
+	if r := results["linux/arch"]; r.Failed() || r.Fallback != "unsupported distribution" {
+		t.Errorf("arch: expected the platform fallback, got %+v", r)
+	}
+	if r := results["windows"]; !r.Failed() || r.Error != "no platform for windows" {
+		t.Errorf("windows: expected no platform, got %+v", r)
+	}
+
+	var out bytes.Buffer
+	printMatrixResult(&out, results["linux/alpine"], false)
+	if !strings.HasPrefix(out.String(), "✗ linux/alpine (Linux, Alpine): 1 step\n   ✗ Install jq\n      command: template execution error: unknown fact 'brew_prefix'") {
+		t.Errorf("unexpected report:\n%s", out.String())
+	}
+}
+
+// TestParseMatrix tests parsing --matrix and expanding platforms to distributions
+func TestParseMatrix(t *testing.T) {
+	config, err := parseConfigData([]byte(matrixConfig), LoadOptions{})
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	targets, err := parseMatrix("darwin, linux")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, target := range expandTargets(config, targets) {
+		names = append(names, target.String())
+	}
+	if strings.Join(names, ",") != "darwin,linux/ubuntu,linux/alpine" {
+		t.Errorf("got %v", names)
+	}
+	for _, bad := range []string{"", ",", "/ubuntu", "linux/a/b"} {
+		if _, err := parseMatrix(bad); err == nil {
+			t.Errorf("expected %q to fail", bad)
+		}
+	}
+	if _, err := mockFacts(config.Facts, "linux", map[string]string{"has_dockr": "true"}); err == nil {
+		t.Error("expected an unknown --fact to fail")
+	}
+}
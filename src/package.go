@@ -0,0 +1,347 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// BundleManifestName is the checksum manifest stored at the root of a bundle
+const BundleManifestName = "SHA256SUMS"
+
+// PackageOptions controls how a config directory is bundled
+type PackageOptions struct {
+	Dir           string // Directory holding the config and the assets it references
+	Config        string // Config file, relative to Dir (default: config.json)
+	Output        string // Bundle path (.tar.gz)
+	SignKey       string // Ed25519 private key used to sign the bundle (optional)
+	GitHubRelease string // owner/repo@tag to upload the bundle to (optional)
+}
+
+// BundleResult describes a written bundle
+type BundleResult struct {
+	Files     []string // Bundled files, slash-separated and relative to Dir
+	Checksum  string   // SHA256 of the bundle itself
+	Artifacts []string // Bundle, .sha256 sidecar and (if signed) .sig
+}
+
+// packageCommand bundles a config directory for publishing
+func packageCommand() {
+	opts := PackageOptions{Config: "config.json"}
+
+	args := os.Args[2:]
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch arg {
+		case "-h", "--help":
+			printPackageHelp()
+			os.Exit(0)
+		case "-o", "--output", "--config", "--sign-key", "--github-release":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: %s requires a value\n", arg)
+				os.Exit(1)
+			}
+			switch arg {
+			case "--config":
+				opts.Config = args[i+1]
+			case "--sign-key":
+				opts.SignKey = args[i+1]
+			case "--github-release":
+				opts.GitHubRelease = args[i+1]
+			default:
+				opts.Output = args[i+1]
+			}
+			i++
+		default:
+			if strings.HasPrefix(arg, "-") || opts.Dir != "" {
+				fmt.Fprintf(os.Stderr, "Error: unexpected argument: %s\n", arg)
+				os.Exit(1)
+			}
+			opts.Dir = arg
+		}
+	}
+
+	if opts.Dir == "" || opts.Output == "" {
+		fmt.Fprintf(os.Stderr, "Error: config directory and -o <bundle.tar.gz> are required\n\n")
+		printPackageHelp()
+		os.Exit(1)
+	}
+
+	result, err := buildConfigBundle(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("📦 Packaged %d files into %s\n", len(result.Files), opts.Output)
+	for _, file := range result.Files {
+		fmt.Printf("   %s\n", file)
+	}
+	fmt.Printf("✅ SHA256 %s\n", result.Checksum)
+	for _, artifact := range result.Artifacts[1:] {
+		fmt.Printf("✅ Wrote %s\n", artifact)
+	}
+
+	if opts.GitHubRelease != "" {
+		if err := uploadGitHubReleaseAssets(opts.GitHubRelease, result.Artifacts); err != nil {
+			fmt.Fprintf(os.Stderr, "Error uploading to GitHub: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("🚀 Uploaded %d assets to %s\n", len(result.Artifacts), opts.GitHubRelease)
+	}
+}
+
+// buildConfigBundle validates the config, then writes a reproducible
+// .tar.gz of every non-hidden file in opts.Dir plus a SHA256SUMS manifest,
+// a <bundle>.sha256 sidecar and, with a signing key, a <bundle>.sig.
+func buildConfigBundle(opts PackageOptions) (*BundleResult, error) {
+	configPath := filepath.Join(opts.Dir, opts.Config)
+	if _, err := LoadConfig(configPath); err != nil {
+		return nil, fmt.Errorf("%s: %w", configPath, err)
+	}
+
+	var signKey ed25519.PrivateKey
+	if opts.SignKey != "" {
+		key, err := loadSigningKey(opts.SignKey)
+		if err != nil {
+			return nil, err
+		}
+		signKey = key
+	}
+
+	outputAbs, err := filepath.Abs(opts.Output)
+	if err != nil {
+		return nil, err
+	}
+
+	// Collect files in lexical order so identical inputs give identical bundles
+	var files []string
+	err = filepath.WalkDir(opts.Dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p != opts.Dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if abs, _ := filepath.Abs(p); abs == outputAbs || abs == outputAbs+".sha256" || abs == outputAbs+".sig" {
+			return nil // Previous bundle and its sidecars
+		}
+		rel, err := filepath.Rel(opts.Dir, p)
+		if err != nil {
+			return err
+		}
+		if rel == BundleManifestName {
+			return nil // Regenerated below
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest strings.Builder
+	for _, file := range files {
+		checksum, err := fileSHA256(filepath.Join(opts.Dir, filepath.FromSlash(file)))
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&manifest, "%s  %s\n", checksum, file)
+	}
+
+	if err := writeBundle(opts.Output, opts.Dir, files, manifest.String()); err != nil {
+		return nil, err
+	}
+
+	sidecar, err := writeChecksumFile(opts.Output, true)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &BundleResult{
+		Files:     append(files, BundleManifestName),
+		Checksum:  strings.Fields(sidecar)[0],
+		Artifacts: []string{opts.Output, opts.Output + ".sha256"},
+	}
+
+	if signKey != nil {
+		data, err := os.ReadFile(opts.Output)
+		if err != nil {
+			return nil, err
+		}
+		sigPath := opts.Output + SignatureExtension
+		if err := os.WriteFile(sigPath, []byte(signData(data, signKey)), 0644); err != nil {
+			return nil, err
+		}
+		result.Artifacts = append(result.Artifacts, sigPath)
+	}
+
+	return result, nil
+}
+
+// writeBundle writes files from dir and the manifest into a gzipped tarball.
+// Timestamps and ownership are normalised so the output is reproducible.
+func writeBundle(output string, dir string, files []string, manifest string) (err error) {
+	out, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+	epoch := time.Unix(0, 0)
+
+	for _, file := range files {
+		path := filepath.Join(dir, filepath.FromSlash(file))
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		header := &tar.Header{
+			Name:    file,
+			Mode:    int64(info.Mode().Perm()),
+			Size:    info.Size(),
+			ModTime: epoch,
+			Format:  tar.FormatPAX,
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(tw, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	header := &tar.Header{
+		Name:    BundleManifestName,
+		Mode:    0644,
+		Size:    int64(len(manifest)),
+		ModTime: epoch,
+		Format:  tar.FormatPAX,
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(tw, manifest); err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// uploadGitHubReleaseAssets uploads files to an existing release given as
+// owner/repo@tag. Requires GITHUB_TOKEN.
+func uploadGitHubReleaseAssets(target string, files []string) error {
+	repo, tag, ok := strings.Cut(target, "@")
+	if !ok || tag == "" || strings.Count(repo, "/") != 1 {
+		return fmt.Errorf("invalid release '%s' (expected owner/repo@tag)", target)
+	}
+	token := os.Getenv("GITHUB_TOKEN")
+	if token == "" {
+		return fmt.Errorf("GITHUB_TOKEN is required to upload release assets")
+	}
+
+	var release GitHubRelease
+	if err := githubAPIGet(fmt.Sprintf("%s/repos/%s/releases/tags/%s", githubAPIBaseURL, repo, tag), &release); err != nil {
+		return fmt.Errorf("failed to fetch release '%s': %w", tag, err)
+	}
+	if release.UploadURL == "" {
+		return fmt.Errorf("release '%s' has no upload URL", tag)
+	}
+	// upload_url is a URI template: .../assets{?name,label}
+	uploadBase, _, _ := strings.Cut(release.UploadURL, "{")
+
+	client := &http.Client{Timeout: httpTimeout(DefaultHTTPTimeout)}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		req, err := http.NewRequest(http.MethodPost, uploadBase+"?name="+url.QueryEscape(filepath.Base(file)), bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Accept", "application/vnd.github+json")
+		req.Header.Set("Content-Type", "application/octet-stream")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusCreated {
+			return fmt.Errorf("HTTP %d uploading %s", resp.StatusCode, filepath.Base(file))
+		}
+	}
+	return nil
+}
+
+func printPackageHelp() {
+	fmt.Print(`sink package - Bundle a config directory for publishing
+
+Usage:
+  sink package <dir> -o <bundle.tar.gz> [options]
+
+Description:
+  Validates the config, then writes a reproducible tarball of every
+  non-hidden file in <dir> (the config plus the scripts and assets it
+  references) with a SHA256SUMS manifest at its root. A <bundle>.sha256
+  sidecar is written next to the bundle, and a <bundle>.sig when a signing
+  key is given.
+
+Options:
+  -o, --output <path>        Bundle to write (.tar.gz)
+  --config <file>            Config inside <dir> (default: config.json)
+  --sign-key <key.pem>       Sign the bundle (see "sink sign")
+  --github-release <o/r@tag> Upload the bundle, .sha256 and .sig to an
+                             existing GitHub release (needs GITHUB_TOKEN)
+  -h, --help                 Show this help message
+
+  Pushing to OCI registries is not built in; push the bundle with a tool
+  such as oras.
+
+Examples:
+  sink package ./config-dir -o bundle.tar.gz
+  sink package ./config-dir -o dist/lab-v1.2.0.tar.gz \
+    --sign-key sink-signing.pem --github-release org/configs@v1.2.0
+
+  # Consume (offline, verifying SHA256SUMS)
+  sink execute --bundle bundle.tar.gz
+
+  # Or by hand
+  tar -xzf bundle.tar.gz -C /tmp/bundle
+  (cd /tmp/bundle && sha256sum -c SHA256SUMS)
+  sink execute /tmp/bundle/config.json
+`)
+}
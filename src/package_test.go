@@ -0,0 +1,162 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const bundleTestConfig = `{"version":"1.0.0","platforms":[{"os":"linux","match":"linux*","name":"Linux","install_steps":[{"name":"a","command":"sh scripts/setup.sh"}]}]}`
+
+// readBundle returns the file contents of a .tar.gz bundle
+func readBundle(t *testing.T, path string) map[string]string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open bundle: %v", err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("bundle is not gzip: %v", err)
+	}
+	tr := tar.NewReader(gz)
+	files := map[string]string{}
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("bad tar: %v", err)
+		}
+		data, _ := io.ReadAll(tr)
+		files[header.Name] = string(data)
+	}
+	return files
+}
+
+// TestBuildConfigBundle tests bundling a config directory
+func TestBuildConfigBundle(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "config.json"), []byte(bundleTestConfig), 0644)
+	os.MkdirAll(filepath.Join(dir, "scripts"), 0755)
+	os.WriteFile(filepath.Join(dir, "scripts", "setup.sh"), []byte("echo hi\n"), 0755)
+	os.WriteFile(filepath.Join(dir, ".secret"), []byte("x"), 0644)
+
+	keyPath := filepath.Join(t.TempDir(), "signing.pem")
+	if err := generateSigningKey(keyPath); err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	// Output inside the directory must not bundle itself
+	output := filepath.Join(dir, "bundle.tar.gz")
+	result, err := buildConfigBundle(PackageOptions{Dir: dir, Config: "config.json", Output: output, SignKey: keyPath})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.Artifacts) != 3 {
+		t.Errorf("expected bundle, sidecar and signature, got %v", result.Artifacts)
+	}
+
+	files := readBundle(t, output)
+	if len(files) != 3 || files["config.json"] != bundleTestConfig || files["scripts/setup.sh"] != "echo hi\n" {
+		t.Errorf("unexpected bundle contents: %v", files)
+	}
+	manifest, err := parseChecksumManifest(files[BundleManifestName])
+	if err != nil {
+		t.Fatalf("invalid bundle manifest: %v", err)
+	}
+	if err := verifyChecksum([]byte("echo hi\n"), manifest["scripts/setup.sh"]); err != nil {
+		t.Errorf("manifest checksum mismatch: %v", err)
+	}
+
+	pub, _ := loadPublicKey(keyPath + ".pub")
+	if err := verifyFileSignature(output, pub); err != nil {
+		t.Errorf("bundle signature invalid: %v", err)
+	}
+
+	// Rebuilding gives an identical bundle
+	first, _ := os.ReadFile(output)
+	if _, err := buildConfigBundle(PackageOptions{Dir: dir, Config: "config.json", Output: output}); err != nil {
+		t.Fatalf("unexpected error on rebuild: %v", err)
+	}
+	second, _ := os.ReadFile(output)
+	if string(first) != string(second) {
+		t.Error("bundle is not reproducible")
+	}
+
+	// Only the output and its sidecars are left out, not files sharing its
+	// name as a prefix
+	output = filepath.Join(dir, "config")
+	if _, err := buildConfigBundle(PackageOptions{Dir: dir, Config: "config.json", Output: output, SignKey: keyPath}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := buildConfigBundle(PackageOptions{Dir: dir, Config: "config.json", Output: output, SignKey: keyPath}); err != nil {
+		t.Fatalf("unexpected error on rebuild: %v", err)
+	}
+	files = readBundle(t, output)
+	for _, name := range []string{"config", "config.sha256", "config.sig"} {
+		if _, ok := files[name]; ok {
+			t.Errorf("bundle contains its own output %s", name)
+		}
+	}
+	if files["config.json"] != bundleTestConfig || files["bundle.tar.gz"] == "" {
+		t.Errorf("expected config.json and the other bundle in the bundle, got %v", sortedKeys(files))
+	}
+
+	// Invalid configs are rejected
+	os.WriteFile(filepath.Join(dir, "broken.json"), []byte(`{"version":"1.0.0"}`), 0644)
+	if _, err := buildConfigBundle(PackageOptions{Dir: dir, Config: "broken.json", Output: output}); err == nil {
+		t.Error("expected error for invalid config")
+	}
+}
+
+// TestUploadGitHubReleaseAssets tests uploading bundle artifacts to a release
+func TestUploadGitHubReleaseAssets(t *testing.T) {
+	dir := t.TempDir()
+	bundle := filepath.Join(dir, "bundle.tar.gz")
+	os.WriteFile(bundle, []byte("bundle"), 0644)
+
+	var uploaded []string
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/repos/org/configs/releases/tags/v1.0.0":
+			json.NewEncoder(w).Encode(GitHubRelease{TagName: "v1.0.0", UploadURL: server.URL + "/upload/1/assets{?name,label}"})
+		case r.Method == http.MethodPost && r.URL.Path == "/upload/1/assets":
+			if r.Header.Get("Authorization") != "Bearer test-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			uploaded = append(uploaded, r.URL.Query().Get("name"))
+			w.WriteHeader(http.StatusCreated)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	origAPI := githubAPIBaseURL
+	githubAPIBaseURL = server.URL
+	defer func() { githubAPIBaseURL = origAPI }()
+	t.Setenv("GITHUB_TOKEN", "test-token")
+
+	if err := uploadGitHubReleaseAssets("org/configs@v1.0.0", []string{bundle}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(uploaded, ",") != "bundle.tar.gz" {
+		t.Errorf("unexpected uploads: %v", uploaded)
+	}
+
+	if err := uploadGitHubReleaseAssets("org/configs", []string{bundle}); err == nil {
+		t.Error("expected error for missing tag")
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// dirOverrides holds --state-dir and --cache-dir, which take precedence over
+// the SINK_* variables and the platform defaults. sink keeps files between
+// runs by kind:
+//
+//   - state: records that must survive, such as the trust store, rate limit
+//     budgets and console history ($XDG_STATE_HOME/sink, ~/Library/Application
+//     Support/sink)
+//   - cache: data that can be fetched again, such as bundles and step
+//     libraries ($XDG_CACHE_HOME/sink, ~/Library/Caches/sink)
+//   - logs: log files ($XDG_STATE_HOME/sink/logs, ~/Library/Logs/sink)
+//   - workspaces: per-run scratch directories, in the temp directory so that
+//     steps running as other users can reach them
+var dirOverrides struct {
+	State string
+	Cache string
+}
+
+// stateDir returns where sink keeps state between runs
+func stateDir() (string, error) {
+	return resolveDir(dirOverrides.State, EnvStateDir, "state", userStateDir)
+}
+
+// cacheDir returns where sink keeps cached data
+func cacheDir() (string, error) {
+	return resolveDir(dirOverrides.Cache, EnvCacheDir, "cache", func() (string, error) {
+		base, err := os.UserCacheDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(base, "sink"), nil
+	})
+}
+
+// logDir returns where sink writes log files
+func logDir() (string, error) {
+	return resolveDir("", EnvLogDir, "log", func() (string, error) {
+		if runtime.GOOS == "darwin" {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return "", err
+			}
+			return filepath.Join(home, "Library", "Logs", "sink"), nil
+		}
+		state, err := stateDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(state, "logs"), nil
+	})
+}
+
+// workspaceBaseDir returns the directory run workspaces are created in
+func workspaceBaseDir() string {
+	if dir := os.Getenv(EnvWorkspaceDir); dir != "" {
+		return dir
+	}
+	return os.TempDir()
+}
+
+// resolveDir picks the flag, then the environment variable, then the
+// platform default
+func resolveDir(override, envVar, kind string, platformDefault func() (string, error)) (string, error) {
+	if override != "" {
+		return override, nil
+	}
+	if dir := os.Getenv(envVar); dir != "" {
+		return dir, nil
+	}
+	dir, err := platformDefault()
+	if err != nil {
+		return "", fmt.Errorf("cannot determine %s directory (set %s): %w", kind, envVar, err)
+	}
+	return dir, nil
+}
+
+// userStateDir returns "sink" under the platform's per-user state directory
+func userStateDir() (string, error) {
+	switch runtime.GOOS {
+	case "windows":
+		base, err := os.UserCacheDir() // %LocalAppData%
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(base, "sink", "state"), nil
+	case "darwin", "ios":
+		base, err := os.UserConfigDir() // ~/Library/Application Support
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(base, "sink"), nil
+	}
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		if !filepath.IsAbs(dir) {
+			return "", fmt.Errorf("XDG_STATE_HOME must be an absolute path, got %q", dir)
+		}
+		return filepath.Join(dir, "sink"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "state", "sink"), nil
+}
+
+// statePath returns name under the state directory. Releases before the
+// state directory existed kept state in the cache directory; a file left
+// there is moved over, or used in place when it cannot be moved.
+func statePath(name string) (string, error) {
+	state, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(state, name)
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		return path, nil
+	}
+	cache, err := cacheDir()
+	if err != nil || cache == state {
+		return path, nil
+	}
+	legacy := filepath.Join(cache, name)
+	if _, err := os.Stat(legacy); err != nil {
+		return path, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return legacy, nil
+	}
+	if err := os.Rename(legacy, path); err != nil {
+		return legacy, nil
+	}
+	return path, nil
+}
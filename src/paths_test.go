@@ -0,0 +1,86 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// TestStateDir tests the state directory's precedence: flag, then
+// SINK_STATE_DIR, then XDG_STATE_HOME
+func TestStateDir(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("XDG defaults apply on Linux")
+	}
+	t.Setenv("HOME", "/home/test")
+	t.Setenv("XDG_STATE_HOME", "")
+	t.Setenv(EnvStateDir, "")
+	if dir, err := stateDir(); err != nil || dir != "/home/test/.local/state/sink" {
+		t.Errorf("stateDir() = %q, %v", dir, err)
+	}
+
+	t.Setenv("XDG_STATE_HOME", "relative/state")
+	if _, err := stateDir(); err == nil {
+		t.Error("expected a relative XDG_STATE_HOME to be rejected")
+	}
+
+	t.Setenv("XDG_STATE_HOME", "/var/lib/test-state")
+	if dir, _ := stateDir(); dir != "/var/lib/test-state/sink" {
+		t.Errorf("expected XDG_STATE_HOME, got %q", dir)
+	}
+	if dir, _ := logDir(); dir != "/var/lib/test-state/sink/logs" {
+		t.Errorf("expected logs under the state directory, got %q", dir)
+	}
+
+	t.Setenv(EnvStateDir, "/srv/sink-state")
+	if dir, _ := stateDir(); dir != "/srv/sink-state" {
+		t.Errorf("expected SINK_STATE_DIR, got %q", dir)
+	}
+
+	dirOverrides.State = "/opt/state"
+	defer func() { dirOverrides.State = "" }()
+	if dir, _ := stateDir(); dir != "/opt/state" {
+		t.Errorf("expected --state-dir, got %q", dir)
+	}
+}
+
+// TestStatePathMigratesFromCache tests that state kept in the cache directory
+// by earlier releases is moved to the state directory
+func TestStatePathMigratesFromCache(t *testing.T) {
+	cache, state := t.TempDir(), t.TempDir()
+	t.Setenv(EnvCacheDir, cache)
+	t.Setenv(EnvStateDir, state)
+	os.WriteFile(filepath.Join(cache, "trusted_sources.json"), []byte("{}"), 0600)
+
+	path, err := statePath("trusted_sources.json")
+	if err != nil {
+		t.Fatalf("statePath failed: %v", err)
+	}
+	if path != filepath.Join(state, "trusted_sources.json") {
+		t.Errorf("expected the state directory, got %s", path)
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "{}" {
+		t.Errorf("expected the legacy file to be moved: %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(cache, "trusted_sources.json")); !os.IsNotExist(err) {
+		t.Errorf("expected the legacy file to be gone, got %v", err)
+	}
+
+	// Nothing to migrate
+	if path, _ := statePath("console_history"); path != filepath.Join(state, "console_history") {
+		t.Errorf("unexpected path %s", path)
+	}
+}
+
+// TestWorkspaceBaseDir tests SINK_WORKSPACE_DIR
+func TestWorkspaceBaseDir(t *testing.T) {
+	t.Setenv(EnvWorkspaceDir, "")
+	if got := workspaceBaseDir(); got != os.TempDir() {
+		t.Errorf("expected the temp directory, got %s", got)
+	}
+	t.Setenv(EnvWorkspaceDir, "/scratch")
+	if got := defaultWorkspace("run1"); got != "/scratch/sink-run1" {
+		t.Errorf("defaultWorkspace = %s", got)
+	}
+}
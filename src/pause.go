@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// PauseStep holds a run between phases: it waits for a duration, asks an
+// operator to confirm a prompt, or both (the prompt is shown after the wait),
+// for runbooks that must let DNS propagate or have someone check a dashboard
+type PauseStep struct {
+	Pause Pause `json:"pause"`
+}
+
+func (PauseStep) isStep() {}
+
+// Pause is the body of a pause step
+type Pause struct {
+	Duration string `json:"duration,omitempty"` // How long to wait, e.g. "2m"
+	Prompt   string `json:"prompt,omitempty"`   // What the operator confirms before the run continues (templated)
+}
+
+// validate checks that a pause waits for something
+func (p Pause) validate() error {
+	if p.Duration == "" && p.Prompt == "" {
+		return fmt.Errorf("needs a duration, a prompt or both")
+	}
+	if p.Duration != "" {
+		d, err := time.ParseDuration(p.Duration)
+		if err != nil {
+			return fmt.Errorf("invalid duration '%s': %w", p.Duration, err)
+		}
+		if d <= 0 {
+			return fmt.Errorf("duration must be positive, got '%s'", p.Duration)
+		}
+	}
+	return nil
+}
+
+// Operator is the person at the terminal, who confirms pause prompts. The
+// executor has none when output is JSON or a full-screen view, and prompts
+// then fail rather than hang.
+type Operator struct {
+	In  *bufio.Reader
+	Out io.Writer
+}
+
+// NewOperator creates an operator reading answers from in and prompting on out
+func NewOperator(in io.Reader, out io.Writer) *Operator {
+	return &Operator{In: bufio.NewReader(in), Out: out}
+}
+
+// confirm shows a prompt and reports whether the operator typed "yes"
+func (o *Operator) confirm(stepName, prompt string) (bool, error) {
+	fmt.Fprintf(o.Out, "\n⏸  %s: %s\n   Type 'yes' to continue: ", stepName, prompt)
+	line, err := o.In.ReadString('\n')
+	if err != nil && line == "" {
+		fmt.Fprintln(o.Out)
+		return false, fmt.Errorf("end of input")
+	}
+	return strings.TrimSpace(line) == "yes", nil
+}
+
+// executePause waits out a pause step. A wait never outlasts the run
+// deadline (--max-duration).
+func (e *Executor) executePause(stepName string, step PauseStep, facts Facts) StepResult {
+	var done []string
+
+	if step.Pause.Duration != "" {
+		d, err := time.ParseDuration(step.Pause.Duration)
+		if err != nil {
+			return StepResult{StepName: stepName, Status: "failed", Error: fmt.Sprintf("invalid pause duration '%s': %v", step.Pause.Duration, err)}
+		}
+		if !e.Deadline.IsZero() {
+			if remaining := time.Until(e.Deadline); remaining < d {
+				if !e.sleep(max(remaining, 0)) && e.Interrupted() {
+					return StepResult{StepName: stepName, Status: "failed", Error: fmt.Sprintf("paused %s: %v", step.Pause.Duration, errRunInterrupted)}
+				}
+				return StepResult{StepName: stepName, Status: "failed", Error: fmt.Sprintf("paused %s: %v", step.Pause.Duration, errRunDeadlineExceeded)}
+			}
+		}
+		if e.Verbose {
+			verboseLog("Pausing for %s...", d)
+		}
+		if !e.sleep(d) && e.Interrupted() {
+			return StepResult{StepName: stepName, Status: "failed", Error: fmt.Sprintf("paused %s: %v", step.Pause.Duration, errRunInterrupted)}
+		}
+		done = append(done, "paused "+step.Pause.Duration)
+	}
+
+	if step.Pause.Prompt != "" {
+		prompt, err := e.interpolate(step.Pause.Prompt, facts)
+		if err != nil {
+			return StepResult{StepName: stepName, Status: "failed", Error: fmt.Sprintf("prompt: %v", err)}
+		}
+		if e.Operator == nil {
+			return StepResult{StepName: stepName, Status: "failed",
+				Error: fmt.Sprintf("needs an operator to confirm %q, but the run is not interactive", prompt)}
+		}
+		confirmed, err := e.Operator.confirm(stepName, prompt)
+		if err != nil {
+			return StepResult{StepName: stepName, Status: "failed", Error: fmt.Sprintf("not confirmed (%v): %s", err, prompt)}
+		}
+		if !confirmed {
+			return StepResult{StepName: stepName, Status: "failed", Error: "not confirmed: " + prompt}
+		}
+		done = append(done, "confirmed: "+prompt)
+	}
+
+	return StepResult{StepName: stepName, Status: "success", Output: strings.Join(done, "; ")}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestPauseStep tests timed waits and confirmation prompts
+func TestPauseStep(t *testing.T) {
+	executor := NewExecutor(&MockTransport{responses: map[string]MockResponse{}})
+	pause := func(p Pause) InstallStep { return InstallStep{Name: "cutover", Step: PauseStep{Pause: p}} }
+
+	start := time.Now()
+	result := executor.ExecuteStep(pause(Pause{Duration: "50ms"}), Facts{})
+	if result.Error != "" || result.Output != "paused 50ms" || time.Since(start) < 50*time.Millisecond {
+		t.Errorf("timed pause: unexpected result %+v after %s", result, time.Since(start))
+	}
+
+	// Without an operator a prompt cannot be confirmed
+	result = executor.ExecuteStep(pause(Pause{Prompt: "confirm DNS"}), Facts{})
+	if !strings.Contains(result.Error, "not interactive") {
+		t.Errorf("expected a non-interactive failure, got %+v", result)
+	}
+
+	var out bytes.Buffer
+	executor.Operator = NewOperator(strings.NewReader("yes\nno\n"), &out)
+	result = executor.ExecuteStep(pause(Pause{Duration: "1ms", Prompt: "confirm {{.zone}} has propagated"}), Facts{"zone": "example.com"})
+	if result.Error != "" || result.Output != "paused 1ms; confirmed: confirm example.com has propagated" {
+		t.Errorf("confirmed pause: unexpected result %+v", result)
+	}
+	if !strings.Contains(out.String(), "⏸  cutover: confirm example.com has propagated\n   Type 'yes' to continue: ") {
+		t.Errorf("unexpected prompt %q", out.String())
+	}
+	if result = executor.ExecuteStep(pause(Pause{Prompt: "flip traffic"}), Facts{}); result.Error != "not confirmed: flip traffic" {
+		t.Errorf("expected a declined prompt to fail, got %+v", result)
+	}
+	if result = executor.ExecuteStep(pause(Pause{Prompt: "flip traffic"}), Facts{}); !strings.Contains(result.Error, "end of input") {
+		t.Errorf("expected end of input to fail, got %+v", result)
+	}
+}
+
+// TestPauseStep_Deadline tests that a pause gives up at the run deadline
+func TestPauseStep_Deadline(t *testing.T) {
+	executor := NewExecutor(&MockTransport{responses: map[string]MockResponse{}})
+	executor.Deadline = time.Now().Add(20 * time.Millisecond)
+	start := time.Now()
+	result := executor.ExecuteStep(InstallStep{Name: "wait", Step: PauseStep{Pause: Pause{Duration: "1h"}}}, Facts{})
+	if !strings.Contains(result.Error, errRunDeadlineExceeded.Error()) || time.Since(start) > time.Second {
+		t.Errorf("expected the pause to stop at the deadline, got %+v after %s", result, time.Since(start))
+	}
+}
+
+// TestUnmarshalPauseStep tests parsing and validating pause steps
+func TestUnmarshalPauseStep(t *testing.T) {
+	var step InstallStep
+	if err := json.Unmarshal([]byte(`{"name": "wait", "pause": {"duration": "2m", "prompt": "confirm DNS has propagated"}}`), &step); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if p, ok := step.Step.(PauseStep); !ok || p.Pause.Duration != "2m" || p.Pause.Prompt != "confirm DNS has propagated" {
+		t.Errorf("expected PauseStep, got %#v", step.Step)
+	}
+
+	for body, want := range map[string]string{
+		`{}`:                   "needs a duration, a prompt or both",
+		`{"duration": "soon"}`: "invalid duration",
+		`{"duration": "-1s"}`:  "duration must be positive",
+	} {
+		if err := json.Unmarshal([]byte(`{"name": "wait", "pause": `+body+`}`), &step); err != nil {
+			t.Fatalf("unmarshal %s: %v", body, err)
+		}
+		err := validateStepAnnotations([]InstallStep{step})
+		if err == nil || !strings.Contains(err.Error(), "pause: "+want) {
+			t.Errorf("pause %s: expected %q, got %v", body, want, err)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// posixConstruct is a shell feature that bash accepts but POSIX sh does not,
+// so a command using it fails where /bin/sh is dash or BusyBox ash
+type posixConstruct struct {
+	Pattern *regexp.Regexp
+	Name    string
+}
+
+// posixConstructs are the bash-isms and GNU-only flags the portability lint
+// looks for. Patterns match command text with template actions and
+// single-quoted strings removed, so awk and sed programs are not flagged.
+var posixConstructs = []posixConstruct{
+	{regexp.MustCompile(`\[\[`), "[[ ]] tests (use [ ])"},
+	{regexp.MustCompile(`(^|[\s;&|(])\[\s[^]]*\s==\s`), "== in [ ] (use =)"},
+	{regexp.MustCompile(`(^|[\s;&|])function\s+\w+`), "the function keyword (use name() { ... })"},
+	{regexp.MustCompile(`(^|[\s;&|])source\s`), "source (use .)"},
+	{regexp.MustCompile(`(^|[\s;&|])(declare|typeset)\s`), "declare/typeset"},
+	{regexp.MustCompile(`<<<`), "here-strings (<<<)"},
+	{regexp.MustCompile(`[<>]\(`), "process substitution (<(...))"},
+	{regexp.MustCompile(`&>`), "&> redirection (use >file 2>&1)"},
+	{regexp.MustCompile(`\|&`), "|& pipes (use 2>&1 |)"},
+	{regexp.MustCompile(`\w=\(`), "arrays"},
+	{regexp.MustCompile(`\$\{#?\w+(/|:[0-9]|\^|,)`), "${var/...}, ${var:offset} and case expansions"},
+	{regexp.MustCompile(`\{\w+\.\.\w+\}`), "brace ranges ({1..3})"},
+	{regexp.MustCompile(`(^|[\s;&|])echo\s+-[nE]*e[nE]*\s`), "echo -e (use printf)"},
+	{regexp.MustCompile(`(^|[\s;&|])grep\s([^|;&]*\s)?(-[a-zA-Z]*P|--perl-regexp)`), "grep -P (GNU grep only)"},
+}
+
+// ansiCQuote matches bash's $'...' quoting, which is looked for before
+// single-quoted strings are removed
+var ansiCQuote = regexp.MustCompile(`\$'`)
+
+// singleQuoted strings are removed before matching, like template actions
+var singleQuoted = regexp.MustCompile(`'[^']*'`)
+
+// nonPOSIX returns the non-POSIX constructs a command uses. A command that
+// hands its work to bash, zsh or ksh is not checked.
+func nonPOSIX(command string) []string {
+	fields := strings.Fields(command)
+	if len(fields) > 1 && fields[0] == "/usr/bin/env" {
+		fields = fields[1:]
+	}
+	if len(fields) > 0 {
+		switch path.Base(fields[0]) {
+		case "bash", "zsh", "ksh":
+			return nil
+		}
+	}
+	text := templateAction.ReplaceAllString(command, "")
+	var found []string
+	if ansiCQuote.MatchString(text) {
+		found = append(found, "$'...' quoting")
+	}
+	text = singleQuoted.ReplaceAllString(text, "''")
+	for _, construct := range posixConstructs {
+		if construct.Pattern.MatchString(text) {
+			found = append(found, construct.Name)
+		}
+	}
+	return found
+}
+
+// posixFindings lists the fact and step commands that are not POSIX, as
+// "<where>: uses <constructs>". Facts limited to Windows are not checked.
+func posixFindings(facts map[string]FactDef, steps []InstallStep) []string {
+	var findings []string
+	for _, name := range sortedKeys(facts) {
+		def := facts[name]
+		if len(def.Platforms) == 1 && def.Platforms[0] == "windows" {
+			continue
+		}
+		if found := nonPOSIX(def.Command); len(found) > 0 {
+			findings = append(findings, fmt.Sprintf("fact %s: uses %s", name, strings.Join(found, ", ")))
+		}
+	}
+	for _, step := range steps {
+		var found []string
+		for _, command := range stepCommands(step) {
+			for _, construct := range nonPOSIX(command) {
+				if !containsString(found, construct) {
+					found = append(found, construct)
+				}
+			}
+		}
+		if len(found) > 0 {
+			findings = append(findings, fmt.Sprintf("step %q: uses %s", step.Name, strings.Join(found, ", ")))
+		}
+	}
+	return findings
+}
+
+// configPOSIXFindings runs the portability lint over every non-Windows
+// platform and distribution of a config
+func configPOSIXFindings(config *Config) []string {
+	findings := posixFindings(config.Facts, nil)
+	for _, platform := range config.Platforms {
+		if platform.OS == "windows" {
+			continue
+		}
+		for _, finding := range posixFindings(nil, platform.InstallSteps) {
+			findings = append(findings, fmt.Sprintf("%s: %s", platform.Name, finding))
+		}
+		for _, dist := range platform.Distributions {
+			for _, finding := range posixFindings(nil, dist.InstallSteps) {
+				findings = append(findings, fmt.Sprintf("%s/%s: %s", platform.Name, dist.Name, finding))
+			}
+		}
+	}
+	return findings
+}
+
+// minimalShell reports whether a shell dialect lacks bash's extensions.
+// An unidentified shell ("sh") is not assumed to lack them.
+func minimalShell(dialect string) bool {
+	return dialect == "dash" || dialect == "ash"
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// TestNonPOSIX tests which commands the portability lint flags
+func TestNonPOSIX(t *testing.T) {
+	tests := []struct {
+		command string
+		want    string // Construct reported, or "" for none
+	}{
+		{`[[ -f /etc/os-release ]] && echo yes`, "[[ ]] tests"},
+		{`if [ "$ID" == alpine ]; then apk add git; fi`, "== in [ ]"},
+		{`function setup { true; }`, "the function keyword"},
+		{`source /etc/os-release && echo $ID`, "source (use .)"},
+		{`grep -q x <<< "$PATH"`, "here-strings"},
+		{`diff <(sort a) <(sort b)`, "process substitution"},
+		{`make &> build.log`, "&> redirection"},
+		{`pkgs=(git curl) && apt-get install "${pkgs[@]}"`, "arrays"},
+		{`echo ${VERSION:1}`, "${var:offset}"},
+		{`for i in {1..3}; do echo $i; done`, "brace ranges"},
+		{`echo -e "a\tb"`, "echo -e"},
+		{`grep -oP 'version \K\S+' file`, "grep -P"},
+		{`printf '%s\n' $'a\tb'`, "$'...' quoting"},
+
+		{`. /etc/os-release && [ "$ID" = alpine ]`, ""},
+		{`echo "${NAME:-default}" && test -n "${HOME#/}"`, ""},
+		{`awk '$1 == "x" { a[$2]++ } END { for (k in a) print k }' file`, ""},
+		{`echo {{.sink.tmpfile}} && sh {{.sink.step_dir}}/install.sh`, ""},
+		{`bash -c '[[ -n $x ]]'`, ""},
+		{`/usr/bin/env bash -c "source ~/.bashrc"`, ""},
+		{`command -v git >/dev/null 2>&1 || apk add git`, ""},
+	}
+	for _, tt := range tests {
+		found := strings.Join(nonPOSIX(tt.command), "; ")
+		if tt.want == "" && found != "" {
+			t.Errorf("nonPOSIX(%q) = %q, want none", tt.command, found)
+		}
+		if tt.want != "" && !strings.Contains(found, tt.want) {
+			t.Errorf("nonPOSIX(%q) = %q, want %q", tt.command, found, tt.want)
+		}
+	}
+}
+
+// TestNonPOSIX_SinkScripts tests that what sink runs itself is POSIX
+func TestNonPOSIX_SinkScripts(t *testing.T) {
+	for name, script := range map[string]string{"preflight": preflightScript, "prelude": scriptPrelude} {
+		if found := nonPOSIX(script); len(found) > 0 {
+			t.Errorf("%s script uses %v", name, found)
+		}
+	}
+	templates, err := listTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tmpl := range templates {
+		data, _ := loadTemplate(tmpl.Name)
+		config, err := parseConfigData(data, LoadOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if findings := configPOSIXFindings(config); len(findings) > 0 {
+			t.Errorf("template %s: %v", tmpl.Name, findings)
+		}
+	}
+}
+
+// TestPOSIXFindings tests that findings name the fact or step, including group members
+func TestPOSIXFindings(t *testing.T) {
+	facts := map[string]FactDef{
+		"os_id":   {Command: "source /etc/os-release; echo $ID"},
+		"version": {Command: "ver", Platforms: []string{"windows"}},
+	}
+	steps := []InstallStep{
+		{Name: "ok", Step: CommandStep{Command: "true"}},
+		{Name: "grouped", Step: GroupStep{Group: Group{Steps: []InstallStep{
+			{Name: "member", Step: CommandStep{Command: "[[ -d /opt ]] && echo -e x"}},
+		}}}},
+	}
+	findings := posixFindings(facts, steps)
+	want := []string{
+		"fact os_id: uses source (use .)",
+		`step "grouped": uses [[ ]] tests (use [ ]), echo -e (use printf)`,
+	}
+	if strings.Join(findings, "\n") != strings.Join(want, "\n") {
+		t.Errorf("findings = %q, want %q", findings, want)
+	}
+}
+
+// TestMinimalShells runs sink's own scripts and a strict script step under
+// the minimal shells installed here, as they run on Debian and Alpine
+func TestMinimalShells(t *testing.T) {
+	shells := map[string][]string{
+		"dash": {"dash", "-c"},
+		"ash":  {"busybox", "sh", "-c"},
+	}
+	ran := 0
+	for dialect, shell := range shells {
+		if _, err := exec.LookPath(shell[0]); err != nil {
+			continue
+		}
+		ran++
+		script := scriptCommand([]string{`name=sink`, `[ "$name" = sink ]`, `printf '%s\n' "$name" | grep -q sink`}, true)
+		args := append(append([]string(nil), shell[1:]...), script)
+		if output, err := exec.Command(shell[0], args...).CombinedOutput(); err != nil {
+			t.Errorf("%s: script step failed: %v: %s", dialect, err, output)
+		}
+
+		args = append(append([]string(nil), shell[1:]...), preflightScript)
+		output, err := exec.Command(shell[0], args...).Output()
+		if err != nil {
+			t.Errorf("%s: preflight failed: %v", dialect, err)
+			continue
+		}
+		var ctx ExecutionContext
+		parsePreflight(string(output), &ctx)
+		if ctx.ShellDialect == "" || ctx.Path == "" {
+			t.Errorf("%s: preflight reported %+v", dialect, ctx)
+		}
+	}
+	if ran == 0 {
+		t.Skip("neither dash nor busybox is installed")
+	}
+}
+
+// TestParsePreflight_ShellDialect tests the shell_dialect fact
+func TestParsePreflight_ShellDialect(t *testing.T) {
+	var ctx ExecutionContext
+	parsePreflight("shell_dialect=ash\n", &ctx)
+	if ctx.ShellDialect != "ash" || ctx.preflightFacts()["shell_dialect"] != "ash" || !minimalShell(ctx.ShellDialect) {
+		t.Errorf("unexpected context %+v", ctx)
+	}
+	if minimalShell("bash") || minimalShell("sh") || minimalShell("") {
+		t.Error("expected only dash and ash to count as minimal shells")
+	}
+}
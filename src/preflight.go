@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// packageManagers are the package managers the preflight probe looks for,
+// in order of preference when a host has several (e.g. dnf over yum)
+var packageManagers = []string{
+	"apt-get", "dnf", "yum", "zypper", "apk", "pacman", "brew", "port", "pkg", "pkg_add", "nix-env", "snap", "flatpak",
+}
+
+// packageInstallCommands install packages non-interactively with each
+// package manager, for {{.sink.package_install}}: FreeBSD's pkg needs -y
+// and OpenBSD's pkg_add -I not to prompt
+var packageInstallCommands = map[string]string{
+	"apt-get": "apt-get install -y",
+	"dnf":     "dnf install -y",
+	"yum":     "yum install -y",
+	"zypper":  "zypper --non-interactive install",
+	"apk":     "apk add --no-cache",
+	"pacman":  "pacman -S --noconfirm --needed",
+	"brew":    "brew install",
+	"port":    "port -N install",
+	"pkg":     "pkg install -y",
+	"pkg_add": "pkg_add -I",
+	"nix-env": "nix-env -i",
+	"snap":    "snap install",
+	"flatpak": "flatpak install -y",
+}
+
+// preflightScript reports the host environment as key=value lines in one
+// round trip: login shell, the dialect of the shell it runs in, PATH, home
+// directory, privilege escalation, CPU count, available package managers,
+// SELinux mode, active firewall and container runtime. Probes that need tools or
+// privileges the host lacks print nothing. It is only run on remote hosts;
+// discoverLocalPreflight reports the same for the local one.
+var preflightScript = `echo "login_shell=$SHELL"
+if [ -n "$BASH_VERSION" ]; then echo shell_dialect=bash
+elif [ -n "$ZSH_VERSION" ]; then echo shell_dialect=zsh
+elif [ -n "$KSH_VERSION" ]; then echo shell_dialect=ksh
+elif [ -n "$BB_ASH_VERSION" ] || /bin/sh --help 2>&1 | grep -q BusyBox || [ "$(uname -s)" = FreeBSD ]; then echo shell_dialect=ash
+else case "$(readlink -f /bin/sh 2>/dev/null)" in *dash) echo shell_dialect=dash ;; *) echo shell_dialect=sh ;; esac
+fi
+echo "path=$PATH"
+echo "home=$HOME"
+if [ "$(id -u 2>/dev/null)" = 0 ]; then echo sudo=root
+elif command -v sudo >/dev/null 2>&1 && sudo -n true 2>/dev/null; then echo sudo=passwordless
+else echo sudo=none
+fi
+echo "cpu_count=$(nproc 2>/dev/null || getconf _NPROCESSORS_ONLN 2>/dev/null || sysctl -n hw.ncpu 2>/dev/null)"
+for pm in ` + strings.Join(packageManagers, " ") + `; do command -v "$pm" >/dev/null 2>&1 && echo "package_manager=$pm"; done
+command -v getenforce >/dev/null 2>&1 && echo "selinux=$(getenforce 2>/dev/null)"
+if command -v firewall-cmd >/dev/null 2>&1 && firewall-cmd --state >/dev/null 2>&1; then echo firewall=firewalld
+elif command -v ufw >/dev/null 2>&1 && { systemctl is-active -q ufw 2>/dev/null || ufw status 2>/dev/null | grep -q 'Status: active'; }; then echo firewall=ufw
+elif command -v nft >/dev/null 2>&1 && [ -n "$(nft list ruleset 2>/dev/null)" ]; then echo firewall=nftables
+elif /usr/libexec/ApplicationFirewall/socketfilterfw --getglobalstate 2>/dev/null | grep -q enabled; then echo firewall=macos
+elif command -v pfctl >/dev/null 2>&1 && pfctl -s info 2>/dev/null | grep -q 'Status: Enabled'; then echo firewall=pf
+elif [ "$(sysctl -n net.inet.ip.fw.enable 2>/dev/null)" = 1 ]; then echo firewall=ipfw
+fi
+if [ -f /.dockerenv ]; then echo container=docker
+elif [ -f /run/.containerenv ]; then echo container=podman
+elif [ -n "$KUBERNETES_SERVICE_HOST" ]; then echo container=kubernetes
+elif [ -n "$container" ]; then echo "container=$container"
+elif [ "$(sysctl -n security.jail.jailed 2>/dev/null)" = 1 ]; then echo container=jail
+else echo "container=$(grep -oaE 'kubepods|docker|containerd|lxc' /proc/1/cgroup 2>/dev/null | head -n 1)"
+fi`
+
+// discoverPreflight fills in the host environment fields of ctx. It needs a
+// POSIX shell; other shells leave the fields empty.
+func (e *Executor) discoverPreflight(ctx *ExecutionContext) {
+	if ctx.Shell != ShellPOSIX {
+		return
+	}
+	if lt, ok := e.transport.(*LocalTransport); ok {
+		discoverLocalPreflight(ctx, lt)
+		return
+	}
+	stdout, _, exitCode, err := e.transport.Run(preflightScript)
+	if err != nil || exitCode != 0 {
+		if e.Verbose {
+			verboseLog("Preflight probe failed (exit %d): %v", exitCode, err)
+		}
+		return
+	}
+	parsePreflight(stdout, ctx)
+}
+
+// preflightProbeTimeout bounds each tool discoverLocalPreflight runs, so a
+// hung firewall or sudo probe cannot stall every run. Variable so tests can
+// shorten it.
+var preflightProbeTimeout = 5 * time.Second
+
+// cgroupRuntimeRegex finds a container runtime in /proc/1/cgroup
+var cgroupRuntimeRegex = regexp.MustCompile(`kubepods|docker|containerd|lxc`)
+
+// discoverLocalPreflight fills in the same fields as preflightScript for the
+// machine sink runs on, without a shell: tools are looked up on the
+// transport's PATH and run directly, and files and variables read with os.
+func discoverLocalPreflight(ctx *ExecutionContext, lt *LocalTransport) {
+	env := os.Environ()
+	if lt.Env != nil {
+		env = lt.Env
+	}
+	env = append(append([]string(nil), env...), lt.ExtraEnv...)
+	getenv := func(name string) string {
+		value := ""
+		for _, entry := range env {
+			if key, v, ok := strings.Cut(entry, "="); ok && key == name {
+				value = v // Later entries win, as for commands
+			}
+		}
+		return value
+	}
+	path := getenv("PATH")
+	// run runs a tool found on PATH, reporting its trimmed output and
+	// whether it exited 0 within preflightProbeTimeout
+	run := func(name string, args ...string) (string, bool) {
+		bin := name
+		if !filepath.IsAbs(name) {
+			if bin = lookPathIn(name, path); bin == "" {
+				return "", false
+			}
+		}
+		probeCtx, cancel := context.WithTimeout(context.Background(), preflightProbeTimeout)
+		defer cancel()
+		cmd := exec.CommandContext(probeCtx, bin, args...)
+		cmd.Env = env
+		cmd.WaitDelay = preflightProbeTimeout // Children holding stdout open
+		out, err := cmd.Output()
+		return strings.TrimSpace(string(out)), err == nil
+	}
+	exists := func(file string) bool {
+		_, err := os.Stat(file)
+		return err == nil
+	}
+
+	ctx.LoginShell = getenv("SHELL")
+	ctx.ShellDialect = localShellDialect()
+	ctx.Path = path
+	if home := getenv("HOME"); home != "" {
+		ctx.Home = home
+	}
+
+	switch {
+	case os.Geteuid() == 0:
+		ctx.Sudo = "root"
+	case lookPathIn("sudo", path) != "":
+		if _, ok := run("sudo", "-n", "true"); ok {
+			ctx.Sudo = "passwordless"
+		} else {
+			ctx.Sudo = "none"
+		}
+	default:
+		ctx.Sudo = "none"
+	}
+
+	for _, pm := range packageManagers {
+		if lookPathIn(pm, path) != "" {
+			ctx.PackageManagers = append(ctx.PackageManagers, pm)
+		}
+	}
+	if mode, ok := run("getenforce"); ok && mode != "" {
+		ctx.SELinux = strings.ToLower(mode)
+	}
+
+	ufwActive := func() bool {
+		if _, ok := run("systemctl", "is-active", "-q", "ufw"); ok {
+			return true
+		}
+		status, _ := run("ufw", "status")
+		return strings.Contains(status, "Status: active")
+	}
+	if _, ok := run("firewall-cmd", "--state"); ok {
+		ctx.Firewall = "firewalld"
+	} else if lookPathIn("ufw", path) != "" && ufwActive() {
+		ctx.Firewall = "ufw"
+	} else if ruleset, _ := run("nft", "list", "ruleset"); ruleset != "" {
+		ctx.Firewall = "nftables"
+	} else if state, _ := run("/usr/libexec/ApplicationFirewall/socketfilterfw", "--getglobalstate"); strings.Contains(state, "enabled") {
+		ctx.Firewall = "macos"
+	} else if info, _ := run("pfctl", "-s", "info"); strings.Contains(info, "Status: Enabled") {
+		ctx.Firewall = "pf"
+	} else if enabled, _ := run("sysctl", "-n", "net.inet.ip.fw.enable"); enabled == "1" {
+		ctx.Firewall = "ipfw"
+	}
+
+	switch {
+	case exists("/.dockerenv"):
+		ctx.Container = "docker"
+	case exists("/run/.containerenv"):
+		ctx.Container = "podman"
+	case getenv("KUBERNETES_SERVICE_HOST") != "":
+		ctx.Container = "kubernetes"
+	case getenv("container") != "":
+		ctx.Container = getenv("container")
+	default:
+		if jailed, _ := run("sysctl", "-n", "security.jail.jailed"); jailed == "1" {
+			ctx.Container = "jail"
+		} else if cgroup, err := os.ReadFile("/proc/1/cgroup"); err == nil {
+			ctx.Container = cgroupRuntimeRegex.FindString(string(cgroup))
+		}
+	}
+	if ctx.Container == "kubepods" {
+		ctx.Container = "kubernetes"
+	}
+}
+
+// localShellDialect reports the dialect of /bin/sh, which local commands
+// run in, from the shell it links to
+func localShellDialect() string {
+	sh := "/bin/sh"
+	switch runtime.GOOS {
+	case "freebsd":
+		return "ash"
+	case "darwin":
+		// /bin/sh runs the shell /private/var/select/sh links to, bash
+		// unless changed
+		sh = "/private/var/select/sh"
+		if _, err := os.Lstat(sh); err != nil {
+			return "bash"
+		}
+	}
+	resolved, err := filepath.EvalSymlinks(sh)
+	if err != nil {
+		return "sh"
+	}
+	name := filepath.Base(resolved)
+	for _, dialect := range []string{"bash", "zsh", "ksh", "dash"} {
+		if strings.Contains(name, dialect) {
+			return dialect
+		}
+	}
+	if strings.Contains(name, "busybox") {
+		return "ash"
+	}
+	return "sh"
+}
+
+// lookPathIn finds an executable file named name in the directories of
+// path, like exec.LookPath with a PATH other than sink's own
+func lookPathIn(name, path string) string {
+	for _, dir := range filepath.SplitList(path) {
+		if dir == "" {
+			dir = "."
+		}
+		file := filepath.Join(dir, name)
+		if info, err := os.Stat(file); err == nil && !info.IsDir() && info.Mode()&0111 != 0 {
+			return file
+		}
+	}
+	return ""
+}
+
+// parsePreflight applies the key=value lines printed by preflightScript
+func parsePreflight(output string, ctx *ExecutionContext) {
+	for _, line := range strings.Split(output, "\n") {
+		key, value, ok := strings.Cut(strings.TrimRight(line, "\r"), "=")
+		value = strings.TrimSpace(value)
+		if !ok || value == "" {
+			continue
+		}
+		switch key {
+		case "login_shell":
+			ctx.LoginShell = value
+		case "shell_dialect":
+			ctx.ShellDialect = value
+		case "path":
+			ctx.Path = value
+		case "home":
+			ctx.Home = value
+		case "sudo":
+			ctx.Sudo = value
+		case "cpu_count":
+			if n, err := strconv.Atoi(value); err == nil && ctx.CPUCount == 0 {
+				ctx.CPUCount = n
+			}
+		case "package_manager":
+			ctx.PackageManagers = append(ctx.PackageManagers, value)
+		case "selinux":
+			ctx.SELinux = strings.ToLower(value)
+		case "firewall":
+			ctx.Firewall = value
+		case "container":
+			if value == "kubepods" {
+				value = "kubernetes"
+			}
+			ctx.Container = value
+		}
+	}
+}
+
+// preflightFacts exposes the host environment as {{.sink.*}} facts
+func (ctx ExecutionContext) preflightFacts() map[string]string {
+	facts := map[string]string{
+		"login_shell":      ctx.LoginShell,
+		"shell_dialect":    ctx.ShellDialect,
+		"path":             ctx.Path,
+		"package_managers": strings.Join(ctx.PackageManagers, " "),
+		"package_manager":  "",
+		"package_install":  "",
+		"selinux":          ctx.SELinux,
+		"firewall":         ctx.Firewall,
+		"container":        ctx.Container,
+	}
+	if len(ctx.PackageManagers) > 0 {
+		facts["package_manager"] = ctx.PackageManagers[0]
+		facts["package_install"] = packageInstallCommands[ctx.PackageManagers[0]]
+	}
+	return facts
+}
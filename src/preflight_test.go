@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestPreflight tests that the host environment reaches the context, events and {{.sink.*}} facts
+func TestPreflight(t *testing.T) {
+	transport := &MockTransport{responses: map[string]MockResponse{
+		preflightScript: {stdout: "login_shell=/bin/zsh\npath=/usr/local/bin:/usr/bin\npackage_manager=dnf\npackage_manager=yum\n" +
+			"selinux=Enforcing\nfirewall=firewalld\ncontainer=kubepods\n"},
+	}}
+	executor := NewExecutor(transport)
+
+	ctx := executor.GetContext()
+	if ctx.LoginShell != "/bin/zsh" || ctx.Path != "/usr/local/bin:/usr/bin" || strings.Join(ctx.PackageManagers, ",") != "dnf,yum" ||
+		ctx.SELinux != "enforcing" || ctx.Firewall != "firewalld" || ctx.Container != "kubernetes" {
+		t.Errorf("unexpected context %+v", ctx)
+	}
+
+	var event ExecutionEvent
+	executor.OnEvent = func(e ExecutionEvent) { event = e }
+	step := InstallStep{Name: "install", Step: CommandStep{Command: "{{.sink.package_manager}} install -y git ({{.sink.container}}, selinux {{.sink.selinux}})"}}
+	facts := executor.stepFacts(step, Facts{})
+	command, err := executor.interpolate(step.Step.(CommandStep).Command, facts)
+	if err != nil || command != "dnf install -y git (kubernetes, selinux enforcing)" {
+		t.Errorf("interpolated %q, %v", command, err)
+	}
+
+	executor.ExecuteStep(InstallStep{Name: "noop", Step: CommandStep{Command: "true"}}, Facts{})
+	data, _ := json.Marshal(event)
+	if !strings.Contains(string(data), `"package_managers":["dnf","yum"]`) || !strings.Contains(string(data), `"container":"kubernetes"`) {
+		t.Errorf("expected events to carry the host environment: %s", data)
+	}
+}
+
+// TestPreflight_Unavailable tests that a failed probe leaves the fields empty
+func TestPreflight_Unavailable(t *testing.T) {
+	executor := NewExecutor(&MockTransport{responses: map[string]MockResponse{}})
+	ctx := executor.GetContext()
+	if ctx.LoginShell != "" || len(ctx.PackageManagers) != 0 || ctx.Container != "" {
+		t.Errorf("expected an empty environment, got %+v", ctx)
+	}
+	facts := executor.stepFacts(InstallStep{Name: "a"}, Facts{})[sinkFactsKey].(map[string]interface{})
+	if value, ok := facts["package_manager"]; !ok || value != "" {
+		t.Errorf("expected package_manager to be defined and empty, got %q, %v", value, ok)
+	}
+}
+
+// TestPreflight_BSD tests the FreeBSD and OpenBSD package managers, firewalls and jails
+func TestPreflight_BSD(t *testing.T) {
+	var ctx ExecutionContext
+	parsePreflight("shell_dialect=ash\npackage_manager=pkg\nfirewall=pf\ncontainer=jail\n", &ctx)
+	facts := ctx.preflightFacts()
+	if facts["package_install"] != "pkg install -y" || facts["firewall"] != "pf" || facts["container"] != "jail" {
+		t.Errorf("unexpected facts %v", facts)
+	}
+
+	ctx = ExecutionContext{}
+	parsePreflight("package_manager=pkg_add\n", &ctx)
+	if got := ctx.preflightFacts()["package_install"]; got != "pkg_add -I" {
+		t.Errorf("package_install = %q", got)
+	}
+	for _, pm := range packageManagers {
+		if packageInstallCommands[pm] == "" {
+			t.Errorf("no install command for %s", pm)
+		}
+	}
+}
+
+// TestDiscoverLocalPreflight tests that the local host's environment, read
+// without a shell, matches what preflightScript reports
+func TestDiscoverLocalPreflight(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("preflightScript needs a POSIX shell")
+	}
+	transport := NewLocalTransport()
+	stdout, _, exitCode, err := transport.Run(preflightScript)
+	if err != nil || exitCode != 0 {
+		t.Skipf("preflight script failed: %v", err)
+	}
+	var probed, local ExecutionContext
+	parsePreflight(stdout, &probed)
+	discoverLocalPreflight(&local, transport)
+	if local.LoginShell != probed.LoginShell || local.ShellDialect != probed.ShellDialect || local.Path != probed.Path ||
+		local.Home != probed.Home || local.Sudo != probed.Sudo || strings.Join(local.PackageManagers, ",") != strings.Join(probed.PackageManagers, ",") ||
+		local.SELinux != probed.SELinux || local.Firewall != probed.Firewall || local.Container != probed.Container {
+		t.Errorf("local preflight %+v differs from the script's %+v", local, probed)
+	}
+
+	// Tools are found on the transport's PATH, not sink's
+	dir := t.TempDir()
+	for _, name := range []string{"apk", "nix-env"} {
+		os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"), 0755)
+	}
+	os.WriteFile(filepath.Join(dir, "brew"), []byte("not executable"), 0644)
+	transport.Env = []string{"PATH=" + dir, "HOME=/home/ci", "container=lxc"}
+	local = ExecutionContext{}
+	discoverLocalPreflight(&local, transport)
+	if strings.Join(local.PackageManagers, ",") != "apk,nix-env" || local.Home != "/home/ci" || local.Path != dir {
+		t.Errorf("expected the transport's environment, got %+v", local)
+	}
+
+	// A tool that hangs is given up on
+	orig := preflightProbeTimeout
+	preflightProbeTimeout = 100 * time.Millisecond
+	defer func() { preflightProbeTimeout = orig }()
+	sleep, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Skip("no sleep command")
+	}
+	os.WriteFile(filepath.Join(dir, "getenforce"), []byte("#!/bin/sh\necho Enforcing\n"+sleep+" 30\n"), 0755)
+	local = ExecutionContext{}
+	start := time.Now()
+	discoverLocalPreflight(&local, transport)
+	if elapsed := time.Since(start); elapsed > 5*time.Second || local.SELinux != "" {
+		t.Errorf("expected the hung probe to time out, took %s with selinux %q", elapsed, local.SELinux)
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// probeDialTimeout bounds how long check_port waits for a connection
+const probeDialTimeout = 2 * time.Second
+
+// CheckProbe is a check sink evaluates itself rather than through a shell,
+// so simple checks behave the same on every transport and shell, cmd.exe
+// included. A check step sets exactly one of these or "check".
+type CheckProbe struct {
+	File          string `json:"check_file"`           // Passes if the path exists (templated)
+	CommandExists string `json:"check_command_exists"` // Passes if the command is found on PATH (templated)
+	Port          int    `json:"check_port"`           // Passes if something accepts TCP connections on localhost:port
+}
+
+// isSet reports whether the probe replaces a check command
+func (p CheckProbe) isSet() bool {
+	return p.File != "" || p.CommandExists != "" || p.Port != 0
+}
+
+// String describes the probe for logs and sink explain
+func (p CheckProbe) String() string {
+	switch {
+	case p.File != "":
+		return fmt.Sprintf("file %s exists", p.File)
+	case p.CommandExists != "":
+		return fmt.Sprintf("command %s exists", p.CommandExists)
+	case p.Port != 0:
+		return fmt.Sprintf("port %d is open", p.Port)
+	}
+	return ""
+}
+
+// validateCheckForm checks that a check step uses exactly one form of check
+func validateCheckForm(check string, probe CheckProbe, expectOutput *string) error {
+	forms := 0
+	for _, set := range []bool{check != "", probe.File != "", probe.CommandExists != "", probe.Port != 0} {
+		if set {
+			forms++
+		}
+	}
+	if forms != 1 {
+		return fmt.Errorf("use exactly one of check, check_file, check_command_exists and check_port")
+	}
+	if probe.Port < 0 || probe.Port > 65535 {
+		return fmt.Errorf("check_port must be between 1 and 65535")
+	}
+	if probe.isSet() && expectOutput != nil {
+		return fmt.Errorf("expect_output needs a check command")
+	}
+	return nil
+}
+
+// ProbeTransport is implemented by transports that evaluate check probes
+// natively. Others run the equivalent command for their shell.
+type ProbeTransport interface {
+	Transport
+	Probe(probe CheckProbe) bool
+}
+
+// Probe evaluates a check probe on this machine without a shell. Relative
+// file paths are resolved against WorkDir and commands are looked up on the
+// transport's PATH.
+func (lt *LocalTransport) Probe(probe CheckProbe) bool {
+	switch {
+	case probe.File != "":
+		path := probe.File
+		if lt.WorkDir != "" && !filepath.IsAbs(path) {
+			path = filepath.Join(lt.WorkDir, path)
+		}
+		_, err := os.Stat(path)
+		return err == nil
+	case probe.CommandExists != "":
+		return lt.lookPath(probe.CommandExists)
+	case probe.Port != 0:
+		conn, err := net.DialTimeout("tcp", net.JoinHostPort("localhost", strconv.Itoa(probe.Port)), probeDialTimeout)
+		if err != nil {
+			return false
+		}
+		conn.Close()
+		return true
+	}
+	return false
+}
+
+// lookPath reports whether name is an executable on the PATH commands run
+// with, which is Env's when the transport sets one
+func (lt *LocalTransport) lookPath(name string) bool {
+	if lt.Env == nil || strings.ContainsAny(name, `/\`) {
+		_, err := exec.LookPath(name)
+		return err == nil
+	}
+	for _, entry := range lt.Env {
+		if value, ok := strings.CutPrefix(entry, "PATH="); ok {
+			for _, dir := range filepath.SplitList(value) {
+				if _, err := exec.LookPath(filepath.Join(dir, name)); err == nil {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	return false
+}
+
+// probeCommand is the command that evaluates a probe with shell, for
+// transports that cannot evaluate probes natively
+func probeCommand(probe CheckProbe, shell string) string {
+	port := strconv.Itoa(probe.Port)
+	switch shell {
+	case ShellCmd:
+		switch {
+		case probe.File != "":
+			return fmt.Sprintf(`if exist "%s" (exit 0) else (exit 1)`, probe.File)
+		case probe.CommandExists != "":
+			return fmt.Sprintf(`where "%s" >nul 2>&1`, probe.CommandExists)
+		default:
+			return `powershell -NoProfile -Command "if ((Test-NetConnection localhost -Port ` + port + ` -WarningAction SilentlyContinue).TcpTestSucceeded) { exit 0 } else { exit 1 }"`
+		}
+	case ShellPowerShell:
+		switch {
+		case probe.File != "":
+			return fmt.Sprintf("if (Test-Path -LiteralPath %s) { exit 0 } else { exit 1 }", powerShellQuote(probe.File))
+		case probe.CommandExists != "":
+			return fmt.Sprintf("if (Get-Command %s -ErrorAction SilentlyContinue) { exit 0 } else { exit 1 }", powerShellQuote(probe.CommandExists))
+		default:
+			return "if ((Test-NetConnection localhost -Port " + port + " -WarningAction SilentlyContinue).TcpTestSucceeded) { exit 0 } else { exit 1 }"
+		}
+	default:
+		switch {
+		case probe.File != "":
+			return "test -e " + shellQuote(probe.File)
+		case probe.CommandExists != "":
+			return "command -v " + shellQuote(probe.CommandExists) + " >/dev/null 2>&1"
+		default:
+			return "nc -z localhost " + port
+		}
+	}
+}
+
+// powerShellQuote quotes s as a PowerShell literal string
+func powerShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// interpolateCheck fills the templates of a check step's command or probe
+func (e *Executor) interpolateCheck(check string, probe CheckProbe, facts Facts) (string, CheckProbe, error) {
+	checkCmd, err := e.interpolate(check, facts)
+	if err != nil {
+		return "", probe, err
+	}
+	probe, err = e.interpolateProbe(probe, facts)
+	return checkCmd, probe, err
+}
+
+// describeCheck is a check's command, or the description of its probe
+func describeCheck(checkCmd string, probe CheckProbe) string {
+	if probe.isSet() {
+		return probe.String()
+	}
+	return checkCmd
+}
+
+// interpolateProbe fills the templates of a probe's file and command
+func (e *Executor) interpolateProbe(probe CheckProbe, facts Facts) (CheckProbe, error) {
+	var err error
+	if probe.File, err = e.interpolate(probe.File, facts); err != nil {
+		return probe, err
+	}
+	probe.CommandExists, err = e.interpolate(probe.CommandExists, facts)
+	return probe, err
+}
+
+// runProbe evaluates a probe natively when the transport can, or with the
+// equivalent command for its shell
+func (e *Executor) runProbe(probe CheckProbe) bool {
+	if pt, ok := e.transport.(ProbeTransport); ok {
+		started := time.Now()
+		passed := pt.Probe(probe)
+		exitCode := 0
+		if !passed {
+			exitCode = 1
+		}
+		e.Transcript.recordCommand("probe: "+probe.String(), "", "", exitCode, time.Since(started))
+		e.audit("probe: "+probe.String(), exitCode, time.Since(started))
+		return passed
+	}
+	_, _, exitCode, _ := e.run(probeCommand(probe, transportCapabilities(e.transport).Shell))
+	return exitCode == 0
+}
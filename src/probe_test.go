@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestCheckProbeParsing tests choosing check variants from probe keys and
+// rejecting ambiguous checks
+func TestCheckProbeParsing(t *testing.T) {
+	var step InstallStep
+	if err := json.Unmarshal([]byte(`{"name": "jq", "check_file": "/usr/local/bin/jq", "error": "jq missing"}`), &step); err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if check, ok := step.Step.(CheckErrorStep); !ok || check.File != "/usr/local/bin/jq" {
+		t.Errorf("expected a check-error step with a file probe, got %#v", step.Step)
+	}
+	if err := json.Unmarshal([]byte(`{"name": "brew", "check_command_exists": "brew", "on_missing": [{"name": "install", "command": "true"}]}`), &step); err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if check, ok := step.Step.(CheckRemediateStep); !ok || check.CommandExists != "brew" {
+		t.Errorf("expected a check-remediate step with a command probe, got %#v", step.Step)
+	}
+
+	tests := []struct {
+		step string
+		err  string
+	}{
+		{`{"name": "x", "check": "true", "check_port": 80, "error": "e"}`, "exactly one of"},
+		{`{"name": "x", "check_port": 70000, "error": "e"}`, "between 1 and 65535"},
+		{`{"name": "x", "check_file": "/x", "expect_output": "y", "error": "e"}`, "expect_output needs a check command"},
+	}
+	for _, tt := range tests {
+		if err := json.Unmarshal([]byte(tt.step), &InstallStep{}); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.step, tt.err, err)
+		}
+	}
+}
+
+// TestLocalTransportProbe tests evaluating probes natively
+func TestLocalTransportProbe(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "present"), nil, 0644)
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedPort := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	listener, err = net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	openPort := listener.Addr().(*net.TCPAddr).Port
+
+	lt := &LocalTransport{WorkDir: dir}
+	tests := []struct {
+		probe CheckProbe
+		want  bool
+	}{
+		{CheckProbe{File: filepath.Join(dir, "present")}, true},
+		{CheckProbe{File: "present"}, true},
+		{CheckProbe{File: "absent"}, false},
+		{CheckProbe{CommandExists: "sink-no-such-command"}, false},
+		{CheckProbe{Port: openPort}, true},
+		{CheckProbe{Port: closedPort}, false},
+	}
+	for _, tt := range tests {
+		if got := lt.Probe(tt.probe); got != tt.want {
+			t.Errorf("%s: got %t, want %t", tt.probe, got, tt.want)
+		}
+	}
+
+	// Commands are looked up on the transport's own PATH
+	bin := t.TempDir()
+	os.WriteFile(filepath.Join(bin, "sink-probe-tool"), []byte("#!/bin/sh\n"), 0755)
+	withPath := &LocalTransport{Env: []string{"PATH=" + bin}}
+	if !withPath.Probe(CheckProbe{CommandExists: "sink-probe-tool"}) {
+		t.Error("expected the command to be found on the transport's PATH")
+	}
+	if lt.Probe(CheckProbe{CommandExists: "sink-probe-tool"}) {
+		t.Error("expected the command not to be found on sink's PATH")
+	}
+}
+
+// TestProbeFallback tests that transports without native probes run the
+// equivalent command for their shell
+func TestProbeFallback(t *testing.T) {
+	mock := &MockTransport{responses: map[string]MockResponse{
+		"test -e '/etc/app.conf'": {exitCode: 0},
+	}}
+	executor := NewExecutor(mock)
+	step := InstallStep{Name: "Config", Step: CheckErrorStep{Error: "no config", CheckProbe: CheckProbe{File: "/etc/{{.app}}.conf"}}}
+	if result := executor.ExecuteStep(step, Facts{"app": "app"}); result.Error != "" {
+		t.Errorf("expected the check to pass, got %q", result.Error)
+	}
+	step.Step = CheckErrorStep{Error: "no brew", CheckProbe: CheckProbe{CommandExists: "brew"}}
+	if result := executor.ExecuteStep(step, nil); result.Error != "no brew" {
+		t.Errorf("expected the check to fail, got %+v", result)
+	}
+
+	if got := probeCommand(CheckProbe{File: `C:\Tools\jq.exe`}, ShellCmd); got != `if exist "C:\Tools\jq.exe" (exit 0) else (exit 1)` {
+		t.Errorf("unexpected cmd probe: %s", got)
+	}
+	if got := probeCommand(CheckProbe{CommandExists: "it's"}, ShellPowerShell); got != `if (Get-Command 'it''s' -ErrorAction SilentlyContinue) { exit 0 } else { exit 1 }` {
+		t.Errorf("unexpected PowerShell probe: %s", got)
+	}
+	if got := probeCommand(CheckProbe{Port: 8080}, ShellPOSIX); got != "nc -z localhost 8080" {
+		t.Errorf("unexpected POSIX probe: %s", got)
+	}
+}
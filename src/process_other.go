@@ -0,0 +1,19 @@
+//go:build windows || plan9
+
+package main
+
+import "os"
+
+// processAlive reports whether a process with this PID exists. Finding a
+// process fails on Windows once it has exited.
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	p.Release()
+	return true
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// progressTick is how often the progress UI redraws to advance timers
+const progressTick = 250 * time.Millisecond
+
+// ProgressUI draws a full-screen view of a run (execute --ui): the step
+// list with statuses and elapsed times, counts, and the output of steps as
+// they finish. It is driven by executor events and a ticker.
+type ProgressUI struct {
+	Title string
+	Out   io.Writer
+
+	mu      sync.Mutex
+	steps   []progressStep
+	output  []string // Output lines of finished steps, newest last
+	started time.Time
+	now     func() time.Time
+	size    func() (rows, cols int)
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+// progressStep is one row of the step list
+type progressStep struct {
+	Name     string
+	Status   string // "" until the step starts, then an event status
+	Started  time.Time
+	Duration time.Duration
+}
+
+// NewProgressUI creates a progress view for the steps of a platform
+func NewProgressUI(title string, steps []InstallStep, out io.Writer) *ProgressUI {
+	ui := &ProgressUI{Title: title, Out: out, now: time.Now, size: terminalSize}
+	for _, step := range steps {
+		ui.steps = append(ui.steps, progressStep{Name: step.Name})
+	}
+	return ui
+}
+
+// Start switches to the alternate screen and redraws until Stop. An
+// interrupt restores the screen before exiting.
+func (ui *ProgressUI) Start() {
+	ui.started = ui.now()
+	ui.done = make(chan struct{})
+	ui.stopped = make(chan struct{})
+	fmt.Fprint(ui.Out, ansiAltScreen+ansiHideCursor)
+	ui.draw()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		defer close(ui.stopped)
+		defer signal.Stop(signals)
+		ticker := time.NewTicker(progressTick)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ui.done:
+				return
+			case <-signals:
+				fmt.Fprint(ui.Out, ansiShowCursor+ansiMainScreen)
+				fmt.Fprintln(os.Stderr, "Interrupted")
+				os.Exit(130)
+			case <-ticker.C:
+				ui.draw()
+			}
+		}
+	}()
+}
+
+// Stop ends the redraws and returns to the main screen
+func (ui *ProgressUI) Stop() {
+	close(ui.done)
+	<-ui.stopped
+	fmt.Fprint(ui.Out, ansiShowCursor+ansiMainScreen)
+}
+
+// HandleEvent records an execution event and redraws; it is meant to be
+// the executor's OnEvent
+func (ui *ProgressUI) HandleEvent(event ExecutionEvent) {
+	ui.mu.Lock()
+	ui.record(event)
+	ui.mu.Unlock()
+	ui.draw()
+}
+
+// record applies an event to the step list and output pane
+func (ui *ProgressUI) record(event ExecutionEvent) {
+	// Retried attempts leave the step running
+	if event.Status == "retrying" {
+		return
+	}
+
+	// Rolling a step back leaves its status alone
+	if event.Status == "rollback" {
+		if event.Error != "" {
+			ui.output = append(ui.output, "✗ Undo "+event.StepName+": "+event.Error)
+		} else {
+			ui.output = append(ui.output, "↩ Undo "+event.StepName)
+		}
+		return
+	}
+
+	// Remediations run inside their parent step
+	if event.ParentStep != "" {
+		switch event.Status {
+		case "running":
+			ui.output = append(ui.output, "  → "+event.StepName)
+		case "failed":
+			ui.output = append(ui.output, "  ✗ "+event.StepName+": "+event.Error)
+		}
+		return
+	}
+
+	i := ui.stepIndex(event.StepName, event.Status == "running")
+	if i < 0 {
+		return
+	}
+	step := &ui.steps[i]
+	now := ui.now()
+	if event.Status == "running" {
+		step.Status, step.Started = "running", now
+		return
+	}
+	if step.Status == "running" {
+		step.Duration = now.Sub(step.Started)
+	}
+	step.Status = event.Status
+
+	var lines []string
+	for _, text := range []string{event.Error, event.Output} {
+		if text = strings.TrimRight(text, "\n"); text != "" {
+			lines = append(lines, strings.Split(text, "\n")...)
+		}
+	}
+	if len(lines) > 0 {
+		ui.output = append(ui.output, progressIcon(step.Status)+" "+step.Name)
+		for _, line := range lines {
+			ui.output = append(ui.output, "    "+line)
+		}
+	}
+}
+
+// stepIndex finds the row an event is about: a step not yet started for a
+// "running" event, otherwise the running (or held pending) step, falling
+// back to one not yet started for steps reported without running
+func (ui *ProgressUI) stepIndex(name string, starting bool) int {
+	waiting := -1
+	for i, step := range ui.steps {
+		if step.Name != name {
+			continue
+		}
+		if !starting && (step.Status == "running" || step.Status == "pending") {
+			return i
+		}
+		if step.Status == "" && waiting < 0 {
+			waiting = i
+		}
+	}
+	return waiting
+}
+
+// draw renders the screen at the terminal's current size
+func (ui *ProgressUI) draw() {
+	rows, cols := ui.size()
+	ui.mu.Lock()
+	screen := ui.render(rows, cols)
+	ui.mu.Unlock()
+	fmt.Fprint(ui.Out, screen)
+}
+
+// render draws the header with counts, the step list (scrolled to keep
+// the running step visible) and as much of the latest output as fits
+func (ui *ProgressUI) render(rows, cols int) string {
+	var b strings.Builder
+	line := func(text string) {
+		b.WriteString(truncateRunes(text, cols) + ansiClearLine + "\r\n")
+	}
+	now := ui.now()
+
+	counts := map[string]int{}
+	current := -1
+	for i, step := range ui.steps {
+		counts[step.Status]++
+		if step.Status == "running" || (current < 0 && step.Status == "") {
+			current = i
+		}
+	}
+	finished := len(ui.steps) - counts[""] - counts["running"] - counts["pending"]
+
+	b.WriteString(ansiHome)
+	line(fmt.Sprintf(" %s   ⏱ %s", ui.Title, formatTranscriptDuration(now.Sub(ui.started).Truncate(time.Second))))
+	line(fmt.Sprintf(" %d/%d done · %d ✓ · %d ✗ · %d skipped", finished, len(ui.steps),
+		counts["success"], counts["failed"], counts["skipped"]+counts["deferred"]+counts["not_run"]))
+	line("")
+
+	// The step list takes at most half the screen below the header
+	listRows := min(len(ui.steps), max((rows-4)/2, 1))
+	offset := 0
+	if current >= listRows {
+		offset = min(current-listRows/2, len(ui.steps)-listRows)
+	}
+	nameWidth := 0
+	for _, step := range ui.steps {
+		nameWidth = max(nameWidth, min(len([]rune(step.Name)), summaryMaxStep))
+	}
+	for _, step := range ui.steps[offset : offset+listRows] {
+		elapsed := ""
+		switch {
+		case step.Status == "running":
+			elapsed = formatTranscriptDuration(now.Sub(step.Started).Truncate(time.Second)) + "…"
+		case step.Duration > 0:
+			elapsed = formatTranscriptDuration(step.Duration)
+		}
+		row := fmt.Sprintf(" %s %-*s  %s", progressIcon(step.Status), nameWidth, truncateRunes(step.Name, summaryMaxStep), elapsed)
+		line(strings.TrimRight(row, " "))
+	}
+
+	b.WriteString(ansiDim + strings.Repeat("─", cols) + ansiReset + ansiClearLine + "\r\n")
+	// The last row stays empty so the final line break does not scroll
+	outputRows := max(rows-5-listRows, 0)
+	start := max(len(ui.output)-outputRows, 0)
+	for _, text := range ui.output[start:] {
+		line(text)
+	}
+	b.WriteString(ansiClearDown)
+	return b.String()
+}
+
+// progressIcon marks a step's status in the step list
+func progressIcon(status string) string {
+	switch status {
+	case "running":
+		return "▶"
+	case "success":
+		return "✓"
+	case "failed":
+		return "✗"
+	case "skipped":
+		return "⊘"
+	case "deferred":
+		return "⏸"
+	case "pending":
+		return "↻"
+	case "not_run":
+		return "-"
+	}
+	return "·"
+}
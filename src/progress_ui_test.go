@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestProgressUI tests that events update the step list, counts and output pane
+func TestProgressUI(t *testing.T) {
+	clock := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	var out bytes.Buffer
+	ui := NewProgressUI("sink execute — Linux", []InstallStep{
+		{Name: "Install tools"}, {Name: "Check disk"}, {Name: "Configure"}, {Name: "Finish"},
+	}, &out)
+	ui.now = func() time.Time { return clock }
+	ui.size = func() (int, int) { return 20, 80 }
+	ui.Start()
+
+	ui.HandleEvent(ExecutionEvent{StepName: "Install tools", Status: "running"})
+	clock = clock.Add(3 * time.Second)
+	ui.HandleEvent(ExecutionEvent{StepName: "Install tools", Status: "success", Output: "installed jq\ninstalled git\n"})
+	ui.HandleEvent(ExecutionEvent{StepName: "Check disk", Status: "running"})
+	ui.HandleEvent(ExecutionEvent{StepName: "Check disk", Status: "pending", Error: "disk low"})
+	ui.HandleEvent(ExecutionEvent{StepName: "Configure", Status: "running"})
+	ui.HandleEvent(ExecutionEvent{StepName: "Free space", ParentStep: "Configure", Status: "running"})
+	clock = clock.Add(2 * time.Second)
+
+	screen := ui.render(20, 80)
+	for _, want := range []string{
+		" sink execute — Linux   ⏱ 5s",
+		" 1/4 done · 1 ✓ · 0 ✗ · 0 skipped",
+		" ✓ Install tools  3s",
+		" ↻ Check disk",
+		" ▶ Configure      2s…",
+		" · Finish",
+		"✓ Install tools\x1b[K\r\n    installed jq\x1b[K\r\n    installed git",
+		"↻ Check disk\x1b[K\r\n    disk low",
+		"  → Free space",
+	} {
+		if !strings.Contains(screen, want) {
+			t.Errorf("screen missing %q:\n%s", want, screen)
+		}
+	}
+
+	// A re-check resolves the pending step
+	ui.HandleEvent(ExecutionEvent{StepName: "Check disk", Status: "success"})
+	ui.HandleEvent(ExecutionEvent{StepName: "Configure", Status: "failed", Error: "exit 1"})
+	ui.HandleEvent(ExecutionEvent{StepName: "Finish", Status: "not_run"})
+	ui.Stop()
+
+	screen = ui.render(20, 80)
+	if !strings.Contains(screen, " 4/4 done · 2 ✓ · 1 ✗ · 1 skipped") {
+		t.Errorf("unexpected counts:\n%s", screen)
+	}
+	if !strings.HasPrefix(out.String(), ansiAltScreen) || !strings.HasSuffix(out.String(), ansiMainScreen) {
+		t.Error("expected the UI to switch to the alternate screen and back")
+	}
+}
+
+// TestProgressUI_Fit tests that the screen fits the terminal with many steps and long output
+func TestProgressUI_Fit(t *testing.T) {
+	var steps []InstallStep
+	for i := 0; i < 30; i++ {
+		steps = append(steps, InstallStep{Name: "step " + string(rune('a'+i))})
+	}
+	ui := NewProgressUI("run", steps, &bytes.Buffer{})
+	ui.now = time.Now
+	for i := 0; i < 20; i++ {
+		ui.record(ExecutionEvent{StepName: steps[i].Name, Status: "running"})
+		ui.record(ExecutionEvent{StepName: steps[i].Name, Status: "success", Output: "line 1\nline 2"})
+	}
+	ui.record(ExecutionEvent{StepName: steps[20].Name, Status: "running"})
+
+	screen := ui.render(24, 80)
+	if lines := strings.Count(screen, "\r\n"); lines != 23 {
+		t.Errorf("expected 23 line breaks on a 24-row terminal, got %d", lines)
+	}
+	if !strings.Contains(screen, "▶ "+steps[20].Name) {
+		t.Errorf("expected the running step to be visible:\n%s", screen)
+	}
+	if !strings.Contains(screen, "    line 2\x1b[K\r\n\x1b[J") {
+		t.Errorf("expected the output pane to show the latest output:\n%s", screen)
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+var (
+	// Rate such as "10/min": a positive count per second, minute or hour
+	rateLimitRegex = regexp.MustCompile(`^([1-9][0-9]*)/(s|sec|second|m|min|minute|h|hour)$`)
+
+	// Named rate limit group, also used as a state file name
+	rateGroupRegex = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+)
+
+// How long to wait for another process holding a group's lock, and when a
+// lock left behind by a killed process is considered stale
+const (
+	rateLockTimeout = 10 * time.Second
+	rateLockStale   = 30 * time.Second
+)
+
+// RateLimit allows Count commands per Per
+type RateLimit struct {
+	Count int
+	Per   time.Duration
+}
+
+// parseRateLimit parses a rate such as "10/min" or "1/s"
+func parseRateLimit(value string) (RateLimit, error) {
+	m := rateLimitRegex.FindStringSubmatch(value)
+	if m == nil {
+		return RateLimit{}, fmt.Errorf("invalid rate '%s' (expected <count>/<s|min|hour>, e.g. \"10/min\")", value)
+	}
+	count, err := strconv.Atoi(m[1])
+	if err != nil {
+		return RateLimit{}, fmt.Errorf("invalid rate '%s': %w", value, err)
+	}
+	per := time.Second
+	switch m[2] {
+	case "m", "min", "minute":
+		per = time.Minute
+	case "h", "hour":
+		per = time.Hour
+	}
+	return RateLimit{Count: count, Per: per}, nil
+}
+
+// validateRateLimits checks the config's rate_limits groups and that every
+// step's rate_limit names one of them or is a rate itself
+func validateRateLimits(groups map[string]string, steps []InstallStep) error {
+	for _, name := range sortedKeys(groups) {
+		if !rateGroupRegex.MatchString(name) {
+			return fmt.Errorf("rate_limits: invalid group name '%s' (use lowercase letters, digits, - and _)", name)
+		}
+		if _, err := parseRateLimit(groups[name]); err != nil {
+			return fmt.Errorf("rate_limits: %s: %w", name, err)
+		}
+	}
+
+	check := func(spec string) error {
+		if spec == "" {
+			return nil
+		}
+		if _, ok := groups[spec]; ok {
+			return nil
+		}
+		if _, err := parseRateLimit(spec); err != nil {
+			return fmt.Errorf("rate_limit: '%s' is neither a rate_limits group nor a rate such as \"10/min\"", spec)
+		}
+		return nil
+	}
+	for i, step := range steps {
+		switch v := step.Step.(type) {
+		case CommandStep:
+			if err := check(v.RateLimit); err != nil {
+				return fmt.Errorf("install_step[%d] %s: %w", i, step.Name, err)
+			}
+		case CheckRemediateStep:
+			for j, rem := range v.OnMissing {
+				if err := check(rem.RateLimit); err != nil {
+					return fmt.Errorf("install_step[%d] %s: on_missing[%d] %s: %w", i, step.Name, j, rem.Name, err)
+				}
+			}
+		case GroupStep:
+			if err := validateRateLimits(groups, v.Group.Steps); err != nil {
+				return fmt.Errorf("install_step[%d] %s: group: %w", i, step.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// RateLimiter throttles the commands of rate-limited steps. Each group keeps
+// the start times of its recent commands in a file under the state
+// directory, so one budget covers every attempt of a retried step, every
+// step in the group and concurrent sink runs sharing the state directory.
+type RateLimiter struct {
+	Dir    string               // Directory holding the per-group state files
+	Groups map[string]RateLimit // Named groups from the config's rate_limits
+	now    func() time.Time
+	sleep  func(time.Duration)
+}
+
+// NewRateLimiter parses the config's rate_limits groups. The state directory
+// is resolved on first use, so runs without rate limits never need it.
+func NewRateLimiter(groups map[string]string) (*RateLimiter, error) {
+	limiter := &RateLimiter{
+		Groups: map[string]RateLimit{},
+		now:    time.Now,
+		sleep:  time.Sleep,
+	}
+	for name, value := range groups {
+		limit, err := parseRateLimit(value)
+		if err != nil {
+			return nil, fmt.Errorf("rate_limits: %s: %w", name, err)
+		}
+		limiter.Groups[name] = limit
+	}
+	return limiter, nil
+}
+
+// resolve maps a step's rate_limit to a group: a named group from
+// rate_limits, or an inline rate private to the step
+func (l *RateLimiter) resolve(spec, stepName string) (string, RateLimit, error) {
+	if limit, ok := l.Groups[spec]; ok {
+		return spec, limit, nil
+	}
+	limit, err := parseRateLimit(spec)
+	if err != nil {
+		return "", RateLimit{}, err
+	}
+	sum := sha256.Sum256([]byte(stepName))
+	return "step-" + hex.EncodeToString(sum[:6]), limit, nil
+}
+
+// Wait blocks until the rate limit allows another command and records it.
+// It returns how long it waited.
+func (l *RateLimiter) Wait(spec, stepName string) (time.Duration, error) {
+	group, limit, err := l.resolve(spec, stepName)
+	if err != nil {
+		return 0, err
+	}
+	if l.Dir == "" {
+		dir, err := statePath("ratelimit")
+		if err != nil {
+			return 0, err
+		}
+		l.Dir = dir
+	}
+	if err := os.MkdirAll(l.Dir, 0700); err != nil {
+		return 0, err
+	}
+
+	var waited time.Duration
+	for {
+		delay, err := l.take(group, limit)
+		if err != nil {
+			return waited, err
+		}
+		if delay <= 0 {
+			return waited, nil
+		}
+		l.sleep(delay)
+		waited += delay
+	}
+}
+
+// take records a command if the group's window has room, otherwise returns
+// how long until the oldest recorded command leaves the window
+func (l *RateLimiter) take(group string, limit RateLimit) (time.Duration, error) {
+	path := filepath.Join(l.Dir, group+".json")
+	unlock, err := l.lock(path + ".lock")
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	var starts []int64
+	if data, err := os.ReadFile(path); err == nil {
+		// A corrupt state file only loses the history
+		json.Unmarshal(data, &starts)
+	}
+
+	now := l.now()
+	cutoff := now.Add(-limit.Per).UnixNano()
+	recent := starts[:0]
+	for _, start := range starts {
+		if start > cutoff {
+			recent = append(recent, start)
+		}
+	}
+	if len(recent) >= limit.Count {
+		oldest := recent[len(recent)-limit.Count]
+		return time.Duration(oldest - cutoff), nil
+	}
+
+	recent = append(recent, now.UnixNano())
+	data, err := json.Marshal(recent)
+	if err != nil {
+		return 0, err
+	}
+	return 0, os.WriteFile(path, data, 0600)
+}
+
+// lock takes an exclusive lock file, breaking locks left by killed runs
+func (l *RateLimiter) lock(path string) (func(), error) {
+	deadline := time.Now().Add(rateLockTimeout)
+	for {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		if info, statErr := os.Stat(path); statErr == nil && time.Since(info.ModTime()) > rateLockStale {
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for rate limit lock %s", path)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestParseRateLimit tests rate parsing
+func TestParseRateLimit(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    RateLimit
+		wantErr bool
+	}{
+		{"10/min", RateLimit{10, time.Minute}, false},
+		{"1/s", RateLimit{1, time.Second}, false},
+		{"500/hour", RateLimit{500, time.Hour}, false},
+		{"0/min", RateLimit{}, true},
+		{"10/day", RateLimit{}, true},
+		{"github", RateLimit{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseRateLimit(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseRateLimit(%q) = %v, %v", tt.value, got, err)
+		}
+	}
+}
+
+// fakeRateClock is a clock that sleeping advances
+type fakeRateClock struct {
+	now   time.Time
+	slept time.Duration
+}
+
+func (c *fakeRateClock) limiter(t *testing.T, dir string, groups map[string]string) *RateLimiter {
+	t.Helper()
+	limiter, err := NewRateLimiter(groups)
+	if err != nil {
+		t.Fatalf("NewRateLimiter failed: %v", err)
+	}
+	limiter.Dir = dir
+	limiter.now = func() time.Time { return c.now }
+	limiter.sleep = func(d time.Duration) {
+		c.now = c.now.Add(d)
+		c.slept += d
+	}
+	return limiter
+}
+
+// TestRateLimiter_SharedBudget tests that a group's budget is shared across limiters using the same state
+func TestRateLimiter_SharedBudget(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeRateClock{now: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)}
+	groups := map[string]string{"github": "2/min"}
+	first := clock.limiter(t, dir, groups)
+	second := clock.limiter(t, dir, groups) // Another sink run on the same machine
+
+	for i, limiter := range []*RateLimiter{first, second} {
+		if waited, err := limiter.Wait("github", "fetch"); err != nil || waited != 0 {
+			t.Fatalf("call %d: waited %s, err %v", i+1, waited, err)
+		}
+	}
+
+	clock.now = clock.now.Add(10 * time.Second)
+	waited, err := second.Wait("github", "release")
+	if err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+	if waited != 50*time.Second {
+		t.Errorf("third call waited %s, want 50s", waited)
+	}
+
+	// Inline rates are private to their step
+	if waited, _ := first.Wait("2/min", "other"); waited != 0 {
+		t.Errorf("inline rate should not share the github budget, waited %s", waited)
+	}
+}
+
+// TestExecutor_RateLimitRetries tests that every retry attempt counts against the rate limit
+func TestExecutor_RateLimitRetries(t *testing.T) {
+	clock := &fakeRateClock{now: time.Now()}
+	attempts := 0
+	transport := &StatefulMockTransport{
+		runFunc: func(cmd string) (string, string, int, error) {
+			if cmd != "gh api /rate" {
+				return "", "", 0, nil
+			}
+			attempts++
+			if attempts < 3 {
+				return "", "busy", 1, nil
+			}
+			return "ok", "", 0, nil
+		},
+	}
+	executor := NewExecutor(transport)
+	executor.RateLimiter = clock.limiter(t, t.TempDir(), map[string]string{"github": "1/min"})
+
+	until := "until"
+	result := executor.executeCommand("api", CommandStep{Command: "gh api /rate", Retry: &until, Timeout: []byte(`"10s"`), RateLimit: "github"}, Facts{})
+	if result.Error != "" {
+		t.Fatalf("unexpected error: %s", result.Error)
+	}
+	if attempts != 3 || clock.slept != 2*time.Minute {
+		t.Errorf("expected 3 attempts throttled by 2m, got %d attempts, %s", attempts, clock.slept)
+	}
+}
+
+// TestValidateConfig_RateLimits tests validation of rate_limits groups and step references
+func TestValidateConfig_RateLimits(t *testing.T) {
+	tests := []struct {
+		name   string
+		groups map[string]string
+		step   StepVariant
+		want   string
+	}{
+		{"group", map[string]string{"github": "10/min"}, CommandStep{Command: "gh api", RateLimit: "github"}, ""},
+		{"inline", nil, CommandStep{Command: "gh api", RateLimit: "30/hour"}, ""},
+		{"bad rate", map[string]string{"github": "10 per minute"}, CommandStep{Command: "gh api"}, "rate_limits: github: invalid rate"},
+		{"unknown group", nil, CommandStep{Command: "gh api", RateLimit: "github"}, "rate_limit: 'github' is neither a rate_limits group"},
+		{"remediation", nil, CheckRemediateStep{Check: "which gh", OnMissing: []RemediationStep{
+			{Name: "install", Command: "brew install gh", RateLimit: "brew"},
+		}}, "on_missing[0] install: rate_limit"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateConfig(&Config{Version: "1.0.0", RateLimits: tt.groups, Platforms: []Platform{{
+				OS: "linux", Match: "linux*", Name: "Linux",
+				InstallSteps: []InstallStep{{Name: "API", Step: tt.step}},
+			}}})
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+// pendingCheck is a failed check step marked "recheck" that is waiting for a
+// later remediation to satisfy it
+type pendingCheck struct {
+	index int // Position in the platform's results
+	step  InstallStep
+	check CheckErrorStep
+}
+
+// isPendingCheck reports whether a check step's failure should be held
+// pending rather than stopping the run
+func isPendingCheck(step InstallStep, result StepResult) bool {
+	check, ok := step.Step.(CheckErrorStep)
+	return ok && check.Recheck && result.Error == check.Error
+}
+
+// recheckPending re-evaluates pending checks after remediatedBy remediated
+// successfully, so one remediation can satisfy several checks. Checks that
+// now pass are reported as successful; the rest stay pending.
+func (e *Executor) recheckPending(pending []pendingCheck, results []StepResult, remediatedBy string, facts Facts) []pendingCheck {
+	var still []pendingCheck
+	for _, p := range pending {
+		checkCmd, probe, err := e.interpolateCheck(p.check.Check, p.check.CheckProbe, facts)
+		if err != nil {
+			still = append(still, p)
+			continue
+		}
+		if e.Verbose {
+			verboseLog("Re-checking '%s' after remediation by '%s': %s", p.step.Name, remediatedBy, describeCheck(checkCmd, probe))
+		}
+		stdout, passed, mismatch, err := e.runCheck(checkCmd, probe, p.check.ExpectOutput, facts)
+		if err != nil || !passed {
+			if mismatch != nil {
+				results[p.index].OutputMismatch = mismatch
+			}
+			still = append(still, p)
+			continue
+		}
+
+		result := StepResult{
+			StepName: p.step.Name,
+			Status:   "success",
+			Output:   stdout,
+		}
+		results[p.index] = result
+		e.Transcript.resolveStep(p.step.Name, result)
+		e.emitRecheckEvent(p.step, result, remediatedBy)
+	}
+	return still
+}
+
+// failPending fails the checks no remediation satisfied
+func (e *Executor) failPending(pending []pendingCheck, results []StepResult) {
+	for _, p := range pending {
+		result := results[p.index]
+		result.Status = "failed"
+		results[p.index] = result
+		e.Transcript.resolveStep(p.step.Name, result)
+		e.emitRecheckEvent(p.step, result, "")
+	}
+}
+
+// emitRecheckEvent reports the final status of a pending check
+func (e *Executor) emitRecheckEvent(step InstallStep, result StepResult, remediatedBy string) {
+	event := ExecutionEvent{
+		RunID:          e.runID,
+		StepName:       step.Name,
+		Status:         result.Status,
+		Output:         result.Output,
+		Error:          result.Error,
+		RecheckedAfter: remediatedBy,
+		OutputMismatch: result.OutputMismatch,
+	}
+	e.populateVerboseMetadata(&event, step)
+	e.emitEvent(event)
+}
@@ -2,9 +2,78 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
 )
 
+var (
+	// Valid file mode: octal permissions such as "644" or "0600"
+	fileModeRegex = regexp.MustCompile(`^0?[0-7]{3}$`)
+
+	// Valid SHA256: 64 lowercase hex characters
+	sha256Regex = regexp.MustCompile(`^[0-9a-f]{64}$`)
+)
+
+// RemoteFile is a supporting file (cert, template, ...) that remote deploy
+// transfers to the target before execution
+type RemoteFile struct {
+	Source      string `json:"source"`           // Local path (relative to the config file)
+	Destination string `json:"destination"`      // Remote path (relative paths land in the deploy workspace)
+	Mode        string `json:"mode,omitempty"`   // Octal permissions, e.g. "0600"
+	SHA256      string `json:"sha256,omitempty"` // Expected checksum of the source
+}
+
+// RemoteDeployOptions controls a remote deployment
+type RemoteDeployOptions struct {
+	Binary    string       // Local sink binary to transfer
+	Config    string       // Config file path or URL
+	Files     []RemoteFile // Supporting files from the config "files" section and --copy
+	BaseDir   string       // Directory relative file sources are resolved against
+	DryRun    bool         // Show the plan without connecting
+	NoCleanup bool         // Leave the workspace and copied files on the target
+	Yes       bool         // Answer the confirmation prompt on the target
+	Variables []string     // name=value pairs passed on as --var
+	SSH       SSHOptions   // Key, agent forwarding and known_hosts for ssh and scp
+	Policy    DeployPolicy // Which hosts of a multi-host deploy must succeed
+	RunDir    string       // Per-host logs and results.json of a multi-host deploy (default: in the log directory)
+}
+
+// stagedFile is a RemoteFile resolved to a local path with its checksum
+type stagedFile struct {
+	RemoteFile
+	LocalPath string
+	Checksum  string
+}
+
+// installedFile is a supporting file deployed to an absolute destination,
+// outside the workspace. Backup holds the file it replaced, if any.
+type installedFile struct {
+	Path     string
+	Backup   string
+	Replaced bool
+}
+
+// undoCommand removes an installed file again, or restores the one it
+// replaced
+func (f installedFile) undoCommand() string {
+	if f.Replaced {
+		return fmt.Sprintf("cp -p %s %s", shellQuote(f.Backup), shellQuote(f.Path))
+	}
+	return "rm -f " + shellQuote(f.Path)
+}
+
+// remoteShell runs commands on and copies files to one deploy target
+type remoteShell interface {
+	Run(command string) (string, error)         // Run a command and capture stdout
+	Upload(local string, remote string) error   // Copy a local file to the target
+	Exec(command string, stdin io.Reader) error // Run a command attached to the terminal
+}
+
 // remoteCommand handles remote deployment
 func remoteCommand() {
 	if len(os.Args) < 3 {
@@ -44,54 +113,450 @@ func remoteDeployCommand() {
 	}
 
 	target := os.Args[3]
-	configSource := os.Args[4]
-
-	// Parse optional flags
-	dryRun := false
-	noCleanup := false
+	opts := RemoteDeployOptions{Config: os.Args[4]}
+	var copies []RemoteFile
 
 	for i := 5; i < len(os.Args); i++ {
 		arg := os.Args[i]
-		switch arg {
-		case "--dry-run":
-			dryRun = true
-		case "--no-cleanup":
-			noCleanup = true
-		case "--yes", "-y":
-			// Skip confirmation (for future use)
-			continue
+		switch {
+		case arg == "--dry-run":
+			opts.DryRun = true
+		case arg == "--no-cleanup":
+			opts.NoCleanup = true
+		case arg == "--yes" || arg == "-y":
+			opts.Yes = true
+		case arg == "--var" && i+1 < len(os.Args):
+			if _, _, err := parseVarFlag(os.Args[i+1]); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			opts.Variables = append(opts.Variables, os.Args[i+1])
+			i++
+		case arg == "--ssh-identity" && i+1 < len(os.Args):
+			opts.SSH.Identity = os.Args[i+1]
+			i++
+		case arg == "--ssh-known-hosts" && i+1 < len(os.Args):
+			opts.SSH.KnownHosts = os.Args[i+1]
+			i++
+		case arg == "--ssh-forward-agent":
+			opts.SSH.ForwardAgent = true
+		case arg == "--ssh-accept-new":
+			opts.SSH.AcceptNew = true
+		case arg == "--all-must-succeed":
+			opts.Policy.Quorum = 0
+		case arg == "--quorum" && i+1 < len(os.Args):
+			n, err := parseQuorum(os.Args[i+1])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			opts.Policy.Quorum = n
+			i++
+		case arg == "--run-dir" && i+1 < len(os.Args):
+			opts.RunDir = os.Args[i+1]
+			i++
+		case arg == "--binary" && i+1 < len(os.Args):
+			opts.Binary = os.Args[i+1]
+			i++
+		case arg == "--copy" && i+1 < len(os.Args):
+			file, err := parseCopyFlag(os.Args[i+1])
+			if err == nil {
+				// --copy sources are relative to the working directory
+				file.Source, err = filepath.Abs(file.Source)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			copies = append(copies, file)
+			i++
 		default:
 			fmt.Fprintf(os.Stderr, "Unknown option: %s\n", arg)
 			os.Exit(1)
 		}
 	}
 
+	if opts.Binary == "" {
+		self, err := os.Executable()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: cannot locate sink binary (use --binary): %v\n", err)
+			os.Exit(1)
+		}
+		opts.Binary = self
+	}
+
+	// A local config is validated here and contributes its "files" section;
+	// a URL config is downloaded on the target, so only --copy files apply
+	if !isURL(opts.Config) {
+		config, err := LoadConfig(opts.Config)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+			os.Exit(1)
+		}
+		opts.Files = config.Files
+		opts.BaseDir = filepath.Dir(opts.Config)
+	}
+	opts.Files = append(opts.Files, copies...)
+	if err := validateRemoteFiles(opts.Files); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	staged, err := stageRemoteFiles(opts.Files, opts.BaseDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	hosts, err := expandHosts(target)
+	if err == nil {
+		err = opts.Policy.check(len(hosts))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
 	fmt.Println("🚀 Sink Remote Deployment")
 	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 	fmt.Printf("   Target: %s\n", target)
-	fmt.Printf("   Config: %s\n", configSource)
-	if dryRun {
+	if len(hosts) > 1 {
+		fmt.Printf("   Hosts:  %d\n", len(hosts))
+		fmt.Printf("   Policy: %s\n", opts.Policy)
+	}
+	fmt.Printf("   Config: %s\n", opts.Config)
+	if len(staged) > 0 {
+		fmt.Printf("   Files:  %d\n", len(staged))
+	}
+	if opts.DryRun {
 		fmt.Println("   Mode:   DRY RUN")
 	}
 	fmt.Println()
 
-	// TODO: Implement SSH deployment
-	// For now, show what would happen
-	fmt.Println("⚠️  SSH remote deployment not yet implemented")
-	fmt.Println()
-	fmt.Println("Planned steps:")
-	fmt.Println("  1. Connect to", target, "via SSH")
-	fmt.Println("  2. Transfer sink binary")
-	fmt.Println("  3. Transfer or download config from", configSource)
-	fmt.Println("  4. Execute: sink bootstrap <config>")
-	if !noCleanup {
-		fmt.Println("  5. Clean up temporary files")
+	if opts.DryRun {
+		printDeployPlan(os.Stdout, opts, staged)
+		fmt.Println("\nHosts:")
+		for i, host := range hosts {
+			fmt.Printf("  [%d] %s\n", i, host)
+		}
+		return
 	}
-	fmt.Println()
-	fmt.Println("For now, use the bash script: ./scripts/bootstrap-remote.sh")
-	fmt.Println("Or manually SSH and run: sink bootstrap <url>")
 
-	os.Exit(1)
+	if len(hosts) == 1 {
+		info := HostInfo{Name: targetHostName(hosts[0]), Count: 1}
+		if err := deployToHost(newSSHShell(hosts[0], opts.SSH), info, opts, staged, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %s: %v\n", hosts[0], err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Several hosts get a run directory of logs and results, and the
+	// policy decides the exit status
+	run, err := newDeployRun(opts.RunDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: cannot create run directory: %v\n", err)
+		os.Exit(1)
+	}
+	config := &fleetConfig{Source: opts.Config, Path: opts.Config, Files: staged}
+	results := make([]fleetResult, len(hosts))
+	for i, host := range hosts {
+		fmt.Printf("▶  %s\n", host)
+		target := fleetTarget{Target: host, Host: HostInfo{Name: targetHostName(host), Index: i, Count: len(hosts)}, Config: config}
+		results[i] = run.deploy(target, opts, func(target string, out io.Writer) remoteShell {
+			shell := newSSHShell(target, opts.SSH)
+			shell.out = out
+			return shell
+		}, os.Stdout)
+		if results[i].Err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %s: %v\n", host, results[i].Err)
+		}
+	}
+	if err := finishDeployRun(os.Stdout, run, results, opts.Policy); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// parseCopyFlag parses a --copy value "local:remote". Without ":remote" the
+// file lands in the deploy workspace under its own name.
+func parseCopyFlag(value string) (RemoteFile, error) {
+	local, remote := value, ""
+	if i := strings.LastIndex(value, ":"); i >= 0 {
+		local, remote = value[:i], value[i+1:]
+	}
+	if local == "" {
+		return RemoteFile{}, fmt.Errorf("invalid --copy '%s' (expected local:remote)", value)
+	}
+	if remote == "" {
+		remote = filepath.Base(local)
+	}
+	return RemoteFile{Source: local, Destination: remote}, nil
+}
+
+// validateRemoteFiles checks a "files" section (and --copy entries)
+func validateRemoteFiles(files []RemoteFile) error {
+	seen := map[string]int{}
+	for i, file := range files {
+		if strings.TrimSpace(file.Source) == "" {
+			return fmt.Errorf("files[%d]: source is required", i)
+		}
+		if strings.TrimSpace(file.Destination) == "" {
+			return fmt.Errorf("files[%d]: destination is required", i)
+		}
+		dest := path.Clean(file.Destination)
+		if !path.IsAbs(dest) && (dest == "." || dest == ".." || strings.HasPrefix(dest, "../")) {
+			return fmt.Errorf("files[%d]: relative destination '%s' must stay inside the deploy workspace", i, file.Destination)
+		}
+		if dest == "sink" || dest == "config.json" {
+			return fmt.Errorf("files[%d]: destination '%s' is reserved for the deployed binary and config", i, file.Destination)
+		}
+		if file.Mode != "" && !fileModeRegex.MatchString(file.Mode) {
+			return fmt.Errorf("files[%d]: invalid mode '%s' (expected octal, e.g. \"0600\")", i, file.Mode)
+		}
+		if file.SHA256 != "" && !sha256Regex.MatchString(file.SHA256) {
+			return fmt.Errorf("files[%d]: sha256 must be 64 lowercase hex characters", i)
+		}
+		if first, ok := seen[dest]; ok {
+			return fmt.Errorf("files[%d]: destination '%s' is already used by files[%d]", i, file.Destination, first)
+		}
+		seen[dest] = i
+	}
+	return nil
+}
+
+// stageRemoteFiles resolves file sources and checksums them, failing before
+// any connection is made if a file is missing or does not match its sha256
+func stageRemoteFiles(files []RemoteFile, baseDir string) ([]stagedFile, error) {
+	staged := make([]stagedFile, 0, len(files))
+	for _, file := range files {
+		local := file.Source
+		if !filepath.IsAbs(local) && baseDir != "" {
+			local = filepath.Join(baseDir, local)
+		}
+		sum, err := fileSHA256(local)
+		if err != nil {
+			return nil, fmt.Errorf("file %s: %w", file.Source, err)
+		}
+		if file.SHA256 != "" && sum != file.SHA256 {
+			return nil, fmt.Errorf("file %s: SHA256 mismatch (expected %s, got %s)", file.Source, file.SHA256, sum)
+		}
+		staged = append(staged, stagedFile{RemoteFile: file, LocalPath: local, Checksum: sum})
+	}
+	return staged, nil
+}
+
+// remotePath returns where a file lands on the target
+func (f stagedFile) remotePath(workspace string) string {
+	if path.IsAbs(f.Destination) {
+		return path.Clean(f.Destination)
+	}
+	return path.Join(workspace, f.Destination)
+}
+
+// deployToHost transfers sink, the config and supporting files to one target,
+// runs the config there and removes the workspace (unless NoCleanup). Files
+// with absolute destinations stay installed after a successful run; when
+// the deploy fails they are removed again, or the files they replaced are
+// restored. host is passed to the run as SINK_HOST* for the {{.sink.host}}
+// facts.
+func deployToHost(shell remoteShell, host HostInfo, opts RemoteDeployOptions, files []stagedFile, out io.Writer) (err error) {
+	workspace, err := shell.Run("mktemp -d /tmp/sink-deploy.XXXXXX")
+	if err != nil {
+		return fmt.Errorf("failed to create remote workspace: %w", err)
+	}
+	workspace = strings.TrimSpace(workspace)
+	if workspace == "" {
+		return fmt.Errorf("failed to create remote workspace: mktemp printed nothing")
+	}
+
+	// Cleanup covers files already installed when a later step fails. The
+	// workspace holds the backups, so it goes last.
+	var installed []installedFile
+	defer func() {
+		if opts.NoCleanup {
+			fmt.Fprintf(out, "   Files left on target (--no-cleanup): %s\n", workspace)
+			return
+		}
+		var commands []string
+		if err != nil {
+			for i := len(installed) - 1; i >= 0; i-- {
+				commands = append(commands, installed[i].undoCommand())
+			}
+		}
+		commands = append(commands, "rm -rf "+shellQuote(workspace))
+		if _, cleanupErr := shell.Run(strings.Join(commands, " && ")); cleanupErr != nil && err == nil {
+			err = fmt.Errorf("cleanup failed: %w", cleanupErr)
+		}
+	}()
+
+	binary := path.Join(workspace, "sink")
+	if err := shell.Upload(opts.Binary, binary); err != nil {
+		return fmt.Errorf("failed to transfer sink binary: %w", err)
+	}
+	if _, err := shell.Run("chmod 755 " + shellQuote(binary)); err != nil {
+		return fmt.Errorf("failed to make sink executable: %w", err)
+	}
+	fmt.Fprintf(out, "   ✓ Transferred sink binary\n")
+
+	configArg := opts.Config
+	if !isURL(opts.Config) {
+		configArg = path.Join(workspace, "config.json")
+		if err := shell.Upload(opts.Config, configArg); err != nil {
+			return fmt.Errorf("failed to transfer config: %w", err)
+		}
+		fmt.Fprintf(out, "   ✓ Transferred config\n")
+	}
+
+	for _, file := range files {
+		dest := file.remotePath(workspace)
+		if _, err := shell.Run("mkdir -p " + shellQuote(path.Dir(dest))); err != nil {
+			return fmt.Errorf("failed to create directory for %s: %w", dest, err)
+		}
+		if path.IsAbs(file.Destination) {
+			backup := path.Join(workspace, ".replaced", fmt.Sprint(len(installed)))
+			stdout, err := shell.Run(fmt.Sprintf("if [ -e %[1]s ]; then mkdir -p %[2]s && cp -p %[1]s %[3]s && echo replaced; fi",
+				shellQuote(dest), shellQuote(path.Dir(backup)), shellQuote(backup)))
+			if err != nil {
+				return fmt.Errorf("failed to back up %s: %w", dest, err)
+			}
+			installed = append(installed, installedFile{Path: dest, Backup: backup, Replaced: strings.TrimSpace(stdout) == "replaced"})
+		}
+		if err := shell.Upload(file.LocalPath, dest); err != nil {
+			return fmt.Errorf("failed to transfer %s: %w", file.Source, err)
+		}
+
+		stdout, err := shell.Run(sha256Command(dest))
+		if err != nil {
+			return fmt.Errorf("failed to checksum %s: %w", dest, err)
+		}
+		if fields := strings.Fields(stdout); len(fields) == 0 || fields[0] != file.Checksum {
+			return fmt.Errorf("%s: SHA256 mismatch after transfer (expected %s)", dest, file.Checksum)
+		}
+
+		if file.Mode != "" {
+			if _, err := shell.Run(fmt.Sprintf("chmod %s %s", file.Mode, shellQuote(dest))); err != nil {
+				return fmt.Errorf("failed to set mode on %s: %w", dest, err)
+			}
+		}
+		fmt.Fprintf(out, "   ✓ %s → %s (sha256 %s…)\n", file.Source, dest, file.Checksum[:12])
+	}
+
+	// Steps run from the workspace so relative file destinations resolve
+	var stdin io.Reader = os.Stdin
+	if opts.Yes {
+		stdin = strings.NewReader("yes\n")
+	}
+	command := fmt.Sprintf("cd %s && %s=%s %s=%d %s=%d ./sink bootstrap %s",
+		shellQuote(workspace),
+		EnvHost, shellQuote(host.Name), EnvHostIndex, host.Index, EnvHostCount, host.Count,
+		shellQuote(configArg))
+	for _, variable := range opts.Variables {
+		command += " --var " + shellQuote(variable)
+	}
+	if err := shell.Exec(command, stdin); err != nil {
+		return fmt.Errorf("execution failed: %w", err)
+	}
+	return nil
+}
+
+// printDeployPlan describes what deploy would do without connecting
+func printDeployPlan(out io.Writer, opts RemoteDeployOptions, files []stagedFile) {
+	fmt.Fprintln(out, "Planned steps:")
+	fmt.Fprintln(out, "  1. Create a workspace with mktemp -d /tmp/sink-deploy.XXXXXX")
+	fmt.Fprintf(out, "  2. Transfer sink binary %s\n", opts.Binary)
+	if isURL(opts.Config) {
+		fmt.Fprintf(out, "  3. Download config on the target from %s\n", opts.Config)
+	} else {
+		fmt.Fprintf(out, "  3. Transfer config %s\n", opts.Config)
+	}
+	fmt.Fprintf(out, "  4. Transfer %d supporting files and verify their checksums\n", len(files))
+	for _, file := range files {
+		dest := file.Destination
+		if !path.IsAbs(dest) {
+			dest = "<workspace>/" + path.Clean(dest)
+		}
+		fmt.Fprintf(out, "     • %s → %s\n       sha256 %s\n", file.Source, dest, file.Checksum)
+	}
+	fmt.Fprintln(out, "  5. Execute: sink bootstrap <config> from the workspace")
+	if !opts.NoCleanup {
+		fmt.Fprintln(out, "  6. Remove the workspace; files with absolute destinations stay installed,")
+		fmt.Fprintln(out, "     or are removed (restoring what they replaced) if the deploy fails")
+	}
+}
+
+// isURL reports whether a config source is an HTTP(S) URL
+func isURL(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
+// shellQuote quotes a string for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// sshShell implements remoteShell with the system ssh and scp commands
+type sshShell struct {
+	host string     // user@host
+	port string     // Empty for the default port
+	opts SSHOptions // Authentication and host key verification
+	out  io.Writer  // Where Exec output goes (nil means this process's stdout and stderr)
+}
+
+// newSSHShell parses a "user@host" or "user@host:port" target
+func newSSHShell(target string, opts SSHOptions) *sshShell {
+	host, port := splitSSHTarget(target)
+	return &sshShell{host: host, port: port, opts: opts}
+}
+
+// sshArgs returns ssh arguments for running command on the target
+func (s *sshShell) sshArgs(tty bool, command string) []string {
+	args := s.opts.args()
+	if s.port != "" {
+		args = append(args, "-p", s.port)
+	}
+	if tty {
+		args = append(args, "-t")
+	}
+	return append(args, s.host, command)
+}
+
+// Run implements remoteShell
+func (s *sshShell) Run(command string) (string, error) {
+	cmd := exec.Command("ssh", s.sshArgs(false, command)...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	stdout, err := cmd.Output()
+	if err != nil && stderr.Len() > 0 {
+		return string(stdout), fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return string(stdout), err
+}
+
+// Upload implements remoteShell
+func (s *sshShell) Upload(local string, remote string) error {
+	args := append([]string{"-q"}, s.opts.args()...)
+	if s.port != "" {
+		args = append(args, "-P", s.port)
+	}
+	args = append(args, local, scpHost(s.host)+":"+remote)
+	output, err := exec.Command("scp", args...).CombinedOutput()
+	if err != nil && len(output) > 0 {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return err
+}
+
+// Exec implements remoteShell. A terminal is allocated when stdin is
+// interactive so the confirmation prompt works.
+func (s *sshShell) Exec(command string, stdin io.Reader) error {
+	cmd := exec.Command("ssh", s.sshArgs(stdin == os.Stdin, command)...)
+	cmd.Stdin = stdin
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	if s.out != nil {
+		cmd.Stdout, cmd.Stderr = s.out, s.out
+	}
+	return cmd.Run()
 }
 
 // printRemoteHelp prints help for the remote command
@@ -108,28 +573,93 @@ Subcommands:
 Arguments:
   target              SSH target (user@host or user@host:port)
                       Multiple targets: user@host1,user@host2
+                      Ranges: user@web[01:20].prod, db-[a:c]
   config-source       Config file path or URL
 
 Options:
-  --dry-run          Show what would be executed without running
+  --copy <local:remote>
+                     Transfer a supporting file before execution
+                     (repeatable; without :remote the file keeps its name
+                     in the workspace)
+  --binary <path>    Sink binary to transfer (default: this binary; it
+                     must match the target's OS and architecture)
+  --dry-run          Show what would be executed without connecting
   --no-cleanup       Don't remove temporary files on remote
-  --yes, -y          Skip confirmation prompt
+  --yes, -y          Answer the confirmation prompt on the target
+  --var <name>=<value>
+                     Set a config variable on every target (repeatable)
+  --ssh-identity <file>
+                     Authenticate with this private key only
+  --ssh-forward-agent
+                     Forward the local ssh-agent to the target
+  --ssh-known-hosts <file>
+                     Verify host keys against this file only; unknown
+                     hosts are refused
+  --ssh-accept-new   Record the keys of hosts not seen before (a changed
+                     key is still refused)
+  --all-must-succeed Fail unless every host succeeds (default)
+  --quorum <n>       Succeed when at least n hosts succeed
+  --run-dir <dir>    Where a multi-host deploy writes its logs and results
+                     (default: deploy-<run id> in the log directory)
   -h, --help         Show this help message
 
 Description:
   The remote command deploys the sink binary and configuration to remote
   hosts via SSH, then executes the installation. This automates the full
-  bootstrap process for new machines.
+  bootstrap process for new machines. It uses the system ssh and scp
+  commands, so ~/.ssh/config, agents and known_hosts apply as usual.
+
+  To run a config on a host without copying sink there, use
+  sink execute <config> --ssh user@host, which runs each step over SSH
+  from this machine.
 
 Deployment Process:
-  1. Connect to target host via SSH
+  1. Create a workspace on the target (mktemp -d /tmp/sink-deploy.XXXXXX)
   2. Transfer sink binary to remote host
   3. Transfer config file or pass URL for download
-  4. Execute sink bootstrap on remote host
-  5. Clean up temporary files (unless --no-cleanup)
+  4. Transfer supporting files and verify their SHA256 on the target
+  5. Execute sink bootstrap on remote host from the workspace
+  6. Remove the workspace (unless --no-cleanup). Files with absolute
+     destinations stay installed; if the deploy fails they are removed
+     again, and files they replaced are restored
+
+Supporting Files:
+  Steps often need more than the config (certs, templates). List them in
+  the config's "files" section or pass --copy:
+
+    "files": [
+      {"source": "certs/web.pem", "destination": "certs/web.pem", "mode": "0600"},
+      {"source": "nginx.conf.tmpl", "destination": "/etc/sink/nginx.conf.tmpl",
+       "sha256": "<64 hex characters>"}
+    ]
+
+  Sources are relative to the config file. Relative destinations land in
+  the workspace, which is the working directory when steps run, so a step
+  can use "certs/web.pem" directly. Every file is checksummed before
+  connecting (and checked against "sha256" when given) and again on the
+  target after transfer. Files from a URL config's "files" section are
+  not transferred; use --copy for those.
+
+Host Patterns:
+  A bracketed range in a target expands to one host per value: numeric
+  ranges keep the start's zero padding (web[08:10] is web08, web09, web10)
+  and letter ranges run through the alphabet (db-[a:c]). Hosts deploy one
+  after another in the expanded order. Steps on each host see
+  {{.sink.host}} (the host name without user@ and :port),
+  {{.sink.host_index}} (its position, from 0) and {{.sink.host_count}},
+  for per-host cert names, node IDs and the like.
+
+Multi-Host Results:
+  A deploy to several hosts writes a run directory with each host's output
+  in hosts/<host>.log and every outcome in results.json, and ends with a
+  matrix of hosts, their status and time, failures last. The exit status
+  follows the policy: by default every host must succeed; with --quorum n
+  the deploy succeeds when at least n hosts do, so CI can tolerate a few
+  unreachable machines while still gating on the fleet.
 
 Security:
-  - Uses SSH key-based authentication
+  - Uses SSH key-based authentication (--ssh-identity)
+  - Verifies host keys against known_hosts (--ssh-known-hosts)
   - Transfers over encrypted SSH connection
   - Supports GitHub URL pinning validation
   - Auto-checksum verification for remote downloads
@@ -145,6 +675,12 @@ Examples:
   # Deploy to multiple hosts
   sink remote deploy user@host1,user@host2 setup.json
 
+  # Deploy to web01.prod through web20.prod
+  sink remote deploy deploy@web[01:20].prod setup.json
+
+  # Send a certificate along with the config
+  sink remote deploy user@host setup.json --copy ./certs/web.pem:certs/web.pem
+
   # Dry run to preview
   sink remote deploy user@host setup.json --dry-run
 
@@ -154,26 +690,19 @@ Examples:
   # Keep files for debugging
   sink remote deploy user@host setup.json --no-cleanup
 
+  # Pass when 18 of the 20 web hosts succeed
+  sink remote deploy deploy@web[01:20].prod setup.json --yes --quorum 18
+
 Exit Codes:
-  0    Success
+  0    Success (every host, or --quorum hosts, succeeded)
   1    Error (connection failed, transfer failed, execution failed)
 
-Current Status:
-  ⚠️  SSH remote deployment is not yet fully implemented.
-  
-  For now, use the provided bash script:
-    ./scripts/bootstrap-remote.sh user@host config.json
-
-  Or manually SSH to the host and run:
-    sink bootstrap <url-or-file>
-
 Related Commands:
   sink bootstrap  - Bootstrap from URL on current host
   sink execute    - Execute local config file
   sink help       - Show general help
 
 See Also:
-  docs/REMOTE_BOOTSTRAP.md    - Remote deployment guide
   scripts/bootstrap-remote.sh - Bash implementation
 `)
 }
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeShell records deploy operations and serves checksums of uploaded files
+type fakeShell struct {
+	commands []string
+	uploads  map[string]string // remote -> local
+	checksum string            // Returned by sha256sum ("" means the real checksum)
+	existing map[string]bool   // Remote files that exist before the deploy
+	execErr  error
+}
+
+func (s *fakeShell) Run(command string) (string, error) {
+	s.commands = append(s.commands, command)
+	switch {
+	case strings.HasPrefix(command, "mktemp"):
+		return "/tmp/sink-deploy.abc123\n", nil
+	case strings.HasPrefix(command, "if [ -e "):
+		for remote := range s.existing {
+			if strings.HasPrefix(command, "if [ -e "+shellQuote(remote)+" ]") {
+				return "replaced\n", nil
+			}
+		}
+	case strings.HasPrefix(command, "sha256sum"):
+		if s.checksum != "" {
+			return s.checksum + "  file\n", nil
+		}
+		for remote, local := range s.uploads {
+			if strings.Contains(command, shellQuote(remote)) {
+				sum, err := fileSHA256(local)
+				return sum + "  " + remote + "\n", err
+			}
+		}
+	}
+	return "", nil
+}
+
+func (s *fakeShell) Upload(local string, remote string) error {
+	if s.uploads == nil {
+		s.uploads = map[string]string{}
+	}
+	s.uploads[remote] = local
+	return nil
+}
+
+func (s *fakeShell) Exec(command string, stdin io.Reader) error {
+	s.commands = append(s.commands, command)
+	return s.execErr
+}
+
+// TestDeployToHost tests that supporting files are transferred, verified and
+// that only the workspace is cleaned up
+func TestDeployToHost(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{"sink": "binary", "config.json": "{}", "web.pem": "cert", "app.tmpl": "tmpl"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	staged, err := stageRemoteFiles([]RemoteFile{
+		{Source: "web.pem", Destination: "certs/web.pem", Mode: "0600"},
+		{Source: "app.tmpl", Destination: "/etc/app/app.tmpl"},
+	}, dir)
+	if err != nil {
+		t.Fatalf("stageRemoteFiles failed: %v", err)
+	}
+
+	shell := &fakeShell{}
+	opts := RemoteDeployOptions{
+		Binary:    filepath.Join(dir, "sink"),
+		Config:    filepath.Join(dir, "config.json"),
+		Yes:       true,
+		Variables: []string{"env=prod", "motd=it's live"},
+	}
+	host := HostInfo{Name: "web02.prod", Index: 1, Count: 3}
+	if err := deployToHost(shell, host, opts, staged, io.Discard); err != nil {
+		t.Fatalf("deployToHost failed: %v", err)
+	}
+
+	for _, remote := range []string{
+		"/tmp/sink-deploy.abc123/sink",
+		"/tmp/sink-deploy.abc123/config.json",
+		"/tmp/sink-deploy.abc123/certs/web.pem",
+		"/etc/app/app.tmpl",
+	} {
+		if _, ok := shell.uploads[remote]; !ok {
+			t.Errorf("Expected upload to %s, got %v", remote, shell.uploads)
+		}
+	}
+
+	all := strings.Join(shell.commands, "\n")
+	for _, want := range []string{
+		"mkdir -p '/tmp/sink-deploy.abc123/certs'",
+		"chmod 0600 '/tmp/sink-deploy.abc123/certs/web.pem'",
+		"cd '/tmp/sink-deploy.abc123' && SINK_HOST='web02.prod' SINK_HOST_INDEX=1 SINK_HOST_COUNT=3 ./sink bootstrap '/tmp/sink-deploy.abc123/config.json' --var 'env=prod' --var 'motd=it'\\''s live'",
+	} {
+		if !strings.Contains(all, want) {
+			t.Errorf("Expected command %q in:\n%s", want, all)
+		}
+	}
+	last := shell.commands[len(shell.commands)-1]
+	if last != "rm -rf '/tmp/sink-deploy.abc123'" {
+		t.Errorf("Expected cleanup of the workspace only, got %q", last)
+	}
+}
+
+// TestDeployToHostRestoresReplacedFiles tests that a failed deploy removes
+// files it installed outside the workspace and restores those it replaced
+func TestDeployToHostRestoresReplacedFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"sink", "app.conf", "app.pem"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	staged, err := stageRemoteFiles([]RemoteFile{
+		{Source: "app.conf", Destination: "/etc/app/app.conf"},
+		{Source: "app.pem", Destination: "/etc/app/app.pem"},
+	}, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shell := &fakeShell{existing: map[string]bool{"/etc/app/app.conf": true}, execErr: fmt.Errorf("exit status 1")}
+	opts := RemoteDeployOptions{Binary: filepath.Join(dir, "sink"), Config: "https://example.com/c.json"}
+	if err := deployToHost(shell, HostInfo{Name: "web", Count: 1}, opts, staged, io.Discard); err == nil {
+		t.Fatal("Expected the failed execution to be reported")
+	}
+
+	all := strings.Join(shell.commands, "\n")
+	backup := "if [ -e '/etc/app/app.conf' ]; then mkdir -p '/tmp/sink-deploy.abc123/.replaced' && cp -p '/etc/app/app.conf' '/tmp/sink-deploy.abc123/.replaced/0' && echo replaced; fi"
+	if !strings.Contains(all, backup) {
+		t.Errorf("Expected a backup of the replaced file in:\n%s", all)
+	}
+	last := shell.commands[len(shell.commands)-1]
+	want := "rm -f '/etc/app/app.pem' && cp -p '/tmp/sink-deploy.abc123/.replaced/0' '/etc/app/app.conf' && rm -rf '/tmp/sink-deploy.abc123'"
+	if last != want {
+		t.Errorf("Expected cleanup\n%q, got\n%q", want, last)
+	}
+}
+
+// TestDeployToHostChecksumMismatch tests that a corrupted transfer fails and still cleans up
+func TestDeployToHostChecksumMismatch(t *testing.T) {
+	dir := t.TempDir()
+	local := filepath.Join(dir, "web.pem")
+	if err := os.WriteFile(local, []byte("cert"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	staged, err := stageRemoteFiles([]RemoteFile{{Source: local, Destination: "web.pem"}}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shell := &fakeShell{checksum: strings.Repeat("0", 64)}
+	err = deployToHost(shell, HostInfo{Name: "web", Count: 1}, RemoteDeployOptions{Binary: local, Config: "https://example.com/c.json"}, staged, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "SHA256 mismatch after transfer") {
+		t.Fatalf("Expected checksum mismatch, got %v", err)
+	}
+	for _, cmd := range shell.commands {
+		if strings.Contains(cmd, "./sink bootstrap") {
+			t.Errorf("Execution should not start after a failed transfer")
+		}
+	}
+	if last := shell.commands[len(shell.commands)-1]; !strings.HasPrefix(last, "rm -rf '/tmp/sink-deploy.abc123'") {
+		t.Errorf("Expected cleanup after failure, got %q", last)
+	}
+}
+
+// TestStageRemoteFilesChecksum tests that declared checksums are enforced before connecting
+func TestStageRemoteFilesChecksum(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("hello\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	good := "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"
+	if _, err := stageRemoteFiles([]RemoteFile{{Source: "a.txt", Destination: "a.txt", SHA256: good}}, dir); err != nil {
+		t.Errorf("Expected matching checksum to pass: %v", err)
+	}
+	if _, err := stageRemoteFiles([]RemoteFile{{Source: "a.txt", Destination: "a.txt", SHA256: strings.Repeat("a", 64)}}, dir); err == nil {
+		t.Error("Expected checksum mismatch error")
+	}
+	if _, err := stageRemoteFiles([]RemoteFile{{Source: "missing.txt", Destination: "m"}}, dir); err == nil {
+		t.Error("Expected error for missing file")
+	}
+}
+
+// TestValidateRemoteFiles tests validation of the files section and --copy
+func TestValidateRemoteFiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   []RemoteFile
+		wantErr string
+	}{
+		{"valid", []RemoteFile{{Source: "a", Destination: "certs/a", Mode: "0600"}, {Source: "b", Destination: "/etc/b"}}, ""},
+		{"missing source", []RemoteFile{{Destination: "a"}}, "source is required"},
+		{"missing destination", []RemoteFile{{Source: "a"}}, "destination is required"},
+		{"escapes workspace", []RemoteFile{{Source: "a", Destination: "../a"}}, "must stay inside the deploy workspace"},
+		{"reserved", []RemoteFile{{Source: "a", Destination: "./sink"}}, "reserved"},
+		{"bad mode", []RemoteFile{{Source: "a", Destination: "a", Mode: "rw"}}, "invalid mode"},
+		{"bad sha256", []RemoteFile{{Source: "a", Destination: "a", SHA256: "abc"}}, "sha256 must be"},
+		{"duplicate", []RemoteFile{{Source: "a", Destination: "x/a"}, {Source: "b", Destination: "x//a"}}, "already used by files[0]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateRemoteFiles(tt.files)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+// TestParseCopyFlag tests --copy local:remote parsing
+func TestParseCopyFlag(t *testing.T) {
+	tests := []struct {
+		value string
+		want  RemoteFile
+	}{
+		{"certs/web.pem:/etc/ssl/web.pem", RemoteFile{Source: "certs/web.pem", Destination: "/etc/ssl/web.pem"}},
+		{"certs/web.pem", RemoteFile{Source: "certs/web.pem", Destination: "web.pem"}},
+		{"app.tmpl:", RemoteFile{Source: "app.tmpl", Destination: "app.tmpl"}},
+	}
+	for _, tt := range tests {
+		got, err := parseCopyFlag(tt.value)
+		if err != nil || got != tt.want {
+			t.Errorf("parseCopyFlag(%q) = %+v, %v; want %+v", tt.value, got, err, tt.want)
+		}
+	}
+	if _, err := parseCopyFlag(":remote"); err == nil {
+		t.Error("Expected error for missing local path")
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// defaultRetryTimeout bounds retry "until" loops whose step sets no timeout
+const defaultRetryTimeout = 60 * time.Second
+
+// retryLoop is a retry "until" loop. Command steps and remediations share
+// it, so timeouts, poll_interval, retry_on and rate_limit behave the same for
+// both.
+type retryLoop struct {
+	name      string          // Step or remediation name, reported in results and rate limits
+	kind      string          // "command" or "remediation", for verbose logs
+	command   string          // Interpolated command, for verbose logs
+	timeout   json.RawMessage // The step's timeout, string or object
+	rateLimit string          // Throttles every attempt
+	sleep     *string         // Pause after success
+	retryOn   *RetryOn        // Failures worth retrying (nil retries all)
+	verbose   bool
+
+	attempt   func(n int) (stdout, stderr string, exitCode int, err error) // n counts attempts from 1
+	succeeded func(exitCode int) bool
+
+	// onRetry is called after each failed attempt that will be retried, with
+	// the attempt's number (from 1) and outcome
+	onRetry func(attempt int, result StepResult)
+}
+
+// retry runs a retry loop until an attempt succeeds, fails permanently, or
+// the timeout expires
+func (e *Executor) retry(l retryLoop) StepResult {
+	failed := func(msg string) StepResult {
+		return StepResult{StepName: l.name, Status: "failed", Error: msg}
+	}
+
+	if l.verbose {
+		verboseLog("Executing %s with retry: %s", l.kind, l.command)
+	}
+
+	// Parse timeout configuration (default 60s if not specified)
+	timeout := defaultRetryTimeout
+	var customErrorCode *int
+	if len(l.timeout) > 0 {
+		parsedTimeout, errCode, err := parseTimeoutConfig(l.timeout)
+		if err != nil {
+			return failed(err.Error())
+		}
+		if parsedTimeout > 0 {
+			timeout = parsedTimeout
+		}
+		customErrorCode = errCode
+	}
+
+	if l.verbose {
+		verboseLog("Retry timeout: %s", timeout)
+		if customErrorCode != nil {
+			verboseLog("Custom timeout error code: %d", *customErrorCode)
+		}
+	}
+
+	// Polling loop
+	startTime := time.Now()
+	deadline := e.retryDeadline(startTime.Add(timeout))
+	pollInterval, _ := parsePollInterval(l.timeout)
+
+	var last StepResult
+	attemptNum := 0
+
+	if l.verbose {
+		verboseLog("Starting %s retry loop: polling every %s, timeout at %s", l.kind, pollInterval, deadline.Format("15:04:05"))
+	}
+
+	for time.Now().Before(deadline) {
+		attemptNum++
+		if err := e.throttle(l.rateLimit, l.name, l.verbose); err != nil {
+			return failed(fmt.Sprintf("rate limit: %v", err))
+		}
+		stdout, stderr, exitCode, err := l.attempt(attemptNum)
+
+		if l.verbose {
+			remaining := time.Until(deadline).Round(time.Second)
+			verboseLog("Retry attempt #%d of %s - exit code: %d (timeout in %s)", attemptNum, l.name, exitCode, remaining)
+		}
+
+		// Success!
+		if err == nil && l.succeeded(exitCode) {
+			elapsed := time.Since(startTime).Round(time.Second)
+			if l.verbose {
+				verboseLog("✓ Retry of %s succeeded after %d attempt(s) in %s", l.name, attemptNum, elapsed)
+			}
+
+			// Apply sleep after successful retry
+			if sleepErr := applySleep(l.sleep, l.verbose); sleepErr != nil {
+				return failed(fmt.Sprintf("sleep error: %v", sleepErr))
+			}
+
+			return StepResult{
+				StepName: l.name,
+				Status:   "success",
+				Output:   fmt.Sprintf("Ready after %s\n%s", elapsed, stdout),
+				ExitCode: exitCode,
+				Stdout:   stdout,
+				Stderr:   stderr,
+			}
+		}
+
+		// Save last error for reporting
+		last = StepResult{
+			StepName: l.name,
+			Status:   "failed",
+			Output:   stdout,
+			Error:    attemptError(stderr, exitCode, err),
+			ExitCode: exitCode,
+			Stdout:   stdout,
+			Stderr:   stderr,
+		}
+
+		// Permanent failures are not worth waiting out
+		if !l.retryOn.retryable(stdout, stderr, exitCode) {
+			if l.verbose {
+				verboseLog("Attempt #%d of %s does not match retry_on, not retrying", attemptNum, l.name)
+			}
+			last.Error = fmt.Sprintf("Permanent failure on attempt %d (does not match retry_on)\nLast error: %s", attemptNum, last.Error)
+			return last
+		}
+
+		if l.onRetry != nil && time.Until(deadline) > 0 {
+			l.onRetry(attemptNum, last)
+		}
+
+		// Wait before retrying, but not past the deadline or an interrupt
+		if !e.sleep(min(pollInterval, time.Until(deadline))) && e.Interrupted() {
+			last.Error = fmt.Sprintf("%v after attempt %d\nLast error: %s", errRunInterrupted, attemptNum, last.Error)
+			return last
+		}
+	}
+
+	// Timeout reached
+	elapsed := time.Since(startTime).Round(time.Second)
+	last.StepName, last.Status = l.name, "failed"
+	last.Error = fmt.Sprintf("Timeout after %s\nLast error: %s", elapsed, last.Error)
+
+	// Use custom error code if specified, otherwise use last exit code
+	if customErrorCode != nil {
+		last.ExitCode = *customErrorCode
+	}
+	return last
+}
+
+// attemptError describes a failed attempt for "Last error:" messages
+func attemptError(stderr string, exitCode int, err error) string {
+	if stderr != "" {
+		return fmt.Sprintf("exit code %d: %s", exitCode, strings.TrimSpace(stderr))
+	} else if err != nil {
+		return fmt.Sprintf("exit code %d: %v", exitCode, err)
+	}
+	return fmt.Sprintf("exit code %d", exitCode)
+}
+
+// emitRetryEvent reports a failed attempt that will be retried. Remediation
+// attempts are children of the step running the remediation.
+func (e *Executor) emitRetryEvent(attempt int, result StepResult) {
+	event := ExecutionEvent{
+		StepName: result.StepName,
+		Status:   "retrying",
+		Attempt:  attempt,
+		Error:    result.Error,
+		Stdout:   result.Stdout,
+		Stderr:   result.Stderr,
+	}
+	exitCode := result.ExitCode
+	event.ExitCode = &exitCode
+	if e.remediationOf != "" {
+		event.ParentStep = e.remediationOf
+		event.RemediationIndex = e.remediationIndex
+	}
+	e.emitEvent(event)
+}
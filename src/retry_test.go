@@ -207,6 +207,53 @@ func TestRetryInRemediationSteps(t *testing.T) {
 	transport.Run("rm -f /tmp/sink-remediation-retry-test")
 }
 
+// TestRetryAttemptEvents tests that commands and remediations report each
+// failed attempt they retry
+func TestRetryAttemptEvents(t *testing.T) {
+	attempts := map[string]int{}
+	transport := &StatefulMockTransport{
+		runFunc: func(cmd string) (string, string, int, error) {
+			if cmd != "wait-api" && cmd != "start-api" && cmd != "api-up" {
+				return "", "", 0, nil
+			}
+			attempts[cmd]++
+			if cmd == "api-up" || attempts[cmd] < 3 {
+				return "", "not ready", 7, nil
+			}
+			return "ok", "", 0, nil
+		},
+	}
+	executor := NewExecutor(transport)
+	var events []ExecutionEvent
+	executor.OnEvent = func(event ExecutionEvent) {
+		if event.Status == "retrying" {
+			events = append(events, event)
+		}
+	}
+
+	timeout := json.RawMessage(`{"interval": "5s", "poll_interval": "10ms"}`)
+	executor.ExecuteStep(InstallStep{Name: "Wait", Step: CommandStep{Command: "wait-api", Retry: stringPtr("until"), Timeout: timeout}}, Facts{})
+	executor.ExecuteStep(InstallStep{Name: "API", Step: CheckRemediateStep{Check: "api-up", OnMissing: []RemediationStep{
+		{Name: "noop", Command: "true"},
+		{Name: "start", Command: "start-api", Retry: stringPtr("until"), Timeout: timeout},
+	}}}, Facts{})
+
+	if len(events) != 4 {
+		t.Fatalf("expected 4 retrying events, got %d: %+v", len(events), events)
+	}
+	for i, event := range events {
+		if event.Attempt != i%2+1 || event.ExitCode == nil || *event.ExitCode != 7 || event.Error != "exit code 7: not ready" {
+			t.Errorf("unexpected event %d: %+v", i, event)
+		}
+	}
+	if events[0].StepName != "Wait" || events[0].ParentStep != "" {
+		t.Errorf("unexpected command attempt event: %+v", events[0])
+	}
+	if events[2].StepName != "start" || events[2].ParentStep != "API" || events[2].RemediationIndex != 2 {
+		t.Errorf("unexpected remediation attempt event: %+v", events[2])
+	}
+}
+
 // Helper function to create string pointer
 func stringPtr(s string) *string {
 	return &s
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// undoEntry is a step that changed the host and has an "undo" command, kept
+// until the run ends in case a later step fails
+type undoEntry struct {
+	step    InstallStep
+	number  int    // The step's step_number
+	command string // Its undo command, interpolated when the step finished
+	result  int    // Index of its result
+}
+
+// undoCommand interpolates a successful step's undo command with the facts
+// the step ran with, for rollback to run if a later step fails
+func (e *Executor) undoCommand(step InstallStep, facts Facts) (*undoEntry, error) {
+	command, err := e.interpolate(step.Undo, facts)
+	if err != nil {
+		return nil, fmt.Errorf("undo: %w", err)
+	}
+	return &undoEntry{step: step, number: e.stepNumber, command: command}, nil
+}
+
+// rollback runs the undo commands of the steps that changed the host before
+// a failure stopped the run, most recent first. Each reports a "rollback"
+// event, with an error when the undo failed; a failed undo does not stop
+// the others.
+//
+// Undo commands run even after --max-duration has passed or an interrupt:
+// a half-changed host is worse than a late exit, and a second interrupt or
+// interruptGrace still ends sink.
+func (e *Executor) rollback(undo []undoEntry, results []StepResult) {
+	defer func(previous string) { e.currentStep = previous }(e.currentStep)
+	defer func(previous int) { e.stepNumber = previous }(e.stepNumber)
+	defer func(deadline time.Time, ctx context.Context) { e.Deadline, e.Context = deadline, ctx }(e.Deadline, e.Context)
+	e.Deadline = time.Time{}
+	if e.Context != nil {
+		e.Context = context.WithoutCancel(e.Context)
+	}
+
+	for i := len(undo) - 1; i >= 0; i-- {
+		entry := undo[i]
+		e.currentStep = entry.step.Name
+		e.stepNumber = entry.number
+		if e.Verbose {
+			verboseLog("Rolling back step: %s", entry.step.Name)
+		}
+
+		e.Transcript.beginStep(InstallStep{Name: "Undo " + entry.step.Name, Annotations: entry.step.Annotations})
+		started := time.Now()
+		stdout, stderr, exitCode, err := e.run(entry.command)
+		undone := StepResult{StepName: entry.step.Name, Status: "success", Output: "rolled back"}
+		if err != nil || exitCode != 0 {
+			undone.Status, undone.Output = "failed", ""
+			undone.Error = fmt.Sprintf("undo failed (exit %d)", exitCode)
+			if err != nil {
+				undone.Error = fmt.Sprintf("%s: %v", undone.Error, err)
+			}
+			if stderr != "" {
+				undone.Error = fmt.Sprintf("%s\nstderr: %s", undone.Error, stderr)
+			}
+		}
+		e.Transcript.endStep(undone, time.Since(started))
+
+		if undone.Error == "" {
+			results[entry.result].RolledBack = true
+		} else {
+			results[entry.result].RollbackError = undone.Error
+		}
+		event := ExecutionEvent{
+			RunID:       e.runID,
+			StepName:    entry.step.Name,
+			Status:      "rollback",
+			Output:      undone.Output,
+			Error:       undone.Error,
+			Stdout:      stdout,
+			Stderr:      stderr,
+			Command:     entry.command,
+			Annotations: entry.step.Annotations,
+		}
+		if exitCode != 0 {
+			event.ExitCode = &exitCode
+		}
+		e.emitEvent(event)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestExecutePlatform_Rollback tests that a failure runs the undo commands
+// of the steps that changed the host, most recent first
+func TestExecutePlatform_Rollback(t *testing.T) {
+	var undone []string
+	transport := &StatefulMockTransport{runFunc: func(cmd string) (string, string, int, error) {
+		switch {
+		case strings.HasPrefix(cmd, "undo "):
+			undone = append(undone, cmd)
+			if cmd == "undo config" {
+				return "", "read-only file system", 1, fmt.Errorf("exit status 1")
+			}
+		case cmd == "start app":
+			return "", "port in use", 1, fmt.Errorf("exit status 1")
+		}
+		return "", "", 0, nil
+	}}
+	executor := NewExecutor(transport)
+	var events []ExecutionEvent
+	executor.OnEvent = func(event ExecutionEvent) { events = append(events, event) }
+
+	results := executor.ExecutePlatform(Platform{InstallSteps: []InstallStep{
+		{Name: "Install", Undo: "undo install {{.sink.step_index}}", Step: CommandStep{Command: "install app"}},
+		{Name: "Check disk", Undo: "undo check", Step: CheckErrorStep{Check: "df /", Error: "no disk"}},
+		{Name: "Configure", Undo: "undo config", Step: CommandStep{Command: "configure app"}},
+		{Name: "Start", Undo: "undo start", Step: CommandStep{Command: "start app"}},
+		{Name: "Never", Undo: "undo never", Step: CommandStep{Command: "echo never"}},
+	}}, Facts{})
+
+	if strings.Join(undone, ",") != "undo config,undo install 0" {
+		t.Fatalf("expected Configure then Install to be undone, got %v", undone)
+	}
+	if len(results) != 4 || !results[0].RolledBack || results[1].RolledBack || !strings.Contains(results[2].RollbackError, "read-only file system") {
+		t.Errorf("unexpected results %+v", results)
+	}
+	if note := summaryNote(results[0], SummaryShort); note != "rolled back" {
+		t.Errorf("expected the summary to note the rollback, got %q", note)
+	}
+
+	var rollbacks []string
+	for _, event := range events {
+		if event.Status == "rollback" {
+			rollbacks = append(rollbacks, fmt.Sprintf("%s#%d:%t", event.StepName, event.StepNumber, event.Error == ""))
+		}
+	}
+	if strings.Join(rollbacks, ",") != "Configure#3:false,Install#1:true" {
+		t.Errorf("unexpected rollback events %v", rollbacks)
+	}
+
+	// A run that continues past the failure is not rolled back
+	undone = nil
+	executor = NewExecutor(transport)
+	executor.ExecutePlatform(Platform{FailurePolicy: FailurePolicyContinueCollect, InstallSteps: []InstallStep{
+		{Name: "Install", Undo: "undo install", Step: CommandStep{Command: "install app"}},
+		{Name: "Start", Step: CommandStep{Command: "start app"}},
+	}}, Facts{})
+	if len(undone) != 0 {
+		t.Errorf("expected no rollback under continue-collect, got %v", undone)
+	}
+}
+
+// TestExecutePlatform_RollbackDeadline tests that a run cut short by
+// --max-duration or an interrupt is rolled back, with the undo commands
+// running past the deadline
+func TestExecutePlatform_RollbackDeadline(t *testing.T) {
+	for _, stop := range []string{"deadline", "interrupt"} {
+		var executor *Executor
+		var ran []string
+		transport := &StatefulMockTransport{runFunc: func(cmd string) (string, string, int, error) {
+			ran = append(ran, cmd)
+			if cmd == "configure app" {
+				// The run's time is up, or it is interrupted, while the step runs
+				if stop == "deadline" {
+					executor.Deadline = time.Now().Add(-time.Second)
+				} else {
+					ctx, cancel := context.WithCancel(context.Background())
+					cancel()
+					executor.Context = ctx
+				}
+			}
+			return "", "", 0, nil
+		}}
+		executor = NewExecutor(transport)
+		executor.Deadline = time.Now().Add(time.Hour)
+		ran = nil
+
+		results := executor.ExecutePlatform(Platform{InstallSteps: []InstallStep{
+			{Name: "Install", Undo: "undo install", Step: CommandStep{Command: "install app"}},
+			{Name: "Configure", Undo: "undo config", Step: CommandStep{Command: "configure app"}},
+			{Name: "Start", Undo: "undo start", Step: CommandStep{Command: "start app"}},
+		}}, Facts{})
+
+		if got := strings.Join(ran, ","); got != "install app,configure app,undo config,undo install" {
+			t.Errorf("%s: expected Configure and Install to be undone, got %s", stop, got)
+		}
+		if len(results) != 3 || !results[0].RolledBack || !results[1].RolledBack || results[2].Status != "not_run" {
+			t.Errorf("%s: unexpected results %+v", stop, results)
+		}
+		if stop == "deadline" && !executor.DeadlineExceeded() {
+			t.Errorf("expected the run deadline to be restored after rolling back")
+		}
+	}
+}
+
+// TestInstallStep_Undo tests parsing and validating "undo"
+func TestInstallStep_Undo(t *testing.T) {
+	var step InstallStep
+	if err := json.Unmarshal([]byte(`{"name": "Install", "command": "make install", "undo": "make uninstall"}`), &step); err != nil || step.Undo != "make uninstall" {
+		t.Fatalf("expected undo to be parsed, got %q, %v", step.Undo, err)
+	}
+	if err := json.Unmarshal([]byte(`{"name": "Install", "command": "make install", "undo": ""}`), &step); err == nil || !strings.Contains(err.Error(), "undo must be a non-empty command") {
+		t.Errorf("expected an empty undo to be rejected, got %v", err)
+	}
+
+	group := GroupStep{Group: Group{Steps: []InstallStep{{Name: "Member", Undo: "rm x", Step: CommandStep{Command: "touch x"}}}}}
+	if err := group.Group.validate(); err == nil || !strings.Contains(err.Error(), "undo is not supported inside a group") {
+		t.Errorf("expected undo on a group member to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// interruptGrace is how long sink winds down after an interrupt (stopping
+// commands, reporting the steps not run) before it exits regardless, so an
+// interrupt at a prompt or in a command that ignores it still ends sink
+const interruptGrace = 5 * time.Second
+
+// errRunInterrupted is reported for commands and downloads cut short by an
+// interrupt (Ctrl-C or SIGTERM)
+var errRunInterrupted = errors.New("run interrupted")
+
+var (
+	// runCtx is the context of the running command. It is done when
+	// --max-duration runs out or on an interrupt, and every HTTP request and
+	// step command derives from it, so network operations stop at once
+	// instead of after the HTTP client's own timeout.
+	runCtx = context.Background()
+
+	runStarted  bool
+	interrupted atomic.Bool
+)
+
+// beginRun starts the run's context with an optional time budget and
+// returns it. Only the first call does anything, so a bootstrap's download
+// and the run that follows share one budget and one interrupt handler.
+//
+// The first interrupt cancels the context: running commands and downloads
+// stop, the remaining steps are reported as not run and sink exits with
+// ExitInterrupted after its summary, or after interruptGrace at the latest.
+// A second interrupt exits at once.
+func beginRun(maxDuration time.Duration) context.Context {
+	if runStarted {
+		return runCtx
+	}
+	runStarted = true
+
+	var cancel context.CancelFunc
+	if maxDuration > 0 {
+		runCtx, cancel = context.WithTimeout(context.Background(), maxDuration)
+	} else {
+		runCtx, cancel = context.WithCancel(context.Background())
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		interrupted.Store(true)
+		signal.Stop(signals)
+		fmt.Fprintln(os.Stderr, "\n🛑 Interrupted, stopping (interrupt again to exit now)")
+		cancel()
+		time.Sleep(interruptGrace)
+		os.Exit(ExitInterrupted)
+	}()
+	return runCtx
+}
+
+// runInterrupted reports whether the run was interrupted
+func runInterrupted() bool {
+	return interrupted.Load()
+}
+
+// runExitCode is the exit status for a run that failed before its steps:
+// ExitInterrupted or ExitDeadlineExceeded when the run's context stopped it,
+// otherwise 1
+func runExitCode() int {
+	switch {
+	case runInterrupted():
+		return ExitInterrupted
+	case errors.Is(runCtx.Err(), context.DeadlineExceeded):
+		return ExitDeadlineExceeded
+	}
+	return 1
+}
+
+// runStopped returns why ctx stopped an operation, errRunInterrupted or
+// errRunDeadlineExceeded, or err when ctx is still running
+func runStopped(ctx context.Context, err error) error {
+	switch {
+	case ctx.Err() == nil:
+		return err
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return errRunDeadlineExceeded
+	}
+	return errRunInterrupted
+}
+
+// sleepContext sleeps for d or until ctx is done, reporting whether the
+// full time passed
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
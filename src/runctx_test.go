@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// withRunContext makes ctx the run's context for the rest of the test
+func withRunContext(t *testing.T, ctx context.Context) {
+	t.Helper()
+	saved := runCtx
+	runCtx = ctx
+	t.Cleanup(func() { runCtx = saved })
+}
+
+// TestHTTPGetCancelled tests that downloads stop as soon as the run is
+// interrupted or out of time, not after the HTTP client's timeout
+func TestHTTPGetCancelled(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	withRunContext(t, ctx)
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	if _, err := httpGet(server.URL, time.Minute); !errors.Is(err, errRunInterrupted) {
+		t.Errorf("expected the download to be interrupted, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the download to stop at once, took %s", elapsed)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	withRunContext(t, ctx)
+	if _, err := fetchText(server.URL); !errors.Is(err, errRunDeadlineExceeded) {
+		t.Errorf("expected the run deadline to stop the download, got %v", err)
+	}
+}
+
+// TestExecutePlatform_Interrupted tests that an interrupt stops the running
+// command and reports the remaining steps as not run
+func TestExecutePlatform_Interrupted(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses POSIX shell commands")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	executor := NewExecutor(NewLocalTransport())
+	executor.Context = ctx
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	results := executor.ExecutePlatform(Platform{InstallSteps: []InstallStep{
+		{Name: "Slow", Step: CommandStep{Command: "sleep 10"}},
+		{Name: "Next", Step: CommandStep{Command: "true"}},
+	}}, Facts{})
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the command to be stopped at once, took %s", elapsed)
+	}
+	if len(results) != 2 || results[0].Status != "failed" || !strings.Contains(results[0].Error, "run interrupted") {
+		t.Fatalf("expected the running step to fail as interrupted, got %+v", results)
+	}
+	if next := results[1]; next.Status != "not_run" || next.SkipReason != SkipReasonInterrupt {
+		t.Errorf("expected the next step not to run, got %+v", next)
+	}
+	if !executor.Interrupted() {
+		t.Error("expected the executor to report the interrupt")
+	}
+}
+
+// TestResultStopReason tests deciding why a run stopped from its results
+// alone
+func TestResultStopReason(t *testing.T) {
+	tests := []struct {
+		result StepResult
+		want   string
+	}{
+		{StepResult{Status: "not_run", SkipReason: SkipReasonDeadline}, SkipReasonDeadline},
+		{StepResult{Status: "not_run", SkipReason: SkipReasonBreakpoint}, SkipReasonBreakpoint},
+		{StepResult{Status: "failed", Error: "command failed (exit 124): " + errRunDeadlineExceeded.Error()}, SkipReasonDeadline},
+		{StepResult{Status: "failed", Error: "command failed (exit 130): " + errRunInterrupted.Error()}, SkipReasonInterrupt},
+		{StepResult{Status: "failed", Error: "command failed (exit 1)"}, ""},
+		{StepResult{Status: "success", Output: errRunDeadlineExceeded.Error()}, ""},
+	}
+	for _, tt := range tests {
+		if got := resultStopReason(tt.result); got != tt.want {
+			t.Errorf("resultStopReason(%+v) = %q, want %q", tt.result, got, tt.want)
+		}
+	}
+}
+
+// TestRetryInterrupted tests that an interrupt ends a retry loop's wait
+func TestRetryInterrupted(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses POSIX shell commands")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	executor := NewExecutor(NewLocalTransport())
+	executor.Context = ctx
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	retry := "until"
+	start := time.Now()
+	result := executor.executeCommand("Wait", CommandStep{Command: "false", Retry: &retry, Timeout: json.RawMessage(`{"interval": "5m", "poll_interval": "10s"}`)}, Facts{})
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the retry wait to end at once, took %s", elapsed)
+	}
+	if result.Status != "failed" || !strings.Contains(result.Error, "run interrupted after attempt 1") {
+		t.Errorf("expected an interrupted retry, got %+v", result)
+	}
+}
+
+// TestSleepContext tests waits that end early when the context is done
+func TestSleepContext(t *testing.T) {
+	if !sleepContext(context.Background(), time.Millisecond) {
+		t.Error("expected a full sleep")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if sleepContext(ctx, time.Minute) {
+		t.Error("expected a cancelled sleep to end early")
+	}
+	if err := runStopped(context.Background(), errors.New("boom")); err.Error() != "boom" {
+		t.Errorf("expected the original error while the run goes on, got %v", err)
+	}
+}
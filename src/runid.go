@@ -0,0 +1,77 @@
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// runIDRegex limits --run-id values to characters that are safe in file
+// names, since the run ID names the run workspace
+var runIDRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,127}$`)
+
+// generateRunID generates a unique run ID of the form <host>-<uuidv7>, e.g.
+// web01-01928c4e-7a3b-7c1d-9e2f-0123456789ab. The UUIDv7 sorts by start time
+// and its random bits keep IDs unique across hosts; the short hostname tells
+// a reader where the run happened.
+func generateRunID() string {
+	uuid := newUUIDv7(time.Now())
+	if host := runIDHost(); host != "" {
+		return host + "-" + uuid
+	}
+	return uuid
+}
+
+// runIDHost returns the first label of the hostname, lowercased and limited
+// to [a-z0-9-]
+func runIDHost() string {
+	name, err := os.Hostname()
+	if err != nil {
+		return ""
+	}
+	name, _, _ = strings.Cut(strings.ToLower(name), ".")
+
+	var b strings.Builder
+	for _, r := range name {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' {
+			b.WriteRune(r)
+		}
+		if b.Len() >= 32 {
+			break
+		}
+	}
+	return strings.Trim(b.String(), "-")
+}
+
+// newUUIDv7 returns an RFC 9562 version 7 UUID: a 48-bit Unix millisecond
+// timestamp followed by random bits
+func newUUIDv7(now time.Time) string {
+	var u [16]byte
+	if _, err := rand.Read(u[6:]); err != nil {
+		// crypto/rand does not fail on supported platforms; fall back to the clock
+		nanos := now.UnixNano()
+		for i := 6; i < 16; i++ {
+			u[i] = byte(nanos >> (8 * (i - 6)))
+		}
+	}
+
+	ms := uint64(now.UnixMilli())
+	for i := 0; i < 6; i++ {
+		u[i] = byte(ms >> (40 - 8*i))
+	}
+	u[6] = 0x70 | (u[6] & 0x0f) // Version 7
+	u[8] = 0x80 | (u[8] & 0x3f) // RFC 9562 variant
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
+
+// validateRunID checks a --run-id value
+func validateRunID(id string) error {
+	if !runIDRegex.MatchString(id) {
+		return fmt.Errorf("invalid --run-id '%s' (use up to 128 letters, digits, '.', '_' or '-', starting with a letter or digit)", id)
+	}
+	return nil
+}
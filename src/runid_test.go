@@ -0,0 +1,75 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+var uuidV7Regex = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+// TestNewUUIDv7 tests the version, variant and timestamp layout
+func TestNewUUIDv7(t *testing.T) {
+	now := time.UnixMilli(0x0123456789ab)
+	a, b := newUUIDv7(now), newUUIDv7(now)
+
+	if !uuidV7Regex.MatchString(a) {
+		t.Errorf("%s is not a version 7 UUID", a)
+	}
+	if !strings.HasPrefix(a, "01234567-89ab-7") {
+		t.Errorf("%s does not start with the millisecond timestamp", a)
+	}
+	if a == b {
+		t.Error("UUIDs generated in the same millisecond should differ")
+	}
+	if later := newUUIDv7(now.Add(time.Millisecond)); later[:13] <= a[:13] {
+		t.Errorf("later UUID %s should sort after %s", later, a)
+	}
+}
+
+// TestGenerateRunID tests the <host>-<uuidv7> format
+func TestGenerateRunID(t *testing.T) {
+	id := generateRunID()
+	uuid := id[max(0, len(id)-36):]
+	if !uuidV7Regex.MatchString(uuid) {
+		t.Errorf("run ID %s does not end with a UUIDv7", id)
+	}
+	if host := runIDHost(); host != "" && id != host+"-"+uuid {
+		t.Errorf("run ID %s should start with host %s", id, host)
+	}
+	if err := validateRunID(id); err != nil {
+		t.Errorf("generated run ID fails validation: %v", err)
+	}
+}
+
+// TestValidateRunID tests --run-id validation
+func TestValidateRunID(t *testing.T) {
+	for _, id := range []string{"ci-1234", "deploy.2024_10_01", "A"} {
+		if err := validateRunID(id); err != nil {
+			t.Errorf("validateRunID(%q): %v", id, err)
+		}
+	}
+	for _, id := range []string{"", "-leading", "has space", "../escape", "a/b", strings.Repeat("x", 129)} {
+		if err := validateRunID(id); err == nil {
+			t.Errorf("validateRunID(%q) should fail", id)
+		}
+	}
+}
+
+// TestExecutor_SetRunID tests that an external run ID reaches events and the workspace
+func TestExecutor_SetRunID(t *testing.T) {
+	executor := NewExecutor(&MockTransport{responses: map[string]MockResponse{}})
+	executor.SetRunID("ci-42")
+
+	var event ExecutionEvent
+	executor.OnEvent = func(e ExecutionEvent) { event = e }
+	executor.emitEvent(ExecutionEvent{StepName: "x", Status: "running"})
+
+	if event.RunID != "ci-42" {
+		t.Errorf("event run ID = %q", event.RunID)
+	}
+	if !strings.HasSuffix(executor.Workspace, "sink-ci-42") {
+		t.Errorf("workspace %s should use the run ID", executor.Workspace)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// SchemaURLFormat is where a tagged release publishes its schema; %s is the
+// version. Pinning the tag rather than main keeps editors validating a
+// config against the schema of the sink that generated it.
+const SchemaURLFormat = "https://raw.githubusercontent.com/radiolabme/sink/v%s/src/sink.schema.json"
+
+// schemaVersionRegex finds the release tag in a "$schema" reference, e.g.
+// the v0.3.2 of ".../radiolabme/sink/v0.3.2/src/sink.schema.json"
+var schemaVersionRegex = regexp.MustCompile(`[/@]v(\d+)\.\d+\.\d+(?:-[0-9A-Za-z.-]+)?/`)
+
+// schemaURL returns the "$schema" reference pinned to this binary's release
+func schemaURL() string {
+	return fmt.Sprintf(SchemaURLFormat, Version)
+}
+
+// withSchemaURL adds a "$schema" reference pinned to this binary's schema
+// as the first key of a generated config. The rest of the text, comments
+// and formatting included, is kept; configs that already name a schema or
+// are not a JSON object are returned unchanged.
+func withSchemaURL(data []byte) []byte {
+	plain := stripJSONC(data)
+	var doc map[string]json.RawMessage
+	if json.Unmarshal(plain, &doc) != nil {
+		return data
+	}
+	if _, ok := doc["$schema"]; ok {
+		return data
+	}
+
+	text := string(data)
+	open := strings.IndexByte(string(plain), '{') // Not one in a comment before it
+	rest := text[open+1:]
+	var entry string
+	switch {
+	case len(doc) == 0:
+		entry = fmt.Sprintf("\n  %q: %q\n", "$schema", schemaURL())
+	case strings.HasPrefix(rest, "\n") || strings.HasPrefix(rest, "\r\n"):
+		// Indent like the first key, on a line of its own
+		line := strings.TrimLeft(rest, "\r\n")
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		entry = fmt.Sprintf("\n%s%q: %q,", indent, "$schema", schemaURL())
+	default:
+		entry = fmt.Sprintf("%q: %q, ", "$schema", schemaURL())
+	}
+	return []byte(text[:open+1] + entry + text[open+1:])
+}
+
+// schemaVersionWarning describes a "$schema" reference to a different major
+// schema version than the one this binary embeds, or returns "" when the
+// reference names no version (main, a local path) or the same major version
+func schemaVersionWarning(ref string) string {
+	m := schemaVersionRegex.FindStringSubmatch(ref)
+	if m == nil {
+		return ""
+	}
+	embedded, _, _ := strings.Cut(SchemaVersion, ".")
+	if m[1] == embedded {
+		return ""
+	}
+	return fmt.Sprintf("$schema %s is for schema version %s.x but sink %s validates against %s; editors may accept keys sink rejects or flag ones it needs",
+		ref, m[1], Version, SchemaVersion)
+}
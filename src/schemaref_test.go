@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// TestWithSchemaURL tests adding a pinned "$schema" to generated configs
+func TestWithSchemaURL(t *testing.T) {
+	pinned := schemaURL()
+	if !strings.Contains(pinned, "/v"+Version+"/") {
+		t.Fatalf("expected %s pinned to v%s", pinned, Version)
+	}
+
+	tests := []struct {
+		name, input, expected string
+	}{
+		{
+			name:     "indented",
+			input:    "{\n    // Comment\n    \"version\": \"1.0.0\"\n}\n",
+			expected: "{\n    \"$schema\": \"" + pinned + "\",\n    // Comment\n    \"version\": \"1.0.0\"\n}\n",
+		},
+		{
+			name:     "one line",
+			input:    `{"version": "1.0.0"}`,
+			expected: "{\"$schema\": \"" + pinned + "\", " + `"version": "1.0.0"}`,
+		},
+		{name: "empty object", input: "{}", expected: "{\n  \"$schema\": \"" + pinned + "\"\n}"},
+		{name: "already set", input: `{"$schema": "../src/sink.schema.json"}`, expected: `{"$schema": "../src/sink.schema.json"}`},
+		{name: "not an object", input: `[1]`, expected: `[1]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(withSchemaURL([]byte(tt.input))); got != tt.expected {
+				t.Errorf("withSchemaURL(%q) = %q, want %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+
+	// Every template still loads, strictly, with its schema reference
+	templates, err := listTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tmpl := range templates {
+		data, _ := loadTemplate(tmpl.Name)
+		config, err := parseConfigData(withSchemaURL(data), LoadOptions{Strict: true})
+		if err != nil {
+			t.Errorf("template %s: %v", tmpl.Name, err)
+		} else if config.Schema != pinned {
+			t.Errorf("template %s: $schema = %q", tmpl.Name, config.Schema)
+		}
+		if !json.Valid(withSchemaURL(data)) {
+			t.Errorf("template %s: rendered invalid JSON", tmpl.Name)
+		}
+	}
+}
+
+// TestSchemaVersionWarning tests warning about "$schema" references to
+// another major schema version
+func TestSchemaVersionWarning(t *testing.T) {
+	major, _, _ := strings.Cut(SchemaVersion, ".")
+	other := "9"
+	if major == other {
+		other = "8"
+	}
+	tests := []struct {
+		ref  string
+		warn bool
+	}{
+		{schemaURL(), false},
+		{"https://raw.githubusercontent.com/radiolabme/sink/v" + major + ".99.0/src/sink.schema.json", false},
+		{"https://raw.githubusercontent.com/radiolabme/sink/v" + other + ".0.0/src/sink.schema.json", true},
+		{"https://example.com/sink@v" + other + ".1.0-rc.1/sink.schema.json", true},
+		{"https://raw.githubusercontent.com/radiolabme/sink/main/src/sink.schema.json", false},
+		{"../src/sink.schema.json", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		got := schemaVersionWarning(tt.ref)
+		if (got != "") != tt.warn {
+			t.Errorf("schemaVersionWarning(%q) = %q, want warning: %v", tt.ref, got, tt.warn)
+		}
+	}
+}
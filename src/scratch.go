@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Scratch is a run's key/value scratch space. A step's "scratch" templates
+// write it once the step succeeds, and later steps read the values as
+// {{.sink.scratch.<name>}}, so steps can pass results along without temp
+// files. Safe for concurrent use.
+type Scratch struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+// Set stores a value
+func (s *Scratch) Set(name, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.values == nil {
+		s.values = map[string]string{}
+	}
+	s.values[name] = value
+}
+
+// Values returns a copy of the stored values
+func (s *Scratch) Values() map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	values := make(map[string]string, len(s.values))
+	for name, value := range s.values {
+		values[name] = value
+	}
+	return values
+}
+
+// writeScratch renders a successful step's "scratch" templates, which also
+// see the step's trimmed standard output as {{.sink.stdout}}, and stores
+// them. Templates are rendered in name order before any value is stored,
+// so they all see the values from before the step.
+func (e *Executor) writeScratch(step InstallStep, facts Facts, result StepResult) error {
+	facts = withSinkFact(facts, "stdout", strings.TrimSpace(result.Stdout))
+	values := map[string]string{}
+	for _, name := range sortedKeys(step.Scratch) {
+		value, err := e.interpolate(step.Scratch[name], facts)
+		if err != nil {
+			return fmt.Errorf("scratch %s: %w", name, err)
+		}
+		values[name] = value
+	}
+	for name, value := range values {
+		e.scratch.Set(name, value)
+		if e.Verbose {
+			verboseLog("Scratch %s = %s", name, activeRedactor.Redact(value))
+		}
+	}
+	return nil
+}
+
+// withSinkFact returns facts with one {{.sink.*}} helper replaced, leaving
+// the original facts untouched
+func withSinkFact(facts Facts, name string, value interface{}) Facts {
+	result := make(Facts, len(facts))
+	for key, fact := range facts {
+		result[key] = fact
+	}
+	helpers := map[string]interface{}{}
+	if current, ok := facts[sinkFactsKey].(map[string]interface{}); ok {
+		for key, helper := range current {
+			helpers[key] = helper
+		}
+	}
+	helpers[name] = value
+	result[sinkFactsKey] = helpers
+	return result
+}
+
+// withAttempt returns facts for attempt n (from 1) of a retried command
+func withAttempt(facts Facts, n int) Facts {
+	return withSinkFact(facts, "attempt", strconv.Itoa(n))
+}
+
+// templateInt reads a template value as an integer; "" (an unset scratch
+// value) is 0
+func templateInt(value interface{}) (int64, error) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case float64:
+		return int64(v), nil
+	case string:
+		if strings.TrimSpace(v) == "" {
+			return 0, nil
+		}
+		return strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+	}
+	return 0, fmt.Errorf("not an integer: %v", value)
+}
+
+// templateAdd sums integers or integer strings, for counters such as
+// {{add (index .sink.scratch "installed") 1}}
+func templateAdd(values ...interface{}) (int64, error) {
+	var sum int64
+	for _, value := range values {
+		n, err := templateInt(value)
+		if err != nil {
+			return 0, fmt.Errorf("add: %w", err)
+		}
+		sum += n
+	}
+	return sum, nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// TestStepRunFacts tests {{.sink.run_id}}, {{.sink.step_index}} and
+// {{.sink.attempt}}, which is re-rendered for every retry
+func TestStepRunFacts(t *testing.T) {
+	attempts := 0
+	transport := &MockTransportWithTracking{responses: map[string]MockResponse{
+		"deploy run-7 step-0": {},
+		"wait --attempt 3":    {},
+		"wait --attempt 1":    {exitCode: 1},
+		"wait --attempt 2":    {exitCode: 1},
+		"report run-7 step-2": {},
+	}, onRun: func(cmd string) {
+		if strings.HasPrefix(cmd, "wait") {
+			attempts++
+		}
+	}}
+	executor := NewExecutor(transport)
+	executor.SetRunID("run-7")
+	retry, timeout := "until", json.RawMessage(`{"duration": "10s", "poll_interval": "1ms"}`)
+	results := executor.ExecutePlatform(Platform{InstallSteps: []InstallStep{
+		{Name: "deploy", Step: CommandStep{Command: "deploy {{.sink.run_id}} step-{{.sink.step_index}}"}},
+		{Name: "wait", Step: CommandStep{Command: "wait --attempt {{.sink.attempt}}", Retry: &retry, Timeout: timeout}},
+		{Name: "report", Step: CommandStep{Command: "report {{.sink.run_id}} step-{{.sink.step_index}}"}},
+	}}, Facts{})
+
+	for _, result := range results {
+		if result.Error != "" {
+			t.Errorf("step %s failed: %s (calls %v)", result.StepName, result.Error, transport.calls)
+		}
+	}
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d: %v", attempts, transport.calls)
+	}
+}
+
+// TestStepScratch tests storing values with "scratch" and reading them in
+// later steps
+func TestStepScratch(t *testing.T) {
+	var step InstallStep
+	if err := json.Unmarshal([]byte(`{"name": "version", "command": "get-version", "scratch": {"release": "v{{.sink.stdout}}", "count": "{{add (index .sink.scratch \"count\") 1}}"}}`), &step); err != nil {
+		t.Fatalf("failed to parse scratch step: %v", err)
+	}
+	transport := &MockTransportWithTracking{responses: map[string]MockResponse{
+		"get-version":   {stdout: "1.4.2\n"},
+		"tag v1.4.2 1":  {},
+		"count-again 2": {},
+	}}
+	executor := NewExecutor(transport)
+	recount := InstallStep{Name: "recount", Step: CommandStep{Command: "true"}, Scratch: map[string]string{"count": "{{add .sink.scratch.count 1}}"}}
+	transport.responses["true"] = MockResponse{}
+	results := executor.ExecutePlatform(Platform{InstallSteps: []InstallStep{
+		step,
+		{Name: "tag", Step: CommandStep{Command: "tag {{.sink.scratch.release}} {{.sink.scratch.count}}"}},
+		recount,
+		{Name: "count again", Step: CommandStep{Command: "count-again {{.sink.scratch.count}}"}},
+	}}, Facts{})
+	for _, result := range results {
+		if result.Error != "" {
+			t.Errorf("step %s failed: %s (calls %v)", result.StepName, result.Error, transport.calls)
+		}
+	}
+
+	// A failing step stores nothing, and a broken template fails the step
+	executor = NewExecutor(transport)
+	results = executor.ExecutePlatform(Platform{InstallSteps: []InstallStep{
+		{Name: "fails", Step: CommandStep{Command: "missing"}, Scratch: map[string]string{"x": "set"}},
+		{Name: "broken", Step: CommandStep{Command: "true"}, Scratch: map[string]string{"x": "{{.nope}}"}},
+	}}, Facts{})
+	if len(executor.scratch.Values()) != 0 {
+		t.Errorf("expected nothing stored, got %v", executor.scratch.Values())
+	}
+	if len(results) == 0 || results[0].Error == "" {
+		t.Errorf("expected the first step to fail: %+v", results)
+	}
+
+	executor = NewExecutor(transport)
+	result := executor.ExecuteStep(InstallStep{Name: "broken", Step: CommandStep{Command: "true"}, Scratch: map[string]string{"x": "{{.nope}}"}}, Facts{})
+	if !strings.Contains(result.Error, "scratch x") {
+		t.Errorf("expected a scratch template error, got %q", result.Error)
+	}
+
+	if err := json.Unmarshal([]byte(`{"name": "bad", "command": "true", "scratch": {"Bad-Name": "x"}}`), &step); err == nil {
+		t.Error("expected an invalid scratch name to be rejected")
+	}
+}
+
+// TestTemplateAdd tests the add template function
+func TestTemplateAdd(t *testing.T) {
+	if sum, err := templateAdd("", 1, "41", int64(2)); err != nil || sum != 44 {
+		t.Errorf("templateAdd = %d, %v", sum, err)
+	}
+	if _, err := templateAdd("many"); err == nil {
+		t.Error("expected an error for a non-integer")
+	}
+}
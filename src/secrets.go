@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Secret fact sources
+const (
+	SecretSourceVault = "vault"  // vault:secret/path#key (vault CLI, VAULT_ADDR/VAULT_TOKEN)
+	SecretSourceAWSSM = "aws-sm" // aws-sm:name#key (aws CLI, default credential chain)
+)
+
+// RedactedValue replaces secret values in displays, events and logs
+const RedactedValue = "<redacted>"
+
+// minRedactLength is the shortest secret value replaced inside other text;
+// shorter values would mangle unrelated output
+const minRedactLength = 4
+
+// SecretSource is a parsed fact "source"
+type SecretSource struct {
+	Kind string // SecretSourceVault or SecretSourceAWSSM
+	Path string // Vault path or Secrets Manager secret name/ARN
+	Key  string // Field within the secret ("" means the whole secret, aws-sm only)
+}
+
+// ParseSecretSource parses "vault:secret/path#key" or "aws-sm:name#key"
+func ParseSecretSource(source string) (SecretSource, error) {
+	kind, rest, ok := strings.Cut(source, ":")
+	if !ok || (kind != SecretSourceVault && kind != SecretSourceAWSSM) {
+		return SecretSource{}, fmt.Errorf("invalid source '%s' (expected vault:<path>#<key> or aws-sm:<name>[#<key>])", source)
+	}
+
+	// Secrets Manager ARNs contain ':', so the key is split off at the last '#'
+	path, key := rest, ""
+	if i := strings.LastIndex(rest, "#"); i >= 0 {
+		path, key = rest[:i], rest[i+1:]
+	}
+	if path == "" {
+		return SecretSource{}, fmt.Errorf("invalid source '%s': missing secret path", source)
+	}
+	if kind == SecretSourceVault && key == "" {
+		return SecretSource{}, fmt.Errorf("invalid source '%s': vault sources need a #key", source)
+	}
+
+	return SecretSource{Kind: kind, Path: path, Key: key}, nil
+}
+
+// Command returns the shell command that fetches the secret with the
+// provider's CLI and its ambient credentials
+func (s SecretSource) Command() string {
+	switch s.Kind {
+	case SecretSourceVault:
+		return fmt.Sprintf("vault kv get -field=%s %s", shellQuote(s.Key), shellQuote(s.Path))
+	default:
+		return fmt.Sprintf("aws secretsmanager get-secret-value --secret-id %s --query SecretString --output text", shellQuote(s.Path))
+	}
+}
+
+// Extract returns the fact value from the command output
+func (s SecretSource) Extract(output string) (string, error) {
+	value := strings.TrimRight(output, "\r\n")
+	if s.Kind != SecretSourceAWSSM || s.Key == "" {
+		return value, nil
+	}
+
+	// Key/value secrets are stored as a JSON object
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(value), &fields); err != nil {
+		return "", fmt.Errorf("secret %s is not a JSON object, cannot select key '%s'", s.Path, s.Key)
+	}
+	field, ok := fields[s.Key]
+	if !ok {
+		keys := make([]string, 0, len(fields))
+		for k := range fields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		return "", fmt.Errorf("secret %s has no key '%s' (keys: %s)", s.Path, s.Key, strings.Join(keys, ", "))
+	}
+	if str, ok := field.(string); ok {
+		return str, nil
+	}
+	return fmt.Sprint(field), nil
+}
+
+// IsSecret reports whether a fact's value must be redacted
+func (def FactDef) IsSecret() bool {
+	return def.Secret || def.Source != ""
+}
+
+// Redactor replaces secret values in text
+type Redactor struct {
+	values []string
+}
+
+// activeRedactor hides the secret facts of the current run in verbose logs,
+// events and transcripts (set once facts are gathered; nil redacts nothing)
+var activeRedactor *Redactor
+
+// NewRedactor returns a redactor for the secret facts among facts
+func NewRedactor(definitions map[string]FactDef, facts Facts) *Redactor {
+	r := &Redactor{}
+	for name, def := range definitions {
+		if !def.IsSecret() {
+			continue
+		}
+		if value, ok := facts[name]; ok {
+			if s := fmt.Sprint(value); len(s) >= minRedactLength {
+				r.values = append(r.values, s)
+			}
+		}
+	}
+	// Longest first, so a secret containing another is replaced whole
+	sort.Slice(r.values, func(i, j int) bool { return len(r.values[i]) > len(r.values[j]) })
+	return r
+}
+
+// Redact replaces every secret value in s. A nil redactor returns s unchanged.
+func (r *Redactor) Redact(s string) string {
+	if r == nil {
+		return s
+	}
+	for _, value := range r.values {
+		s = strings.ReplaceAll(s, value, RedactedValue)
+	}
+	return s
+}
+
+// redactEvent redacts the free-text fields of an execution event
+func (r *Redactor) redactEvent(event ExecutionEvent) ExecutionEvent {
+	if r == nil || len(r.values) == 0 {
+		return event
+	}
+	event.Output = r.Redact(event.Output)
+	event.Error = r.Redact(event.Error)
+	event.Command = r.Redact(event.Command)
+	event.Stdout = r.Redact(event.Stdout)
+	event.Stderr = r.Redact(event.Stderr)
+	event.Message = r.Redact(event.Message)
+	event.CustomError = r.Redact(event.CustomError)
+	event.OutputMismatch = r.redactMismatch(event.OutputMismatch)
+	return event
+}
+
+// redactMismatch redacts expected and actual check output
+func (r *Redactor) redactMismatch(m *OutputMismatch) *OutputMismatch {
+	if m == nil {
+		return nil
+	}
+	return &OutputMismatch{Expected: r.Redact(m.Expected), Actual: r.Redact(m.Actual)}
+}
+
+// displayFactValue renders a fact value for humans, hiding secrets
+func displayFactValue(def FactDef, value interface{}) string {
+	if def.IsSecret() {
+		return RedactedValue
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestParseSecretSource tests parsing of fact sources
+func TestParseSecretSource(t *testing.T) {
+	tests := []struct {
+		source  string
+		want    SecretSource
+		wantErr string
+	}{
+		{"vault:secret/data/app#api_key", SecretSource{Kind: "vault", Path: "secret/data/app", Key: "api_key"}, ""},
+		{"aws-sm:prod/db#password", SecretSource{Kind: "aws-sm", Path: "prod/db", Key: "password"}, ""},
+		{"aws-sm:prod/token", SecretSource{Kind: "aws-sm", Path: "prod/token"}, ""},
+		{"aws-sm:arn:aws:secretsmanager:us-east-1:123:secret:db#user", SecretSource{Kind: "aws-sm", Path: "arn:aws:secretsmanager:us-east-1:123:secret:db", Key: "user"}, ""},
+		{"vault:secret/data/app", SecretSource{}, "need a #key"},
+		{"aws-sm:#key", SecretSource{}, "missing secret path"},
+		{"gcp:projects/x", SecretSource{}, "invalid source"},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseSecretSource(tt.source)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseSecretSource(%q): expected error containing %q, got %v", tt.source, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseSecretSource(%q) = %+v, %v; want %+v", tt.source, got, err, tt.want)
+		}
+	}
+}
+
+// TestGatherSecretFacts tests resolving secret sources through the transport
+func TestGatherSecretFacts(t *testing.T) {
+	transport := &MockTransport{
+		responses: map[string]MockResponse{
+			"vault kv get -field='api_key' 'secret/data/app'":                                              {stdout: "vault-s3cret\n"},
+			"aws secretsmanager get-secret-value --secret-id 'prod/db' --query SecretString --output text": {stdout: `{"username":"app","password":"aws-s3cret"}` + "\n"},
+		},
+	}
+
+	definitions := map[string]FactDef{
+		"api_key":     {Source: "vault:secret/data/app#api_key", Required: true},
+		"db_password": {Source: "aws-sm:prod/db#password", Required: true},
+		"db_missing":  {Source: "aws-sm:prod/db#token"},
+	}
+	facts, err := NewFactGatherer(definitions, transport).Gather()
+	if err != nil {
+		t.Fatalf("Gather failed: %v", err)
+	}
+	if facts["api_key"] != "vault-s3cret" || facts["db_password"] != "aws-s3cret" {
+		t.Errorf("Unexpected secret facts: %v", facts)
+	}
+	if _, ok := facts["db_missing"]; ok {
+		t.Error("Optional fact with a missing key should be skipped")
+	}
+
+	definitions["db_missing"] = FactDef{Source: "aws-sm:prod/db#token", Required: true}
+	if _, err := NewFactGatherer(definitions, transport).Gather(); err == nil || !strings.Contains(err.Error(), "has no key 'token' (keys: password, username)") {
+		t.Errorf("Expected missing key error, got %v", err)
+	}
+}
+
+// TestRedactor tests that secret fact values are hidden in events and transcripts
+func TestRedactor(t *testing.T) {
+	definitions := map[string]FactDef{
+		"token":   {Source: "vault:secret/app#token"},
+		"pin":     {Command: "echo 12", Secret: true},
+		"version": {Command: "echo 1.0"},
+	}
+	facts := Facts{"token": "abcd1234", "pin": "12", "version": "1.0"}
+
+	oldRedactor := activeRedactor
+	activeRedactor = NewRedactor(definitions, facts)
+	defer func() { activeRedactor = oldRedactor }()
+
+	if got := activeRedactor.Redact("curl -H 'Token: abcd1234' v1.0"); got != "curl -H 'Token: <redacted>' v1.0" {
+		t.Errorf("Redact = %q", got)
+	}
+
+	transport := &MockTransport{responses: map[string]MockResponse{
+		"echo abcd1234": {stdout: "abcd1234\n"},
+	}}
+	executor := NewExecutor(transport)
+	executor.Verbose = true
+	executor.Transcript = NewTranscript()
+	var events []ExecutionEvent
+	executor.OnEvent = func(event ExecutionEvent) { events = append(events, event) }
+	executor.ExecutePlatform(Platform{
+		InstallSteps: []InstallStep{{Name: "use token", Step: CommandStep{Command: "echo {{.token}}"}}},
+	}, facts)
+
+	for _, event := range events {
+		if strings.Contains(event.Output+event.Command+event.Stdout, "abcd1234") {
+			t.Errorf("Secret leaked in %s event: %+v", event.Status, event)
+		}
+	}
+	if md := executor.Transcript.Markdown(); strings.Contains(md, "abcd1234") || !strings.Contains(md, "echo <redacted>") {
+		t.Errorf("Secret not redacted in transcript:\n%s", md)
+	}
+
+	if got := displayFactValue(definitions["pin"], "12"); got != RedactedValue {
+		t.Errorf("displayFactValue = %q, want %q", got, RedactedValue)
+	}
+}
+
+// TestSecretFactValidation tests command/source validation
+func TestSecretFactValidation(t *testing.T) {
+	if err := ValidateFactDef("token", FactDef{Source: "vault:secret/app#token"}); err != nil {
+		t.Errorf("Expected valid source fact, got %v", err)
+	}
+	if err := ValidateFactDef("token", FactDef{Command: "echo x", Source: "vault:secret/app#token"}); err == nil || !strings.Contains(err.Error(), "mutually exclusive") {
+		t.Errorf("Expected mutually exclusive error, got %v", err)
+	}
+	if err := ValidateFactDef("token", FactDef{Source: "vault:secret/app"}); err == nil {
+		t.Error("Expected error for vault source without a key")
+	}
+}
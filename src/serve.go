@@ -0,0 +1,376 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultServeAddress is the listen address for sink serve-config
+const DefaultServeAddress = ":8321"
+
+// Probe endpoints, answered before the served directory is consulted
+const (
+	healthzPath = "/healthz" // Liveness: the process is serving requests
+	readyzPath  = "/readyz"  // Readiness: the config directory is readable
+	statusPath  = "/status"  // Server state as JSON
+)
+
+// ConfigServerOptions controls how serve-config exposes a directory
+type ConfigServerOptions struct {
+	Dir               string // Directory of configs to serve
+	GenerateChecksums bool   // Serve <file>.sha256 sidecars and /SHA256SUMS computed on the fly
+	Token             string // Required bearer token (empty means no auth)
+}
+
+// serveConfigCommand serves a directory of configs over HTTP
+func serveConfigCommand() {
+	opts := ConfigServerOptions{}
+	listen := DefaultServeAddress
+
+	args := os.Args[2:]
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch arg {
+		case "-h", "--help":
+			printServeConfigHelp()
+			os.Exit(0)
+		case "--generate-checksums":
+			opts.GenerateChecksums = true
+		case "--listen", "--token":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: %s requires a value\n", arg)
+				os.Exit(1)
+			}
+			if arg == "--listen" {
+				listen = args[i+1]
+			} else {
+				opts.Token = args[i+1]
+			}
+			i++
+		default:
+			if strings.HasPrefix(arg, "-") || opts.Dir != "" {
+				fmt.Fprintf(os.Stderr, "Error: unexpected argument: %s\n", arg)
+				os.Exit(1)
+			}
+			opts.Dir = arg
+		}
+	}
+
+	if opts.Dir == "" {
+		fmt.Fprintf(os.Stderr, "Error: config directory required\n\n")
+		printServeConfigHelp()
+		os.Exit(1)
+	}
+	if info, err := os.Stat(opts.Dir); err != nil || !info.IsDir() {
+		fmt.Fprintf(os.Stderr, "Error: %s is not a directory\n", opts.Dir)
+		os.Exit(1)
+	}
+
+	fmt.Printf("📡 Serving %s on %s\n", opts.Dir, listen)
+	if opts.GenerateChecksums {
+		fmt.Println("   Checksums: <file>.sha256 and /SHA256SUMS generated on request")
+	}
+	if opts.Token != "" {
+		fmt.Println("   Auth:      bearer token or ?token= required")
+	}
+	fmt.Printf("   Probes:    %s, %s and %s\n", healthzPath, readyzPath, statusPath)
+	fmt.Println()
+	fmt.Println("   Bootstrap a machine (sink checksum <config>.json prints the sha256 here):")
+	fmt.Printf("     sink bootstrap http://<this-host>%s/<config>.json --sha256 <sha256>\n", listenPort(listen))
+	fmt.Println()
+
+	if err := http.ListenAndServe(listen, newConfigServer(opts)); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// listenPort returns the ":port" part of a listen address for display
+func listenPort(listen string) string {
+	if i := strings.LastIndex(listen, ":"); i >= 0 {
+		return listen[i:]
+	}
+	return ""
+}
+
+// ServerStatus is the /status response
+type ServerStatus struct {
+	Dir         string `json:"dir"`
+	Ready       bool   `json:"ready"`
+	Started     string `json:"started"`
+	Uptime      string `json:"uptime"`
+	Requests    int64  `json:"requests"`
+	Errors      int64  `json:"errors"`                 // Responses with status 500 and above
+	LastRequest string `json:"last_request,omitempty"` // Time of the last request for a file
+}
+
+// configServer serves a directory of configs and tracks enough state to
+// answer supervisor probes
+type configServer struct {
+	opts    ConfigServerOptions
+	started time.Time
+
+	mu          sync.Mutex
+	requests    int64
+	errors      int64
+	lastRequest time.Time
+}
+
+// newConfigServer returns a handler serving opts.Dir read-only
+func newConfigServer(opts ConfigServerOptions) http.Handler {
+	return &configServer{opts: opts, started: time.Now()}
+}
+
+// ServeHTTP implements http.Handler
+func (s *configServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case healthzPath:
+		io.WriteString(w, "ok\n")
+		return
+	case readyzPath:
+		if err := s.ready(); err != nil {
+			http.Error(w, "not ready: "+err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		io.WriteString(w, "ok\n")
+		return
+	}
+
+	var status int
+	if r.URL.Path == statusPath {
+		status = s.serveStatus(w, r)
+	} else {
+		status = serveConfigRequest(w, r, s.opts)
+		s.mu.Lock()
+		s.requests++
+		if status >= http.StatusInternalServerError {
+			s.errors++
+		}
+		s.lastRequest = time.Now()
+		s.mu.Unlock()
+	}
+	fmt.Printf("%s %s %s %d\n", r.RemoteAddr, r.Method, r.URL.Path, status)
+}
+
+// ready reports whether the config directory can be listed
+func (s *configServer) ready() error {
+	f, err := os.Open(s.opts.Dir)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Readdirnames(1)
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
+
+// serveStatus answers /status, behind the same token as the configs
+func (s *configServer) serveStatus(w http.ResponseWriter, r *http.Request) int {
+	if s.opts.Token != "" && !requestHasToken(r, s.opts.Token) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return http.StatusUnauthorized
+	}
+
+	s.mu.Lock()
+	status := ServerStatus{
+		Dir:      s.opts.Dir,
+		Started:  eventTimestamp(s.started),
+		Uptime:   time.Since(s.started).Round(time.Second).String(),
+		Requests: s.requests,
+		Errors:   s.errors,
+	}
+	if !s.lastRequest.IsZero() {
+		status.LastRequest = eventTimestamp(s.lastRequest)
+	}
+	s.mu.Unlock()
+	status.Ready = s.ready() == nil
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	out, _ := json.MarshalIndent(status, "", "  ")
+	w.Write(append(out, '\n'))
+	return http.StatusOK
+}
+
+// serveConfigRequest handles a single request and returns the response status
+func serveConfigRequest(w http.ResponseWriter, r *http.Request, opts ConfigServerOptions) int {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return http.StatusMethodNotAllowed
+	}
+	if opts.Token != "" && !requestHasToken(r, opts.Token) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return http.StatusUnauthorized
+	}
+
+	// path.Clean on a rooted path cannot escape the served directory
+	urlPath := path.Clean("/" + r.URL.Path)
+	for _, part := range strings.Split(urlPath, "/") {
+		if strings.HasPrefix(part, ".") {
+			http.NotFound(w, r)
+			return http.StatusNotFound
+		}
+	}
+	file := filepath.Join(opts.Dir, filepath.FromSlash(urlPath))
+
+	if opts.GenerateChecksums {
+		if _, err := os.Stat(file); os.IsNotExist(err) {
+			switch {
+			case urlPath == "/SHA256SUMS":
+				manifest, err := generateChecksumManifest(opts.Dir)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return http.StatusInternalServerError
+				}
+				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+				io.WriteString(w, manifest)
+				return http.StatusOK
+			case strings.HasSuffix(urlPath, ".sha256"):
+				target := strings.TrimSuffix(file, ".sha256")
+				checksum, err := fileSHA256(target)
+				if err != nil {
+					http.NotFound(w, r)
+					return http.StatusNotFound
+				}
+				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+				fmt.Fprintf(w, "%s  %s\n", checksum, filepath.Base(target))
+				return http.StatusOK
+			}
+		}
+	}
+
+	info, err := os.Stat(file)
+	if err != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return http.StatusNotFound
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	http.ServeFile(w, r, file)
+	return http.StatusOK
+}
+
+// requestHasToken checks the Authorization header or the token query parameter
+func requestHasToken(r *http.Request, token string) bool {
+	given := r.URL.Query().Get("token")
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		given = strings.TrimPrefix(auth, "Bearer ")
+	}
+	return subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
+
+// generateChecksumManifest builds a SHA256SUMS manifest for every regular,
+// non-hidden file under dir, keyed by slash-separated relative path
+// (WalkDir visits files in lexical order, so the output is stable)
+func generateChecksumManifest(dir string) (string, error) {
+	var lines []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p != dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || strings.HasSuffix(p, ".sha256") {
+			return nil
+		}
+
+		checksum, err := fileSHA256(p)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		lines = append(lines, fmt.Sprintf("%s  %s", checksum, filepath.ToSlash(rel)))
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(lines, "\n") + "\n", nil
+}
+
+// fileSHA256 returns the hex SHA256 of a regular file
+func fileSHA256(file string) (string, error) {
+	info, err := os.Stat(file)
+	if err != nil {
+		return "", err
+	}
+	if !info.Mode().IsRegular() {
+		return "", fmt.Errorf("%s is not a regular file", file)
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func printServeConfigHelp() {
+	fmt.Print(`sink serve-config - Serve a directory of configs over HTTP
+
+Usage:
+  sink serve-config <dir> [options]
+
+Description:
+  Serves the files in <dir> read-only so lab machines can bootstrap from an
+  operator laptop. Hidden files are never served.
+
+Options:
+  --listen <addr>        Address to listen on (default: :8321)
+  --generate-checksums   Serve <file>.sha256 sidecars and a /SHA256SUMS
+                         manifest computed from the current file contents
+                         (files that exist on disk take precedence)
+  --token <token>        Require "Authorization: Bearer <token>" or
+                         ?token=<token> on every request except the
+                         /healthz and /readyz probes
+  -h, --help             Show this help message
+
+Probes:
+  /healthz               200 while the server is running (liveness)
+  /readyz                200 while <dir> is readable, 503 otherwise
+  /status                JSON with uptime and request counts (needs the
+                         token when one is set)
+  These paths are answered by sink, not looked up in <dir>.
+
+Examples:
+  # Serve ./configs with checksums
+  sink serve-config ./configs --listen :8321 --generate-checksums
+
+  # Bootstrap from another machine with the checksum computed here
+  # (served checksums travel over the same plain HTTP as the config)
+  sink checksum configs/dev.json
+  sink bootstrap http://laptop:8321/dev.json --sha256 <sha256>
+
+  # Require a token
+  sink serve-config ./configs --generate-checksums --token s3cret
+  sink bootstrap "http://laptop:8321/dev.json?token=s3cret" --sha256 <sha256>
+`)
+}
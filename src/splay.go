@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// parseSplay parses a --splay value such as "10m"
+func parseSplay(value string) (time.Duration, error) {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid --splay '%s': %w", value, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("--splay must be positive, got '%s'", value)
+	}
+	return d, nil
+}
+
+// randomSplay picks a uniformly random delay in [0, max)
+func randomSplay(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(max)))
+}
+
+// waitSplay sleeps a random duration up to max so that a fleet of machines
+// started at the same moment does not hit the config server all at once
+func waitSplay(max time.Duration, jsonOutput bool) {
+	delay := randomSplay(max)
+	if delay == 0 {
+		return
+	}
+	if !jsonOutput {
+		fmt.Printf("⏳ Splay: waiting %s before starting (max %s)\n", delay.Round(time.Second), max)
+	}
+	time.Sleep(delay)
+}
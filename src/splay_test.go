@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestParseSplay tests --splay value parsing
+func TestParseSplay(t *testing.T) {
+	d, err := parseSplay("10m")
+	if err != nil || d != 10*time.Minute {
+		t.Errorf("parseSplay(10m) = %v, %v", d, err)
+	}
+	for _, value := range []string{"", "soon", "0s", "-5m"} {
+		if _, err := parseSplay(value); err == nil {
+			t.Errorf("expected error for %q", value)
+		}
+	}
+}
+
+// TestRandomSplay tests that splay delays stay within bounds
+func TestRandomSplay(t *testing.T) {
+	if d := randomSplay(0); d != 0 {
+		t.Errorf("randomSplay(0) = %v, want 0", d)
+	}
+	for i := 0; i < 100; i++ {
+		if d := randomSplay(time.Second); d < 0 || d >= time.Second {
+			t.Fatalf("randomSplay(1s) = %v, out of range", d)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// sshConnectionFailed is the exit status ssh reports when it could not
+// connect, authenticate or verify the host key, as opposed to the status of
+// the remote command
+const sshConnectionFailed = 255
+
+// SSHConnectTimeout bounds how long the SSH transport waits to connect
+const SSHConnectTimeout = 15 * time.Second
+
+// SSHOptions controls how sink authenticates to and verifies SSH hosts. The
+// system ssh and scp are used, so ~/.ssh/config applies beneath these.
+type SSHOptions struct {
+	Identity     string // Private key to authenticate with (-i); only this key is offered
+	ForwardAgent bool   // Forward the local ssh-agent, so steps can reach other hosts (-A)
+	KnownHosts   string // known_hosts file to verify host keys against instead of ~/.ssh/known_hosts
+	AcceptNew    bool   // Record the keys of hosts not seen before; changed keys are still refused
+}
+
+// args returns the ssh and scp options for o
+func (o SSHOptions) args() []string {
+	var args []string
+	if o.Identity != "" {
+		args = append(args, "-i", o.Identity, "-o", "IdentitiesOnly=yes")
+	}
+	if o.ForwardAgent {
+		args = append(args, "-o", "ForwardAgent=yes")
+	}
+	if o.KnownHosts != "" {
+		args = append(args, "-o", "UserKnownHostsFile="+o.KnownHosts)
+	}
+	switch {
+	case o.AcceptNew:
+		args = append(args, "-o", "StrictHostKeyChecking=accept-new")
+	case o.KnownHosts != "":
+		args = append(args, "-o", "StrictHostKeyChecking=yes")
+	}
+	return args
+}
+
+// splitSSHTarget splits a "user@host" or "user@host:port" target. An IPv6
+// address takes a port only in brackets ("user@[::1]:22"), which are
+// dropped from the host since ssh expects the bare address.
+func splitSSHTarget(target string) (host, port string) {
+	user, rest := "", target
+	if i := strings.LastIndex(target, "@"); i >= 0 {
+		user, rest = target[:i+1], target[i+1:]
+	}
+	if strings.HasPrefix(rest, "[") {
+		if end := strings.Index(rest, "]"); end > 0 {
+			return user + rest[1:end], strings.TrimPrefix(rest[end+1:], ":")
+		}
+	}
+	if i := strings.LastIndex(rest, ":"); i > 0 && strings.Count(rest, ":") == 1 {
+		return user + rest[:i], rest[i+1:]
+	}
+	return target, ""
+}
+
+// scpHost returns a "user@host" as scp addresses it, with an IPv6 address
+// in brackets so its colons are not read as the path separator
+func scpHost(target string) string {
+	user, host := "", target
+	if i := strings.LastIndex(target, "@"); i >= 0 {
+		user, host = target[:i+1], target[i+1:]
+	}
+	if strings.Contains(host, ":") {
+		return user + "[" + host + "]"
+	}
+	return target
+}
+
+// SSHTransport runs commands on a remote host with the system ssh client
+// and copies files with scp. Every command is a separate ssh session run
+// by sh, whatever the remote user's login shell. Host keys are always
+// verified, and authentication never prompts: a run is unattended once it
+// starts, so a missing key or an unknown host fails the command instead.
+type SSHTransport struct {
+	Host    string     // user@host
+	Port    string     // Empty for the default port (or ~/.ssh/config's)
+	Options SSHOptions // Authentication and host key verification
+	Env     []string   // NAME=value pairs exported before every command (see SetEnv)
+}
+
+// NewSSHTransport creates a transport for a "user@host" or "user@host:port"
+// target
+func NewSSHTransport(target string, opts SSHOptions) *SSHTransport {
+	host, port := splitSSHTarget(target)
+	return &SSHTransport{Host: host, Port: port, Options: opts}
+}
+
+// Capabilities reports that remote commands run with sh, can be cancelled
+// (the ssh session is closed) and given stdin, and that files are copied
+// with scp. Output is captured, not streamed.
+func (st *SSHTransport) Capabilities() Capabilities {
+	return Capabilities{
+		Shell:        ShellPOSIX,
+		Cancel:       true,
+		Stdin:        true,
+		FileTransfer: true,
+	}
+}
+
+// SetEnv implements EnvTransport. sshd only passes variables the server
+// allows (AcceptEnv), so they are exported by the command itself.
+func (st *SSHTransport) SetEnv(env []string) {
+	st.Env = env
+}
+
+// sshArgs returns the ssh arguments that run command on the host
+func (st *SSHTransport) sshArgs(command string) []string {
+	args := []string{
+		"-o", "BatchMode=yes",
+		"-o", fmt.Sprintf("ConnectTimeout=%d", int(SSHConnectTimeout.Seconds())),
+	}
+	if !st.Options.AcceptNew && st.Options.KnownHosts == "" {
+		args = append(args, "-o", "StrictHostKeyChecking=yes")
+	}
+	args = append(args, st.Options.args()...)
+	if st.Port != "" {
+		args = append(args, "-p", st.Port)
+	}
+	if len(st.Env) > 0 {
+		exports := make([]string, len(st.Env))
+		for i, entry := range st.Env {
+			name, value, _ := strings.Cut(entry, "=")
+			exports[i] = name + "=" + shellQuote(value)
+		}
+		command = "export " + strings.Join(exports, " ") + "\n" + command
+	}
+	// ssh joins its arguments into one line for the login shell, so the
+	// command is quoted once for that shell and run by sh
+	return append(args, "--", st.Host, "sh -c "+shellQuote(command))
+}
+
+// Run executes a command on the host
+func (st *SSHTransport) Run(command string) (stdout, stderr string, exitCode int, err error) {
+	return st.RunContext(context.Background(), command)
+}
+
+// RunContext executes a command on the host, closing the session when ctx
+// is done. A cancelled command returns ctx.Err() as its error.
+func (st *SSHTransport) RunContext(ctx context.Context, command string) (stdout, stderr string, exitCode int, err error) {
+	return st.RunInput(ctx, command, "")
+}
+
+// RunInput runs a command like RunContext with stdin as its standard input.
+// Failing to connect is an error; the remote command's own failures are
+// reported by exit code like LocalTransport's.
+func (st *SSHTransport) RunInput(ctx context.Context, command string, stdin string) (stdout, stderr string, exitCode int, err error) {
+	cmd := exec.CommandContext(ctx, "ssh", st.sshArgs(command)...)
+	cmd.WaitDelay = time.Second
+
+	var outBuf, errBuf bytes.Buffer
+	cmd.Stdout = &outBuf
+	cmd.Stderr = &errBuf
+	if stdin != "" {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
+
+	err = cmd.Run()
+	stdout, stderr = outBuf.String(), errBuf.String()
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return stdout, stderr, -1, ctxErr
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return stdout, stderr, 0, nil
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == sshConnectionFailed:
+		return stdout, stderr, sshConnectionFailed, fmt.Errorf("ssh %s: %s", st.Host, sshError(stderr, err))
+	case errors.As(err, &exitErr):
+		return stdout, stderr, exitErr.ExitCode(), nil
+	}
+	return stdout, stderr, 127, fmt.Errorf("ssh %s: %w", st.Host, err)
+}
+
+// Upload copies a local file to a path on the host
+func (st *SSHTransport) Upload(local, remote string) error {
+	return st.scp(local, scpHost(st.Host)+":"+remote)
+}
+
+// Download copies a file on the host to a local path
+func (st *SSHTransport) Download(remote, local string) error {
+	return st.scp(scpHost(st.Host)+":"+remote, local)
+}
+
+// scp copies src to dst, keeping the file's mode
+func (st *SSHTransport) scp(src, dst string) error {
+	args := []string{"-q", "-p", "-o", "BatchMode=yes"}
+	if !st.Options.AcceptNew && st.Options.KnownHosts == "" {
+		args = append(args, "-o", "StrictHostKeyChecking=yes")
+	}
+	args = append(args, st.Options.args()...)
+	if st.Port != "" {
+		args = append(args, "-P", st.Port)
+	}
+	args = append(args, "--", src, dst)
+	output, err := exec.Command("scp", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("scp %s %s: %s", src, dst, sshError(string(output), err))
+	}
+	return nil
+}
+
+// sshError describes a failed ssh or scp by its last line of stderr, which
+// holds the reason ("Host key verification failed.", "Permission denied")
+func sshError(stderr string, err error) string {
+	lines := strings.Split(strings.TrimSpace(stderr), "\n")
+	if last := strings.TrimSpace(lines[len(lines)-1]); last != "" {
+		return last
+	}
+	return err.Error()
+}
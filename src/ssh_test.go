@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeSSH puts ssh and scp scripts first on PATH that run commands and copy
+// files locally, recording each ssh invocation's arguments in the returned
+// file. Hosts named "unreachable" fail like a host key mismatch.
+func fakeSSH(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake ssh needs a POSIX shell")
+	}
+	bin := t.TempDir()
+	log := filepath.Join(bin, "ssh.log")
+	ssh := `#!/bin/sh
+printf '%s\n' "$*" >> "` + log + `"
+for arg; do host="$last"; last="$arg"; done
+case "$host" in *unreachable*) echo "Host key verification failed." >&2; exit 255 ;; esac
+exec /bin/sh -c "$last"
+`
+	scp := `#!/bin/sh
+for arg; do src="$dst"; dst="$arg"; done
+exec cp "${src#*:}" "${dst#*:}"
+`
+	os.WriteFile(filepath.Join(bin, "ssh"), []byte(ssh), 0755)
+	os.WriteFile(filepath.Join(bin, "scp"), []byte(scp), 0755)
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return log
+}
+
+// TestSSHTransport_Args tests the ssh options for authentication and host
+// key verification
+func TestSSHTransport_Args(t *testing.T) {
+	st := NewSSHTransport("deploy@web01:2222", SSHOptions{})
+	args := strings.Join(st.sshArgs("echo 'hi'"), " ")
+	for _, want := range []string{"BatchMode=yes", "StrictHostKeyChecking=yes", "-p 2222", "-- deploy@web01 sh -c 'echo '\\''hi'\\'''"} {
+		if !strings.Contains(args, want) {
+			t.Errorf("expected %q in %s", want, args)
+		}
+	}
+
+	st = NewSSHTransport("deploy@web01", SSHOptions{Identity: "/keys/id", ForwardAgent: true, KnownHosts: "/keys/known", AcceptNew: true})
+	args = strings.Join(st.sshArgs("true"), " ")
+	for _, want := range []string{"-i /keys/id", "IdentitiesOnly=yes", "ForwardAgent=yes", "UserKnownHostsFile=/keys/known", "StrictHostKeyChecking=accept-new"} {
+		if !strings.Contains(args, want) {
+			t.Errorf("expected %q in %s", want, args)
+		}
+	}
+	if strings.Contains(args, "StrictHostKeyChecking=yes") || strings.Contains(args, "-p ") {
+		t.Errorf("unexpected options in %s", args)
+	}
+
+	// IPv6 hosts take a port only in brackets, and scp needs them back
+	for _, tc := range []struct{ target, host, port, scp string }{
+		{"deploy@web01", "deploy@web01", "", "deploy@web01"},
+		{"deploy@[::1]:2222", "deploy@::1", "2222", "deploy@[::1]"},
+		{"[fe80::1]", "fe80::1", "", "[fe80::1]"},
+		{"deploy@2001:db8::1", "deploy@2001:db8::1", "", "deploy@[2001:db8::1]"},
+	} {
+		host, port := splitSSHTarget(tc.target)
+		if host != tc.host || port != tc.port || scpHost(host) != tc.scp {
+			t.Errorf("%s: got host %q port %q scp %q", tc.target, host, port, scpHost(host))
+		}
+	}
+
+	// remote deploy passes the same options to its ssh and scp
+	shell := newSSHShell("deploy@web01", SSHOptions{Identity: "/keys/id"})
+	if args := strings.Join(shell.sshArgs(false, "true"), " "); !strings.Contains(args, "-i /keys/id") {
+		t.Errorf("expected the identity in deploy's ssh arguments, got %s", args)
+	}
+}
+
+// TestSSHTransport_Run tests running commands, exit codes, stdin and
+// connection failures over ssh
+func TestSSHTransport_Run(t *testing.T) {
+	fakeSSH(t)
+	st := NewSSHTransport("deploy@web01", SSHOptions{})
+
+	stdout, _, exitCode, err := st.Run("echo \"$0\" && exit 3")
+	if err != nil || exitCode != 3 || strings.TrimSpace(stdout) != "sh" {
+		t.Errorf("expected sh's output and exit code 3, got %q, %d, %v", stdout, exitCode, err)
+	}
+
+	stdout, _, _, err = st.RunInput(context.Background(), "tr a-z A-Z", "quoted 'input'")
+	if err != nil || stdout != "QUOTED 'INPUT'" {
+		t.Errorf("expected stdin to reach the command, got %q, %v", stdout, err)
+	}
+
+	st.SetEnv([]string{"SINK_OS=it's Linux"})
+	stdout, _, _, err = st.Run(`echo "$SINK_OS"`)
+	if err != nil || stdout != "it's Linux\n" {
+		t.Errorf("expected the exported variable on the host, got %q, %v", stdout, err)
+	}
+
+	_, _, exitCode, err = NewSSHTransport("deploy@unreachable", SSHOptions{}).Run("true")
+	if err == nil || exitCode != sshConnectionFailed || !strings.Contains(err.Error(), "Host key verification failed.") {
+		t.Errorf("expected a connection error, got %d, %v", exitCode, err)
+	}
+}
+
+// TestSSHTransport_Files tests copying files to and from the host
+func TestSSHTransport_Files(t *testing.T) {
+	fakeSSH(t)
+	st := NewSSHTransport("deploy@web01", SSHOptions{})
+	dir := t.TempDir()
+	local := filepath.Join(dir, "local.txt")
+	os.WriteFile(local, []byte("payload"), 0600)
+
+	remote := filepath.Join(dir, "remote.txt")
+	if err := st.Upload(local, remote); err != nil {
+		t.Fatal(err)
+	}
+	back := filepath.Join(dir, "back.txt")
+	if err := st.Download(remote, back); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(back); string(data) != "payload" {
+		t.Errorf("expected the file to round-trip, got %q", data)
+	}
+	if err := st.Upload(filepath.Join(dir, "missing"), remote); err == nil {
+		t.Error("expected copying a missing file to fail")
+	}
+}
+
+// TestSSHTransport_Executor tests that the executor discovers the remote
+// context and runs steps over ssh
+func TestSSHTransport_Executor(t *testing.T) {
+	log := fakeSSH(t)
+	executor := NewExecutor(NewSSHTransport("deploy@web01", SSHOptions{}))
+
+	ctx := executor.GetContext()
+	if ctx.Transport != "ssh" || ctx.Shell != ShellPOSIX || ctx.Host == "" || ctx.OS == "" {
+		t.Errorf("expected a context probed over ssh, got %+v", ctx)
+	}
+
+	results := executor.ExecutePlatform(Platform{InstallSteps: []InstallStep{
+		{Name: "Greet", Step: CommandStep{Command: "echo hello from {{.name}}"}},
+	}}, Facts{"name": "web01"})
+	if len(results) != 1 || results[0].Status != "success" || !strings.Contains(results[0].Output, "hello from web01") {
+		t.Errorf("expected the step to run over ssh, got %+v", results)
+	}
+	if data, _ := os.ReadFile(log); !strings.Contains(string(data), "deploy@web01 sh -c 'echo hello from web01'") {
+		t.Errorf("expected the step's command in the ssh log, got:\n%s", data)
+	}
+}
+
+// TestGOOSFromUname tests mapping uname -s names to platform "os" values
+func TestGOOSFromUname(t *testing.T) {
+	for name, want := range map[string]string{
+		"Linux": "linux", "Darwin": "darwin", "FreeBSD": "freebsd", "OpenBSD": "openbsd",
+		"SunOS": "solaris", "Windows_NT": "windows", "MINGW64_NT-10.0-19045": "windows",
+	} {
+		if got := goosFromUname(name); got != want {
+			t.Errorf("goosFromUname(%q) = %q, want %q", name, got, want)
+		}
+	}
+	for _, goos := range []string{"linux", "darwin", "freebsd", "openbsd", "netbsd", "windows"} {
+		if got := goosFromUname(unameOS(goos)); got != goos {
+			t.Errorf("goosFromUname(unameOS(%q)) = %q", goos, got)
+		}
+	}
+}
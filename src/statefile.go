@@ -0,0 +1,222 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// stateFileRuns is how many runs a state file keeps; older ones are dropped
+// when a run is recorded
+const stateFileRuns = 20
+
+// StateFile records the result of every step of runs started with
+// --state-file, keyed by run ID, so a run cut short by a network drop or a
+// reboot can be resumed with --resume
+type StateFile struct {
+	Runs map[string]*StateRun `json:"runs"`
+
+	path string
+}
+
+// StateRun is one run in a state file
+type StateRun struct {
+	Config   string            `json:"config"`   // Config file or URL the run executed
+	Platform string            `json:"platform"` // Platform the steps came from
+	Started  time.Time         `json:"started"`
+	Updated  time.Time         `json:"updated"`
+	Steps    []StateStep       `json:"steps"`             // Steps that ran, in order
+	Scratch  map[string]string `json:"scratch,omitempty"` // Scratch space after the last step, restored on resume
+}
+
+// StateStep is the last result of one step of a run
+type StateStep struct {
+	Name        string    `json:"name"`
+	Status      string    `json:"status"` // "success" or "failed"
+	Error       string    `json:"error,omitempty"`
+	Fingerprint string    `json:"fingerprint"` // SHA256 of the step's definition; a changed step runs again
+	Finished    time.Time `json:"finished"`
+}
+
+// loadStateFile reads a state file; one that does not exist yet is empty
+func loadStateFile(path string) (*StateFile, error) {
+	state := &StateFile{Runs: map[string]*StateRun{}, path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read state file: %w", err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("invalid state file %s: %w", path, err)
+	}
+	if state.Runs == nil {
+		state.Runs = map[string]*StateRun{}
+	}
+	return state, nil
+}
+
+// latest returns the ID of the most recently updated run of a config and
+// platform, or "" when there is none
+func (s *StateFile) latest(config, platform string) string {
+	var runID string
+	var updated time.Time
+	for id, run := range s.Runs {
+		if run.Config == config && run.Platform == platform && run.Updated.After(updated) {
+			runID, updated = id, run.Updated
+		}
+	}
+	return runID
+}
+
+// save writes the state file atomically, keeping the stateFileRuns most
+// recently updated runs
+func (s *StateFile) save() error {
+	if len(s.Runs) > stateFileRuns {
+		ids := sortedKeys(s.Runs)
+		sort.SliceStable(ids, func(i, j int) bool { return s.Runs[ids[i]].Updated.After(s.Runs[ids[j]].Updated) })
+		for _, id := range ids[stateFileRuns:] {
+			delete(s.Runs, id)
+		}
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(s.path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// RunState connects a run to its entry in a state file. Its methods do
+// nothing on a nil RunState, so the executor calls them unconditionally.
+type RunState struct {
+	file  *StateFile
+	runID string
+	run   *StateRun
+
+	// completedSteps maps the steps a resumed run finished earlier to
+	// their fingerprints
+	completedSteps map[string]string
+}
+
+// newRunState starts recording a run in a state file under requestedID
+// (--run-id), or newID when none was requested. With resume, the run
+// requested or else the latest run of the same config and platform is
+// continued under its own ID, and its successful steps are not run again.
+func newRunState(file *StateFile, requestedID, newID, config, platform string, resume bool) *RunState {
+	state := &RunState{file: file, runID: requestedID, completedSteps: map[string]string{}}
+	if resume {
+		if state.runID == "" {
+			state.runID = file.latest(config, platform)
+		}
+		if run := file.Runs[state.runID]; run != nil {
+			state.run = run
+			for _, step := range run.Steps {
+				if step.Status == "success" {
+					state.completedSteps[step.Name] = step.Fingerprint
+				}
+			}
+		}
+	}
+	if state.runID == "" {
+		state.runID = newID
+	}
+	if state.run == nil {
+		now := time.Now().UTC()
+		state.run = &StateRun{Config: config, Platform: platform, Started: now, Updated: now}
+	}
+	return state
+}
+
+// Resumed reports how many steps an earlier attempt of the run completed
+func (r *RunState) Resumed() int {
+	if r == nil {
+		return 0
+	}
+	return len(r.completedSteps)
+}
+
+// completed reports whether a resumed run finished the step earlier,
+// unchanged since
+func (r *RunState) completed(step InstallStep) bool {
+	if r == nil {
+		return false
+	}
+	fingerprint, ok := r.completedSteps[step.Name]
+	return ok && fingerprint == stepFingerprint(step)
+}
+
+// scratch returns the scratch space as the earlier attempt left it
+func (r *RunState) scratch() map[string]string {
+	if r == nil {
+		return nil
+	}
+	return r.run.Scratch
+}
+
+// record stores a finished step's result and the scratch space, and writes
+// the state file. Steps that did not finish (deferred, pending or skipped)
+// keep their earlier entry.
+func (r *RunState) record(step InstallStep, result StepResult, scratch map[string]string) error {
+	if r == nil {
+		return nil
+	}
+	switch result.Status {
+	case "deferred", "pending", "skipped", "not_run":
+		return nil
+	}
+	entry := StateStep{
+		Name:        step.Name,
+		Status:      "success",
+		Fingerprint: stepFingerprint(step),
+		Finished:    time.Now().UTC(),
+	}
+	if result.Error != "" {
+		entry.Status = "failed"
+		entry.Error = result.Error
+	}
+
+	replaced := false
+	for i := range r.run.Steps {
+		if r.run.Steps[i].Name == step.Name {
+			r.run.Steps[i] = entry
+			replaced = true
+		}
+	}
+	if !replaced {
+		r.run.Steps = append(r.run.Steps, entry)
+	}
+	if len(scratch) > 0 {
+		r.run.Scratch = scratch
+	}
+	r.run.Updated = entry.Finished
+	r.file.Runs[r.runID] = r.run
+	return r.file.save()
+}
+
+// stepFingerprint identifies a step's definition, so a resumed run runs a
+// step again when the config changed it
+func stepFingerprint(step InstallStep) string {
+	data, _ := json.Marshal(struct {
+		Name string
+		Step StepVariant
+	}{step.Name, step.Step})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestStateFileResume tests that a resumed run skips the steps an earlier
+// attempt completed, runs the rest and keeps the scratch space
+func TestStateFileResume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "install.json")
+	failing := true
+	var ran []string
+	transport := &StatefulMockTransport{runFunc: func(cmd string) (string, string, int, error) {
+		switch strings.Fields(cmd + " ?")[0] {
+		case "prepare", "install", "configure":
+			ran = append(ran, cmd)
+		}
+		if cmd == "install app" && failing {
+			return "", "network unreachable", 1, fmt.Errorf("exit status 1")
+		}
+		return "", "", 0, nil
+	}}
+	platform := Platform{Name: "Linux", InstallSteps: []InstallStep{
+		{Name: "Prepare", Scratch: map[string]string{"prepared": "yes"}, Step: CommandStep{Command: "prepare"}},
+		{Name: "Install", Step: CommandStep{Command: "install app"}},
+		{Name: "Configure", Step: CommandStep{Command: "configure {{.sink.scratch.prepared}}"}},
+	}}
+
+	run := func(resume bool) (*RunState, []StepResult) {
+		t.Helper()
+		file, err := loadStateFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		executor := NewExecutor(transport)
+		state := newRunState(file, "", executor.runID, "/etc/sink/install.json", platform.Name, resume)
+		executor.SetRunID(state.runID)
+		executor.State = state
+		return state, executor.ExecutePlatform(platform, Facts{})
+	}
+
+	first, results := run(false)
+	if len(results) != 2 || results[1].Error == "" {
+		t.Fatalf("expected the first attempt to fail at Install, got %+v", results)
+	}
+
+	failing = false
+	ran = nil
+	resumed, results := run(true)
+	if resumed.runID != first.runID || resumed.Resumed() != 1 {
+		t.Errorf("expected to resume run %s with 1 completed step, got %s with %d", first.runID, resumed.runID, resumed.Resumed())
+	}
+	if len(results) != 3 || results[0].SkipReason != SkipReasonResumed || results[1].Error != "" || results[2].Error != "" {
+		t.Fatalf("expected Prepare to be skipped and the rest to succeed, got %+v", results)
+	}
+	if strings.Join(ran, ",") != "install app,configure yes" {
+		t.Errorf("unexpected commands %v", ran)
+	}
+
+	file, err := loadStateFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded := file.Runs[first.runID]
+	if len(file.Runs) != 1 || recorded == nil || len(recorded.Steps) != 3 || recorded.Steps[1].Status != "success" {
+		t.Errorf("expected one run with three successful steps, got %+v", file.Runs)
+	}
+
+	// A step changed since the earlier attempt runs again
+	platform.InstallSteps[0].Step = CommandStep{Command: "prepare --fast"}
+	ran = nil
+	if _, results = run(true); results[0].SkipReason == SkipReasonResumed || ran[0] != "prepare --fast" {
+		t.Errorf("expected the changed step to run again, got %+v (ran %v)", results[0], ran)
+	}
+}
+
+// TestStateFilePrune tests that a state file keeps only the latest runs
+func TestStateFilePrune(t *testing.T) {
+	file, err := loadStateFile(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	for i := 0; i < stateFileRuns+5; i++ {
+		file.Runs[fmt.Sprintf("run-%02d", i)] = &StateRun{Config: "c.json", Platform: "Linux", Updated: start.Add(time.Duration(i) * time.Second)}
+	}
+	if err := file.save(); err != nil {
+		t.Fatal(err)
+	}
+	if len(file.Runs) != stateFileRuns || file.Runs["run-04"] != nil || file.Runs["run-05"] == nil {
+		t.Errorf("expected the oldest runs to be dropped, got %d runs", len(file.Runs))
+	}
+	if latest := file.latest("c.json", "Linux"); latest != fmt.Sprintf("run-%02d", stateFileRuns+4) {
+		t.Errorf("unexpected latest run %q", latest)
+	}
+
+	if err := os.WriteFile(file.path, []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadStateFile(file.path); err == nil || !strings.Contains(err.Error(), "invalid state file") {
+		t.Errorf("expected an invalid state file error, got %v", err)
+	}
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// checkUnknownFields reports config keys that the embedded schema does not
+// define, e.g. a misspelled "on_missng", which json.Unmarshal would silently
+// ignore. Every object whose schema declares properties is treated as closed
+// unless it explicitly allows additional properties.
+func checkUnknownFields(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	var schema map[string]interface{}
+	if err := json.Unmarshal([]byte(embeddedSchema), &schema); err != nil {
+		return fmt.Errorf("invalid embedded schema: %w", err)
+	}
+
+	w := &schemaWalker{root: schema, unknown: map[string]string{}}
+	w.walk(value, schema, "")
+	if len(w.unknown) == 0 {
+		return nil
+	}
+
+	paths := make([]string, 0, len(w.unknown))
+	for path := range w.unknown {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	msgs := make([]string, len(paths))
+	for i, path := range paths {
+		msgs[i] = fmt.Sprintf("unknown field '%s'", path)
+		if suggestion := w.unknown[path]; suggestion != "" {
+			msgs[i] += fmt.Sprintf(" (did you mean '%s'?)", suggestion)
+		}
+	}
+	return fmt.Errorf("%s", strings.Join(msgs, "; "))
+}
+
+// checkDuplicateKeys reports object keys that appear more than once, such as
+// a fact defined twice. json.Unmarshal keeps the last value silently.
+func checkDuplicateKeys(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var duplicates []string
+
+	var walk func(path string) error
+	walk = func(path string) error {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'):
+			seen := map[string]bool{}
+			for dec.More() {
+				keyTok, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				key, _ := keyTok.(string)
+				childPath := key
+				if path != "" {
+					childPath = path + "." + key
+				}
+				if seen[key] {
+					duplicates = append(duplicates, childPath)
+				}
+				seen[key] = true
+				if err := walk(childPath); err != nil {
+					return err
+				}
+			}
+			_, err = dec.Token()
+			return err
+		case json.Delim('['):
+			for i := 0; dec.More(); i++ {
+				if err := walk(fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+			_, err = dec.Token()
+			return err
+		}
+		return nil
+	}
+
+	if err := walk(""); err != nil {
+		return err
+	}
+	if len(duplicates) == 0 {
+		return nil
+	}
+
+	msgs := make([]string, len(duplicates))
+	for i, path := range duplicates {
+		msgs[i] = fmt.Sprintf("duplicate key '%s'", path)
+		if strings.HasPrefix(path, "facts.") && strings.Count(path, ".") == 1 {
+			msgs[i] = fmt.Sprintf("duplicate fact '%s'", strings.TrimPrefix(path, "facts."))
+		}
+	}
+	return fmt.Errorf("%s", strings.Join(msgs, "; "))
+}
+
+// schemaWalker walks a JSON value alongside the subset of JSON Schema used by
+// sink.schema.json: properties, patternProperties, additionalProperties,
+// items, $ref and oneOf/anyOf/allOf.
+type schemaWalker struct {
+	root    map[string]interface{}
+	unknown map[string]string // path -> closest known key
+}
+
+// resolve follows local "#/..." references
+func (w *schemaWalker) resolve(node map[string]interface{}) map[string]interface{} {
+	for i := 0; i < 16; i++ {
+		ref, ok := node["$ref"].(string)
+		if !ok || !strings.HasPrefix(ref, "#/") {
+			return node
+		}
+		var target interface{} = w.root
+		for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			m, ok := target.(map[string]interface{})
+			if !ok {
+				return node
+			}
+			target = m[part]
+		}
+		next, ok := target.(map[string]interface{})
+		if !ok {
+			return node
+		}
+		node = next
+	}
+	return node
+}
+
+// branches returns node and every schema combined into it
+func (w *schemaWalker) branches(node map[string]interface{}) []map[string]interface{} {
+	node = w.resolve(node)
+	result := []map[string]interface{}{node}
+	for _, keyword := range []string{"oneOf", "anyOf", "allOf"} {
+		list, _ := node[keyword].([]interface{})
+		for _, item := range list {
+			if branch, ok := item.(map[string]interface{}); ok {
+				result = append(result, w.branches(branch)...)
+			}
+		}
+	}
+	return result
+}
+
+// walk records unknown keys in value
+func (w *schemaWalker) walk(value interface{}, node map[string]interface{}, path string) {
+	nodes := w.branches(node)
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		declared := false
+		open := false
+		var known []string
+		for _, n := range nodes {
+			if props, ok := n["properties"].(map[string]interface{}); ok {
+				declared = true
+				for key := range props {
+					known = append(known, key)
+				}
+			}
+			if _, ok := n["patternProperties"].(map[string]interface{}); ok {
+				declared = true
+			}
+			if ap, ok := n["additionalProperties"]; ok && ap != false {
+				open = true
+			}
+		}
+
+		for key, child := range v {
+			childPath := key
+			if path != "" {
+				childPath = path + "." + key
+			}
+
+			var subs []map[string]interface{}
+			for _, n := range nodes {
+				if props, ok := n["properties"].(map[string]interface{}); ok {
+					if sub, ok := props[key].(map[string]interface{}); ok {
+						subs = append(subs, sub)
+					}
+				}
+				if patterns, ok := n["patternProperties"].(map[string]interface{}); ok {
+					for pattern, sub := range patterns {
+						if re, err := regexp.Compile(pattern); err == nil && re.MatchString(key) {
+							if s, ok := sub.(map[string]interface{}); ok {
+								subs = append(subs, s)
+							}
+						}
+					}
+				}
+			}
+			if len(subs) == 0 {
+				for _, n := range nodes {
+					if ap, ok := n["additionalProperties"].(map[string]interface{}); ok {
+						subs = append(subs, ap)
+					}
+				}
+			}
+
+			if len(subs) == 0 {
+				if declared && !open {
+					w.unknown[childPath] = closestMatch(key, known)
+				}
+				continue
+			}
+			for _, sub := range subs {
+				w.walk(child, sub, childPath)
+			}
+		}
+
+	case []interface{}:
+		for _, n := range nodes {
+			items, ok := n["items"].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			for i, item := range v {
+				w.walk(item, items, fmt.Sprintf("%s[%d]", path, i))
+			}
+		}
+	}
+}
+
+// closestMatch returns the candidate with the smallest edit distance to name,
+// or "" when none is close enough to be a plausible typo
+func closestMatch(name string, candidates []string) string {
+	best := ""
+	bestDistance := len(name)/2 + 2 // Allow roughly one typo per two characters
+	for _, candidate := range candidates {
+		if d := editDistance(name, candidate); d < bestDistance || (d == bestDistance && candidate < best) {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+// editDistance computes the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestCheckUnknownFields tests that misspelled keys are reported with their path
+func TestCheckUnknownFields(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want []string // substrings of the error; empty means valid
+	}{
+		{
+			name: "valid config",
+			json: `{"version":"1.0.0","facts":{"arch":{"command":"uname -m","transform":{"x86_64":"amd64"}}},"defaults":{"package":"git","timeout":"30s"},"platforms":[{"os":"linux","match":"linux*","name":"L","install_steps":[{"name":"a","command":"true","timeout":{"interval":"5s","error_code":3}}]}]}`,
+		},
+		{
+			name: "top-level typo",
+			json: `{"version":"1.0.0","platfroms":[],"platforms":[]}`,
+			want: []string{"'platfroms' (did you mean 'platforms'?)"},
+		},
+		{
+			name: "step typo",
+			json: `{"version":"1.0.0","platforms":[{"os":"linux","match":"linux*","name":"L","install_steps":[{"name":"a","command":"true","retyr":"until"}]}]}`,
+			want: []string{"'platforms[0].install_steps[0].retyr' (did you mean 'retry'?)"},
+		},
+		{
+			name: "remediation and distribution typos",
+			json: `{"version":"1.0.0","platforms":[{"os":"linux","match":"linux","name":"L","distributions":[{"ids":["ubuntu"],"name":"U","install_steps":[{"name":"a","check":"true","on_missing":[{"name":"b","command":"x","slep":"1s"}]}],"idz":[]}]}]}`,
+			want: []string{
+				"platforms[0].distributions[0].install_steps[0].on_missing[0].slep",
+				"platforms[0].distributions[0].idz",
+			},
+		},
+		{
+			name: "fact typo without suggestion",
+			json: `{"version":"1.0.0","facts":{"os":{"command":"uname","zzzzzzzzzz":true}},"platforms":[]}`,
+			want: []string{"'facts.os.zzzzzzzzzz'"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkUnknownFields([]byte(tt.json))
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not contain %q", err, want)
+				}
+			}
+			if strings.Contains(err.Error(), "zzzzzzzzzz' (did you mean") {
+				t.Errorf("unexpected suggestion in %q", err)
+			}
+		})
+	}
+}
+
+// TestExamplesPassStrictParsing tests that every shipped example is strictly valid
+func TestExamplesPassStrictParsing(t *testing.T) {
+	files, _ := filepath.Glob("../examples/*.json")
+	if len(files) == 0 {
+		t.Skip("no examples found")
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := checkUnknownFields(data); err != nil {
+			t.Errorf("%s: %v", file, err)
+		}
+	}
+}
+
+// TestEditDistance tests the Levenshtein distance used for suggestions
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"retry", "retry", 0},
+		{"retyr", "retry", 2},
+		{"on_missng", "on_missing", 1},
+		{"kitten", "sitting", 3},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
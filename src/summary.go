@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Summary table modes (--summary)
+const (
+	SummaryShort = "short" // Table with notes cut to fit a terminal (default)
+	SummaryWide  = "wide"  // Table with full notes, including output of successful steps
+	SummaryNone  = "none"  // Only the final result line
+)
+
+// Column limits for the short summary
+const (
+	summaryMaxStep = 40
+	summaryMaxNote = 60
+)
+
+// summaryRow is one line of the end-of-run summary table
+type summaryRow struct {
+	Step     string
+	Status   string
+	Changed  string
+	Duration string
+	Note     string
+}
+
+// parseSummaryMode validates a --summary value
+func parseSummaryMode(value string) (string, error) {
+	switch value {
+	case SummaryShort, SummaryWide, SummaryNone:
+		return value, nil
+	}
+	return "", fmt.Errorf("invalid --summary '%s' (use wide, short or none)", value)
+}
+
+// summaryRows pairs each step with its result. Steps after a failure have no
+// result and are listed as not run.
+func summaryRows(steps []InstallStep, results []StepResult, dryRun bool, mode string) []summaryRow {
+	rows := make([]summaryRow, 0, len(steps))
+	for i, step := range steps {
+		if i >= len(results) {
+			rows = append(rows, summaryRow{Step: step.Name, Status: "not_run", Changed: "-", Duration: "-", Note: describeSkipReason(SkipReasonNotReached)})
+			continue
+		}
+		result := results[i]
+		row := summaryRow{
+			Step:     step.Name,
+			Status:   transcriptStatus(result),
+			Changed:  stepChanged(step, result, dryRun),
+			Duration: "-",
+			Note:     summaryNote(result, mode),
+		}
+		if result.Duration > 0 {
+			row.Duration = formatTranscriptDuration(result.Duration)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// stepChanged reports whether a step changed the host: commands are assumed
+// to, checks never do, check-with-remediation steps only when they
+// remediated, and copies and fetches unless the destination was up to date
+func stepChanged(step InstallStep, result StepResult, dryRun bool) string {
+	if dryRun || transcriptStatus(result) != "success" {
+		return "-"
+	}
+	switch v := step.Step.(type) {
+	case CommandStep:
+		return "yes"
+	case CheckRemediateStep:
+		if len(result.RemediationSteps) > 0 {
+			return "yes"
+		}
+	case CopyStep, FetchStep:
+		if !result.Unchanged {
+			return "yes"
+		}
+	case GroupStep:
+		for i, member := range result.GroupSteps {
+			if stepChanged(v.Group.Steps[i], member, dryRun) == "yes" {
+				return "yes"
+			}
+		}
+	}
+	return "no"
+}
+
+// summaryNote explains a step's status in one line
+func summaryNote(result StepResult, mode string) string {
+	var note string
+	switch transcriptStatus(result) {
+	case "failed":
+		note = result.Error
+		if result.Tolerated {
+			note = "continued: " + note
+		}
+	case "not_run", "skipped":
+		note = describeSkipReason(result.SkipReason)
+	case "success":
+		if result.RolledBack {
+			note = "rolled back"
+		} else if result.RollbackError != "" {
+			note = result.RollbackError
+		} else if len(result.RemediationSteps) > 0 {
+			note = "remediated"
+		} else if mode == SummaryWide {
+			note = result.Output
+		}
+	default:
+		note = result.Output
+	}
+
+	note = strings.TrimSpace(note)
+	if i := strings.IndexByte(note, '\n'); i >= 0 {
+		note = note[:i]
+	}
+	return note
+}
+
+// writeSummaryTable writes the rows as a table with aligned columns
+func writeSummaryTable(w io.Writer, rows []summaryRow, mode string) {
+	lines := [][]string{{"STEP", "STATUS", "CHANGED", "DURATION", "NOTE"}}
+	for _, row := range rows {
+		step, note := row.Step, row.Note
+		if mode != SummaryWide {
+			step = truncateRunes(step, summaryMaxStep)
+			note = truncateRunes(note, summaryMaxNote)
+		}
+		lines = append(lines, []string{step, transcriptStatusLabel(row.Status), row.Changed, row.Duration, note})
+	}
+
+	widths := make([]int, len(lines[0]))
+	for _, line := range lines {
+		for i, cell := range line {
+			if n := utf8.RuneCountInString(cell); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+
+	for _, line := range lines {
+		var b strings.Builder
+		for i, cell := range line {
+			if i == len(line)-1 {
+				b.WriteString(cell)
+				break
+			}
+			b.WriteString(cell)
+			b.WriteString(strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell)+2))
+		}
+		fmt.Fprintln(w, strings.TrimRight(b.String(), " "))
+	}
+}
+
+// truncateRunes cuts s to at most n runes, marking the cut with "…"
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:n-1]) + "…"
+}
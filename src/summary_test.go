@@ -0,0 +1,84 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestSummaryRows tests building summary rows from steps and results
+func TestSummaryRows(t *testing.T) {
+	steps := []InstallStep{
+		{Name: "install", Step: CommandStep{Command: "make install"}},
+		{Name: "docker", Step: CheckRemediateStep{Check: "command -v docker", OnMissing: []RemediationStep{{Name: "get", Command: "x"}}}},
+		{Name: "root", Step: CheckErrorStep{Check: "test -w /", Error: "need root"}},
+		{Name: "after", Step: CommandStep{Command: "echo after"}},
+	}
+	results := []StepResult{
+		{StepName: "install", Status: "success", Output: "installed\nmore", Duration: 1500 * time.Millisecond},
+		{StepName: "docker", Status: "success", RemediationSteps: []StepResult{{StepName: "get"}}},
+		{StepName: "root", Status: "failed", Error: "need root\nstderr: denied"},
+	}
+
+	rows := summaryRows(steps, results, false, SummaryShort)
+	want := []summaryRow{
+		{Step: "install", Status: "success", Changed: "yes", Duration: "1.5s", Note: ""},
+		{Step: "docker", Status: "success", Changed: "yes", Duration: "-", Note: "remediated"},
+		{Step: "root", Status: "failed", Changed: "-", Duration: "-", Note: "need root"},
+		{Step: "after", Status: "not_run", Changed: "-", Duration: "-", Note: "not reached"},
+	}
+	for i := range want {
+		if rows[i] != want[i] {
+			t.Errorf("row %d = %+v, want %+v", i, rows[i], want[i])
+		}
+	}
+
+	if wide := summaryRows(steps, results, false, SummaryWide); wide[0].Note != "installed" {
+		t.Errorf("wide note = %q, want first output line", wide[0].Note)
+	}
+	if dry := summaryRows(steps, results, true, SummaryShort); dry[0].Changed != "-" {
+		t.Errorf("dry run should not report changes, got %q", dry[0].Changed)
+	}
+}
+
+// TestWriteSummaryTable tests column alignment and truncation
+func TestWriteSummaryTable(t *testing.T) {
+	rows := []summaryRow{
+		{Step: "a", Status: "success", Changed: "yes", Duration: "1s"},
+		{Step: "longer step", Status: "failed", Changed: "-", Duration: "-", Note: strings.Repeat("x", 80)},
+	}
+
+	var b strings.Builder
+	writeSummaryTable(&b, rows, SummaryShort)
+	lines := strings.Split(strings.TrimRight(b.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected header and 2 rows, got:\n%s", b.String())
+	}
+	if lines[0] != "STEP         STATUS     CHANGED  DURATION  NOTE" {
+		t.Errorf("header = %q", lines[0])
+	}
+	if lines[1] != "a            ✓ success  yes      1s" {
+		t.Errorf("row = %q", lines[1])
+	}
+	if !strings.HasSuffix(lines[2], strings.Repeat("x", summaryMaxNote-1)+"…") {
+		t.Errorf("long note not truncated: %q", lines[2])
+	}
+
+	b.Reset()
+	writeSummaryTable(&b, rows, SummaryWide)
+	if !strings.Contains(b.String(), strings.Repeat("x", 80)) {
+		t.Error("wide mode should keep full notes")
+	}
+}
+
+// TestParseSummaryMode tests --summary validation
+func TestParseSummaryMode(t *testing.T) {
+	for _, mode := range []string{"wide", "short", "none"} {
+		if got, err := parseSummaryMode(mode); err != nil || got != mode {
+			t.Errorf("parseSummaryMode(%q) = %q, %v", mode, got, err)
+		}
+	}
+	if _, err := parseSummaryMode("full"); err == nil {
+		t.Error("expected error for unknown mode")
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// template: command:1:7: executing "command" at <.arhc>: map has no entry for key "arhc"
+	templateErrorPattern  = regexp.MustCompile(`^template: [^:]*:(\d+)(?::(\d+))?: (.*)$`)
+	templateActionPattern = regexp.MustCompile(`executing "[^"]*" at <([^>]*)>: (.*)$`)
+	templateMissingKey    = regexp.MustCompile(`map has no entry for key "([^"]*)"`)
+	templateUnknownFunc   = regexp.MustCompile(`function "([^"]*)" not defined`)
+)
+
+// TemplateError explains a failed command template in terms of the config:
+// where it failed, which fact or function was unknown, and what was likely meant
+type TemplateError struct {
+	Template   string // The command template as written
+	Line       int    // 1-based line within the template
+	Column     int    // 1-based column (0 when unknown)
+	Snippet    string // Offending action, or the offending line for parse errors
+	Key        string // Unknown fact or function name
+	Suggestion string // Closest known name, if any
+	Reason     string // Underlying text/template message
+	Available  []string
+}
+
+// Error implements error
+func (e *TemplateError) Error() string {
+	var b strings.Builder
+	switch {
+	case e.Key != "" && strings.HasPrefix(e.Reason, "function"):
+		fmt.Fprintf(&b, "unknown template function '%s'", e.Key)
+	case e.Key != "":
+		fmt.Fprintf(&b, "unknown fact '%s'", e.Key)
+	default:
+		b.WriteString(e.Reason)
+	}
+
+	if e.Column > 0 {
+		fmt.Fprintf(&b, " at line %d, column %d", e.Line, e.Column)
+	} else if e.Line > 0 {
+		fmt.Fprintf(&b, " at line %d", e.Line)
+	}
+	if e.Snippet != "" {
+		fmt.Fprintf(&b, " in %q", e.Snippet)
+	}
+
+	if e.Suggestion != "" {
+		fmt.Fprintf(&b, " (did you mean '%s'?)", e.Suggestion)
+	} else if e.Key != "" && !strings.HasPrefix(e.Reason, "function") {
+		if len(e.Available) == 0 {
+			b.WriteString(" (no facts are defined)")
+		} else {
+			fmt.Fprintf(&b, " (available facts: %s)", strings.Join(e.Available, ", "))
+		}
+	}
+	return b.String()
+}
+
+// explainTemplateError converts a text/template parse or execution error into
+// a TemplateError. Errors it cannot parse are returned unchanged.
+func explainTemplateError(err error, tmpl string, facts Facts, funcs []string) error {
+	matches := templateErrorPattern.FindStringSubmatch(err.Error())
+	if matches == nil {
+		return err
+	}
+
+	te := &TemplateError{Template: tmpl, Reason: matches[3]}
+	te.Line, _ = strconv.Atoi(matches[1])
+	te.Column, _ = strconv.Atoi(matches[2])
+
+	for name := range facts {
+		te.Available = append(te.Available, name)
+	}
+	sort.Strings(te.Available)
+
+	if action := templateActionPattern.FindStringSubmatch(te.Reason); action != nil {
+		te.Snippet = "{{" + action[1] + "}}"
+		te.Reason = action[2]
+	} else {
+		lines := strings.Split(tmpl, "\n")
+		if te.Line >= 1 && te.Line <= len(lines) {
+			te.Snippet = strings.TrimSpace(lines[te.Line-1])
+		}
+	}
+
+	if m := templateMissingKey.FindStringSubmatch(te.Reason); m != nil {
+		te.Key = m[1]
+		te.Suggestion = closestMatch(te.Key, te.Available)
+	} else if m := templateUnknownFunc.FindStringSubmatch(te.Reason); m != nil {
+		te.Key = m[1]
+		te.Suggestion = closestMatch(te.Key, funcs)
+	}
+
+	return te
+}
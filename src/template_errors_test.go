@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestTemplateErrorMessages tests that interpolation failures explain themselves
+func TestTemplateErrorMessages(t *testing.T) {
+	executor := NewExecutor(&MockTransport{})
+	facts := Facts{"arch": "amd64", "os_version": "14.2"}
+
+	tests := []struct {
+		name     string
+		template string
+		want     []string
+	}{
+		{
+			name:     "misspelled fact",
+			template: "curl -LO https://example.com/tool-{{.arhc}}.tar.gz",
+			want:     []string{"unknown fact 'arhc'", "line 1, column", `"{{.arhc}}"`, "did you mean 'arch'?"},
+		},
+		{
+			name:     "unknown fact without close match",
+			template: "echo first\necho {{.region}}",
+			want:     []string{"unknown fact 'region'", "line 2", "available facts: arch, os_version"},
+		},
+		{
+			name:     "misspelled function",
+			template: `echo {{checksm "tool.tar.gz"}}`,
+			want:     []string{"unknown template function 'checksm'", "line 1", "did you mean 'checksum'?"},
+		},
+		{
+			name:     "syntax error",
+			template: "echo {{.arch",
+			want:     []string{"template parse error", "unclosed action", `"echo {{.arch"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := executor.interpolate(tt.template, facts)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not contain %q", err, want)
+				}
+			}
+		})
+	}
+
+	var te *TemplateError
+	_, err := executor.interpolate("{{.arhc}}", facts)
+	if !errors.As(err, &te) || te.Key != "arhc" || te.Suggestion != "arch" {
+		t.Errorf("expected a TemplateError for arhc, got %#v", err)
+	}
+
+	if result, err := executor.interpolate("{{.arch}}-{{.os_version}}", facts); err != nil || result != "amd64-14.2" {
+		t.Errorf("valid template failed: %q, %v", result, err)
+	}
+
+	// Declared facts that were not gathered are zero values, not unknown
+	executor.Gatherer = NewFactGatherer(map[string]FactDef{
+		"arch":     {Command: "uname -m"},
+		"gpu":      {Command: "nvidia-smi -L", Platforms: []string{"linux"}},
+		"cores":    {Command: "nproc", Type: "integer"},
+		"has_sudo": {Command: "sudo -n true && echo true", Type: "boolean"},
+	}, &MockTransport{})
+	result, err := executor.interpolate("{{if .gpu}}gpu{{else}}cpu{{end}} {{.cores}} {{.has_sudo}}", facts)
+	if err != nil || result != "cpu 0 false" {
+		t.Errorf("expected zero values for facts without a value, got %q, %v", result, err)
+	}
+	if _, err := executor.interpolate("{{.gup}}", facts); err == nil || !strings.Contains(err.Error(), "did you mean 'gpu'?") {
+		t.Errorf("expected undeclared names to stay errors, got %v", err)
+	}
+	if _, ok := facts["gpu"]; ok {
+		t.Error("interpolation must not add facts to the caller's map")
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// embeddedTemplates are starter configs built into the binary, so new users
+// can run something useful offline before writing JSON
+//
+//go:embed templates/*.json
+var embeddedTemplates embed.FS
+
+// ConfigTemplate is an embedded starter config
+type ConfigTemplate struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// listTemplates returns the embedded templates sorted by name, described by
+// their config's "description"
+func listTemplates() ([]ConfigTemplate, error) {
+	entries, err := fs.ReadDir(embeddedTemplates, "templates")
+	if err != nil {
+		return nil, err
+	}
+	templates := make([]ConfigTemplate, 0, len(entries))
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), ".json")
+		data, err := loadTemplate(name)
+		if err != nil {
+			return nil, err
+		}
+		var config struct {
+			Description string `json:"description"`
+		}
+		if err := json.Unmarshal(data, &config); err != nil {
+			return nil, fmt.Errorf("template %s: %w", name, err)
+		}
+		templates = append(templates, ConfigTemplate{Name: name, Description: config.Description})
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return templates, nil
+}
+
+// loadTemplate returns a template's config. "install-docker" names the
+// same template as "docker".
+func loadTemplate(name string) ([]byte, error) {
+	name = strings.TrimPrefix(name, "install-")
+	if name == "" || strings.ContainsAny(name, `/\.`) {
+		return nil, fmt.Errorf("unknown template '%s'", name)
+	}
+	data, err := embeddedTemplates.ReadFile(path.Join("templates", name+".json"))
+	if err != nil {
+		var names []string
+		if entries, err := fs.ReadDir(embeddedTemplates, "templates"); err == nil {
+			for _, entry := range entries {
+				names = append(names, strings.TrimSuffix(entry.Name(), ".json"))
+			}
+		}
+		return nil, fmt.Errorf("unknown template '%s' (available: %s)", name, strings.Join(names, ", "))
+	}
+	return data, nil
+}
+
+// printTemplates lists the embedded templates
+func printTemplates(w io.Writer, templates []ConfigTemplate) {
+	width := 0
+	for _, t := range templates {
+		width = max(width, len(t.Name))
+	}
+	for _, t := range templates {
+		fmt.Fprintf(w, "  %-*s  %s\n", width, t.Name, t.Description)
+	}
+}
+
+// templatesCommand handles sink templates list|render
+func templatesCommand() {
+	args := os.Args[2:]
+	for _, arg := range args {
+		if arg == "-h" || arg == "--help" {
+			printTemplatesHelp()
+			os.Exit(0)
+		}
+	}
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: Missing subcommand\n\n")
+		printTemplatesHelp()
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "list":
+		jsonOutput := len(args) > 1 && args[1] == "--json"
+		templates, err := listTemplates()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if jsonOutput {
+			out, _ := json.MarshalIndent(templates, "", "  ")
+			fmt.Println(string(out))
+			return
+		}
+		fmt.Println("Templates:")
+		printTemplates(os.Stdout, templates)
+		fmt.Println("\nRender one with: sink templates render <name> > config.json")
+	case "render":
+		if len(args) != 2 {
+			fmt.Fprintf(os.Stderr, "Error: render needs exactly one template name\n")
+			os.Exit(1)
+		}
+		data, err := loadTemplate(args[1])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		os.Stdout.Write(withSchemaURL(data))
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown subcommand: %s\n\n", args[0])
+		printTemplatesHelp()
+		os.Exit(1)
+	}
+}
+
+func printTemplatesHelp() {
+	fmt.Printf(`sink templates - Starter configs built into sink
+
+Usage:
+  sink templates list [--json]
+  sink templates render <name>
+
+Subcommands:
+  list               List the templates and what they install
+  render <name>      Print a template's config to stdout
+
+Description:
+  sink carries a few ready-to-run configs, so there is something useful to
+  run before writing any JSON, even offline. Render one, read it, adjust it
+  and run it like any config. "install-<name>" is accepted for <name>.
+
+  Rendered configs start with a "$schema" reference to the schema of this
+  sink's release tag, so editors validate against what this binary accepts
+  rather than whatever main holds by then.
+
+Examples:
+  sink templates list
+  sink templates render docker > config.json
+  sink execute config.json --dry-run
+`)
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestTemplates tests that every embedded template is a valid config
+func TestTemplates(t *testing.T) {
+	templates, err := listTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, tmpl := range templates {
+		names = append(names, tmpl.Name)
+		if tmpl.Description == "" {
+			t.Errorf("template %s has no description", tmpl.Name)
+		}
+		data, err := loadTemplate(tmpl.Name)
+		if err != nil {
+			t.Fatalf("load %s: %v", tmpl.Name, err)
+		}
+		if _, err := parseConfigData(data, LoadOptions{Strict: true}); err != nil {
+			t.Errorf("template %s is not a valid config: %v", tmpl.Name, err)
+		}
+	}
+	if strings.Join(names, ",") != "dev-baseline,docker,homebrew" {
+		t.Errorf("unexpected templates: %v", names)
+	}
+
+	if _, err := loadTemplate("install-docker"); err != nil {
+		t.Errorf("expected install-docker to name the docker template: %v", err)
+	}
+	if _, err := loadTemplate("../main"); err == nil || !strings.Contains(err.Error(), "unknown template") {
+		t.Errorf("expected an unknown template error, got %v", err)
+	}
+	if _, err := loadTemplate("kubernetes"); err == nil || !strings.Contains(err.Error(), "available: dev-baseline, docker, homebrew") {
+		t.Errorf("expected the available templates to be listed, got %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// ANSI sequences for full-screen terminal UIs
+const (
+	ansiAltScreen  = "\033[?1049h" // Switch to the alternate screen
+	ansiMainScreen = "\033[?1049l" // Back to the main screen
+	ansiHideCursor = "\033[?25l"
+	ansiShowCursor = "\033[?25h"
+	ansiHome       = "\033[H"  // Cursor to the top left
+	ansiClearLine  = "\033[K"  // Clear to the end of the line
+	ansiClearDown  = "\033[J"  // Clear to the end of the screen
+	ansiReverse    = "\033[7m" // Reverse video, for the selected row
+	ansiDim        = "\033[2m"
+)
+
+// Control bytes read from a raw terminal
+const (
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyBackspace = 8
+	keyTab       = '\t'
+	keyCtrlU     = 21
+	keyEscape    = 27
+	keyDelete    = 127
+)
+
+// isTerminal reports whether f is a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// rawTerminal switches the terminal on stdin to unbuffered, no-echo input
+// with stty and returns a function restoring it. ok is false when stty is
+// unavailable (e.g. on Windows); the console then reads plain lines.
+func rawTerminal() (restore func(), ok bool) {
+	stty := func(args ...string) (string, error) {
+		cmd := exec.Command("stty", args...)
+		cmd.Stdin = os.Stdin
+		out, err := cmd.Output()
+		return strings.TrimSpace(string(out)), err
+	}
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, false
+	}
+	if _, err := stty("-icanon", "-echo", "-isig", "min", "1"); err != nil {
+		return nil, false
+	}
+	return func() { stty(saved) }, true
+}
+
+// terminalSize returns the rows and columns of the terminal on stdin,
+// falling back to 24x80 when stty cannot tell
+func terminalSize() (rows, cols int) {
+	cmd := exec.Command("stty", "size")
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	if err == nil {
+		fields := strings.Fields(string(out))
+		if len(fields) == 2 {
+			rows, _ = strconv.Atoi(fields[0])
+			cols, _ = strconv.Atoi(fields[1])
+		}
+	}
+	if rows <= 0 || cols <= 0 {
+		return 24, 80
+	}
+	return rows, cols
+}
+
+// readKey reads one key press from a raw terminal: arrow keys are returned
+// as "up", "down", "left" and "right", Enter as "enter", Escape as "esc",
+// Ctrl-C as "ctrl-c" and other keys as themselves
+func readKey(in *bufio.Reader) (string, error) {
+	b, err := in.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	switch b {
+	case '\r', '\n':
+		return "enter", nil
+	case keyCtrlC:
+		return "ctrl-c", nil
+	case keyEscape:
+		// A lone Escape has nothing buffered behind it
+		if in.Buffered() == 0 {
+			return "esc", nil
+		}
+		if next, _ := in.ReadByte(); next != '[' {
+			return "esc", nil
+		}
+		key, _ := in.ReadByte()
+		switch key {
+		case 'A':
+			return "up", nil
+		case 'B':
+			return "down", nil
+		case 'C':
+			return "right", nil
+		case 'D':
+			return "left", nil
+		}
+		return "esc", nil
+	}
+	return string(b), nil
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// tfWrapperKeys are the keys of the spec sink tf-wrapper reads, besides
+// "var.<name>" for config variables
+var tfWrapperKeys = []string{
+	"config", "host", "platform", "sha256", "dry_run", "run_id", "confirm_host",
+	"ssh_identity", "ssh_known_hosts",
+}
+
+// readTFWrapperSpec reads the JSON object Terraform's external data source
+// writes to a program's stdin. Terraform only passes strings, so anything
+// else is rejected rather than guessed at.
+func readTFWrapperSpec(r io.Reader) (map[string]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read spec: %w", err)
+	}
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil || raw == nil {
+		return nil, fmt.Errorf("spec must be a JSON object on stdin")
+	}
+	spec := map[string]string{}
+	for key, value := range raw {
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("spec key '%s' must be a string", key)
+		}
+		spec[key] = s
+	}
+	return spec, nil
+}
+
+// tfWrapperArgs returns the sink command line that runs a spec: bootstrap
+// of the config on this machine, or execute --ssh of a local config on
+// "host". A "sha256" for a local file is checked here, since neither
+// command checks one.
+func tfWrapperArgs(spec map[string]string) ([]string, error) {
+	config := spec["config"]
+	if config == "" {
+		return nil, fmt.Errorf("spec key 'config' is required")
+	}
+	host := spec["host"]
+	if host != "" && isURL(config) {
+		return nil, fmt.Errorf("'host' needs a local config file, got %s", config)
+	}
+
+	var args []string
+	if host == "" {
+		args = []string{"bootstrap", config, "--json"}
+	} else {
+		args = []string{"execute", config, "--json", "--ssh", host}
+	}
+	if sum := strings.ToLower(spec["sha256"]); sum != "" {
+		if !sha256Regex.MatchString(sum) {
+			return nil, fmt.Errorf("invalid sha256 '%s', must be 64 hex characters", spec["sha256"])
+		}
+		if isURL(config) {
+			args = append(args, "--sha256", sum)
+		} else if actual, err := fileSHA256(config); err != nil {
+			return nil, err
+		} else if actual != sum {
+			return nil, fmt.Errorf("checksum mismatch for %s: expected %s, got %s", config, sum, actual)
+		}
+	}
+	if value := spec["dry_run"]; value != "" {
+		dryRun, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid dry_run '%s', must be true or false", value)
+		}
+		if dryRun {
+			args = append(args, "--dry-run")
+		}
+	}
+
+	flags := map[string]string{
+		"platform":        "--platform",
+		"run_id":          "--run-id",
+		"confirm_host":    "--confirm-host",
+		"ssh_identity":    "--ssh-identity",
+		"ssh_known_hosts": "--ssh-known-hosts",
+	}
+	var names []string
+	for _, key := range sortedKeys(spec) {
+		value := spec[key]
+		if name, ok := strings.CutPrefix(key, "var."); ok {
+			if _, _, err := parseVarFlag(name + "=" + value); err != nil {
+				return nil, err
+			}
+			names = append(names, name+"="+value)
+			continue
+		}
+		if !containsString(tfWrapperKeys, key) {
+			return nil, fmt.Errorf("unknown spec key '%s'", key)
+		}
+		flag, ok := flags[key]
+		if !ok || value == "" {
+			continue
+		}
+		if strings.HasPrefix(key, "ssh_") && host == "" {
+			return nil, fmt.Errorf("spec key '%s' needs 'host'", key)
+		}
+		args = append(args, flag, value)
+	}
+	for _, variable := range names {
+		args = append(args, "--var", variable)
+	}
+	return args, nil
+}
+
+// tfWrapperResult sums up the JSON events of a run as the flat object of
+// strings Terraform expects, and returns an error when the run failed.
+// --json runs exit 0 when a step fails, so the events decide.
+func tfWrapperResult(events []byte) (map[string]string, error) {
+	runs, err := parseResults(bytes.NewReader(events), "sink")
+	if err != nil {
+		return nil, err
+	}
+	if len(runs) == 0 {
+		return nil, fmt.Errorf("sink reported no steps")
+	}
+	run := runs[len(runs)-1]
+
+	result := map[string]string{
+		"run_id":      run.RunID,
+		"host":        run.Host,
+		"status":      "success",
+		"steps":       strconv.Itoa(len(run.Steps)),
+		"succeeded":   "0",
+		"failed":      "0",
+		"skipped":     "0",
+		"failed_step": "",
+		"error":       "",
+	}
+	counts := map[string]int{}
+	for _, step := range run.Steps {
+		switch step.Status {
+		case "success", "failed":
+			counts[step.Status]++
+		default:
+			counts["skipped"]++
+		}
+		if step.Status == "failed" && result["failed_step"] == "" {
+			result["failed_step"] = step.Name
+			result["error"] = strings.TrimSpace(step.Error)
+		}
+	}
+	result["succeeded"] = strconv.Itoa(counts["success"])
+	result["failed"] = strconv.Itoa(counts["failed"])
+	result["skipped"] = strconv.Itoa(counts["skipped"])
+	if counts["failed"] > 0 {
+		result["status"] = "failed"
+		return result, fmt.Errorf("step '%s' failed: %s", result["failed_step"], result["error"])
+	}
+	return result, nil
+}
+
+func tfWrapperCommand() {
+	for _, arg := range os.Args[2:] {
+		if arg == "-h" || arg == "--help" {
+			printTFWrapperHelp()
+			os.Exit(0)
+		}
+		fmt.Fprintf(os.Stderr, "Error: unexpected argument: %s (the spec is read from stdin)\n", arg)
+		os.Exit(1)
+	}
+
+	// Terraform shows stderr when the program fails, and parses stdout as
+	// the result, so nothing but the result goes to stdout
+	fail := func(err error) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	spec, err := readTFWrapperSpec(os.Stdin)
+	if err != nil {
+		fail(err)
+	}
+	args, err := tfWrapperArgs(spec)
+	if err != nil {
+		fail(err)
+	}
+	self, err := os.Executable()
+	if err != nil {
+		fail(fmt.Errorf("cannot locate sink binary: %w", err))
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(self, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	runErr := cmd.Run()
+
+	result, err := tfWrapperResult(stdout.Bytes())
+	if result == nil {
+		// The run never started, so its own message says why
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			fmt.Fprintln(os.Stderr, message)
+			os.Exit(1)
+		}
+		if runErr != nil {
+			err = runErr
+		}
+		fail(err)
+	}
+	encoded, _ := json.Marshal(result)
+	fmt.Println(string(encoded))
+	if err != nil {
+		fail(err)
+	}
+}
+
+func printTFWrapperHelp() {
+	fmt.Print(`sink tf-wrapper - Run a config for Terraform
+
+Usage:
+  echo '{"config": "config.json"}' | sink tf-wrapper
+
+Description:
+  Reads a JSON object of strings on stdin, as Terraform's "external" data
+  source passes its query, runs the config, and writes one JSON object of
+  strings to stdout. A failed step makes it exit 1 with the error on
+  stderr, which fails the Terraform plan or provisioner.
+
+  Without "host" the config is bootstrapped on this machine and may be a
+  URL. With "host" a local config is run on that host over SSH.
+
+Spec keys:
+  config           Config file or URL (required)
+  host             Run over SSH on user@host or user@host:port
+  platform         Override platform detection
+  sha256           Expected SHA256 of the config
+  dry_run          "true" to only show what would run
+  run_id           Run ID for the events
+  confirm_host     Host name a dangerous config must confirm
+  ssh_identity     Private key file for "host"
+  ssh_known_hosts  known_hosts file for "host"
+  var.<name>       Set a config variable
+
+Result keys:
+  status           success or failed
+  run_id, host     The run and the host it ran on
+  steps            Top-level steps that reported a result
+  succeeded, failed, skipped
+                   Steps by outcome
+  failed_step      First step that failed, and its error
+  error
+
+Example:
+  data "external" "web" {
+    program = ["sink", "tf-wrapper"]
+    query = {
+      config    = "web.json"
+      host      = "deploy@${aws_instance.web.public_ip}"
+      "var.env" = "prod"
+    }
+  }
+`)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestTFWrapper tests turning a Terraform spec into a sink command line and
+// a run's events into the result Terraform reads
+func TestTFWrapper(t *testing.T) {
+	spec, err := readTFWrapperSpec(strings.NewReader(`{"config": "web.json", "host": "deploy@10.0.0.5", "dry_run": "true", "var.env": "prod", "ssh_identity": "id_ed25519"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	args, err := tfWrapperArgs(spec)
+	if want := "execute web.json --json --ssh deploy@10.0.0.5 --dry-run --ssh-identity id_ed25519 --var env=prod"; err != nil || strings.Join(args, " ") != want {
+		t.Errorf("expected %q, got %q, %v", want, strings.Join(args, " "), err)
+	}
+	args, err = tfWrapperArgs(map[string]string{"config": "https://example.com/web.json", "sha256": strings.Repeat("AB", 32)})
+	if want := "bootstrap https://example.com/web.json --json --sha256 " + strings.Repeat("ab", 32); err != nil || strings.Join(args, " ") != want {
+		t.Errorf("expected %q, got %q, %v", want, strings.Join(args, " "), err)
+	}
+
+	for spec, want := range map[string]string{
+		`{"config": "web.json", "dry_run": true}`: "must be a string",
+		`["web.json"]`: "must be a JSON object",
+	} {
+		if _, err := readTFWrapperSpec(strings.NewReader(spec)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: expected %q, got %v", spec, want, err)
+		}
+	}
+	for _, c := range []struct {
+		spec map[string]string
+		want string
+	}{
+		{map[string]string{"host": "web"}, "'config' is required"},
+		{map[string]string{"config": "web.json", "verbose": "true"}, "unknown spec key 'verbose'"},
+		{map[string]string{"config": "https://example.com/web.json", "host": "web"}, "needs a local config file"},
+		{map[string]string{"config": "web.json", "ssh_identity": "id_ed25519"}, "needs 'host'"},
+	} {
+		if _, err := tfWrapperArgs(c.spec); err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%v: expected %q, got %v", c.spec, c.want, err)
+		}
+	}
+
+	events := `{"run_id": "r1", "host": "web", "step_name": "Install", "status": "running", "context": {"host": "web"}}
+{"run_id": "r1", "host": "web", "step_name": "Install", "status": "success", "context": {"host": "web"}}
+{"run_id": "r1", "host": "web", "step_name": "Start", "status": "failed", "error": "command failed (exit 1)\n", "context": {"host": "web"}}
+{"event": "warnings", "warnings": []}
+`
+	result, err := tfWrapperResult([]byte(events))
+	if err == nil || !strings.Contains(err.Error(), "step 'Start' failed") {
+		t.Errorf("expected the failed step to be an error, got %v", err)
+	}
+	if result["status"] != "failed" || result["succeeded"] != "1" || result["failed_step"] != "Start" || result["error"] != "command failed (exit 1)" || result["host"] != "web" {
+		t.Errorf("unexpected result %v", result)
+	}
+	if _, err := tfWrapperResult(nil); err == nil {
+		t.Error("expected a run without events to be an error")
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// TrustStore remembers the checksum of each bootstrap source after its first
+// successful run (trust on first use). A later download whose content changed
+// while the config's version did not is reported, catching silent changes to
+// mutable sources such as branch URLs.
+type TrustStore struct {
+	Path   string // JSON file mapping sources to TrustEntry
+	Strict bool   // Fail instead of warning on an unexplained change (--tofu-strict)
+
+	now     func() time.Time
+	pending *trustObservation // Checked source to record once the run succeeds
+}
+
+// TrustEntry is what the trust store knows about one source
+type TrustEntry struct {
+	SHA256    string    `json:"sha256"`
+	Version   string    `json:"version,omitempty"` // The config's "version" when last recorded
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// trustObservation is a checked download waiting to be recorded
+type trustObservation struct {
+	source  string
+	sha256  string
+	version string
+}
+
+// NewTrustStore opens the trust store under the state directory
+func NewTrustStore(strict bool) (*TrustStore, error) {
+	path, err := statePath("trusted_sources.json")
+	if err != nil {
+		return nil, err
+	}
+	return &TrustStore{Path: path, Strict: strict, now: time.Now}, nil
+}
+
+// Check compares a downloaded config with the checksum recorded for its
+// source. Content may change with the config's version, or when the caller
+// verified it against an explicit checksum; any other change is a warning,
+// or an error when Strict. The result is recorded by Record.
+func (s *TrustStore) Check(source string, data []byte, version string, explicit bool) error {
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:])
+	entries, err := s.load()
+	if err != nil {
+		return err
+	}
+	observation := &trustObservation{source: source, sha256: checksum, version: version}
+
+	entry, ok := entries[source]
+	switch {
+	case !ok:
+		fmt.Printf("🔐 First use of this source: its checksum will be recorded after a successful run\n")
+	case entry.SHA256 == checksum:
+		fmt.Printf("✅ Matches the checksum first seen %s\n", entry.FirstSeen.Format("2006-01-02"))
+	case entry.Version != version:
+		fmt.Printf("🔄 Content changed with the config version (%s → %s)\n", displayVersion(entry.Version), displayVersion(version))
+	case explicit:
+		fmt.Printf("🔄 Content changed; accepting the new checksum verified by --sha256\n")
+	default:
+		err := fmt.Errorf("content changed since this source was first trusted on %s without a version change (sha256 %s → %s); pass --sha256 %s to accept it",
+			entry.FirstSeen.Format("2006-01-02"), entry.SHA256[:12], checksum[:12], checksum)
+		if s.Strict {
+			return fmt.Errorf("--tofu-strict: %w", err)
+		}
+		// The original checksum stays trusted, so the warning repeats
+		warn(WarningSourceChanged, "TOFU: %v", err)
+		return nil
+	}
+	s.pending = observation
+	return nil
+}
+
+// Record stores the checksum of the source checked last, after its run
+// succeeded
+func (s *TrustStore) Record() error {
+	if s.pending == nil {
+		return nil
+	}
+	entries, err := s.load()
+	if err != nil {
+		return err
+	}
+	now := s.now().UTC()
+	entry, ok := entries[s.pending.source]
+	if !ok || entry.SHA256 != s.pending.sha256 {
+		entry = TrustEntry{SHA256: s.pending.sha256, FirstSeen: now}
+	}
+	entry.Version = s.pending.version
+	entry.LastSeen = now
+	entries[s.pending.source] = entry
+	s.pending = nil
+	return s.save(entries)
+}
+
+// load reads the store; a missing file is an empty store
+func (s *TrustStore) load() (map[string]TrustEntry, error) {
+	entries := map[string]TrustEntry{}
+	data, err := os.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("trust store: %w", err)
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("trust store %s is corrupt: %w", s.Path, err)
+	}
+	return entries, nil
+}
+
+// save writes the store atomically
+func (s *TrustStore) save(entries map[string]TrustEntry) error {
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0700); err != nil {
+		return fmt.Errorf("trust store: %w", err)
+	}
+	tmp := s.Path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("trust store: %w", err)
+	}
+	return os.Rename(tmp, s.Path)
+}
+
+// displayVersion shows an unset version
+func displayVersion(version string) string {
+	if version == "" {
+		return "(none)"
+	}
+	return version
+}
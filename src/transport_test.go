@@ -1,9 +1,13 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"os"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
 )
 
 // TestLocalTransportCommandExecution tests basic command execution
@@ -243,6 +247,25 @@ func TestLocalTransportWithExistingEnvironment(t *testing.T) {
 	}
 }
 
+// TestLocalTransportExtraEnv tests that SetEnv adds variables to, and
+// overrides, the inherited environment
+func TestLocalTransportExtraEnv(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses POSIX shell commands")
+	}
+	t.Setenv("SINK_TEST_INHERITED", "parent")
+	transport := NewLocalTransport()
+	transport.SetEnv([]string{"SINK_TEST_INHERITED=fact", "SINK_TEST_NEW=added"})
+
+	stdout, _, _, err := transport.Run("echo \"$SINK_TEST_INHERITED $SINK_TEST_NEW $HOME\"")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "fact added " + os.Getenv("HOME") + "\n"; stdout != want {
+		t.Errorf("stdout = %q, want %q", stdout, want)
+	}
+}
+
 // TestLocalTransportExitCodes tests various exit codes
 func TestLocalTransportExitCodes(t *testing.T) {
 	transport := NewLocalTransport()
@@ -268,3 +291,81 @@ func TestLocalTransportExitCodes(t *testing.T) {
 		})
 	}
 }
+
+// TestLocalTransportRunContextCancel tests that a cancelled context kills the command
+func TestLocalTransportRunContextCancel(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sleep")
+	}
+
+	transport := NewLocalTransport()
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, _, _, err := transport.RunContext(ctx, "sleep 10")
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("command was not killed (took %s)", elapsed)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+// TestLocalTransportRunInput tests feeding standard input to a command
+func TestLocalTransportRunInput(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses tr")
+	}
+	transport := NewLocalTransport()
+	stdout, _, exitCode, err := transport.RunInput(context.Background(), "tr a-z A-Z", "piped input\n")
+	if err != nil || exitCode != 0 {
+		t.Fatalf("RunInput failed: exit %d, %v", exitCode, err)
+	}
+	if stdout != "PIPED INPUT\n" {
+		t.Errorf("stdout = %q", stdout)
+	}
+}
+
+// TestTransportCapabilities tests described and inferred transport capabilities
+func TestTransportCapabilities(t *testing.T) {
+	local := transportCapabilities(&LocalTransport{Shell: ShellPowerShell})
+	if local != (Capabilities{Shell: ShellPowerShell, Cancel: true, Stdin: true, FileTransfer: true}) {
+		t.Errorf("unexpected local capabilities %+v", local)
+	}
+
+	// Transports without optional interfaces can only run commands
+	basic := transportCapabilities(&MockTransport{})
+	if basic != (Capabilities{Shell: ShellPOSIX}) {
+		t.Errorf("unexpected inferred capabilities %+v", basic)
+	}
+}
+
+// TestUnsupportedSteps tests rejecting steps the transport cannot run
+func TestUnsupportedSteps(t *testing.T) {
+	input := "data"
+	steps := []InstallStep{
+		{Name: "plain", Step: CommandStep{Command: "true"}},
+		{Name: "apply", Step: CommandStep{Command: "kubectl apply -f -", Stdin: &input}},
+		{Name: "build", Step: CommandStep{Command: "make", Script: ScriptLines{"make"}}},
+		{Name: "cert", Step: CopyStep{Copy: FileTransfer{Source: "a", Destination: "b"}}},
+	}
+
+	if problems := unsupportedSteps(steps, transportCapabilities(NewLocalTransport())); runtime.GOOS != "windows" && len(problems) != 0 {
+		t.Errorf("local transport should run every step, got %v", problems)
+	}
+
+	got := strings.Join(unsupportedSteps(steps, Capabilities{Shell: ShellCmd}), "\n")
+	for _, want := range []string{
+		`step "apply": the transport cannot send stdin to commands`,
+		`step "build": script needs a POSIX shell, but the transport runs commands with cmd`,
+		`step "cert": copy needs a transport that can transfer files`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, `"plain"`) {
+		t.Errorf("plain command steps run on any transport:\n%s", got)
+	}
+}
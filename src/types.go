@@ -3,23 +3,45 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 )
 
 // Config represents the top-level configuration
 type Config struct {
-	Schema      string             `json:"$schema,omitempty"`
-	Name        string             `json:"name,omitempty"`
-	Version     string             `json:"version"`
-	Description string             `json:"description,omitempty"`
-	Facts       map[string]FactDef `json:"facts,omitempty"`
-	Defaults    map[string]string  `json:"defaults,omitempty"`
-	Platforms   []Platform         `json:"platforms"`
-	Fallback    *Fallback          `json:"fallback,omitempty"`
+	Schema      string                 `json:"$schema,omitempty"`
+	Name        string                 `json:"name,omitempty"`
+	Version     string                 `json:"version"`
+	Description string                 `json:"description,omitempty"`
+	Facts       map[string]FactDef     `json:"facts,omitempty"`
+	Variables   map[string]VariableDef `json:"variables,omitempty"` // Inputs set by defaults or --var
+	Defaults    *Defaults              `json:"defaults,omitempty"`
+	Platforms   []Platform             `json:"platforms"`
+	Fallback    *Fallback              `json:"fallback,omitempty"`
+	Policy      *Policy                `json:"policy,omitempty"`
+	Window      string                 `json:"window,omitempty"`       // Maintenance window for the whole run (see ParseWindow)
+	Snapshot    *SnapshotConfig        `json:"snapshot,omitempty"`     // Record host state before and after the run
+	Files       []RemoteFile           `json:"files,omitempty"`        // Supporting files remote deploy transfers before execution
+	RateLimits  map[string]string      `json:"rate_limits,omitempty"`  // Named rate limit groups, e.g. "github": "10/min"
+	Confirm     *Confirm               `json:"confirm,omitempty"`      // Custom confirmation prompt before a real run
+	ExportFacts *bool                  `json:"export_facts,omitempty"` // Pass facts with an "export" name to steps as environment variables (default true)
+
+	Dependencies map[string]Dependency `json:"dependencies,omitempty"` // Step libraries "use" steps reference (see composeConfig)
+
+	// InstallScope is "user", "system" or "auto" (the default): whether
+	// {{.sink.bin_dir}} is in the user's home or system-wide
+	InstallScope string `json:"install_scope,omitempty"`
+}
+
+// Policy holds security controls a config imposes on how it may be run
+type Policy struct {
+	RequirePinned bool `json:"require_pinned,omitempty"` // Refuse to run unless fetched from a pinned ref or with a checksum
 }
 
 // FactDef defines how to gather a single fact
 type FactDef struct {
-	Command     string            `json:"command"`
+	Command     string            `json:"command,omitempty"`
+	Source      string            `json:"source,omitempty"` // Secret source instead of a command: "vault:path#key" or "aws-sm:name#key"
+	Secret      bool              `json:"secret,omitempty"` // Redact the value in output (implied by Source)
 	Description string            `json:"description,omitempty"`
 	Export      string            `json:"export,omitempty"`
 	Platforms   []string          `json:"platforms,omitempty"`
@@ -42,6 +64,77 @@ type Platform struct {
 	InstallSteps  []InstallStep  `json:"install_steps,omitempty"`
 	Distributions []Distribution `json:"distributions,omitempty"`
 	Fallback      *Fallback      `json:"fallback,omitempty"`
+	Defaults      *StepDefaults  `json:"defaults,omitempty"`       // Step policy defaults for this platform
+	FailurePolicy string         `json:"failure_policy,omitempty"` // What a failed step does to the run, unless the step sets its own (FailurePolicy*)
+}
+
+// StepDefaults holds step policy applied to every command and remediation step
+// that does not set the field itself. Platform defaults override config defaults.
+type StepDefaults struct {
+	Timeout json.RawMessage `json:"timeout,omitempty"` // Can be string or TimeoutConfig object
+	Retry   *string         `json:"retry,omitempty"`
+	Sleep   *string         `json:"sleep,omitempty"`
+	Verbose *bool           `json:"verbose,omitempty"`
+}
+
+// Defaults holds config-wide default values. The step policy keys (timeout,
+// retry, sleep, verbose) are parsed into StepDefaults; every other key is a
+// free-form string value such as "package" or "check_command".
+type Defaults struct {
+	StepDefaults
+	Values map[string]string
+}
+
+// stepDefaultKeys are the defaults keys that configure step policy
+var stepDefaultKeys = map[string]bool{"timeout": true, "retry": true, "sleep": true, "verbose": true}
+
+// UnmarshalJSON implements custom JSON unmarshaling for Defaults
+func (d *Defaults) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	policy := map[string]json.RawMessage{}
+	d.Values = map[string]string{}
+	for key, value := range raw {
+		if stepDefaultKeys[key] {
+			policy[key] = value
+			continue
+		}
+		var s string
+		if err := json.Unmarshal(value, &s); err != nil {
+			return fmt.Errorf("defaults.%s: must be a string", key)
+		}
+		d.Values[key] = s
+	}
+
+	policyJSON, err := json.Marshal(policy)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(policyJSON, &d.StepDefaults)
+}
+
+// MarshalJSON implements custom JSON marshaling for Defaults
+func (d Defaults) MarshalJSON() ([]byte, error) {
+	out := map[string]interface{}{}
+	for key, value := range d.Values {
+		out[key] = value
+	}
+	if len(d.Timeout) > 0 {
+		out["timeout"] = d.Timeout
+	}
+	if d.Retry != nil {
+		out["retry"] = *d.Retry
+	}
+	if d.Sleep != nil {
+		out["sleep"] = *d.Sleep
+	}
+	if d.Verbose != nil {
+		out["verbose"] = *d.Verbose
+	}
+	return json.Marshal(out)
 }
 
 // Distribution represents a Linux distribution configuration
@@ -54,10 +147,42 @@ type Distribution struct {
 // InstallStep represents a single installation step
 // The Step field contains the variant (one of the Step* types)
 type InstallStep struct {
-	Name string
+	Name        string
+	Description string // What the step is for, shown by "sink explain"
+	Window      string // Maintenance window; outside it the step is deferred (see ParseWindow)
+	Impact      string // What the step affects, e.g. "restarts nginx" (shown in plans and prompts)
+	Risk        string // "low", "medium" or "high"
+
+	// Annotations are free-form labels such as a ticket or owning team,
+	// copied verbatim into events and reports for dashboards to group by
+	Annotations map[string]string
+
+	// RefreshFacts names facts to re-gather after the step succeeds, so later
+	// steps see e.g. has_docker=true once a remediation installed docker
+	RefreshFacts []string
+
+	// Scratch maps names to templates stored in the run's scratch space once
+	// the step succeeds; later steps read them as {{.sink.scratch.<name>}}
+	Scratch map[string]string
+
+	// FailurePolicy overrides the platform's failure_policy for this step
+	FailurePolicy string
+
+	// Undo is a command that reverses the step. When a later step fails and
+	// the run stops, the undo commands of the steps that changed the host
+	// run in reverse order.
+	Undo string
+
 	Step StepVariant
 }
 
+// Step risk levels
+const (
+	RiskLow    = "low"
+	RiskMedium = "medium"
+	RiskHigh   = "high"
+)
+
 // UnmarshalJSON implements custom JSON unmarshaling for InstallStep
 func (is *InstallStep) UnmarshalJSON(data []byte) error {
 	// First unmarshal into a map to inspect fields
@@ -69,26 +194,146 @@ func (is *InstallStep) UnmarshalJSON(data []byte) error {
 	// Extract name
 	name, _ := raw["name"].(string)
 	is.Name = name
+	is.Description, _ = raw["description"].(string)
+	is.Window, _ = raw["window"].(string)
+	is.Impact, _ = raw["impact"].(string)
+	is.Risk, _ = raw["risk"].(string)
+	is.FailurePolicy, _ = raw["failure_policy"].(string)
+	if undo, ok := raw["undo"]; ok {
+		command, ok := undo.(string)
+		if !ok || strings.TrimSpace(command) == "" {
+			return fmt.Errorf("step '%s': undo must be a non-empty command", name)
+		}
+		is.Undo = command
+	}
+	if annotations, ok := raw["annotations"]; ok {
+		labels, ok := annotations.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("step '%s': annotations must be an object of string values", name)
+		}
+		is.Annotations = make(map[string]string, len(labels))
+		for key, value := range labels {
+			text, ok := value.(string)
+			if !ok {
+				return fmt.Errorf("step '%s': annotation '%s' must be a string", name, key)
+			}
+			is.Annotations[key] = text
+		}
+	}
+	if refresh, ok := raw["refresh_facts"]; ok {
+		names, ok := refresh.([]interface{})
+		if !ok {
+			return fmt.Errorf("step '%s': refresh_facts must be an array of fact names", name)
+		}
+		for _, n := range names {
+			factName, ok := n.(string)
+			if !ok {
+				return fmt.Errorf("step '%s': refresh_facts must be an array of fact names", name)
+			}
+			is.RefreshFacts = append(is.RefreshFacts, factName)
+		}
+	}
+	if scratch, ok := raw["scratch"]; ok {
+		templates, ok := scratch.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("step '%s': scratch must be an object of string templates", name)
+		}
+		is.Scratch = make(map[string]string, len(templates))
+		for key, value := range templates {
+			text, ok := value.(string)
+			if !ok {
+				return fmt.Errorf("step '%s': scratch '%s' must be a string", name, key)
+			}
+			if !factNameRegex.MatchString(key) {
+				return fmt.Errorf("step '%s': scratch name '%s' must match pattern ^[a-z_][a-z0-9_]*$", name, key)
+			}
+			is.Scratch[key] = text
+		}
+	}
 
 	// Determine which variant based on fields present
 	_, hasCommand := raw["command"]
-	_, hasCheck := raw["check"]
+	_, hasScript := raw["script"]
+	_, hasCheckCommand := raw["check"]
+	_, hasCheckFile := raw["check_file"]
+	_, hasCheckCommandExists := raw["check_command_exists"]
+	_, hasCheckPort := raw["check_port"]
+	hasCheck := hasCheckCommand || hasCheckFile || hasCheckCommandExists || hasCheckPort
 	_, hasOnMissing := raw["on_missing"]
+	_, hasCopy := raw["copy"]
+	_, hasFetch := raw["fetch"]
+	_, hasVerifyChecksum := raw["verify_checksum"]
+	_, hasPause := raw["pause"]
+	_, hasGroup := raw["group"]
 	errorVal, hasError := raw["error"]
 
-	if hasCommand {
+	if hasCommand || hasScript {
 		// CommandStep
 		var cmd CommandStep
 		if err := json.Unmarshal(data, &cmd); err != nil {
-			return err
+			return fmt.Errorf("step '%s': %w", name, err)
+		}
+		if hasScript {
+			if hasCommand {
+				return fmt.Errorf("step '%s': use either command or script, not both", name)
+			}
+			if strings.TrimSpace(strings.Join(cmd.Script, "")) == "" {
+				return fmt.Errorf("step '%s': script is empty", name)
+			}
+			cmd.Command = scriptCommand(cmd.Script, cmd.ScriptStrict == nil || *cmd.ScriptStrict)
+		}
+		if cmd.Stdin != nil && cmd.StdinFile != "" {
+			return fmt.Errorf("step '%s': use either stdin or stdin_file, not both", name)
 		}
 		is.Step = cmd
+	} else if hasCopy || hasFetch {
+		// CopyStep or FetchStep
+		if hasCopy && hasFetch {
+			return fmt.Errorf("step '%s': use either copy or fetch, not both", name)
+		}
+		if hasCopy {
+			var cp CopyStep
+			if err := json.Unmarshal(data, &cp); err != nil {
+				return fmt.Errorf("step '%s': %w", name, err)
+			}
+			is.Step = cp
+		} else {
+			var fs FetchStep
+			if err := json.Unmarshal(data, &fs); err != nil {
+				return fmt.Errorf("step '%s': %w", name, err)
+			}
+			is.Step = fs
+		}
+	} else if hasVerifyChecksum {
+		// VerifyChecksumStep
+		var vc VerifyChecksumStep
+		if err := json.Unmarshal(data, &vc); err != nil {
+			return fmt.Errorf("step '%s': %w", name, err)
+		}
+		is.Step = vc
+	} else if hasGroup {
+		// GroupStep
+		var gs GroupStep
+		if err := json.Unmarshal(data, &gs); err != nil {
+			return fmt.Errorf("step '%s': %w", name, err)
+		}
+		is.Step = gs
+	} else if hasPause {
+		// PauseStep
+		var ps PauseStep
+		if err := json.Unmarshal(data, &ps); err != nil {
+			return fmt.Errorf("step '%s': %w", name, err)
+		}
+		is.Step = ps
 	} else if hasCheck && hasOnMissing {
 		// CheckRemediateStep
 		var cr CheckRemediateStep
 		if err := json.Unmarshal(data, &cr); err != nil {
 			return err
 		}
+		if err := validateCheckForm(cr.Check, cr.CheckProbe, cr.ExpectOutput); err != nil {
+			return fmt.Errorf("step '%s': %w", name, err)
+		}
 		is.Step = cr
 	} else if hasCheck && hasError {
 		// CheckErrorStep
@@ -96,6 +341,9 @@ func (is *InstallStep) UnmarshalJSON(data []byte) error {
 		if err := json.Unmarshal(data, &ce); err != nil {
 			return err
 		}
+		if err := validateCheckForm(ce.Check, ce.CheckProbe, ce.ExpectOutput); err != nil {
+			return fmt.Errorf("step '%s': %w", name, err)
+		}
 		is.Step = ce
 	} else if hasError && errorVal != nil {
 		// ErrorOnlyStep
@@ -125,22 +373,67 @@ type CommandStep struct {
 	Timeout json.RawMessage // Can be string or TimeoutConfig object
 	Sleep   *string         // Duration string like "1s", "500ms"
 	Verbose bool            // Enable verbose output
+
+	// SuccessExitCodes lists the exit codes that count as success (default
+	// [0]), for tools like diff and grep whose non-zero codes are expected
+	SuccessExitCodes []int `json:"success_exit_codes"`
+
+	RetryOn   *RetryOn `json:"retry_on"`   // With retry "until", retry only transient failures
+	RateLimit string   `json:"rate_limit"` // rate_limits group or inline rate ("10/min") throttling every attempt
+
+	// Verify is a post-condition run after the command succeeds; the step
+	// only succeeds if it exits 0, the way a check-with-remediation step
+	// re-runs its check after remediating
+	Verify string `json:"verify"`
+
+	// Script is a multi-line alternative to Command, given as an array of
+	// lines or one string. UnmarshalJSON joins it into Command, prefixed with
+	// "set -euo pipefail" unless ScriptStrict is false.
+	Script       ScriptLines `json:"script"`
+	ScriptStrict *bool       `json:"script_strict"`
+
+	Stdin     *string `json:"stdin"`      // Standard input for the command (templated)
+	StdinFile string  `json:"stdin_file"` // File whose contents are the standard input (templated path)
 }
 
 func (CommandStep) isStep() {}
 
+// succeeded reports whether exitCode counts as success for the command
+func (c CommandStep) succeeded(exitCode int) bool {
+	if len(c.SuccessExitCodes) == 0 {
+		return exitCode == 0
+	}
+	for _, code := range c.SuccessExitCodes {
+		if code == exitCode {
+			return true
+		}
+	}
+	return false
+}
+
 // CheckErrorStep checks a condition and fails with error if not met
 type CheckErrorStep struct {
-	Check string
-	Error string
+	Check        string
+	Error        string
+	ExpectOutput *string `json:"expect_output"` // Check also fails unless its trimmed output equals this (templated)
+
+	// Recheck keeps a failing check pending instead of stopping the run; it
+	// is re-evaluated after each later remediation in the same step list and
+	// fails the run only if it still fails at the end
+	Recheck bool
+
+	CheckProbe // check_file, check_command_exists or check_port instead of Check
 }
 
 func (CheckErrorStep) isStep() {}
 
 // CheckRemediateStep checks a condition and runs remediation if not met
 type CheckRemediateStep struct {
-	Check     string            `json:"check"`
-	OnMissing []RemediationStep `json:"on_missing"`
+	Check        string            `json:"check"`
+	ExpectOutput *string           `json:"expect_output"` // Check also fails unless its trimmed output equals this (templated)
+	OnMissing    []RemediationStep `json:"on_missing"`
+
+	CheckProbe // check_file, check_command_exists or check_port instead of Check
 }
 
 func (CheckRemediateStep) isStep() {}
@@ -154,19 +447,63 @@ func (ErrorOnlyStep) isStep() {}
 
 // RemediationStep is a step that runs during remediation
 type RemediationStep struct {
-	Name    string
-	Command string
-	Error   *string
-	Retry   *string         // "until" = retry until success or timeout
-	Timeout json.RawMessage // Can be string or TimeoutConfig object
-	Sleep   *string         // Duration string like "1s", "500ms"
-	Verbose bool            // Enable verbose output
+	Name        string
+	Description string `json:"description"` // What the remediation does, shown by "sink explain"
+	Command     string
+	Error       *string
+	Retry       *string         // "until" = retry until success or timeout
+	Timeout     json.RawMessage // Can be string or TimeoutConfig object
+	Sleep       *string         // Duration string like "1s", "500ms"
+	Verbose     bool            // Enable verbose output
+	RetryOn     *RetryOn        `json:"retry_on"`   // With retry "until", retry only transient failures
+	RateLimit   string          `json:"rate_limit"` // rate_limits group or inline rate ("10/min") throttling every attempt
+}
+
+// ScriptLines is a script given as an array of lines or a multi-line string
+type ScriptLines []string
+
+// UnmarshalJSON accepts either form
+func (s *ScriptLines) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*s = strings.Split(strings.TrimRight(text, "\n"), "\n")
+		return nil
+	}
+	var lines []string
+	if err := json.Unmarshal(data, &lines); err != nil {
+		return fmt.Errorf("script must be a string or an array of lines")
+	}
+	*s = lines
+	return nil
+}
+
+// scriptPrelude makes scripts stop at the first failing command, unset
+// variable or failing pipeline stage. pipefail is only set where /bin/sh
+// supports it (dash before 0.5.11 does not).
+const scriptPrelude = "set -eu\n(set -o pipefail) 2>/dev/null && set -o pipefail\n"
+
+// scriptCommand joins script lines into one shell script
+func scriptCommand(lines []string, strict bool) string {
+	script := strings.Join(lines, "\n")
+	if strict {
+		script = scriptPrelude + script
+	}
+	return script
+}
+
+// RetryOn limits retry "until" to transient failures: an attempt is retried
+// only if its exit code is listed or its output matches, and any other
+// failure fails the step at once instead of retrying until the timeout
+type RetryOn struct {
+	ExitCodes     []int  `json:"exit_codes,omitempty"`     // Exit codes worth retrying, e.g. 75 (EX_TEMPFAIL)
+	OutputMatches string `json:"output_matches,omitempty"` // Regular expression matched against stdout and stderr
 }
 
 // TimeoutConfig represents advanced timeout configuration
 type TimeoutConfig struct {
-	Interval  string `json:"interval"`             // Duration string like "30s", "5m"
-	ErrorCode *int   `json:"error_code,omitempty"` // Custom exit code on timeout
+	Interval     string `json:"interval"`                // Duration string like "30s", "5m"
+	ErrorCode    *int   `json:"error_code,omitempty"`    // Custom exit code on timeout
+	PollInterval string `json:"poll_interval,omitempty"` // Wait between retry attempts (default 1s)
 }
 
 // ParseTimeout parses a timeout field that can be either a string or TimeoutConfig object
@@ -200,31 +537,72 @@ type Facts map[string]interface{}
 
 // ExecutionContext represents the environment where commands are executed
 type ExecutionContext struct {
-	Host      string `json:"host"`      // Hostname where commands run
-	User      string `json:"user"`      // User running commands
-	WorkDir   string `json:"work_dir"`  // Current working directory
-	OS        string `json:"os"`        // Operating system (uname -s)
-	Arch      string `json:"arch"`      // Architecture (uname -m)
-	Transport string `json:"transport"` // "local" or "ssh:user@host"
-	Timestamp string `json:"timestamp"` // When context was captured
+	Host      string `json:"host"`       // Hostname where commands run
+	User      string `json:"user"`       // User running commands
+	WorkDir   string `json:"work_dir"`   // Current working directory
+	OS        string `json:"os"`         // Operating system (uname -s)
+	Arch      string `json:"arch"`       // Architecture (uname -m)
+	Transport string `json:"transport"`  // "local" or "ssh:user@host"
+	Shell     string `json:"shell"`      // Shell commands run in: "sh", "cmd" or "powershell"
+	Timezone  string `json:"timezone"`   // Host time zone abbreviation (e.g. "CET")
+	UTCOffset string `json:"utc_offset"` // Host offset from UTC (e.g. "+01:00")
+	Timestamp string `json:"timestamp"`  // When context was captured (UTC, RFC3339Nano)
+
+	// Host environment from the preflight probe (see preflightScript); empty
+	// when it could not be determined
+	LoginShell      string   `json:"login_shell,omitempty"`      // The user's $SHELL
+	ShellDialect    string   `json:"shell_dialect,omitempty"`    // Shell commands run in: "bash", "dash", "ash" (BusyBox, FreeBSD), "zsh", "ksh" or "sh"
+	Path            string   `json:"path,omitempty"`             // $PATH commands are looked up in
+	Home            string   `json:"home,omitempty"`             // The user's $HOME
+	Sudo            string   `json:"sudo,omitempty"`             // Privilege escalation: "root", "passwordless" (sudo -n works) or "none"
+	PackageManagers []string `json:"package_managers,omitempty"` // Available package managers, preferred first
+	SELinux         string   `json:"selinux,omitempty"`          // "enforcing", "permissive" or "disabled"
+	Firewall        string   `json:"firewall,omitempty"`         // Active firewall: "firewalld", "ufw", "nftables", "macos", "pf" or "ipfw"
+	Container       string   `json:"container,omitempty"`        // Container runtime sink runs in, e.g. "docker", "kubernetes" or "jail"
+	CPUCount        int      `json:"cpu_count,omitempty"`        // CPUs available; the Go runtime's count on the local host
+
+	// Variables holds the config's variables as resolved for this run, so
+	// every event records the inputs it ran with
+	Variables map[string]interface{} `json:"variables,omitempty"`
 }
 
 // ExecutionEvent represents an event during execution
 type ExecutionEvent struct {
-	Timestamp string           `json:"timestamp"`
-	RunID     string           `json:"run_id"`
-	StepName  string           `json:"step_name"`
-	Status    string           `json:"status"` // "running", "success", "failed", "skipped"
-	Output    string           `json:"output,omitempty"`
-	Error     string           `json:"error,omitempty"`
-	Context   ExecutionContext `json:"context"` // Execution context for this event
+	Timestamp  string           `json:"timestamp"` // UTC, RFC3339Nano
+	Seq        int64            `json:"seq"`       // Position of the event within the run, from 1
+	RunID      string           `json:"run_id"`
+	Host       string           `json:"host"` // Host the event comes from: the deploy's name for it, or the context's host name
+	StepName   string           `json:"step_name"`
+	StepNumber int              `json:"step_number,omitempty"` // Position of the step in the order steps started, from 1; group members count too
+	Status     string           `json:"status"`                // "running", "retrying", "success", "failed", "skipped", "deferred", "not_run", "pending", "rollback"
+	Output     string           `json:"output,omitempty"`
+	Error      string           `json:"error,omitempty"`
+	Stdout     string           `json:"stdout,omitempty"` // Standard output of a completed command or remediation
+	Stderr     string           `json:"stderr,omitempty"` // Standard error of a completed command or remediation
+	Impact     string           `json:"impact,omitempty"` // Declared step impact
+	Risk       string           `json:"risk,omitempty"`   // Declared step risk level
+	Context    ExecutionContext `json:"context"`          // Execution context for this event
+
+	Annotations map[string]string `json:"annotations,omitempty"` // The step's config annotations, verbatim
+
+	// Remediation events are children of the check-with-remediation step
+	// running them; StepName is then the remediation's name
+	ParentStep       string `json:"parent_step,omitempty"`       // Name of the parent step
+	RemediationIndex int    `json:"remediation_index,omitempty"` // Position in the parent's on_missing list, from 1
+
+	Attempt int `json:"attempt,omitempty"` // Failed attempt of a "retrying" event, from 1
+
+	SkipReason string `json:"skip_reason,omitempty"` // Why a "skipped", "deferred" or "not_run" step did not run: dry_run, maintenance_window, max_duration, breakpoint, interrupted, broken_pipe or resumed
+
+	FailurePolicy string `json:"failure_policy,omitempty"` // Set on a "failed" event when the run goes on: continue or continue-collect
+
+	RecheckedAfter string          `json:"rechecked_after,omitempty"` // Step whose remediation triggered re-checking a pending check
+	OutputMismatch *OutputMismatch `json:"output_mismatch,omitempty"` // Expected vs actual output of a failed expect_output check
 
 	// Verbose metadata (populated when verbose mode is enabled)
 	StepType         string                `json:"step_type,omitempty"`         // Type of step (CommandStep, CheckRemediateStep, etc.)
 	Command          string                `json:"command,omitempty"`           // Command being executed
 	ExitCode         *int                  `json:"exit_code,omitempty"`         // Command exit code
-	Stdout           string                `json:"stdout,omitempty"`            // Standard output
-	Stderr           string                `json:"stderr,omitempty"`            // Standard error
 	Message          string                `json:"message,omitempty"`           // Step message
 	CustomError      string                `json:"custom_error,omitempty"`      // Custom error message
 	Retry            string                `json:"retry,omitempty"`             // Retry configuration
@@ -252,4 +630,5 @@ type ExecutionResult struct {
 	StartTime string           `json:"start_time"`
 	EndTime   string           `json:"end_time"`
 	Error     string           `json:"error,omitempty"`
+	Warnings  []Warning        `json:"warnings,omitempty"`
 }
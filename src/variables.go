@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// VariableDef declares an input a config is run with. Unlike facts, which
+// are probed from the host, variables are chosen by whoever runs the config:
+// a default in the config or --var name=value on the command line.
+type VariableDef struct {
+	Type        string      `json:"type,omitempty"` // "string" (default), "integer", "boolean" or "enum"
+	Default     interface{} `json:"default,omitempty"`
+	Description string      `json:"description,omitempty"`
+	Values      []string    `json:"values,omitempty"`  // Allowed values of an enum
+	Pattern     string      `json:"pattern,omitempty"` // Regular expression a string must match
+	Min         *int64      `json:"min,omitempty"`     // Smallest allowed integer
+	Max         *int64      `json:"max,omitempty"`     // Largest allowed integer
+}
+
+// validateVariableDef checks a variable declaration and its default
+func validateVariableDef(name string, def VariableDef, facts map[string]FactDef) error {
+	if !factNameRegex.MatchString(name) {
+		return fmt.Errorf("variable name must match pattern ^[a-z_][a-z0-9_]*$")
+	}
+	if name == sinkFactsKey {
+		return fmt.Errorf("variable name '%s' is reserved for built-in helpers such as {{.sink.tmpfile}}", name)
+	}
+	if isBuiltinFact(name) {
+		return fmt.Errorf("variable name '%s' is reserved for the built-in fact", name)
+	}
+	if _, ok := facts[name]; ok {
+		return fmt.Errorf("a fact has the same name; templates could not tell them apart")
+	}
+
+	switch def.Type {
+	case "", "string", "integer", "boolean":
+		if len(def.Values) > 0 {
+			return fmt.Errorf("values is only used with type \"enum\"")
+		}
+	case "enum":
+		if len(def.Values) == 0 {
+			return fmt.Errorf("enum needs values")
+		}
+	default:
+		return fmt.Errorf("invalid type '%s', must be one of: string, integer, boolean, enum", def.Type)
+	}
+	if def.Pattern != "" {
+		if def.Type != "" && def.Type != "string" {
+			return fmt.Errorf("pattern only allowed for string type, got type '%s'", def.Type)
+		}
+		if _, err := regexp.Compile(def.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+	}
+	if def.Min != nil || def.Max != nil {
+		if def.Type != "integer" {
+			return fmt.Errorf("min and max only allowed for integer type")
+		}
+		if def.Min != nil && def.Max != nil && *def.Min > *def.Max {
+			return fmt.Errorf("min %d is greater than max %d", *def.Min, *def.Max)
+		}
+	}
+
+	if def.Default != nil {
+		value, ok := defaultString(def.Default, def.Type)
+		if !ok {
+			return fmt.Errorf("default must be a %s", variableTypeName(def.Type))
+		}
+		if _, err := def.resolve(value); err != nil {
+			return fmt.Errorf("default: %w", err)
+		}
+	}
+	return nil
+}
+
+// variableTypeName names the JSON type a variable's default must have
+func variableTypeName(typ string) string {
+	switch typ {
+	case "integer":
+		return "whole number"
+	case "boolean":
+		return "boolean"
+	}
+	return "string"
+}
+
+// defaultString returns a JSON default in the form --var would give it, and
+// whether its JSON type suits the variable type
+func defaultString(value interface{}, typ string) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, typ == "" || typ == "string" || typ == "enum"
+	case bool:
+		return strconv.FormatBool(v), typ == "boolean"
+	case float64:
+		if v != float64(int64(v)) {
+			return "", false
+		}
+		return strconv.FormatInt(int64(v), 10), typ == "integer"
+	}
+	return "", false
+}
+
+// resolve converts a value given as text to the variable's type and checks
+// it against the declared values, pattern or range
+func (def VariableDef) resolve(value string) (interface{}, error) {
+	switch def.Type {
+	case "enum":
+		for _, allowed := range def.Values {
+			if value == allowed {
+				return value, nil
+			}
+		}
+		return nil, fmt.Errorf("'%s' is not one of: %s", value, strings.Join(def.Values, ", "))
+	case "boolean":
+		// The spellings SINK_* environment variables accept
+		b, err := parseEnvBool("", value)
+		if err != nil || value == "" {
+			return nil, fmt.Errorf("cannot convert '%s' to boolean (use true/false, yes/no or 1/0)", value)
+		}
+		return b, nil
+	}
+
+	typed, err := coerceType(value, def.Type)
+	if err != nil {
+		return nil, err
+	}
+	if def.Pattern != "" && !regexp.MustCompile(def.Pattern).MatchString(value) {
+		return nil, fmt.Errorf("'%s' does not match pattern %s", value, def.Pattern)
+	}
+	if i, ok := typed.(int64); ok {
+		if def.Min != nil && i < *def.Min {
+			return nil, fmt.Errorf("%d is less than the minimum %d", i, *def.Min)
+		}
+		if def.Max != nil && i > *def.Max {
+			return nil, fmt.Errorf("%d is greater than the maximum %d", i, *def.Max)
+		}
+	}
+	return typed, nil
+}
+
+// parseVarFlag splits a --var argument of the form name=value
+func parseVarFlag(arg string) (string, string, error) {
+	name, value, ok := strings.Cut(arg, "=")
+	if !ok || name == "" {
+		return "", "", fmt.Errorf("--var must be name=value, got '%s'", arg)
+	}
+	return name, value, nil
+}
+
+// ResolveVariables returns the value of every declared variable: the --var
+// override if given, else the default. Overrides of undeclared variables and
+// variables with neither are errors.
+func ResolveVariables(defs map[string]VariableDef, overrides map[string]string) (map[string]interface{}, error) {
+	names := make([]string, 0, len(defs))
+	for name := range defs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for name := range overrides {
+		if _, ok := defs[name]; !ok {
+			if len(names) == 0 {
+				return nil, fmt.Errorf("--var %s: the config declares no variables", name)
+			}
+			return nil, fmt.Errorf("--var %s: unknown variable (declared: %s)", name, strings.Join(names, ", "))
+		}
+	}
+
+	values := make(map[string]interface{}, len(defs))
+	for _, name := range names {
+		def := defs[name]
+		value, ok := overrides[name]
+		if !ok {
+			if def.Default == nil {
+				return nil, fmt.Errorf("variable '%s' has no default; set it with --var %s=<value>", name, name)
+			}
+			value, _ = defaultString(def.Default, def.Type)
+		}
+		typed, err := def.resolve(value)
+		if err != nil {
+			return nil, fmt.Errorf("variable '%s': %w", name, err)
+		}
+		values[name] = typed
+	}
+	return values, nil
+}
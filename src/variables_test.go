@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// TestResolveVariables tests defaults, --var overrides and type checks
+func TestResolveVariables(t *testing.T) {
+	config, err := parseConfigData([]byte(`{"version": "1.0.0",
+		"variables": {
+			"env": {"type": "enum", "values": ["staging", "production"], "default": "staging"},
+			"replicas": {"type": "integer", "default": 2, "min": 1, "max": 9},
+			"debug": {"type": "boolean", "default": false},
+			"release": {"pattern": "^v[0-9]+", "description": "Tag to deploy"}
+		},
+		"platforms": [{"os": "linux", "match": "Linux", "name": "Linux", "install_steps": [{"name": "a", "command": "deploy {{.release}} {{.env}}"}]}]}`), LoadOptions{Strict: true})
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+
+	values, err := ResolveVariables(config.Variables, map[string]string{"release": "v1.4", "debug": "yes"})
+	if err != nil {
+		t.Fatalf("resolve failed: %v", err)
+	}
+	data, _ := json.Marshal(values)
+	if string(data) != `{"debug":true,"env":"staging","release":"v1.4","replicas":2}` {
+		t.Errorf("unexpected values %s", data)
+	}
+
+	for overrides, want := range map[string]string{
+		"":                           "variable 'release' has no default; set it with --var release=<value>",
+		"release=1.4":                "variable 'release': '1.4' does not match pattern ^v[0-9]+",
+		"release=v1,env=prod":        "variable 'env': 'prod' is not one of: staging, production",
+		"release=v1,replicas=10":     "variable 'replicas': 10 is greater than the maximum 9",
+		"release=v1,replicas=many":   "variable 'replicas': cannot convert 'many' to integer",
+		"release=v1,debug=sometimes": "variable 'debug': cannot convert 'sometimes' to boolean",
+		"release=v1,region=eu":       "--var region: unknown variable (declared: debug, env, release, replicas)",
+	} {
+		vars := map[string]string{}
+		for _, pair := range strings.Split(overrides, ",") {
+			if pair != "" {
+				name, value, _ := parseVarFlag(pair)
+				vars[name] = value
+			}
+		}
+		if _, err := ResolveVariables(config.Variables, vars); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: expected %q, got %v", overrides, want, err)
+		}
+	}
+	if _, _, err := parseVarFlag("=x"); err == nil {
+		t.Error("expected --var without a name to fail")
+	}
+}
+
+// TestValidateVariableDef tests rejecting inconsistent variable declarations
+func TestValidateVariableDef(t *testing.T) {
+	for body, want := range map[string]string{
+		`{"arch": {}}`:               "a fact has the same name",
+		`{"sink": {}}`:               "reserved",
+		`{"Env": {}}`:                "variable name must match",
+		`{"env": {"type": "list"}}`:  "invalid type 'list'",
+		`{"env": {"type": "enum"}}`:  "enum needs values",
+		`{"env": {"values": ["a"]}}`: "values is only used with type \"enum\"",
+		`{"env": {"type": "enum", "values": ["a"], "default": "b"}}`: "default: 'b' is not one of: a",
+		`{"env": {"type": "integer", "default": "2"}}`:               "default must be a whole number",
+		`{"env": {"type": "integer", "default": 2.5}}`:               "default must be a whole number",
+		`{"env": {"type": "integer", "min": 5, "max": 1}}`:           "min 5 is greater than max 1",
+		`{"env": {"min": 5}}`:                                        "min and max only allowed for integer type",
+		`{"env": {"pattern": "("}}`:                                  "invalid pattern",
+	} {
+		data := `{"version": "1.0.0", "facts": {"arch": {"command": "uname -m"}}, "variables": ` + body + `,
+			"platforms": [{"os": "linux", "match": "Linux", "name": "Linux", "install_steps": [{"name": "a", "command": "true"}]}]}`
+		if _, err := parseConfigData([]byte(data), LoadOptions{}); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: expected %q, got %v", body, want, err)
+		}
+	}
+}
+
+// TestVariablesInContext tests that resolved variables reach events and templates
+func TestVariablesInContext(t *testing.T) {
+	mock := &MockTransportWithTracking{responses: map[string]MockResponse{"deploy v2 production": {exitCode: 0}}}
+	executor := NewExecutor(mock)
+	executor.SetVariables(map[string]interface{}{"release": "v2", "env": "production"})
+
+	var event ExecutionEvent
+	executor.OnEvent = func(e ExecutionEvent) { event = e }
+	result := executor.ExecuteStep(InstallStep{Name: "deploy", Step: CommandStep{Command: "deploy {{.release}} {{.env}}"}},
+		Facts{"release": "v2", "env": "production"})
+	if result.Error != "" {
+		t.Fatalf("step failed: %+v", result)
+	}
+	data, _ := json.Marshal(event)
+	if !strings.Contains(string(data), `"variables":{"env":"production","release":"v2"}`) {
+		t.Errorf("expected events to record variables: %s", data)
+	}
+}
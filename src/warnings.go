@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Warning codes, stable for tools that filter warnings
+const (
+	WarningMutableRef      = "mutable_ref"      // The config comes from a branch or unrecognized ref
+	WarningNoChecksum      = "no_checksum"      // The config was not verified against a checksum
+	WarningChecksumSkipped = "checksum_skipped" // --skip-checksum bypassed verification
+	WarningSourceChanged   = "source_changed"   // Content changed since first trusted, without a version change
+	WarningTrustDisabled   = "trust_disabled"   // Trust-on-first-use checks could not run
+	WarningAuditFailed     = "audit_failed"     // A command could not be written to the audit log
+	WarningNotPOSIX        = "not_posix"        // A step uses bash-isms the target's shell lacks
+	WarningEventsDropped   = "events_dropped"   // Events were dropped or could not be delivered to an event sink
+	WarningStdoutClosed    = "stdout_closed"    // Stdout was closed during the run, cutting its output short
+	WarningStateFailed     = "state_failed"     // A step result could not be written to the --state-file
+)
+
+// Warning is a problem that does not stop the run but should not scroll
+// away unnoticed, such as loading a config from a mutable ref
+type Warning struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// WarningLog collects the warnings of a run so they can be repeated in the
+// final summary and result JSON, and fail the run with --warnings-as-errors
+type WarningLog struct {
+	mu       sync.Mutex
+	warnings []Warning
+}
+
+// runWarnings collects the warnings of the current run
+var runWarnings = &WarningLog{}
+
+// warn prints a warning where it happens and records it for the summary
+func warn(code, format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	fmt.Printf("⚠️  %s\n", message)
+	runWarnings.add(Warning{Code: code, Message: message})
+}
+
+// add records a warning
+func (l *WarningLog) add(w Warning) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.warnings = append(l.warnings, w)
+}
+
+// List returns the warnings recorded so far, in order
+func (l *WarningLog) List() []Warning {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]Warning(nil), l.warnings...)
+}
+
+// printWarnings repeats a run's warnings at the end of its output
+func printWarnings(w io.Writer, warnings []Warning) {
+	if len(warnings) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "⚠️  %d warning(s):\n", len(warnings))
+	for _, warning := range warnings {
+		fmt.Fprintf(w, "   - %s [%s]\n", warning.Message, warning.Code)
+	}
+}
+
+// WarningsEvent lists a run's warnings at its end (JSON mode)
+type WarningsEvent struct {
+	Timestamp string    `json:"timestamp"`
+	Event     string    `json:"event"` // Always "warnings"
+	Warnings  []Warning `json:"warnings"`
+}
+
+// emitWarningsEventJSON reports a run's warnings (JSON mode)
+func emitWarningsEventJSON(warnings []Warning) {
+	if len(warnings) == 0 {
+		return
+	}
+	event := WarningsEvent{
+		Timestamp: eventTimestamp(time.Now()),
+		Event:     "warnings",
+		Warnings:  warnings,
+	}
+	jsonBytes, err := json.MarshalIndent(event, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: Failed to marshal warnings event to JSON: %v\n", err)
+		return
+	}
+	writeJSONObject(os.Stdout, jsonBytes)
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestSourceWarnings tests that loading unverified configs records warnings
+// for the summary
+func TestSourceWarnings(t *testing.T) {
+	runWarnings = &WarningLog{}
+	defer func() { runWarnings = &WarningLog{} }()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"version": "1.0", "platforms": [{"name": "Test", "os": "linux", "match": "Linux", "install_steps": [{"name": "test", "command": "true"}]}]}`))
+	}))
+	defer server.Close()
+
+	if _, err := loadConfigFromURL(server.URL, "", true); err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	validateGitHubPin(&GitHubURLInfo{Owner: "org", Repo: "configs", Ref: "main", PinType: GitHubPinBranch, IsMutable: true})
+
+	warnings := runWarnings.List()
+	if len(warnings) != 2 || warnings[0].Code != WarningChecksumSkipped || warnings[1].Code != WarningMutableRef {
+		t.Fatalf("unexpected warnings: %+v", warnings)
+	}
+
+	var out bytes.Buffer
+	printWarnings(&out, warnings)
+	if !strings.Contains(out.String(), "2 warning(s)") || !strings.Contains(out.String(), "Using MUTABLE branch 'main' (content can change) [mutable_ref]") {
+		t.Errorf("unexpected summary:\n%s", out.String())
+	}
+}
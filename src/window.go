@@ -0,0 +1,281 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Window is a maintenance window during which execution is allowed
+type Window interface {
+	Contains(t time.Time) bool
+}
+
+// ParseWindow parses a window specification. Two forms are supported:
+//
+//   - Time range: "22:00-06:00", optionally prefixed with days and suffixed
+//     with a timezone: "Mon-Fri 22:00-06:00 Europe/Berlin". Ranges may wrap
+//     past midnight; the after-midnight part belongs to the previous day.
+//     A CRON_TZ=<zone> prefix may give the timezone instead of the suffix.
+//   - Cron expression: five fields (minute hour day-of-month month day-of-week)
+//     describing the minutes in which execution is allowed, optionally
+//     prefixed with CRON_TZ=<zone>: "CRON_TZ=UTC * 1-4 * * 6,0".
+func ParseWindow(spec string) (Window, error) {
+	fields := strings.Fields(spec)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("window cannot be empty")
+	}
+
+	var loc *time.Location
+	if strings.HasPrefix(fields[0], "CRON_TZ=") {
+		l, err := time.LoadLocation(strings.TrimPrefix(fields[0], "CRON_TZ="))
+		if err != nil {
+			return nil, fmt.Errorf("invalid window timezone: %w", err)
+		}
+		loc = l
+		fields = fields[1:]
+	}
+
+	if len(fields) == 5 {
+		if loc == nil {
+			loc = time.Local
+		}
+		return parseCronWindow(fields, loc)
+	}
+
+	return parseRangeWindow(fields, loc)
+}
+
+// timeRangeWindow allows execution between two times of day on selected weekdays
+type timeRangeWindow struct {
+	days     [7]bool // Indexed by time.Weekday
+	start    int     // Minutes since midnight
+	end      int     // Minutes since midnight (exclusive)
+	location *time.Location
+}
+
+// parseRangeWindow parses "[days] HH:MM-HH:MM [zone]" in loc, the CRON_TZ
+// timezone, or nil for none
+func parseRangeWindow(fields []string, loc *time.Location) (Window, error) {
+	w := &timeRangeWindow{location: time.Local}
+	if loc != nil {
+		w.location = loc
+	}
+	for i := range w.days {
+		w.days[i] = true
+	}
+
+	rangeIdx := -1
+	for i, f := range fields {
+		if strings.Contains(f, ":") {
+			rangeIdx = i
+			break
+		}
+	}
+	if rangeIdx < 0 || rangeIdx > 1 || len(fields) > rangeIdx+2 {
+		return nil, fmt.Errorf("invalid window '%s' (expected '[days] HH:MM-HH:MM [zone]' or a 5-field cron expression)", strings.Join(fields, " "))
+	}
+
+	if rangeIdx == 1 {
+		days, err := parseWeekdays(fields[0])
+		if err != nil {
+			return nil, err
+		}
+		w.days = days
+	}
+
+	parts := strings.Split(fields[rangeIdx], "-")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid time range '%s' (expected HH:MM-HH:MM)", fields[rangeIdx])
+	}
+	var err error
+	if w.start, err = parseClock(parts[0]); err != nil {
+		return nil, err
+	}
+	if w.end, err = parseClock(parts[1]); err != nil {
+		return nil, err
+	}
+	if w.start == w.end {
+		return nil, fmt.Errorf("invalid time range '%s': start equals end", fields[rangeIdx])
+	}
+
+	if len(fields) == rangeIdx+2 {
+		if loc != nil {
+			return nil, fmt.Errorf("invalid window '%s': timezone given both as CRON_TZ and '%s'", strings.Join(fields, " "), fields[rangeIdx+1])
+		}
+		loc, err := time.LoadLocation(fields[rangeIdx+1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid window timezone: %w", err)
+		}
+		w.location = loc
+	}
+
+	return w, nil
+}
+
+// Contains implements Window
+func (w *timeRangeWindow) Contains(t time.Time) bool {
+	t = t.In(w.location)
+	minute := t.Hour()*60 + t.Minute()
+
+	if w.start < w.end {
+		return w.days[t.Weekday()] && minute >= w.start && minute < w.end
+	}
+
+	// Wraps past midnight
+	if minute >= w.start {
+		return w.days[t.Weekday()]
+	}
+	if minute < w.end {
+		return w.days[(t.Weekday()+6)%7]
+	}
+	return false
+}
+
+// parseClock parses "HH:MM" into minutes since midnight ("24:00" is end of day)
+func parseClock(s string) (int, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("invalid time '%s' (expected HH:MM)", s)
+	}
+	h, err1 := strconv.Atoi(parts[0])
+	m, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil || h < 0 || h > 24 || m < 0 || m > 59 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("invalid time '%s' (expected HH:MM)", s)
+	}
+	return h*60 + m, nil
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// parseWeekdays parses "Mon-Fri", "Sat,Sun" or "Mon,Wed-Fri"
+func parseWeekdays(s string) ([7]bool, error) {
+	var days [7]bool
+	for _, part := range strings.Split(strings.ToLower(s), ",") {
+		bounds := strings.Split(part, "-")
+		if len(bounds) > 2 {
+			return days, fmt.Errorf("invalid weekday range '%s'", part)
+		}
+		from, ok := weekdayNames[bounds[0]]
+		if !ok {
+			return days, fmt.Errorf("invalid weekday '%s'", bounds[0])
+		}
+		to := from
+		if len(bounds) == 2 {
+			if to, ok = weekdayNames[bounds[1]]; !ok {
+				return days, fmt.Errorf("invalid weekday '%s'", bounds[1])
+			}
+		}
+		for d := from; ; d = (d + 1) % 7 {
+			days[d] = true
+			if d == to {
+				break
+			}
+		}
+	}
+	return days, nil
+}
+
+// cronWindow allows execution in the minutes matched by a cron expression
+type cronWindow struct {
+	minutes, hours, doms, months, dows []bool
+	domStar, dowStar                   bool
+	location                           *time.Location
+}
+
+// parseCronWindow parses the five fields of a cron expression
+func parseCronWindow(fields []string, loc *time.Location) (Window, error) {
+	w := &cronWindow{location: loc}
+	var err error
+
+	if w.minutes, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("cron minute: %w", err)
+	}
+	if w.hours, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("cron hour: %w", err)
+	}
+	if w.doms, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("cron day-of-month: %w", err)
+	}
+	if w.months, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("cron month: %w", err)
+	}
+	// Day-of-week accepts 0-7 (both 0 and 7 are Sunday)
+	if w.dows, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("cron day-of-week: %w", err)
+	}
+	w.dows[0] = w.dows[0] || w.dows[7]
+	w.domStar = fields[2] == "*"
+	w.dowStar = fields[4] == "*"
+
+	return w, nil
+}
+
+// Contains implements Window. As in cron, when both day-of-month and
+// day-of-week are restricted, a match on either is enough.
+func (w *cronWindow) Contains(t time.Time) bool {
+	t = t.In(w.location)
+	if !w.minutes[t.Minute()] || !w.hours[t.Hour()] || !w.months[int(t.Month())] {
+		return false
+	}
+
+	domMatch := w.doms[t.Day()]
+	dowMatch := w.dows[int(t.Weekday())]
+	if w.domStar || w.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// parseCronField parses "*", "5", "1-5", "*/15", "0-30/10" and comma lists
+// into a lookup table indexed by value
+func parseCronField(field string, min, max int) ([]bool, error) {
+	values := make([]bool, max+1)
+
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			s, err := strconv.Atoi(part[i+1:])
+			if err != nil || s <= 0 {
+				return nil, fmt.Errorf("invalid step in '%s'", part)
+			}
+			step = s
+			part = part[:i]
+		}
+
+		lo, hi := min, max
+		if part != "*" {
+			bounds := strings.Split(part, "-")
+			if len(bounds) > 2 {
+				return nil, fmt.Errorf("invalid range '%s'", part)
+			}
+			var err error
+			if lo, err = strconv.Atoi(bounds[0]); err != nil {
+				return nil, fmt.Errorf("invalid value '%s'", bounds[0])
+			}
+			hi = lo
+			if len(bounds) == 1 && step > 1 {
+				// "5/10" means every 10th value starting at 5
+				hi = max
+			}
+			if len(bounds) == 2 {
+				if hi, err = strconv.Atoi(bounds[1]); err != nil {
+					return nil, fmt.Errorf("invalid value '%s'", bounds[1])
+				}
+			}
+		}
+
+		if lo < min || hi > max || lo > hi {
+			return nil, fmt.Errorf("'%s' out of range %d-%d", field, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			values[v] = true
+		}
+	}
+
+	return values, nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestParseWindow tests maintenance window parsing and matching
+func TestParseWindow(t *testing.T) {
+	// 2026-03-07 is a Saturday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, 3, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		spec string
+		at   time.Time
+		want bool
+	}{
+		{"09:00-17:00 UTC", at(9, 9, 0), true},
+		{"09:00-17:00 UTC", at(9, 17, 0), false},
+		{"22:00-06:00 UTC", at(9, 23, 30), true},
+		{"22:00-06:00 UTC", at(9, 5, 59), true},
+		{"22:00-06:00 UTC", at(9, 12, 0), false},
+		{"Sat,Sun 01:00-05:00 UTC", at(7, 2, 0), true},
+		{"Sat,Sun 01:00-05:00 UTC", at(9, 2, 0), false},
+		// After-midnight part of Friday's window falls on Saturday
+		{"Fri 22:00-02:00 UTC", at(7, 1, 0), true},
+		{"Fri 22:00-02:00 UTC", at(8, 1, 0), false},
+		{"Mon-Fri 00:00-24:00 UTC", at(9, 12, 0), true},
+		// CRON_TZ sets the timezone of a time range too (Tokyo is UTC+9)
+		{"CRON_TZ=Asia/Tokyo 09:00-17:00", at(9, 1, 0), true},
+		{"CRON_TZ=Asia/Tokyo 09:00-17:00", at(9, 9, 0), false},
+		{"CRON_TZ=UTC * 1-4 * * 6,0", at(8, 3, 15), true},
+		{"CRON_TZ=UTC * 1-4 * * 6,0", at(9, 3, 15), false},
+		{"CRON_TZ=UTC */15 * * * *", at(9, 3, 30), true},
+		{"CRON_TZ=UTC */15 * * * *", at(9, 3, 31), false},
+		{"CRON_TZ=UTC 5/10 * * * *", at(9, 3, 25), true},
+		{"CRON_TZ=UTC * * * * 7", at(8, 10, 0), true},
+		// Day-of-month and day-of-week both restricted: either matches
+		{"CRON_TZ=UTC * * 1 * 1", at(9, 10, 0), true},
+		{"CRON_TZ=UTC * * 1 * 1", at(10, 10, 0), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec+" "+tt.at.Format(time.RFC3339), func(t *testing.T) {
+			w, err := ParseWindow(tt.spec)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := w.Contains(tt.at); got != tt.want {
+				t.Errorf("Contains = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestParseWindowInvalid tests that malformed windows are rejected
+func TestParseWindowInvalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"tonight",
+		"22:00",
+		"25:00-06:00",
+		"10:00-10:00",
+		"Funday 01:00-02:00",
+		"01:00-02:00 Mars/Olympus",
+		"CRON_TZ=UTC 01:00-02:00 UTC",
+		"* * * *",
+		"60 * * * *",
+		"* * * * 8",
+		"*/0 * * * *",
+	} {
+		if _, err := ParseWindow(spec); err == nil {
+			t.Errorf("expected error for %q", spec)
+		}
+	}
+}
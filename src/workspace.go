@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	// sinkFactsKey is the reserved fact holding per-step helpers, host facts
+	// and the run's scratch space
+	sinkFactsKey = "sink"
+
+	// workspacePIDFile holds the PID of the run using a local workspace, so
+	// sink gc leaves the workspaces of live runs alone
+	workspacePIDFile = ".sink-pid"
+)
+
+// stepFacts returns a copy of facts with the reserved "sink" helpers for the
+// step about to run, so configs stop hardcoding /tmp paths that collide
+// between concurrent runs:
+//
+//   - {{.sink.run_dir}}:  workspace shared by every step of this run
+//   - {{.sink.step_dir}}: directory private to this step
+//   - {{.sink.tmpfile}}:  a file path inside step_dir
+//
+// Nothing is created until a command actually references these paths.
+// The host facts ({{.sink.host}}, {{.sink.host_index}}, {{.sink.host_count}})
+// come from Executor.Host, and {{.sink.arch}} is the context's architecture
+// normalized (see normalizeArch). {{.sink.bin_dir}}, {{.sink.sudo}} and
+// the other install scope facts come from installScopeFacts. {{.sink.run_id}}, {{.sink.step_index}} (steps
+// started before this one, including group members) and {{.sink.attempt}}
+// (from 1; see withAttempt) identify the command, and {{.sink.scratch}}
+// holds what earlier steps stored. The built-in facts ({{._os}} and so on)
+// are added alongside the config's.
+func (e *Executor) stepFacts(step InstallStep, facts Facts) Facts {
+	e.stepSeq++
+	e.stepDir = path.Join(e.Workspace, fmt.Sprintf("%02d-%s", e.stepSeq, stepSlug(step.Name)))
+
+	builtins := e.context.builtinFacts()
+	result := make(Facts, len(facts)+len(builtins)+1)
+	for name, value := range builtins {
+		result[name] = value
+	}
+	for name, value := range facts {
+		result[name] = value
+	}
+	helpers := map[string]interface{}{}
+	for name, value := range e.Host.facts() {
+		helpers[name] = value
+	}
+	for name, value := range e.context.preflightFacts() {
+		helpers[name] = value
+	}
+	for name, value := range installScopeFacts(e.InstallScope, e.context) {
+		helpers[name] = value
+	}
+	helpers["arch"] = normalizeArch(e.context.Arch)
+	helpers["run_id"] = e.runID
+	helpers["step_index"] = strconv.Itoa(e.stepSeq - 1)
+	helpers["attempt"] = "1"
+	helpers["scratch"] = e.scratch.Values()
+	helpers["run_dir"] = e.Workspace
+	helpers["step_dir"] = e.stepDir
+	helpers["tmpfile"] = path.Join(e.stepDir, "tmpfile")
+	result[sinkFactsKey] = helpers
+	return result
+}
+
+// ensureStepDir creates the current step's directory the first time an
+// interpolated command refers to the run workspace
+func (e *Executor) ensureStepDir(command string) {
+	if e.stepDir == "" || e.Workspace == "" || !strings.Contains(command, e.Workspace) {
+		return
+	}
+	if e.createdDirs[e.stepDir] {
+		return
+	}
+	if _, stderr, exitCode, err := e.transport.Run("mkdir -p " + shellQuote(e.stepDir)); err != nil || exitCode != 0 {
+		if e.Verbose {
+			verboseLog("Failed to create step directory %s: %v %s", e.stepDir, err, stderr)
+		}
+		return
+	}
+	if e.createdDirs == nil {
+		e.createdDirs = map[string]bool{}
+		if _, local := e.transport.(*LocalTransport); local {
+			os.WriteFile(filepath.Join(filepath.FromSlash(e.Workspace), workspacePIDFile), []byte(strconv.Itoa(os.Getpid())), TempFilePermission)
+		}
+	}
+	e.createdDirs[e.stepDir] = true
+}
+
+// cleanupWorkspace removes the run workspace if any step created it
+func (e *Executor) cleanupWorkspace() {
+	if len(e.createdDirs) == 0 {
+		return
+	}
+	if _, stderr, exitCode, err := e.transport.Run("rm -rf " + shellQuote(e.Workspace)); (err != nil || exitCode != 0) && e.Verbose {
+		verboseLog("Failed to remove workspace %s: %v %s", e.Workspace, err, stderr)
+	}
+	e.createdDirs = nil
+}
+
+// defaultWorkspace returns the run workspace for a run ID
+func defaultWorkspace(runID string) string {
+	return filepath.ToSlash(filepath.Join(workspaceBaseDir(), "sink-"+runID))
+}
+
+// stepSlug turns a step name into a short directory-safe name
+func stepSlug(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+		if b.Len() >= 40 {
+			break
+		}
+	}
+	slug := strings.TrimRight(b.String(), "-")
+	if slug == "" {
+		return "step"
+	}
+	return slug
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestStepHelperFacts tests {{.sink.step_dir}} and {{.sink.tmpfile}} on the local transport
+func TestStepHelperFacts(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+
+	executor := NewExecutor(NewLocalTransport())
+	executor.Workspace = filepath.ToSlash(filepath.Join(t.TempDir(), "run"))
+
+	results := executor.ExecutePlatform(Platform{
+		InstallSteps: []InstallStep{
+			{Name: "Write temp file", Step: CommandStep{Command: "echo hello > {{.sink.tmpfile}} && cat {{.sink.tmpfile}} && echo {{.sink.step_dir}}"}},
+			{Name: "Second step!", Step: CommandStep{Command: "echo {{.sink.step_dir}} {{.sink.run_dir}}"}},
+			{Name: "No helpers", Step: CommandStep{Command: "true"}},
+			{Name: "Owner", Step: CommandStep{Command: "cat {{.sink.run_dir}}/" + workspacePIDFile}},
+		},
+	}, Facts{"os": "linux"})
+
+	for _, result := range results {
+		if result.Error != "" {
+			t.Fatalf("Step %s failed: %s", result.StepName, result.Error)
+		}
+	}
+	if !strings.HasPrefix(results[0].Output, "hello\n"+executor.Workspace+"/01-write-temp-file") {
+		t.Errorf("Unexpected first step output: %q", results[0].Output)
+	}
+	if !strings.Contains(results[1].Output, executor.Workspace+"/02-second-step "+executor.Workspace) {
+		t.Errorf("Unexpected second step output: %q", results[1].Output)
+	}
+	if strings.TrimSpace(results[3].Output) != strconv.Itoa(os.Getpid()) {
+		t.Errorf("Expected the workspace to name this process for sink gc, got %q", results[3].Output)
+	}
+
+	if _, err := os.Stat(executor.Workspace); !os.IsNotExist(err) {
+		t.Errorf("Expected workspace to be removed after the run, got %v", err)
+	}
+}
+
+// TestStepHelperFactsLazy tests that steps not using the helpers create nothing
+func TestStepHelperFactsLazy(t *testing.T) {
+	transport := &MockTransportWithTracking{responses: map[string]MockResponse{}}
+	executor := NewExecutor(transport)
+	transport.calls = nil
+
+	executor.ExecutePlatform(Platform{
+		InstallSteps: []InstallStep{{Name: "plain", Step: CommandStep{Command: "echo {{.os}}"}}},
+	}, Facts{"os": "linux"})
+
+	for _, call := range transport.calls {
+		if strings.HasPrefix(call, "mkdir") || strings.HasPrefix(call, "rm -rf") {
+			t.Errorf("Unexpected workspace command %q", call)
+		}
+	}
+}
+
+// TestStepHostFacts tests {{.sink.host}}, {{.sink.host_index}} and {{.sink.host_count}}
+func TestStepHostFacts(t *testing.T) {
+	transport := &MockTransportWithTracking{responses: map[string]MockResponse{
+		"hostname": {stdout: "laptop\n"},
+	}}
+	executor := NewExecutor(transport)
+	facts := executor.stepFacts(InstallStep{Name: "cert"}, Facts{})
+	if got := facts[sinkFactsKey].(map[string]interface{}); got["host"] != "laptop" || got["host_index"] != "0" || got["host_count"] != "1" {
+		t.Errorf("Expected this host alone by default, got %v", got)
+	}
+
+	executor.Host = HostInfo{Name: "web03.prod", Index: 2, Count: 20}
+	transport.calls = nil
+	executor.ExecutePlatform(Platform{
+		InstallSteps: []InstallStep{{Name: "cert", Step: CommandStep{Command: "gen-cert {{.sink.host}} node-{{.sink.host_index}}-of-{{.sink.host_count}}"}}},
+	}, Facts{})
+	if len(transport.calls) == 0 || transport.calls[0] != "gen-cert web03.prod node-2-of-20" {
+		t.Errorf("Unexpected commands %v", transport.calls)
+	}
+}
+
+// TestReservedSinkFact tests that configs cannot define a fact named "sink"
+func TestReservedSinkFact(t *testing.T) {
+	err := ValidateFactDef("sink", FactDef{Command: "echo x"})
+	if err == nil || !strings.Contains(err.Error(), "reserved") {
+		t.Errorf("Expected reserved name error, got %v", err)
+	}
+}
+
+// TestStepSlug tests step directory naming
+func TestStepSlug(t *testing.T) {
+	tests := map[string]string{
+		"Install nginx":         "install-nginx",
+		"  Setup: SSL / certs ": "setup-ssl-certs",
+		"!!!":                   "step",
+		strings.Repeat("a", 60): strings.Repeat("a", 40),
+	}
+	for name, want := range tests {
+		if got := stepSlug(name); got != want {
+			t.Errorf("stepSlug(%q) = %q, want %q", name, got, want)
+		}
+	}
+}